@@ -1,19 +1,26 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	server "github.com/osauer/hyperserve/pkg/server"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "mcp-replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+
 	var (
-		port    = flag.Int("port", 8080, "Port to listen on")
-		mcp     = flag.Bool("mcp", true, "Enable MCP support")
-		verbose = flag.Bool("verbose", false, "Enable verbose logging")
+		port        = flag.Int("port", 8080, "Port to listen on")
+		mcp         = flag.Bool("mcp", true, "Enable MCP support")
+		verbose     = flag.Bool("verbose", false, "Enable verbose logging")
+		printConfig = flag.Bool("print-config", false, "Print the effective configuration with the source of each value and exit")
 	)
 	flag.Parse()
 
@@ -41,6 +48,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *printConfig {
+		out, err := json.MarshalIndent(srv.EffectiveConfig(), "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
 	// Add routes
 	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	server "github.com/osauer/hyperserve/pkg/server"
+)
+
+// runReplay implements the mcp-replay subcommand and returns the process exit code.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("mcp-replay", flag.ExitOnError)
+	var (
+		url     = fs.String("url", "http://localhost:8080/mcp", "MCP endpoint of the server to replay against")
+		ignore  = fs.String("ignore", "", "Comma-separated result fields to ignore (e.g. timestamp,uptime)")
+		stop    = fs.Bool("stop", false, "Stop at the first mismatch")
+		jsonOut = fs.Bool("json", false, "Print the full report as JSON")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Replay a recorded MCP trace and compare responses with the recording\n\n")
+		fmt.Fprintf(fs.Output(), "Usage: hyperserve mcp-replay [flags] trace.ndjson\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := server.MCPReplayOptions{StopOnMismatch: *stop}
+	for _, field := range strings.Split(*ignore, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			opts.IgnoreFields = append(opts.IgnoreFields, field)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	report, err := server.ReplayMCPTraceFile(ctx, fs.Arg(0), server.MCPEndpointTarget(*url, nil), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay failed: %v\n", err)
+		return 1
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, res := range report.Results {
+			if res.Status != "match" {
+				fmt.Printf("line %d %s (id %v): %s: %s\n", res.Line, res.Method, res.ID, res.Status, res.Detail)
+			}
+		}
+		fmt.Printf("%d replayed: %d matched, %d mismatched, %d errors, %d skipped\n",
+			report.Total, report.Matched, report.Mismatched, report.Errors, report.Skipped)
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
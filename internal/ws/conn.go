@@ -22,6 +22,13 @@ type Conn struct {
 	messageMu     sync.Mutex
 	messageBuffer []byte
 	messageType   int
+	discarding    bool // skipping the remaining frames of an oversized message
+	
+	// Serialises frame writes, e.g. pings sent while a message is written
+	writeMu sync.Mutex
+	
+	// Called with the payload of received pongs
+	pongHandler func(data []byte)
 	
 	// Close handling
 	closeMu    sync.Mutex
@@ -39,6 +46,24 @@ func NewConn(netConn net.Conn, buf *bufio.ReadWriter, isServer bool, maxMessageS
 	}
 }
 
+// SetMaxMessageSize sets the size limit of messages read from the connection. With
+// discard, oversized messages are skipped and reported as ErrMessageTooBig, after which
+// reading can continue; otherwise the connection must be closed after the error.
+func (c *Conn) SetMaxMessageSize(n int64, discard bool) {
+	c.messageMu.Lock()
+	defer c.messageMu.Unlock()
+	if n > 0 {
+		c.reader.maxMessageSize = n
+	}
+	c.reader.discardOversized = discard
+}
+
+// SetPongHandler sets a function called with the payload of each pong received. Set it
+// before reading.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
 // ReadFrame reads the next frame from the connection
 func (c *Conn) ReadFrame() (*Frame, error) {
 	return c.reader.ReadFrame()
@@ -46,6 +71,15 @@ func (c *Conn) ReadFrame() (*Frame, error) {
 
 // WriteFrame writes a frame to the connection
 func (c *Conn) WriteFrame(frame *Frame) error {
+	// Clients must mask every frame (RFC 6455 section 5.3)
+	if !c.isServer && !frame.Masked {
+		frame.Masked = true
+		if _, err := rand.Read(frame.MaskKey[:]); err != nil {
+			return err
+		}
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	return c.writer.WriteFrame(frame)
 }
 
@@ -56,9 +90,19 @@ func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
 	
 	for {
 		frame, err := c.ReadFrame()
+		if errors.Is(err, ErrMessageTooBig) && frame != nil {
+			// The payload was discarded; skip the rest of a fragmented message
+			c.messageBuffer = nil
+			c.discarding = !frame.Fin
+			return 0, nil, err
+		}
 		if err != nil {
 			return 0, nil, err
 		}
+		if c.discarding && frame.Opcode == OpcodeContinuation {
+			c.discarding = !frame.Fin
+			continue
+		}
 		
 		switch frame.Opcode {
 		case OpcodeText, OpcodeBinary:
@@ -81,6 +125,11 @@ func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
 			if c.messageBuffer == nil {
 				return 0, nil, ErrUnexpectedContinuation
 			}
+			if int64(len(c.messageBuffer)+len(frame.Payload)) > c.reader.maxMessageSize {
+				c.messageBuffer = nil
+				c.discarding = !frame.Fin
+				return 0, nil, ErrMessageTooBig
+			}
 			c.messageBuffer = append(c.messageBuffer, frame.Payload...)
 			
 			if frame.Fin {
@@ -129,7 +178,9 @@ func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
 			}
 			
 		case OpcodePong:
-			// Pong received, no action needed
+			if c.pongHandler != nil {
+				c.pongHandler(frame.Payload)
+			}
 			continue
 			
 		default:
@@ -204,6 +255,23 @@ func (c *Conn) Close() error {
 	return c.conn.Close()
 }
 
+// CloseWithCode sends a close frame with code and text, unless one was already sent,
+// and closes the connection
+func (c *Conn) CloseWithCode(code int, text string) error {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	
+	if !c.closeSent {
+		c.closeSent = true
+		closePayload := make([]byte, 2+len(text))
+		binary.BigEndian.PutUint16(closePayload, uint16(code))
+		copy(closePayload[2:], text)
+		_ = c.WriteControl(OpcodeClose, closePayload) // Best effort close notification
+	}
+	
+	return c.conn.Close()
+}
+
 // SetDeadline sets the read and write deadlines
 func (c *Conn) SetDeadline(t time.Time) error {
 	return c.conn.SetDeadline(t)
@@ -62,6 +62,9 @@ type Frame struct {
 type FrameReader struct {
 	reader *bufio.Reader
 	maxMessageSize int64
+	// discardOversized skips the payload of frames over maxMessageSize, keeping the
+	// stream in sync, instead of leaving it unread
+	discardOversized bool
 }
 
 // NewFrameReader creates a new frame reader
@@ -121,7 +124,17 @@ func (fr *FrameReader) ReadFrame() (*Frame, error) {
 	
 	// Check message size limit
 	if payloadLen > fr.maxMessageSize {
-		return nil, ErrMessageTooBig
+		if !fr.discardOversized {
+			return nil, ErrMessageTooBig
+		}
+		skip := payloadLen
+		if frame.Masked {
+			skip += 4
+		}
+		if _, err := io.CopyN(io.Discard, fr.reader, skip); err != nil {
+			return nil, err
+		}
+		return frame, ErrMessageTooBig
 	}
 	
 	// Read mask key if present
@@ -4,12 +4,14 @@ package ws
 
 import (
 	"bufio"
+	"crypto/rand"
 	"crypto/sha1" //nolint:gosec // Required by WebSocket RFC 6455
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -126,6 +128,57 @@ func PerformHandshake(w http.ResponseWriter, r *http.Request, opts *HandshakeOpt
 	return conn, buf, nil
 }
 
+// ClientHandshake performs the client side of the opening handshake over netConn for a
+// ws:// or wss:// URL. It returns the buffered connection to build a client Conn from
+// and the server response, which is also returned when the server refused the upgrade.
+func ClientHandshake(netConn net.Conn, u *url.URL, header http.Header, subprotocols []string) (*bufio.ReadWriter, *http.Response, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	
+	reqURL := *u
+	switch u.Scheme {
+	case "ws":
+		reqURL.Scheme = "http"
+	case "wss":
+		reqURL.Scheme = "https"
+	}
+	req, err := http.NewRequest(http.MethodGet, reqURL.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", websocketVersion)
+	if len(subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(subprotocols, ", "))
+	}
+	if err := req.Write(netConn); err != nil {
+		return nil, nil, err
+	}
+	
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		!strings.Contains(strings.ToLower(resp.Header.Get("Connection")), "upgrade") {
+		return nil, resp, fmt.Errorf("%w: server responded %s", ErrBadHandshake, resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != generateAcceptKey(key) {
+		return nil, resp, fmt.Errorf("%w: invalid Sec-WebSocket-Accept", ErrBadHandshake)
+	}
+	return bufio.NewReadWriter(br, bufio.NewWriter(netConn)), resp, nil
+}
+
 // isWebSocketUpgrade checks if the request is a WebSocket upgrade
 func isWebSocketUpgrade(r *http.Request) bool {
 	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
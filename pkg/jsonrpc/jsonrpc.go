@@ -1,7 +1,9 @@
 package jsonrpc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sort"
@@ -26,13 +28,18 @@ type Response struct {
 	ID      interface{}   `json:"id"`
 }
 
-// ErrorDetails represents a JSON-RPC 2.0 error object.
+// ErrorDetails represents a JSON-RPC 2.0 error object. Method handlers can return it as
+// an error to answer with a specific code instead of an internal error.
 type ErrorDetails struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
+func (e *ErrorDetails) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
 // Standard JSON-RPC error codes.
 const (
 	ErrorCodeParseError     = -32700
@@ -45,9 +52,13 @@ const (
 // MethodHandler defines the signature for JSON-RPC method handlers.
 type MethodHandler func(params interface{}) (interface{}, error)
 
+// ContextMethodHandler is a method handler that receives the context passed to
+// ProcessRequestContext, e.g. to reach the transport the request arrived on.
+type ContextMethodHandler func(ctx context.Context, params interface{}) (interface{}, error)
+
 // Engine handles JSON-RPC 2.0 request processing.
 type Engine struct {
-	methods map[string]MethodHandler
+	methods map[string]ContextMethodHandler
 	logger  *slog.Logger
 }
 
@@ -57,13 +68,20 @@ func NewEngine(logger *slog.Logger) *Engine {
 		logger = slog.Default()
 	}
 	return &Engine{
-		methods: make(map[string]MethodHandler),
+		methods: make(map[string]ContextMethodHandler),
 		logger:  logger,
 	}
 }
 
 // RegisterMethod registers a method handler with the JSON-RPC engine.
 func (engine *Engine) RegisterMethod(name string, handler MethodHandler) {
+	engine.RegisterMethodWithContext(name, func(_ context.Context, params interface{}) (interface{}, error) {
+		return handler(params)
+	})
+}
+
+// RegisterMethodWithContext registers a method handler that receives the request context.
+func (engine *Engine) RegisterMethodWithContext(name string, handler ContextMethodHandler) {
 	engine.methods[name] = handler
 	engine.logger.Debug("JSON-RPC method registered", "method", name)
 }
@@ -111,6 +129,12 @@ func (engine *Engine) ProcessRequest(requestData []byte) []byte {
 
 // ProcessRequestDirect processes a JSON-RPC request object and returns the response object.
 func (engine *Engine) ProcessRequestDirect(request *Request) *Response {
+	return engine.ProcessRequestContext(context.Background(), request)
+}
+
+// ProcessRequestContext is ProcessRequestDirect with a context that is passed to the
+// method handler.
+func (engine *Engine) ProcessRequestContext(ctx context.Context, request *Request) *Response {
 	// Validate JSON-RPC version
 	if request.JSONRPC != Version {
 		engine.logger.Error("Invalid JSON-RPC version", "version", request.JSONRPC)
@@ -141,8 +165,13 @@ func (engine *Engine) ProcessRequestDirect(request *Request) *Response {
 	}
 
 	// Call method handler
-	result, err := handler(request.Params)
+	result, err := handler(ctx, request.Params)
 	if err != nil {
+		var details *ErrorDetails
+		if errors.As(err, &details) {
+			engine.logger.Debug("JSON-RPC method returned an error", "method", request.Method, "code", details.Code)
+			return &Response{JSONRPC: Version, Error: details, ID: request.ID}
+		}
 		engine.logger.Error("JSON-RPC method execution error", "method", request.Method, "error", err)
 		return &Response{
 			JSONRPC: Version,
@@ -1,7 +1,9 @@
 package jsonrpc
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -93,6 +95,32 @@ func TestProcessRequestMethodError(t *testing.T) {
 	}
 }
 
+func TestProcessRequestErrorDetails(t *testing.T) {
+	engine := NewEngine(nil)
+	engine.RegisterMethod("validate", func(params interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("checking params: %w", &ErrorDetails{Code: ErrorCodeInvalidParams, Message: "Invalid params", Data: "name is required"})
+	})
+
+	resp := engine.ProcessRequestDirect(&Request{JSONRPC: Version, Method: "validate", ID: 1})
+	if resp.Error == nil || resp.Error.Code != ErrorCodeInvalidParams || resp.Error.Data != "name is required" {
+		t.Fatalf("expected the handler's error details, got %+v", resp.Error)
+	}
+}
+
+func TestProcessRequestContext(t *testing.T) {
+	type key struct{}
+	engine := NewEngine(nil)
+	engine.RegisterMethodWithContext("whoami", func(ctx context.Context, params interface{}) (interface{}, error) {
+		return ctx.Value(key{}), nil
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "caller")
+	resp := engine.ProcessRequestContext(ctx, &Request{JSONRPC: Version, Method: "whoami", ID: 1})
+	if resp.Error != nil || resp.Result != "caller" {
+		t.Fatalf("expected result from context, got %+v", resp)
+	}
+}
+
 // assertError implements error for test assertions.
 type assertError string
 
@@ -0,0 +1,45 @@
+package mcptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// UpdateEnv names the environment variable that makes AssertToolSchema write golden
+// files instead of comparing with them:
+//
+//	MCPTEST_UPDATE=1 go test ./...
+const UpdateEnv = "MCPTEST_UPDATE"
+
+// AssertToolSchema compares the listing of a tool (name, description, and input schema)
+// with the golden file at path, so that changes to the contract the model sees show up
+// in review. Set MCPTEST_UPDATE=1 to create or update the file.
+func (c *Client) AssertToolSchema(name, path string) {
+	c.tb.Helper()
+	got, err := json.MarshalIndent(c.Tool(name), "", "  ")
+	if err != nil {
+		c.tb.Fatalf("mcptest: encode schema of %s: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	if v := os.Getenv(UpdateEnv); v == "1" || v == "true" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			c.tb.Fatalf("mcptest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			c.tb.Fatalf("mcptest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		c.tb.Fatalf("mcptest: read golden file: %v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	if !bytes.Equal(bytes.TrimSpace(got), bytes.TrimSpace(want)) {
+		c.tb.Errorf("mcptest: schema of %s differs from %s (run with %s=1 to update)\ngot:\n%s\nwant:\n%s",
+			name, path, UpdateEnv, got, want)
+	}
+}
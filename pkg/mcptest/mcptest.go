@@ -0,0 +1,297 @@
+// Package mcptest tests MCP tools and resources without starting an HTTP server.
+//
+// A Client talks to an MCP handler in memory, over the same JSON-RPC messages a real
+// client sends, so tests cover argument validation, tool middleware, and result
+// formatting as well as the tool itself. Helpers fail the test on unexpected errors,
+// and AssertToolSchema compares a tool's schema with a golden file.
+//
+// Example:
+//
+//	func TestPublishTool(t *testing.T) {
+//		c := mcptest.New(t, mcptest.WithTools(&PublishTool{store: newMemoryStore()}))
+//		text := c.CallToolText("publish", map[string]interface{}{"title": "Hello"})
+//		if !strings.Contains(text, "published") {
+//			t.Errorf("unexpected result %q", text)
+//		}
+//		c.AssertToolSchema("publish", "testdata/publish.schema.json")
+//	}
+//
+// Applications that register tools on a server, for example through an MCP extension,
+// use NewFromServer instead.
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osauer/hyperserve/pkg/server"
+)
+
+// callTimeout bounds every request of a Client.
+const callTimeout = 30 * time.Second
+
+// Client is an MCP client connected in memory to a handler.
+type Client struct {
+	tb      testing.TB
+	handler *server.MCPHandler
+	mcp     *server.MCPClient
+}
+
+// Option configures a Client.
+type Option func(*config)
+
+type config struct {
+	tools       []server.MCPTool
+	resources   []server.MCPResource
+	sampling    func(server.MCPSamplingRequest) (*server.MCPSamplingResult, error)
+	elicitation func(server.MCPElicitationRequest) (*server.MCPElicitationResult, error)
+	roots       []server.MCPRoot
+	rootsSet    bool
+}
+
+// WithTools registers tools on the handler created by New.
+func WithTools(tools ...server.MCPTool) Option {
+	return func(c *config) {
+		c.tools = append(c.tools, tools...)
+	}
+}
+
+// WithResources registers resources on the handler created by New.
+func WithResources(resources ...server.MCPResource) Option {
+	return func(c *config) {
+		c.resources = append(c.resources, resources...)
+	}
+}
+
+// WithSampling answers sampling/createMessage requests of tools with fn, standing in
+// for the client's LLM.
+func WithSampling(fn func(server.MCPSamplingRequest) (*server.MCPSamplingResult, error)) Option {
+	return func(c *config) {
+		c.sampling = fn
+	}
+}
+
+// WithElicitation answers elicitation/create requests of tools with fn, standing in
+// for the user.
+func WithElicitation(fn func(server.MCPElicitationRequest) (*server.MCPElicitationResult, error)) Option {
+	return func(c *config) {
+		c.elicitation = fn
+	}
+}
+
+// WithRoots makes the client declare the roots capability and expose the directories
+// dirs, so that file tools are restricted to them. Change them during a test with
+// c.MCP().SetRoots.
+func WithRoots(dirs ...string) Option {
+	return func(c *config) {
+		c.rootsSet = true
+		for _, dir := range dirs {
+			c.roots = append(c.roots, server.NewMCPRoot(dir))
+		}
+	}
+}
+
+// New creates an MCP handler with the tools and resources of opts, connects a client,
+// and initializes the session. Tools and resources are registered without a namespace,
+// so they are called by their own names. The client is closed when the test ends.
+func New(tb testing.TB, opts ...Option) *Client {
+	tb.Helper()
+	h := server.NewMCPHandler(server.MCPServerInfo{Name: "mcptest", Version: "1.0.0"})
+	return NewFromHandler(tb, h, opts...)
+}
+
+// NewFromServer connects a client to the MCP handler of srv, which must have MCP enabled.
+// Tools are called by the names the server registered them under, e.g.
+// mcp__blog__publish for tools of a namespace.
+func NewFromServer(tb testing.TB, srv *server.Server, opts ...Option) *Client {
+	tb.Helper()
+	h := srv.MCPHandler()
+	if h == nil {
+		tb.Fatal("mcptest: MCP is not enabled on the server")
+	}
+	return NewFromHandler(tb, h, opts...)
+}
+
+// NewFromHandler connects a client to h after registering the tools and resources of opts.
+func NewFromHandler(tb testing.TB, h *server.MCPHandler, opts ...Option) *Client {
+	tb.Helper()
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	for _, tool := range cfg.tools {
+		h.RegisterTool(tool)
+	}
+	for _, resource := range cfg.resources {
+		h.RegisterResource(resource)
+	}
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- h.ServeStdio(serverIn, serverOut)
+		serverOut.Close()
+	}()
+
+	clientOpts := []server.MCPClientOption{server.WithMCPClientInfo("mcptest", "1.0.0")}
+	if cfg.rootsSet {
+		clientOpts = append(clientOpts, server.WithMCPClientRoots(cfg.roots...))
+	}
+	mcp := server.NewMCPStdioClient(clientIn, clientOut, clientOpts...)
+	if cfg.sampling != nil || cfg.elicitation != nil {
+		mcp.OnRequest(cfg.answer)
+	}
+	c := &Client{tb: tb, handler: h, mcp: mcp}
+	tb.Cleanup(func() {
+		mcp.Close()
+		if err := <-done; err != nil {
+			tb.Errorf("mcptest: handler stopped: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if _, err := mcp.Initialize(ctx); err != nil {
+		tb.Fatalf("mcptest: initialize: %v", err)
+	}
+	return c
+}
+
+// answer dispatches requests from the server to the sampling and elicitation functions.
+func (cfg *config) answer(_ context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch {
+	case method == "sampling/createMessage" && cfg.sampling != nil:
+		var req server.MCPSamplingRequest
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, &server.JSONRPCError{Code: server.ErrorCodeInvalidParams, Message: err.Error()}
+		}
+		return cfg.sampling(req)
+	case method == "elicitation/create" && cfg.elicitation != nil:
+		var req server.MCPElicitationRequest
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, &server.JSONRPCError{Code: server.ErrorCodeInvalidParams, Message: err.Error()}
+		}
+		return cfg.elicitation(req)
+	}
+	return nil, &server.JSONRPCError{Code: server.ErrorCodeMethodNotFound, Message: "Method not found: " + method}
+}
+
+// Handler returns the MCP handler the client is connected to, for registering more
+// tools during a test.
+func (c *Client) Handler() *server.MCPHandler {
+	return c.handler
+}
+
+// MCP returns the underlying client, for requests the helpers do not cover.
+func (c *Client) MCP() *server.MCPClient {
+	return c.mcp
+}
+
+// CallTool calls a tool and returns its result, failing the test if the call fails.
+// Results the tool flagged as errors are returned; check IsError.
+func (c *Client) CallTool(name string, args map[string]interface{}) *server.MCPClientToolResult {
+	c.tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	result, err := c.mcp.CallTool(ctx, name, args)
+	if err != nil {
+		c.tb.Fatalf("mcptest: call %s: %v", name, err)
+	}
+	return result
+}
+
+// CallToolText calls a tool and returns the text of its result, failing the test if
+// the call fails or the result is flagged as an error.
+func (c *Client) CallToolText(name string, args map[string]interface{}) string {
+	c.tb.Helper()
+	result := c.CallTool(name, args)
+	if result.IsError {
+		c.tb.Fatalf("mcptest: call %s: tool returned an error: %s", name, result.Text())
+	}
+	return result.Text()
+}
+
+// CallToolError calls a tool that is expected to fail and returns the error, which is
+// a *server.JSONRPCError when the server rejected the call. The test fails if the call
+// succeeds.
+func (c *Client) CallToolError(name string, args map[string]interface{}) error {
+	c.tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	result, err := c.mcp.CallTool(ctx, name, args)
+	switch {
+	case err != nil:
+		return err
+	case result.IsError:
+		return fmt.Errorf("tool returned an error: %s", result.Text())
+	}
+	c.tb.Fatalf("mcptest: call %s: expected an error, got %q", name, result.Text())
+	return nil
+}
+
+// ListTools returns the tools the handler lists.
+func (c *Client) ListTools() []server.MCPToolInfo {
+	c.tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	tools, err := c.mcp.ListTools(ctx)
+	if err != nil {
+		c.tb.Fatalf("mcptest: list tools: %v", err)
+	}
+	return tools
+}
+
+// Tool returns the listing of the named tool, failing the test if it is not listed.
+func (c *Client) Tool(name string) server.MCPToolInfo {
+	c.tb.Helper()
+	for _, tool := range c.ListTools() {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	c.tb.Fatalf("mcptest: tool %s is not listed", name)
+	return server.MCPToolInfo{}
+}
+
+// ListResources returns the resources the handler lists.
+func (c *Client) ListResources() []server.MCPResourceInfo {
+	c.tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resources, err := c.mcp.ListResources(ctx)
+	if err != nil {
+		c.tb.Fatalf("mcptest: list resources: %v", err)
+	}
+	return resources
+}
+
+// ReadResource reads a resource and returns its text, failing the test if the read fails.
+// Contents that are not text are returned as JSON.
+func (c *Client) ReadResource(uri string) string {
+	c.tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	contents, err := c.mcp.ReadResource(ctx, uri)
+	if err != nil {
+		c.tb.Fatalf("mcptest: read %s: %v", uri, err)
+	}
+	texts := make([]string, 0, len(contents))
+	for _, content := range contents {
+		if text, ok := content.Text.(string); ok {
+			texts = append(texts, text)
+			continue
+		}
+		data, err := json.Marshal(content.Text)
+		if err != nil {
+			c.tb.Fatalf("mcptest: read %s: %v", uri, err)
+		}
+		texts = append(texts, string(data))
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,139 @@
+package mcptest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/osauer/hyperserve/pkg/server"
+)
+
+func greetTool() server.MCPTool {
+	return server.NewTool("greet").
+		WithDescription("Greets someone").
+		WithParameter("name", "string", "Who to greet", true).
+		WithExecute(func(params map[string]interface{}) (interface{}, error) {
+			if params["name"] == "nobody" {
+				return nil, errors.New("nobody to greet")
+			}
+			return "Hello, " + params["name"].(string), nil
+		}).
+		Build()
+}
+
+func TestClientCallsTools(t *testing.T) {
+	c := New(t,
+		WithTools(greetTool()),
+		WithResources(server.NewResource("notes://today").
+			WithName("Today").
+			WithRead(func() (interface{}, error) { return "buy milk", nil }).
+			Build()),
+	)
+
+	if text := c.CallToolText("greet", map[string]interface{}{"name": "Ada"}); text != "Hello, Ada" {
+		t.Errorf("unexpected result %q", text)
+	}
+	if err := c.CallToolError("greet", map[string]interface{}{}); err == nil {
+		t.Error("expected missing arguments to be rejected")
+	}
+	var rpcErr *server.JSONRPCError
+	if err := c.CallToolError("greet", map[string]interface{}{"name": "nobody"}); !errors.As(err, &rpcErr) {
+		t.Errorf("expected a JSON-RPC error, got %v", err)
+	}
+	if text := c.ReadResource("notes://today"); text != "buy milk" {
+		t.Errorf("unexpected resource text %q", text)
+	}
+	if tool := c.Tool("greet"); tool.Description != "Greets someone" {
+		t.Errorf("unexpected listing %+v", tool)
+	}
+}
+
+func TestAssertToolSchema(t *testing.T) {
+	c := New(t, WithTools(greetTool()))
+	c.AssertToolSchema("greet", "testdata/greet.schema.json")
+
+	t.Setenv(UpdateEnv, "1")
+	path := filepath.Join(t.TempDir(), "schemas", "greet.json")
+	c.AssertToolSchema("greet", path)
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.ReadFile("testdata/greet.schema.json")
+	if string(got) != string(want) {
+		t.Errorf("updated golden file differs:\n%s", got)
+	}
+}
+
+// askTool asks the LLM for a suggestion and the user to confirm it.
+type askTool struct{}
+
+func (askTool) Name() string                   { return "ask" }
+func (askTool) Description() string            { return "Asks the client" }
+func (askTool) Schema() map[string]interface{} { return map[string]interface{}{"type": "object"} }
+func (askTool) Execute(map[string]interface{}) (interface{}, error) {
+	return nil, errors.New("requires a client")
+}
+
+func (askTool) ExecuteWithClient(ctx context.Context, _ map[string]interface{}, client server.MCPClientRequester) (interface{}, error) {
+	suggestion, err := client.CreateMessage(ctx, server.MCPSamplingRequest{MaxTokens: 10})
+	if err != nil {
+		return nil, err
+	}
+	answer, err := client.Elicit(ctx, server.MCPElicitationRequest{Message: suggestion.Content.Text})
+	if err != nil {
+		return nil, err
+	}
+	return answer.Action + ": " + suggestion.Content.Text, nil
+}
+
+func TestClientAnswersSamplingAndElicitation(t *testing.T) {
+	c := New(t,
+		WithTools(askTool{}),
+		WithSampling(func(server.MCPSamplingRequest) (*server.MCPSamplingResult, error) {
+			return &server.MCPSamplingResult{Role: "assistant", Content: server.MCPContent{Type: "text", Text: "ship it"}, Model: "fake"}, nil
+		}),
+		WithElicitation(func(req server.MCPElicitationRequest) (*server.MCPElicitationResult, error) {
+			if req.Message != "ship it" {
+				return &server.MCPElicitationResult{Action: "decline"}, nil
+			}
+			return &server.MCPElicitationResult{Action: "accept"}, nil
+		}),
+	)
+	if text := c.CallToolText("ask", nil); text != "accept: ship it" {
+		t.Errorf("unexpected result %q", text)
+	}
+}
+
+func TestNewFromServer(t *testing.T) {
+	srv, err := server.NewServer(server.WithMCPSupport("blog", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterMCPToolInNamespace(greetTool(), "blog"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewFromServer(t, srv)
+	if text := c.CallToolText("mcp__blog__greet", map[string]interface{}{"name": "Bo"}); text != "Hello, Bo" {
+		t.Errorf("unexpected result %q", text)
+	}
+}
+
+func TestWithRoots(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"docs", "private"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := server.NewListDirectoryTool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(t, WithTools(list), WithRoots(filepath.Join(dir, "docs")))
+
+	c.CallToolText("list_directory", map[string]interface{}{"path": "docs"})
+	c.CallToolError("list_directory", map[string]interface{}{"path": "private"})
+}
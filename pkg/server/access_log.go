@@ -0,0 +1,347 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const accessLogKey contextKey = "accessLog"
+
+// AccessLogFormat selects how RequestLoggerMiddleware renders access log entries.
+type AccessLogFormat string
+
+const (
+	// AccessLogText logs key=value records through slog (the default).
+	AccessLogText AccessLogFormat = "text"
+	// AccessLogJSON writes one JSON object per request.
+	AccessLogJSON AccessLogFormat = "json"
+	// AccessLogCommon writes NCSA Common Log Format lines.
+	AccessLogCommon AccessLogFormat = "common"
+	// AccessLogCombined writes Combined Log Format lines (Common plus referer and user agent).
+	AccessLogCombined AccessLogFormat = "combined"
+	// AccessLogTemplate renders AccessLogConfig.Template for each request.
+	AccessLogTemplate AccessLogFormat = "template"
+)
+
+// AccessLogField names a field that can be selected for text and JSON access logs.
+type AccessLogField string
+
+const (
+	AccessLogFieldRemoteAddr  AccessLogField = "remote_addr"
+	AccessLogFieldMethod      AccessLogField = "method"
+	AccessLogFieldURL         AccessLogField = "url"
+	AccessLogFieldProto       AccessLogField = "proto"
+	AccessLogFieldRoute       AccessLogField = "route"
+	AccessLogFieldStatus      AccessLogField = "status"
+	AccessLogFieldBytes       AccessLogField = "bytes"
+	AccessLogFieldLatency     AccessLogField = "latency"
+	AccessLogFieldUserAgent   AccessLogField = "user_agent"
+	AccessLogFieldReferer     AccessLogField = "referer"
+	AccessLogFieldRequestID   AccessLogField = "request_id"
+	AccessLogFieldUser        AccessLogField = "user"
+	AccessLogFieldAnnotations AccessLogField = "annotations"
+)
+
+// defaultAccessLogFields are logged when AccessLogConfig.Fields is empty.
+var defaultAccessLogFields = []AccessLogField{
+	AccessLogFieldRemoteAddr, AccessLogFieldMethod, AccessLogFieldURL, AccessLogFieldRoute,
+	AccessLogFieldRequestID, AccessLogFieldStatus, AccessLogFieldBytes, AccessLogFieldLatency,
+	AccessLogFieldAnnotations,
+}
+
+// AccessLogConfig configures the access log written by RequestLoggerMiddleware.
+type AccessLogConfig struct {
+	Format AccessLogFormat `json:"format,omitempty"`
+	// Fields selects the fields for text and JSON formats. Defaults to remote address, method,
+	// URL, route, request ID, status, bytes, latency, and annotations.
+	Fields []AccessLogField `json:"fields,omitempty"`
+	// Template is a text/template rendered with an AccessLogEntry for AccessLogTemplate.
+	Template string `json:"template,omitempty"`
+	// Output receives the access log, separately from application logs. Defaults to the
+	// application logger for AccessLogText and to stdout for the other formats.
+	Output io.Writer `json:"-"`
+	// File writes the access log to a size- and age-rotated file when Output is not set.
+	File *RotatingFileConfig `json:"file,omitempty"`
+	// UserFunc identifies the user for the "user" field. Defaults to the basic auth username.
+	UserFunc func(r *http.Request) string `json:"-"`
+}
+
+// AccessLogEntry holds the data of one access log record; it is the data passed to
+// custom access log templates.
+type AccessLogEntry struct {
+	Time        time.Time
+	RemoteAddr  string
+	Method      string
+	URL         string
+	Proto       string
+	Route       string
+	Status      int
+	Bytes       int
+	Latency     time.Duration
+	UserAgent   string
+	Referer     string
+	RequestID   string
+	User        string
+	Annotations map[string]any
+}
+
+// accessLogger is the compiled form of an AccessLogConfig.
+type accessLogger struct {
+	format   AccessLogFormat
+	fields   []AccessLogField
+	tmpl     *template.Template
+	userFunc func(r *http.Request) string
+	slog     *slog.Logger // for AccessLogText with a custom output
+	mu       sync.Mutex   // serializes writes to out
+	out      io.Writer
+	file     *RotatingFile // set when the access log owns its output file
+}
+
+func newAccessLogger(cfg AccessLogConfig) (*accessLogger, error) {
+	al := &accessLogger{
+		format:   cfg.Format,
+		fields:   cfg.Fields,
+		userFunc: cfg.UserFunc,
+		out:      cfg.Output,
+	}
+	if al.format == "" {
+		al.format = AccessLogText
+	}
+	if len(al.fields) == 0 {
+		al.fields = defaultAccessLogFields
+	}
+	for _, f := range al.fields {
+		if !knownAccessLogField(f) {
+			return nil, fmt.Errorf("unknown access log field %q", f)
+		}
+	}
+
+	switch al.format {
+	case AccessLogText, AccessLogJSON, AccessLogCommon, AccessLogCombined:
+	case AccessLogTemplate:
+		if cfg.Template == "" {
+			return nil, fmt.Errorf("access log template format requires a template")
+		}
+		tmpl, err := template.New("access_log").Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access log template: %w", err)
+		}
+		al.tmpl = tmpl
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", al.format)
+	}
+
+	if al.out == nil && cfg.File != nil {
+		file, err := NewRotatingFile(*cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		al.file = file
+		al.out = file
+	}
+	if al.format == AccessLogText && al.out != nil {
+		al.slog = slog.New(slog.NewTextHandler(al.out, nil))
+	}
+	if al.out == nil {
+		al.out = os.Stdout
+	}
+	return al, nil
+}
+
+// close closes the access log file, if the access log owns one.
+func (al *accessLogger) close() error {
+	if al.file == nil {
+		return nil
+	}
+	return al.file.Close()
+}
+
+func knownAccessLogField(f AccessLogField) bool {
+	switch f {
+	case AccessLogFieldRemoteAddr, AccessLogFieldMethod, AccessLogFieldURL, AccessLogFieldProto,
+		AccessLogFieldRoute, AccessLogFieldStatus, AccessLogFieldBytes, AccessLogFieldLatency,
+		AccessLogFieldUserAgent, AccessLogFieldReferer, AccessLogFieldRequestID, AccessLogFieldUser,
+		AccessLogFieldAnnotations:
+		return true
+	}
+	return false
+}
+
+// entry collects the access log data for a completed request, with sensitive query
+// parameters and annotations redacted.
+func (al *accessLogger) entry(r *http.Request, start time.Time, status, size int) AccessLogEntry {
+	requestID, _ := r.Context().Value(traceIDKey).(string)
+	if requestID == "" {
+		requestID = r.Header.Get("X-Request-ID")
+	}
+	user := ""
+	if al.userFunc != nil {
+		user = al.userFunc(r)
+	} else if name, _, ok := r.BasicAuth(); ok {
+		user = name
+	}
+	rd := requestRedactor(r)
+	return AccessLogEntry{
+		Time:        start,
+		RemoteAddr:  clientIP(r),
+		Method:      r.Method,
+		URL:         rd.url(r.URL.String()),
+		Proto:       r.Proto,
+		Route:       RoutePattern(r),
+		Status:      status,
+		Bytes:       size,
+		Latency:     time.Since(start),
+		UserAgent:   r.UserAgent(),
+		Referer:     rd.url(r.Referer()),
+		RequestID:   requestID,
+		User:        user,
+		Annotations: rd.fieldValues(Annotations(r)),
+	}
+}
+
+func (e *AccessLogEntry) field(f AccessLogField) any {
+	switch f {
+	case AccessLogFieldRemoteAddr:
+		return e.RemoteAddr
+	case AccessLogFieldMethod:
+		return e.Method
+	case AccessLogFieldURL:
+		return e.URL
+	case AccessLogFieldProto:
+		return e.Proto
+	case AccessLogFieldRoute:
+		return e.Route
+	case AccessLogFieldStatus:
+		return e.Status
+	case AccessLogFieldBytes:
+		return e.Bytes
+	case AccessLogFieldLatency:
+		return e.Latency
+	case AccessLogFieldUserAgent:
+		return e.UserAgent
+	case AccessLogFieldReferer:
+		return e.Referer
+	case AccessLogFieldRequestID:
+		return e.RequestID
+	case AccessLogFieldUser:
+		return e.User
+	case AccessLogFieldAnnotations:
+		return e.Annotations
+	}
+	return nil
+}
+
+// write renders the entry in the configured format.
+func (al *accessLogger) write(e AccessLogEntry) {
+	var buf bytes.Buffer
+	switch al.format {
+	case AccessLogText:
+		args := make([]any, 0, len(al.fields))
+		for _, f := range al.fields {
+			if f == AccessLogFieldAnnotations {
+				if len(e.Annotations) > 0 {
+					args = append(args, slog.Any(string(f), e.Annotations))
+				}
+				continue
+			}
+			args = append(args, slog.Any(string(f), e.field(f)))
+		}
+		target := al.slog
+		if target == nil {
+			target = logger
+		}
+		target.Info("Request completed", args...)
+		return
+
+	case AccessLogJSON:
+		record := make(map[string]any, len(al.fields)+1)
+		record["time"] = e.Time.Format(time.RFC3339Nano)
+		for _, f := range al.fields {
+			v := e.field(f)
+			if f == AccessLogFieldLatency {
+				v = e.Latency.Microseconds()
+				f = "latency_us"
+			}
+			if f == AccessLogFieldAnnotations && len(e.Annotations) == 0 {
+				continue
+			}
+			record[string(f)] = v
+		}
+		if err := json.NewEncoder(&buf).Encode(record); err != nil {
+			logger.Error("Failed to encode access log entry", "error", err)
+			return
+		}
+
+	case AccessLogCommon, AccessLogCombined:
+		user := e.User
+		if user == "" {
+			user = "-"
+		}
+		size := "-"
+		if e.Bytes > 0 {
+			size = strconv.Itoa(e.Bytes)
+		}
+		fmt.Fprintf(&buf, "%s - %s [%s] %q %d %s", e.RemoteAddr, user,
+			e.Time.Format("02/Jan/2006:15:04:05 -0700"), e.Method+" "+e.URL+" "+e.Proto, e.Status, size)
+		if al.format == AccessLogCombined {
+			fmt.Fprintf(&buf, " %q %q", e.Referer, e.UserAgent)
+		}
+		buf.WriteByte('\n')
+
+	case AccessLogTemplate:
+		if err := al.tmpl.Execute(&buf, &e); err != nil {
+			logger.Error("Failed to render access log template", "error", err)
+			return
+		}
+		if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if _, err := al.out.Write(buf.Bytes()); err != nil {
+		logger.Error("Failed to write access log entry", "error", err)
+	}
+}
+
+// WithAccessLog configures the format, fields, and destination of the access log written
+// by RequestLoggerMiddleware. Returns an error for unknown formats or fields, or an
+// invalid template.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithAccessLog(server.AccessLogConfig{
+//			Format: server.AccessLogJSON,
+//			Fields: []server.AccessLogField{"method", "url", "status", "latency", "user_agent"},
+//			Output: accessLogFile,
+//		}),
+//	)
+func WithAccessLog(cfg AccessLogConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		al, err := newAccessLogger(cfg)
+		if err != nil {
+			return err
+		}
+		srv.Options.AccessLog = &cfg
+		srv.middleware.accessLog = al
+		return nil
+	}
+}
+
+// withAccessLogger attaches the configured access logger to the request context so that
+// RequestLoggerMiddleware can use it.
+func withAccessLogger(r *http.Request, al *accessLogger) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), accessLogKey, al))
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveWithAccessLog(t *testing.T, cfg AccessLogConfig, req *http.Request) string {
+	t.Helper()
+	var buf bytes.Buffer
+	cfg.Output = &buf
+
+	srv, err := NewServer(WithAccessLog(cfg))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r, "item", r.PathValue("id"))
+		w.Write([]byte("hello"))
+	})
+
+	rec := httptest.NewRecorder()
+	srv.middleware.applyToMux(srv.mux).ServeHTTP(rec, req)
+	return buf.String()
+}
+
+func TestAccessLogJSONFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Request-ID", "req-1")
+	req.SetBasicAuth("alice", "secret")
+
+	out := serveWithAccessLog(t, AccessLogConfig{
+		Format: AccessLogJSON,
+		Fields: []AccessLogField{AccessLogFieldMethod, AccessLogFieldStatus, AccessLogFieldBytes,
+			AccessLogFieldLatency, AccessLogFieldUserAgent, AccessLogFieldRequestID, AccessLogFieldUser,
+			AccessLogFieldRoute, AccessLogFieldAnnotations},
+	}, req)
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(out), &record); err != nil {
+		t.Fatalf("access log is not JSON: %v (%q)", err, out)
+	}
+	want := map[string]any{
+		"method":     "GET",
+		"status":     float64(200),
+		"bytes":      float64(5),
+		"user_agent": "test-agent",
+		"request_id": "req-1",
+		"user":       "alice",
+		"route":      "/items/{id}",
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("%s = %v, want %v", k, record[k], v)
+		}
+	}
+	if _, ok := record["latency_us"]; !ok {
+		t.Error("latency_us missing")
+	}
+	if _, ok := record["url"]; ok {
+		t.Error("unselected field url was logged")
+	}
+	if ann, _ := record["annotations"].(map[string]any); ann["item"] != "42" {
+		t.Errorf("annotations = %v", record["annotations"])
+	}
+	if strings.Contains(out, "secret") {
+		t.Error("access log contains the password")
+	}
+}
+
+func TestAccessLogCommonAndCombined(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items/7?x=1", nil)
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", "curl/8")
+
+	common := serveWithAccessLog(t, AccessLogConfig{Format: AccessLogCommon}, req)
+	if !strings.HasPrefix(common, "192.0.2.1 - - [") || !strings.HasSuffix(common, `] "GET /items/7?x=1 HTTP/1.1" 200 5`+"\n") {
+		t.Errorf("unexpected common log line: %q", common)
+	}
+
+	combined := serveWithAccessLog(t, AccessLogConfig{Format: AccessLogCombined}, req)
+	if !strings.HasSuffix(combined, `200 5 "https://example.com/" "curl/8"`+"\n") {
+		t.Errorf("unexpected combined log line: %q", combined)
+	}
+}
+
+func TestAccessLogTemplate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/items/1", nil)
+	out := serveWithAccessLog(t, AccessLogConfig{
+		Format:   AccessLogTemplate,
+		Template: "{{.Method}} {{.Route}} {{.Status}}",
+	}, req)
+	if out != "POST /items/{id} 200\n" {
+		t.Errorf("template output = %q", out)
+	}
+}
+
+func TestAccessLogTextOutput(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items/3", nil)
+	out := serveWithAccessLog(t, AccessLogConfig{
+		Fields: []AccessLogField{AccessLogFieldMethod, AccessLogFieldStatus},
+	}, req)
+	if !strings.Contains(out, `msg="Request completed" method=GET status=200`) {
+		t.Errorf("text output = %q", out)
+	}
+}
+
+func TestWithAccessLogRejectsInvalidConfig(t *testing.T) {
+	tests := []AccessLogConfig{
+		{Format: "xml"},
+		{Format: AccessLogJSON, Fields: []AccessLogField{"password"}},
+		{Format: AccessLogTemplate},
+		{Format: AccessLogTemplate, Template: "{{.Method"},
+	}
+	for _, cfg := range tests {
+		if _, err := NewServer(WithAccessLog(cfg)); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+const annotationsKey contextKey = "annotations"
+
+// requestAnnotations holds handler-supplied metadata for a single request.
+// Insertion order is kept so access log output is stable.
+type requestAnnotations struct {
+	mu     sync.Mutex
+	keys   []string
+	values map[string]any
+}
+
+func (a *requestAnnotations) set(key string, value any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.values == nil {
+		a.values = make(map[string]any)
+	}
+	if _, exists := a.values[key]; !exists {
+		a.keys = append(a.keys, key)
+	}
+	a.values[key] = value
+}
+
+func (a *requestAnnotations) snapshot() map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.values) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(a.values))
+	for k, v := range a.values {
+		out[k] = v
+	}
+	return out
+}
+
+// attrs returns the annotations as log attributes, with the values of sensitive keys
+// redacted.
+func (a *requestAnnotations) attrs(rd *redactor) []any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	attrs := make([]any, 0, len(a.keys))
+	for _, k := range a.keys {
+		if rd.sensitiveField(k) {
+			attrs = append(attrs, slog.String(k, redactedValue))
+			continue
+		}
+		attrs = append(attrs, slog.Any(k, a.values[k]))
+	}
+	return attrs
+}
+
+// withAnnotations ensures the request carries an annotation store.
+// Requests that already have one are returned unchanged.
+func withAnnotations(r *http.Request) *http.Request {
+	if annotationsFromContext(r.Context()) != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), annotationsKey, &requestAnnotations{}))
+}
+
+func annotationsFromContext(ctx context.Context) *requestAnnotations {
+	a, _ := ctx.Value(annotationsKey).(*requestAnnotations)
+	return a
+}
+
+// Annotate attaches metadata to the current request, such as a cache decision, tenant,
+// or shard. Annotations flow into the access log, request captures, and (for allowlisted
+// keys) metrics labels without the need to set artificial response headers.
+//
+// Requests served through Server always carry an annotation store; for requests that
+// do not (e.g. handlers invoked directly in tests), Annotate is a no-op.
+//
+// Example:
+//
+//	srv.HandleFunc("/api/items", func(w http.ResponseWriter, r *http.Request) {
+//		server.Annotate(r, "cache", "miss")
+//		server.Annotate(r, "tenant", tenantFrom(r))
+//		// ...
+//	})
+func Annotate(r *http.Request, key string, value any) {
+	if r == nil || key == "" {
+		return
+	}
+	if a := annotationsFromContext(r.Context()); a != nil {
+		a.set(key, value)
+	}
+}
+
+// Annotations returns a copy of the metadata attached to the request via Annotate.
+// Returns nil if the request has no annotations.
+func Annotations(r *http.Request) map[string]any {
+	if r == nil {
+		return nil
+	}
+	if a := annotationsFromContext(r.Context()); a != nil {
+		return a.snapshot()
+	}
+	return nil
+}
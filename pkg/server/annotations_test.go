@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnnotationsFlowIntoAccessLog(t *testing.T) {
+	var logBuffer bytes.Buffer
+	oldLogger := logger
+	logger = slog.New(slog.NewTextHandler(&logBuffer, nil))
+	defer func() { logger = oldLogger }()
+
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r, "cache", "miss")
+		Annotate(r, "tenant", "acme")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	logs := logBuffer.String()
+	if !strings.Contains(logs, "annotations.cache=miss") || !strings.Contains(logs, "annotations.tenant=acme") {
+		t.Errorf("expected annotations in access log, got: %s", logs)
+	}
+	if rec.Header().Get("cache") != "" {
+		t.Error("annotations must not be exposed as response headers")
+	}
+}
+
+func TestAnnotationsOverwriteAndCopy(t *testing.T) {
+	req := withAnnotations(httptest.NewRequest(http.MethodGet, "/", nil))
+	Annotate(req, "shard", 1)
+	Annotate(req, "shard", 2)
+
+	got := Annotations(req)
+	if got["shard"] != 2 {
+		t.Errorf("expected latest value to win, got %v", got["shard"])
+	}
+
+	got["shard"] = 99
+	if Annotations(req)["shard"] != 2 {
+		t.Error("Annotations should return a copy")
+	}
+}
+
+func TestAnnotateWithoutStoreIsNoop(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	Annotate(req, "cache", "hit")
+	if got := Annotations(req); got != nil {
+		t.Errorf("expected no annotations, got %v", got)
+	}
+}
+
+func TestAnnotationsRecordedInCapture(t *testing.T) {
+	debugger := &RequestDebuggerTool{}
+	handler := RequestCaptureMiddleware(debugger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r, "cache", "hit")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/cached", nil))
+
+	count := 0
+	debugger.captures.Range(func(_, value any) bool {
+		count++
+		captured := value.(*CapturedRequest)
+		if captured.Annotations["cache"] != "hit" {
+			t.Errorf("expected cache annotation in capture, got %v", captured.Annotations)
+		}
+		return true
+	})
+	if count != 1 {
+		t.Fatalf("expected 1 captured request, got %d", count)
+	}
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for APIUsageConfig.
+const (
+	defaultAPIUsageMaxKeys      = 1000
+	defaultAPIUsageTopEndpoints = 5
+)
+
+var defaultAPIUsageWindows = []time.Duration{5 * time.Minute, time.Hour, 24 * time.Hour}
+
+// APIUsageConfig configures per-key usage analytics. Keys are derived like rate limit keys,
+// so WithRateLimitKeyFunc(RateLimitByHeader("X-API-Key")) reports usage per API key, and
+// requests without a key are attributed to the client IP address. Keys are only stored
+// and reported in masked form, the first 8 hex characters of the SHA-256 of the full key,
+// such as "X-Api-Key:abc" for RateLimitByHeader.
+type APIUsageConfig struct {
+	Path         string           `json:"path,omitempty"`          // Serves the report to authenticated clients, empty disables
+	Windows      []time.Duration  `json:"windows,omitempty"`       // Rolling windows to report, at least a minute each (default 5m, 1h, 24h)
+	MaxKeys      int              `json:"max_keys,omitempty"`      // Distinct keys tracked; further keys are reported as MetricsOtherLabel (default 1000)
+	TopEndpoints int              `json:"top_endpoints,omitempty"` // Endpoints listed per key (default 5)
+	KeyFunc      RateLimitKeyFunc `json:"-"`                       // Overrides the rate limit key
+}
+
+func (c *APIUsageConfig) validate() error {
+	if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("API usage path must start with '/': %q", c.Path)
+	}
+	if len(c.Windows) == 0 {
+		c.Windows = defaultAPIUsageWindows
+	}
+	for _, w := range c.Windows {
+		if w < time.Minute {
+			return fmt.Errorf("API usage window must be at least a minute, got %v", w)
+		}
+	}
+	if c.MaxKeys == 0 {
+		c.MaxKeys = defaultAPIUsageMaxKeys
+	}
+	if c.TopEndpoints == 0 {
+		c.TopEndpoints = defaultAPIUsageTopEndpoints
+	}
+	if c.MaxKeys < 0 || c.TopEndpoints < 0 {
+		return fmt.Errorf("API usage limits must not be negative")
+	}
+	return nil
+}
+
+// WithAPIUsageAnalytics aggregates request counts, error rates, and top endpoints per API
+// key over rolling windows, so questions like "who is hammering /api/search" can be
+// answered without external analytics. Requests are recorded by MetricsMiddleware, which
+// is part of the default middleware. The report is available from Server.APIUsage, the
+// metrics://server/api-usage MCP resource, and, if cfg.Path is set, an endpoint that
+// requires a bearer token as validated by AuthMiddleware.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithRateLimitKeyFunc(server.RateLimitByHeader("X-API-Key")),
+//		server.WithAuthTokenValidator(validateAdminToken),
+//		server.WithAPIUsageAnalytics(server.APIUsageConfig{Path: "/admin/usage"}),
+//	)
+//	// curl -H "Authorization: Bearer $TOKEN" 'http://localhost:8080/admin/usage?window=1h'
+func WithAPIUsageAnalytics(cfg APIUsageConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.APIUsage = &cfg
+		return nil
+	}
+}
+
+// apiUsageBucket counts the requests of one minute.
+type apiUsageBucket struct {
+	minute       int64
+	requests     int64
+	clientErrors int64
+	serverErrors int64
+}
+
+// apiUsageSeries holds the buckets of one key and endpoint, oldest first.
+type apiUsageSeries struct {
+	buckets []apiUsageBucket
+}
+
+func (s *apiUsageSeries) add(minute int64, status int) {
+	if n := len(s.buckets); n == 0 || s.buckets[n-1].minute < minute {
+		s.buckets = append(s.buckets, apiUsageBucket{minute: minute})
+	}
+	b := &s.buckets[len(s.buckets)-1]
+	b.requests++
+	switch {
+	case status >= 500:
+		b.serverErrors++
+	case status >= 400:
+		b.clientErrors++
+	}
+}
+
+// prune drops buckets before minute and reports whether any remain.
+func (s *apiUsageSeries) prune(minute int64) bool {
+	i := 0
+	for i < len(s.buckets) && s.buckets[i].minute < minute {
+		i++
+	}
+	if i > 0 {
+		s.buckets = append(s.buckets[:0], s.buckets[i:]...)
+	}
+	return len(s.buckets) > 0
+}
+
+// sum totals the buckets from minute on.
+func (s *apiUsageSeries) sum(minute int64) apiUsageBucket {
+	var total apiUsageBucket
+	for i := len(s.buckets) - 1; i >= 0 && s.buckets[i].minute >= minute; i-- {
+		total.requests += s.buckets[i].requests
+		total.clientErrors += s.buckets[i].clientErrors
+		total.serverErrors += s.buckets[i].serverErrors
+	}
+	return total
+}
+
+// apiUsageStore records requests per key and endpoint in one-minute buckets spanning the
+// longest configured window.
+type apiUsageStore struct {
+	mu      sync.Mutex
+	cfg     APIUsageConfig
+	keys    map[string]map[string]*apiUsageSeries // key -> endpoint -> series
+	horizon int64                                 // Minutes kept, the longest window
+	now     func() time.Time
+}
+
+func newAPIUsageStore(cfg APIUsageConfig) *apiUsageStore {
+	s := &apiUsageStore{cfg: cfg, keys: make(map[string]map[string]*apiUsageSeries), now: time.Now}
+	for _, w := range cfg.Windows {
+		s.horizon = max(s.horizon, int64(w/time.Minute))
+	}
+	return s
+}
+
+func (s *apiUsageStore) record(key, endpoint string, status int) {
+	minute := s.now().Unix() / 60
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoints, ok := s.keys[key]
+	if !ok {
+		if len(s.keys) >= s.cfg.MaxKeys {
+			s.pruneLocked(minute)
+		}
+		if len(s.keys) >= s.cfg.MaxKeys {
+			key = MetricsOtherLabel
+		}
+		if endpoints, ok = s.keys[key]; !ok {
+			endpoints = make(map[string]*apiUsageSeries)
+			s.keys[key] = endpoints
+		}
+	}
+	series, ok := endpoints[endpoint]
+	if !ok {
+		series = &apiUsageSeries{}
+		endpoints[endpoint] = series
+	}
+	series.add(minute, status)
+	series.prune(minute - s.horizon + 1)
+}
+
+// pruneLocked removes keys and endpoints without requests in the longest window.
+func (s *apiUsageStore) pruneLocked(minute int64) {
+	for key, endpoints := range s.keys {
+		for endpoint, series := range endpoints {
+			if !series.prune(minute - s.horizon + 1) {
+				delete(endpoints, endpoint)
+			}
+		}
+		if len(endpoints) == 0 {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// report aggregates the usage of every key over window, busiest keys first.
+func (s *apiUsageStore) report(window time.Duration) []APIKeyUsage {
+	minute := s.now().Unix() / 60
+	since := minute - int64(window/time.Minute) + 1
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(minute)
+
+	keys := make([]APIKeyUsage, 0, len(s.keys))
+	for key, endpoints := range s.keys {
+		usage := APIKeyUsage{Key: key, TopEndpoints: []APIEndpointUsage{}}
+		for endpoint, series := range endpoints {
+			total := series.sum(since)
+			if total.requests == 0 {
+				continue
+			}
+			usage.Requests += total.requests
+			usage.ClientErrors += total.clientErrors
+			usage.ServerErrors += total.serverErrors
+			usage.TopEndpoints = append(usage.TopEndpoints, APIEndpointUsage{
+				Endpoint:     endpoint,
+				Requests:     total.requests,
+				ClientErrors: total.clientErrors,
+				ServerErrors: total.serverErrors,
+			})
+		}
+		if usage.Requests == 0 {
+			continue
+		}
+		usage.ErrorRate = float64(usage.ServerErrors) / float64(usage.Requests)
+		slices.SortFunc(usage.TopEndpoints, func(a, b APIEndpointUsage) int {
+			if c := cmp.Compare(b.Requests, a.Requests); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Endpoint, b.Endpoint)
+		})
+		if len(usage.TopEndpoints) > s.cfg.TopEndpoints {
+			usage.TopEndpoints = usage.TopEndpoints[:s.cfg.TopEndpoints]
+		}
+		keys = append(keys, usage)
+	}
+	slices.SortFunc(keys, func(a, b APIKeyUsage) int {
+		if c := cmp.Compare(b.Requests, a.Requests); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return keys
+}
+
+// APIKeyUsage is the usage of one API key over a window.
+type APIKeyUsage struct {
+	Key          string             `json:"key"` // Masked key, see APIUsageConfig
+	Requests     int64              `json:"requests"`
+	ClientErrors int64              `json:"client_errors"` // 4xx responses
+	ServerErrors int64              `json:"server_errors"` // 5xx responses
+	ErrorRate    float64            `json:"error_rate"`    // Fraction of 5xx responses
+	TopEndpoints []APIEndpointUsage `json:"top_endpoints"`
+}
+
+// APIEndpointUsage is the usage of one route pattern by an API key.
+type APIEndpointUsage struct {
+	Endpoint     string `json:"endpoint"`
+	Requests     int64  `json:"requests"`
+	ClientErrors int64  `json:"client_errors"`
+	ServerErrors int64  `json:"server_errors"`
+}
+
+// APIUsageWindow is the per-key usage over one rolling window.
+type APIUsageWindow struct {
+	Window string        `json:"window"`
+	Keys   []APIKeyUsage `json:"keys"`
+}
+
+// APIUsage returns the per-key usage for each configured window, busiest keys first, or
+// nil unless WithAPIUsageAnalytics is configured.
+func (srv *Server) APIUsage() []APIUsageWindow {
+	if srv.apiUsage == nil {
+		return nil
+	}
+	windows := make([]APIUsageWindow, 0, len(srv.apiUsage.cfg.Windows))
+	for _, w := range srv.apiUsage.cfg.Windows {
+		windows = append(windows, APIUsageWindow{Window: w.String(), Keys: srv.apiUsage.report(w)})
+	}
+	return windows
+}
+
+// recordAPIUsage attributes a finished request to its API key, which is masked so that
+// credentials are not kept in memory or exposed by the report.
+func (srv *Server) recordAPIUsage(r *http.Request, status int) {
+	key := ""
+	if fn := srv.apiUsage.cfg.KeyFunc; fn != nil {
+		key = fn(r)
+	}
+	if key == "" {
+		key = srv.rateLimitKey(r)
+	}
+	srv.apiUsage.record(maskKey(key), srv.routePatternFor(r), status)
+}
+
+// registerAPIUsage serves the report under Options.APIUsage.Path.
+func (srv *Server) registerAPIUsage() {
+	srv.Handle(srv.Options.APIUsage.Path, AuthMiddleware(srv.Options)(http.HandlerFunc(srv.apiUsageHandler)))
+	logger.Info("API usage endpoint enabled", "path", srv.Options.APIUsage.Path)
+}
+
+// apiUsageHandler reports all windows, or the one selected with ?window=1h. ?key= limits
+// the report to one masked key.
+func (srv *Server) apiUsageHandler(w http.ResponseWriter, r *http.Request) {
+	windows := srv.APIUsage()
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute || d > time.Duration(srv.apiUsage.horizon)*time.Minute {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("window must be a duration between 1m and %v", time.Duration(srv.apiUsage.horizon)*time.Minute))
+			return
+		}
+		windows = []APIUsageWindow{{Window: d.String(), Keys: srv.apiUsage.report(d)}}
+	}
+	if key := r.URL.Query().Get("key"); key != "" {
+		for i := range windows {
+			windows[i].Keys = slices.DeleteFunc(windows[i].Keys, func(u APIKeyUsage) bool { return u.Key != key })
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"windows":   windows,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// APIUsageResource exposes per-key usage analytics as an MCP resource.
+type APIUsageResource struct {
+	server *Server
+}
+
+// NewAPIUsageResource creates a new API usage resource.
+func NewAPIUsageResource(srv *Server) *APIUsageResource {
+	return &APIUsageResource{server: srv}
+}
+
+func (r *APIUsageResource) URI() string {
+	return "metrics://server/api-usage"
+}
+
+func (r *APIUsageResource) CacheTTL() time.Duration {
+	return 0
+}
+
+func (r *APIUsageResource) Name() string {
+	return "API Usage"
+}
+
+func (r *APIUsageResource) Description() string {
+	return "Request counts, error rates, and top endpoints per API key over rolling windows"
+}
+
+func (r *APIUsageResource) MimeType() string {
+	return "application/json"
+}
+
+func (r *APIUsageResource) Read() (interface{}, error) {
+	windows := r.server.APIUsage()
+	if windows == nil {
+		return nil, fmt.Errorf("API usage analytics are not enabled")
+	}
+	return map[string]interface{}{
+		"windows":   windows,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+func (r *APIUsageResource) List() ([]string, error) {
+	return []string{r.URI()}, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIUsageByKey(t *testing.T) {
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithRateLimitKeyFunc(RateLimitByHeader("X-API-Key")),
+		WithAuthTokenValidator(func(token string) (bool, error) { return token == "admin", nil }),
+		WithAPIUsageAnalytics(APIUsageConfig{Path: "/admin/usage", TopEndpoints: 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.HandleFunc("GET /api/search", func(w http.ResponseWriter, r *http.Request) {})
+	srv.HandleFunc("GET /api/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	handler := srv.Handler()
+	send := func(key, path string, n int) {
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("X-API-Key", key)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+	send("acme", "/api/search", 8)
+	send("acme", "/api/fail", 2)
+	send("globex", "/api/search", 3)
+
+	windows := srv.APIUsage()
+	if len(windows) != 3 {
+		t.Fatalf("expected the default windows, got %d", len(windows))
+	}
+	keys := windows[0].Keys
+	if len(keys) != 2 || keys[0].Key != maskKey("X-Api-Key:acme") || keys[0].Requests != 10 || keys[0].ServerErrors != 2 {
+		t.Fatalf("unexpected usage %+v", keys)
+	}
+	if keys[0].ErrorRate != 0.2 || len(keys[0].TopEndpoints) != 1 || keys[0].TopEndpoints[0].Endpoint != "GET /api/search" {
+		t.Errorf("unexpected breakdown %+v", keys[0])
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/usage?window=1h&key="+maskKey("X-Api-Key:globex"), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d, want 401", rec.Code)
+	}
+	req.Header.Set("Authorization", "Bearer admin")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var report struct {
+		Windows []APIUsageWindow `json:"windows"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report %s: %v", rec.Body.String(), err)
+	}
+	if len(report.Windows) != 1 || report.Windows[0].Window != "1h0m0s" || len(report.Windows[0].Keys) != 1 || report.Windows[0].Keys[0].Requests != 3 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if strings.Contains(rec.Body.String(), "globex") {
+		t.Errorf("report exposes the raw key: %s", rec.Body.String())
+	}
+}
+
+func TestAPIUsageRollingWindows(t *testing.T) {
+	cfg := APIUsageConfig{Windows: []time.Duration{time.Minute, 10 * time.Minute}, MaxKeys: 2}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	store := newAPIUsageStore(cfg)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.record("a", "GET /x", http.StatusOK)
+	store.record("b", "GET /x", http.StatusTooManyRequests)
+	store.record("c", "GET /x", http.StatusOK) // beyond MaxKeys
+	now = now.Add(5 * time.Minute)
+	store.record("a", "GET /x", http.StatusOK)
+
+	if usage := store.report(time.Minute); len(usage) != 1 || usage[0].Requests != 1 {
+		t.Errorf("one-minute window = %+v", usage)
+	}
+	usage := store.report(10 * time.Minute)
+	if len(usage) != 3 || usage[0].Key != "a" || usage[0].Requests != 2 {
+		t.Fatalf("ten-minute window = %+v", usage)
+	}
+	if usage[1].Key != MetricsOtherLabel || usage[2].Key != "b" || usage[2].ClientErrors != 1 {
+		t.Errorf("unexpected keys %+v", usage)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if usage := store.report(10 * time.Minute); len(usage) != 0 {
+		t.Errorf("expired usage reported: %+v", usage)
+	}
+	if len(store.keys) != 0 {
+		t.Errorf("expired keys were not pruned: %d", len(store.keys))
+	}
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStaticPrefix  = "/static/"
+	assetFingerprintLen  = 12
+	immutableCacheHeader = "public, max-age=31536000, immutable"
+)
+
+// assetFingerprintPattern matches the fingerprint inserted before a file extension.
+var assetFingerprintPattern = regexp.MustCompile(`\.[0-9a-f]{12}(\.[^./]+)?$`)
+
+// AssetEntry describes one fingerprinted static asset.
+type AssetEntry struct {
+	Path      string `json:"path"`      // Fingerprinted URL, e.g. /static/app.3f2a1b9c0d4e.css
+	Integrity string `json:"integrity"` // Subresource Integrity hash
+	Size      int64  `json:"size"`
+}
+
+// AssetManifest maps logical asset names (paths relative to StaticDir) to their
+// fingerprinted URLs. Version changes whenever any asset changes.
+type AssetManifest struct {
+	Version   string                `json:"version"`
+	Generated time.Time             `json:"generated"`
+	Assets    map[string]AssetEntry `json:"assets"`
+
+	hashed map[string]string // fingerprinted name -> logical name
+}
+
+// Lookup returns the manifest entry for a logical asset name such as "css/app.css".
+func (m *AssetManifest) Lookup(name string) (AssetEntry, bool) {
+	entry, ok := m.Assets[strings.TrimPrefix(name, "/")]
+	return entry, ok
+}
+
+// fingerprintedName inserts the fingerprint before the extension: app.css -> app.<hash>.css.
+func fingerprintedName(name, fingerprint string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + fingerprint + ext
+}
+
+// buildAssetManifest hashes every regular, non-hidden file in fsys.
+func buildAssetManifest(fsys fs.FS, prefix string) (*AssetManifest, error) {
+	m := &AssetManifest{
+		Generated: time.Now().UTC(),
+		Assets:    make(map[string]AssetEntry),
+		hashed:    make(map[string]string),
+	}
+	versionHash := sha512.New384()
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		h := sha512.New384()
+		size, err := io.Copy(h, file)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", name, err)
+		}
+		sum := h.Sum(nil)
+
+		hashedName := fingerprintedName(name, hex.EncodeToString(sum)[:assetFingerprintLen])
+		m.Assets[name] = AssetEntry{
+			Path:      prefix + hashedName,
+			Integrity: "sha384-" + base64.StdEncoding.EncodeToString(sum),
+			Size:      size,
+		}
+		m.hashed[hashedName] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(m.Assets))
+	for name := range m.Assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(versionHash, "%s %s\n", name, m.Assets[name].Integrity)
+	}
+	m.Version = hex.EncodeToString(versionHash.Sum(nil))[:16]
+	return m, nil
+}
+
+// staticFS returns the static directory as an fs.FS, preferring the secure os.Root.
+func (srv *Server) staticFS() (fs.FS, error) {
+	if srv.staticRoot != nil {
+		return srv.staticRoot.FS(), nil
+	}
+	if srv.Options.StaticDir == "" {
+		return nil, fmt.Errorf("no static directory configured")
+	}
+	return os.DirFS(srv.Options.StaticDir), nil
+}
+
+// AssetManifest returns the manifest of fingerprinted files in StaticDir, generating it on
+// first use. Fingerprinted URLs are served by HandleStatic with immutable cache headers.
+// In DebugMode the static directory is checked on every call and the manifest regenerated
+// when a file was added, removed, or modified; otherwise call RefreshAssetManifest after
+// changing static files.
+func (srv *Server) AssetManifest() (*AssetManifest, error) {
+	srv.assetsMu.Lock()
+	defer srv.assetsMu.Unlock()
+	if srv.assets != nil {
+		if srv.Options == nil || !srv.Options.current().DebugMode {
+			return srv.assets, nil
+		}
+		if fsys, err := srv.staticFS(); err == nil {
+			if stamp, err := assetStamp(fsys); err == nil && stamp == srv.assetsStamp {
+				return srv.assets, nil
+			}
+		}
+	}
+	return srv.buildAssetsLocked()
+}
+
+// RefreshAssetManifest regenerates the asset manifest after static files have changed.
+func (srv *Server) RefreshAssetManifest() (*AssetManifest, error) {
+	srv.assetsMu.Lock()
+	defer srv.assetsMu.Unlock()
+	return srv.buildAssetsLocked()
+}
+
+func (srv *Server) buildAssetsLocked() (*AssetManifest, error) {
+	fsys, err := srv.staticFS()
+	if err != nil {
+		return nil, err
+	}
+	prefix := srv.staticPrefix
+	if prefix == "" {
+		prefix = defaultStaticPrefix
+	}
+	// Stamp before hashing so that a change made while hashing triggers another rebuild
+	srv.assetsStamp, _ = assetStamp(fsys)
+	m, err := buildAssetManifest(fsys, EnsureTrailingSlash(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build asset manifest: %w", err)
+	}
+	srv.assets = m
+	logger.Debug("Asset manifest generated", "assets", len(m.Assets), "version", m.Version)
+	return m, nil
+}
+
+// assetStamp summarises the files that buildAssetManifest hashes so that changes can be
+// detected without hashing them again.
+func assetStamp(fsys fs.FS) (string, error) {
+	var b strings.Builder
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
+
+// AssetURL returns the fingerprinted URL of a file in StaticDir, e.g.
+// "/static/app.3f2a1b9c0d4e.css" for "app.css", which HandleStatic serves with immutable
+// cache headers. Templates call it as {{asset "app.css"}}. Files missing from the
+// manifest, and all files in DebugMode, where they change while the server runs, get
+// their plain URL under the static prefix.
+func (srv *Server) AssetURL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if !srv.Options.current().DebugMode {
+		if m, err := srv.AssetManifest(); err == nil {
+			if entry, ok := m.Lookup(name); ok {
+				return entry.Path
+			}
+		} else {
+			logger.Debug("Asset manifest unavailable", "asset", name, "error", err)
+		}
+	}
+	srv.assetsMu.Lock()
+	prefix := srv.staticPrefix
+	srv.assetsMu.Unlock()
+	if prefix == "" {
+		prefix = defaultStaticPrefix
+	}
+	return EnsureTrailingSlash(prefix) + name
+}
+
+// fingerprintedAssets resolves fingerprinted file names to the original files and marks
+// them immutable, since their URL changes whenever their content does.
+func (srv *Server) fingerprintedAssets(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if !assetFingerprintPattern.MatchString(name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		m, err := srv.AssetManifest()
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		original, ok := m.hashed[name]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = strings.TrimSuffix(r.URL.Path, name) + original
+		r2.URL.RawPath = ""
+		w.Header().Set("Cache-Control", immutableCacheHeader)
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// WithAssetManifest serves a JSON manifest of the fingerprinted files in StaticDir at route,
+// mapping logical names to hashed URLs and Subresource Integrity hashes for service
+// workers and build pipelines. The manifest is served with an ETag and must be
+// revalidated by clients. The path can also be set via "asset_manifest_path" in
+// options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithAssetManifest("/asset-manifest.json"))
+//	srv.HandleStatic("/static/")
+func WithAssetManifest(route string) ServerOptionFunc {
+	return func(srv *Server) error {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("asset manifest path must start with '/': %q", route)
+		}
+		srv.Options.AssetManifestPath = route
+		return nil
+	}
+}
+
+// assetManifestHandler serves the asset manifest with ETag revalidation.
+func (srv *Server) assetManifestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	m, err := srv.AssetManifest()
+	if err != nil {
+		logger.Error("Failed to serve asset manifest", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, "Asset manifest unavailable")
+		return
+	}
+
+	etag := `"` + m.Version + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logger.Error("Failed to encode asset manifest", "error", err)
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newAssetServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0o755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0o755)
+	os.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body{color:red}"), 0o644)
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".git", "config"), []byte("secret"), 0o644)
+
+	srv, err := NewServer(WithAssetManifest("/asset-manifest.json"))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.Options.StaticDir = dir
+	srv.HandleStatic("/assets/")
+	return srv
+}
+
+func TestAssetManifestEndpoint(t *testing.T) {
+	srv := newAssetServer(t)
+
+	rec := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/asset-manifest.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("expected manifest to require revalidation, got %q", rec.Header().Get("Cache-Control"))
+	}
+
+	var manifest AssetManifest
+	if err := json.Unmarshal(rec.Body.Bytes(), &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if len(manifest.Assets) != 2 {
+		t.Fatalf("expected 2 assets (hidden files skipped), got %v", manifest.Assets)
+	}
+	css := manifest.Assets["css/app.css"]
+	if !strings.HasPrefix(css.Path, "/assets/css/app.") || !strings.HasSuffix(css.Path, ".css") {
+		t.Errorf("unexpected fingerprinted path %q", css.Path)
+	}
+	sum := sha512.Sum384([]byte("body{color:red}"))
+	if want := "sha384-" + base64.StdEncoding.EncodeToString(sum[:]); css.Integrity != want {
+		t.Errorf("expected integrity %s, got %s", want, css.Integrity)
+	}
+
+	// Revalidation with the ETag
+	req := httptest.NewRequest(http.MethodGet, "/asset-manifest.json", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+}
+
+func TestFingerprintedAssetServing(t *testing.T) {
+	srv := newAssetServer(t)
+	manifest, err := srv.AssetManifest()
+	if err != nil {
+		t.Fatalf("AssetManifest: %v", err)
+	}
+	entry, ok := manifest.Lookup("/app.js")
+	if !ok {
+		t.Fatal("expected app.js in manifest")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, entry.Path, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log(1)" {
+		t.Fatalf("expected fingerprinted asset to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != immutableCacheHeader {
+		t.Errorf("expected immutable caching, got %q", rec.Header().Get("Cache-Control"))
+	}
+
+	// Plain names keep working without immutable caching
+	rec = httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "" {
+		t.Errorf("unexpected response for original name: %d %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+
+	// Unknown fingerprints are not found
+	rec = httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.000000000000.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown fingerprint, got %d", rec.Code)
+	}
+}
+
+func TestAssetURLTemplateFunc(t *testing.T) {
+	srv := newAssetServer(t)
+	manifest, err := srv.AssetManifest()
+	if err != nil {
+		t.Fatalf("AssetManifest: %v", err)
+	}
+	hashed := manifest.Assets["css/app.css"].Path
+
+	if got := srv.AssetURL("/css/app.css"); got != hashed {
+		t.Errorf("expected %s, got %s", hashed, got)
+	}
+	if got := srv.AssetURL("missing.css"); got != "/assets/missing.css" {
+		t.Errorf("expected plain URL for a file missing from the manifest, got %s", got)
+	}
+
+	srv.Options.TemplateDir = writeTemplates(t, map[string]string{
+		"page.html": `<link rel="stylesheet" href="{{asset "css/app.css"}}">`,
+	})
+	rec := httptest.NewRecorder()
+	if err := srv.RenderTemplate(rec, "page.html", nil); err != nil {
+		t.Fatalf("RenderTemplate: %v", err)
+	}
+	if want := `<link rel="stylesheet" href="` + hashed + `">`; rec.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, rec.Body.String())
+	}
+
+	// Files change while developing, so debug mode links to them directly
+	srv.Options.DebugMode = true
+	if got := srv.AssetURL("css/app.css"); got != "/assets/css/app.css" {
+		t.Errorf("expected plain URL in debug mode, got %s", got)
+	}
+}
+
+func TestAssetManifestReloadsInDebugMode(t *testing.T) {
+	srv := newAssetServer(t)
+	before, err := srv.AssetManifest()
+	if err != nil {
+		t.Fatalf("AssetManifest: %v", err)
+	}
+	file := filepath.Join(srv.Options.StaticDir, "app.js")
+	os.WriteFile(file, []byte("console.log(2)"), 0o644)
+	if m, _ := srv.AssetManifest(); m != before {
+		t.Error("expected the manifest to be kept outside debug mode")
+	}
+
+	srv.Options.DebugMode = true
+	after, err := srv.AssetManifest()
+	if err != nil {
+		t.Fatalf("AssetManifest: %v", err)
+	}
+	if after.Version == before.Version || after.Assets["app.js"].Path == before.Assets["app.js"].Path {
+		t.Fatal("expected the manifest to be regenerated after app.js changed")
+	}
+	if m, _ := srv.AssetManifest(); m != after {
+		t.Error("expected the manifest to be kept while no file changes")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, after.Assets["app.js"].Path, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log(2)" {
+		t.Errorf("expected the new fingerprint to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultBatchPath         = "/batch"
+	defaultBatchMaxRequests  = 20
+	defaultBatchConcurrency  = 4
+	defaultBatchMaxBodyBytes = 1 << 20
+)
+
+// BatchConfig configures the batch endpoint, which runs several sub-requests in one round
+// trip.
+type BatchConfig struct {
+	Path         string `json:"path,omitempty"`           // Endpoint path (default "/batch")
+	MaxRequests  int    `json:"max_requests,omitempty"`   // Sub-requests per batch (default 20)
+	Concurrency  int    `json:"concurrency,omitempty"`    // Sub-requests served in parallel (default 4)
+	MaxBodyBytes int64  `json:"max_body_bytes,omitempty"` // Size limit of the batch request body (default 1 MiB)
+}
+
+// BatchRequest is one sub-request of a batch. A JSON body is sent with Content-Type
+// application/json unless Headers set another type.
+type BatchRequest struct {
+	ID      string            `json:"id,omitempty"` // Echoed in the response
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path"` // Path and query, e.g. "/api/users?page=2"
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is the result of one sub-request. JSON bodies are embedded as JSON, other
+// bodies as strings.
+type BatchResponse struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// WithBatchEndpoint serves a batch endpoint that accepts a JSON array of sub-requests
+// (method, path, headers, body), serves them through the server's routes and middleware,
+// and returns the responses as a JSON array in the same order. Mobile clients and
+// assistants can use it to save round trips. Sub-requests inherit the headers of the
+// batch request, such as Authorization and cookies, so they are authorized like direct
+// requests. The endpoint can also be enabled via the "batch" key in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithBatchEndpoint(server.BatchConfig{MaxRequests: 10}))
+//
+//	// POST /batch
+//	// [{"id":"me","path":"/api/me"},{"id":"feed","path":"/api/feed?limit=5"}]
+func WithBatchEndpoint(cfg BatchConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		srv.Options.Batch = &cfg
+		return nil
+	}
+}
+
+func (cfg *BatchConfig) validate() error {
+	if cfg.Path != "" && !strings.HasPrefix(cfg.Path, "/") {
+		return fmt.Errorf("batch path must start with '/': %q", cfg.Path)
+	}
+	if cfg.MaxRequests < 0 || cfg.Concurrency < 0 || cfg.MaxBodyBytes < 0 {
+		return fmt.Errorf("batch limits must not be negative")
+	}
+	return nil
+}
+
+func (cfg *BatchConfig) path() string {
+	if cfg.Path == "" {
+		return defaultBatchPath
+	}
+	return cfg.Path
+}
+
+// batchHandler serves the batch endpoint.
+func (srv *Server) batchHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := srv.Options.Batch
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	maxBody, maxRequests, concurrency := cfg.MaxBodyBytes, cfg.MaxRequests, cfg.Concurrency
+	if maxBody == 0 {
+		maxBody = defaultBatchMaxBodyBytes
+	}
+	if maxRequests == 0 {
+		maxRequests = defaultBatchMaxRequests
+	}
+	if concurrency == 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	var requests []BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(&requests); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Batch must be a JSON array of requests")
+		return
+	}
+	if len(requests) > maxRequests {
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Batch exceeds %d requests", maxRequests))
+		return
+	}
+
+	handler := srv.middleware.applyToMux(srv.mux)
+	responses := make([]BatchResponse, len(requests))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, sub := range requests {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					// Panics escape the goroutine of the sub-request if RecoveryMiddleware is disabled
+					logger.Error("Batch sub-request panicked", "path", sub.Path, "panic", p)
+					responses[i] = BatchResponse{ID: sub.ID, Status: http.StatusInternalServerError}
+				}
+				<-sem
+				wg.Done()
+			}()
+			responses[i] = srv.serveBatchRequest(handler, r, sub)
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(responses); err != nil {
+		logger.Error("Failed to write batch response", "error", err)
+	}
+}
+
+// serveBatchRequest serves one sub-request of the batch request parent.
+func (srv *Server) serveBatchRequest(handler http.Handler, parent *http.Request, sub BatchRequest) BatchResponse {
+	fail := func(status int, message string) BatchResponse {
+		body, _ := json.Marshal(map[string]string{"error": message})
+		return BatchResponse{ID: sub.ID, Status: status, Body: body}
+	}
+	method := strings.ToUpper(sub.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !strings.HasPrefix(sub.Path, "/") || strings.HasPrefix(sub.Path, "//") {
+		return fail(http.StatusBadRequest, "Path must start with '/'")
+	}
+
+	req, err := http.NewRequestWithContext(batchContext{parent.Context()}, method, sub.Path, bytes.NewReader(sub.Body))
+	if err != nil {
+		return fail(http.StatusBadRequest, "Invalid request")
+	}
+	if req.URL.Path == srv.Options.Batch.path() {
+		return fail(http.StatusBadRequest, "Batches cannot be nested")
+	}
+	req.Host, req.RemoteAddr, req.TLS, req.Proto = parent.Host, parent.RemoteAddr, parent.TLS, parent.Proto
+	for k, v := range parent.Header {
+		switch k {
+		case "Content-Length", "Content-Type", "Content-Encoding", "Accept":
+		default:
+			req.Header[k] = v
+		}
+	}
+	if len(sub.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+
+	rec := &batchResponseWriter{header: make(http.Header), status: http.StatusOK}
+	handler.ServeHTTP(rec, req)
+
+	resp := BatchResponse{ID: sub.ID, Status: rec.status, Headers: make(map[string]string, len(rec.header))}
+	for k := range rec.header {
+		resp.Headers[k] = rec.header.Get(k)
+	}
+	body := rec.body.Bytes()
+	switch {
+	case len(body) == 0:
+	case strings.HasPrefix(rec.header.Get("Content-Type"), "application/json") && json.Valid(body):
+		resp.Body = bytes.TrimSpace(body)
+	default:
+		resp.Body, _ = json.Marshal(string(body))
+	}
+	return resp
+}
+
+// batchContext carries the cancellation of the batch request but none of its values, so
+// that sub-requests get their own route pattern, annotations, and session.
+type batchContext struct {
+	context.Context
+}
+
+func (batchContext) Value(key any) any {
+	return nil
+}
+
+// batchResponseWriter records the response of a sub-request.
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if !w.wrote && status >= 200 {
+		w.status = status
+		w.wrote = true
+	}
+}
+
+func (w *batchResponseWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.body.Write(b)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serveBatch(t *testing.T, srv *Server, body string) (*httptest.ResponseRecorder, []BatchResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	var responses []BatchResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &responses); err != nil {
+			t.Fatalf("invalid batch response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, responses
+}
+
+func TestBatchEndpoint(t *testing.T) {
+	srv, err := NewServer(
+		WithBatchEndpoint(BatchConfig{}),
+		WithAuthTokenValidator(func(token string) (bool, error) { return token == "secret", nil }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AddMiddleware("/api/", AuthMiddleware(srv.Options))
+	srv.HandleFunc("GET /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": r.PathValue("id"), "route": RoutePattern(r)})
+	})
+	srv.HandleFunc("POST /api/echo", func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&v)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(v)
+	})
+	srv.HandleFunc("GET /text", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("plain")) })
+
+	rec, responses := serveBatch(t, srv, `[
+		{"id":"user","path":"/api/users/7"},
+		{"id":"echo","method":"POST","path":"/api/echo","body":{"n":1}},
+		{"id":"text","path":"/text"},
+		{"id":"anon","path":"/api/users/7","headers":{"Authorization":"Bearer wrong"}},
+		{"id":"nested","method":"POST","path":"/batch"},
+		{"id":"bad","path":"users"}
+	]`)
+	if rec.Code != http.StatusOK || len(responses) != 6 {
+		t.Fatalf("got %d with %d responses", rec.Code, len(responses))
+	}
+	want := []struct {
+		id     string
+		status int
+		body   string
+	}{
+		{"user", 200, `{"id":"7","route":"GET /api/users/{id}"}`},
+		{"echo", 201, `{"n":1}`},
+		{"text", 200, `"plain"`},
+		{"anon", 401, ""},
+		{"nested", 400, ""},
+		{"bad", 400, ""},
+	}
+	for i, w := range want {
+		got := responses[i]
+		if got.ID != w.id || got.Status != w.status || (w.body != "" && string(got.Body) != w.body) {
+			t.Errorf("response %d = {%s %d %s}, want {%s %d %s}", i, got.ID, got.Status, got.Body, w.id, w.status, w.body)
+		}
+	}
+}
+
+func TestBatchEndpointLimits(t *testing.T) {
+	srv, err := NewServer(WithBatchEndpoint(BatchConfig{MaxRequests: 4, Concurrency: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var running, peak atomic.Int32
+	srv.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+	})
+
+	_, responses := serveBatch(t, srv, `[{"path":"/slow"},{"path":"/slow"},{"path":"/slow"},{"path":"/slow"}]`)
+	if len(responses) != 4 {
+		t.Fatalf("expected 4 responses, got %d", len(responses))
+	}
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent sub-requests, got %d", peak.Load())
+	}
+
+	if rec, _ := serveBatch(t, srv, `[{"path":"/slow"},{"path":"/slow"},{"path":"/slow"},{"path":"/slow"},{"path":"/slow"}]`); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized batch: expected 413, got %d", rec.Code)
+	}
+	if rec, _ := serveBatch(t, srv, `{"path":"/slow"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("non-array batch: expected 400, got %d", rec.Code)
+	}
+}
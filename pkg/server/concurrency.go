@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// inFlightLimiter bounds concurrent executions for a single route.
+type inFlightLimiter struct {
+	slots chan struct{} // held while a handler runs
+	queue chan struct{} // held while a request waits for a slot
+}
+
+// MaxInFlight returns a middleware function that bounds the number of concurrent handler
+// executions per route pattern. Up to n requests run at once; up to queueSize further
+// requests wait for a free slot for at most timeout. Requests beyond the queue, or that
+// time out while queued, are shed with 503 Service Unavailable. A timeout of zero lets
+// queued requests wait until the client gives up.
+//
+// Unlike RateLimitMiddleware, which limits how often clients may call, MaxInFlight limits
+// how much work runs simultaneously, protecting expensive endpoints from overload.
+//
+// Example:
+//
+//	srv.AddMiddleware("/reports", server.MaxInFlight(4, 16, 2*time.Second))
+func MaxInFlight(n, queueSize int, timeout time.Duration) MiddlewareFunc {
+	if n <= 0 {
+		n = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	var mu sync.Mutex
+	limiters := make(map[string]*inFlightLimiter)
+	limiterFor := func(route string) *inFlightLimiter {
+		mu.Lock()
+		defer mu.Unlock()
+		l, ok := limiters[route]
+		if !ok {
+			l = &inFlightLimiter{
+				slots: make(chan struct{}, n),
+				queue: make(chan struct{}, queueSize),
+			}
+			limiters[route] = l
+		}
+		return l
+	}
+
+	shed := func(w http.ResponseWriter, r *http.Request, reason string) {
+		Annotate(r, "inflight", reason)
+		w.Header().Set("Retry-After", "1")
+		writeErrorResponse(w, http.StatusServiceUnavailable, "Server busy, try again later")
+	}
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			l := limiterFor(RoutePattern(r))
+
+			select {
+			case l.slots <- struct{}{}:
+			default:
+				// All slots busy: wait in the queue if there is room
+				select {
+				case l.queue <- struct{}{}:
+				default:
+					shed(w, r, "shed")
+					return
+				}
+
+				var timer <-chan time.Time
+				if timeout > 0 {
+					t := time.NewTimer(timeout)
+					defer t.Stop()
+					timer = t.C
+				}
+				select {
+				case l.slots <- struct{}{}:
+					<-l.queue
+				case <-timer:
+					<-l.queue
+					shed(w, r, "timeout")
+					return
+				case <-r.Context().Done():
+					<-l.queue
+					return
+				}
+			}
+			defer func() { <-l.slots }()
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
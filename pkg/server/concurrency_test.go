@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxInFlightQueuesAndSheds(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	handler := MaxInFlight(1, 1, time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	codes := make(chan int, 3)
+	var wg sync.WaitGroup
+	send := func() {
+		defer wg.Done()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes <- rec.Code
+	}
+
+	// First request occupies the slot
+	wg.Add(1)
+	go send()
+	<-started
+
+	// Second request waits in the queue
+	wg.Add(1)
+	go send()
+	time.Sleep(20 * time.Millisecond)
+
+	// Third request finds slot and queue full and is shed immediately
+	wg.Add(1)
+	go send()
+	if code := <-codes; code != http.StatusServiceUnavailable {
+		t.Fatalf("expected shed request to get 503, got %d", code)
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("expected queued and running requests to succeed, got %d", code)
+		}
+	}
+}
+
+func TestMaxInFlightQueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	handler := MaxInFlight(1, 5, 20*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 503 with Retry-After after queue timeout, got %d", rec.Code)
+	}
+}
+
+func TestMaxInFlightPerRoute(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+
+	srv.AddMiddleware("/jobs", MaxInFlight(1, 0, 0))
+	srv.HandleFunc("/jobs/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	srv.HandleFunc("/jobs/fast", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.Handler()
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/jobs/slow", nil))
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/fast", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected other route to have its own limit, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected busy route to shed load, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configSectionPattern matches valid names of application config sections.
+var configSectionPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// serverEnvVars are the environment variables of the server options, which the variables
+// of a config section must not shadow.
+var serverEnvVars = []string{
+	paramHardenedMode, paramMCPEnabled, paramMCPEndpoint, paramMCPServerName, paramMCPServerVersion,
+	paramMCPToolsEnabled, paramMCPResourcesEnabled, paramMCPFileToolRoot, paramMCPDev, paramMCPDevOverride,
+	paramMCPObservability, paramMCPTransport, paramMCPSuspended, paramMCPAdminPath, paramMCPAdminToken,
+	paramCSPWebWorkerSupport, paramCSPNonce, paramTLSMinVersion, paramTLSCipherSuites, paramTLSCurves,
+	paramRedactHeaders, paramRedactFields, paramCORSAllowedOrigins, paramCORSAllowCredentials,
+	paramCORSAllowedMethods, paramCORSAllowedHeaders, paramCORSExposeHeaders, paramCORSMaxAge,
+	paramLogLevel, paramDebugMode, paramSuppressBanner, paramProfile, paramBannerColor, paramPprofPath,
+	paramPprofToken, paramIntrospectURL, paramIntrospectClientID, paramIntrospectSecret,
+}
+
+// reservedConfigSection reports why name cannot be a config section: it is a key of the
+// server options in options.json, or its environment variables would share a prefix with
+// those of the server, like "mcp" with HS_MCP_ENABLED.
+func reservedConfigSection(name string) (string, bool) {
+	if name == "profiles" {
+		return "holds the configuration profiles", true
+	}
+	options := reflect.TypeOf(ServerOptions{})
+	for i := 0; i < options.NumField(); i++ {
+		if key, _, _ := strings.Cut(options.Field(i).Tag.Get("json"), ","); key == name {
+			return "is a server option", true
+		}
+	}
+	prefix := "HS_" + strings.ToUpper(name) + "_"
+	for _, variable := range serverEnvVars {
+		if strings.HasPrefix(variable, prefix) {
+			return "its environment variables would overlap " + variable, true
+		}
+	}
+	return "", false
+}
+
+// BindConfig populates cfg, a pointer to an application's own configuration struct, with
+// the same precedence as the server options: the values already in cfg are the defaults,
+// the section name of options.json overrides them, the section in the block of the active
+// profile (profiles.<profile>.<name>) overrides that, and HS_<NAME>_<FIELD> environment
+// variables override all of them. Fields are named by their JSON tag, so the field tagged
+// "posts_per_page" of the "blog" section is set by HS_BLOG_POSTS_PER_PAGE; nested structs
+// add their own name, as in HS_BLOG_DB_HOST. Secret references such as ${env:DB_PASSWORD}
+// are resolved in string fields, see WithSecretProvider.
+//
+// Environment values are parsed according to the field type: strings, booleans, numbers,
+// durations like "30s", comma-separated string slices, and types that implement
+// encoding.TextUnmarshaler. Sections are read when BindConfig is called and are not
+// updated by configuration reloads. Names of server options, such as "cors", and names
+// whose environment variables would overlap the server's, such as "mcp", are rejected.
+//
+// Example:
+//
+//	type BlogConfig struct {
+//		Title        string        `json:"title"`
+//		PostsPerPage int           `json:"posts_per_page"`
+//		CacheTTL     time.Duration `json:"cache_ttl"`
+//	}
+//
+//	blog := BlogConfig{Title: "My Blog", PostsPerPage: 10, CacheTTL: time.Minute}
+//	if err := srv.BindConfig("blog", &blog); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// With {"blog": {"title": "Notes"}} in options.json and HS_BLOG_POSTS_PER_PAGE=20, blog
+// holds the title "Notes", 20 posts per page, and the default cache TTL.
+func (srv *Server) BindConfig(name string, cfg interface{}) error {
+	if !configSectionPattern.MatchString(name) {
+		return fmt.Errorf("invalid config section name %q: use lowercase letters, digits, and underscores", name)
+	}
+	if reason, reserved := reservedConfigSection(name); reserved {
+		return fmt.Errorf("config section name %q is reserved: %s", name, reason)
+	}
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config section %q must be bound to a non-nil pointer to a struct, got %T", name, cfg)
+	}
+
+	data, err := os.ReadFile(paramFileName)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", paramFileName, err)
+	default:
+		var sections map[string]json.RawMessage
+		if err := json.Unmarshal(data, &sections); err != nil {
+			return fmt.Errorf("invalid %s: %w", paramFileName, err)
+		}
+		if section, ok := sections[name]; ok {
+			if err := json.Unmarshal(section, cfg); err != nil {
+				return fmt.Errorf("invalid config section %q in %s: %w", name, paramFileName, err)
+			}
+		}
+		if err := bindProfileConfigSection(sections, srv.Options.current().Profile, name, cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := applyConfigSectionEnv(v.Elem(), "HS_"+strings.ToUpper(name)); err != nil {
+		return fmt.Errorf("config section %q: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+	resolved, changed, err := srv.Options.resolveSecretsIn(ctx, v.Elem())
+	if err != nil {
+		return fmt.Errorf("config section %q: %w", name, err)
+	}
+	if changed {
+		v.Elem().Set(resolved)
+	}
+	logger.Debug("Config section bound", "section", name)
+	return nil
+}
+
+// bindProfileConfigSection applies the section name of the block of profile, if any.
+func bindProfileConfigSection(sections map[string]json.RawMessage, profile, name string, cfg interface{}) error {
+	raw, ok := sections["profiles"]
+	if !ok || profile == "" {
+		return nil
+	}
+	var profiles map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return fmt.Errorf("invalid profiles in %s: %w", paramFileName, err)
+	}
+	if section, ok := profiles[profile][name]; ok {
+		if err := json.Unmarshal(section, cfg); err != nil {
+			return fmt.Errorf("invalid config section %q of profile %q in %s: %w", name, profile, paramFileName, err)
+		}
+	}
+	return nil
+}
+
+// applyConfigSectionEnv sets the fields of the struct v from environment variables named
+// prefix_FIELD, recursing into nested structs.
+func applyConfigSectionEnv(v reflect.Value, prefix string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+		variable := prefix + "_" + strings.ToUpper(strings.ReplaceAll(tag, "-", "_"))
+		fv := v.Field(i)
+
+		if raw, ok := os.LookupEnv(variable); ok {
+			if err := setConfigFieldFromEnv(fv, raw); err != nil {
+				return fmt.Errorf("invalid %s: %w", variable, err)
+			}
+			logger.Debug("Config section field set from environment variable", "variable", variable)
+			continue
+		}
+		nested := fv
+		if nested.Kind() == reflect.Pointer && nested.Type().Elem().Kind() == reflect.Struct && !isTextUnmarshaler(nested) {
+			if nested.IsNil() {
+				// Only allocate the struct if the environment sets one of its fields
+				fresh := reflect.New(nested.Type().Elem())
+				if err := applyConfigSectionEnv(fresh.Elem(), variable); err != nil {
+					return err
+				}
+				if !fresh.Elem().IsZero() {
+					nested.Set(fresh)
+				}
+				continue
+			}
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && !isTextUnmarshaler(nested) {
+			if err := applyConfigSectionEnv(nested, variable); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTextUnmarshaler reports whether v, or a pointer to it, implements encoding.TextUnmarshaler.
+func isTextUnmarshaler(v reflect.Value) bool {
+	unmarshaler := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	return v.Type().Implements(unmarshaler) || reflect.PointerTo(v.Type()).Implements(unmarshaler)
+}
+
+// setConfigFieldFromEnv parses raw according to the type of v and stores it.
+func setConfigFieldFromEnv(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setConfigFieldFromEnv(v.Elem(), raw)
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(raw))
+		}
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		out := reflect.MakeSlice(v.Type(), 0, strings.Count(raw, ",")+1)
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = reflect.Append(out, reflect.ValueOf(part).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(out)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBindConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{
+		"burst": 20,
+		"blog": {"title": "Notes", "posts_per_page": 5, "db": {"host": "db.internal", "password": "${env:TEST_BLOG_DB_PASSWORD}"}}
+	}`)
+	t.Setenv("HS_BLOG_POSTS_PER_PAGE", "20")
+	t.Setenv("HS_BLOG_CACHE_TTL", "30s")
+	t.Setenv("HS_BLOG_TAGS", "go, web")
+	t.Setenv("HS_BLOG_DB_PORT", "5433")
+	t.Setenv("HS_BLOG_ADMIN_IP", "10.0.0.1")
+	t.Setenv("TEST_BLOG_DB_PASSWORD", "s3cret")
+
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	type dbConfig struct {
+		Host     string `json:"host"`
+		Port     int    `json:"port"`
+		Password string `json:"password"`
+	}
+	type blogConfig struct {
+		Title        string        `json:"title"`
+		PostsPerPage int           `json:"posts_per_page"`
+		CacheTTL     time.Duration `json:"cache_ttl"`
+		Drafts       bool          `json:"drafts"`
+		Tags         []string      `json:"tags"`
+		AdminIP      netip.Addr    `json:"admin_ip"`
+		DB           dbConfig      `json:"db"`
+	}
+	blog := blogConfig{Title: "My Blog", PostsPerPage: 10, Drafts: true, DB: dbConfig{Port: 5432}}
+	if err := srv.BindConfig("blog", &blog); err != nil {
+		t.Fatalf("BindConfig failed: %v", err)
+	}
+
+	want := blogConfig{
+		Title:        "Notes",
+		PostsPerPage: 20,
+		CacheTTL:     30 * time.Second,
+		Drafts:       true,
+		Tags:         []string{"go", "web"},
+		AdminIP:      netip.MustParseAddr("10.0.0.1"),
+		DB:           dbConfig{Host: "db.internal", Port: 5433, Password: "s3cret"},
+	}
+	if !reflect.DeepEqual(blog, want) {
+		t.Errorf("expected %+v, got %+v", want, blog)
+	}
+}
+
+func TestBindConfigErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	var cfg struct {
+		Limit int `json:"limit"`
+	}
+	if err := srv.BindConfig("Blog", &cfg); err == nil {
+		t.Error("expected an error for an invalid section name")
+	}
+	if err := srv.BindConfig("blog", cfg); err == nil {
+		t.Error("expected an error for a non-pointer")
+	}
+	for _, name := range []string{"cors", "mcp", "tls_cipher", "log", "profiles", "read_timeout"} {
+		if err := srv.BindConfig(name, &cfg); err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("%s: expected the section name to be reserved, got %v", name, err)
+		}
+	}
+	t.Setenv("HS_BLOG_LIMIT", "lots")
+	if err := srv.BindConfig("blog", &cfg); err == nil || !strings.Contains(err.Error(), "HS_BLOG_LIMIT") {
+		t.Errorf("expected the error to name the variable, got %v", err)
+	}
+}
+
+func TestBindConfigProfile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{
+		"profile": "staging",
+		"blog": {"title": "Notes", "posts_per_page": 5},
+		"profiles": {
+			"staging": {"blog": {"posts_per_page": 50}},
+			"production": {"blog": {"title": "Production"}}
+		}
+	}`)
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	var blog struct {
+		Title        string `json:"title"`
+		PostsPerPage int    `json:"posts_per_page"`
+	}
+	if err := srv.BindConfig("blog", &blog); err != nil {
+		t.Fatalf("BindConfig failed: %v", err)
+	}
+	if blog.Title != "Notes" || blog.PostsPerPage != 50 {
+		t.Errorf("expected the staging block to override the section, got %+v", blog)
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigChange describes a configuration field whose value differs between the running
+// server and what a configuration reload would apply.
+type ConfigChange struct {
+	Field           string      `json:"field"`
+	Current         interface{} `json:"current"`
+	Reloaded        interface{} `json:"reloaded"`
+	RequiresRestart bool        `json:"requires_restart"`
+}
+
+// reloadableConfigFields lists the options (by JSON name) that are read per request or
+// applied in place, so a reload takes effect without restarting listeners.
+var reloadableConfigFields = map[string]bool{
+	"rate_limit":           true,
+	"burst":                true,
+	"rate_limit_overrides": true,
+	"chaos_mode":           true,
+	"chaos_max_latency":    true,
+	"chaos_min_latency":    true,
+	"chaos_error_rate":     true,
+	"chaos_throttle_rate":  true,
+	"chaos_panic_rate":     true,
+	"log_level":            true,
+	"debug_mode":           true,
+	"metrics_labels":       true,
+	"route_windows":        true,
+	"mcp_discovery_policy": true,
+	"cors":                 true,
+	"maintenance_mode":     true,
+	"security_headers":     true,
+}
+
+// redactedConfigFields are reported as changed without revealing their values,
+// matching what the configuration resources expose. Options resolved from secret
+// references are redacted as well.
+var redactedConfigFields = map[string]bool{
+	"key_file":           true,
+	"cert_file":          true,
+	"static_dir":         true,
+	"template_dir":       true,
+	"mcp_file_tool_root": true,
+}
+
+// PendingConfigChanges returns the differences between the effective configuration and
+// the configuration a reload would produce from the current options file and environment.
+// Options set programmatically are kept unless the file or environment overrides them.
+func (srv *Server) PendingConfigChanges() []ConfigChange {
+	candidate, err := srv.reloadCandidate()
+	if err != nil {
+		logger.Warn("Cannot preview configuration changes", "error", err)
+		return make([]ConfigChange, 0)
+	}
+	return diffServerOptions(srv.Options.current(), candidate)
+}
+
+// diffServerOptions compares the serializable fields of two option sets.
+func diffServerOptions(current, next *ServerOptions) []ConfigChange {
+	currentValue := reflect.ValueOf(current).Elem()
+	nextValue := reflect.ValueOf(next).Elem()
+	optionsType := currentValue.Type()
+
+	changes := make([]ConfigChange, 0)
+	for i := 0; i < optionsType.NumField(); i++ {
+		field := optionsType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || name == "" || field.Type.Kind() == reflect.Func {
+			continue
+		}
+
+		a := currentValue.Field(i).Interface()
+		b := nextValue.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+
+		change := ConfigChange{
+			Field:           name,
+			Current:         displayConfigValue(a),
+			Reloaded:        displayConfigValue(b),
+			RequiresRestart: !reloadableConfigFields[name],
+		}
+		if redactedConfigFields[name] || current.secretFields[name] || next.secretFields[name] {
+			change.Current, change.Reloaded = redactedValue, redactedValue
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// displayConfigValue renders durations readably; other values are returned unchanged.
+func displayConfigValue(v interface{}) interface{} {
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+	return v
+}
+
+// ConfigDiffTool shows what a configuration reload would change before it is triggered.
+type ConfigDiffTool struct {
+	server *Server
+}
+
+// NewConfigDiffTool creates a new configuration diff tool.
+func NewConfigDiffTool(srv *Server) *ConfigDiffTool {
+	return &ConfigDiffTool{server: srv}
+}
+
+func (t *ConfigDiffTool) Name() string {
+	return "config_diff"
+}
+
+func (t *ConfigDiffTool) Description() string {
+	return "Show the differences between the running configuration and what a reload would apply from " +
+		paramFileName + " and environment variables, including which changes require a restart"
+}
+
+func (t *ConfigDiffTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *ConfigDiffTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.server == nil {
+		return nil, fmt.Errorf("server not initialized")
+	}
+
+	changes := t.server.PendingConfigChanges()
+	requiresRestart := false
+	for _, c := range changes {
+		if c.RequiresRestart {
+			requiresRestart = true
+			break
+		}
+	}
+
+	return map[string]interface{}{
+		"changes":          changes,
+		"change_count":     len(changes),
+		"requires_restart": requiresRestart,
+		"sources":          []string{paramFileName, "environment"},
+		"timestamp":        time.Now().Format(time.RFC3339),
+	}, nil
+}
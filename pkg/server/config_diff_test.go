@@ -0,0 +1,73 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPendingConfigChanges(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if changes := srv.PendingConfigChanges(); len(changes) != 0 {
+		t.Fatalf("expected no pending changes, got %+v", changes)
+	}
+
+	config := `{"addr": ":9999", "burst": 42, "cert_file": "/secret/server.crt", "mcp_server_name": "${env:TEST_DIFF_SECRET}"}`
+	if err := os.WriteFile(filepath.Join(".", paramFileName), []byte(config), 0o600); err != nil {
+		t.Fatalf("failed to write options file: %v", err)
+	}
+	t.Setenv(paramLogLevel, "DEBUG")
+	t.Setenv("TEST_DIFF_SECRET", "hunter2")
+
+	changes := map[string]ConfigChange{}
+	for _, c := range srv.PendingConfigChanges() {
+		changes[c.Field] = c
+	}
+
+	if c, ok := changes["addr"]; !ok || !c.RequiresRestart || c.Reloaded != ":9999" {
+		t.Errorf("expected addr change requiring restart, got %+v", c)
+	}
+	if c, ok := changes["burst"]; !ok || c.RequiresRestart || c.Reloaded != 42 {
+		t.Errorf("expected reloadable burst change, got %+v", c)
+	}
+	if c, ok := changes["log_level"]; !ok || c.RequiresRestart || c.Reloaded != "DEBUG" {
+		t.Errorf("expected log level change from environment, got %+v", c)
+	}
+	if c := changes["cert_file"]; c.Reloaded != "[redacted]" {
+		t.Errorf("expected cert file path to be redacted, got %+v", c)
+	}
+	if c := changes["mcp_server_name"]; c.Reloaded != redactedValue {
+		t.Errorf("expected the resolved secret to be redacted, got %+v", c)
+	}
+
+	// The running configuration is untouched
+	if srv.Options.Burst == 42 {
+		t.Error("PendingConfigChanges must not modify the running options")
+	}
+}
+
+func TestConfigDiffTool(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("test", "1.0.0", MCPObservability()))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if _, ok := srv.mcpHandler.tools["mcp__hyperserve__config_diff"]; !ok {
+		t.Fatal("expected config_diff tool to be registered in observability mode")
+	}
+
+	result, err := NewConfigDiffTool(srv).Execute(nil)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	data := result.(map[string]interface{})
+	if data["change_count"] != 0 || data["requires_restart"] != false {
+		t.Errorf("expected no changes, got %v", data)
+	}
+}
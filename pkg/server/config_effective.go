@@ -0,0 +1,51 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ConfigValue is an option of the effective configuration together with where it was set.
+type ConfigValue struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"` // ConfigSourceDefault, ConfigSourceFile, ConfigSourceEnv, or ConfigSourceProgrammatic
+}
+
+// EffectiveConfig returns the configuration the server runs with, keyed by the JSON name
+// of each option, after defaults, options.json, environment variables, ServerOptionFuncs,
+// and configuration reloads have been applied. Each value is annotated with the source
+// that set it, which helps to find out why an option does not have the expected value.
+//
+// Secrets are redacted: options whose value came from a secret reference, file paths
+// such as key_file, and nested keys that look like credentials, e.g. client_secret.
+// Options that cannot be serialized, such as hooks and providers, are left out.
+//
+// Example:
+//
+//	out, _ := json.MarshalIndent(srv.EffectiveConfig(), "", "  ")
+//	fmt.Println(string(out))
+func (srv *Server) EffectiveConfig() map[string]ConfigValue {
+	srv.configMu.Lock()
+	defer srv.configMu.Unlock()
+
+	o := srv.Options.current()
+	value := reflect.ValueOf(o).Elem()
+	config := make(map[string]ConfigValue, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || name == "" || field.Type.Kind() == reflect.Func {
+			continue
+		}
+		v := displayConfigValue(value.Field(i).Interface())
+		switch field.Type.Kind() {
+		case reflect.Pointer, reflect.Struct, reflect.Map, reflect.Slice:
+			v = srv.redactor.value(v) // Nested credentials such as client_secret
+		}
+		if o.secretFields[name] || (redactedConfigFields[name] && !value.Field(i).IsZero()) {
+			v = redactedValue
+		}
+		config[name] = ConfigValue{Value: v, Source: o.source(name)}
+	}
+	return config
+}
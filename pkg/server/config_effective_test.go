@@ -0,0 +1,62 @@
+package server
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		if err := os.WriteFile(name, []byte("pem"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeOptionsFile(t, `{
+		"burst": 50,
+		"mcp_server_name": "${env:TEST_EFFECTIVE_NAME}",
+		"rate_limit_overrides": {"10.0.0.1": {"limit": 100, "burst": 100}, "token:abc123": {"limit": 100, "burst": 100}}
+	}`)
+	t.Setenv("TEST_EFFECTIVE_NAME", "api")
+	t.Setenv(paramMCPEndpoint, "/rpc")
+
+	srv, err := NewServer(WithAddr(":0"), WithTLS("tls.crt", "tls.key"), WithRateLimit(10, 50))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	config := srv.EffectiveConfig()
+
+	want := map[string]ConfigValue{
+		"addr":            {Value: ":0", Source: ConfigSourceProgrammatic},
+		"burst":           {Value: 50, Source: ConfigSourceFile},
+		"mcp_endpoint":    {Value: "/rpc", Source: ConfigSourceEnv},
+		"read_timeout":    {Value: "30s", Source: ConfigSourceDefault},
+		"mcp_server_name": {Value: redactedValue, Source: ConfigSourceFile},
+		"key_file":        {Value: redactedValue, Source: ConfigSourceProgrammatic},
+	}
+	for name, w := range want {
+		got, ok := config[name]
+		if !ok {
+			t.Errorf("expected %s in the effective configuration", name)
+			continue
+		}
+		if got.Source != w.Source || got.Value != w.Value {
+			t.Errorf("%s: expected %v from %s, got %v from %s", name, w.Value, w.Source, got.Value, got.Source)
+		}
+	}
+	overrides, _ := config["rate_limit_overrides"].Value.(map[string]interface{})
+	if overrides["token:abc123"] != redactedValue || overrides["10.0.0.1"] == redactedValue {
+		t.Errorf("expected only the override keyed by a token to be redacted, got %v", overrides)
+	}
+	if _, ok := config["on_config_change"]; ok {
+		t.Error("expected hooks to be left out")
+	}
+
+	writeOptionsFile(t, `{"burst": 80}`)
+	if _, err := srv.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	if got := srv.EffectiveConfig()["burst"]; got.Value != 80 || got.Source != ConfigSourceFile {
+		t.Errorf("expected the reloaded burst from the file, got %+v", got)
+	}
+}
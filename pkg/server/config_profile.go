@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Built-in configuration profiles, see ServerOptions.Profile.
+const (
+	ProfileDev        = "dev"
+	ProfileStaging    = "staging"
+	ProfileProduction = "production"
+)
+
+// builtinConfigProfiles holds the options each built-in profile sets. They apply to
+// options the configuration file does not mention, so the top level and the profile's
+// block in the file, environment variables, and ServerOptionFuncs all override them.
+var builtinConfigProfiles = map[string]*ServerOptions{
+	// Debug logging and a CSP that allows the blob: workers of dev tooling
+	ProfileDev:        {DebugMode: true, LogLevel: "DEBUG", CSPWebWorkerSupport: true},
+	ProfileStaging:    {HardenedMode: true},
+	ProfileProduction: {HardenedMode: true, SuppressBanner: true},
+}
+
+// configProfileAliases maps common alternative names to the built-in profiles.
+var configProfileAliases = map[string]string{
+	"development": ProfileDev,
+	"prod":        ProfileProduction,
+}
+
+// configProfile returns the active profile: HS_PROFILE if set, otherwise the "profile"
+// key of the configuration file.
+func configProfile(fileProfile string) string {
+	profile := strings.ToLower(strings.TrimSpace(fileProfile))
+	if env := strings.TrimSpace(os.Getenv(paramProfile)); env != "" {
+		profile = strings.ToLower(env)
+	}
+	if alias, ok := configProfileAliases[profile]; ok {
+		profile = alias
+	}
+	return profile
+}
+
+// applyConfigProfile applies the built-in values of profile and then the profile's block
+// from the configuration file, whose top-level keys are given. Unlike the top level, keys
+// in the block are applied even when they hold zero values, so a block can switch off
+// what a built-in profile switches on. Unknown profiles are reported by Validate.
+func applyConfigProfile(config *ServerOptions, profile string, keys map[string]json.RawMessage) error {
+	var block map[string]json.RawMessage
+	blockConfig := &ServerOptions{}
+	if raw, ok := keys["profiles"]; ok {
+		var profiles map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &profiles); err != nil {
+			return fmt.Errorf("invalid profiles in %s: %w", paramFileName, err)
+		}
+		if raw, ok := profiles[profile]; ok {
+			if err := json.Unmarshal(raw, &block); err != nil {
+				return fmt.Errorf("invalid profile %q in %s: %w", profile, paramFileName, err)
+			}
+			if err := json.Unmarshal(raw, blockConfig); err != nil {
+				return fmt.Errorf("invalid profile %q in %s: %w", profile, paramFileName, err)
+			}
+		}
+	}
+	config.profileDefined = block != nil
+
+	if builtin, ok := builtinConfigProfiles[profile]; ok {
+		builtinValue := reflect.ValueOf(builtin).Elem()
+		configValue := reflect.ValueOf(config).Elem()
+		for i := 0; i < builtinValue.NumField(); i++ {
+			field := builtinValue.Type().Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			_, inFile := keys[name]
+			_, inBlock := block[name]
+			if !field.IsExported() || builtinValue.Field(i).IsZero() || inFile || inBlock {
+				continue
+			}
+			if source := config.source(name); source != ConfigSourceDefault && source != ConfigSourceProfile {
+				continue // Set by code on a reload
+			}
+			configValue.Field(i).Set(builtinValue.Field(i))
+			config.markConfigSource(name, ConfigSourceProfile)
+		}
+	}
+
+	for name := range block {
+		if copyConfigField(config, blockConfig, name) {
+			config.markConfigSource(name, ConfigSourceFile)
+		}
+	}
+	return nil
+}
+
+// resetConfigProfile restores the options set by the built-in values of a profile to
+// their defaults, so that a reload applies the new profile to a clean slate instead of
+// keeping what the previous profile switched on.
+func resetConfigProfile(config *ServerOptions) {
+	for name, source := range config.sources {
+		if source == ConfigSourceProfile {
+			copyConfigField(config, defaultServerOptions, name)
+			delete(config.sources, name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func TestConfigProfiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{
+		"profile": "prod",
+		"burst": 20,
+		"suppress_banner": false,
+		"profiles": {
+			"production": {"log_level": "WARN", "burst": 40},
+			"dev": {"log_level": "ERROR"}
+		}
+	}`)
+
+	opts := NewServerOptions()
+	if opts.Profile != ProfileProduction {
+		t.Fatalf("expected the prod alias to select %q, got %q", ProfileProduction, opts.Profile)
+	}
+	if !opts.HardenedMode || opts.source("hardened_mode") != ConfigSourceProfile {
+		t.Errorf("expected hardened mode from the profile, got %v from %s", opts.HardenedMode, opts.source("hardened_mode"))
+	}
+	if opts.SuppressBanner {
+		t.Error("expected the top level of the file to override the built-in profile")
+	}
+	if opts.LogLevel != "WARN" || opts.Burst != 40 || opts.source("burst") != ConfigSourceFile {
+		t.Errorf("expected the profile block to override the top level, got %q and %d", opts.LogLevel, opts.Burst)
+	}
+
+	t.Setenv(paramProfile, "dev")
+	opts = NewServerOptions()
+	if opts.Profile != ProfileDev || !opts.DebugMode || !opts.CSPWebWorkerSupport || opts.HardenedMode {
+		t.Errorf("expected HS_PROFILE to select the dev profile, got %+v", opts)
+	}
+	if opts.LogLevel != "ERROR" || opts.Burst != 20 || opts.source("profile") != ConfigSourceEnv {
+		t.Errorf("expected the dev block, got log level %q, burst %d, profile from %s", opts.LogLevel, opts.Burst, opts.source("profile"))
+	}
+}
+
+func TestReloadConfigProfile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{"profile": "dev"}`)
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer slog.SetLogLoggerLevel(slog.LevelInfo)
+	if opts := srv.Options.current(); !opts.DebugMode || !opts.CSPWebWorkerSupport {
+		t.Fatalf("expected the dev profile, got %+v", opts)
+	}
+
+	writeOptionsFile(t, `{"profile": "staging"}`)
+	event, err := srv.ReloadConfig()
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !slices.ContainsFunc(event.RequiresRestart, func(c ConfigChange) bool { return c.Field == "profile" }) {
+		t.Errorf("expected the profile switch to require a restart, got %+v", event.RequiresRestart)
+	}
+	// Reloadable values of the dev profile are reset rather than kept
+	opts := srv.Options.current()
+	if opts.DebugMode || opts.LogLevel != "INFO" {
+		t.Errorf("expected the dev profile's values to be reset, got debug %v, log level %q", opts.DebugMode, opts.LogLevel)
+	}
+	if opts.source("debug_mode") != ConfigSourceDefault {
+		t.Errorf("expected debug_mode from the defaults, got %s", opts.source("debug_mode"))
+	}
+}
+
+func TestUnknownConfigProfile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(paramProfile, "qa")
+	_, err := NewServer(WithAddr(":0"))
+	var verr *ConfigValidationError
+	if !errors.As(err, &verr) || verr.Problems[0].Field != "profile" || verr.Problems[0].Source != ConfigSourceEnv {
+		t.Fatalf("expected an unknown profile to fail validation, got %v", err)
+	}
+
+	writeOptionsFile(t, `{"profiles": {"qa": {"burst": 30}}}`)
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("expected a profile defined in the file to be accepted, got %v", err)
+	}
+	if srv.Options.Burst != 30 {
+		t.Errorf("expected burst 30 from the qa profile, got %d", srv.Options.Burst)
+	}
+
+	writeOptionsFile(t, `{"profiles": {"qa": {"burst": 50}}}`)
+	if _, err := srv.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	if srv.CurrentOptions().Burst != 50 {
+		t.Errorf("expected the reload to apply the profile block, got burst %d", srv.CurrentOptions().Burst)
+	}
+}
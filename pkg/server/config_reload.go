@@ -0,0 +1,322 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// configResourceURI is the MCP resource that shows the running configuration.
+const configResourceURI = "config://server/current"
+
+// ConfigChangeEvent reports the outcome of a configuration reload.
+type ConfigChangeEvent struct {
+	Applied         []ConfigChange `json:"applied"`          // Changes now in effect
+	RequiresRestart []ConfigChange `json:"requires_restart"` // Changes that take effect on the next start
+	Time            time.Time      `json:"time"`
+}
+
+// WithConfigReload watches the options file and applies changes to reloadable options,
+// such as rate limits, the log level, CORS origins, and maintenance mode, while the
+// server runs. The file is checked every interval. Changes to other options, such as
+// listen addresses or TLS files, are logged as requiring a restart and left alone.
+// The interval can also be set via "config_reload_interval" in options.json.
+//
+// Options removed from the file keep their current value; set them explicitly, e.g.
+// "maintenance_mode": false, to change them. Reloaded values are visible through
+// CurrentOptions, while Options keeps the values the server was created with.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithConfigReload(5*time.Second),
+//		server.WithOnConfigChange(func(e server.ConfigChangeEvent) {
+//			for _, c := range e.RequiresRestart {
+//				alert("restart needed for " + c.Field)
+//			}
+//		}),
+//	)
+func WithConfigReload(interval time.Duration) ServerOptionFunc {
+	return func(srv *Server) error {
+		if interval <= 0 {
+			return fmt.Errorf("config reload interval must be positive: %v", interval)
+		}
+		srv.Options.ConfigReloadInterval = interval
+		return nil
+	}
+}
+
+// WithOnConfigChange adds a function called after each configuration reload that
+// changed something, with the applied changes and those that need a restart.
+func WithOnConfigChange(fn func(ConfigChangeEvent)) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.OnConfigChange = append(srv.Options.OnConfigChange, fn)
+		return nil
+	}
+}
+
+// WithMaintenanceMode starts the server in maintenance mode, see SetMaintenanceMode. It
+// can also be set via "maintenance_mode" in options.json and toggled by a config reload.
+func WithMaintenanceMode() ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.MaintenanceMode = true
+		return nil
+	}
+}
+
+// SetMaintenanceMode turns maintenance mode on or off. In maintenance mode every request
+// except the health checks is answered with 503 and a Retry-After header.
+func (srv *Server) SetMaintenanceMode(on bool) {
+	srv.updateOptions(func(o *ServerOptions) { o.MaintenanceMode = on })
+	srv.maintenance.Store(on)
+	logger.Info("Maintenance mode changed", "enabled", on)
+}
+
+// CurrentOptions returns the options in effect, including changes applied by
+// ReloadConfig since the server was created; Options keeps the options the server was
+// created with. The returned options are shared and must not be modified.
+func (srv *Server) CurrentOptions() *ServerOptions {
+	return srv.Options.current()
+}
+
+// current returns the latest snapshot published by a reload, or o itself before the first
+// reload. Reloads never change options in place, since requests read them without a lock,
+// so the request path reads reloadable options through current.
+func (o *ServerOptions) current() *ServerOptions {
+	if o != nil && o.reloaded != nil {
+		if latest := o.reloaded.Load(); latest != nil {
+			return latest
+		}
+	}
+	return o
+}
+
+// updateOptions publishes a copy of the current options with fn applied.
+func (srv *Server) updateOptions(fn func(*ServerOptions)) {
+	srv.configMu.Lock()
+	defer srv.configMu.Unlock()
+	next := *srv.Options.current()
+	fn(&next)
+	srv.publishOptions(&next)
+}
+
+// publishOptions makes next the options in effect. The caller holds configMu.
+func (srv *Server) publishOptions(next *ServerOptions) {
+	if srv.Options.reloaded == nil {
+		srv.Options.reloaded = new(atomic.Pointer[ServerOptions])
+	}
+	next.reloaded = srv.Options.reloaded
+	srv.Options.reloaded.Store(next)
+}
+
+// maintenanceHandler answers requests with 503 while maintenance mode is on.
+func (srv *Server) maintenanceHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !srv.maintenance.Load() || normalizeHealthPath(r.URL.Path) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", "60")
+		writeErrorResponse(w, http.StatusServiceUnavailable, "Service under maintenance")
+	})
+}
+
+// ReloadConfig re-reads the options file and environment and applies the changes to
+// reloadable options. Changes that need a restart are returned but not applied. If the
+// options file cannot be parsed or the result fails Validate, nothing is changed and the
+// error is returned.
+func (srv *Server) ReloadConfig() (*ConfigChangeEvent, error) {
+	srv.configMu.Lock()
+	defer srv.configMu.Unlock()
+
+	candidate, err := srv.reloadCandidate()
+	if err != nil {
+		return nil, err
+	}
+	if err := candidate.Validate(); err != nil {
+		return nil, err
+	}
+	event := &ConfigChangeEvent{
+		Applied:         make([]ConfigChange, 0),
+		RequiresRestart: make([]ConfigChange, 0),
+		Time:            time.Now(),
+	}
+	// Changes are applied to a copy that is then published, see current. The maps are
+	// replaced rather than updated, since the copy shares them with the current options.
+	current := srv.Options.current()
+	next := *current
+	next.sources, next.secretFields = maps.Clone(current.sources), maps.Clone(current.secretFields)
+	if next.sources == nil {
+		next.sources = make(map[string]string)
+	}
+	if next.secretFields == nil {
+		next.secretFields = make(map[string]bool)
+	}
+	for _, change := range diffServerOptions(current, candidate) {
+		// Route windows are enforced by a middleware that cannot be added to a running
+		// server, so the first windows need a restart; see installRouteWindows
+		if change.Field == "route_windows" && !srv.routeWindowsWired && srv.isRunning.Load() {
+			change.RequiresRestart = true
+		}
+		if change.RequiresRestart {
+			event.RequiresRestart = append(event.RequiresRestart, change)
+			continue
+		}
+		copyConfigField(&next, candidate, change.Field)
+		next.sources[change.Field] = candidate.source(change.Field)
+		next.secretFields[change.Field] = candidate.secretFields[change.Field]
+		event.Applied = append(event.Applied, change)
+	}
+	if len(event.Applied) == 0 && len(event.RequiresRestart) == 0 {
+		return event, nil
+	}
+	srv.publishOptions(&next)
+	for _, c := range event.Applied {
+		srv.applyConfigField(&next, c.Field)
+	}
+
+	for _, c := range event.Applied {
+		logger.Info("Configuration change applied", "field", c.Field, "value", c.Reloaded)
+	}
+	if len(event.RequiresRestart) > 0 {
+		fields := make([]string, len(event.RequiresRestart))
+		for i, c := range event.RequiresRestart {
+			fields[i] = c.Field
+		}
+		logger.Warn("Configuration changes require a restart and were not applied", "fields", strings.Join(fields, ", "))
+	}
+
+	if srv.MCPEnabled() {
+		srv.mcpHandler.InvalidateResource(configResourceURI)
+		srv.mcpHandler.broadcastNotification("notifications/resources/updated", map[string]interface{}{
+			"uri": configResourceURI,
+		})
+	}
+	for _, fn := range srv.Options.OnConfigChange {
+		fn(*event)
+	}
+	return event, nil
+}
+
+// reloadCandidate returns the options a reload would produce. Unlike at startup, keys in
+// the options file are applied even when they hold zero values, so that options such as
+// maintenance_mode can be switched off again. Built-in profile values are reset first,
+// so that switching profiles does not keep the previous profile's values.
+func (srv *Server) reloadCandidate() (*ServerOptions, error) {
+	current := srv.Options.current()
+	candidate := *current
+	candidate.sources = maps.Clone(current.sources)
+	resetConfigProfile(&candidate)
+	var keys map[string]json.RawMessage
+	data, err := os.ReadFile(paramFileName)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s: %w", paramFileName, err)
+	default:
+		fileConfig := &ServerOptions{}
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", paramFileName, err)
+		}
+		if err := json.Unmarshal(data, fileConfig); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", paramFileName, err)
+		}
+		for name := range keys {
+			if copyConfigField(&candidate, fileConfig, name) {
+				candidate.markConfigSource(name, ConfigSourceFile)
+			}
+		}
+	}
+	if profile := configProfile(candidate.Profile); profile != "" {
+		candidate.Profile = profile
+		if err := applyConfigProfile(&candidate, profile, keys); err != nil {
+			return nil, err
+		}
+	}
+	fromFile := candidate
+	applyEnvVars(&candidate)
+	candidate.CORS = normalizeCORSOptions(candidate.CORS)
+	candidate.markConfigSources(&fromFile, &candidate, ConfigSourceEnv)
+	if err := candidate.resolveSecrets(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to resolve configuration secrets: %w", err)
+	}
+	return &candidate, nil
+}
+
+// applyConfigField applies the side effects of a change to the option with the given JSON
+// name, once next has been published. The caller holds configMu.
+func (srv *Server) applyConfigField(next *ServerOptions, name string) {
+	switch name {
+	case "log_level", "debug_mode":
+		level := slog.LevelInfo
+		if next.DebugMode {
+			level = slog.LevelDebug
+		} else if l, err := parseLogLevel(next.LogLevel); err == nil {
+			level = l
+		}
+		slog.SetLogLoggerLevel(level)
+	case "rate_limit", "burst", "rate_limit_overrides":
+		// Buckets are created with the limits in effect at the time
+		srv.limitersMu.Lock()
+		clear(srv.clientLimiters)
+		srv.limitersMu.Unlock()
+	case "maintenance_mode":
+		srv.maintenance.Store(next.MaintenanceMode)
+	case "route_windows":
+		// The middleware reads the windows from the current options once installed
+		if len(next.RouteWindows) > 0 {
+			srv.installRouteWindows()
+		}
+	}
+}
+
+// copyConfigField copies the option with the given JSON name from src to dst and reports
+// whether such an option exists.
+func copyConfigField(dst, src *ServerOptions, name string) bool {
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src).Elem()
+	for i := 0; i < dstValue.NumField(); i++ {
+		field := dstValue.Type().Field(i)
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == name && name != "-" && field.IsExported() {
+			dstValue.Field(i).Set(srcValue.Field(i))
+			return true
+		}
+	}
+	return false
+}
+
+// watchConfig reloads the configuration when the modification time of the options file
+// changes.
+func (srv *Server) watchConfig(ctx context.Context, interval time.Duration) {
+	var modTime time.Time
+	if info, err := os.Stat(paramFileName); err == nil {
+		modTime = info.ModTime()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(paramFileName)
+			if err != nil || info.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = info.ModTime()
+			if _, err := srv.ReloadConfig(); err != nil {
+				logger.Error("Configuration reload failed, keeping current configuration", "file", paramFileName, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type methodNotifier struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (n *methodNotifier) Notify(method string, params interface{}) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.methods = append(n.methods, method)
+	return nil
+}
+
+func writeOptionsFile(t *testing.T, content string) {
+	t.Helper()
+	if err := os.WriteFile(paramFileName, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write options file: %v", err)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{"burst": 5}`)
+
+	var events []ConfigChangeEvent
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithMCPSupport("test", "1.0.0"),
+		WithOnConfigChange(func(e ConfigChangeEvent) { events = append(events, e) }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	notifier := &methodNotifier{}
+	defer srv.mcpHandler.addListener(notifier)()
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.Handler()
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	writeOptionsFile(t, `{
+		"burst": 7,
+		"addr": ":9999",
+		"maintenance_mode": true,
+		"cors": {"allowed_origins": ["https://app.example.com"]}
+	}`)
+	event, err := srv.ReloadConfig()
+	if err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+
+	applied := map[string]bool{}
+	for _, c := range event.Applied {
+		applied[c.Field] = true
+	}
+	if !applied["burst"] || !applied["maintenance_mode"] || !applied["cors"] {
+		t.Errorf("expected burst, maintenance_mode, and cors to be applied, got %+v", event.Applied)
+	}
+	if len(event.RequiresRestart) != 1 || event.RequiresRestart[0].Field != "addr" {
+		t.Errorf("expected addr to require a restart, got %+v", event.RequiresRestart)
+	}
+	if srv.CurrentOptions().Burst != 7 || srv.CurrentOptions().Addr != ":0" {
+		t.Errorf("expected burst 7 and the original addr, got %d %q", srv.CurrentOptions().Burst, srv.CurrentOptions().Addr)
+	}
+	if srv.CurrentOptions().CORS == nil || len(srv.CurrentOptions().CORS.AllowedOrigins) != 1 {
+		t.Errorf("expected the reloaded CORS origins, got %+v", srv.CurrentOptions().CORS)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected one change callback, got %d", len(events))
+	}
+	notifier.mu.Lock()
+	if len(notifier.methods) != 1 || notifier.methods[0] != "notifications/resources/updated" {
+		t.Errorf("expected a resource update notification, got %v", notifier.methods)
+	}
+	notifier.mu.Unlock()
+
+	if code := status("/"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 in maintenance mode, got %d", code)
+	}
+	if code := status("/healthz"); code == http.StatusServiceUnavailable {
+		t.Error("expected health checks to bypass maintenance mode")
+	}
+
+	// A zero value in the file switches the option off again
+	writeOptionsFile(t, `{"burst": 7, "addr": ":9999", "maintenance_mode": false}`)
+	if _, err := srv.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	if code := status("/"); code != http.StatusOK {
+		t.Errorf("expected maintenance mode to be off, got %d", code)
+	}
+
+	writeOptionsFile(t, `{"burst": "many"}`)
+	if _, err := srv.ReloadConfig(); err == nil {
+		t.Error("expected an invalid options file to fail the reload")
+	}
+	if srv.CurrentOptions().Burst != 7 {
+		t.Errorf("expected a failed reload to keep the configuration, got burst %d", srv.CurrentOptions().Burst)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{"log_level": "INFO"}`)
+
+	changed := make(chan ConfigChangeEvent, 1)
+	srv, err := NewServer(WithAddr(":0"), WithOnConfigChange(func(e ConfigChangeEvent) { changed <- e }))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.watchConfig(ctx, 10*time.Millisecond)
+
+	writeOptionsFile(t, `{"log_level": "WARN"}`)
+	deadline := time.After(2 * time.Second)
+	for i := 1; ; i++ {
+		// Keep moving the modification time in case the watcher started after the write
+		later := time.Now().Add(time.Duration(i) * time.Second)
+		os.Chtimes(paramFileName, later, later)
+		select {
+		case e := <-changed:
+			if len(e.Applied) != 1 || e.Applied[0].Field != "log_level" {
+				t.Errorf("expected the log level change, got %+v", e.Applied)
+			}
+		case <-time.After(20 * time.Millisecond):
+			continue
+		case <-deadline:
+			t.Fatal("expected the change to be picked up")
+		}
+		break
+	}
+	cancel()
+	// Restore the default level for other tests
+	writeOptionsFile(t, `{"log_level": "INFO"}`)
+	srv.ReloadConfig()
+}
+
+func TestReloadConfigDuringTraffic(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{"burst": 1000}`)
+	srv, err := NewServer(WithAddr(":0"), WithRateLimit(1000, 1000))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.Handler()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		writeOptionsFile(t, fmt.Sprintf(`{"burst": %d, "cors": {"allowed_origins": ["https://%d.example.com"]}}`, 1000+i, i))
+		if _, err := srv.ReloadConfig(); err != nil {
+			t.Errorf("ReloadConfig failed: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+	if srv.CurrentOptions().Burst != 1019 || srv.Options.Burst != 1000 {
+		t.Errorf("expected reloads to publish new options, got burst %d (started with %d)", srv.CurrentOptions().Burst, srv.Options.Burst)
+	}
+}
+
+func TestReloadConfigRouteWindows(t *testing.T) {
+	t.Chdir(t.TempDir())
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	running, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	running.isRunning.Store(true)
+
+	window := `{"route_windows": [{"name": "freeze", "route": "/", "mode": "disable", "start": "00:00", "end": "00:00"}]}`
+	writeOptionsFile(t, `{"route_windows": [{"name": "freeze", "route": "/", "mode": "sometimes"}]}`)
+	if _, err := srv.ReloadConfig(); err == nil {
+		t.Error("expected an invalid route window to fail the reload")
+	}
+
+	writeOptionsFile(t, window)
+	if _, err := srv.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the reloaded window to disable the route, got %d", rec.Code)
+	}
+
+	// A running server without windows has no middleware to enforce them
+	event, err := running.ReloadConfig()
+	if err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	if len(event.RequiresRestart) != 1 || event.RequiresRestart[0].Field != "route_windows" || len(running.CurrentOptions().RouteWindows) != 0 {
+		t.Errorf("expected the first route windows to require a restart, got %+v", event)
+	}
+}
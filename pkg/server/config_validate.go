@@ -0,0 +1,216 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Sources of configuration values, as reported by Validate.
+const (
+	ConfigSourceDefault      = "default"
+	ConfigSourceFile         = "json" // options.json
+	ConfigSourceEnv          = "env"
+	ConfigSourceProgrammatic = "programmatic" // ServerOptionFuncs and direct assignment
+	ConfigSourceProfile      = "profile"      // Built-in values of the selected profile
+)
+
+// ConfigProblem is an invalid or contradictory configuration value.
+type ConfigProblem struct {
+	Field   string `json:"field"`  // JSON name of the option, e.g. "read_timeout"
+	Source  string `json:"source"` // Where the value came from, see ConfigSourceFile and friends
+	Message string `json:"message"`
+}
+
+// ConfigValidationError lists every problem Validate found, so that a configuration can
+// be fixed in one go.
+type ConfigValidationError struct {
+	Problems []ConfigProblem
+}
+
+func (e *ConfigValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problem", len(e.Problems))
+	if len(e.Problems) != 1 {
+		b.WriteByte('s')
+	}
+	b.WriteByte(')')
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s (set by %s): %s", p.Field, p.Source, p.Message)
+	}
+	return b.String()
+}
+
+// Validate checks the options for invalid and contradictory values, such as negative
+// timeouts, TLS without certificate files, or a burst too small for the rate limit. All
+// problems are returned together as a *ConfigValidationError, each with the option's
+// JSON name and the source that set it. NewServer calls Validate after applying its
+// options.
+func (o *ServerOptions) Validate() error {
+	v := &configValidator{options: o}
+
+	for _, d := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"read_timeout", o.ReadTimeout},
+		{"write_timeout", o.WriteTimeout},
+		{"idle_timeout", o.IdleTimeout},
+		{"read_header_timeout", o.ReadHeaderTimeout},
+		{"chaos_min_latency", o.ChaosMinLatency},
+		{"chaos_max_latency", o.ChaosMaxLatency},
+		{"mcp_session_idle_timeout", o.MCPSessionIdleTimeout},
+		{"gateway_reload_interval", o.GatewayReloadInterval},
+		{"config_reload_interval", o.ConfigReloadInterval},
+	} {
+		if d.value < 0 {
+			v.add(d.field, "must not be negative, got %v", d.value)
+		}
+	}
+	if o.ChaosMinLatency > o.ChaosMaxLatency && o.ChaosMaxLatency > 0 {
+		v.add("chaos_min_latency", "must not exceed chaos_max_latency (%v > %v)", o.ChaosMinLatency, o.ChaosMaxLatency)
+	}
+	for _, r := range []struct {
+		field string
+		value float64
+	}{
+		{"chaos_error_rate", o.ChaosErrorRate},
+		{"chaos_throttle_rate", o.ChaosThrottleRate},
+		{"chaos_panic_rate", o.ChaosPanicRate},
+	} {
+		if r.value < 0 || r.value > 1 {
+			v.add(r.field, "must be between 0 and 1, got %v", r.value)
+		}
+	}
+
+	for _, a := range []struct {
+		field, value string
+	}{
+		{"addr", o.Addr},
+		{"health_addr", o.HealthAddr},
+		{"tls_addr", o.TLSAddr},
+		{"tls_health_addr", o.TLSHealthAddr},
+	} {
+		if a.value == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(a.value); err != nil {
+			v.add(a.field, "must be host:port, e.g. \":8080\": %v", err)
+		}
+	}
+
+	if o.EnableTLS && !o.tlsConfigHasCertificates() {
+		for _, f := range []struct {
+			field, path string
+		}{
+			{"cert_file", o.CertFile},
+			{"key_file", o.KeyFile},
+		} {
+			if f.path == "" {
+				v.add(f.field, "is required when TLS is enabled")
+			} else if _, err := os.Stat(f.path); err != nil {
+				v.add(f.field, "TLS is enabled but the file cannot be read: %v", err)
+			}
+		}
+	}
+
+	o.validateTLS(v)
+
+	if o.RateLimit < 0 {
+		v.add("rate_limit", "must not be negative, got %v", float64(o.RateLimit))
+	}
+	if o.Burst < 0 {
+		v.add("burst", "must not be negative, got %d", o.Burst)
+	} else if o.RateLimit > 0 && float64(o.Burst) < float64(o.RateLimit) {
+		v.add("burst", "is smaller than rate_limit (%d < %v), so clients can never reach the rate limit; raise burst to at least %v",
+			o.Burst, float64(o.RateLimit), float64(o.RateLimit))
+	}
+	for key, override := range o.RateLimitOverrides {
+		if override.Limit < 0 || override.Burst < 0 {
+			v.add("rate_limit_overrides", "limit and burst of %q must not be negative", key)
+		}
+	}
+
+	if _, builtin := builtinConfigProfiles[o.Profile]; o.Profile != "" && !builtin && !o.profileDefined {
+		v.add("profile", "unknown profile %q: use %s, %s, or %s, or define it under \"profiles\" in %s",
+			o.Profile, ProfileDev, ProfileStaging, ProfileProduction, paramFileName)
+	}
+	if o.SecurityHeaders != nil {
+		if err := o.SecurityHeaders.validate(); err != nil {
+			v.add("security_headers", "%v", err)
+		}
+	}
+	for i := range o.RouteWindows {
+		if err := o.RouteWindows[i].Validate(); err != nil {
+			v.add("route_windows", "%v", err)
+		}
+	}
+	if o.LogLevel != "" {
+		if _, err := parseLogLevel(o.LogLevel); err != nil {
+			v.add("log_level", "must be one of DEBUG, INFO, WARN, or ERROR, got %q", o.LogLevel)
+		}
+	}
+	if o.MCPEnabled && o.MCPTransport != StdioTransport && !strings.HasPrefix(o.MCPEndpoint, "/") {
+		v.add("mcp_endpoint", "must start with '/', got %q", o.MCPEndpoint)
+	}
+	if o.MCPMaxSessions < 0 {
+		v.add("mcp_max_sessions", "must not be negative, got %d", o.MCPMaxSessions)
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ConfigValidationError{Problems: v.problems}
+}
+
+// configValidator collects the problems found by Validate.
+type configValidator struct {
+	options  *ServerOptions
+	problems []ConfigProblem
+}
+
+func (v *configValidator) add(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, ConfigProblem{
+		Field:   field,
+		Source:  v.options.source(field),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// source returns where the option with the given JSON name was set.
+func (o *ServerOptions) source(field string) string {
+	if s, ok := o.sources[field]; ok {
+		return s
+	}
+	return ConfigSourceDefault
+}
+
+// markConfigSources records source for the options whose value differs between before and
+// after.
+func (o *ServerOptions) markConfigSources(before, after *ServerOptions, source string) {
+	beforeValue := reflect.ValueOf(before).Elem()
+	afterValue := reflect.ValueOf(after).Elem()
+	optionsType := beforeValue.Type()
+	for i := 0; i < optionsType.NumField(); i++ {
+		field := optionsType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || name == "" || field.Type.Kind() == reflect.Func {
+			continue
+		}
+		if reflect.DeepEqual(beforeValue.Field(i).Interface(), afterValue.Field(i).Interface()) {
+			continue
+		}
+		o.markConfigSource(name, source)
+	}
+}
+
+// markConfigSource records source for the option with the given JSON name.
+func (o *ServerOptions) markConfigSource(name, source string) {
+	if o.sources == nil {
+		o.sources = make(map[string]string)
+	}
+	o.sources[name] = source
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewServerValidatesConfiguration(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{"read_timeout": -1, "tls": true, "cert_file": "missing.crt"}`)
+	t.Setenv(paramLogLevel, "LOUD")
+
+	_, err := NewServer(WithRateLimit(100, 20), WithReadHeaderTimeout(-time.Second))
+	var verr *ConfigValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ConfigValidationError, got %v", err)
+	}
+
+	problems := map[string]ConfigProblem{}
+	for _, p := range verr.Problems {
+		problems[p.Field] = p
+	}
+	want := map[string]string{
+		"read_timeout":        ConfigSourceFile,
+		"cert_file":           ConfigSourceFile,
+		"key_file":            ConfigSourceDefault,
+		"log_level":           ConfigSourceEnv,
+		"burst":               ConfigSourceProgrammatic,
+		"read_header_timeout": ConfigSourceProgrammatic,
+	}
+	for field, source := range want {
+		p, ok := problems[field]
+		if !ok {
+			t.Errorf("expected a problem with %s, got %v", field, verr.Problems)
+			continue
+		}
+		if p.Source != source {
+			t.Errorf("%s: expected source %s, got %s", field, source, p.Source)
+		}
+	}
+	if len(verr.Problems) != len(want) {
+		t.Errorf("expected %d problems, got %d:\n%v", len(want), len(verr.Problems), err)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "invalid configuration (6 problems)") || !strings.Contains(msg, "read_timeout (set by json): must not be negative") {
+		t.Errorf("unexpected error message:\n%s", msg)
+	}
+}
+
+func TestValidateAcceptsDefaults(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := NewServerOptions().Validate(); err != nil {
+		t.Fatalf("expected the defaults to be valid, got %v", err)
+	}
+	opts := NewServerOptions()
+	opts.ChaosMinLatency, opts.ChaosMaxLatency = 3e9, 1e9
+	opts.ChaosErrorRate = 1.5
+	err := opts.Validate()
+	if err == nil || !strings.Contains(err.Error(), "chaos_min_latency") || !strings.Contains(err.Error(), "chaos_error_rate") {
+		t.Errorf("expected chaos problems, got %v", err)
+	}
+}
+
+func TestReloadConfigValidates(t *testing.T) {
+	t.Chdir(t.TempDir())
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	writeOptionsFile(t, `{"burst": -1}`)
+	var verr *ConfigValidationError
+	if _, err := srv.ReloadConfig(); !errors.As(err, &verr) || verr.Problems[0].Field != "burst" {
+		t.Fatalf("expected the reload to fail validation, got %v", err)
+	}
+	if srv.CurrentOptions().Burst < 0 {
+		t.Error("expected an invalid reload to keep the configuration")
+	}
+}
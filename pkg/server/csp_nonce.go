@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// cspNonceKey is the context key of the request's CSP nonce.
+const cspNonceKey contextKey = "cspNonce"
+
+// cspNoncePlaceholder is what the cspNonce template function renders; it is replaced with
+// the response's nonce as the template is written. It starts with "_", which base64
+// nonces never contain, and is random so that page content cannot forge it.
+var cspNoncePlaceholder = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "_hs_csp_nonce_" + hex.EncodeToString(b) + "_"
+}()
+
+// WithCSPNonce generates a nonce for every request handled by HeadersMiddleware (see
+// SecureWeb) and allows inline scripts that carry it, instead of allowing all inline
+// scripts with 'unsafe-inline'. Templates insert the nonce with the cspNonce function,
+// and handlers writing HTML themselves get it from CSPNonce. It can also be enabled via
+// "csp_nonce" in options.json or HS_CSP_NONCE.
+//
+// Example:
+//
+//	<!-- index.html -->
+//	<script nonce="{{cspNonce}}">document.body.classList.add("js")</script>
+//
+//	srv, _ := server.NewServer(server.WithCSPNonce())
+//	srv.AddMiddlewareStack("*", server.SecureWeb(srv.Options))
+//	srv.HandleTemplate("/", "index.html", nil)
+func WithCSPNonce() ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.CSPNonce = true
+		return nil
+	}
+}
+
+// CSPNonce returns the nonce that the Content-Security-Policy of the response to r allows
+// inline scripts with, or "" if nonces are not enabled, see WithCSPNonce.
+func CSPNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey).(string)
+	return nonce
+}
+
+// newCSPNonce returns a random nonce of 128 bits.
+func newCSPNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// withCSPNonce stores nonce in the request context and allows it in the script-src
+// directive of csp in place of 'unsafe-inline'.
+func withCSPNonce(r *http.Request, csp, nonce string) (*http.Request, string) {
+	directives := strings.Split(csp, ";")
+	for i, directive := range directives {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 || fields[0] != "script-src" {
+			continue
+		}
+		sources := fields[:1]
+		for _, source := range fields[1:] {
+			if source != "'unsafe-inline'" {
+				sources = append(sources, source)
+			}
+		}
+		directives[i] = " " + strings.Join(append(sources, "'nonce-"+nonce+"'"), " ")
+	}
+	csp = strings.TrimSpace(strings.Join(directives, ";"))
+	return r.WithContext(context.WithValue(r.Context(), cspNonceKey, nonce)), csp
+}
+
+// responseCSPNonce returns the nonce in the Content-Security-Policy header of a response.
+func responseCSPNonce(h http.Header) string {
+	csp := h.Get("Content-Security-Policy")
+	_, rest, ok := strings.Cut(csp, "'nonce-")
+	if !ok {
+		return ""
+	}
+	nonce, _, _ := strings.Cut(rest, "'")
+	return nonce
+}
+
+// cspNonceWriter replaces cspNoncePlaceholder in template output with the nonce of the
+// response. Template actions write their output in one call, so the placeholder never
+// spans writes.
+type cspNonceWriter struct {
+	w     io.Writer
+	nonce []byte
+}
+
+func (cw *cspNonceWriter) Write(p []byte) (int, error) {
+	if !bytes.Contains(p, []byte(cspNoncePlaceholder)) {
+		return cw.w.Write(p)
+	}
+	if _, err := cw.w.Write(bytes.ReplaceAll(p, []byte(cspNoncePlaceholder), cw.nonce)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
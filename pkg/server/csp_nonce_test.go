@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSPNonce(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"index.html": `<script nonce="{{cspNonce}}">init()</script>`,
+	})
+	srv, err := NewServer(WithTemplateDir(dir), WithCSPNonce())
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.AddMiddlewareStack("*", SecureWeb(srv.Options))
+	if err := srv.HandleTemplate("/", "index.html", nil); err != nil {
+		t.Fatalf("HandleTemplate failed: %v", err)
+	}
+	var handlerNonce string
+	srv.HandleFunc("/inline", func(w http.ResponseWriter, r *http.Request) {
+		handlerNonce = CSPNonce(r)
+	})
+	handler := srv.Handler()
+
+	nonces := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		csp := rec.Header().Get("Content-Security-Policy")
+		nonce := responseCSPNonce(rec.Header())
+		if nonce == "" || !strings.Contains(csp, "script-src 'self' 'nonce-"+nonce+"'") {
+			t.Fatalf("expected a nonce in script-src, got %q", csp)
+		}
+		if strings.Contains(csp, "script-src 'self' 'unsafe-inline'") {
+			t.Errorf("expected 'unsafe-inline' to be dropped from script-src, got %q", csp)
+		}
+		if want := `<script nonce="` + nonce + `">`; !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("expected the page to carry the nonce, got %q", rec.Body.String())
+		}
+		nonces[nonce] = true
+	}
+	if len(nonces) != 2 {
+		t.Error("expected a new nonce per request")
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/inline", nil))
+	if handlerNonce == "" || handlerNonce != responseCSPNonce(rec.Header()) {
+		t.Errorf("expected CSPNonce to match the header, got %q", handlerNonce)
+	}
+}
+
+func TestCSPNonceWithoutMiddleware(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"index.html": `<script nonce="{{cspNonce}}"></script>`,
+	})
+	srv, err := NewServer(WithTemplateDir(dir))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	if err := srv.RenderTemplate(rec, "index.html", nil); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if got := rec.Body.String(); got != `<script nonce=""></script>` {
+		t.Errorf("expected an empty nonce, got %q", got)
+	}
+}
+
+func TestCSPNonceIntoBuffer(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"index.html": `{{define "script"}}<script nonce="{{cspNonce}}"></script>{{end}}<main>{{template "script"}}</main>`,
+	})
+	srv, err := NewServer(WithTemplateDir(dir), WithCSPNonce())
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.AddMiddlewareStack("*", SecureWeb(srv.Options))
+	var partial, page strings.Builder
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := srv.ExecuteTemplate(&partial, r, "index.html", "script", nil); err != nil {
+			t.Errorf("ExecuteTemplate failed: %v", err)
+		}
+		if err := srv.ExecuteTemplate(&page, nil, "index.html", "", nil); err != nil {
+			t.Errorf("ExecuteTemplate failed: %v", err)
+		}
+	})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	nonce := responseCSPNonce(rec.Header())
+	if want := `<script nonce="` + nonce + `"></script>`; nonce == "" || partial.String() != want {
+		t.Errorf("expected %q, got %q", want, partial.String())
+	}
+	if got := page.String(); got != `<main><script nonce=""></script></main>` {
+		t.Errorf("expected an empty nonce without a request, got %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirectoryListingConfig lists the contents of static directories that have no
+// index.html. Listing is off unless a directory is enabled under Prefixes, so files that
+// are served but not linked anywhere stay undiscoverable.
+//
+// Prefixes are directories of StaticDir, e.g. "downloads" or "downloads/private", and
+// apply to the directories below them; the longest matching prefix decides, and "" stands
+// for the whole static directory. Directories that are not listed answer 404.
+//
+// Listings are HTML, or JSON for clients that prefer application/json or request
+// "?format=json".
+type DirectoryListingConfig struct {
+	Prefixes   map[string]bool `json:"prefixes,omitempty"`    // Enables or disables listing per directory prefix
+	ShowHidden bool            `json:"show_hidden,omitempty"` // Lists files and directories whose name starts with "."
+}
+
+// DirectoryEntry is a file or directory in a directory listing.
+type DirectoryEntry struct {
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// DirectoryListing is the JSON form of a directory listing.
+type DirectoryListing struct {
+	Path    string           `json:"path"` // Directory path relative to the static route, e.g. "/downloads/"
+	Entries []DirectoryEntry `json:"entries"`
+}
+
+var directoryListingPage = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{if ne .Path "/"}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Name}}{{if .Dir}}/{{end}}">{{.Name}}{{if .Dir}}/{{end}}</a></td><td>{{if not .Dir}}{{.Size}}{{end}}</td><td>{{.ModTime.UTC.Format "2006-01-02 15:04:05"}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WithDirectoryListing lists static directories without an index.html, for the prefixes
+// enabled in cfg. It can also be configured via the "directory_listing" key in
+// options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithDirectoryListing(server.DirectoryListingConfig{
+//			Prefixes: map[string]bool{"downloads": true, "downloads/internal": false},
+//		}),
+//	)
+//	srv.HandleStatic("/static/")
+func WithDirectoryListing(cfg DirectoryListingConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.DirectoryListing = &cfg
+		return nil
+	}
+}
+
+// listingEnabled reports whether the directory dir of StaticDir may be listed.
+func (cfg *DirectoryListingConfig) listingEnabled(dir string) bool {
+	enabled, longest := false, -1
+	for prefix, on := range cfg.Prefixes {
+		prefix = strings.Trim(prefix, "/")
+		if prefix != "" && dir != prefix && !strings.HasPrefix(dir, prefix+"/") {
+			continue
+		}
+		if len(prefix) > longest {
+			enabled, longest = on, len(prefix)
+		}
+	}
+	return enabled
+}
+
+// directoryListing answers requests for static directories that have no index.html with
+// a listing, or with 404 where listing is not enabled. Without a DirectoryListingConfig
+// requests are passed on unchanged.
+func (srv *Server) directoryListing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := srv.Options.DirectoryListing
+		if cfg == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		fsys, err := srv.staticFS()
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		dir := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+		if dir == "" {
+			dir = "."
+		}
+		info, err := fs.Stat(fsys, dir)
+		if err != nil || !info.IsDir() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, err := fs.Stat(fsys, path.Join(dir, "index.html")); err == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if dir == "." {
+			dir = ""
+		}
+		if !cfg.listingEnabled(dir) || (!cfg.ShowHidden && hiddenPath(dir)) {
+			http.NotFound(w, r)
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			// Relative links in the listing need the trailing slash
+			target := path.Base(r.URL.Path) + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		listing, err := readDirectoryListing(fsys, dir, cfg.ShowHidden)
+		if err != nil {
+			logger.Error("Failed to list static directory", "dir", dir, "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		w.Header().Set("Vary", "Accept")
+		if r.URL.Query().Get("format") == "json" ||
+			negotiateContentType(r.Header.Get("Accept"), "text/html", "application/json") == "application/json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(listing)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := directoryListingPage.Execute(w, listing); err != nil {
+			logger.Error("Failed to render directory listing", "dir", dir, "error", err)
+		}
+	})
+}
+
+// readDirectoryListing lists dir of fsys, directories first.
+func readDirectoryListing(fsys fs.FS, dir string, showHidden bool) (*DirectoryListing, error) {
+	name := dir
+	if name == "" {
+		name = "."
+	}
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	listing := &DirectoryListing{Path: "/", Entries: make([]DirectoryEntry, 0, len(entries))}
+	if dir != "" {
+		listing.Path = "/" + dir + "/"
+	}
+	for _, entry := range entries {
+		if !showHidden && strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		e := DirectoryEntry{Name: entry.Name(), Dir: entry.IsDir(), ModTime: info.ModTime()}
+		if !e.Dir {
+			e.Size = info.Size()
+		}
+		listing.Entries = append(listing.Entries, e)
+	}
+	sort.SliceStable(listing.Entries, func(i, j int) bool {
+		return listing.Entries[i].Dir && !listing.Entries[j].Dir
+	})
+	return listing, nil
+}
+
+// hiddenPath reports whether any element of a slash-separated path starts with ".".
+func hiddenPath(p string) bool {
+	for _, elem := range strings.Split(p, "/") {
+		if strings.HasPrefix(elem, ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDirectoryListing(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"downloads/report.pdf":          "pdf",
+		"downloads/.secret":             "hidden",
+		"downloads/archive/old.zip":     "zip",
+		"downloads/internal/notes.txt":  "internal",
+		"downloads/.git/config":         "git",
+		"site/index.html":               "<h1>site</h1>",
+		"private/keys.txt":              "keys",
+		"downloads/archive/2024/a.json": "{}",
+	})
+	srv, err := NewServer(WithDirectoryListing(DirectoryListingConfig{
+		Prefixes: map[string]bool{"downloads": true, "downloads/internal": false},
+	}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.Options.StaticDir = dir
+	srv.HandleStatic("/files/")
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/files/downloads/", "text/html")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected listing, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`href="archive/"`, `href="report.pdf"`, "Index of /downloads/"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected listing to contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, ".secret") || strings.Contains(body, ".git") {
+		t.Errorf("expected hidden files to be filtered:\n%s", body)
+	}
+
+	rec = get("/files/downloads/archive/?format=json", "")
+	var listing DirectoryListing
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatalf("expected JSON listing, got %q: %v", rec.Body.String(), err)
+	}
+	if listing.Path != "/downloads/archive/" || len(listing.Entries) != 2 ||
+		listing.Entries[0].Name != "2024" || !listing.Entries[0].Dir || listing.Entries[1].Size != 3 {
+		t.Errorf("unexpected JSON listing: %+v", listing)
+	}
+	if rec := get("/files/downloads/", "application/json"); rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected JSON for Accept: application/json, got %q", rec.Header().Get("Content-Type"))
+	}
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/files/", http.StatusNotFound},                    // Root not enabled
+		{"/files/private/", http.StatusNotFound},            // Not enabled
+		{"/files/downloads/internal/", http.StatusNotFound}, // Disabled by a longer prefix
+		{"/files/downloads/.git/", http.StatusNotFound},     // Hidden directory
+		{"/files/downloads", http.StatusMovedPermanently},
+		{"/files/downloads/report.pdf", http.StatusOK},
+		{"/files/private/keys.txt", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := get(tt.path, ""); rec.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, rec.Code)
+		}
+	}
+	if rec := get("/files/downloads", ""); rec.Header().Get("Location") != "downloads/" {
+		t.Errorf("expected redirect to the slashed path, got %q", rec.Header().Get("Location"))
+	}
+}
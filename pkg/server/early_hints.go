@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PreloadLink describes a resource the browser should start fetching before the page
+// that needs it has arrived.
+type PreloadLink struct {
+	URL         string `json:"url"`                   // Path or URL, or a logical asset name when Asset is set
+	Rel         string `json:"rel,omitempty"`         // "preload" (default), "modulepreload", or "preconnect"
+	As          string `json:"as,omitempty"`          // Destination: "style", "script", "font", "image", "fetch"
+	Type        string `json:"type,omitempty"`        // MIME type, e.g. "font/woff2"
+	CrossOrigin string `json:"crossorigin,omitempty"` // "anonymous" (required for fonts) or "use-credentials"
+	// Asset resolves URL through the asset manifest to its fingerprinted path.
+	Asset bool `json:"asset,omitempty"`
+}
+
+// String formats the link as a Link header value.
+func (l PreloadLink) String() string {
+	rel := l.Rel
+	if rel == "" {
+		rel = "preload"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%s>; rel=%s", l.URL, rel)
+	if l.As != "" {
+		fmt.Fprintf(&b, "; as=%s", l.As)
+	}
+	if l.Type != "" {
+		fmt.Fprintf(&b, "; type=%q", l.Type)
+	}
+	switch l.CrossOrigin {
+	case "":
+	case "anonymous":
+		b.WriteString("; crossorigin")
+	default:
+		fmt.Fprintf(&b, "; crossorigin=%s", l.CrossOrigin)
+	}
+	return b.String()
+}
+
+// SendEarlyHints adds Link headers for the given resources and, for GET requests from
+// HTTP/1.1 and HTTP/2 clients, sends them ahead of the response as 103 Early Hints so the
+// browser can fetch critical assets while the handler is still rendering. The Link headers
+// are repeated on the final response. Call it before writing the response.
+func SendEarlyHints(w http.ResponseWriter, r *http.Request, links ...PreloadLink) {
+	if len(links) == 0 {
+		return
+	}
+	for _, link := range links {
+		w.Header().Add("Link", link.String())
+	}
+	if r.Method == http.MethodGet && r.ProtoAtLeast(1, 1) {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+}
+
+// WithPreload declares critical assets of a route, typically a template route, so that
+// responses carry Link preload headers and 103 Early Hints. Links marked Asset are
+// resolved to fingerprinted URLs through the asset manifest.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithPreload("/",
+//			server.PreloadLink{URL: "css/app.css", As: "style", Asset: true},
+//			server.PreloadLink{URL: "/fonts/inter.woff2", As: "font", Type: "font/woff2", CrossOrigin: "anonymous"},
+//		),
+//	)
+//	srv.HandleTemplate("/", "index.html", nil)
+func WithPreload(route string, links ...PreloadLink) ServerOptionFunc {
+	return func(srv *Server) error {
+		for _, link := range links {
+			if link.URL == "" {
+				return fmt.Errorf("preload link for route %s has no URL", route)
+			}
+		}
+		srv.AddMiddleware(route, srv.preloadMiddleware(links))
+		return nil
+	}
+}
+
+func (srv *Server) preloadMiddleware(links []PreloadLink) MiddlewareFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			SendEarlyHints(w, r, srv.resolvePreloads(links)...)
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// resolvePreloads replaces logical asset names with fingerprinted URLs. Assets missing
+// from the manifest are dropped rather than preloading a URL that may not exist.
+func (srv *Server) resolvePreloads(links []PreloadLink) []PreloadLink {
+	resolved := make([]PreloadLink, 0, len(links))
+	for _, link := range links {
+		if link.Asset {
+			m, err := srv.AssetManifest()
+			if err != nil {
+				logger.Debug("Skipping preload, asset manifest unavailable", "asset", link.URL, "error", err)
+				continue
+			}
+			entry, ok := m.Lookup(link.URL)
+			if !ok {
+				logger.Debug("Skipping preload of unknown asset", "asset", link.URL)
+				continue
+			}
+			link.URL = entry.Path
+		}
+		resolved = append(resolved, link)
+	}
+	return resolved
+}
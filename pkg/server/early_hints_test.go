@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreloadLinkString(t *testing.T) {
+	tests := []struct {
+		link PreloadLink
+		want string
+	}{
+		{PreloadLink{URL: "/static/app.css", As: "style"}, "</static/app.css>; rel=preload; as=style"},
+		{PreloadLink{URL: "/fonts/a.woff2", As: "font", Type: "font/woff2", CrossOrigin: "anonymous"},
+			`</fonts/a.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin`},
+		{PreloadLink{URL: "/app.js", Rel: "modulepreload", CrossOrigin: "use-credentials"},
+			"</app.js>; rel=modulepreload; crossorigin=use-credentials"},
+	}
+	for _, tt := range tests {
+		if got := tt.link.String(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestWithPreloadSendsEarlyHints(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0o755)
+	os.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body{}"), 0o644)
+
+	srv, err := NewServer(
+		WithPreload("/page",
+			PreloadLink{URL: "css/app.css", As: "style", Asset: true},
+			PreloadLink{URL: "missing.js", As: "script", Asset: true},
+			PreloadLink{URL: "/logo.svg", As: "image"},
+		),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.Options.StaticDir = dir
+	var status int
+	srv.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	})
+	handler := srv.middleware.applyToMux(srv.mux)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lrw := &loggingResponseWriter{w, http.StatusOK, 0}
+		handler.ServeHTTP(lrw, r)
+		status = lrw.statusCode
+	}))
+	defer ts.Close()
+
+	m, err := srv.AssetManifest()
+	if err != nil {
+		t.Fatalf("AssetManifest: %v", err)
+	}
+	wantCSS := "<" + m.Assets["css/app.css"].Path + ">; rel=preload; as=style"
+
+	var hints []textproto.MIMEHeader
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints = append(hints, header)
+			}
+			return nil
+		},
+	}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, ts.URL+"/page", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	ts.Close() // waits for the handler, so status is set
+
+	if len(hints) != 1 {
+		t.Fatalf("expected one 103 response, got %d", len(hints))
+	}
+	want := []string{wantCSS, "</logo.svg>; rel=preload; as=image"}
+	for _, links := range [][]string{hints[0]["Link"], resp.Header["Link"]} {
+		if len(links) != len(want) || links[0] != want[0] || links[1] != want[1] {
+			t.Errorf("expected links %q, got %q", want, links)
+		}
+	}
+	if resp.StatusCode != http.StatusOK || status != http.StatusOK {
+		t.Errorf("expected final status 200, got %d (recorded %d)", resp.StatusCode, status)
+	}
+}
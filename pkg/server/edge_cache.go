@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	surrogateKeyHeader = "Surrogate-Key" // Fastly and Varnish
+	cacheTagHeader     = "Cache-Tag"     // Cloudflare
+
+	fastlyPurgeBatch     = 256
+	cloudflarePurgeBatch = 30
+)
+
+var edgePurgeClient = &http.Client{Timeout: 10 * time.Second}
+
+// AddSurrogateKeys tags the response with cache keys, so that a CDN can purge every
+// response that depends on some data with one request. The keys are sent as the
+// Surrogate-Key header (Fastly, Varnish) and the Cache-Tag header (Cloudflare). Keys must
+// not contain whitespace or commas; such keys are dropped. Call it before writing the
+// response header.
+//
+// Example:
+//
+//	srv.HandleFunc("GET /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+//		server.AddSurrogateKeys(w, "products", "product-"+r.PathValue("id"))
+//		...
+//	})
+func AddSurrogateKeys(w http.ResponseWriter, keys ...string) {
+	existing := strings.Fields(w.Header().Get(surrogateKeyHeader))
+	seen := make(map[string]bool, len(existing)+len(keys))
+	for _, key := range existing {
+		seen[key] = true
+	}
+	for _, key := range keys {
+		if key == "" || strings.ContainsAny(key, " \t\r\n,") {
+			logger.Warn("Dropping invalid surrogate key", "key", key)
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			existing = append(existing, key)
+		}
+	}
+	if len(existing) == 0 {
+		return
+	}
+	w.Header().Set(surrogateKeyHeader, strings.Join(existing, " "))
+	w.Header().Set(cacheTagHeader, strings.Join(existing, ","))
+}
+
+// EdgePurger invalidates cached responses by surrogate key at a CDN or caching proxy.
+type EdgePurger interface {
+	Purge(ctx context.Context, keys []string) error
+}
+
+// EdgePurgerFunc adapts an ordinary function to the EdgePurger interface.
+type EdgePurgerFunc func(ctx context.Context, keys []string) error
+
+// Purge calls f(ctx, keys).
+func (f EdgePurgerFunc) Purge(ctx context.Context, keys []string) error {
+	return f(ctx, keys)
+}
+
+// FastlyPurger purges keys through the Fastly API.
+type FastlyPurger struct {
+	ServiceID string
+	APIToken  string
+	Soft      bool         // Marks content stale instead of removing it
+	Client    *http.Client // Defaults to Server.HTTPClient, see sendPurge
+	BaseURL   string       // Defaults to https://api.fastly.com
+}
+
+// Purge implements EdgePurger.
+func (p *FastlyPurger) Purge(ctx context.Context, keys []string) error {
+	base := p.BaseURL
+	if base == "" {
+		base = "https://api.fastly.com"
+	}
+	endpoint := fmt.Sprintf("%s/service/%s/purge", strings.TrimSuffix(base, "/"), url.PathEscape(p.ServiceID))
+	for batch := range chunkStrings(keys, fastlyPurgeBatch) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Fastly-Key", p.APIToken)
+		req.Header.Set(surrogateKeyHeader, strings.Join(batch, " "))
+		if p.Soft {
+			req.Header.Set("Fastly-Soft-Purge", "1")
+		}
+		if err := sendPurge(p.Client, req, "fastly"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloudflarePurger purges cache tags through the Cloudflare API.
+type CloudflarePurger struct {
+	ZoneID   string
+	APIToken string
+	Client   *http.Client // Defaults to Server.HTTPClient, see sendPurge
+	BaseURL  string       // Defaults to https://api.cloudflare.com/client/v4
+}
+
+// Purge implements EdgePurger.
+func (p *CloudflarePurger) Purge(ctx context.Context, keys []string) error {
+	base := p.BaseURL
+	if base == "" {
+		base = "https://api.cloudflare.com/client/v4"
+	}
+	endpoint := fmt.Sprintf("%s/zones/%s/purge_cache", strings.TrimSuffix(base, "/"), url.PathEscape(p.ZoneID))
+	for batch := range chunkStrings(keys, cloudflarePurgeBatch) {
+		body, _ := json.Marshal(map[string][]string{"tags": batch})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", bearerTokenPrefix+p.APIToken)
+		req.Header.Set("Content-Type", "application/json")
+		if err := sendPurge(p.Client, req, "cloudflare"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VarnishPurger purges keys on Varnish instances that use the xkey module. Each URL is
+// sent a request carrying the keys, which the VCL passes to xkey.purge.
+type VarnishPurger struct {
+	URLs   []string
+	Method string       // Defaults to PURGE
+	Header string       // Defaults to xkey
+	Client *http.Client // Defaults to Server.HTTPClient, see sendPurge
+}
+
+// Purge implements EdgePurger.
+func (p *VarnishPurger) Purge(ctx context.Context, keys []string) error {
+	method, header := p.Method, p.Header
+	if method == "" {
+		method = "PURGE"
+	}
+	if header == "" {
+		header = "xkey"
+	}
+	var errs []error
+	for _, target := range p.URLs {
+		req, err := http.NewRequestWithContext(ctx, method, target, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		req.Header.Set(header, strings.Join(keys, " "))
+		errs = append(errs, sendPurge(p.Client, req, "varnish"))
+	}
+	return errors.Join(errs...)
+}
+
+// sendPurge sends a purge request. Purgers registered with WithEdgePurger default to the
+// server's shared client; outside a server a client with a 10s timeout is used.
+func sendPurge(client *http.Client, req *http.Request, provider string) error {
+	if client == nil {
+		client = edgePurgeClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s purge failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s purge failed with status %d: %s", provider, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// chunkStrings yields s in slices of at most n elements.
+func chunkStrings(s []string, n int) func(yield func([]string) bool) {
+	return func(yield func([]string) bool) {
+		for len(s) > 0 {
+			end := min(n, len(s))
+			if !yield(s[:end]) {
+				return
+			}
+			s = s[end:]
+		}
+	}
+}
+
+// WithEdgePurger configures the CDNs and caching proxies that PurgeSurrogateKeys purges.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithEdgePurger(&server.FastlyPurger{
+//			ServiceID: "SU1Z0isxPaozGVKXdv0eY",
+//			APIToken:  os.Getenv("FASTLY_API_TOKEN"),
+//		}),
+//	)
+func WithEdgePurger(purgers ...EdgePurger) ServerOptionFunc {
+	return func(srv *Server) error {
+		for _, p := range purgers {
+			if p == nil {
+				return fmt.Errorf("edge purger must not be nil")
+			}
+		}
+		srv.edgePurgers = append(srv.edgePurgers, purgers...)
+		return nil
+	}
+}
+
+// useSharedClientForPurgers lets the built-in purgers without a client of their own send
+// through the shared outbound client, once the options are applied.
+func (srv *Server) useSharedClientForPurgers() {
+	for _, p := range srv.edgePurgers {
+		var client **http.Client
+		switch p := p.(type) {
+		case *FastlyPurger:
+			client = &p.Client
+		case *CloudflarePurger:
+			client = &p.Client
+		case *VarnishPurger:
+			client = &p.Client
+		default:
+			continue
+		}
+		if *client == nil {
+			*client = srv.HTTPClient()
+		}
+	}
+}
+
+// OnSurrogateKeyPurge registers a hook that runs before the edge is purged, so that
+// in-process caches can drop entries tagged with the same keys.
+func (srv *Server) OnSurrogateKeyPurge(fn func(ctx context.Context, keys []string)) {
+	srv.edgePurgeMu.Lock()
+	defer srv.edgePurgeMu.Unlock()
+	srv.purgeHooks = append(srv.purgeHooks, fn)
+}
+
+// PurgeSurrogateKeys invalidates the responses tagged with the keys in all configured
+// edge caches. Purge after the change that invalidates the data is durable, e.g. from an
+// AfterCommit hook, so that the edge does not cache the old data again.
+//
+// Example:
+//
+//	server.AfterCommit(r.Context(), func(ctx context.Context) error {
+//		return srv.PurgeSurrogateKeys(ctx, "product-"+id)
+//	})
+func (srv *Server) PurgeSurrogateKeys(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	srv.edgePurgeMu.Lock()
+	hooks := append([]func(context.Context, []string){}, srv.purgeHooks...)
+	srv.edgePurgeMu.Unlock()
+	for _, hook := range hooks {
+		hook(ctx, keys)
+	}
+
+	var errs []error
+	for _, p := range srv.edgePurgers {
+		if err := p.Purge(ctx, keys); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		logger.Error("Edge purge failed", "keys", keys, "error", err)
+		return err
+	}
+	logger.Debug("Edge purged", "keys", keys, "purgers", len(srv.edgePurgers))
+	return nil
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAddSurrogateKeys(t *testing.T) {
+	rec := httptest.NewRecorder()
+	AddSurrogateKeys(rec, "products", "product-1")
+	AddSurrogateKeys(rec, "product-1", "bad key", "", "user-7")
+
+	if got := rec.Header().Get("Surrogate-Key"); got != "products product-1 user-7" {
+		t.Errorf("Surrogate-Key = %q", got)
+	}
+	if got := rec.Header().Get("Cache-Tag"); got != "products,product-1,user-7" {
+		t.Errorf("Cache-Tag = %q", got)
+	}
+}
+
+// purgeRecorder records the requests of a fake purge API.
+type purgeRecorder struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	status   int
+}
+
+func (p *purgeRecorder) server(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		p.mu.Lock()
+		p.requests = append(p.requests, r)
+		p.bodies = append(p.bodies, string(body))
+		p.mu.Unlock()
+		if p.status != 0 {
+			http.Error(w, "denied", p.status)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestFastlyPurger(t *testing.T) {
+	var rec purgeRecorder
+	ts := rec.server(t)
+	keys := make([]string, 300)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+	}
+
+	p := &FastlyPurger{ServiceID: "svc", APIToken: "secret", Soft: true, BaseURL: ts.URL}
+	if err := p.Purge(context.Background(), keys); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.requests) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(rec.requests))
+	}
+	first := rec.requests[0]
+	if first.URL.Path != "/service/svc/purge" || first.Header.Get("Fastly-Key") != "secret" || first.Header.Get("Fastly-Soft-Purge") != "1" {
+		t.Errorf("unexpected request %s %v", first.URL.Path, first.Header)
+	}
+	if n := len(strings.Fields(first.Header.Get("Surrogate-Key"))); n != 256 {
+		t.Errorf("first batch has %d keys", n)
+	}
+}
+
+func TestCloudflarePurger(t *testing.T) {
+	var rec purgeRecorder
+	ts := rec.server(t)
+
+	p := &CloudflarePurger{ZoneID: "zone", APIToken: "secret", BaseURL: ts.URL}
+	if err := p.Purge(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	req := rec.requests[0]
+	var body map[string][]string
+	json.Unmarshal([]byte(rec.bodies[0]), &body)
+	if req.URL.Path != "/zones/zone/purge_cache" || req.Header.Get("Authorization") != "Bearer secret" || !reflect.DeepEqual(body["tags"], []string{"a", "b"}) {
+		t.Errorf("unexpected request %s %v %s", req.URL.Path, req.Header, rec.bodies[0])
+	}
+
+	rec.status = http.StatusForbidden
+	if err := p.Purge(context.Background(), []string{"a"}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a status error, got %v", err)
+	}
+}
+
+func TestVarnishPurger(t *testing.T) {
+	var rec purgeRecorder
+	ts := rec.server(t)
+
+	p := &VarnishPurger{URLs: []string{ts.URL, ts.URL}}
+	if err := p.Purge(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.requests) != 2 || rec.requests[0].Method != "PURGE" || rec.requests[0].Header.Get("xkey") != "a b" {
+		t.Errorf("unexpected requests: %d %s %v", len(rec.requests), rec.requests[0].Method, rec.requests[0].Header)
+	}
+}
+
+func TestPurgeSurrogateKeys(t *testing.T) {
+	var purged [][]string
+	srv, err := NewServer(WithEdgePurger(
+		EdgePurgerFunc(func(ctx context.Context, keys []string) error {
+			purged = append(purged, keys)
+			return nil
+		}),
+		EdgePurgerFunc(func(ctx context.Context, keys []string) error {
+			return errors.New("cdn down")
+		}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hooked []string
+	srv.OnSurrogateKeyPurge(func(ctx context.Context, keys []string) {
+		hooked = append(hooked, keys...)
+	})
+
+	err = srv.PurgeSurrogateKeys(context.Background(), "product-1")
+	if err == nil || !strings.Contains(err.Error(), "cdn down") {
+		t.Errorf("expected the failing purger's error, got %v", err)
+	}
+	if !reflect.DeepEqual(purged, [][]string{{"product-1"}}) || !reflect.DeepEqual(hooked, []string{"product-1"}) {
+		t.Errorf("purged = %v, hooked = %v", purged, hooked)
+	}
+}
+
+func TestEdgePurgersUseSharedClient(t *testing.T) {
+	var rec purgeRecorder
+	ts := rec.server(t)
+
+	own := &http.Client{}
+	srv, err := NewServer(WithEdgePurger(&VarnishPurger{URLs: []string{ts.URL}}, &VarnishPurger{URLs: []string{ts.URL}, Client: own}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.PurgeSurrogateKeys(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.HTTPClientStats().Requests; got != 1 {
+		t.Errorf("expected 1 purge through the shared client, got %d", got)
+	}
+	if srv.edgePurgers[1].(*VarnishPurger).Client != own {
+		t.Error("expected a purger's own client to be kept")
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrorPagesConfig renders HTML error pages for clients that prefer text/html, such as
+// browsers navigating a web app. AuthMiddleware and RateLimitMiddleware use it for 401 and
+// 429 responses; API clients keep getting the plain text or JSON errors.
+//
+// Templates are html/template files keyed by status code and receive an ErrorPage. A
+// status without a template gets a minimal built-in page.
+type ErrorPagesConfig struct {
+	Templates  map[int]string `json:"templates,omitempty"`   // Template file per status code
+	LoginURL   string         `json:"login_url,omitempty"`   // Browsers without a valid token are redirected here instead of a 401 page
+	RedirectTo string         `json:"redirect_to,omitempty"` // Query parameter carrying the original URL (default "next")
+
+	pages map[int]*template.Template
+}
+
+// ErrorPage is the data passed to error page templates.
+type ErrorPage struct {
+	Status     int
+	Title      string // Status text, e.g. "Too Many Requests"
+	Message    string
+	Path       string
+	RetryAfter int // Seconds until the client may retry, 0 if unknown
+}
+
+var defaultErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.Status}} {{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .RetryAfter}}<p>Please try again in {{.RetryAfter}} second{{if ne .RetryAfter 1}}s{{end}}.</p>{{end}}
+</body>
+</html>
+`))
+
+// WithErrorPages renders HTML error pages and redirects to a login page for browser
+// clients. It can also be configured via the "error_pages" key in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithErrorPages(server.ErrorPagesConfig{
+//			Templates: map[int]string{http.StatusTooManyRequests: "template/errors/429.html"},
+//			LoginURL:  "/login",
+//		}),
+//	)
+func WithErrorPages(cfg ErrorPagesConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if err := cfg.compile(); err != nil {
+			return err
+		}
+		srv.Options.ErrorPages = &cfg
+		return nil
+	}
+}
+
+// compile parses the templates. It is a no-op once they are parsed.
+func (cfg *ErrorPagesConfig) compile() error {
+	if cfg.pages != nil {
+		return nil
+	}
+	if cfg.LoginURL != "" {
+		if _, err := url.Parse(cfg.LoginURL); err != nil {
+			return fmt.Errorf("invalid login URL %q: %w", cfg.LoginURL, err)
+		}
+	}
+	pages := make(map[int]*template.Template, len(cfg.Templates))
+	for status, file := range cfg.Templates {
+		tmpl, err := template.ParseFiles(file)
+		if err != nil {
+			return fmt.Errorf("failed to parse error page for status %d: %w", status, err)
+		}
+		pages[status] = tmpl
+	}
+	cfg.pages = pages
+	return nil
+}
+
+// render answers the request with an HTML error page if the client prefers HTML, and
+// reports whether it did. Callers fall back to their usual error response otherwise.
+func (cfg *ErrorPagesConfig) render(w http.ResponseWriter, r *http.Request, page ErrorPage) bool {
+	if cfg == nil || !prefersHTML(r) {
+		return false
+	}
+	if page.Status == http.StatusUnauthorized && cfg.LoginURL != "" {
+		http.Redirect(w, r, cfg.loginRedirect(r), http.StatusSeeOther)
+		return true
+	}
+
+	tmpl := cfg.pages[page.Status]
+	if tmpl == nil {
+		tmpl = defaultErrorPage
+	}
+	page.Title = http.StatusText(page.Status)
+	page.Path = r.URL.Path
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, page); err != nil {
+		logger.Error("Failed to render error page", "status", page.Status, "error", err)
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(page.Status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logger.Debug("Failed to write error page", "error", err)
+	}
+	return true
+}
+
+// loginRedirect returns the login URL with the requested URL as return address.
+func (cfg *ErrorPagesConfig) loginRedirect(r *http.Request) string {
+	login, _ := url.Parse(cfg.LoginURL)
+	param := cfg.RedirectTo
+	if param == "" {
+		param = "next"
+	}
+	q := login.Query()
+	q.Set(param, r.URL.RequestURI())
+	login.RawQuery = q.Encode()
+	return login.String()
+}
+
+// prefersHTML reports whether the client ranks text/html above JSON and plain text, as
+// browsers do for navigations. Wildcards alone do not count, so fetch() and API clients
+// sending "*/*" keep getting machine-readable errors.
+func prefersHTML(r *http.Request) bool {
+	return negotiateContentType(r.Header.Get("Accept"), "text/html", "application/json", "text/plain") == "text/html"
+}
+
+// negotiateContentType returns the offer the Accept header ranks highest, preferring
+// earlier offers on ties. Only offers listed explicitly count, not wildcard matches; ""
+// means that none is listed.
+func negotiateContentType(accept string, offers ...string) string {
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality the Accept header assigns to mediaType, 0 if the
+// type is not listed.
+func acceptQuality(accept, mediaType string) float64 {
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mt != mediaType {
+			continue
+		}
+		if v, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0
+			}
+			return q
+		}
+		return 1
+	}
+	return 0
+}
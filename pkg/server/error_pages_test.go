@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{browserAccept, true},
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/json, text/html;q=0.5", false},
+		{"text/html;q=0.9, application/json;q=0.8", true},
+		{"text/html;q=0", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		if got := prefersHTML(r); got != tt.want {
+			t.Errorf("prefersHTML(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestAuthMiddlewareErrorPages(t *testing.T) {
+	options := &ServerOptions{
+		AuthTokenValidatorFunc: func(token string) (bool, error) { return false, nil },
+		ErrorPages:             &ErrorPagesConfig{LoginURL: "/login?app=web"},
+	}
+	handler := AuthMiddleware(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/account?tab=billing", nil)
+	req.Header.Set("Accept", browserAccept)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login?app=web&next=%2Faccount%3Ftab%3Dbilling" {
+		t.Errorf("browser: got %d to %q, want a login redirect", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// API clients are not redirected
+	req = httptest.NewRequest(http.MethodGet, "/account", nil)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "Bearer token required") {
+		t.Errorf("API client: got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Without a login URL browsers get the built-in page
+	options.ErrorPages = &ErrorPagesConfig{}
+	req = httptest.NewRequest(http.MethodGet, "/account", nil)
+	req.Header.Set("Accept", browserAccept)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "<h1>Unauthorized</h1>") {
+		t.Errorf("browser without login URL: got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRateLimitErrorPage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "429.html")
+	if err := os.WriteFile(file, []byte(`<p>Slow down on {{.Path}}, retry in {{.RetryAfter}}s</p>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServer(
+		WithRateLimit(1, 1),
+		WithErrorPages(ErrorPagesConfig{Templates: map[int]string{http.StatusTooManyRequests: file}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RateLimitMiddleware(srv)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	serve(browserAccept)
+	if rec := serve(browserAccept); rec.Code != http.StatusTooManyRequests || rec.Body.String() != "<p>Slow down on /search, retry in 1s</p>" {
+		t.Errorf("browser: got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := serve("application/json"); rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("API client: expected a JSON error, got %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestWithErrorPagesRejectsMissingTemplates(t *testing.T) {
+	if _, err := NewServer(WithErrorPages(ErrorPagesConfig{Templates: map[int]string{429: "does-not-exist.html"}})); err == nil {
+		t.Error("expected an error for a missing template file")
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+const errorScopeKey contextKey = "errorScope"
+
+// ErrorReporter receives errors for forwarding to an error tracking or APM backend.
+// It is called for recovered panics (*PanicError), 5xx responses (*StatusError), MCP tool
+// failures (*MCPToolError), and errors passed to ReportError. r is nil for MCP tool
+// failures, which may not originate from an HTTP request.
+// Reporters run synchronously on the request path and should hand off slow work.
+type ErrorReporter func(ctx context.Context, err error, r *http.Request)
+
+// PanicError is reported when RecoveryMiddleware recovers from a panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// StatusError is reported when a handler responds with a 5xx status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// MCPToolError is reported when an MCP tool call fails.
+type MCPToolError struct {
+	Tool string
+	Err  error
+}
+
+func (e *MCPToolError) Error() string {
+	return fmt.Sprintf("MCP tool %s failed: %v", e.Tool, e.Err)
+}
+
+func (e *MCPToolError) Unwrap() error {
+	return e.Err
+}
+
+// errorScope carries the reporter through a request and remembers whether an error has
+// already been reported, so a recovered panic is not reported again as a 500 response.
+type errorScope struct {
+	reporter ErrorReporter
+	reported atomic.Bool
+}
+
+func (s *errorScope) report(r *http.Request, err error) {
+	s.reported.Store(true)
+	s.reporter(r.Context(), err, r)
+}
+
+// WithErrorReporter plugs an error tracking backend into the server with one option.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithErrorReporter(func(ctx context.Context, err error, r *http.Request) {
+//			sentry.CaptureException(err)
+//		}),
+//	)
+func WithErrorReporter(reporter ErrorReporter) ServerOptionFunc {
+	return func(srv *Server) error {
+		if reporter == nil {
+			return fmt.Errorf("error reporter must not be nil")
+		}
+		srv.Options.ErrorReporter = reporter
+		srv.middleware.reporter = reporter
+		return nil
+	}
+}
+
+// ReportError passes err to the configured ErrorReporter. It is a no-op if no reporter is
+// configured.
+func ReportError(r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	if scope, ok := r.Context().Value(errorScopeKey).(*errorScope); ok {
+		scope.report(r, err)
+	}
+}
+
+// serveWithErrorReporting serves the request and reports 5xx responses that were not
+// already reported.
+func serveWithErrorReporting(reporter ErrorReporter, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	scope := &errorScope{reporter: reporter}
+	r = r.WithContext(context.WithValue(r.Context(), errorScopeKey, scope))
+	lrw := &loggingResponseWriter{w, http.StatusOK, 0}
+	next.ServeHTTP(lrw, r)
+	if lrw.statusCode >= http.StatusInternalServerError && !scope.reported.Load() {
+		scope.report(r, &StatusError{StatusCode: lrw.statusCode})
+	}
+}
+
+// reportToolError forwards an MCP tool failure to the configured reporter.
+func (h *MCPHandler) reportToolError(ctx context.Context, tool string, err error) {
+	if h.reporter != nil {
+		h.reporter(ctx, &MCPToolError{Tool: tool, Err: err}, nil)
+	}
+}
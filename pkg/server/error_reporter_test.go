@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type reportedError struct {
+	err error
+	req *http.Request
+}
+
+type errorCollector struct {
+	mu      sync.Mutex
+	reports []reportedError
+}
+
+func (c *errorCollector) report(ctx context.Context, err error, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reports = append(c.reports, reportedError{err: err, req: r})
+}
+
+func (c *errorCollector) all() []reportedError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]reportedError(nil), c.reports...)
+}
+
+type failingTool struct{}
+
+func (t *failingTool) Name() string        { return "failing_tool" }
+func (t *failingTool) Description() string { return "Always fails" }
+func (t *failingTool) Schema() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *failingTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return nil, errors.New("database unreachable")
+}
+
+func TestErrorReporterHTTP(t *testing.T) {
+	collector := &errorCollector{}
+	srv, err := NewServer(WithErrorReporter(collector.report))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	srv.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	srv.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	srv.HandleFunc("/manual", func(w http.ResponseWriter, r *http.Request) {
+		ReportError(r, errors.New("degraded"))
+	})
+	handler := srv.middleware.applyToMux(srv.mux)
+
+	tests := []struct {
+		path   string
+		status int
+		check  func(t *testing.T, err error)
+	}{
+		{"/panic", http.StatusInternalServerError, func(t *testing.T, err error) {
+			var panicErr *PanicError
+			if !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+				t.Errorf("expected PanicError with stack, got %#v", err)
+			}
+		}},
+		{"/unavailable", http.StatusServiceUnavailable, func(t *testing.T, err error) {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("expected StatusError 503, got %#v", err)
+			}
+		}},
+		{"/missing", http.StatusNotFound, nil},
+		{"/manual", http.StatusOK, func(t *testing.T, err error) {
+			if err.Error() != "degraded" {
+				t.Errorf("expected manually reported error, got %v", err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			collector.mu.Lock()
+			collector.reports = nil
+			collector.mu.Unlock()
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+
+			reports := collector.all()
+			if tt.check == nil {
+				if len(reports) != 0 {
+					t.Fatalf("expected no reports, got %v", reports)
+				}
+				return
+			}
+			if len(reports) != 1 {
+				t.Fatalf("expected exactly one report, got %d", len(reports))
+			}
+			if reports[0].req == nil || reports[0].req.URL.Path != tt.path {
+				t.Errorf("expected request for %s to be passed to the reporter", tt.path)
+			}
+			tt.check(t, reports[0].err)
+		})
+	}
+}
+
+func TestErrorReporterMCPToolFailure(t *testing.T) {
+	collector := &errorCollector{}
+	srv, err := NewServer(
+		WithMCPSupport("test", "1.0.0"),
+		WithErrorReporter(collector.report),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.mcpHandler.RegisterTool(&failingTool{})
+
+	srv.mcpHandler.ProcessRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"failing_tool","arguments":{}}}`))
+
+	reports := collector.all()
+	if len(reports) != 1 {
+		t.Fatalf("expected one report, got %d", len(reports))
+	}
+	var toolErr *MCPToolError
+	if !errors.As(reports[0].err, &toolErr) || toolErr.Tool != "failing_tool" {
+		t.Fatalf("expected MCPToolError for failing_tool, got %#v", reports[0].err)
+	}
+	if toolErr.Err.Error() != "database unreachable" {
+		t.Errorf("expected wrapped tool error, got %v", toolErr.Err)
+	}
+	if reports[0].req != nil {
+		t.Error("expected nil request for MCP tool failures")
+	}
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFanoutWriteTimeout bounds how long one slow client can hold up a broadcast.
+const defaultFanoutWriteTimeout = 5 * time.Second
+
+// fanoutFrameKind selects which encoding of a broadcast a subscriber receives.
+type fanoutFrameKind int
+
+const (
+	fanoutSSE fanoutFrameKind = iota
+	fanoutWebSocket
+)
+
+// Fanout broadcasts events to many SSE and WebSocket clients. Each event is encoded once
+// and the same byte slice is written to every client, instead of every connection
+// re-encoding the payload. Every write has its own deadline, and clients that miss it or
+// fail are dropped, so one stalled connection cannot hold up the rest. Idle SSE streams
+// get a keepalive comment every 15s unless SetHeartbeat changes the interval.
+//
+// Example:
+//
+//	events := server.NewFanout(2 * time.Second)
+//	srv.Handle("/events", events.ServeHTTP)
+//
+//	go func() {
+//		for price := range prices {
+//			events.Broadcast("price", price)
+//		}
+//	}()
+type Fanout struct {
+	writeTimeout time.Duration
+
+	mu          sync.RWMutex
+	subscribers map[*FanoutSubscriber]struct{}
+	heartbeat   time.Duration
+}
+
+// FanoutSubscriber is one client of a Fanout.
+type FanoutSubscriber struct {
+	kind  fanoutFrameKind
+	write func(frame []byte, deadline time.Time) error
+
+	mu   sync.Mutex // serialises writes to the connection
+	done chan struct{}
+	once sync.Once
+}
+
+// Done is closed when the subscriber has been removed, e.g. after a failed write.
+func (s *FanoutSubscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// NewFanout creates a Fanout whose per-client writes time out after writeTimeout
+// (default 5s when zero or negative).
+func NewFanout(writeTimeout time.Duration) *Fanout {
+	if writeTimeout <= 0 {
+		writeTimeout = defaultFanoutWriteTimeout
+	}
+	return &Fanout{
+		writeTimeout: writeTimeout,
+		subscribers:  make(map[*FanoutSubscriber]struct{}),
+		heartbeat:    defaultSSEHeartbeat,
+	}
+}
+
+// SetHeartbeat sets how often SSE streams get a keepalive comment; zero or negative
+// disables heartbeats. It applies to clients that connect afterwards.
+func (f *Fanout) SetHeartbeat(interval time.Duration) {
+	f.mu.Lock()
+	f.heartbeat = interval
+	f.mu.Unlock()
+}
+
+// ServeHTTP streams broadcasts to the client as Server-Sent Events until the client
+// disconnects or falls behind.
+func (f *Fanout) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		logger.Error("Streaming not supported", "error", err)
+		return
+	}
+
+	sub := f.add(fanoutSSE, func(frame []byte, deadline time.Time) error {
+		if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+		// Idle streams must not be cut off by the server's WriteTimeout
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	})
+
+	defer func() {
+		f.Remove(sub)
+		// Wait for an in-flight write, w must not be used after the handler returns
+		sub.mu.Lock()
+		sub.mu.Unlock()
+	}()
+
+	f.mu.RLock()
+	interval := f.heartbeat
+	f.mu.RUnlock()
+	var heartbeat <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case <-heartbeat:
+			// A failed heartbeat removes the subscriber, which reaps clients that went
+			// away without closing the connection even when nothing is broadcast
+			f.deliver(sub, sseHeartbeatFrame, time.Now().Add(f.writeTimeout))
+		}
+	}
+}
+
+// AddWebSocket subscribes a WebSocket connection. Broadcasts are sent as text messages
+// holding the JSON payload. The caller keeps reading from conn, must not write to it
+// while subscribed, and calls Remove when the connection closes.
+func (f *Fanout) AddWebSocket(conn *Conn) *FanoutSubscriber {
+	return f.add(fanoutWebSocket, func(frame []byte, deadline time.Time) error {
+		if err := conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+		return conn.WriteMessage(TextMessage, frame)
+	})
+}
+
+func (f *Fanout) add(kind fanoutFrameKind, write func([]byte, time.Time) error) *FanoutSubscriber {
+	sub := &FanoutSubscriber{kind: kind, write: write, done: make(chan struct{})}
+	f.mu.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.mu.Unlock()
+	return sub
+}
+
+// Remove unsubscribes a client. It is safe to call more than once.
+func (f *Fanout) Remove(sub *FanoutSubscriber) {
+	f.mu.Lock()
+	delete(f.subscribers, sub)
+	f.mu.Unlock()
+	sub.once.Do(func() { close(sub.done) })
+}
+
+// Len returns the number of subscribed clients.
+func (f *Fanout) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subscribers)
+}
+
+// Broadcast sends an event to all subscribers and returns how many received it. data is
+// JSON-encoded unless it is a string or []byte. SSE clients receive it as an event of the
+// given type (no event field when empty), WebSocket clients receive the payload only.
+func (f *Fanout) Broadcast(event string, data any) (int, error) {
+	payload, err := fanoutPayload(data)
+	if err != nil {
+		return 0, err
+	}
+	frame, err := encodeSSEFrame("", event, payload)
+	if err != nil {
+		return 0, err
+	}
+	frames := [...][]byte{
+		fanoutSSE:       frame,
+		fanoutWebSocket: payload,
+	}
+
+	f.mu.RLock()
+	subs := make([]*FanoutSubscriber, 0, len(f.subscribers))
+	for sub := range f.subscribers {
+		subs = append(subs, sub)
+	}
+	f.mu.RUnlock()
+	if len(subs) == 0 {
+		return 0, nil
+	}
+
+	// Writes are spread over a bounded number of goroutines; a slow client delays only
+	// the clients after it in the same chunk, and at most by the write timeout
+	deadline := time.Now().Add(f.writeTimeout)
+	workers := min(runtime.GOMAXPROCS(0), len(subs))
+	chunk := (len(subs) + workers - 1) / workers
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	for start := 0; start < len(subs); start += chunk {
+		batch := subs[start:min(start+chunk, len(subs))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := 0
+			for _, sub := range batch {
+				if f.deliver(sub, frames[sub.kind], deadline) {
+					n++
+				}
+			}
+			mu.Lock()
+			delivered += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return delivered, nil
+}
+
+// deliver writes one frame, dropping the subscriber on failure.
+func (f *Fanout) deliver(sub *FanoutSubscriber, frame []byte, deadline time.Time) bool {
+	sub.mu.Lock()
+	select {
+	case <-sub.done:
+		sub.mu.Unlock()
+		return false
+	default:
+	}
+	err := sub.write(frame, deadline)
+	sub.mu.Unlock()
+	if err != nil {
+		logger.Debug("Dropping fan-out subscriber", "error", err)
+		f.Remove(sub)
+		return false
+	}
+	return true
+}
+
+func fanoutPayload(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// encodeSSEFrame formats an SSE event, splitting multi-line payloads into data lines at
+// each CRLF, CR, or LF, as clients do. The ID and event type are omitted when empty, and
+// rejected if they contain line breaks, which would inject fields or events.
+func encodeSSEFrame(id, event string, payload []byte) ([]byte, error) {
+	if strings.ContainsAny(id, "\r\n\x00") {
+		return nil, fmt.Errorf("SSE event ID must not contain line breaks or NUL: %q", id)
+	}
+	if strings.ContainsAny(event, "\r\n") {
+		return nil, fmt.Errorf("SSE event type must not contain line breaks: %q", event)
+	}
+	var b bytes.Buffer
+	b.Grow(len(id) + len(event) + len(payload) + 24)
+	if id != "" {
+		b.WriteString("id: ")
+		b.WriteString(id)
+		b.WriteByte('\n')
+	}
+	if event != "" {
+		b.WriteString("event: ")
+		b.WriteString(event)
+		b.WriteByte('\n')
+	}
+	for {
+		i := bytes.IndexAny(payload, "\r\n")
+		if i < 0 {
+			break
+		}
+		b.WriteString("data: ")
+		b.Write(payload[:i])
+		b.WriteByte('\n')
+		if payload[i] == '\r' && i+1 < len(payload) && payload[i+1] == '\n' {
+			i++
+		}
+		payload = payload[i+1:]
+	}
+	b.WriteString("data: ")
+	b.Write(payload)
+	b.WriteString("\n\n")
+	return b.Bytes(), nil
+}
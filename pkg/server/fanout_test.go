@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFanoutBroadcastsToSSEClients(t *testing.T) {
+	fanout := NewFanout(time.Second)
+	ts := httptest.NewServer(fanout)
+	defer ts.Close()
+
+	var readers []*bufio.Reader
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected event stream, got %q", ct)
+		}
+		readers = append(readers, bufio.NewReader(resp.Body))
+	}
+	waitFor(t, func() bool { return fanout.Len() == 2 })
+
+	n, err := fanout.Broadcast("tick", map[string]int{"n": 1})
+	if err != nil || n != 2 {
+		t.Fatalf("expected delivery to 2 clients, got %d, %v", n, err)
+	}
+	for i, reader := range readers {
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("client %d: %v", i, err)
+			}
+			if line == "\n" {
+				break
+			}
+			frame.WriteString(line)
+		}
+		if got := frame.String(); got != "event: tick\ndata: {\"n\":1}\n" {
+			t.Errorf("client %d: unexpected frame %q", i, got)
+		}
+	}
+
+	ts.CloseClientConnections()
+	waitFor(t, func() bool { return fanout.Len() == 0 })
+}
+
+func TestFanoutDropsFailingSubscribers(t *testing.T) {
+	fanout := NewFanout(0)
+	var got []byte
+	healthy := fanout.add(fanoutWebSocket, func(frame []byte, deadline time.Time) error {
+		if time.Until(deadline) <= 0 || time.Until(deadline) > defaultFanoutWriteTimeout {
+			t.Errorf("unexpected deadline %s", deadline)
+		}
+		got = frame
+		return nil
+	})
+	failing := fanout.add(fanoutSSE, func([]byte, time.Time) error {
+		return errors.New("i/o timeout")
+	})
+
+	n, err := fanout.Broadcast("", []byte("raw"))
+	if err != nil || n != 1 {
+		t.Fatalf("expected one delivery, got %d, %v", n, err)
+	}
+	if string(got) != "raw" {
+		t.Errorf("expected WebSocket subscriber to get the bare payload, got %q", got)
+	}
+	select {
+	case <-failing.Done():
+	default:
+		t.Error("expected failing subscriber to be removed")
+	}
+	if fanout.Len() != 1 {
+		t.Errorf("expected 1 subscriber left, got %d", fanout.Len())
+	}
+	fanout.Remove(healthy)
+	fanout.Remove(healthy)
+
+	if _, err := fanout.Broadcast("bad\nevent", "x"); err == nil {
+		t.Error("expected error for event type with a line break")
+	}
+}
+
+func TestEncodeSSEFrame(t *testing.T) {
+	tests := []struct {
+		id, event, payload string
+		want               string
+	}{
+		{"", "note", "line one\r\nline two", "event: note\ndata: line one\ndata: line two\n\n"},
+		{"", "", "", "data: \n\n"},
+		{"7", "", "a\rb\nc\r\n\rd", "id: 7\ndata: a\ndata: b\ndata: c\ndata: \ndata: d\n\n"},
+		{"", "", "trailing\r", "data: trailing\ndata: \n\n"},
+	}
+	for _, tt := range tests {
+		got, err := encodeSSEFrame(tt.id, tt.event, []byte(tt.payload))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("encodeSSEFrame(%q, %q, %q) = %q, %v, want %q", tt.id, tt.event, tt.payload, got, err, tt.want)
+		}
+	}
+
+	for _, field := range []struct{ id, event string }{{"", "a\rb"}, {"", "a\nevent: x"}, {"1\r", ""}, {"1\ndata: x", ""}} {
+		if _, err := encodeSSEFrame(field.id, field.event, nil); err == nil {
+			t.Errorf("expected line breaks in id %q or event %q to be rejected", field.id, field.event)
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type fanoutBenchEvent struct {
+	Symbol string    `json:"symbol"`
+	Price  float64   `json:"price"`
+	Time   time.Time `json:"time"`
+	Tags   []string  `json:"tags"`
+}
+
+var benchEvent = fanoutBenchEvent{Symbol: "HYPR", Price: 42.5, Time: time.Unix(1700000000, 0), Tags: []string{"equity", "nasdaq"}}
+
+const fanoutBenchClients = 5000
+
+// BenchmarkFanoutBroadcast measures one broadcast to many SSE clients with the event
+// encoded once and shared.
+func BenchmarkFanoutBroadcast(b *testing.B) {
+	fanout := NewFanout(time.Second)
+	for i := 0; i < fanoutBenchClients; i++ {
+		fanout.add(fanoutSSE, func(frame []byte, _ time.Time) error {
+			_, err := io.Discard.Write(frame)
+			return err
+		})
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := fanout.Broadcast("price", benchEvent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFanoutPerClientEncoding is the baseline of encoding the event for every client.
+func BenchmarkFanoutPerClientEncoding(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for c := 0; c < fanoutBenchClients; c++ {
+			data, err := json.Marshal(benchEvent)
+			if err != nil {
+				b.Fatal(err)
+			}
+			msg := &SSEMessage{Event: "price", Data: string(data)}
+			io.WriteString(io.Discard, msg.String())
+		}
+	}
+}
+
+func TestFanoutHeartbeat(t *testing.T) {
+	fanout := NewFanout(time.Second)
+	fanout.SetHeartbeat(10 * time.Millisecond)
+	ts := httptest.NewServer(fanout)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != ": keepalive\n" {
+		t.Errorf("expected keepalive comment, got %q: %v", line, err)
+	}
+}
@@ -0,0 +1,554 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// gatewayLimiterMaxIdle is how long an unused per-client limiter of a gateway route is kept.
+const gatewayLimiterMaxIdle = 10 * time.Minute
+
+// GatewayConfig declares proxied routes so hyperserve can run as a configuration-driven
+// edge gateway. It is read from a JSON file, see WithGatewayConfig.
+//
+// Example file:
+//
+//	{
+//	  "upstreams": {
+//	    "users": {"targets": ["http://10.0.0.1:8080", "http://10.0.0.2:8080"], "timeout": 5000000000,
+//	              "health_check": {"path": "/healthz"}}
+//	  },
+//	  "routes": [
+//	    {"path": "/api/users/", "upstream": "users", "strip_prefix": true, "auth": true,
+//	     "rate_limit": {"limit": 50, "burst": 100}}
+//	  ]
+//	}
+type GatewayConfig struct {
+	Upstreams map[string]GatewayUpstream `json:"upstreams"`
+	Routes    []GatewayRoute             `json:"routes"`
+}
+
+// GatewayUpstream is a named set of backends. Requests are balanced round-robin across
+// the targets that a health check has not ejected (see GatewayHealthCheck). Failed
+// requests are only retried when they have no body and are idempotent according to the
+// route metadata (see Server.IsIdempotent). WebSocket upgrades are tunnelled to the
+// target; they are not subject to Timeout but are closed after WebSocketIdleTimeout
+// without traffic in either direction.
+type GatewayUpstream struct {
+	Targets              []string      `json:"targets"`                          // Base URLs, e.g. http://10.0.0.1:8080
+	Timeout              time.Duration `json:"timeout,omitempty"`                // Per-request timeout, 0 for none
+	Retries              int           `json:"retries,omitempty"`                // Attempts on other targets after a connection failure
+	WebSocketIdleTimeout time.Duration `json:"websocket_idle_timeout,omitempty"` // 0 keeps idle WebSocket connections open
+	// Affinity keeps a client on the same target, for stateful backends
+	Affinity *GatewayAffinity `json:"affinity,omitempty"`
+	// HealthCheck ejects failing targets, nil keeps every target in rotation
+	HealthCheck *GatewayHealthCheck `json:"health_check,omitempty"`
+}
+
+// GatewayAffinity pins clients to one target of an upstream so that stateful backends,
+// such as WebSocket or long-polling servers, keep seeing the same client. With Header set,
+// the header value is hashed to choose the target; otherwise the gateway sets a cookie
+// naming the target it picked. Each upstream has its own cookie, so clients can be pinned
+// to targets of several upstreams at once. A retry after a failed request, or the
+// ejection of the target by a health check, moves the client to another target.
+type GatewayAffinity struct {
+	Cookie string        `json:"cookie,omitempty"`  // Cookie name (default "hs_upstream_" and the upstream name)
+	Header string        `json:"header,omitempty"`  // Request header to hash, e.g. X-Session-ID
+	MaxAge time.Duration `json:"max_age,omitempty"` // Cookie lifetime, 0 for a browser-session cookie
+}
+
+// GatewayRoute forwards requests to an upstream. Paths ending in a slash match the whole
+// subtree, other paths match exactly, as with http.ServeMux.
+type GatewayRoute struct {
+	Path        string             `json:"path"`
+	Upstream    string             `json:"upstream"`
+	Methods     []string           `json:"methods,omitempty"`      // Allowed methods, empty allows all
+	StripPrefix bool               `json:"strip_prefix,omitempty"` // Remove Path before forwarding
+	Auth        bool               `json:"auth,omitempty"`         // Require a bearer token accepted by AuthTokenValidatorFunc
+	RateLimit   *RateLimitOverride `json:"rate_limit,omitempty"`   // Per-client limit, keyed like RateLimitMiddleware
+}
+
+// LoadGatewayConfig reads and validates a gateway configuration file.
+func LoadGatewayConfig(path string) (*GatewayConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gateway config: %w", err)
+	}
+	var cfg GatewayConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse gateway config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid gateway config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks that every route references a defined upstream with valid targets.
+func (cfg *GatewayConfig) Validate() error {
+	for name, upstream := range cfg.Upstreams {
+		if len(upstream.Targets) == 0 {
+			return fmt.Errorf("upstream %q has no targets", name)
+		}
+		if upstream.Retries < 0 {
+			return fmt.Errorf("upstream %q retries must not be negative", name)
+		}
+		if upstream.WebSocketIdleTimeout < 0 {
+			return fmt.Errorf("upstream %q websocket idle timeout must not be negative", name)
+		}
+		if a := upstream.Affinity; a != nil && a.Cookie != "" && !isCookieName(a.Cookie) {
+			return fmt.Errorf("upstream %q affinity cookie name is invalid: %q", name, a.Cookie)
+		}
+		if hc := upstream.HealthCheck; hc != nil {
+			if err := hc.validate(); err != nil {
+				return fmt.Errorf("upstream %q: %w", name, err)
+			}
+		}
+		for _, target := range upstream.Targets {
+			if _, err := parseGatewayTarget(target); err != nil {
+				return fmt.Errorf("upstream %q: %w", name, err)
+			}
+		}
+	}
+	seen := make(map[string]bool, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("route path must start with '/': %q", route.Path)
+		}
+		if seen[route.Path] {
+			return fmt.Errorf("duplicate route %s", route.Path)
+		}
+		seen[route.Path] = true
+		if _, ok := cfg.Upstreams[route.Upstream]; !ok {
+			return fmt.Errorf("route %s references unknown upstream %q", route.Path, route.Upstream)
+		}
+		if rl := route.RateLimit; rl != nil && (rl.Limit <= 0 || rl.Burst <= 0) {
+			return fmt.Errorf("route %s rate limit needs a positive limit and burst", route.Path)
+		}
+	}
+	return nil
+}
+
+func parseGatewayTarget(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("target %q must be an absolute http(s) URL", target)
+	}
+	return u, nil
+}
+
+// WithGatewayConfig proxies the routes declared in a JSON gateway config file (see
+// GatewayConfig). The file is loaded by NewServer, and an invalid file fails startup.
+// When reloadInterval is positive the file is checked for changes while the server runs
+// and valid changes are applied without a restart; ReloadGateway applies them on demand.
+// Routes use the server's AuthTokenValidatorFunc and RateLimitKeyFunc. The file can also
+// be set via "gateway_config_file" in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithAuthTokenValidator(validateToken),
+//		server.WithGatewayConfig("gateway.json", 10*time.Second),
+//	)
+func WithGatewayConfig(path string, reloadInterval time.Duration) ServerOptionFunc {
+	return func(srv *Server) error {
+		if path == "" {
+			return fmt.Errorf("gateway config path must not be empty")
+		}
+		srv.Options.GatewayConfigFile = path
+		srv.Options.GatewayReloadInterval = reloadInterval
+		return nil
+	}
+}
+
+// ReloadGateway re-reads the gateway config file and swaps in the new routes. If the file
+// is invalid the current routes stay in place and the error is returned.
+func (srv *Server) ReloadGateway() error {
+	if srv.gateway == nil {
+		return fmt.Errorf("no gateway configured")
+	}
+	return srv.gateway.reload()
+}
+
+// GatewayConfig returns the gateway configuration currently in effect, or nil.
+func (srv *Server) GatewayConfig() *GatewayConfig {
+	if srv.gateway == nil {
+		return nil
+	}
+	return srv.gateway.state.Load().config
+}
+
+// gateway serves the configured routes. Paths are mounted on the server mux once and stay
+// mounted across reloads; requests for routes that were removed get 404.
+type gateway struct {
+	srv  *Server
+	path string
+
+	mu      sync.Mutex // serialises reloads
+	modTime time.Time
+	mounted map[string]bool
+	state   atomic.Pointer[gatewayState]
+	tunnels gatewayTunnels
+}
+
+type gatewayState struct {
+	config    *GatewayConfig
+	routes    []*gatewayRoute // longest path first
+	upstreams map[string]*gatewayUpstream
+}
+
+type gatewayRoute struct {
+	GatewayRoute
+	handler http.Handler
+}
+
+func newGateway(srv *Server, path string) (*gateway, error) {
+	g := &gateway{srv: srv, path: path, mounted: make(map[string]bool)}
+	g.tunnels.upgrades = &srv.websocketConnections
+	if err := g.reload(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *gateway) reload() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	info, err := os.Stat(g.path)
+	if err != nil {
+		return fmt.Errorf("failed to read gateway config: %w", err)
+	}
+	cfg, err := LoadGatewayConfig(g.path)
+	if err != nil {
+		return err
+	}
+	state, err := g.build(cfg)
+	if err != nil {
+		return err
+	}
+	for _, route := range state.routes {
+		if err := g.mount(route.Path); err != nil {
+			return err
+		}
+	}
+	g.state.Store(state)
+	g.modTime = info.ModTime()
+	logger.Info("Gateway config loaded", "file", g.path, "routes", len(state.routes), "upstreams", len(cfg.Upstreams))
+	return nil
+}
+
+// mount registers path on the server mux. ServeMux panics on conflicting patterns, which
+// is turned into an error so a bad reload cannot crash the server.
+func (g *gateway) mount(path string) (err error) {
+	if g.mounted[path] {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("gateway route %s conflicts with an existing route: %v", path, r)
+		}
+	}()
+	g.srv.mux.Handle(path, g)
+	g.srv.registerRoute(path)
+	g.mounted[path] = true
+	return nil
+}
+
+func (g *gateway) build(cfg *GatewayConfig) (*gatewayState, error) {
+	state := &gatewayState{config: cfg, upstreams: make(map[string]*gatewayUpstream, len(cfg.Upstreams))}
+	var prev map[string]*gatewayUpstream
+	if current := g.state.Load(); current != nil {
+		prev = current.upstreams
+	}
+	upstreams := make(map[string]http.Handler, len(cfg.Upstreams))
+	for name, upstream := range cfg.Upstreams {
+		u, err := newGatewayUpstream(name, upstream, prev[name])
+		if err != nil {
+			return nil, err
+		}
+		state.upstreams[name] = u
+		upstreams[name] = newGatewayProxy(u, g.srv.outbound().transport, g.srv.IsIdempotent, &g.tunnels)
+	}
+
+	for _, route := range cfg.Routes {
+		handler := upstreams[route.Upstream]
+		if route.StripPrefix {
+			handler = stripGatewayPrefix(route.Path, handler)
+		}
+		if route.RateLimit != nil {
+			handler = g.srv.gatewayRateLimit(*route.RateLimit)(handler)
+		}
+		if route.Auth {
+			handler = AuthMiddleware(g.srv.Options)(handler)
+		}
+		state.routes = append(state.routes, &gatewayRoute{GatewayRoute: route, handler: handler})
+	}
+	sort.SliceStable(state.routes, func(i, j int) bool {
+		return len(state.routes[i].Path) > len(state.routes[j].Path)
+	})
+	return state, nil
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := g.state.Load().match(r.URL.Path)
+	if route == nil {
+		writeErrorResponse(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if len(route.Methods) > 0 && !slices.Contains(route.Methods, r.Method) {
+		w.Header().Set("Allow", strings.Join(route.Methods, ", "))
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	route.handler.ServeHTTP(w, r)
+}
+
+func (s *gatewayState) match(path string) *gatewayRoute {
+	if s == nil {
+		return nil
+	}
+	for _, route := range s.routes {
+		if route.Path == path || (strings.HasSuffix(route.Path, "/") && strings.HasPrefix(path, route.Path)) {
+			return route
+		}
+	}
+	return nil
+}
+
+// watch reloads the config when its modification time changes.
+func (g *gateway) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(g.path)
+			if err != nil {
+				logger.Warn("Gateway config unavailable", "file", g.path, "error", err)
+				continue
+			}
+			g.mu.Lock()
+			changed := !info.ModTime().Equal(g.modTime)
+			g.mu.Unlock()
+			if !changed {
+				continue
+			}
+			if err := g.reload(); err != nil {
+				logger.Error("Gateway config reload failed, keeping current routes", "file", g.path, "error", err)
+			}
+		}
+	}
+}
+
+const (
+	// defaultAffinityCookie is followed by the upstream name, see affinityCookieName.
+	defaultAffinityCookie = "hs_upstream_"
+
+	// gatewayAttemptKey counts retries of a proxied request.
+	gatewayAttemptKey contextKey = "gatewayAttempt"
+	// gatewayInboundKey carries the inbound request, so that retries are rewritten from it
+	// rather than from the outbound request of the failed attempt.
+	gatewayInboundKey contextKey = "gatewayInbound"
+	// gatewayAffinityKey carries the target to pin the client to in a cookie.
+	gatewayAffinityKey contextKey = "gatewayAffinity"
+)
+
+// newGatewayProxy returns a reverse proxy balancing across the upstream's available
+// targets. Connections are pooled with the server's outbound client (see
+// Server.HTTPClient).
+func newGatewayProxy(u *gatewayUpstream, transport http.RoundTripper, retryable func(*http.Request) bool, tunnels *gatewayTunnels) http.Handler {
+	name, upstream := u.name, u.config
+	affinity := upstream.Affinity
+	cookie := affinityCookieName(name)
+	if affinity != nil && affinity.Cookie != "" {
+		cookie = affinity.Cookie
+	}
+
+	var proxy *httputil.ReverseProxy
+	proxy = &httputil.ReverseProxy{
+		Transport: transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			now := time.Now()
+			var target *gatewayTarget
+			attempt, _ := pr.In.Context().Value(gatewayAttemptKey).(int)
+			if affinity != nil && attempt == 0 {
+				if affinity.Header != "" {
+					if v := pr.In.Header.Get(affinity.Header); v != "" {
+						target = u.targets[hashString(v)%uint64(len(u.targets))]
+					}
+				} else if c, err := pr.In.Cookie(cookie); err == nil {
+					target = u.byID(c.Value)
+				}
+				if target != nil && !target.available(now) {
+					target = nil // Ejected, move the client
+				}
+			}
+			if target == nil {
+				target = u.pick(now)
+				if affinity != nil && affinity.Header == "" {
+					pr.Out = pr.Out.WithContext(context.WithValue(pr.Out.Context(), gatewayAffinityKey, target.id))
+				}
+			}
+			pr.Out = pr.Out.WithContext(context.WithValue(pr.Out.Context(), gatewayTargetKey, target))
+			pr.SetURL(target.url)
+			pr.SetXForwarded()
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			reportGatewayResponse(u, resp)
+			if resp.StatusCode == http.StatusSwitchingProtocols {
+				if t, ok := resp.Request.Context().Value(gatewayTunnelKey).(*gatewayTunnel); ok {
+					if err := t.attachBackend(resp); err != nil {
+						return err
+					}
+				}
+			}
+			if id, ok := resp.Request.Context().Value(gatewayAffinityKey).(string); ok {
+				c := &http.Cookie{
+					Name:     cookie,
+					Value:    id,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   resp.Request.Header.Get("X-Forwarded-Proto") == "https", // set from the inbound TLS state
+					SameSite: http.SameSiteLaxMode,
+				}
+				if affinity.MaxAge > 0 {
+					c.MaxAge = int(affinity.MaxAge.Seconds())
+				}
+				resp.Header.Add("Set-Cookie", c.String())
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			reportGatewayError(u, r, err)
+			// The error handler runs before anything is written, so the request can be
+			// sent to the next target
+			attempt, _ := r.Context().Value(gatewayAttemptKey).(int)
+			in, ok := r.Context().Value(gatewayInboundKey).(*http.Request)
+			if ok && attempt < upstream.Retries && r.Context().Err() == nil &&
+				(r.Body == nil || r.Body == http.NoBody) && retryable(in) {
+				logger.Debug("Retrying gateway request", "upstream", name, "path", in.URL.Path, "attempt", attempt+1, "error", err)
+				ctx := context.WithValue(withGatewayInbound(in).Context(), gatewayAttemptKey, attempt+1)
+				proxy.ServeHTTP(w, in.WithContext(ctx))
+				return
+			}
+			logger.Warn("Gateway upstream request failed", "upstream", name, "path", r.URL.Path, "error", err)
+			if errors.Is(err, context.DeadlineExceeded) {
+				writeErrorResponse(w, http.StatusGatewayTimeout, "Upstream timed out")
+				return
+			}
+			writeErrorResponse(w, http.StatusBadGateway, "Upstream unavailable")
+		},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWebSocketRequest(r) {
+			tunnels.serve(proxy, upstream.WebSocketIdleTimeout, w, r)
+			return
+		}
+		if upstream.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), upstream.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		proxy.ServeHTTP(w, withGatewayInbound(r))
+	})
+}
+
+// withGatewayInbound returns r carrying itself for retries, see gatewayInboundKey.
+func withGatewayInbound(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), gatewayInboundKey, r))
+}
+
+// stripGatewayPrefix removes the route path, keeping the remainder rooted at "/".
+func stripGatewayPrefix(prefix string, next http.Handler) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		if !strings.HasPrefix(r2.URL.Path, "/") {
+			r2.URL.Path = "/" + r2.URL.Path
+		}
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// gatewayRateLimit limits each client of a route separately from the server-wide limit.
+func (srv *Server) gatewayRateLimit(cfg RateLimitOverride) MiddlewareFunc {
+	var mu sync.Mutex
+	var lastSweep time.Time
+	clients := make(map[string]*rateLimiterEntry)
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := srv.rateLimitKey(r)
+			now := time.Now()
+			mu.Lock()
+			entry, ok := clients[key]
+			if !ok {
+				if now.Sub(lastSweep) > time.Minute {
+					for k, e := range clients {
+						if now.Sub(e.lastAccess) > gatewayLimiterMaxIdle {
+							delete(clients, k)
+						}
+					}
+					lastSweep = now
+				}
+				entry = &rateLimiterEntry{limiter: rate.NewLimiter(cfg.Limit, cfg.Burst)}
+				clients[key] = entry
+			}
+			entry.lastAccess = now
+			mu.Unlock()
+
+			if !entry.limiter.Allow() {
+				w.Header().Set("Retry-After", "1")
+				writeErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// affinityCookieName returns the default affinity cookie of an upstream, using a hash
+// of its name if the name is not a valid cookie token.
+func affinityCookieName(upstream string) string {
+	if name := defaultAffinityCookie + upstream; isCookieName(name) {
+		return name
+	}
+	return defaultAffinityCookie + strconv.FormatUint(hashString(upstream), 16)
+}
+
+// isCookieName reports whether name is a valid cookie token.
+func isCookieName(name string) bool {
+	return (&http.Cookie{Name: name, Value: "x"}).Valid() == nil
+}
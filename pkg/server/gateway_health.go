@@ -0,0 +1,357 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of GatewayHealthCheck.
+const (
+	defaultGatewayProbeInterval = 10 * time.Second
+	defaultGatewayProbeTimeout  = 2 * time.Second
+	defaultGatewayMaxFailures   = 3
+	defaultGatewayEjectTime     = 30 * time.Second
+
+	// gatewayProbeTick is how often the prober looks for targets that are due a probe.
+	gatewayProbeTick = time.Second
+
+	// gatewayTargetKey carries the target a proxied request is sent to.
+	gatewayTargetKey contextKey = "gatewayTarget"
+)
+
+// GatewayHealthCheck ejects failing targets of an upstream from load balancing. A target
+// fails when a proxied request cannot reach it or gets a 5xx response (passive
+// detection), and when a GET of Path does not answer with 2xx or 3xx (active probes). A
+// target that fails Failures times in a row is ejected for EjectTime and then receives
+// traffic again; a successful probe readmits it early. When every target of an upstream
+// is ejected, requests are balanced across all of them rather than rejected.
+type GatewayHealthCheck struct {
+	Path      string        `json:"path,omitempty"`       // Probe path, e.g. /healthz; empty disables active probes
+	Interval  time.Duration `json:"interval,omitempty"`   // Time between probes of a target (default 10s)
+	Timeout   time.Duration `json:"timeout,omitempty"`    // Per probe (default 2s)
+	Failures  int           `json:"failures,omitempty"`   // Consecutive failures that eject a target (default 3)
+	EjectTime time.Duration `json:"eject_time,omitempty"` // How long a target stays ejected (default 30s)
+}
+
+func (hc *GatewayHealthCheck) validate() error {
+	if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+		return fmt.Errorf("health check path must start with '/': %q", hc.Path)
+	}
+	if hc.Interval < 0 || hc.Timeout < 0 || hc.Failures < 0 || hc.EjectTime < 0 {
+		return fmt.Errorf("health check settings must not be negative")
+	}
+	return nil
+}
+
+// withDefaults returns a copy of hc with unset fields defaulted.
+func (hc GatewayHealthCheck) withDefaults() *GatewayHealthCheck {
+	if hc.Interval == 0 {
+		hc.Interval = defaultGatewayProbeInterval
+	}
+	if hc.Timeout == 0 {
+		hc.Timeout = defaultGatewayProbeTimeout
+	}
+	if hc.Failures == 0 {
+		hc.Failures = defaultGatewayMaxFailures
+	}
+	if hc.EjectTime == 0 {
+		hc.EjectTime = defaultGatewayEjectTime
+	}
+	return &hc
+}
+
+// gatewayUpstream is an upstream of the gateway config in effect, with its targets.
+type gatewayUpstream struct {
+	name    string
+	config  GatewayUpstream
+	health  *GatewayHealthCheck // With defaults applied, nil without health checks
+	targets []*gatewayTarget
+	next    atomic.Uint64 // Round-robin position
+}
+
+// newGatewayUpstream parses the targets of an upstream. Targets that prev already had
+// are kept, so that a reload does not readmit ejected targets.
+func newGatewayUpstream(name string, cfg GatewayUpstream, prev *gatewayUpstream) (*gatewayUpstream, error) {
+	u := &gatewayUpstream{name: name, config: cfg, targets: make([]*gatewayTarget, len(cfg.Targets))}
+	if cfg.HealthCheck != nil {
+		u.health = cfg.HealthCheck.withDefaults()
+	}
+	for i, target := range cfg.Targets {
+		if prev != nil {
+			if j := slices.IndexFunc(prev.targets, func(t *gatewayTarget) bool { return t.raw == target }); j >= 0 {
+				u.targets[i] = prev.targets[j]
+				continue
+			}
+		}
+		parsed, err := parseGatewayTarget(target)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %q: %w", name, err)
+		}
+		// Cookies name targets by hash so backend addresses are not exposed
+		u.targets[i] = &gatewayTarget{raw: target, url: parsed, id: strconv.FormatUint(hashString(target), 16)}
+	}
+	return u, nil
+}
+
+// pick balances round-robin across the available targets, or across all targets when
+// none is available.
+func (u *gatewayUpstream) pick(now time.Time) *gatewayTarget {
+	n := uint64(len(u.targets))
+	start := u.next.Add(1) - 1
+	for k := range n {
+		if t := u.targets[(start+k)%n]; t.available(now) {
+			if k > 0 {
+				u.next.Add(k) // Skip the ejected targets next time too
+			}
+			return t
+		}
+	}
+	return u.targets[start%n]
+}
+
+// byID returns the target with the given affinity ID, or nil.
+func (u *gatewayUpstream) byID(id string) *gatewayTarget {
+	for _, t := range u.targets {
+		if t.id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// gatewayTarget is one backend of an upstream and its health.
+type gatewayTarget struct {
+	raw string
+	url *url.URL
+	id  string // Names the target in affinity cookies
+
+	mu           sync.Mutex
+	failures     int
+	ejectedUntil time.Time
+	lastError    string
+	nextProbe    time.Time
+	probing      bool
+}
+
+// available reports whether the target takes requests: it has not been ejected, or its
+// ejection has expired.
+func (t *gatewayTarget) available(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !now.Before(t.ejectedUntil)
+}
+
+// report records the outcome of a request or probe; err is nil for a success. Without a
+// health check targets are never ejected.
+func (t *gatewayTarget) report(upstream string, hc *GatewayHealthCheck, err error) {
+	if hc == nil {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		if !t.ejectedUntil.IsZero() {
+			logger.Info("Gateway target readmitted", "upstream", upstream, "target", t.url.Redacted())
+		}
+		t.failures, t.ejectedUntil, t.lastError = 0, time.Time{}, ""
+		return
+	}
+	t.failures++
+	t.lastError = err.Error()
+	// A target back from ejection is ejected again by its next failure
+	if t.failures >= hc.Failures && !now.Before(t.ejectedUntil) {
+		t.ejectedUntil = now.Add(hc.EjectTime)
+		logger.Warn("Gateway target ejected", "upstream", upstream, "target", t.url.Redacted(),
+			"failures", t.failures, "until", t.ejectedUntil, "error", err)
+	}
+}
+
+// dueForProbe reports whether the target should be probed now and, if so, marks the probe
+// as running.
+func (t *gatewayTarget) dueForProbe(now time.Time, interval time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.probing || now.Before(t.nextProbe) {
+		return false
+	}
+	t.probing = true
+	t.nextProbe = now.Add(interval)
+	return true
+}
+
+// probe runs the active health checks of the upstreams in effect until ctx is done.
+func (g *gateway) probe(ctx context.Context) {
+	ticker := time.NewTicker(gatewayProbeTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, u := range g.state.Load().upstreams {
+				if u.health == nil || u.health.Path == "" {
+					continue
+				}
+				for _, t := range u.targets {
+					if t.dueForProbe(now, u.health.Interval) {
+						go g.probeTarget(ctx, u, t)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (g *gateway) probeTarget(ctx context.Context, u *gatewayUpstream, t *gatewayTarget) {
+	probeCtx, cancel := context.WithTimeout(ctx, u.health.Timeout)
+	defer cancel()
+	err := probeGatewayTarget(probeCtx, g.srv.outbound().transport, t.url, u.health.Path)
+	t.mu.Lock()
+	t.probing = false
+	t.mu.Unlock()
+	if ctx.Err() != nil {
+		return // Shutting down
+	}
+	t.report(u.name, u.health, err)
+}
+
+// probeGatewayTarget sends a GET for path to target and fails unless it answers with 2xx
+// or 3xx. The transport is used directly so that probes are not retried.
+func probeGatewayTarget(ctx context.Context, transport http.RoundTripper, target *url.URL, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.JoinPath(path).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("health probe failed: %w", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) // Lets the connection be reused
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("health probe returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// reportGatewayResponse records a proxied response with the target it came from.
+func reportGatewayResponse(u *gatewayUpstream, resp *http.Response) {
+	t, ok := resp.Request.Context().Value(gatewayTargetKey).(*gatewayTarget)
+	if !ok {
+		return
+	}
+	var err error
+	if resp.StatusCode >= 500 {
+		err = fmt.Errorf("upstream returned %d", resp.StatusCode)
+	}
+	t.report(u.name, u.health, err)
+}
+
+// reportGatewayError records a failed proxied request, unless the client went away.
+func reportGatewayError(u *gatewayUpstream, r *http.Request, err error) {
+	t, ok := r.Context().Value(gatewayTargetKey).(*gatewayTarget)
+	if !ok || errors.Is(r.Context().Err(), context.Canceled) {
+		return
+	}
+	t.report(u.name, u.health, err)
+}
+
+// GatewayTargetStatus is the health of one target of a gateway upstream.
+type GatewayTargetStatus struct {
+	Upstream     string    `json:"upstream"`
+	Target       string    `json:"target"`
+	Healthy      bool      `json:"healthy"`
+	Failures     int       `json:"failures"`               // Consecutive failed requests and probes
+	EjectedUntil time.Time `json:"ejected_until,omitzero"` // Set while the target is ejected
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// GatewayUpstreamStatus returns the health of the gateway targets, sorted by upstream and
+// in the order of their targets, or nil without a gateway. Targets of upstreams without a
+// health check are always healthy. It is also reported by the /healthz/?verbose=1
+// payload of the health server and the gateway://server/upstreams MCP resource.
+func (srv *Server) GatewayUpstreamStatus() []GatewayTargetStatus {
+	if srv.gateway == nil {
+		return nil
+	}
+	upstreams := srv.gateway.state.Load().upstreams
+	names := make([]string, 0, len(upstreams))
+	for name := range upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	out := make([]GatewayTargetStatus, 0)
+	for _, name := range names {
+		for _, t := range upstreams[name].targets {
+			t.mu.Lock()
+			status := GatewayTargetStatus{
+				Upstream:  name,
+				Target:    t.url.Redacted(),
+				Healthy:   !now.Before(t.ejectedUntil),
+				Failures:  t.failures,
+				LastError: t.lastError,
+			}
+			if !status.Healthy {
+				status.EjectedUntil = t.ejectedUntil
+			}
+			t.mu.Unlock()
+			out = append(out, status)
+		}
+	}
+	return out
+}
+
+// GatewayUpstreamsResource implements MCPResource for the health of gateway upstreams.
+type GatewayUpstreamsResource struct {
+	server *Server
+}
+
+// NewGatewayUpstreamsResource creates a new gateway upstream health resource.
+func NewGatewayUpstreamsResource(srv *Server) *GatewayUpstreamsResource {
+	return &GatewayUpstreamsResource{server: srv}
+}
+
+func (r *GatewayUpstreamsResource) URI() string {
+	return "gateway://server/upstreams"
+}
+
+func (r *GatewayUpstreamsResource) CacheTTL() time.Duration {
+	return 0
+}
+
+func (r *GatewayUpstreamsResource) Name() string {
+	return "Gateway Upstreams"
+}
+
+func (r *GatewayUpstreamsResource) Description() string {
+	return "Health of the gateway upstream targets, including ejected targets and their last error"
+}
+
+func (r *GatewayUpstreamsResource) MimeType() string {
+	return "application/json"
+}
+
+func (r *GatewayUpstreamsResource) Read() (interface{}, error) {
+	return map[string]interface{}{
+		"targets":   r.server.GatewayUpstreamStatus(),
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+func (r *GatewayUpstreamsResource) List() ([]string, error) {
+	return []string{r.URI()}, nil
+}
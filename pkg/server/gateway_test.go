@@ -0,0 +1,358 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeGatewayConfig(t *testing.T, path string, cfg GatewayConfig) {
+	t.Helper()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func gatewayGet(t *testing.T, h http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGatewayProxiesConfiguredRoutes(t *testing.T) {
+	var backends []*httptest.Server
+	for _, name := range []string{"a", "b"} {
+		name := name
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name+" "+r.URL.Path+" "+r.Header.Get("X-Forwarded-Host"))
+		}))
+		defer backend.Close()
+		backends = append(backends, backend)
+	}
+
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, path, GatewayConfig{
+		Upstreams: map[string]GatewayUpstream{
+			"users": {Targets: []string{backends[0].URL, backends[1].URL}},
+		},
+		Routes: []GatewayRoute{
+			{Path: "/api/users/", Upstream: "users", StripPrefix: true, Methods: []string{http.MethodGet}},
+			{Path: "/private/", Upstream: "users", Auth: true},
+			{Path: "/limited", Upstream: "users", RateLimit: &RateLimitOverride{Limit: 1, Burst: 1}},
+		},
+	})
+
+	srv, err := NewServer(
+		WithGatewayConfig(path, 0),
+		WithAuthTokenValidator(func(token string) (bool, error) { return token == "secret", nil }),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	h := srv.middleware.applyToMux(srv.mux)
+
+	first := gatewayGet(t, h, http.MethodGet, "/api/users/42", "").Body.String()
+	second := gatewayGet(t, h, http.MethodGet, "/api/users/42", "").Body.String()
+	if first != "a /42 example.com" || second != "b /42 example.com" {
+		t.Errorf("expected round-robin with stripped prefix, got %q and %q", first, second)
+	}
+	if rec := gatewayGet(t, h, http.MethodPost, "/api/users/42", ""); rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET" {
+		t.Errorf("expected 405 with Allow header, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+
+	if rec := gatewayGet(t, h, http.MethodGet, "/private/x", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := gatewayGet(t, h, http.MethodGet, "/private/x", "secret"); rec.Code != http.StatusOK || !strings.HasSuffix(rec.Body.String(), "/private/x example.com") {
+		t.Errorf("expected proxied request with token, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := gatewayGet(t, h, http.MethodGet, "/limited", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected first limited request to pass, got %d", rec.Code)
+	}
+	if rec := gatewayGet(t, h, http.MethodGet, "/limited", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", rec.Code)
+	}
+	if rec := gatewayGet(t, h, http.MethodGet, "/limited/sub", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected exact match only, got %d", rec.Code)
+	}
+}
+
+func TestGatewayReload(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+	upstreams := map[string]GatewayUpstream{"svc": {Targets: []string{backend.URL}}}
+
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, path, GatewayConfig{Upstreams: upstreams, Routes: []GatewayRoute{{Path: "/old/", Upstream: "svc"}}})
+	srv, err := NewServer(WithGatewayConfig(path, 0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	h := srv.middleware.applyToMux(srv.mux)
+
+	writeGatewayConfig(t, path, GatewayConfig{Upstreams: upstreams, Routes: []GatewayRoute{{Path: "/new/", Upstream: "svc"}}})
+	if err := srv.ReloadGateway(); err != nil {
+		t.Fatalf("ReloadGateway: %v", err)
+	}
+	if rec := gatewayGet(t, h, http.MethodGet, "/new/x", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected new route to be served, got %d", rec.Code)
+	}
+	if rec := gatewayGet(t, h, http.MethodGet, "/old/x", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected removed route to return 404, got %d", rec.Code)
+	}
+
+	writeGatewayConfig(t, path, GatewayConfig{Upstreams: upstreams, Routes: []GatewayRoute{{Path: "/new/", Upstream: "missing"}}})
+	if err := srv.ReloadGateway(); err == nil || !strings.Contains(err.Error(), "unknown upstream") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+	if got := srv.GatewayConfig().Routes[0].Upstream; got != "svc" {
+		t.Errorf("expected previous config to stay active, got upstream %q", got)
+	}
+}
+
+func TestGatewayUpstreamUnavailable(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close()
+
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, path, GatewayConfig{
+		Upstreams: map[string]GatewayUpstream{"down": {Targets: []string{backend.URL}}},
+		Routes:    []GatewayRoute{{Path: "/down/", Upstream: "down"}},
+	})
+	srv, err := NewServer(WithGatewayConfig(path, 0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if rec := gatewayGet(t, srv.mux, http.MethodGet, "/down/x", ""); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", rec.Code)
+	}
+}
+
+func TestGatewayRetriesIdempotentRequests(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer up.Close()
+
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, path, GatewayConfig{
+		Upstreams: map[string]GatewayUpstream{
+			"svc":  {Targets: []string{down.URL, up.URL}, Retries: 1},
+			"jobs": {Targets: []string{down.URL, up.URL}, Retries: 1},
+		},
+		Routes: []GatewayRoute{
+			{Path: "/svc/", Upstream: "svc"},
+			{Path: "/jobs/", Upstream: "jobs"},
+		},
+	})
+	srv, err := NewServer(
+		WithGatewayConfig(path, 0),
+		WithRouteMeta("/jobs/", RouteMeta{Idempotent: false}),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	// Targets alternate, so each request below starts on the unavailable one
+	if rec := gatewayGet(t, srv.mux, http.MethodGet, "/svc/a", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected GET to be retried on the next target, got %d", rec.Code)
+	}
+	if rec := gatewayGet(t, srv.mux, http.MethodDelete, "/svc/a", ""); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected DELETE without metadata not to be retried, got %d", rec.Code)
+	}
+	if rec := gatewayGet(t, srv.mux, http.MethodGet, "/jobs/a", ""); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected GET on a non-idempotent route not to be retried, got %d", rec.Code)
+	}
+}
+
+func TestGatewayRetryRewritesInboundRequest(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	var path, forwardedHost string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, forwardedHost = r.URL.Path, r.Header.Get("X-Forwarded-Host")
+	}))
+	defer up.Close()
+
+	config := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, config, GatewayConfig{
+		Upstreams: map[string]GatewayUpstream{
+			"svc": {Targets: []string{down.URL + "/base", up.URL + "/base"}, Retries: 1},
+		},
+		Routes: []GatewayRoute{{Path: "/svc/", Upstream: "svc"}},
+	})
+	srv, err := NewServer(WithGatewayConfig(config, 0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	if rec := gatewayGet(t, srv.mux, http.MethodGet, "/svc/a", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the retry to succeed, got %d", rec.Code)
+	}
+	if path != "/base/svc/a" || forwardedHost != "example.com" {
+		t.Errorf("expected the retry to reach /base/svc/a for example.com, got %q for %q", path, forwardedHost)
+	}
+}
+
+func TestGatewayStickySessions(t *testing.T) {
+	var backends []string
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}))
+		defer backend.Close()
+		backends = append(backends, backend.URL)
+	}
+
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, path, GatewayConfig{
+		Upstreams: map[string]GatewayUpstream{
+			"cookie": {Targets: backends, Affinity: &GatewayAffinity{MaxAge: time.Hour}},
+			"header": {Targets: backends, Affinity: &GatewayAffinity{Header: "X-Session-ID"}},
+		},
+		Routes: []GatewayRoute{
+			{Path: "/ws/", Upstream: "cookie"},
+			{Path: "/poll/", Upstream: "header"},
+		},
+	})
+	srv, err := NewServer(WithGatewayConfig(path, 0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	first := gatewayGet(t, srv.mux, http.MethodGet, "/ws/", "")
+	cookies := first.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "hs_upstream_cookie" || !cookies[0].HttpOnly || cookies[0].MaxAge != 3600 {
+		t.Fatalf("expected affinity cookie, got %v", cookies)
+	}
+	if name := affinityCookieName("api v2"); !isCookieName(name) {
+		t.Errorf("invalid default cookie name %q", name)
+	}
+	if strings.Contains(cookies[0].Value, "127.0.0.1") {
+		t.Errorf("cookie exposes the backend address: %q", cookies[0].Value)
+	}
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/ws/", nil)
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		if rec.Body.String() != first.Body.String() {
+			t.Fatalf("expected pinned target %q, got %q", first.Body.String(), rec.Body.String())
+		}
+		if rec.Header().Get("Set-Cookie") != "" {
+			t.Error("expected no new cookie for a pinned client")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, session := range []string{"s1", "s2", "s3", "s4", "s5", "s6"} {
+		var target string
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/poll/", nil)
+			req.Header.Set("X-Session-ID", session)
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, req)
+			if target != "" && rec.Body.String() != target {
+				t.Fatalf("session %s moved from %q to %q", session, target, rec.Body.String())
+			}
+			target = rec.Body.String()
+		}
+		seen[target] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected sessions to spread over targets, got %v", seen)
+	}
+}
+
+func TestGatewayHealthChecksEjectFailingTargets(t *testing.T) {
+	var healthy atomic.Bool
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		io.WriteString(w, "bad")
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "good")
+	}))
+	defer good.Close()
+
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, path, GatewayConfig{
+		Upstreams: map[string]GatewayUpstream{
+			"svc": {Targets: []string{bad.URL, good.URL}, HealthCheck: &GatewayHealthCheck{Path: "/healthz", Failures: 2, EjectTime: time.Hour}},
+		},
+		Routes: []GatewayRoute{{Path: "/svc/", Upstream: "svc"}},
+	})
+	srv, err := NewServer(WithGatewayConfig(path, 0), WithMCPSupport("test", "1.0.0", MCPObservability()))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	// Passive detection: the failing target is ejected after two 503s
+	var served []string
+	for range 6 {
+		served = append(served, gatewayGet(t, srv.mux, http.MethodGet, "/svc/a", "").Body.String())
+	}
+	if strings.Count(strings.Join(served, " "), "bad") != 2 {
+		t.Errorf("expected the failing target to be ejected after two failures, got %v", served)
+	}
+	status := srv.GatewayUpstreamStatus()
+	if len(status) != 2 || status[0].Healthy || status[0].Failures != 2 || status[0].EjectedUntil.IsZero() || !status[1].Healthy {
+		t.Errorf("unexpected upstream status %+v", status)
+	}
+
+	// Reloads keep the ejection
+	if err := srv.ReloadGateway(); err != nil {
+		t.Fatalf("ReloadGateway: %v", err)
+	}
+	if srv.GatewayUpstreamStatus()[0].Healthy {
+		t.Error("expected the target to stay ejected across a reload")
+	}
+
+	// Active probes readmit the target once it recovers
+	healthy.Store(true)
+	u := srv.gateway.state.Load().upstreams["svc"]
+	srv.gateway.probeTarget(context.Background(), u, u.targets[0])
+	if status := srv.GatewayUpstreamStatus(); !status[0].Healthy || status[0].Failures != 0 {
+		t.Errorf("expected a successful probe to readmit the target, got %+v", status[0])
+	}
+
+	rec := httptest.NewRecorder()
+	srv.isRunning.Store(true)
+	srv.healthzHandler(rec, httptest.NewRequest(http.MethodGet, "/healthz/?verbose=1", nil))
+	if !strings.Contains(rec.Body.String(), `"upstreams":[{"upstream":"svc"`) {
+		t.Errorf("expected the verbose health payload to report upstreams, got %s", rec.Body.String())
+	}
+	registered := false
+	for _, r := range srv.mcpHandler.resources {
+		registered = registered || r.URI() == NewGatewayUpstreamsResource(srv).URI()
+	}
+	if !registered {
+		t.Error("expected the upstream resource to be registered")
+	}
+}
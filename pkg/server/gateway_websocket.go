@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/osauer/hyperserve/internal/ws"
+	pkgwebsocket "github.com/osauer/hyperserve/pkg/websocket"
+)
+
+// gatewayTunnelKey carries the tunnel of a proxied WebSocket upgrade.
+const gatewayTunnelKey contextKey = "gatewayTunnel"
+
+// GatewayWebSocketStats counts WebSocket connections tunnelled to gateway upstreams.
+// The counters are those of pkg/websocket.PoolStats.
+type GatewayWebSocketStats struct {
+	ActiveConnections  int64 `json:"active_connections"`
+	ConnectionsCreated int64 `json:"connections_created"`
+	FailedConnections  int64 `json:"failed_connections"` // Upgrades the upstream refused or could not be reached for
+	IdleTimeouts       int64 `json:"idle_timeouts"`      // Tunnels closed by WebSocketIdleTimeout
+}
+
+// GatewayWebSocketStats returns the counters of WebSocket connections proxied by the
+// gateway, or zero values when no gateway is configured.
+func (srv *Server) GatewayWebSocketStats() GatewayWebSocketStats {
+	if srv.gateway == nil {
+		return GatewayWebSocketStats{}
+	}
+	t := &srv.gateway.tunnels
+	return GatewayWebSocketStats{
+		ActiveConnections:  t.stats.ActiveConnections.Load(),
+		ConnectionsCreated: t.stats.ConnectionsCreated.Load(),
+		FailedConnections:  t.stats.FailedConnections.Load(),
+		IdleTimeouts:       t.idleTimeouts.Load(),
+	}
+}
+
+// gatewayTunnels tracks the WebSocket tunnels of a gateway across config reloads.
+type gatewayTunnels struct {
+	stats        pkgwebsocket.PoolStats
+	idleTimeouts atomic.Int64
+	upgrades     *atomic.Uint64 // the server's WebSocket connection counter
+}
+
+// isWebSocketRequest reports whether r asks to upgrade to a WebSocket.
+func isWebSocketRequest(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serve proxies a WebSocket upgrade. httputil.ReverseProxy performs the handshake and
+// copies frames in both directions, so close frames reach the other side unchanged; the
+// tunnel adds the idle timeout and the statistics. The upstream Timeout does not apply,
+// since it would cut off every connection after a fixed time.
+func (ts *gatewayTunnels) serve(proxy http.Handler, idle time.Duration, w http.ResponseWriter, r *http.Request) {
+	t := &gatewayTunnel{tunnels: ts, idle: idle}
+	proxy.ServeHTTP(&tunnelResponseWriter{ResponseWriter: w, tunnel: t},
+		withGatewayInbound(r.WithContext(context.WithValue(r.Context(), gatewayTunnelKey, t))))
+
+	if !t.established() {
+		ts.stats.FailedConnections.Add(1)
+		return
+	}
+	t.stop()
+	ts.stats.ActiveConnections.Add(-1)
+}
+
+// gatewayTunnel is one proxied WebSocket connection.
+type gatewayTunnel struct {
+	tunnels *gatewayTunnels
+	idle    time.Duration
+	last    atomic.Int64 // UnixNano of the last read or write on either side
+
+	mu      sync.Mutex
+	client  *tunnelEnd
+	backend *tunnelEnd
+	timer   *time.Timer
+	stopped bool
+}
+
+func (t *gatewayTunnel) touch() {
+	t.last.Store(time.Now().UnixNano())
+}
+
+func (t *gatewayTunnel) established() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.client != nil
+}
+
+// attachBackend wraps the upstream connection of a 101 response.
+func (t *gatewayTunnel) attachBackend(resp *http.Response) error {
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return fmt.Errorf("upstream connection does not support WebSocket upgrades")
+	}
+	t.mu.Lock()
+	t.backend = &tunnelEnd{ReadWriteCloser: rwc, tunnel: t}
+	resp.Body = t.backend
+	t.mu.Unlock()
+	return nil
+}
+
+// attachClient wraps the hijacked client connection and starts the idle timer. The
+// proxy hijacks the client only after the upstream accepted the upgrade.
+func (t *gatewayTunnel) attachClient(conn net.Conn) net.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.client = &tunnelEnd{ReadWriteCloser: conn, tunnel: t, toClient: true}
+	t.tunnels.stats.ConnectionsCreated.Add(1)
+	t.tunnels.stats.ActiveConnections.Add(1)
+	if t.tunnels.upgrades != nil {
+		t.tunnels.upgrades.Add(1)
+	}
+	t.touch()
+	if t.idle > 0 {
+		t.timer = time.AfterFunc(t.idle, t.checkIdle)
+	}
+	return &tunnelClientConn{Conn: conn, end: t.client}
+}
+
+func (t *gatewayTunnel) checkIdle() {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	if idleFor := time.Since(time.Unix(0, t.last.Load())); idleFor < t.idle {
+		t.timer.Reset(t.idle - idleFor)
+		t.mu.Unlock()
+		return
+	}
+	t.stopped = true
+	client, backend := t.client, t.backend
+	t.mu.Unlock()
+
+	t.tunnels.idleTimeouts.Add(1)
+	logger.Debug("Closing idle gateway WebSocket", "idle_timeout", t.idle)
+	// Both sides get a close frame before either connection is closed, since closing one
+	// ends the proxy's copy loops and closes the other
+	if conn, ok := client.ReadWriteCloser.(net.Conn); ok {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+	}
+	client.writeClose(CloseGoingAway, "idle timeout")
+	if backend != nil {
+		backend.writeClose(CloseGoingAway, "idle timeout")
+		backend.Close()
+	}
+	client.Close()
+}
+
+func (t *gatewayTunnel) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// tunnelEnd records activity on one side of a tunnel and serialises writes so a close
+// frame cannot interleave with a frame being copied.
+type tunnelEnd struct {
+	io.ReadWriteCloser
+	tunnel   *gatewayTunnel
+	toClient bool // frames to the client are unmasked, frames to the upstream masked
+
+	wmu sync.Mutex
+}
+
+func (e *tunnelEnd) Read(p []byte) (int, error) {
+	n, err := e.ReadWriteCloser.Read(p)
+	if n > 0 {
+		e.tunnel.touch()
+	}
+	return n, err
+}
+
+func (e *tunnelEnd) Write(p []byte) (int, error) {
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+	n, err := e.ReadWriteCloser.Write(p)
+	if n > 0 {
+		e.tunnel.touch()
+	}
+	return n, err
+}
+
+func (e *tunnelEnd) writeClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code)) //nolint:gosec // close codes fit in 16 bits
+	payload = append(payload, reason...)
+	frame := &ws.Frame{Fin: true, Opcode: ws.OpcodeClose, Payload: payload, Masked: !e.toClient}
+	if frame.Masked {
+		if _, err := rand.Read(frame.MaskKey[:]); err != nil {
+			return err
+		}
+	}
+	e.wmu.Lock()
+	defer e.wmu.Unlock()
+	return ws.NewFrameWriter(bufio.NewWriter(e.ReadWriteCloser), e.toClient).WriteFrame(frame)
+}
+
+// tunnelClientConn is the hijacked client connection handed to the proxy.
+type tunnelClientConn struct {
+	net.Conn
+	end *tunnelEnd
+}
+
+func (c *tunnelClientConn) Read(p []byte) (int, error)  { return c.end.Read(p) }
+func (c *tunnelClientConn) Write(p []byte) (int, error) { return c.end.Write(p) }
+
+// tunnelResponseWriter hands the proxy a wrapped connection when it hijacks the client.
+type tunnelResponseWriter struct {
+	http.ResponseWriter
+	tunnel *gatewayTunnel
+}
+
+func (w *tunnelResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// The server's read and write timeouts end with the handshake, the idle timeout
+	// governs the tunnel
+	conn.SetDeadline(time.Time{})
+	return w.tunnel.attachClient(conn), brw, nil
+}
+
+func (w *tunnelResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+// wsEchoBackend echoes messages and reports the close code it received.
+func wsEchoBackend(t *testing.T) (*httptest.Server, <-chan int) {
+	t.Helper()
+	closed := make(chan int, 1)
+	upgrader := &Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				var closeErr *ws.CloseError
+				if errors.As(err, &closeErr) {
+					closed <- closeErr.Code
+				}
+				return
+			}
+			if err := conn.WriteMessage(mt, msg); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(backend.Close)
+	return backend, closed
+}
+
+// dialGatewayWebSocket performs the handshake and returns the raw connection.
+func dialGatewayWebSocket(t *testing.T, url string) (io.ReadWriteCloser, *ws.FrameReader, *ws.FrameWriter) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	conn := resp.Body.(io.ReadWriteCloser)
+	t.Cleanup(func() { conn.Close() })
+	return conn, ws.NewFrameReader(bufio.NewReader(conn), 1<<20), ws.NewFrameWriter(bufio.NewWriter(conn), false)
+}
+
+func writeClientFrame(t *testing.T, fw *ws.FrameWriter, opcode int, payload []byte) {
+	t.Helper()
+	frame := &ws.Frame{Fin: true, Opcode: opcode, Payload: payload, Masked: true}
+	rand.Read(frame.MaskKey[:])
+	if err := fw.WriteFrame(frame); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+func TestGatewayProxiesWebSockets(t *testing.T) {
+	echo, echoClosed := wsEchoBackend(t)
+	idle, idleClosed := wsEchoBackend(t)
+
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeGatewayConfig(t, path, GatewayConfig{
+		Upstreams: map[string]GatewayUpstream{
+			"echo":    {Targets: []string{echo.URL}, Timeout: 50 * time.Millisecond},
+			"idle":    {Targets: []string{idle.URL}, WebSocketIdleTimeout: 100 * time.Millisecond},
+			"offline": {Targets: []string{"http://127.0.0.1:1"}},
+		},
+		Routes: []GatewayRoute{
+			{Path: "/echo", Upstream: "echo"},
+			{Path: "/idle", Upstream: "idle"},
+			{Path: "/offline", Upstream: "offline"},
+		},
+	})
+	srv, err := NewServer(WithGatewayConfig(path, 0))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	t.Run("messages and close frames pass through", func(t *testing.T) {
+		_, fr, fw := dialGatewayWebSocket(t, ts.URL+"/echo")
+		// Outlives the upstream Timeout, which does not apply to tunnels
+		time.Sleep(100 * time.Millisecond)
+		writeClientFrame(t, fw, ws.OpcodeText, []byte("hello"))
+		frame, err := fr.ReadFrame()
+		if err != nil || string(frame.Payload) != "hello" {
+			t.Fatalf("expected echo, got %v %v", frame, err)
+		}
+
+		payload := binary.BigEndian.AppendUint16(nil, CloseNormalClosure)
+		writeClientFrame(t, fw, ws.OpcodeClose, payload)
+		if frame, err := fr.ReadFrame(); err != nil || frame.Opcode != ws.OpcodeClose {
+			t.Fatalf("expected close reply, got %v %v", frame, err)
+		}
+		select {
+		case code := <-echoClosed:
+			if code != CloseNormalClosure {
+				t.Errorf("upstream received close code %d", code)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("upstream did not receive the close frame")
+		}
+	})
+
+	t.Run("idle connections are closed", func(t *testing.T) {
+		_, fr, _ := dialGatewayWebSocket(t, ts.URL+"/idle")
+		frame, err := fr.ReadFrame()
+		if err != nil || frame.Opcode != ws.OpcodeClose || binary.BigEndian.Uint16(frame.Payload) != CloseGoingAway {
+			t.Fatalf("expected close frame 1001, got %v %v", frame, err)
+		}
+		select {
+		case code := <-idleClosed:
+			if code != CloseGoingAway {
+				t.Errorf("upstream received close code %d", code)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("upstream did not receive the close frame")
+		}
+	})
+
+	t.Run("unreachable upstream", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/offline", nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("expected 502, got %d", resp.StatusCode)
+		}
+	})
+
+	waitFor(t, func() bool { return srv.GatewayWebSocketStats().ActiveConnections == 0 })
+	stats := srv.GatewayWebSocketStats()
+	want := GatewayWebSocketStats{ConnectionsCreated: 2, FailedConnections: 1, IdleTimeouts: 1}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+	if n := srv.websocketConnections.Load(); n != 2 {
+		t.Errorf("server counted %d WebSocket connections, want 2", n)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const geoInfoKey contextKey = "geoInfo"
+
+// GeoInfo describes the network origin of a request as resolved by a GeoProvider.
+type GeoInfo struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 country code, e.g. "DE"
+	ASN     uint32 `json:"asn,omitempty"`     // Autonomous system number
+	ASOrg   string `json:"as_org,omitempty"`  // Autonomous system organisation
+}
+
+// GeoProvider resolves geolocation data for a client IP address.
+// HyperServe does not bundle a database; implementations typically wrap a
+// MaxMind/GeoIP reader or a remote lookup service.
+type GeoProvider interface {
+	Lookup(ip net.IP) (GeoInfo, error)
+}
+
+// GeoProviderFunc adapts an ordinary function to the GeoProvider interface.
+type GeoProviderFunc func(ip net.IP) (GeoInfo, error)
+
+// Lookup calls f(ip).
+func (f GeoProviderFunc) Lookup(ip net.IP) (GeoInfo, error) {
+	return f(ip)
+}
+
+// WithGeoProvider enables IP geolocation enrichment. Every request is tagged with the
+// country and ASN of the client, which are available via GeoFromRequest and recorded as
+// request annotations ("geo.country", "geo.asn") so they appear in access logs and captures.
+//
+// Example:
+//
+//	db, _ := geoip2.Open("GeoLite2-Country.mmdb")
+//	srv, _ := server.NewServer(
+//		server.WithGeoProvider(server.GeoProviderFunc(func(ip net.IP) (server.GeoInfo, error) {
+//			rec, err := db.Country(ip)
+//			if err != nil {
+//				return server.GeoInfo{}, err
+//			}
+//			return server.GeoInfo{Country: rec.Country.IsoCode}, nil
+//		})),
+//	)
+func WithGeoProvider(provider GeoProvider) ServerOptionFunc {
+	return func(srv *Server) error {
+		if provider == nil {
+			return fmt.Errorf("geo provider must not be nil")
+		}
+		srv.Options.GeoProvider = provider
+		srv.AddMiddleware(GlobalMiddlewareRoute, GeoEnrichmentMiddleware(srv))
+		return nil
+	}
+}
+
+// GeoEnrichmentMiddleware returns a middleware function that resolves the client IP with the
+// configured GeoProvider and stores the result in the request context.
+// Lookup failures are logged at debug level and leave the request unenriched.
+// It is installed automatically by WithGeoProvider.
+func GeoEnrichmentMiddleware(srv *Server) MiddlewareFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			provider := srv.Options.GeoProvider
+			if provider == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := GeoFromRequest(r); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := net.ParseIP(clientIP(r))
+			if ip == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			info, err := provider.Lookup(ip)
+			if err != nil {
+				logger.Debug("Geo lookup failed", "ip", ip.String(), "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			info.Country = strings.ToUpper(info.Country)
+			if info.Country != "" {
+				Annotate(r, "geo.country", info.Country)
+			}
+			if info.ASN != 0 {
+				Annotate(r, "geo.asn", info.ASN)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), geoInfoKey, info)))
+		}
+	}
+}
+
+// GeoFromRequest returns the geolocation data attached to the request by GeoEnrichmentMiddleware.
+func GeoFromRequest(r *http.Request) (GeoInfo, bool) {
+	info, ok := r.Context().Value(geoInfoKey).(GeoInfo)
+	return info, ok
+}
+
+// RateLimitByCountry keys rate limiting on the client's country, so that overrides can
+// apply per-country limits (e.g. WithRateLimitOverride("country:US", ...)).
+// Requests without geolocation data fall back to the client IP address.
+func RateLimitByCountry(r *http.Request) string {
+	if info, ok := GeoFromRequest(r); ok && info.Country != "" {
+		return "country:" + info.Country
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+)
+
+// GeoPolicyAction determines what happens to a request that matches a GeoPolicy.
+type GeoPolicyAction string
+
+const (
+	// GeoPolicyAllow lets matching requests through and stops policy evaluation.
+	GeoPolicyAllow GeoPolicyAction = "allow"
+	// GeoPolicyDeny rejects matching requests with 403 Forbidden.
+	GeoPolicyDeny GeoPolicyAction = "deny"
+	// GeoPolicyLegalBlock rejects matching requests with 451 Unavailable For Legal Reasons.
+	GeoPolicyLegalBlock GeoPolicyAction = "legal_block"
+	// GeoPolicyThrottle rate limits matching requests by origin: the requests of each
+	// country and ASN pair share one token bucket per policy, whichever client sends them.
+	// Combine it with RateLimitMiddleware to limit clients individually.
+	GeoPolicyThrottle GeoPolicyAction = "throttle"
+)
+
+// GeoPolicy is an access rule based on the client's country or ASN as resolved by the
+// configured GeoProvider. A policy matches when the request's country is listed in
+// Countries or its ASN is listed in ASNs. A policy with neither countries nor ASNs
+// matches every request, which makes it useful as a final catch-all
+// (e.g. deny everything that was not explicitly allowed).
+type GeoPolicy struct {
+	Name      string          `json:"name"`
+	Countries []string        `json:"countries,omitempty"` // ISO 3166-1 alpha-2 codes
+	ASNs      []uint32        `json:"asns,omitempty"`
+	Action    GeoPolicyAction `json:"action"`
+	Limit     RateLimit       `json:"limit,omitempty"`  // Requests per second per country and ASN for GeoPolicyThrottle
+	Burst     int             `json:"burst,omitempty"`  // Burst size for GeoPolicyThrottle
+	Reason    string          `json:"reason,omitempty"` // Message returned to rejected clients
+}
+
+// matches reports whether the policy applies to the given geolocation data.
+func (p *GeoPolicy) matches(info GeoInfo, known bool) bool {
+	if len(p.Countries) == 0 && len(p.ASNs) == 0 {
+		return true
+	}
+	if !known {
+		return false
+	}
+	if info.Country != "" && slices.ContainsFunc(p.Countries, func(c string) bool {
+		return strings.EqualFold(c, info.Country)
+	}) {
+		return true
+	}
+	return info.ASN != 0 && slices.Contains(p.ASNs, info.ASN)
+}
+
+func (p *GeoPolicy) validate() error {
+	switch p.Action {
+	case GeoPolicyAllow, GeoPolicyDeny, GeoPolicyLegalBlock:
+	case GeoPolicyThrottle:
+		if p.Limit <= 0 || p.Burst <= 0 {
+			return fmt.Errorf("geo policy %q: throttle requires a positive limit and burst", p.Name)
+		}
+	default:
+		return fmt.Errorf("geo policy %q: unknown action %q", p.Name, p.Action)
+	}
+	if p.Name == "" {
+		return fmt.Errorf("geo policy requires a name")
+	}
+	return nil
+}
+
+// geoPolicyHits counts policy matches per "name/action" for observability.
+type geoPolicyHits struct {
+	counters sync.Map // map[string]*atomic.Uint64
+}
+
+func (h *geoPolicyHits) inc(p *GeoPolicy) {
+	key := p.Name + "/" + string(p.Action)
+	counter, _ := h.counters.LoadOrStore(key, new(atomic.Uint64))
+	counter.(*atomic.Uint64).Add(1)
+}
+
+// GeoPolicyHits returns the number of requests matched by each geo policy, keyed by
+// "name/action", e.g. "embargo/legal_block".
+func (srv *Server) GeoPolicyHits() map[string]uint64 {
+	hits := make(map[string]uint64)
+	srv.geoPolicyHits.counters.Range(func(key, value any) bool {
+		hits[key.(string)] = value.(*atomic.Uint64).Load()
+		return true
+	})
+	return hits
+}
+
+// WithGeoPolicy applies country/ASN access policies to all routes starting with route.
+// Policies are evaluated in order and the first match decides; requests that match no
+// policy pass through. Requires WithGeoProvider to be configured first.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithGeoProvider(provider),
+//		server.WithGeoPolicy("/api",
+//			server.GeoPolicy{Name: "embargo", Countries: []string{"KP"}, Action: server.GeoPolicyLegalBlock},
+//			server.GeoPolicy{Name: "hosting", ASNs: []uint32{16509}, Action: server.GeoPolicyThrottle, Limit: 5, Burst: 10},
+//		),
+//	)
+func WithGeoPolicy(route string, policies ...GeoPolicy) ServerOptionFunc {
+	return func(srv *Server) error {
+		if srv.Options.GeoProvider == nil {
+			return fmt.Errorf("geo policies require a geo provider (use WithGeoProvider first)")
+		}
+		mw, err := GeoPolicyMiddleware(srv, policies...)
+		if err != nil {
+			return err
+		}
+		srv.AddMiddleware(route, mw)
+		return nil
+	}
+}
+
+// GeoPolicyMiddleware returns a middleware function enforcing the given geo policies.
+// It relies on GeoEnrichmentMiddleware having run earlier in the chain.
+// Returns an error if a policy is invalid.
+func GeoPolicyMiddleware(srv *Server, policies ...GeoPolicy) (MiddlewareFunc, error) {
+	policies = slices.Clone(policies)
+	for i := range policies {
+		if err := policies[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var limitersMu sync.Mutex
+	limiters := make(map[string]*rate.Limiter)
+	throttled := func(idx int, p *GeoPolicy, info GeoInfo) bool {
+		key := fmt.Sprintf("%d|%s|%d", idx, info.Country, info.ASN)
+		limitersMu.Lock()
+		limiter, ok := limiters[key]
+		if !ok {
+			limiter = rate.NewLimiter(p.Limit, p.Burst)
+			limiters[key] = limiter
+		}
+		limitersMu.Unlock()
+		return !limiter.Allow()
+	}
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			info, known := GeoFromRequest(r)
+			for i := range policies {
+				p := &policies[i]
+				if !p.matches(info, known) {
+					continue
+				}
+				srv.geoPolicyHits.inc(p)
+				Annotate(r, "geo.policy", p.Name)
+
+				switch p.Action {
+				case GeoPolicyDeny:
+					writeErrorResponse(w, http.StatusForbidden, geoPolicyReason(p, "Access denied from your location"))
+					return
+				case GeoPolicyLegalBlock:
+					writeErrorResponse(w, http.StatusUnavailableForLegalReasons,
+						geoPolicyReason(p, "Unavailable for legal reasons in your location"))
+					return
+				case GeoPolicyThrottle:
+					if throttled(i, p, info) {
+						w.Header().Set("Retry-After", "1")
+						writeErrorResponse(w, http.StatusTooManyRequests, geoPolicyReason(p, "Rate limit exceeded"))
+						return
+					}
+				}
+				break
+			}
+			next.ServeHTTP(w, r)
+		}
+	}, nil
+}
+
+func geoPolicyReason(p *GeoPolicy, fallback string) string {
+	if p.Reason != "" {
+		return p.Reason
+	}
+	return fallback
+}
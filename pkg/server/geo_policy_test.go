@@ -0,0 +1,130 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newGeoPolicyServer(t *testing.T, policies ...GeoPolicy) *Server {
+	t.Helper()
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithGeoProvider(staticGeoProvider(map[string]GeoInfo{
+			"192.0.2.10": {Country: "KP"},
+			"192.0.2.20": {Country: "FR", ASN: 16509},
+			"192.0.2.30": {Country: "DE", ASN: 3320},
+			"192.0.2.31": {Country: "DE", ASN: 3320},
+		})),
+		WithGeoPolicy("/api", policies...),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {})
+	srv.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request) {})
+	return srv
+}
+
+func geoRequest(srv *Server, path, ip string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestGeoPolicyDenyAndLegalBlock(t *testing.T) {
+	srv := newGeoPolicyServer(t,
+		GeoPolicy{Name: "embargo", Countries: []string{"kp"}, Action: GeoPolicyLegalBlock},
+		GeoPolicy{Name: "hosting", ASNs: []uint32{16509}, Action: GeoPolicyDeny},
+	)
+
+	if code := geoRequest(srv, "/api/data", "192.0.2.10"); code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("expected 451 for embargoed country, got %d", code)
+	}
+	if code := geoRequest(srv, "/api/data", "192.0.2.20"); code != http.StatusForbidden {
+		t.Errorf("expected 403 for denied ASN, got %d", code)
+	}
+	if code := geoRequest(srv, "/api/data", "192.0.2.30"); code != http.StatusOK {
+		t.Errorf("expected unmatched request to pass, got %d", code)
+	}
+	if code := geoRequest(srv, "/public", "192.0.2.10"); code != http.StatusOK {
+		t.Errorf("expected policies to apply only to their route group, got %d", code)
+	}
+
+	hits := srv.GeoPolicyHits()
+	if hits["embargo/legal_block"] != 1 || hits["hosting/deny"] != 1 {
+		t.Errorf("unexpected policy hits: %v", hits)
+	}
+}
+
+func TestGeoPolicyAllowlistWithCatchAll(t *testing.T) {
+	srv := newGeoPolicyServer(t,
+		GeoPolicy{Name: "eu", Countries: []string{"DE", "FR"}, Action: GeoPolicyAllow},
+		GeoPolicy{Name: "default", Action: GeoPolicyDeny},
+	)
+
+	if code := geoRequest(srv, "/api/data", "192.0.2.30"); code != http.StatusOK {
+		t.Errorf("expected allowed country to pass, got %d", code)
+	}
+	if code := geoRequest(srv, "/api/data", "192.0.2.10"); code != http.StatusForbidden {
+		t.Errorf("expected catch-all deny, got %d", code)
+	}
+	if code := geoRequest(srv, "/api/data", "203.0.113.1"); code != http.StatusForbidden {
+		t.Errorf("expected unknown location to hit catch-all, got %d", code)
+	}
+}
+
+func TestGeoPolicyThrottle(t *testing.T) {
+	srv := newGeoPolicyServer(t,
+		GeoPolicy{Name: "slow", Countries: []string{"DE"}, Action: GeoPolicyThrottle, Limit: 0.001, Burst: 2},
+	)
+
+	passed := 0
+	for i := 0; i < 5; i++ {
+		if geoRequest(srv, "/api/data", "192.0.2.30") == http.StatusOK {
+			passed++
+		}
+	}
+	if passed != 2 {
+		t.Errorf("expected burst of 2 requests to pass, got %d", passed)
+	}
+	if code := geoRequest(srv, "/api/data", "192.0.2.31"); code != http.StatusTooManyRequests {
+		t.Errorf("expected clients of the same country and ASN to share the bucket, got %d", code)
+	}
+}
+
+func TestGeoPolicyThrottleByOrigin(t *testing.T) {
+	srv := newGeoPolicyServer(t,
+		GeoPolicy{Name: "slow", Countries: []string{"DE", "FR"}, Action: GeoPolicyThrottle, Limit: 0.001, Burst: 1},
+	)
+	if code := geoRequest(srv, "/api/data", "192.0.2.30"); code != http.StatusOK {
+		t.Fatalf("expected the first request to pass, got %d", code)
+	}
+	if code := geoRequest(srv, "/api/data", "192.0.2.20"); code != http.StatusOK {
+		t.Errorf("expected another country to have its own bucket, got %d", code)
+	}
+}
+
+func TestGeoPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy GeoPolicy
+	}{
+		{"missing name", GeoPolicy{Action: GeoPolicyDeny}},
+		{"unknown action", GeoPolicy{Name: "x", Action: "block"}},
+		{"throttle without limit", GeoPolicy{Name: "x", Action: GeoPolicyThrottle}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GeoPolicyMiddleware(nil, tt.policy); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+
+	if _, err := NewServer(WithGeoPolicy("/api", GeoPolicy{Name: "x", Action: GeoPolicyDeny})); err == nil {
+		t.Error("expected error when no geo provider is configured")
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func staticGeoProvider(table map[string]GeoInfo) GeoProvider {
+	return GeoProviderFunc(func(ip net.IP) (GeoInfo, error) {
+		info, ok := table[ip.String()]
+		if !ok {
+			return GeoInfo{}, errors.New("not found")
+		}
+		return info, nil
+	})
+}
+
+func TestGeoEnrichmentTagsRequest(t *testing.T) {
+	var logBuffer bytes.Buffer
+	oldLogger := logger
+	logger = slog.New(slog.NewTextHandler(&logBuffer, nil))
+	defer func() { logger = oldLogger }()
+
+	srv, err := NewServer(WithAddr(":0"), WithGeoProvider(staticGeoProvider(map[string]GeoInfo{
+		"198.51.100.1": {Country: "de", ASN: 3320},
+	})))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	var got GeoInfo
+	var found bool
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		got, found = GeoFromRequest(r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	if !found || got.Country != "DE" || got.ASN != 3320 {
+		t.Fatalf("expected DE/3320 geo info, got %+v (found=%v)", got, found)
+	}
+	if !strings.Contains(logBuffer.String(), "annotations.geo.country=DE") {
+		t.Errorf("expected country in access log, got: %s", logBuffer.String())
+	}
+}
+
+func TestGeoEnrichmentLookupFailure(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithGeoProvider(staticGeoProvider(nil)))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	found := true
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, found = GeoFromRequest(r)
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || found {
+		t.Errorf("expected unenriched request to pass, got status %d found=%v", rec.Code, found)
+	}
+}
+
+func TestRateLimitByCountry(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key := RateLimitByCountry(req); key != "" {
+		t.Errorf("expected empty key without geo info, got %q", key)
+	}
+
+	srv, err := NewServer(WithGeoProvider(staticGeoProvider(map[string]GeoInfo{
+		"192.0.2.1": {Country: "US"},
+	})))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	var key string
+	handler := GeoEnrichmentMiddleware(srv)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = RateLimitByCountry(r)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if key != "country:US" {
+		t.Errorf("expected country key, got %q", key)
+	}
+}
+
+func TestWithGeoProviderRejectsNil(t *testing.T) {
+	if _, err := NewServer(WithGeoProvider(nil)); err == nil {
+		t.Fatal("expected error for nil provider")
+	}
+}
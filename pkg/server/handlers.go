@@ -24,7 +24,7 @@ func (srv *Server) templateHandler(templateName string, data interface{}) http.H
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-		if err := srv.templates.ExecuteTemplate(w, templateName, data); err != nil {
+		if err := srv.executeTemplate(w, r, templateName, templateName, data); err != nil {
 			slog.Error("Error rendering template", "error", err)
 			http.Error(w, "Error rendering template", http.StatusInternalServerError)
 		}
@@ -68,6 +68,10 @@ func (srv *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (srv *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	if v := r.URL.Query().Get("verbose"); v == "1" || v == "true" {
+		srv.writeVerboseHealth(w, srv.isRunning.Load())
+		return
+	}
 	srv.healthHandlerHelper(w, r, "ok", &srv.isRunning)
 }
 
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"math"
+	"time"
+)
+
+// latencyBucketBounds are the upper bounds of the latency histogram buckets, growing by
+// a factor of √2 from 1µs to roughly 4.5 minutes. Quantiles are therefore accurate to
+// within about 41%, which is sufficient to tell microseconds from milliseconds.
+var latencyBucketBounds = func() []time.Duration {
+	bounds := make([]time.Duration, 57)
+	for i := range bounds {
+		bounds[i] = time.Duration(float64(time.Microsecond) * math.Pow(math.Sqrt2, float64(i)))
+	}
+	return bounds
+}()
+
+// latencyHistogram aggregates durations into fixed exponential buckets so that
+// percentiles can be estimated in constant memory. It is not safe for concurrent use;
+// callers provide locking.
+type latencyHistogram struct {
+	counts [58]uint64 // one per bound plus an overflow bucket
+	count  uint64
+	total  time.Duration
+	max    time.Duration
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.count++
+	h.total += d
+	if d > h.max {
+		h.max = d
+	}
+	for i, bound := range latencyBucketBounds {
+		if d <= bound {
+			h.counts[i]++
+			return
+		}
+	}
+	h.counts[len(h.counts)-1]++
+}
+
+// quantile estimates the q-th quantile (0 < q <= 1) as the upper bound of the bucket
+// containing it, capped at the largest observed value.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	if rank == 0 {
+		rank = 1
+	}
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= rank {
+			if i < len(latencyBucketBounds) && latencyBucketBounds[i] < h.max {
+				return latencyBucketBounds[i]
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// merge adds the observations of o to h.
+func (h *latencyHistogram) merge(o *latencyHistogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.count += o.count
+	h.total += o.total
+	h.max = max(h.max, o.max)
+}
+
+// countAbove returns the number of observations that may exceed d: those in buckets
+// whose upper bound is above d.
+func (h *latencyHistogram) countAbove(d time.Duration) uint64 {
+	var n uint64
+	for i, c := range h.counts {
+		if i >= len(latencyBucketBounds) || latencyBucketBounds[i] > d {
+			n += c
+		}
+	}
+	return n
+}
+
+func (h *latencyHistogram) mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.total / time.Duration(h.count)
+}
+
+// buckets returns the non-empty buckets in ascending order.
+func (h *latencyHistogram) buckets() []LatencyBucket {
+	var out []LatencyBucket
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		bound := time.Duration(math.MaxInt64)
+		if i < len(latencyBucketBounds) {
+			bound = latencyBucketBounds[i]
+		}
+		out = append(out, LatencyBucket{UpperBound: bound, Count: c})
+	}
+	return out
+}
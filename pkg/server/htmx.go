@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HTMXRequest holds the request headers htmx sends with the requests it makes.
+type HTMXRequest struct {
+	Enabled        bool   // HX-Request: the request was made by htmx
+	Boosted        bool   // HX-Boosted: a boosted link or form, which expects a full page
+	HistoryRestore bool   // HX-History-Restore-Request: the page is restored after a cache miss
+	Target         string // HX-Target: id of the target element
+	Trigger        string // HX-Trigger: id of the element that triggered the request
+	TriggerName    string // HX-Trigger-Name: name of the element that triggered the request
+	CurrentURL     string // HX-Current-URL: URL of the browser
+	Prompt         string // HX-Prompt: the user's response to hx-prompt
+}
+
+// HTMX returns the htmx headers of r. For requests not made by htmx, Enabled is false.
+func HTMX(r *http.Request) HTMXRequest {
+	return HTMXRequest{
+		Enabled:        r.Header.Get("HX-Request") == "true",
+		Boosted:        r.Header.Get("HX-Boosted") == "true",
+		HistoryRestore: r.Header.Get("HX-History-Restore-Request") == "true",
+		Target:         r.Header.Get("HX-Target"),
+		Trigger:        r.Header.Get("HX-Trigger"),
+		TriggerName:    r.Header.Get("HX-Trigger-Name"),
+		CurrentURL:     r.Header.Get("HX-Current-URL"),
+		Prompt:         r.Header.Get("HX-Prompt"),
+	}
+}
+
+// IsHTMX reports whether r was made by htmx.
+func IsHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// wantsPartial reports whether an htmx request swaps a fragment into the page, rather
+// than loading a whole page as boosted and history restore requests do.
+func (h HTMXRequest) wantsPartial() bool {
+	return h.Enabled && !h.Boosted && !h.HistoryRestore
+}
+
+// HTMXRedirect makes htmx navigate to url with a full page load. Unlike an HTTP
+// redirect, which htmx follows with another AJAX request, the header is sent with a
+// normal response.
+func HTMXRedirect(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Redirect", url)
+}
+
+// HTMXTrigger triggers a client-side event once htmx has received the response. A
+// non-nil detail is passed as the event's detail and must be JSON-encodable. Calling
+// it several times triggers several events.
+//
+// Example:
+//
+//	server.HTMXTrigger(w, "userCreated", map[string]any{"id": user.ID})
+//	server.HTMXTrigger(w, "closeModal", nil)
+func HTMXTrigger(w http.ResponseWriter, event string, detail any) error {
+	names, details, err := parseHTMXTriggers(w.Header().Get("HX-Trigger"))
+	if err != nil {
+		return err
+	}
+	if _, exists := details[event]; !exists {
+		names = append(names, event)
+	}
+	details[event] = detail
+
+	plain := true
+	for _, d := range details {
+		if d != nil {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		// Events without details are sent as a list of names, which is easier to read
+		w.Header().Set("HX-Trigger", strings.Join(names, ", "))
+		return nil
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(details[name])
+		if err != nil {
+			return fmt.Errorf("failed to encode detail of htmx event %s: %w", name, err)
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	w.Header().Set("HX-Trigger", b.String())
+	return nil
+}
+
+// parseHTMXTriggers parses an HX-Trigger response header, either a list of event names
+// or a JSON object of events and their details, keeping the order of the events.
+func parseHTMXTriggers(header string) ([]string, map[string]any, error) {
+	details := make(map[string]any)
+	var names []string
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return names, details, nil
+	}
+	if !strings.HasPrefix(header, "{") {
+		for _, name := range strings.Split(header, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				if _, exists := details[name]; !exists {
+					names = append(names, name)
+				}
+				details[name] = nil
+			}
+		}
+		return names, details, nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(header))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, fmt.Errorf("invalid HX-Trigger header: %w", err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid HX-Trigger header: %w", err)
+		}
+		name, _ := tok.(string)
+		var detail json.RawMessage
+		if err := dec.Decode(&detail); err != nil {
+			return nil, nil, fmt.Errorf("invalid HX-Trigger header: %w", err)
+		}
+		names = append(names, name)
+		details[name] = detail
+		if string(detail) == "null" {
+			details[name] = nil
+		}
+	}
+	return names, details, nil
+}
+
+// RenderHTMX renders block of page for htmx requests that swap a fragment into the page,
+// and the whole page otherwise, including for boosted navigation. The response varies
+// by HX-Request so that caches keep both forms apart.
+//
+// Example:
+//
+//	srv.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+//		srv.RenderHTMX(w, r, "users.html", "rows", users)
+//	})
+func (srv *Server) RenderHTMX(w http.ResponseWriter, r *http.Request, page, block string, data any) error {
+	w.Header().Add("Vary", "HX-Request")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if !HTMX(r).wantsPartial() {
+		block = page
+	}
+	return srv.executeTemplate(w, r, page, block, data)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTMXRequestHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if IsHTMX(req) || HTMX(req).Enabled {
+		t.Fatal("expected a plain request not to be an htmx request")
+	}
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-Target", "user-list")
+	req.Header.Set("HX-Trigger", "load-more")
+	req.Header.Set("HX-Trigger-Name", "page")
+	h := HTMX(req)
+	if !IsHTMX(req) || !h.Enabled || h.Boosted || h.Target != "user-list" || h.Trigger != "load-more" || h.TriggerName != "page" {
+		t.Errorf("unexpected htmx headers: %+v", h)
+	}
+}
+
+func TestHTMXResponseHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HTMXRedirect(rec, "/login")
+	if got := rec.Header().Get("HX-Redirect"); got != "/login" {
+		t.Errorf("expected HX-Redirect /login, got %q", got)
+	}
+
+	HTMXTrigger(rec, "closeModal", nil)
+	HTMXTrigger(rec, "refresh", nil)
+	if got := rec.Header().Get("HX-Trigger"); got != "closeModal, refresh" {
+		t.Errorf("expected a list of events, got %q", got)
+	}
+	if err := HTMXTrigger(rec, "userCreated", map[string]int{"id": 7}); err != nil {
+		t.Fatalf("HTMXTrigger: %v", err)
+	}
+	want := `{"closeModal":null,"refresh":null,"userCreated":{"id":7}}`
+	if got := rec.Header().Get("HX-Trigger"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	HTMXTrigger(rec, "refresh", "all")
+	want = `{"closeModal":null,"refresh":"all","userCreated":{"id":7}}`
+	if got := rec.Header().Get("HX-Trigger"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if err := HTMXTrigger(rec, "bad", func() {}); err == nil {
+		t.Error("expected an error for a detail that cannot be encoded")
+	}
+}
+
+func TestRenderHTMX(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/base.html": `<main>{{block "content" .}}{{end}}</main>`,
+		"list.html":         `{{template "layouts/base.html" .}}{{define "content"}}<ul>{{block "items" .}}{{range .}}<li>{{.}}</li>{{end}}{{end}}</ul>{{end}}`,
+	})
+	srv, err := NewServer(WithTemplateDir(dir))
+	if err != nil {
+		t.Fatalf("error creating server: %v", err)
+	}
+	srv.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		srv.RenderHTMX(w, r, "list.html", "items", []string{"a"})
+	})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"full page", nil, "<main><ul><li>a</li></ul></main>"},
+		{"htmx swap", map[string]string{"HX-Request": "true"}, "<li>a</li>"},
+		{"boosted", map[string]string{"HX-Request": "true", "HX-Boosted": "true"}, "<main><ul><li>a</li></ul></main>"},
+		{"history restore", map[string]string{"HX-Request": "true", "HX-History-Restore-Request": "true"}, "<main><ul><li>a</li></ul></main>"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		if rec.Body.String() != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, rec.Body.String())
+		}
+		if rec.Header().Get("Vary") != "HX-Request" {
+			t.Errorf("%s: expected Vary: HX-Request, got %q", tt.name, rec.Header().Get("Vary"))
+		}
+	}
+}
@@ -120,7 +120,9 @@ func (ic *InterceptorChain) WrapHandler(next http.Handler) http.Handler {
 		ic.mu.RUnlock()
 
 		for _, interceptor := range interceptors {
+			start := time.Now()
 			resp, err := interceptor.InterceptRequest(r.Context(), ireq)
+			observeLayer(r.Context(), "interceptor:"+interceptor.Name(), time.Since(start))
 			if err != nil {
 				// Interceptor error - return 500
 				http.Error(w, "Interceptor error", http.StatusInternalServerError)
@@ -156,7 +158,9 @@ func (ic *InterceptorChain) WrapHandler(next http.Handler) http.Handler {
 
 		// Run response interceptors in reverse order
 		for i := len(interceptors) - 1; i >= 0; i-- {
+			start := time.Now()
 			err := interceptors[i].InterceptResponse(r.Context(), ireq, iresp)
+			observeLayer(r.Context(), "interceptor:"+interceptors[i].Name(), time.Since(start))
 			if err != nil {
 				// Log error but continue
 				// In production, you might want to handle this differently
@@ -228,6 +232,14 @@ func (rr *responseRecorder) Header() http.Header {
 }
 
 func (rr *responseRecorder) WriteHeader(code int) {
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		// Pass informational responses such as 103 Early Hints straight through
+		for k, v := range rr.Header() {
+			rr.ResponseWriter.Header()[k] = v
+		}
+		rr.ResponseWriter.WriteHeader(code)
+		return
+	}
 	if !rr.written {
 		rr.statusCode = code
 		rr.written = true
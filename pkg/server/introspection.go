@@ -0,0 +1,327 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrIntrospectionUnavailable is returned while the introspection circuit breaker is open
+// after repeated failures to reach the introspection endpoint.
+var ErrIntrospectionUnavailable = errors.New("token introspection unavailable")
+
+const (
+	defaultIntrospectionCacheTTL         = 5 * time.Minute
+	defaultIntrospectionNegativeCacheTTL = 10 * time.Second
+	defaultIntrospectionTimeout          = 5 * time.Second
+	defaultIntrospectionFailureThreshold = 5
+	defaultIntrospectionResetTimeout     = 30 * time.Second
+	introspectionCacheMaxEntries         = 10000
+)
+
+// IntrospectionConfig configures validation of bearer tokens against an OAuth2 token
+// introspection endpoint (RFC 7662), typically provided by an external identity provider.
+// The client secret is never read from options.json; set it programmatically or via the
+// HS_INTROSPECTION_CLIENT_SECRET environment variable.
+type IntrospectionConfig struct {
+	Endpoint         string        `json:"endpoint"`
+	ClientID         string        `json:"client_id,omitempty"`
+	ClientSecret     string        `json:"-"`
+	RequiredScopes   []string      `json:"required_scopes,omitempty"` // All scopes must be granted
+	Audience         string        `json:"audience,omitempty"`        // Required "aud" value, if set
+	CacheTTL         time.Duration `json:"cache_ttl,omitempty"`       // Upper bound for caching active tokens (default 5m)
+	NegativeCacheTTL time.Duration `json:"negative_cache_ttl,omitempty"`
+	Timeout          time.Duration `json:"timeout,omitempty"`           // Per-request timeout (default 5s)
+	FailureThreshold int           `json:"failure_threshold,omitempty"` // Consecutive failures that open the circuit (default 5)
+	ResetTimeout     time.Duration `json:"reset_timeout,omitempty"`     // How long the circuit stays open (default 30s)
+	HTTPClient       *http.Client  `json:"-"`                           // Defaults to Server.HTTPClient
+}
+
+// IntrospectionResult holds the fields of an RFC 7662 introspection response.
+type IntrospectionResult struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Sid       string   `json:"sid,omitempty"` // Identity provider session, returned by some providers
+	Audience  []string `json:"-"`
+}
+
+// UnmarshalJSON accepts "aud" as either a string or an array of strings.
+func (res *IntrospectionResult) UnmarshalJSON(data []byte) error {
+	type plain IntrospectionResult
+	var raw struct {
+		plain
+		Aud json.RawMessage `json:"aud,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*res = IntrospectionResult(raw.plain)
+	if len(raw.Aud) == 0 {
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(raw.Aud, &single); err == nil {
+		res.Audience = []string{single}
+		return nil
+	}
+	return json.Unmarshal(raw.Aud, &res.Audience)
+}
+
+// HasScope reports whether the token was granted the given scope.
+func (res *IntrospectionResult) HasScope(scope string) bool {
+	for _, s := range strings.Fields(res.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type introspectionCacheEntry struct {
+	result    *IntrospectionResult
+	expiresAt time.Time
+}
+
+// IntrospectionValidator validates bearer tokens by calling an introspection endpoint.
+// Results are cached by token hash, and a circuit breaker stops calling the endpoint
+// after repeated failures so that an unavailable identity provider does not stall
+// every request.
+type IntrospectionValidator struct {
+	config IntrospectionConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]introspectionCacheEntry
+
+	breakerMu sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// NewIntrospectionValidator creates a validator for the given configuration.
+func NewIntrospectionValidator(cfg IntrospectionConfig) (*IntrospectionValidator, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return nil, fmt.Errorf("invalid introspection endpoint %q", cfg.Endpoint)
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultIntrospectionCacheTTL
+	}
+	if cfg.NegativeCacheTTL <= 0 {
+		cfg.NegativeCacheTTL = defaultIntrospectionNegativeCacheTTL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultIntrospectionTimeout
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultIntrospectionFailureThreshold
+	}
+	if cfg.ResetTimeout <= 0 {
+		cfg.ResetTimeout = defaultIntrospectionResetTimeout
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &IntrospectionValidator{
+		config: cfg,
+		client: client,
+		cache:  make(map[string]introspectionCacheEntry),
+	}, nil
+}
+
+// Validate reports whether the token is active and satisfies the required audience and
+// scopes. It matches the signature expected by WithAuthTokenValidator.
+func (v *IntrospectionValidator) Validate(token string) (bool, error) {
+	res, err := v.Introspect(context.Background(), token)
+	if err != nil {
+		return false, err
+	}
+	return v.accepts(res), nil
+}
+
+// Introspect returns the introspection result for the token, from cache if possible.
+func (v *IntrospectionValidator) Introspect(ctx context.Context, token string) (*IntrospectionResult, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	v.mu.Lock()
+	entry, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.result, nil
+	}
+
+	if !v.allowRequest(now) {
+		return nil, ErrIntrospectionUnavailable
+	}
+	res, err := v.introspect(ctx, token)
+	v.recordOutcome(err)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := v.config.NegativeCacheTTL
+	if res.Active {
+		ttl = v.config.CacheTTL
+		if res.Exp > 0 {
+			if untilExpiry := time.Until(time.Unix(res.Exp, 0)); untilExpiry < ttl {
+				ttl = untilExpiry
+			}
+		}
+	}
+	if ttl > 0 {
+		v.store(key, res, now.Add(ttl))
+	}
+	return res, nil
+}
+
+func (v *IntrospectionValidator) introspect(ctx context.Context, token string) (*IntrospectionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.config.Timeout)
+	defer cancel()
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.config.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if v.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(v.config.ClientID), url.QueryEscape(v.config.ClientSecret))
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned status %d", resp.StatusCode)
+	}
+	var res IntrospectionResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&res); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	return &res, nil
+}
+
+// accepts applies expiry, audience, and scope checks to an introspection result.
+func (v *IntrospectionValidator) accepts(res *IntrospectionResult) bool {
+	if !res.Active {
+		return false
+	}
+	if res.Exp > 0 && time.Now().After(time.Unix(res.Exp, 0)) {
+		return false
+	}
+	if v.config.Audience != "" {
+		found := false
+		for _, aud := range res.Audience {
+			if aud == v.config.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, scope := range v.config.RequiredScopes {
+		if !res.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *IntrospectionValidator) store(key string, res *IntrospectionResult, expiresAt time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.cache) >= introspectionCacheMaxEntries {
+		now := time.Now()
+		for k, e := range v.cache {
+			if now.After(e.expiresAt) {
+				delete(v.cache, k)
+			}
+		}
+		if len(v.cache) >= introspectionCacheMaxEntries {
+			v.cache = make(map[string]introspectionCacheEntry)
+		}
+	}
+	v.cache[key] = introspectionCacheEntry{result: res, expiresAt: expiresAt}
+}
+
+// allowRequest reports whether the circuit breaker lets a request through. Once the
+// reset timeout has passed, a single trial request is allowed.
+func (v *IntrospectionValidator) allowRequest(now time.Time) bool {
+	v.breakerMu.Lock()
+	defer v.breakerMu.Unlock()
+	if v.failures < v.config.FailureThreshold {
+		return true
+	}
+	if now.Before(v.openUntil) {
+		return false
+	}
+	v.openUntil = now.Add(v.config.ResetTimeout)
+	return true
+}
+
+func (v *IntrospectionValidator) recordOutcome(err error) {
+	v.breakerMu.Lock()
+	defer v.breakerMu.Unlock()
+	if err == nil {
+		v.failures = 0
+		return
+	}
+	v.failures++
+	if v.failures == v.config.FailureThreshold {
+		v.openUntil = time.Now().Add(v.config.ResetTimeout)
+		logger.Warn("Token introspection circuit opened", "failures", v.failures, "retry_in", v.config.ResetTimeout)
+	}
+}
+
+// WithIntrospection validates bearer tokens against an OAuth2 introspection endpoint
+// (RFC 7662) instead of a custom validator, for deployments fronting an external identity
+// provider. It replaces the token validator used by AuthMiddleware. Introspection can also
+// be configured via the "introspection" key in options.json or the HS_INTROSPECTION_*
+// environment variables.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithIntrospection(server.IntrospectionConfig{
+//			Endpoint:       "https://idp.example.com/oauth2/introspect",
+//			ClientID:       "gateway",
+//			ClientSecret:   os.Getenv("IDP_CLIENT_SECRET"),
+//			RequiredScopes: []string{"api:read"},
+//		}),
+//	)
+func WithIntrospection(cfg IntrospectionConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		// The validator is created by NewServer, once secret references are resolved
+		if _, err := NewIntrospectionValidator(cfg); err != nil {
+			return err
+		}
+		srv.Options.Introspection = &cfg
+		return nil
+	}
+}
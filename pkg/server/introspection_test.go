@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newIntrospectionEndpoint(t *testing.T, calls *atomic.Int32, respond func(w http.ResponseWriter, token string)) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "gateway" || secret != "s3cret" {
+			t.Errorf("unexpected client credentials %q/%q", id, secret)
+		}
+		respond(w, r.PostFormValue("token"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestIntrospectionValidator(t *testing.T) {
+	var calls atomic.Int32
+	ts := newIntrospectionEndpoint(t, &calls, func(w http.ResponseWriter, token string) {
+		switch token {
+		case "good":
+			json.NewEncoder(w).Encode(map[string]any{
+				"active": true, "scope": "api:read api:write", "aud": "orders",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+		case "wrong-aud":
+			json.NewEncoder(w).Encode(map[string]any{"active": true, "scope": "api:read", "aud": []string{"billing"}})
+		case "no-scope":
+			json.NewEncoder(w).Encode(map[string]any{"active": true, "aud": "orders"})
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"active": false})
+		}
+	})
+
+	v, err := NewIntrospectionValidator(IntrospectionConfig{
+		Endpoint:       ts.URL,
+		ClientID:       "gateway",
+		ClientSecret:   "s3cret",
+		Audience:       "orders",
+		RequiredScopes: []string{"api:read"},
+	})
+	if err != nil {
+		t.Fatalf("NewIntrospectionValidator: %v", err)
+	}
+
+	tests := map[string]bool{"good": true, "wrong-aud": false, "no-scope": false, "revoked": false}
+	for token, want := range tests {
+		got, err := v.Validate(token)
+		if err != nil {
+			t.Fatalf("Validate(%q): %v", token, err)
+		}
+		if got != want {
+			t.Errorf("Validate(%q) = %v, want %v", token, got, want)
+		}
+	}
+
+	// Results are cached, including inactive tokens
+	before := calls.Load()
+	for token := range tests {
+		v.Validate(token)
+	}
+	if calls.Load() != before {
+		t.Errorf("expected cached results, endpoint called %d more times", calls.Load()-before)
+	}
+}
+
+func TestIntrospectionCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	ts := newIntrospectionEndpoint(t, &calls, func(w http.ResponseWriter, token string) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"active": true})
+	})
+
+	v, err := NewIntrospectionValidator(IntrospectionConfig{
+		Endpoint:         ts.URL,
+		ClientID:         "gateway",
+		ClientSecret:     "s3cret",
+		FailureThreshold: 2,
+		ResetTimeout:     50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewIntrospectionValidator: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := v.Validate("token"); err == nil || errors.Is(err, ErrIntrospectionUnavailable) {
+			t.Fatalf("attempt %d: expected endpoint error, got %v", i, err)
+		}
+	}
+	if _, err := v.Validate("token"); !errors.Is(err, ErrIntrospectionUnavailable) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("endpoint called %d times while circuit open, want 2", calls.Load())
+	}
+
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if ok, err := v.Validate("token"); err != nil || !ok {
+		t.Fatalf("expected recovery after reset timeout, got %v, %v", ok, err)
+	}
+}
+
+func TestWithIntrospectionFromEnvironment(t *testing.T) {
+	var calls atomic.Int32
+	ts := newIntrospectionEndpoint(t, &calls, func(w http.ResponseWriter, token string) {
+		json.NewEncoder(w).Encode(map[string]any{"active": token == "good"})
+	})
+	t.Setenv(paramIntrospectURL, ts.URL)
+	t.Setenv(paramIntrospectClientID, "gateway")
+	t.Setenv(paramIntrospectSecret, "s3cret")
+
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	handler := AuthMiddleware(srv.Options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for token, want := range map[string]int{"good": http.StatusOK, "bad": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("token %q: status %d, want %d", token, rec.Code, want)
+		}
+	}
+}
+
+func TestWithIntrospectionResolvesClientSecret(t *testing.T) {
+	var calls atomic.Int32
+	ts := newIntrospectionEndpoint(t, &calls, func(w http.ResponseWriter, token string) {
+		json.NewEncoder(w).Encode(map[string]any{"active": true})
+	})
+	t.Setenv("TEST_IDP_SECRET", "s3cret")
+
+	srv, err := NewServer(WithIntrospection(IntrospectionConfig{
+		Endpoint:     ts.URL,
+		ClientID:     "gateway",
+		ClientSecret: "${env:TEST_IDP_SECRET}",
+	}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	if ok, err := srv.Options.AuthTokenValidatorFunc("good"); !ok || err != nil {
+		t.Errorf("expected the token to be accepted with the resolved secret, got %v, %v", ok, err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected one introspection call, got %d", calls.Load())
+	}
+	if srv.HTTPClientStats().Requests != 1 {
+		t.Error("expected introspection to use the shared client")
+	}
+}
+
+func TestWithIntrospectionRejectsInvalidEndpoint(t *testing.T) {
+	if _, err := NewServer(WithIntrospection(IntrospectionConfig{Endpoint: "idp.local/introspect"})); err == nil {
+		t.Error("expected error for endpoint without scheme")
+	}
+}
@@ -8,6 +8,8 @@ type (
 	JSONRPCError         = pkgjsonrpc.ErrorDetails
 	JSONRPCEngine        = pkgjsonrpc.Engine
 	JSONRPCMethodHandler = pkgjsonrpc.MethodHandler
+
+	JSONRPCContextMethodHandler = pkgjsonrpc.ContextMethodHandler
 )
 
 const (
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const layerScopeKey contextKey = "layerScope"
+
+// LayerMetrics reports the time spent inside one middleware layer or interceptor for a
+// route, excluding the time spent in the layers and handler it wraps.
+type LayerMetrics struct {
+	Route string        `json:"route"`
+	Layer string        `json:"layer"`
+	Count uint64        `json:"count"`
+	Total time.Duration `json:"total"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P99   time.Duration `json:"p99"`
+}
+
+type layerKey struct {
+	route string
+	layer string
+}
+
+// layerMetricsStore aggregates per-layer execution time by route.
+type layerMetricsStore struct {
+	mu     sync.Mutex
+	layers map[layerKey]*latencyHistogram
+}
+
+func newLayerMetricsStore() *layerMetricsStore {
+	return &layerMetricsStore{layers: make(map[layerKey]*latencyHistogram)}
+}
+
+func (s *layerMetricsStore) observe(route, layer string, d time.Duration) {
+	key := layerKey{route: route, layer: layer}
+	s.mu.Lock()
+	h, ok := s.layers[key]
+	if !ok {
+		h = &latencyHistogram{}
+		s.layers[key] = h
+	}
+	h.observe(d)
+	s.mu.Unlock()
+}
+
+func (s *layerMetricsStore) snapshot() []LayerMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]LayerMetrics, 0, len(s.layers))
+	for key, h := range s.layers {
+		out = append(out, LayerMetrics{
+			Route: key.route,
+			Layer: key.layer,
+			Count: h.count,
+			Total: h.total,
+			Mean:  h.mean(),
+			P50:   h.quantile(0.50),
+			P99:   h.quantile(0.99),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Route != out[j].Route {
+			return out[i].Route < out[j].Route
+		}
+		return out[i].Layer < out[j].Layer
+	})
+	return out
+}
+
+// layerScope is carried in the request context so that nested layers such as
+// interceptors can report their timings.
+type layerScope struct {
+	store *layerMetricsStore
+	route string
+}
+
+// observeLayer records a layer timing if middleware metrics are enabled for the request.
+func observeLayer(ctx context.Context, layer string, d time.Duration) {
+	if scope, ok := ctx.Value(layerScopeKey).(*layerScope); ok {
+		scope.store.observe(scope.route, layer, d)
+	}
+}
+
+// wrap times a middleware layer, subtracting the time spent in next.
+func (s *layerMetricsStore) wrap(mw MiddlewareFunc, next http.Handler, route string) http.Handler {
+	name := middlewareName(mw)
+	var inner atomic.Int64
+	layer := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		inner.Add(int64(time.Since(start)))
+	}))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		layer.ServeHTTP(w, r)
+		s.observe(route, name, time.Since(start)-time.Duration(inner.Load()))
+	})
+}
+
+var (
+	middlewareNames   sync.Map // map[uintptr]string
+	closureNameSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+)
+
+// middlewareName derives a readable layer name from the function that created the
+// middleware, e.g. "server.RateLimitMiddleware".
+func middlewareName(mw MiddlewareFunc) string {
+	pc := reflect.ValueOf(mw).Pointer()
+	if name, ok := middlewareNames.Load(pc); ok {
+		return name.(string)
+	}
+	name := "unknown"
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		name = fn.Name()
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		name = closureNameSuffix.ReplaceAllString(name, "")
+	}
+	middlewareNames.Store(pc, name)
+	return name
+}
+
+// WithMiddlewareMetrics measures the time spent in each middleware layer and interceptor
+// per route, so that expensive layers in long chains can be identified. The results are
+// available from srv.MiddlewareMetrics() and the metrics MCP resource.
+// Measuring adds two clock reads per layer per request.
+func WithMiddlewareMetrics() ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.MiddlewareMetrics = true
+		srv.middleware.layerMetrics = newLayerMetricsStore()
+		return nil
+	}
+}
+
+// MiddlewareMetrics returns the aggregated execution time of each middleware layer and
+// interceptor per route. Returns nil unless WithMiddlewareMetrics is enabled.
+func (srv *Server) MiddlewareMetrics() []LayerMetrics {
+	if srv.middleware == nil || srv.middleware.layerMetrics == nil {
+		return nil
+	}
+	return srv.middleware.layerMetrics.snapshot()
+}
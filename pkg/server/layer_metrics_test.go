@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func slowMiddleware(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		next.ServeHTTP(w, r)
+	}
+}
+
+type slowInterceptor struct{}
+
+func (slowInterceptor) Name() string { return "slow" }
+func (slowInterceptor) InterceptRequest(ctx context.Context, req *InterceptableRequest) (*InterceptorResponse, error) {
+	time.Sleep(2 * time.Millisecond)
+	return nil, nil
+}
+func (slowInterceptor) InterceptResponse(ctx context.Context, req *InterceptableRequest, resp *InterceptableResponse) error {
+	return nil
+}
+
+func TestMiddlewareMetricsPerLayer(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithMiddlewareMetrics())
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.AddMiddleware("/api", slowMiddleware)
+
+	chain := NewInterceptorChain()
+	chain.Add(slowInterceptor{})
+	srv.mux.Handle("GET /api/items", chain.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	})))
+
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	layers := map[string]LayerMetrics{}
+	for _, m := range srv.MiddlewareMetrics() {
+		if m.Route != "GET /api/items" {
+			t.Errorf("expected route pattern, got %q", m.Route)
+		}
+		layers[m.Layer] = m
+	}
+
+	slow, ok := layers["server.slowMiddleware"]
+	if !ok {
+		t.Fatalf("expected slowMiddleware layer, got %v", layers)
+	}
+	// Self time excludes the 10ms handler
+	if slow.Total < 5*time.Millisecond || slow.Total >= 15*time.Millisecond {
+		t.Errorf("expected ~5ms self time for slow layer, got %v", slow.Total)
+	}
+	if _, ok := layers["server.MetricsMiddleware"]; !ok {
+		t.Errorf("expected closure middleware to be named after its constructor, got %v", layers)
+	}
+	if m, ok := layers["interceptor:slow"]; !ok || m.Count != 2 || m.Total < 2*time.Millisecond {
+		t.Errorf("expected interceptor timings, got %+v", m)
+	}
+}
+
+func TestMiddlewareMetricsDisabledByDefault(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if m := srv.MiddlewareMetrics(); m != nil {
+		t.Errorf("expected no layer metrics when disabled, got %v", m)
+	}
+}
+
+func TestLatencyHistogramQuantiles(t *testing.T) {
+	var h latencyHistogram
+	for i := 0; i < 99; i++ {
+		h.observe(30 * time.Microsecond)
+	}
+	h.observe(3 * time.Millisecond)
+
+	if p50 := h.quantile(0.5); p50 < 30*time.Microsecond || p50 > 45*time.Microsecond {
+		t.Errorf("expected p50 near 30µs, got %v", p50)
+	}
+	if p99 := h.quantile(0.99); p99 > 45*time.Microsecond {
+		t.Errorf("expected p99 near 30µs, got %v", p99)
+	}
+	if p100 := h.quantile(1); p100 != 3*time.Millisecond {
+		t.Errorf("expected max of 3ms, got %v", p100)
+	}
+	if h.mean() != (99*30*time.Microsecond+3*time.Millisecond)/100 {
+		t.Errorf("unexpected mean %v", h.mean())
+	}
+}
@@ -1,6 +1,13 @@
 package server
 
-import "log/slog"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
 
 var logger = slog.Default()
 
@@ -17,3 +24,270 @@ func SetDefaultLogger(l *slog.Logger) {
 	}
 	logger = l
 }
+
+// Log modules used by the server package. Records are assigned to a module by their
+// "module" attribute; use ModuleLogger to tag application loggers.
+const (
+	LogModuleHTTP = "http"
+	LogModuleMCP  = "mcp"
+	LogModuleWS   = "ws"
+
+	logModuleKey = "module"
+)
+
+// LogConfig reduces log volume on busy servers. Debug and info records can be sampled,
+// keeping 1 in N; warnings and errors are always logged. ModuleLevels overrides the global
+// log level per module, e.g. {"mcp": "DEBUG", "http": "WARN"}.
+// All settings can be changed at runtime with the server_control MCP tool.
+type LogConfig struct {
+	SampleDebug  int               `json:"sample_debug,omitempty"`  // Keep 1 in N debug records (0 or 1 keeps all)
+	SampleInfo   int               `json:"sample_info,omitempty"`   // Keep 1 in N info records (0 or 1 keeps all)
+	ModuleLevels map[string]string `json:"module_levels,omitempty"` // Module name -> level
+}
+
+// logControl holds the process-wide sampling and module level state. Like the package
+// logger it is shared by all servers in the process.
+type logControl struct {
+	sampleDebug atomic.Int64
+	sampleInfo  atomic.Int64
+	debugSeen   atomic.Uint64
+	infoSeen    atomic.Uint64
+
+	mu       sync.RWMutex
+	modules  map[string]slog.Level
+	minLevel slog.Level // lowest module override, valid if len(modules) > 0
+}
+
+var logControls = &logControl{modules: make(map[string]slog.Level)}
+
+func (c *logControl) moduleLevel(module string) (slog.Level, bool) {
+	if module == "" {
+		return 0, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	level, ok := c.modules[module]
+	return level, ok
+}
+
+// overrideBelow reports whether some module is configured to log at the given level.
+func (c *logControl) overrideBelow(level slog.Level) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.modules) > 0 && level >= c.minLevel
+}
+
+func (c *logControl) setModuleLevel(module string, level slog.Level, clear bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if clear {
+		delete(c.modules, module)
+	} else {
+		c.modules[module] = level
+	}
+	first := true
+	for _, l := range c.modules {
+		if first || l < c.minLevel {
+			c.minLevel = l
+			first = false
+		}
+	}
+}
+
+// sample reports whether a record at the given level passes sampling.
+func (c *logControl) sample(level slog.Level) bool {
+	var every int64
+	var seen *atomic.Uint64
+	switch {
+	case level < slog.LevelInfo:
+		every, seen = c.sampleDebug.Load(), &c.debugSeen
+	case level < slog.LevelWarn:
+		every, seen = c.sampleInfo.Load(), &c.infoSeen
+	default:
+		return true
+	}
+	if every <= 1 {
+		return true
+	}
+	return (seen.Add(1)-1)%uint64(every) == 0
+}
+
+// logControlHandler applies module levels and sampling before passing records on.
+type logControlHandler struct {
+	next   slog.Handler
+	ctl    *logControl
+	module string // set when the logger was created with a module attribute
+}
+
+func (h *logControlHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if threshold, ok := h.ctl.moduleLevel(h.module); ok {
+		return level >= threshold
+	}
+	if h.next.Enabled(ctx, level) {
+		return true
+	}
+	// The record may carry a module attribute with a more verbose override
+	return h.module == "" && h.ctl.overrideBelow(level)
+}
+
+func (h *logControlHandler) Handle(ctx context.Context, r slog.Record) error {
+	module := h.module
+	if module == "" {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == logModuleKey {
+				module = a.Value.String()
+				return false
+			}
+			return true
+		})
+	}
+	if threshold, ok := h.ctl.moduleLevel(module); ok {
+		if r.Level < threshold {
+			return nil
+		}
+	} else if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	if !h.ctl.sample(r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *logControlHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	module := h.module
+	for _, a := range attrs {
+		if a.Key == logModuleKey {
+			module = a.Value.String()
+		}
+	}
+	return &logControlHandler{next: h.next.WithAttrs(attrs), ctl: h.ctl, module: module}
+}
+
+func (h *logControlHandler) WithGroup(name string) slog.Handler {
+	return &logControlHandler{next: h.next.WithGroup(name), ctl: h.ctl, module: h.module}
+}
+
+// installLogControl wraps the package logger so module levels and sampling apply to it.
+func installLogControl() {
+	if _, ok := logger.Handler().(*logControlHandler); ok {
+		return
+	}
+	logger = slog.New(&logControlHandler{next: logger.Handler(), ctl: logControls})
+}
+
+// ModuleLogger returns the package logger tagged with a module, so that its records follow
+// the module's level override.
+func ModuleLogger(module string) *slog.Logger {
+	return logger.With(logModuleKey, module)
+}
+
+// parseLogLevel accepts level names such as "DEBUG", "info", or "WARN+2".
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return level, nil
+}
+
+// SetModuleLogLevel overrides the log level of a module, independently of the global level.
+func SetModuleLogLevel(module string, level slog.Level) {
+	logControls.setModuleLevel(module, level, false)
+}
+
+// ClearModuleLogLevel removes a module's level override so it follows the global level.
+func ClearModuleLogLevel(module string) {
+	logControls.setModuleLevel(module, 0, true)
+}
+
+// ModuleLogLevels returns the current per-module level overrides.
+func ModuleLogLevels() map[string]slog.Level {
+	logControls.mu.RLock()
+	defer logControls.mu.RUnlock()
+	levels := make(map[string]slog.Level, len(logControls.modules))
+	for module, level := range logControls.modules {
+		levels[module] = level
+	}
+	return levels
+}
+
+// SetLogSampling keeps 1 in debugEvery debug records and 1 in infoEvery info records.
+// Values of 0 or 1 disable sampling for that level. Warnings and errors are never sampled.
+func SetLogSampling(debugEvery, infoEvery int) {
+	logControls.sampleDebug.Store(int64(debugEvery))
+	logControls.sampleInfo.Store(int64(infoEvery))
+}
+
+// LogSampling returns the current sampling rates for debug and info records.
+func LogSampling() (debugEvery, infoEvery int) {
+	return int(logControls.sampleDebug.Load()), int(logControls.sampleInfo.Load())
+}
+
+// applyLogConfig applies sampling and module levels from the server configuration.
+func applyLogConfig(cfg *LogConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.SampleDebug < 0 || cfg.SampleInfo < 0 {
+		return fmt.Errorf("log sampling rates must not be negative")
+	}
+	for module, name := range cfg.ModuleLevels {
+		level, err := parseLogLevel(name)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		SetModuleLogLevel(module, level)
+	}
+	SetLogSampling(cfg.SampleDebug, cfg.SampleInfo)
+	return nil
+}
+
+// WithLogSampling keeps 1 in debugEvery debug records and 1 in infoEvery info records, so
+// high-traffic servers are not drowned in per-request logs. Warnings and errors are always
+// logged. Use 0 or 1 to keep every record of a level.
+func WithLogSampling(debugEvery, infoEvery int) ServerOptionFunc {
+	return func(srv *Server) error {
+		if debugEvery < 0 || infoEvery < 0 {
+			return fmt.Errorf("log sampling rates must not be negative")
+		}
+		cfg := srv.ensureLogConfig()
+		cfg.SampleDebug = debugEvery
+		cfg.SampleInfo = infoEvery
+		return nil
+	}
+}
+
+// WithModuleLogLevel sets the log level of one module (LogModuleHTTP, LogModuleMCP,
+// LogModuleWS, or an application module created with ModuleLogger).
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithLoglevel(slog.LevelWarn),
+//		server.WithModuleLogLevel(server.LogModuleMCP, slog.LevelDebug),
+//	)
+func WithModuleLogLevel(module string, level slog.Level) ServerOptionFunc {
+	return func(srv *Server) error {
+		if module == "" {
+			return fmt.Errorf("log module must not be empty")
+		}
+		cfg := srv.ensureLogConfig()
+		cfg.ModuleLevels[module] = level.String()
+		return nil
+	}
+}
+
+// ensureLogConfig returns a private copy of the logging configuration for modification.
+func (srv *Server) ensureLogConfig() *LogConfig {
+	cfg := &LogConfig{ModuleLevels: make(map[string]string)}
+	if srv.Options.Logging != nil {
+		cfg.SampleDebug = srv.Options.Logging.SampleDebug
+		cfg.SampleInfo = srv.Options.Logging.SampleInfo
+		for module, level := range srv.Options.Logging.ModuleLevels {
+			cfg.ModuleLevels[module] = level
+		}
+	}
+	srv.Options.Logging = cfg
+	return cfg
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newTestLogControl returns a logger that applies a fresh log control on top of an
+// INFO-level text handler.
+func newTestLogControl() (*slog.Logger, *logControl, *bytes.Buffer) {
+	var buf bytes.Buffer
+	ctl := &logControl{modules: make(map[string]slog.Level)}
+	next := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	return slog.New(&logControlHandler{next: next, ctl: ctl}), ctl, &buf
+}
+
+func TestLogControlModuleLevels(t *testing.T) {
+	l, ctl, buf := newTestLogControl()
+	ctl.setModuleLevel(LogModuleMCP, slog.LevelDebug, false)
+	ctl.setModuleLevel(LogModuleHTTP, slog.LevelWarn, false)
+
+	mcpLogger := l.With(logModuleKey, LogModuleMCP)
+	mcpLogger.Debug("mcp debug")
+	l.Debug("record attribute debug", logModuleKey, LogModuleMCP)
+	l.Info("request", logModuleKey, LogModuleHTTP)
+	l.Warn("slow request", logModuleKey, LogModuleHTTP)
+	l.Debug("global debug")
+	l.Info("global info")
+
+	out := buf.String()
+	for _, want := range []string{"mcp debug", "record attribute debug", "slow request", "global info"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q to be logged, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"msg=request", "global debug"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected %q to be suppressed, got:\n%s", unwanted, out)
+		}
+	}
+
+	ctl.setModuleLevel(LogModuleMCP, 0, true)
+	buf.Reset()
+	mcpLogger.Debug("mcp debug")
+	if buf.Len() != 0 {
+		t.Errorf("expected cleared module to follow the global level, got:\n%s", buf.String())
+	}
+}
+
+func TestLogControlSampling(t *testing.T) {
+	l, ctl, buf := newTestLogControl()
+	ctl.sampleInfo.Store(3)
+
+	for i := 0; i < 6; i++ {
+		l.Info("sampled")
+		l.Error("failure")
+	}
+
+	if got := strings.Count(buf.String(), "msg=sampled"); got != 2 {
+		t.Errorf("expected 2 of 6 info records with 1-in-3 sampling, got %d", got)
+	}
+	if got := strings.Count(buf.String(), "msg=failure"); got != 6 {
+		t.Errorf("expected all 6 error records, got %d", got)
+	}
+}
+
+func TestServerControlLogActions(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() {
+		ClearModuleLogLevel(LogModuleMCP)
+		SetLogSampling(0, 0)
+	})
+	tool := &ServerControlTool{server: srv}
+
+	if _, err := tool.Execute(map[string]interface{}{
+		"action":    "set_module_log_level",
+		"module":    LogModuleMCP,
+		"log_level": "DEBUG",
+	}); err != nil {
+		t.Fatalf("set_module_log_level failed: %v", err)
+	}
+	if _, err := tool.Execute(map[string]interface{}{
+		"action":       "set_log_sampling",
+		"sample_debug": float64(10),
+	}); err != nil {
+		t.Fatalf("set_log_sampling failed: %v", err)
+	}
+	if _, err := tool.Execute(map[string]interface{}{
+		"action":    "set_module_log_level",
+		"module":    LogModuleHTTP,
+		"log_level": "LOUD",
+	}); err == nil {
+		t.Error("expected error for invalid level")
+	}
+
+	result, err := tool.Execute(map[string]interface{}{"action": "get_status"})
+	if err != nil {
+		t.Fatalf("get_status failed: %v", err)
+	}
+	status := result.(map[string]interface{})
+	if levels := status["module_log_levels"].(map[string]string); levels[LogModuleMCP] != "DEBUG" {
+		t.Errorf("expected mcp module at DEBUG, got %v", levels)
+	}
+	if sampling := status["log_sampling"].(map[string]int); sampling["debug"] != 10 || sampling["info"] != 0 {
+		t.Errorf("unexpected sampling %v", sampling)
+	}
+}
+
+func TestWithLogConfigOptions(t *testing.T) {
+	t.Cleanup(func() {
+		ClearModuleLogLevel(LogModuleWS)
+		SetLogSampling(0, 0)
+	})
+	_, err := NewServer(
+		WithLogSampling(5, 2),
+		WithModuleLogLevel(LogModuleWS, slog.LevelError),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if debugEvery, infoEvery := LogSampling(); debugEvery != 5 || infoEvery != 2 {
+		t.Errorf("expected sampling 5/2, got %d/%d", debugEvery, infoEvery)
+	}
+	if level := ModuleLogLevels()[LogModuleWS]; level != slog.LevelError {
+		t.Errorf("expected ws module at ERROR, got %v", level)
+	}
+	if _, ok := DefaultLogger().Handler().(*logControlHandler); !ok {
+		t.Error("expected package logger to apply log controls")
+	}
+}
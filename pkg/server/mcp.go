@@ -6,16 +6,36 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // MCP Protocol constants
 const (
+	// MCPVersion is the protocol revision used with clients that do not request one.
 	MCPVersion = "2024-11-05"
+	// MCPLatestVersion is the newest protocol revision the handler implements.
+	MCPLatestVersion = "2025-06-18"
 )
 
+// mcpSupportedVersions lists the implemented protocol revisions, newest first.
+var mcpSupportedVersions = []string{MCPLatestVersion, "2025-03-26", MCPVersion}
+
+// negotiateMCPVersion answers an initialize request: the requested revision if it is
+// supported, otherwise the latest, which the client may reject by disconnecting.
+func negotiateMCPVersion(requested string) string {
+	if requested == "" {
+		return MCPVersion
+	}
+	if slices.Contains(mcpSupportedVersions, requested) {
+		return requested
+	}
+	return MCPLatestVersion
+}
+
 // MCPTransportType represents the type of transport for MCP communication
 type MCPTransportType int
 
@@ -45,6 +65,7 @@ type mcpTransportOptions struct {
 	endpoint          string
 	observabilityMode bool // If true, only register observability resources
 	developerMode     bool // If true, enable developer tools (NEVER in production!)
+	websocket         bool // If true, accept WebSocket upgrades on the endpoint
 }
 
 // MCPTool defines the interface for Model Context Protocol tools.
@@ -141,6 +162,10 @@ type MCPNamespace struct {
 	Name      string
 	Tools     []MCPTool
 	Resources []MCPResource
+
+	Description     string           // Shown in the discovery document
+	Tags            []string         // OpenAPI-style tags shown in the discovery document
+	DiscoveryPolicy *DiscoveryPolicy // Overrides the server's discovery policy when set
 }
 
 // MCPNamespaceConfig is a function that configures namespace options
@@ -160,20 +185,63 @@ func WithNamespaceResources(resources ...MCPResource) MCPNamespaceConfig {
 	}
 }
 
+// WithNamespaceDescription describes the namespace in the discovery document
+func WithNamespaceDescription(description string) MCPNamespaceConfig {
+	return func(ns *MCPNamespace) {
+		ns.Description = description
+	}
+}
+
+// WithNamespaceTags tags the namespace and its tools in the discovery document
+func WithNamespaceTags(tags ...string) MCPNamespaceConfig {
+	return func(ns *MCPNamespace) {
+		ns.Tags = append(ns.Tags, tags...)
+	}
+}
+
+// WithNamespaceDiscoveryPolicy sets the discovery policy for the namespace's tools and
+// resources, overriding the server's policy. DiscoveryNone hides the namespace entirely.
+func WithNamespaceDiscoveryPolicy(policy DiscoveryPolicy) MCPNamespaceConfig {
+	return func(ns *MCPNamespace) {
+		ns.DiscoveryPolicy = &policy
+	}
+}
+
 // MCPHandler manages MCP protocol communication with multiple namespace support
 type MCPHandler struct {
-	tools       map[string]MCPTool       // Flat map with prefixed keys: mcp__namespace__toolname
-	resources   map[string]MCPResource   // Flat map with prefixed keys: mcp__namespace__resourcename
-	namespaces  map[string]*MCPNamespace // Track registered namespaces
-	rpcEngine   *JSONRPCEngine
-	serverInfo  MCPServerInfo
-	logger      *slog.Logger
-	transport   MCPTransport
-	metrics     *MCPMetrics
-	cache       *resourceCache
-	sseManager  *SSEManager
-	sseRequests map[string]chan *JSONRPCRequest // Maps SSE client IDs to request channels
-	sseMutex    sync.RWMutex
+	tools        map[string]MCPTool     // Flat map with prefixed keys: mcp__namespace__toolname
+	resources    map[string]MCPResource // Flat map with prefixed keys: mcp__namespace__resourcename
+	templates    []*compiledResourceTemplate
+	registryMu   sync.RWMutex             // Guards tools, resources, and templates, which change at runtime
+	namespaces   map[string]*MCPNamespace // Track registered namespaces
+	rpcEngine    *JSONRPCEngine
+	serverInfo   MCPServerInfo
+	logger       *slog.Logger
+	transport    MCPTransport
+	metrics      *MCPMetrics
+	cache        *resourceCache
+	sseManager   *SSEManager
+	sseRequests  map[string]chan *JSONRPCRequest // Maps SSE client IDs to request channels
+	sseMutex     sync.RWMutex
+	sessions     *mcpSessionStore          // Streamable HTTP sessions
+	state        *mcpSessionStateStore     // Per-client state that tools keep between calls
+	wsUpgrader   *Upgrader                 // Accepts WebSocket clients, nil when disabled
+	tracer       atomic.Pointer[mcpTracer] // Mirrors traffic to a trace file, nil when disabled
+	auditor      *mcpAuditor               // Records tool calls and resource reads, nil when disabled
+	redactor     *redactor                 // Scrubs traces and audit entries, nil for the built-in patterns
+	reporter     ErrorReporter             // Receives tool failures, nil unless configured
+	toolPolicies *mcpToolPolicies          // Per-tool authorization and rate limits, nil when none are configured
+	toolLimiter  *mcpToolLimiter           // Tool concurrency limits, nil when none are configured
+	clientCalls  mcpClientCalls            // Sampling and elicitation requests awaiting the client's response
+
+	toolMiddleware   []ToolMiddleware // Wraps tool executions, first added runs outermost
+	toolMiddlewareMu sync.RWMutex
+
+	listenersMu sync.Mutex
+	listeners   map[mcpNotifier]struct{} // Connected clients that receive list_changed notifications
+
+	disabled  atomic.Bool          // Set by Disable; requests are rejected until Enable
+	suspended *mcpRegistrySnapshot // Registrations removed by Disable, guarded by registryMu
 }
 
 // httpTransport implements MCPTransport for HTTP-based communication
@@ -229,12 +297,16 @@ func NewMCPHandler(serverInfo MCPServerInfo) *MCPHandler {
 		namespaces:  make(map[string]*MCPNamespace),
 		rpcEngine:   NewJSONRPCEngine(),
 		serverInfo:  serverInfo,
-		logger:      logger,
+		logger:      logger.With(logModuleKey, LogModuleMCP),
 		metrics:     newMCPMetrics(),
 		cache:       newResourceCache(100), // Default cache size of 100 items
 		sseManager:  NewSSEManager(),
 		sseRequests: make(map[string]chan *JSONRPCRequest),
+		sessions:    newMCPSessionStore(),
+		state:       newMCPSessionStateStore(),
+		listeners:   make(map[mcpNotifier]struct{}),
 	}
+	handler.sessions.onClose = handler.state.remove
 
 	// Register MCP protocol methods
 	handler.registerMCPMethods()
@@ -254,29 +326,91 @@ func (h *MCPHandler) formatResourceName(namespace, resourceName string) string {
 
 // RegisterTool registers an MCP tool without namespace prefixing (for simplicity)
 func (h *MCPHandler) RegisterTool(tool MCPTool) {
+	h.registryMu.Lock()
 	h.tools[tool.Name()] = tool
+	h.registryMu.Unlock()
 	h.logger.Debug("MCP tool registered", "tool", tool.Name())
+	h.notifyToolsChanged()
 }
 
 // RegisterToolInNamespace registers an MCP tool in the specified namespace
-// This always applies namespace prefixing
-func (h *MCPHandler) RegisterToolInNamespace(tool MCPTool, namespace string) {
+// This always applies namespace prefixing. A tool already registered under the prefixed
+// name is kept and an error returned.
+func (h *MCPHandler) RegisterToolInNamespace(tool MCPTool, namespace string) error {
 	// Use server name as default namespace if empty
 	if namespace == "" {
 		namespace = h.serverInfo.Name
 	}
 
 	prefixedName := h.formatToolName(namespace, tool.Name())
-	h.tools[prefixedName] = tool
+	if err := h.addTool(prefixedName, tool, false); err != nil {
+		return err
+	}
 	h.logger.Debug("MCP tool registered in namespace", "tool", tool.Name(), "namespace", namespace, "prefixedName", prefixedName)
+	return nil
+}
+
+// addTool registers tool under name. Unless replace is set, an existing tool of that
+// name is kept and an error returned.
+func (h *MCPHandler) addTool(name string, tool MCPTool, replace bool) error {
+	h.registryMu.Lock()
+	if _, exists := h.tools[name]; exists && !replace {
+		h.registryMu.Unlock()
+		return fmt.Errorf("MCP tool %q is already registered", name)
+	}
+	h.tools[name] = tool
+	h.registryMu.Unlock()
+	h.notifyToolsChanged()
+	return nil
+}
+
+// UnregisterTool removes a tool by the name clients call it by, including the namespace
+// prefix, and reports whether it was registered. Connected clients are notified.
+func (h *MCPHandler) UnregisterTool(name string) bool {
+	h.registryMu.Lock()
+	_, exists := h.tools[name]
+	delete(h.tools, name)
+	h.registryMu.Unlock()
+	if !exists {
+		return false
+	}
+	h.logger.Debug("MCP tool unregistered", "tool", name)
+	h.notifyToolsChanged()
+	return true
 }
 
 // RegisterResource registers an MCP resource without namespace prefixing (for simplicity)
 func (h *MCPHandler) RegisterResource(resource MCPResource) {
+	h.registryMu.Lock()
 	h.resources[resource.URI()] = resource
+	h.registryMu.Unlock()
+	h.cache.delete(resource.URI())
 	h.logger.Debug("MCP resource registered", "resource", resource.Name(), "uri", resource.URI())
 }
 
+// UnregisterResource removes a resource by the URI clients read it by, including the
+// namespace prefix, and reports whether it was registered.
+func (h *MCPHandler) UnregisterResource(uri string) bool {
+	h.registryMu.Lock()
+	_, exists := h.resources[uri]
+	delete(h.resources, uri)
+	h.registryMu.Unlock()
+	if !exists {
+		return false
+	}
+	h.cache.delete(uri)
+	h.logger.Debug("MCP resource unregistered", "uri", uri)
+	return true
+}
+
+// hasResource reports whether a resource is registered under uri.
+func (h *MCPHandler) hasResource(uri string) bool {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
+	_, exists := h.resources[uri]
+	return exists
+}
+
 // RegisterResourceInNamespace registers an MCP resource in the specified namespace
 // This always applies namespace prefixing
 func (h *MCPHandler) RegisterResourceInNamespace(resource MCPResource, namespace string) {
@@ -286,7 +420,10 @@ func (h *MCPHandler) RegisterResourceInNamespace(resource MCPResource, namespace
 	}
 
 	prefixedURI := h.formatResourceName(namespace, resource.URI())
+	h.registryMu.Lock()
 	h.resources[prefixedURI] = resource
+	h.registryMu.Unlock()
+	h.cache.delete(prefixedURI)
 	h.logger.Debug("MCP resource registered in namespace", "resource", resource.Name(), "namespace", namespace, "uri", resource.URI(), "prefixedURI", prefixedURI)
 }
 
@@ -308,9 +445,9 @@ func (h *MCPHandler) RegisterNamespace(name string, configs ...MCPNamespaceConfi
 		config(ns)
 	}
 
-	// Register tools
+	// Register tools; a later tool of the same name replaces an earlier one
 	for _, tool := range ns.Tools {
-		h.RegisterToolInNamespace(tool, name)
+		h.addTool(h.formatToolName(name, tool.Name()), tool, true)
 	}
 
 	// Register resources
@@ -319,7 +456,9 @@ func (h *MCPHandler) RegisterNamespace(name string, configs ...MCPNamespaceConfi
 	}
 
 	// Store namespace
+	h.registryMu.Lock()
 	h.namespaces[name] = ns
+	h.registryMu.Unlock()
 
 	h.logger.Debug("MCP namespace registered", "namespace", name, "tools", len(ns.Tools), "resources", len(ns.Resources))
 	return nil
@@ -335,6 +474,8 @@ func (h *MCPHandler) GetMetrics() map[string]interface{} {
 
 // GetRegisteredTools returns a list of all registered tool names
 func (h *MCPHandler) GetRegisteredTools() []string {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
 	tools := make([]string, 0, len(h.tools))
 	for name := range h.tools {
 		tools = append(tools, name)
@@ -344,6 +485,8 @@ func (h *MCPHandler) GetRegisteredTools() []string {
 
 // GetRegisteredResources returns a list of all registered resource URIs
 func (h *MCPHandler) GetRegisteredResources() []string {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
 	resources := make([]string, 0, len(h.resources))
 	for uri := range h.resources {
 		resources = append(resources, uri)
@@ -353,6 +496,8 @@ func (h *MCPHandler) GetRegisteredResources() []string {
 
 // GetToolByName returns a tool by its name (for discovery filtering)
 func (h *MCPHandler) GetToolByName(name string) (MCPTool, bool) {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
 	tool, exists := h.tools[name]
 	return tool, exists
 }
@@ -365,7 +510,7 @@ func (h *MCPHandler) getCapabilities() MCPCapabilities {
 			ListChanged: false,
 		},
 		Tools: &ToolsCapability{
-			ListChanged: false,
+			ListChanged: true,
 		},
 		SSE: &SSECapability{
 			Enabled:       true,
@@ -375,9 +520,22 @@ func (h *MCPHandler) getCapabilities() MCPCapabilities {
 	}
 }
 
-// ProcessRequest processes an MCP request
+// ProcessRequest processes an encoded MCP request and returns the encoded response. The
+// request is counted, traced, and audited like those of the other transports.
 func (h *MCPHandler) ProcessRequest(requestData []byte) []byte {
-	return h.rpcEngine.ProcessRequest(requestData)
+	var request JSONRPCRequest
+	if err := json.Unmarshal(requestData, &request); err != nil {
+		responseData, _ := json.Marshal(createErrorResponse(ErrorCodeParseError, "Parse error", err.Error()))
+		return responseData
+	}
+	response := h.processRequest(context.Background(), "direct", &request)
+	responseData, err := json.Marshal(response)
+	if err != nil {
+		errorResponse := createErrorResponse(ErrorCodeInternalError, "Internal error", "failed to marshal response")
+		errorResponse.ID = response.ID
+		responseData, _ = json.Marshal(errorResponse)
+	}
+	return responseData
 }
 
 // isJSONAccepted checks if the Accept header indicates JSON is acceptable
@@ -417,6 +575,22 @@ func (h *MCPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.logger.Debug("MCP ServeHTTP called", "path", r.URL.Path, "method", r.Method)
 	}
 
+	if h.disabled.Load() {
+		h.writeDisabled(w)
+		return
+	}
+
+	if h.wsUpgrader != nil && isWebSocketRequest(r) {
+		h.ServeWebSocket(w, r)
+		return
+	}
+
+	// Streamable HTTP clients (2025-03-26 and later) are recognised by their headers
+	if isStreamableRequest(r) {
+		h.serveStreamable(w, r)
+		return
+	}
+
 	// Unified handler: Route based on Accept header
 	if r.Header.Get("Accept") == "text/event-stream" {
 		h.sseManager.HandleSSE(w, r, h)
@@ -554,8 +728,6 @@ func (h *MCPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // ProcessRequestWithTransport processes an MCP request using the provided transport
 func (h *MCPHandler) ProcessRequestWithTransport(transport MCPTransport) error {
-	start := time.Now()
-
 	// Receive request
 	request, err := transport.Receive()
 	if err != nil {
@@ -563,14 +735,14 @@ func (h *MCPHandler) ProcessRequestWithTransport(transport MCPTransport) error {
 	}
 
 	// Process with JSON-RPC engine directly (avoiding double marshaling)
-	response := h.rpcEngine.ProcessRequestDirect(request)
-
-	// Record metrics
-	var responseErr error
-	if response.Error != nil {
-		responseErr = fmt.Errorf("error: %s", response.Error.Message)
+	ctx := withMCPNotifier(context.Background(), transport)
+	switch t := transport.(type) {
+	case *httpTransport:
+		ctx = withMCPRequest(ctx, t.r)
+	case *stdioTransport:
+		ctx = h.withMCPSession(ctx, t.sessionID)
 	}
-	h.metrics.recordRequest(request.Method, time.Since(start), responseErr)
+	response := h.processRequest(ctx, transportName(transport), request)
 
 	// Send response
 	if err := transport.Send(response); err != nil {
@@ -580,19 +752,42 @@ func (h *MCPHandler) ProcessRequestWithTransport(transport MCPTransport) error {
 	return nil
 }
 
+// processRequest runs a request through the JSON-RPC engine, recording metrics and the trace.
+func (h *MCPHandler) processRequest(ctx context.Context, transport string, request *JSONRPCRequest) *JSONRPCResponse {
+	if h.disabled.Load() {
+		response := createErrorResponse(ErrorCodeMCPDisabled, "MCP is disabled", nil)
+		response.ID = request.ID
+		return response
+	}
+	start := time.Now()
+	response := h.rpcEngine.ProcessRequestContext(ctx, request)
+
+	var responseErr error
+	if response.Error != nil {
+		responseErr = fmt.Errorf("error: %s", response.Error.Message)
+	}
+	h.metrics.recordRequest(request.Method, time.Since(start), responseErr)
+	h.trace(transport, request, response, time.Since(start))
+	h.audit(ctx, transport, request, response, time.Since(start))
+	return response
+}
+
 // registerMCPMethods registers all MCP protocol methods with the JSON-RPC engine
 func (h *MCPHandler) registerMCPMethods() {
 	// Initialize methods
-	h.rpcEngine.RegisterMethod("initialize", h.handleInitialize)
+	h.rpcEngine.RegisterMethodWithContext("initialize", h.handleInitializeContext)
 	h.rpcEngine.RegisterMethod("initialized", h.handleInitialized)
+	h.rpcEngine.RegisterMethod("notifications/initialized", h.handleInitialized) // 2025-03-26 and later
+	h.rpcEngine.RegisterMethodWithContext("notifications/roots/list_changed", h.handleRootsListChanged)
 
 	// Resource methods
 	h.rpcEngine.RegisterMethod("resources/list", h.handleResourcesList)
-	h.rpcEngine.RegisterMethod("resources/read", h.handleResourcesRead)
+	h.rpcEngine.RegisterMethodWithContext("resources/read", h.handleResourcesReadContext)
+	h.rpcEngine.RegisterMethod("resources/templates/list", h.handleResourceTemplatesList)
 
 	// Tool methods
 	h.rpcEngine.RegisterMethod("tools/list", h.handleToolsList)
-	h.rpcEngine.RegisterMethod("tools/call", h.handleToolsCall)
+	h.rpcEngine.RegisterMethodWithContext("tools/call", h.handleToolsCallContext)
 
 	// Utility methods
 	h.rpcEngine.RegisterMethod("ping", h.handlePing)
@@ -614,13 +809,15 @@ type MCPInitializeResult struct {
 
 // MCPResourceReadParams represents the parameters for reading a resource
 type MCPResourceReadParams struct {
-	URI string `json:"uri"`
+	URI         string `json:"uri"`
+	IfNoneMatch string `json:"ifNoneMatch,omitempty"` // ETag from a previous read; unchanged contents are not sent again
 }
 
 // MCPToolCallParams represents the parameters for calling a tool
 type MCPToolCallParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
+	Meta      *MCPRequestMeta        `json:"_meta,omitempty"`
 }
 
 // MCPToolInfo represents information about a tool
@@ -638,11 +835,14 @@ type MCPResourceInfo struct {
 	MimeType    string `json:"mimeType"`
 }
 
-// MCPResourceContent represents the content of a resource
+// MCPResourceContent represents the content of a resource. A resource whose Read returns
+// []MCPResourceContent has them passed through as the contents of resources/read, e.g.
+// for binary or several contents.
 type MCPResourceContent struct {
 	URI      string      `json:"uri"`
 	MimeType string      `json:"mimeType"`
-	Text     interface{} `json:"text"`
+	Text     interface{} `json:"text,omitempty"`
+	Blob     string      `json:"blob,omitempty"` // Base64-encoded binary contents
 }
 
 // MCPToolResult represents the result of a tool execution
@@ -653,6 +853,10 @@ type MCPToolResult struct {
 // MCP method handlers
 
 func (h *MCPHandler) handleInitialize(params interface{}) (interface{}, error) {
+	return h.handleInitializeContext(context.Background(), params)
+}
+
+func (h *MCPHandler) handleInitializeContext(ctx context.Context, params interface{}) (interface{}, error) {
 	var initParams MCPInitializeParams
 
 	// Parse parameters
@@ -668,10 +872,13 @@ func (h *MCPHandler) handleInitialize(params interface{}) (interface{}, error) {
 	}
 
 	h.logger.Debug("MCP client initialized", "client", initParams.ClientInfo.Name, "version", initParams.ClientInfo.Version)
+	if session := MCPSessionFromContext(ctx); session != nil {
+		session.setRootsSupported(initializeDeclaresRoots(params))
+	}
 
 	// Return server capabilities
 	return map[string]interface{}{
-		"protocolVersion": MCPVersion,
+		"protocolVersion": negotiateMCPVersion(initParams.ProtocolVersion),
 		"capabilities":    h.getCapabilities(),
 		"serverInfo":      h.serverInfo,
 		"instructions":    "Follow the initialization protocol: after receiving this response, send an 'initialized' notification, then the server will send a 'ready' notification. For SSE support, connect to the SAME endpoint with 'Accept: text/event-stream' header.",
@@ -685,6 +892,8 @@ func (h *MCPHandler) handleInitialized(params interface{}) (interface{}, error)
 }
 
 func (h *MCPHandler) handleResourcesList(params interface{}) (interface{}, error) {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
 	resources := make([]map[string]interface{}, 0, len(h.resources))
 
 	for prefixedURI, resource := range h.resources {
@@ -702,6 +911,10 @@ func (h *MCPHandler) handleResourcesList(params interface{}) (interface{}, error
 }
 
 func (h *MCPHandler) handleResourcesRead(params interface{}) (interface{}, error) {
+	return h.handleResourcesReadContext(context.Background(), params)
+}
+
+func (h *MCPHandler) handleResourcesReadContext(ctx context.Context, params interface{}) (interface{}, error) {
 	start := time.Now()
 	var readParams MCPResourceReadParams
 
@@ -741,28 +954,30 @@ func (h *MCPHandler) handleResourcesRead(params interface{}) (interface{}, error
 		return nil, fmt.Errorf("uri parameter is required for resources/read method")
 	}
 
-	resource, exists := h.resources[readParams.URI]
+	resource, exists := h.findResource(readParams.URI)
 	if !exists {
 		return nil, fmt.Errorf("resource not found: %s", readParams.URI)
 	}
 
-	// Check cache first
+	// On the HTTP transports, the If-None-Match header stands in for the parameter
+	ifNoneMatch := readParams.IfNoneMatch
+	if r := mcpRequestFromContext(ctx); ifNoneMatch == "" && r != nil {
+		ifNoneMatch = r.Header.Get("If-None-Match")
+	}
+
+	// Check cache first, unless the resource opted out of caching
 	cacheKey := readParams.URI
+	cacheTTL := resourceCacheTTL(resource)
 	cacheHit := false
-	if cachedContent, hit := h.cache.get(cacheKey); hit {
-		cacheHit = true
-		h.metrics.recordResourceRead(readParams.URI, time.Since(start), nil, true)
-
-		// Return cached content
-		return map[string]interface{}{
-			"contents": []map[string]interface{}{
-				{
-					"uri":      resource.URI(),
-					"mimeType": resource.MimeType(),
-					"text":     cachedContent,
-				},
-			},
-		}, nil
+	if cacheTTL > 0 {
+		if cached, hit := h.cache.get(cacheKey); hit {
+			cacheHit = true
+			h.metrics.recordResourceRead(readParams.URI, time.Since(start), nil, true)
+
+			// Return cached content
+			content := cached.(resourceContent)
+			return resourceReadResult(resource, content, ifNoneMatch), nil
+		}
 	}
 
 	// Read from resource
@@ -775,6 +990,12 @@ func (h *MCPHandler) handleResourcesRead(params interface{}) (interface{}, error
 		return nil, fmt.Errorf("failed to read resource: %w", err)
 	}
 
+	// A resource returning contents, such as an upstream resource, passes them through
+	contents, passThrough := content.([]MCPResourceContent)
+	if passThrough && contents == nil {
+		contents = []MCPResourceContent{}
+	}
+
 	// Convert content to string if it's not already
 	var textContent string
 	switch v := content.(type) {
@@ -791,21 +1012,18 @@ func (h *MCPHandler) handleResourcesRead(params interface{}) (interface{}, error
 		textContent = string(jsonBytes)
 	}
 
-	// Cache the string result (with 5 minute TTL for now)
-	h.cache.set(cacheKey, textContent, 5*time.Minute)
+	// Cache the string result for the resource's TTL
+	result := resourceContent{text: textContent, etag: contentETag(textContent), contents: contents}
+	if cacheTTL > 0 {
+		h.cache.set(cacheKey, result, cacheTTL)
+	}
 
-	return map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"uri":      resource.URI(),
-				"mimeType": resource.MimeType(),
-				"text":     textContent,
-			},
-		},
-	}, nil
+	return resourceReadResult(resource, result, ifNoneMatch), nil
 }
 
 func (h *MCPHandler) handleToolsList(params interface{}) (interface{}, error) {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
 	tools := make([]map[string]interface{}, 0, len(h.tools))
 
 	for prefixedName, tool := range h.tools {
@@ -822,6 +1040,10 @@ func (h *MCPHandler) handleToolsList(params interface{}) (interface{}, error) {
 }
 
 func (h *MCPHandler) handleToolsCall(params interface{}) (interface{}, error) {
+	return h.handleToolsCallContext(context.Background(), params)
+}
+
+func (h *MCPHandler) handleToolsCallContext(reqCtx context.Context, params interface{}) (interface{}, error) {
 	start := time.Now()
 	var callParams MCPToolCallParams
 
@@ -837,28 +1059,56 @@ func (h *MCPHandler) handleToolsCall(params interface{}) (interface{}, error) {
 		}
 	}
 
-	tool, exists := h.tools[callParams.Name]
+	tool, exists := h.GetToolByName(callParams.Name)
 	if !exists {
 		return nil, fmt.Errorf("tool not found: %s", callParams.Name)
 	}
 
-	// Wrap tool to support context if needed
-	ctxTool := wrapToolWithContext(tool)
+	if err := h.toolPolicies.authorize(reqCtx, callParams.Name); err != nil {
+		return nil, err
+	}
+
+	// Reject arguments that do not match the schema before the tool sees them
+	if err := validateToolArguments(tool, callParams.Arguments); err != nil {
+		return nil, err
+	}
+
+	// Wait for a slot under the concurrency limits; queueing does not count against the timeout
+	release, err := h.toolLimiter.acquire(reqCtx, callParams.Name)
+	if err != nil {
+		if result, ok := h.toolLimiter.busyResult(err); ok {
+			return result, nil
+		}
+		return nil, err
+	}
+	defer release()
 
 	// Create context with timeout (default 30 seconds)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(reqCtx, 30*time.Second)
 	defer cancel()
 
-	// Execute tool with context
-	result, err := ctxTool.ExecuteWithContext(ctx, callParams.Arguments)
+	// Execute tool through the middleware chain
+	call := &ToolCall{
+		Name:      callParams.Name,
+		Arguments: callParams.Arguments,
+		Tool:      tool,
+		Request:   mcpRequestFromContext(reqCtx),
+	}
+	result, err := h.toolExecutor(reqCtx, callParams.Meta)(ctx, call)
 
 	// Record metrics
 	h.metrics.recordToolExecution(callParams.Name, time.Since(start), err)
 
 	if err != nil {
+		h.reportToolError(ctx, callParams.Name, err)
 		return nil, fmt.Errorf("tool execution failed: %w", err)
 	}
 
+	// Typed content blocks such as images and resource links are sent as they are
+	if response, ok := toolContentResponse(result); ok {
+		return response, nil
+	}
+
 	// Handle different response types
 	var content []map[string]interface{}
 
@@ -1060,6 +1310,22 @@ func newResourceCache(maxSize int) *resourceCache {
 	}
 }
 
+// clear drops all cached values
+func (c *resourceCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = make(map[string]*cacheEntry)
+}
+
+// delete removes a cached value and reports whether it existed
+func (c *resourceCache) delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, exists := c.data[key]
+	delete(c.data, key)
+	return exists
+}
+
 // get retrieves a value from the cache if it exists and hasn't expired
 func (c *resourceCache) get(key string) (interface{}, bool) {
 	c.mu.RLock()
@@ -1505,6 +1771,7 @@ type SimpleResource struct {
 	MimeTypeFunc    func() string
 	ReadFunc        func() (interface{}, error)
 	ListFunc        func() ([]string, error)
+	CacheTTLFunc    func() time.Duration // nil uses DefaultResourceCacheTTL
 }
 
 func (r *SimpleResource) URI() string {
@@ -1549,6 +1816,13 @@ func (r *SimpleResource) List() ([]string, error) {
 	return []string{r.URI()}, nil
 }
 
+func (r *SimpleResource) CacheTTL() time.Duration {
+	if r.CacheTTLFunc != nil {
+		return r.CacheTTLFunc()
+	}
+	return DefaultResourceCacheTTL
+}
+
 // ToolBuilder provides a fluent API for building tools
 type ToolBuilder struct {
 	name        string
@@ -1611,6 +1885,7 @@ type ResourceBuilder struct {
 	description string
 	mimeType    string
 	readFunc    func() (interface{}, error)
+	cacheTTL    func() time.Duration
 }
 
 // NewResource creates a new resource builder
@@ -1641,6 +1916,12 @@ func (b *ResourceBuilder) WithRead(fn func() (interface{}, error)) *ResourceBuil
 	return b
 }
 
+// WithCacheTTL sets how long reads are cached; zero disables caching.
+func (b *ResourceBuilder) WithCacheTTL(ttl time.Duration) *ResourceBuilder {
+	b.cacheTTL = func() time.Duration { return ttl }
+	return b
+}
+
 func (b *ResourceBuilder) Build() MCPResource {
 	return &SimpleResource{
 		URIFunc:         func() string { return b.uri },
@@ -1649,6 +1930,7 @@ func (b *ResourceBuilder) Build() MCPResource {
 		MimeTypeFunc:    func() string { return b.mimeType },
 		ReadFunc:        b.readFunc,
 		ListFunc:        func() ([]string, error) { return []string{b.uri}, nil },
+		CacheTTLFunc:    b.cacheTTL,
 	}
 }
 
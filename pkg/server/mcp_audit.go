@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultMCPAuditBufferSize is the number of entries audit://mcp/recent keeps by default.
+const defaultMCPAuditBufferSize = 500
+
+// Outcomes of an audited MCP call.
+const (
+	MCPAuditOK        = "ok"         // The call succeeded
+	MCPAuditToolError = "tool_error" // The tool returned a result flagged isError
+	MCPAuditError     = "error"      // The call failed with a JSON-RPC error
+)
+
+// MCPAuditConfig configures the audit trail of MCP tool calls and resource reads.
+type MCPAuditConfig struct {
+	BufferSize int                 `json:"buffer_size,omitempty"` // Entries kept for audit://mcp/recent (default 500)
+	Log        *RotatingFileConfig `json:"log,omitempty"`         // Also append entries to an NDJSON audit log
+	Sink       func(MCPAuditEntry) `json:"-"`                     // Also forward entries, e.g. to a SIEM; called synchronously
+}
+
+// MCPAuditEntry records one tools/call or resources/read. Arguments are not stored,
+// only their hash, so the trail can be kept without retaining the data sent to tools.
+type MCPAuditEntry struct {
+	Time          time.Time      `json:"time"`
+	Method        string         `json:"method"` // "tools/call" or "resources/read"
+	Target        string         `json:"target"` // Tool name or resource URI
+	Caller        MCPAuditCaller `json:"caller"`
+	Transport     string         `json:"transport"`
+	ArgumentsHash string         `json:"arguments_hash,omitempty"` // SHA-256 of the JSON-encoded tool arguments
+	Duration      time.Duration  `json:"duration"`
+	Outcome       string         `json:"outcome"` // MCPAuditOK, MCPAuditToolError, or MCPAuditError
+	Error         string         `json:"error,omitempty"`
+}
+
+// MCPAuditCaller identifies who made an audited call, as far as the transport allows.
+type MCPAuditCaller struct {
+	Subject  string `json:"subject,omitempty"`   // Principal of the access token, see WithMCPPrincipal
+	Session  string `json:"session,omitempty"`   // MCP session or connection ID
+	RemoteIP string `json:"remote_ip,omitempty"` // Client IP for HTTP transports
+}
+
+// WithMCPAudit records every MCP tool call and resource read, with the caller, a hash of
+// the arguments, the duration, and the outcome. Recent entries are exposed as the
+// audit://mcp/recent resource; cfg.Log and cfg.Sink forward all entries for long-term
+// retention. Requires MCP support. The audit trail can also be configured via the
+// "mcp_audit" key in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("MyApp", "1.0.0"),
+//		server.WithMCPAudit(server.MCPAuditConfig{
+//			Log: &server.RotatingFileConfig{Path: "logs/mcp-audit.ndjson"},
+//		}),
+//	)
+func WithMCPAudit(cfg MCPAuditConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if cfg.BufferSize < 0 {
+			return fmt.Errorf("MCP audit buffer size must not be negative")
+		}
+		srv.Options.MCPAudit = &cfg
+		return nil
+	}
+}
+
+// mcpAuditor keeps recent audit entries in a ring buffer and forwards all of them.
+type mcpAuditor struct {
+	principal MCPPrincipalFunc // Identifies the caller of HTTP requests, nil to skip
+	file      *RotatingFile    // nil without an audit log
+	sink      func(MCPAuditEntry)
+
+	mu      sync.RWMutex
+	entries []MCPAuditEntry
+	next    int // Index of the oldest entry once the buffer is full
+	full    bool
+}
+
+func newMCPAuditor(cfg MCPAuditConfig, principal MCPPrincipalFunc) (*mcpAuditor, error) {
+	size := cfg.BufferSize
+	if size <= 0 {
+		size = defaultMCPAuditBufferSize
+	}
+	a := &mcpAuditor{
+		principal: principal,
+		sink:      cfg.Sink,
+		entries:   make([]MCPAuditEntry, 0, size),
+	}
+	if cfg.Log != nil {
+		file, err := NewRotatingFile(*cfg.Log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open MCP audit log: %w", err)
+		}
+		a.file = file
+	}
+	return a, nil
+}
+
+// record stores an entry and forwards it to the audit log and sink.
+func (a *mcpAuditor) record(entry MCPAuditEntry) {
+	a.mu.Lock()
+	if len(a.entries) < cap(a.entries) {
+		a.entries = append(a.entries, entry)
+	} else {
+		a.entries[a.next] = entry
+		a.next = (a.next + 1) % len(a.entries)
+		a.full = true
+	}
+	a.mu.Unlock()
+
+	if a.file != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, err = a.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			logger.Error("Failed to write MCP audit entry", "error", err)
+		}
+	}
+	if a.sink != nil {
+		a.sink(entry)
+	}
+}
+
+// recent returns the buffered entries, oldest first.
+func (a *mcpAuditor) recent() []MCPAuditEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	entries := make([]MCPAuditEntry, 0, len(a.entries))
+	entries = append(entries, a.entries[a.next:]...)
+	return append(entries, a.entries[:a.next]...)
+}
+
+func (a *mcpAuditor) close() error {
+	if a.file == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+// audit records a processed tools/call or resources/read if the audit trail is enabled.
+func (h *MCPHandler) audit(ctx context.Context, transport string, request *JSONRPCRequest, response *JSONRPCResponse, duration time.Duration) {
+	a := h.auditor
+	if a == nil || request == nil || (request.Method != "tools/call" && request.Method != "resources/read") {
+		return
+	}
+	params, _ := request.Params.(map[string]interface{})
+	entry := MCPAuditEntry{
+		Time:      time.Now().UTC(),
+		Method:    request.Method,
+		Caller:    a.caller(ctx),
+		Transport: transport,
+		Duration:  duration,
+		Outcome:   MCPAuditOK,
+	}
+	if request.Method == "tools/call" {
+		entry.Target, _ = params["name"].(string)
+		entry.ArgumentsHash = hashAuditArguments(params["arguments"])
+	} else {
+		uri, _ := params["uri"].(string)
+		entry.Target = h.redactor.url(uri) // Resource URIs can carry credentials in their query
+	}
+	switch result, _ := response.Result.(map[string]interface{}); {
+	case response.Error != nil:
+		entry.Outcome, entry.Error = MCPAuditError, response.Error.Message
+	case result["isError"] == true:
+		entry.Outcome = MCPAuditToolError
+	}
+	a.record(entry)
+}
+
+// caller identifies the client of the request being served in ctx.
+func (a *mcpAuditor) caller(ctx context.Context) MCPAuditCaller {
+	var caller MCPAuditCaller
+	if ref, ok := ctx.Value(mcpSessionKey).(mcpSessionRef); ok {
+		caller.Session = ref.id
+	}
+	if r := mcpRequestFromContext(ctx); r != nil {
+		caller.RemoteIP = clientIP(r)
+		if a.principal != nil {
+			if principal, err := a.principal(r); err == nil && principal != nil {
+				caller.Subject = principal.Subject
+			}
+		}
+	}
+	return caller
+}
+
+// hashAuditArguments returns the SHA-256 of the JSON-encoded arguments, whose map keys
+// are sorted, so equal arguments hash equally.
+func hashAuditArguments(args interface{}) string {
+	if args == nil {
+		return ""
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// EnableAudit starts recording tool calls and resource reads and registers the
+// audit://mcp/recent resource. Call it before serving clients.
+func (h *MCPHandler) EnableAudit(cfg MCPAuditConfig) error {
+	return h.enableAudit(cfg, nil)
+}
+
+func (h *MCPHandler) enableAudit(cfg MCPAuditConfig, principal MCPPrincipalFunc) error {
+	a, err := newMCPAuditor(cfg, principal)
+	if err != nil {
+		return err
+	}
+	h.auditor = a
+	h.RegisterResource(&MCPAuditResource{auditor: a})
+	return nil
+}
+
+// AuditEntries returns the recent audit entries, oldest first, or nil if the audit trail
+// is not enabled.
+func (h *MCPHandler) AuditEntries() []MCPAuditEntry {
+	if h.auditor == nil {
+		return nil
+	}
+	return h.auditor.recent()
+}
+
+// MCPAuditResource exposes the recent audit entries as audit://mcp/recent.
+type MCPAuditResource struct {
+	auditor *mcpAuditor
+}
+
+// URI returns the resource URI.
+func (r *MCPAuditResource) URI() string {
+	return "audit://mcp/recent"
+}
+
+// CacheTTL disables caching, since every call adds an entry.
+func (r *MCPAuditResource) CacheTTL() time.Duration {
+	return 0
+}
+
+// Name returns the resource name.
+func (r *MCPAuditResource) Name() string {
+	return "MCP Audit Trail"
+}
+
+// Description returns the resource description.
+func (r *MCPAuditResource) Description() string {
+	return fmt.Sprintf("Recent MCP tool calls and resource reads (last %d entries)", cap(r.auditor.entries))
+}
+
+// MimeType returns the resource MIME type.
+func (r *MCPAuditResource) MimeType() string {
+	return "application/json"
+}
+
+// Read returns the recent audit entries, oldest first.
+func (r *MCPAuditResource) Read() (interface{}, error) {
+	r.auditor.mu.RLock()
+	truncated := r.auditor.full
+	r.auditor.mu.RUnlock()
+	entries := r.auditor.recent()
+
+	jsonBytes, err := json.Marshal(map[string]interface{}{
+		"entries":   entries,
+		"count":     len(entries),
+		"max_size":  cap(r.auditor.entries),
+		"truncated": truncated,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit entries: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// List returns the available resource URIs.
+func (r *MCPAuditResource) List() ([]string, error) {
+	return []string{r.URI()}, nil
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func postMCPMessage(t *testing.T, srv *Server, body string) *JSONRPCResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", "alice")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	var resp JSONRPCResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+	}
+	return &resp
+}
+
+func TestMCPAuditTrail(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.ndjson")
+	var forwarded []MCPAuditEntry
+	srv, err := NewServer(
+		WithMCPSupport("test", "1.0.0"),
+		WithMCPPrincipal(func(r *http.Request) (*MCPPrincipal, error) {
+			return &MCPPrincipal{Subject: r.Header.Get("X-User")}, nil
+		}),
+		WithMCPAudit(MCPAuditConfig{
+			Log:  &RotatingFileConfig{Path: logPath},
+			Sink: func(e MCPAuditEntry) { forwarded = append(forwarded, e) },
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.mcpHandler.auditor.close()
+	if err := srv.RegisterMCPTool(validationTool()); err != nil {
+		t.Fatal(err)
+	}
+
+	postMCPMessage(t, srv, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"deploy","arguments":{"service":"api","replicas":2}}}`)
+	postMCPMessage(t, srv, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"missing"}}`)
+	postMCPMessage(t, srv, `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+
+	entries := srv.mcpHandler.AuditEntries()
+	if len(entries) != 2 || len(forwarded) != 2 {
+		t.Fatalf("expected 2 audited calls, got %d buffered and %d forwarded", len(entries), len(forwarded))
+	}
+	first := entries[0]
+	if first.Target != "deploy" || first.Outcome != MCPAuditOK || first.Caller.Subject != "alice" || first.Caller.RemoteIP == "" {
+		t.Errorf("unexpected entry %+v", first)
+	}
+	if want := hashAuditArguments(map[string]interface{}{"replicas": 2, "service": "api"}); first.ArgumentsHash != want {
+		t.Errorf("expected arguments hash %s, got %s", want, first.ArgumentsHash)
+	}
+	if entries[1].Target != "missing" || entries[1].Outcome != MCPAuditError || entries[1].Error == "" {
+		t.Errorf("unexpected entry for a failed call %+v", entries[1])
+	}
+
+	// The resource read is itself audited after it is served
+	resp := postMCPMessage(t, srv, `{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"audit://mcp/recent"}}`)
+	if resp.Error != nil || !strings.Contains(toJSON(t, resp.Result), `\"target\":\"deploy\"`) {
+		t.Errorf("unexpected audit resource %+v", resp)
+	}
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); lines++ {
+		var entry MCPAuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit log line %q: %v", scanner.Text(), err)
+		}
+	}
+	if lines != 3 {
+		t.Errorf("expected 3 audit log lines, got %d", lines)
+	}
+}
+
+func TestMCPAuditDirectRequests(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"), WithMCPAudit(MCPAuditConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterMCPTool(validationTool()); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mcpHandler.ProcessRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"deploy","arguments":{"service":"api","replicas":2}}}`))
+	if entries := srv.mcpHandler.AuditEntries(); len(entries) != 1 || entries[0].Transport != "direct" {
+		t.Errorf("expected the direct call to be audited, got %+v", entries)
+	}
+	if total := srv.mcpHandler.metrics.GetMetricsSummary()["total_requests"]; total != int64(1) {
+		t.Errorf("expected the direct call to be counted, got %v", total)
+	}
+}
+
+func TestMCPAuditRingBuffer(t *testing.T) {
+	a, err := newMCPAuditor(MCPAuditConfig{BufferSize: 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"a", "b", "c"} {
+		a.record(MCPAuditEntry{Target: target})
+	}
+	entries := a.recent()
+	if len(entries) != 2 || entries[0].Target != "b" || entries[1].Target != "c" {
+		t.Errorf("expected the two newest entries oldest first, got %+v", entries)
+	}
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
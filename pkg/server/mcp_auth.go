@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// protectedResourceMetadataPath is the well-known path of OAuth 2.0 protected resource
+// metadata (RFC 9728).
+const protectedResourceMetadataPath = "/.well-known/oauth-protected-resource"
+
+// MCPAuthorizationConfig protects the MCP endpoint as an OAuth 2.1 resource server, as
+// described by the MCP authorization specification. Clients discover the authorization
+// servers from the protected resource metadata and send access tokens as bearer tokens.
+//
+// Tokens are checked with the server's AuthTokenValidatorFunc, typically set up by
+// WithIntrospection. Set IntrospectionConfig.Audience to the resource URI so that tokens
+// issued for other services are rejected.
+type MCPAuthorizationConfig struct {
+	AuthorizationServers []string `json:"authorization_servers"`      // Issuer URLs of the authorization servers
+	Resource             string   `json:"resource,omitempty"`         // Canonical URI of the MCP endpoint (default derived from the request)
+	ScopesSupported      []string `json:"scopes_supported,omitempty"` // Scopes advertised to clients
+	ResourceName         string   `json:"resource_name,omitempty"`    // Human-readable name shown by clients
+}
+
+func (cfg *MCPAuthorizationConfig) validate() error {
+	if len(cfg.AuthorizationServers) == 0 {
+		return errors.New("MCP authorization requires at least one authorization server")
+	}
+	urls := cfg.AuthorizationServers
+	if cfg.Resource != "" {
+		urls = append(urls[:len(urls):len(urls)], cfg.Resource)
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") || u.Fragment != "" {
+			return fmt.Errorf("invalid MCP authorization URL %q", raw)
+		}
+	}
+	return nil
+}
+
+// protectedResourceMetadata is the RFC 9728 metadata document of the MCP endpoint.
+type protectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+}
+
+// WithMCPAuthorization requires OAuth access tokens on the MCP endpoint and publishes the
+// protected resource metadata at /.well-known/oauth-protected-resource, so that remote MCP
+// clients can run the authorization flow against your identity provider. Unauthenticated
+// requests are answered with 401 and a WWW-Authenticate header pointing to the metadata.
+// Authorization can also be configured via the "mcp_authorization" key in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("MyApp", "1.0.0"),
+//		server.WithMCPAuthorization(server.MCPAuthorizationConfig{
+//			AuthorizationServers: []string{"https://auth.example.com"},
+//			ScopesSupported:      []string{"mcp:tools"},
+//		}),
+//		server.WithIntrospection(server.IntrospectionConfig{
+//			Endpoint: "https://auth.example.com/oauth2/introspect",
+//			Audience: "https://api.example.com/mcp",
+//		}),
+//	)
+func WithMCPAuthorization(cfg MCPAuthorizationConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		srv.Options.MCPAuthorization = &cfg
+		return nil
+	}
+}
+
+// baseURL returns the scheme and host the request was addressed to.
+func (srv *Server) baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	host := r.Host
+	if host == "" {
+		host = "localhost" + srv.Options.Addr
+	}
+	return scheme + "://" + host
+}
+
+// mcpResource returns the canonical URI of the MCP endpoint.
+func (srv *Server) mcpResource(r *http.Request) string {
+	if resource := srv.Options.MCPAuthorization.Resource; resource != "" {
+		return resource
+	}
+	return srv.baseURL(r) + srv.Options.MCPEndpoint
+}
+
+// setupProtectedResourceMetadata serves the metadata at the well-known path, both with the
+// MCP endpoint path appended as RFC 9728 specifies and at the root for older clients.
+func (srv *Server) setupProtectedResourceMetadata() {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		cfg := srv.Options.MCPAuthorization
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		if err := json.NewEncoder(w).Encode(protectedResourceMetadata{
+			Resource:               srv.mcpResource(r),
+			AuthorizationServers:   cfg.AuthorizationServers,
+			ScopesSupported:        cfg.ScopesSupported,
+			BearerMethodsSupported: []string{"header"},
+			ResourceName:           cfg.ResourceName,
+		}); err != nil {
+			logger.Error("Failed to encode protected resource metadata", "error", err)
+		}
+	}
+	for _, path := range []string{protectedResourceMetadataPath + srv.Options.MCPEndpoint, protectedResourceMetadataPath} {
+		srv.registerRoute(path)
+		srv.mux.HandleFunc(path, handler)
+	}
+}
+
+// mcpAuthorization rejects MCP requests without a valid access token.
+func (srv *Server) mcpAuthorization(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challenge := func(errorCode string) {
+			value := fmt.Sprintf(`Bearer resource_metadata="%s%s%s"`, srv.baseURL(r), protectedResourceMetadataPath, srv.Options.MCPEndpoint)
+			if errorCode != "" {
+				value += fmt.Sprintf(`, error="%s"`, errorCode)
+			}
+			if scopes := srv.Options.MCPAuthorization.ScopesSupported; len(scopes) > 0 {
+				value += fmt.Sprintf(`, scope="%s"`, strings.Join(scopes, " "))
+			}
+			w.Header().Set("WWW-Authenticate", value)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get(authorizationHeader), bearerTokenPrefix)
+		if !ok || token == "" {
+			challenge("")
+			return
+		}
+
+		if srv.Options.AuthTokenValidatorFunc == nil {
+			http.Error(w, "Internal Server Error: Auth not configured", http.StatusInternalServerError)
+			return
+		}
+		var valid bool
+		var err error
+		subtle.WithDataIndependentTiming(func() {
+			valid, err = srv.Options.AuthTokenValidatorFunc(token)
+		})
+		if errors.Is(err, ErrIntrospectionUnavailable) {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+			return
+		}
+		if err != nil {
+			logger.Error("error validating MCP access token", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			challenge("invalid_token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionIDKey, token)))
+	})
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAuthorizedMCPServer(t *testing.T) *Server {
+	t.Helper()
+	srv, err := NewServer(
+		WithMCPSupport("test", "1.0.0"),
+		WithMCPAuthorization(MCPAuthorizationConfig{
+			AuthorizationServers: []string{"https://auth.example.com"},
+			ScopesSupported:      []string{"mcp:tools"},
+		}),
+		WithAuthTokenValidator(func(token string) (bool, error) { return token == "valid", nil }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestMCPAuthorizationChallenge(t *testing.T) {
+	srv := newAuthorizedMCPServer(t)
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+	tests := []struct {
+		name      string
+		token     string
+		status    int
+		challenge string
+	}{
+		{"missing token", "", http.StatusUnauthorized, `Bearer resource_metadata="http://example.com/.well-known/oauth-protected-resource/mcp", scope="mcp:tools"`},
+		{"invalid token", "expired", http.StatusUnauthorized, `Bearer resource_metadata="http://example.com/.well-known/oauth-protected-resource/mcp", error="invalid_token", scope="mcp:tools"`},
+		{"valid token", "valid", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/mcp", strings.NewReader(ping))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.challenge)
+			}
+		})
+	}
+}
+
+func TestMCPAuthorizationMetadata(t *testing.T) {
+	srv := newAuthorizedMCPServer(t)
+
+	for _, path := range []string{"/.well-known/oauth-protected-resource/mcp", "/.well-known/oauth-protected-resource"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com"+path, nil)
+		srv.mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rec.Code)
+		}
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+			t.Fatal(err)
+		}
+		if metadata["resource"] != "https://api.example.com/mcp" {
+			t.Errorf("%s: resource = %v", path, metadata["resource"])
+		}
+		if servers, _ := metadata["authorization_servers"].([]interface{}); len(servers) != 1 || servers[0] != "https://auth.example.com" {
+			t.Errorf("%s: authorization_servers = %v", path, metadata["authorization_servers"])
+		}
+	}
+}
+
+func TestMCPAuthorizationConfigValidation(t *testing.T) {
+	invalid := []MCPAuthorizationConfig{
+		{},
+		{AuthorizationServers: []string{"auth.example.com"}},
+		{AuthorizationServers: []string{"https://auth.example.com"}, Resource: "https://api.example.com/mcp#tools"},
+	}
+	for _, cfg := range invalid {
+		if _, err := NewServer(WithMCPSupport("test", "1.0.0"), WithMCPAuthorization(cfg)); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
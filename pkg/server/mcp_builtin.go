@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -48,6 +49,10 @@ import (
 //
 // ⚠️  SECURITY WARNING: Only use in development environments!
 // Enables powerful tools that can restart your server and modify its behavior.
+// NewServer refuses developer mode when the server looks like production (hardened or
+// FIPS mode, an Addr that is not loopback including wildcard binds such as the default
+// ":8080", APP_ENV=production) unless HS_MCP_DEV_I_UNDERSTAND=true is set, and the tools
+// only answer unforwarded loopback clients unless WithMCPDevAllowRemote is set.
 //
 // Tools provided:
 //   - mcp__hyperserve__server_control: Restart server, reload config, change log levels, get status
@@ -81,7 +86,7 @@ func (t *ServerControlTool) Name() string {
 }
 
 func (t *ServerControlTool) Description() string {
-	return "Control HyperServe server lifecycle and configuration. Actions: get_status (check server health), set_log_level (DEBUG/INFO/WARN/ERROR), reload (refresh config), restart (graceful restart)"
+	return "Control HyperServe server lifecycle and configuration. Actions: get_status (check server health), set_log_level (DEBUG/INFO/WARN/ERROR), set_module_log_level (per-module level for mcp/http/ws), set_log_sampling (keep 1 in N debug/info records), reload (refresh config), restart (graceful restart)"
 }
 
 func (t *ServerControlTool) Schema() map[string]interface{} {
@@ -90,13 +95,27 @@ func (t *ServerControlTool) Schema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"restart", "reload", "set_log_level", "get_status"},
-				"description": "Action to perform: get_status (check server health), set_log_level (change logging verbosity), reload (refresh configuration without restart), restart (graceful server restart)",
+				"enum":        []string{"restart", "reload", "set_log_level", "set_module_log_level", "set_log_sampling", "get_status"},
+				"description": "Action to perform: get_status (check server health), set_log_level (change logging verbosity), set_module_log_level (override the level of one module), set_log_sampling (sample debug and info records), reload (refresh configuration without restart), restart (graceful server restart)",
 			},
 			"log_level": map[string]interface{}{
 				"type":        "string",
 				"enum":        []string{"DEBUG", "INFO", "WARN", "ERROR"},
-				"description": "New log level for set_log_level action. DEBUG shows all logs, INFO shows informational and above, WARN shows warnings and errors, ERROR shows only errors",
+				"description": "New log level for set_log_level and set_module_log_level actions. DEBUG shows all logs, INFO shows informational and above, WARN shows warnings and errors, ERROR shows only errors. For set_module_log_level, omit to remove the module override",
+			},
+			"module": map[string]interface{}{
+				"type":        "string",
+				"description": "Module for set_module_log_level: mcp (MCP protocol), http (request logs), ws (WebSocket), or an application module",
+			},
+			"sample_debug": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     0,
+				"description": "For set_log_sampling: keep 1 in N debug records (0 or 1 keeps all)",
+			},
+			"sample_info": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     0,
+				"description": "For set_log_sampling: keep 1 in N info records (0 or 1 keeps all). Warnings and errors are never sampled",
 			},
 		},
 		"required": []string{"action"},
@@ -123,16 +142,18 @@ func (t *ServerControlTool) Execute(params map[string]interface{}) (interface{},
 		}, nil
 
 	case "reload":
-		// Reload configuration, templates, etc. without full restart
+		// Reload configuration without full restart
 		logger.Info("Configuration reload requested via MCP developer tools")
-		// Here you would implement actual reload logic:
-		// - Reload templates
-		// - Refresh static file cache
-		// - Re-read configuration files
+		event, err := t.server.ReloadConfig()
+		if err != nil {
+			return nil, fmt.Errorf("configuration reload failed: %w", err)
+		}
 		return map[string]interface{}{
-			"status":    "reloaded",
-			"timestamp": time.Now().Format(time.RFC3339),
-			"message":   "Configuration and templates reloaded",
+			"status":           "reloaded",
+			"timestamp":        event.Time.Format(time.RFC3339),
+			"message":          "Configuration reloaded",
+			"applied":          event.Applied,
+			"requires_restart": event.RequiresRestart,
 		}, nil
 
 	case "set_log_level":
@@ -153,19 +174,68 @@ func (t *ServerControlTool) Execute(params map[string]interface{}) (interface{},
 		default:
 			return nil, fmt.Errorf("invalid log level: %s", level)
 		}
-		t.server.Options.LogLevel = level
+		t.server.updateOptions(func(o *ServerOptions) { o.LogLevel = level })
 		return map[string]interface{}{
 			"status":    "log_level_changed",
 			"new_level": level,
 		}, nil
 
+	case "set_module_log_level":
+		module, _ := params["module"].(string)
+		if module == "" {
+			return nil, fmt.Errorf("module is required for set_module_log_level action")
+		}
+		name, _ := params["log_level"].(string)
+		if name == "" {
+			ClearModuleLogLevel(module)
+			return map[string]interface{}{
+				"status": "module_log_level_cleared",
+				"module": module,
+			}, nil
+		}
+		level, err := parseLogLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		SetModuleLogLevel(module, level)
+		return map[string]interface{}{
+			"status":    "module_log_level_changed",
+			"module":    module,
+			"new_level": level.String(),
+		}, nil
+
+	case "set_log_sampling":
+		debugEvery, infoEvery := LogSampling()
+		if v, ok := params["sample_debug"].(float64); ok {
+			debugEvery = int(v)
+		}
+		if v, ok := params["sample_info"].(float64); ok {
+			infoEvery = int(v)
+		}
+		if debugEvery < 0 || infoEvery < 0 {
+			return nil, fmt.Errorf("sampling rates must not be negative")
+		}
+		SetLogSampling(debugEvery, infoEvery)
+		return map[string]interface{}{
+			"status":       "log_sampling_changed",
+			"sample_debug": debugEvery,
+			"sample_info":  infoEvery,
+		}, nil
+
 	case "get_status":
+		moduleLevels := make(map[string]string)
+		for module, level := range ModuleLogLevels() {
+			moduleLevels[module] = level.String()
+		}
+		debugEvery, infoEvery := LogSampling()
 		return map[string]interface{}{
-			"running":   t.server.isRunning.Load(),
-			"ready":     t.server.isReady.Load(),
-			"uptime":    time.Since(t.server.serverStart).String(),
-			"log_level": t.server.Options.LogLevel,
-			"addr":      t.server.Options.Addr,
+			"running":           t.server.isRunning.Load(),
+			"ready":             t.server.isReady.Load(),
+			"uptime":            time.Since(t.server.serverStart).String(),
+			"log_level":         t.server.Options.current().LogLevel,
+			"module_log_levels": moduleLevels,
+			"log_sampling":      map[string]int{"debug": debugEvery, "info": infoEvery},
+			"addr":              t.server.Options.Addr,
 		}, nil
 
 	default:
@@ -320,14 +390,25 @@ type RequestDebuggerTool struct {
 	requestIDCounter int64
 }
 
+// CapturedRequest is a request recorded by the request debugger. Credentials in headers,
+// query parameters, and JSON or form bodies are redacted, see WithRedaction.
 type CapturedRequest struct {
 	ID        string              `json:"id"`
 	Method    string              `json:"method"`
 	Path      string              `json:"path"`
+	Query     string              `json:"query,omitempty"`
 	Headers   map[string][]string `json:"headers"`
 	Body      string              `json:"body"`
 	Timestamp time.Time           `json:"timestamp"`
 	Response  *CapturedResponse   `json:"response,omitempty"`
+	// Annotations attached by the handler via Annotate
+	Annotations map[string]any `json:"annotations,omitempty"`
+
+	// The request as received, with credentials, so that it can be replayed
+	rawQuery      string
+	rawHeaders    http.Header
+	rawBody       string
+	rawRemoteAddr string
 }
 
 type CapturedResponse struct {
@@ -363,12 +444,28 @@ func (t *RequestDebuggerTool) Schema() map[string]interface{} {
 				"properties": map[string]interface{}{
 					"headers": map[string]interface{}{
 						"type":        "object",
-						"description": "Headers to add/override as key-value pairs",
+						"description": "Headers to add/override as key-value pairs; an empty value removes the header",
 					},
 					"body": map[string]interface{}{
 						"type":        "string",
 						"description": "New request body to use instead of original",
 					},
+					"method": map[string]interface{}{
+						"type":        "string",
+						"description": "HTTP method to use instead of original",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Route to send the request to instead of original",
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Query string to use instead of original, without '?'",
+					},
+					"keep_credentials": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replay the original credentials even though the method, path, or headers are modified; they are stripped otherwise",
+					},
 				},
 			},
 		},
@@ -409,11 +506,16 @@ func (t *RequestDebuggerTool) Execute(params map[string]interface{}) (interface{
 		return nil, fmt.Errorf("request not found: %s", id)
 
 	case "replay":
-		// Replay functionality would go here
-		return map[string]interface{}{
-			"status": "replay_not_implemented",
-			"note":   "Request replay would replay the captured request with modifications",
-		}, nil
+		id, _ := params["request_id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("request_id is required")
+		}
+		val, ok := t.captures.Load(id)
+		if !ok {
+			return nil, fmt.Errorf("request not found: %s", id)
+		}
+		modifications, _ := params["modifications"].(map[string]interface{})
+		return t.replay(val.(*CapturedRequest), modifications)
 
 	case "clear":
 		t.captures.Range(func(key, value interface{}) bool {
@@ -429,6 +531,140 @@ func (t *RequestDebuggerTool) Execute(params map[string]interface{}) (interface{
 	}
 }
 
+const (
+	// replayHeader marks requests sent by the replay action with the ID of the original request.
+	replayHeader = "X-Hyperserve-Replay"
+	// replayRemoteAddr is the client of replays whose original client is unknown, from the
+	// TEST-NET-1 documentation range so that it is never mistaken for loopback.
+	replayRemoteAddr = "192.0.2.1:0"
+)
+
+// replay sends a captured request, with modifications applied, through the server's
+// handler and middleware, and returns the response. The replayed request is captured too.
+// The original credentials are only replayed to the same method and path with the same
+// headers, unless keep_credentials opts in, so that a replay cannot carry them to another
+// route; otherwise sensitive headers are dropped and the query and body stay redacted.
+func (t *RequestDebuggerTool) replay(orig *CapturedRequest, modifications map[string]interface{}) (interface{}, error) {
+	if t.server == nil {
+		return nil, fmt.Errorf("request replay requires a server")
+	}
+	method, path, query, body := orig.Method, orig.Path, orig.Query, orig.Body
+	headers := orig.Headers
+	retargeted := slices.ContainsFunc([]string{"method", "path", "headers"}, func(key string) bool {
+		_, ok := modifications[key]
+		return ok
+	})
+	keep, _ := modifications["keep_credentials"].(bool)
+	stripped := orig.rawHeaders == nil || retargeted && !keep
+	if !stripped {
+		query, headers, body = orig.rawQuery, orig.rawHeaders, orig.rawBody
+	}
+	if v, ok := modifications["method"].(string); ok && v != "" {
+		method = strings.ToUpper(v)
+	}
+	if v, ok := modifications["path"].(string); ok && v != "" {
+		path = v
+	}
+	if v, ok := modifications["query"].(string); ok {
+		query = strings.TrimPrefix(v, "?")
+	}
+	if v, ok := modifications["body"].(string); ok {
+		body = v
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path must start with '/': %q", path)
+	}
+
+	target := path
+	if query != "" {
+		target += "?" + query
+	}
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid replay request: %w", err)
+	}
+	for k, v := range headers {
+		if !stripped || !t.server.redactor.sensitiveHeader(k) {
+			req.Header[k] = slices.Clone(v)
+		}
+	}
+	if headers, ok := modifications["headers"].(map[string]interface{}); ok {
+		for k, v := range headers {
+			value := fmt.Sprint(v)
+			if value == "" {
+				req.Header.Del(k)
+			} else {
+				req.Header.Set(k, value)
+			}
+		}
+	}
+	req.Header.Del("Content-Length")
+	req.Header.Set(replayHeader, orig.ID)
+	req.Host = "localhost"
+	// Keep the original client, so that replays are not trusted as loopback requests
+	req.RemoteAddr = orig.rawRemoteAddr
+	if req.RemoteAddr == "" {
+		req.RemoteAddr = replayRemoteAddr
+	}
+
+	rec := &replayRecorder{header: make(http.Header), body: &bytes.Buffer{}, status: http.StatusOK}
+	start := time.Now()
+	t.server.Handler().ServeHTTP(rec, req)
+	duration := time.Since(start)
+
+	rd := t.server.redactor
+	result := map[string]interface{}{
+		"replay_of": orig.ID,
+		"request": map[string]interface{}{
+			"method":  method,
+			"path":    path,
+			"query":   rd.query(query),
+			"headers": rd.header(req.Header),
+			"body":    rd.body(req.Header.Get("Content-Type"), body),
+		},
+		"response": &CapturedResponse{
+			Status:  rec.status,
+			Headers: rd.header(rec.header),
+			Body:    rd.body(rec.header.Get("Content-Type"), rec.body.String()),
+		},
+		"duration_ms":          float64(duration.Microseconds()) / 1000,
+		"credentials_stripped": stripped,
+	}
+	if orig.Response != nil {
+		result["original_status"] = orig.Response.Status
+		result["status_changed"] = orig.Response.Status != rec.status
+	}
+	return result, nil
+}
+
+// replayRecorder collects the response to a replayed request.
+type replayRecorder struct {
+	header      http.Header
+	body        *bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func (r *replayRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *replayRecorder) WriteHeader(code int) {
+	// Informational responses such as 103 Early Hints precede the final status
+	if !r.wroteHeader && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		r.status = code
+		r.wroteHeader = true
+	}
+}
+
+func (r *replayRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	if r.body.Len() < 64*1024 { // Same limit as captured responses
+		r.body.Write(b)
+	}
+	return len(b), nil
+}
+
 // CaptureRequest captures an HTTP request and stores it in the debug tool
 func (t *RequestDebuggerTool) CaptureRequest(r *http.Request, responseHeaders map[string][]string, statusCode int, responseBody string) {
 	// Generate unique request ID
@@ -446,19 +682,29 @@ func (t *RequestDebuggerTool) CaptureRequest(r *http.Request, responseHeaders ma
 		}
 	}
 
-	// Create captured request
+	// Create captured request, keeping credentials out of what the tool returns
+	var rd *redactor
+	if t.server != nil {
+		rd = t.server.redactor
+	}
 	capturedReq := &CapturedRequest{
-		ID:        id,
-		Method:    r.Method,
-		Path:      r.URL.Path,
-		Headers:   r.Header,
-		Body:      body,
-		Timestamp: time.Now(),
+		ID:          id,
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Query:       rd.query(r.URL.RawQuery),
+		Headers:     rd.header(r.Header),
+		Body:        rd.body(r.Header.Get("Content-Type"), body),
+		Timestamp:   time.Now(),
+		Annotations: rd.fieldValues(Annotations(r)),
 		Response: &CapturedResponse{
 			Status:  statusCode,
-			Headers: responseHeaders,
-			Body:    responseBody,
+			Headers: rd.header(responseHeaders),
+			Body:    rd.body(http.Header(responseHeaders).Get("Content-Type"), responseBody),
 		},
+		rawQuery:      r.URL.RawQuery,
+		rawHeaders:    r.Header.Clone(),
+		rawBody:       body,
+		rawRemoteAddr: r.RemoteAddr,
 	}
 
 	// Store in captures map
@@ -496,6 +742,14 @@ func RequestCaptureMiddleware(debuggerTool *RequestDebuggerTool) MiddlewareFunc
 				next.ServeHTTP(w, r)
 				return
 			}
+			r = withAnnotations(r)
+
+			// Buffer the body so that it can be captured after the handler consumed it
+			var bodyBytes []byte
+			if r.Body != nil {
+				bodyBytes, _ = io.ReadAll(r.Body)
+				r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+			}
 
 			// Create a response writer that captures response data
 			crw := &captureResponseWriter{
@@ -519,6 +773,9 @@ func RequestCaptureMiddleware(debuggerTool *RequestDebuggerTool) MiddlewareFunc
 				responseHeaders[k] = v
 			}
 
+			if r.Body != nil {
+				r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+			}
 			debuggerTool.CaptureRequest(r, responseHeaders, crw.statusCode, crw.body.String())
 		}
 	}
@@ -533,7 +790,9 @@ type captureResponseWriter struct {
 }
 
 func (crw *captureResponseWriter) WriteHeader(code int) {
-	crw.statusCode = code
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		crw.statusCode = code
+	}
 	crw.ResponseWriter.WriteHeader(code)
 }
 
@@ -757,18 +1016,22 @@ func (t *DevGuideTool) Execute(params map[string]interface{}) (interface{}, erro
 	}
 }
 
-// RegisterDeveloperMCPTools registers all developer tools
-func (srv *Server) RegisterDeveloperMCPTools() {
+// RegisterDeveloperMCPTools registers all developer tools. Over HTTP the tools only answer
+// loopback clients unless WithMCPDevAllowRemote is set. It fails without registering
+// anything when the server looks like production, see MCPDev.
+func (srv *Server) RegisterDeveloperMCPTools() error {
 	if srv.mcpHandler == nil {
-		logger.Warn("Cannot register developer MCP tools: MCP handler not initialized")
-		return
+		return fmt.Errorf("cannot register developer MCP tools: MCP handler not initialized")
+	}
+	if err := srv.checkDeveloperMode(); err != nil {
+		return err
 	}
 
 	// Log prominent warning about developer mode
 	logger.Warn("⚠️  MCP DEVELOPER MODE ENABLED ⚠️",
 		"warning", "This mode allows server restart and configuration changes",
 		"security", "Only use in development environments",
-		"tools", []string{"mcp__hyperserve__server_control", "mcp__hyperserve__route_inspector", "mcp__hyperserve__request_debugger", "mcp__hyperserve__dev_guide"},
+		"tools", developerToolNames,
 	)
 
 	// Create and register the request debugger tool
@@ -781,6 +1044,11 @@ func (srv *Server) RegisterDeveloperMCPTools() {
 	srv.mcpHandler.RegisterToolInNamespace(&RouteInspectorTool{server: srv}, "hyperserve")
 	srv.mcpHandler.RegisterToolInNamespace(requestDebuggerTool, "hyperserve")
 	srv.mcpHandler.RegisterToolInNamespace(&DevGuideTool{server: srv}, "hyperserve")
+	srv.mcpHandler.RegisterToolInNamespace(NewConfigDiffTool(srv), "hyperserve")
+	srv.mcpHandler.RegisterToolInNamespace(&MCPTraceTool{server: srv}, "hyperserve")
+	if !srv.Options.MCPDevAllowRemote {
+		srv.mcpHandler.UseToolMiddleware(loopbackOnlyTools(developerToolNames))
+	}
 
 	// Add request capture middleware to capture HTTP requests
 	srv.AddMiddleware("*", RequestCaptureMiddleware(requestDebuggerTool))
@@ -796,6 +1064,7 @@ func (srv *Server) RegisterDeveloperMCPTools() {
 		"tools", []string{"mcp__hyperserve__server_control", "mcp__hyperserve__route_inspector", "mcp__hyperserve__request_debugger", "mcp__hyperserve__dev_guide"},
 		"resources", []string{"logs://server/stream", "routes://server/all"},
 	)
+	return nil
 }
 
 // =============================================================================
@@ -868,33 +1137,41 @@ func (r *ServerConfigResource) Read() (interface{}, error) {
 	// - ECHKeys: Encrypted Client Hello keys
 	// - StaticDir/TemplateDir: Could expose internal file structure
 	// - MCPFileToolRoot: Could expose sandboxed directory paths
+	options := r.server.Options.current()
 	config := map[string]interface{}{
 		"version":       Version,
 		"build_hash":    BuildHash,
 		"build_time":    BuildTime,
 		"go_version":    runtime.Version(),
-		"addr":          r.server.Options.Addr,
-		"health_addr":   r.server.Options.HealthAddr,
-		"tls_enabled":   r.server.Options.EnableTLS,
-		"rate_limit":    r.server.Options.RateLimit,
-		"burst":         r.server.Options.Burst,
-		"hardened_mode": r.server.Options.HardenedMode,
-		"fips_mode":     r.server.Options.FIPSMode,
-		"mcp_enabled":   r.server.Options.MCPEnabled,
-		"mcp_endpoint":  r.server.Options.MCPEndpoint,
-		"debug_mode":    r.server.Options.DebugMode,
-		"log_level":     r.server.Options.LogLevel,
+		"addr":          options.Addr,
+		"health_addr":   options.HealthAddr,
+		"tls_enabled":   options.EnableTLS,
+		"rate_limit":    options.RateLimit,
+		"burst":         options.Burst,
+		"hardened_mode": options.HardenedMode,
+		"fips_mode":     options.FIPSMode,
+		"mcp_enabled":   options.MCPEnabled,
+		"mcp_endpoint":  options.MCPEndpoint,
+		"debug_mode":    options.DebugMode,
+		"log_level":     options.LogLevel,
 		"timeouts": map[string]string{
-			"read":  r.server.Options.ReadTimeout.String(),
-			"write": r.server.Options.WriteTimeout.String(),
-			"idle":  r.server.Options.IdleTimeout.String(),
+			"read":  options.ReadTimeout.String(),
+			"write": options.WriteTimeout.String(),
+			"idle":  options.IdleTimeout.String(),
 		},
+		"route_windows":    routeWindowStatus(options.RouteWindows, time.Now()),
 		"middleware_count": len(r.server.middleware.middleware),
 		"is_running":       r.server.isRunning.Load(),
 		"is_ready":         r.server.isReady.Load(),
 	}
+	rd := r.server.redactor
+	if rd == nil {
+		rd = defaultRedactor
+	}
+	config["redaction"] = map[string][]string{"headers": rd.headers, "fields": rd.fields}
 
-	return config, nil
+	// Values of keys matching the redaction patterns are scrubbed as a last line of defence
+	return rd.value(config), nil
 }
 
 // List returns the available resource URIs.
@@ -917,6 +1194,11 @@ func (r *ServerHealthResource) URI() string {
 	return "health://server/status"
 }
 
+// CacheTTL disables caching, since the content changes constantly.
+func (r *ServerHealthResource) CacheTTL() time.Duration {
+	return 0
+}
+
 // Name returns the resource name.
 func (r *ServerHealthResource) Name() string {
 	return "Server Health Status"
@@ -999,6 +1281,11 @@ func (r *ServerLogResource) URI() string {
 	return "logs://server/recent"
 }
 
+// CacheTTL disables caching, since the content changes constantly.
+func (r *ServerLogResource) CacheTTL() time.Duration {
+	return 0
+}
+
 // Name returns the resource name.
 func (r *ServerLogResource) Name() string {
 	return "Server Logs"
@@ -1171,6 +1458,12 @@ func (srv *Server) RegisterObservabilityMCPResources() {
 	// Register server health resource
 	srv.mcpHandler.RegisterResource(NewServerHealthResource(srv))
 
+	// Register per-route metrics resource
+	srv.mcpHandler.RegisterResource(NewRouteMetricsResource(srv))
+
+	// Register read-only configuration diff tool to preview reloads
+	srv.mcpHandler.RegisterToolInNamespace(NewConfigDiffTool(srv), "hyperserve")
+
 	// Create and register log resource with custom logger
 	logResource := NewServerLogResource(srv.Options.MCPLogResourceSize)
 	srv.mcpHandler.RegisterResource(logResource)
@@ -1186,7 +1479,8 @@ func (srv *Server) RegisterObservabilityMCPResources() {
 	}
 
 	logger.Info("Observability MCP resources registered",
-		"resources", []string{"config://server/current", "health://server/status", "logs://server/recent"})
+		"resources", []string{"config://server/current", "health://server/status", "metrics://server/routes", "logs://server/recent"},
+		"tools", []string{"mcp__hyperserve__config_diff"})
 }
 
 // =============================================================================
@@ -1196,6 +1490,7 @@ func (srv *Server) RegisterObservabilityMCPResources() {
 // FileReadTool implements MCPTool for reading files from the filesystem
 type FileReadTool struct {
 	root *os.Root // Secure file access using os.Root
+	dir  string   // Absolute path of the root directory, for checking the client's roots
 }
 
 // NewFileReadTool creates a new file read tool with optional root directory restriction
@@ -1209,7 +1504,7 @@ func NewFileReadTool(rootDir string) (*FileReadTool, error) {
 		}
 	}
 
-	return &FileReadTool{root: root}, nil
+	return &FileReadTool{root: root, dir: absRootDir(rootDir)}, nil
 }
 
 func (t *FileReadTool) Name() string {
@@ -1268,9 +1563,21 @@ func (t *FileReadTool) Execute(params map[string]interface{}) (interface{}, erro
 	return string(content), nil
 }
 
+// ExecuteWithClient reads the file if it lies within the client's roots as well as the
+// root directory, when the client declared the roots capability.
+func (t *FileReadTool) ExecuteWithClient(ctx context.Context, params map[string]interface{}, client MCPClientRequester) (interface{}, error) {
+	if path, ok := params["path"].(string); ok {
+		if err := checkClientRoots(ctx, client, t.dir, filepath.Clean(path)); err != nil {
+			return nil, err
+		}
+	}
+	return t.Execute(params)
+}
+
 // ListDirectoryTool implements MCPTool for listing directory contents
 type ListDirectoryTool struct {
 	root *os.Root
+	dir  string
 }
 
 // NewListDirectoryTool creates a new directory listing tool
@@ -1284,7 +1591,7 @@ func NewListDirectoryTool(rootDir string) (*ListDirectoryTool, error) {
 		}
 	}
 
-	return &ListDirectoryTool{root: root}, nil
+	return &ListDirectoryTool{root: root, dir: absRootDir(rootDir)}, nil
 }
 
 func (t *ListDirectoryTool) Name() string {
@@ -1356,6 +1663,30 @@ func (t *ListDirectoryTool) Execute(params map[string]interface{}) (interface{},
 	return files, nil
 }
 
+// ExecuteWithClient lists the directory if it lies within the client's roots as well as
+// the root directory, when the client declared the roots capability.
+func (t *ListDirectoryTool) ExecuteWithClient(ctx context.Context, params map[string]interface{}, client MCPClientRequester) (interface{}, error) {
+	path := "."
+	if p, ok := params["path"].(string); ok {
+		path = p
+	}
+	if err := checkClientRoots(ctx, client, t.dir, filepath.Clean(path)); err != nil {
+		return nil, err
+	}
+	return t.Execute(params)
+}
+
+// absRootDir returns the absolute path of a tool's root directory, or "" without one.
+func absRootDir(rootDir string) string {
+	if rootDir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(rootDir); err == nil {
+		return abs
+	}
+	return rootDir
+}
+
 func getFileType(entry os.DirEntry) string {
 	if entry.IsDir() {
 		return "directory"
@@ -1582,23 +1913,25 @@ func (r *ConfigResource) MimeType() string {
 
 func (r *ConfigResource) Read() (interface{}, error) {
 	// Return a sanitized version of the configuration (no sensitive data)
+	options := r.options.current()
 	config := map[string]interface{}{
-		"addr":            r.options.Addr,
-		"enableTLS":       r.options.EnableTLS,
-		"tlsAddr":         r.options.TLSAddr,
-		"healthAddr":      r.options.HealthAddr,
-		"rateLimit":       float64(r.options.RateLimit),
-		"burst":           r.options.Burst,
-		"readTimeout":     r.options.ReadTimeout.String(),
-		"writeTimeout":    r.options.WriteTimeout.String(),
-		"idleTimeout":     r.options.IdleTimeout.String(),
-		"staticDir":       r.options.StaticDir,
-		"templateDir":     r.options.TemplateDir,
-		"runHealthServer": r.options.RunHealthServer,
-		"chaosMode":       r.options.ChaosMode,
-		"fipsMode":        r.options.FIPSMode,
-		"hardenedMode":    r.options.HardenedMode,
-		"enableECH":       r.options.EnableECH,
+		"addr":            options.Addr,
+		"enableTLS":       options.EnableTLS,
+		"tlsAddr":         options.TLSAddr,
+		"healthAddr":      options.HealthAddr,
+		"rateLimit":       float64(options.RateLimit),
+		"burst":           options.Burst,
+		"readTimeout":     options.ReadTimeout.String(),
+		"writeTimeout":    options.WriteTimeout.String(),
+		"idleTimeout":     options.IdleTimeout.String(),
+		"staticDir":       options.StaticDir,
+		"templateDir":     options.TemplateDir,
+		"runHealthServer": options.RunHealthServer,
+		"chaosMode":       options.ChaosMode,
+		"fipsMode":        options.FIPSMode,
+		"hardenedMode":    options.HardenedMode,
+		"enableECH":       options.EnableECH,
+		"routeWindows":    routeWindowStatus(options.RouteWindows, time.Now()),
 	}
 
 	configJSON, err := json.MarshalIndent(config, "", "  ")
@@ -1627,6 +1960,11 @@ func (r *MetricsResource) URI() string {
 	return "metrics://server/stats"
 }
 
+// CacheTTL disables caching, since the content changes constantly.
+func (r *MetricsResource) CacheTTL() time.Duration {
+	return 0
+}
+
 func (r *MetricsResource) Name() string {
 	return "Server Metrics"
 }
@@ -1658,6 +1996,9 @@ func (r *MetricsResource) Read() (interface{}, error) {
 		"isReady":           r.server.isReady.Load(),
 		"timestamp":         time.Now().Format(time.RFC3339),
 	}
+	if layers := r.server.MiddlewareMetrics(); layers != nil {
+		metrics["middlewareLayers"] = layers
+	}
 
 	metricsJSON, err := json.MarshalIndent(metrics, "", "  ")
 	if err != nil {
@@ -1683,6 +2024,11 @@ func (r *SystemResource) URI() string {
 	return "system://runtime/info"
 }
 
+// CacheTTL disables caching, since the content changes constantly.
+func (r *SystemResource) CacheTTL() time.Duration {
+	return 0
+}
+
 func (r *SystemResource) Name() string {
 	return "System Information"
 }
@@ -1747,6 +2093,11 @@ func (r *LogResource) URI() string {
 	return "logs://server/recent"
 }
 
+// CacheTTL disables caching, since the content changes constantly.
+func (r *LogResource) CacheTTL() time.Duration {
+	return 0
+}
+
 func (r *LogResource) Name() string {
 	return "Recent Log Entries"
 }
@@ -0,0 +1,712 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrMCPClientClosed is returned by calls on a closed MCPClient.
+var ErrMCPClientClosed = errors.New("MCP client closed")
+
+// MCPClient talks to an MCP server over Streamable HTTP or stdio. It is used to mount
+// upstream servers with Server.MountMCPUpstream, and can also be used on its own.
+//
+// Example:
+//
+//	client := server.NewMCPHTTPClient("http://docs.internal:8080/mcp",
+//		server.WithMCPClientHeader("Authorization", "Bearer "+token))
+//	defer client.Close()
+//	if _, err := client.Initialize(ctx); err != nil {
+//		return err
+//	}
+//	result, err := client.CallTool(ctx, "search", map[string]interface{}{"query": "tls"})
+type MCPClient struct {
+	transport mcpClientTransport
+	info      MCPClientInfo
+	nextID    atomic.Int64
+
+	mu             sync.RWMutex
+	initResult     *MCPInitializeResult
+	onNotification func(method string, params interface{})
+	onRequest      MCPClientRequestHandler
+	roots          []MCPRoot // Answers roots/list when rootsEnabled is set
+	rootsEnabled   bool
+}
+
+// MCPClientRequestHandler answers a request the server sends to the client, such as
+// sampling/createMessage or elicitation/create. A returned *JSONRPCError is sent to the
+// server as is; other errors are sent as internal errors.
+type MCPClientRequestHandler func(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+
+// MCPClientOption configures an MCPClient.
+type MCPClientOption func(*MCPClient)
+
+// WithMCPClientInfo sets the name and version sent to the server on initialize. The
+// default is "hyperserve".
+func WithMCPClientInfo(name, version string) MCPClientOption {
+	return func(c *MCPClient) {
+		c.info = MCPClientInfo{Name: name, Version: version}
+	}
+}
+
+// WithMCPClientRoots declares the roots capability on initialize and answers the
+// server's roots/list requests with roots. Change them with SetRoots.
+func WithMCPClientRoots(roots ...MCPRoot) MCPClientOption {
+	return func(c *MCPClient) {
+		c.roots, c.rootsEnabled = roots, true
+	}
+}
+
+// WithMCPClientHTTPClient sets the http.Client of an HTTP MCP client.
+func WithMCPClientHTTPClient(hc *http.Client) MCPClientOption {
+	return func(c *MCPClient) {
+		if t, ok := c.transport.(*mcpHTTPClientTransport); ok {
+			t.client = hc
+		}
+	}
+}
+
+// WithMCPClientHeader adds a header, such as Authorization, to every request of an HTTP
+// MCP client.
+func WithMCPClientHeader(key, value string) MCPClientOption {
+	return func(c *MCPClient) {
+		if t, ok := c.transport.(*mcpHTTPClientTransport); ok {
+			t.header.Set(key, value)
+		}
+	}
+}
+
+// mcpClientTransport sends messages to an MCP server.
+type mcpClientTransport interface {
+	// call sends a request and waits for its response.
+	call(ctx context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error)
+	// notify sends a notification.
+	notify(ctx context.Context, n jsonrpcNotification) error
+	// setProtocolVersion is called with the revision agreed on initialize.
+	setProtocolVersion(version string)
+	close() error
+}
+
+// NewMCPHTTPClient creates a client for the Streamable HTTP transport at endpoint.
+func NewMCPHTTPClient(endpoint string, opts ...MCPClientOption) *MCPClient {
+	c := newMCPClient(&mcpHTTPClientTransport{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		header:   make(http.Header),
+	})
+	c.transport.(*mcpHTTPClientTransport).notifications = c.dispatchNotification
+	c.transport.(*mcpHTTPClientTransport).requests = c.answerRequest
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewMCPStdioClient creates a client that writes newline-delimited JSON-RPC to w and
+// reads responses and notifications from r, such as the pipes of a child process.
+func NewMCPStdioClient(r io.Reader, w io.Writer, opts ...MCPClientOption) *MCPClient {
+	t := &mcpStdioClientTransport{
+		w:       w,
+		pending: make(map[string]chan *JSONRPCResponse),
+		done:    make(chan struct{}),
+	}
+	if closer, ok := w.(io.Closer); ok {
+		t.closers = append(t.closers, closer)
+	}
+	c := newMCPClient(t)
+	for _, opt := range opts {
+		opt(c)
+	}
+	go t.readLoop(r, c.dispatchNotification, c.answerRequest)
+	return c
+}
+
+// NewMCPCommandClient starts an MCP server command and talks to it over its stdin and
+// stdout. Close stops the command.
+func NewMCPCommandClient(name string, args []string, opts ...MCPClientOption) (*MCPClient, error) {
+	cmd := exec.Command(name, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin of %s: %w", name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout of %s: %w", name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	c := NewMCPStdioClient(stdout, stdin, opts...)
+	c.transport.(*mcpStdioClientTransport).cmd = cmd
+	return c, nil
+}
+
+func newMCPClient(t mcpClientTransport) *MCPClient {
+	return &MCPClient{
+		transport: t,
+		info:      MCPClientInfo{Name: "hyperserve", Version: Version},
+	}
+}
+
+// OnNotification sets the function called with notifications from the server, such as
+// notifications/tools/list_changed. Over HTTP, only notifications sent on a request's
+// response stream are delivered.
+func (c *MCPClient) OnNotification(fn func(method string, params interface{})) {
+	c.mu.Lock()
+	c.onNotification = fn
+	c.mu.Unlock()
+}
+
+func (c *MCPClient) dispatchNotification(method string, params interface{}) {
+	c.mu.RLock()
+	fn := c.onNotification
+	c.mu.RUnlock()
+	if fn != nil {
+		fn(method, params)
+	}
+}
+
+// OnRequest sets the function that answers requests from the server, and makes
+// Initialize declare the sampling and elicitation capabilities. Without it, such
+// requests are answered with a method not found error.
+func (c *MCPClient) OnRequest(fn MCPClientRequestHandler) {
+	c.mu.Lock()
+	c.onRequest = fn
+	c.mu.Unlock()
+}
+
+// answerRequest runs a request from the server and returns the response to send back.
+func (c *MCPClient) answerRequest(msg *mcpClientMessage) interface{} {
+	c.mu.RLock()
+	fn := c.onRequest
+	c.mu.RUnlock()
+
+	reply := mcpClientReply{JSONRPC: JSONRPCVersion, ID: msg.ID}
+	if roots, ok := c.listRoots(msg.Method); ok {
+		reply.Result = map[string]interface{}{"roots": roots}
+		return reply
+	}
+	if fn == nil {
+		reply.Error = &JSONRPCError{Code: ErrorCodeMethodNotFound, Message: "Method not found: " + msg.Method}
+		return reply
+	}
+	params, _ := json.Marshal(msg.Params)
+	result, err := fn(context.Background(), msg.Method, params)
+	var rpcErr *JSONRPCError
+	switch {
+	case errors.As(err, &rpcErr):
+		reply.Error = rpcErr
+	case err != nil:
+		reply.Error = &JSONRPCError{Code: ErrorCodeInternalError, Message: err.Error()}
+	case result == nil:
+		reply.Result = map[string]interface{}{}
+	default:
+		reply.Result = result
+	}
+	return reply
+}
+
+// listRoots returns the roots to answer a roots/list request with, if the client has roots.
+func (c *MCPClient) listRoots(method string) ([]MCPRoot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if method != "roots/list" || !c.rootsEnabled {
+		return nil, false
+	}
+	roots := c.roots
+	if roots == nil {
+		roots = []MCPRoot{}
+	}
+	return roots, true
+}
+
+// SetRoots replaces the client's roots and sends notifications/roots/list_changed, so
+// that the server fetches them again. The client must have been created with
+// WithMCPClientRoots.
+func (c *MCPClient) SetRoots(ctx context.Context, roots ...MCPRoot) error {
+	c.mu.Lock()
+	if !c.rootsEnabled {
+		c.mu.Unlock()
+		return ErrMCPRootsUnsupported
+	}
+	c.roots = roots
+	initialized := c.initResult != nil
+	c.mu.Unlock()
+	if !initialized {
+		return nil
+	}
+	return c.transport.notify(ctx, jsonrpcNotification{JSONRPC: JSONRPCVersion, Method: "notifications/roots/list_changed"})
+}
+
+// mcpClientReply is the client's response to a request from the server.
+type mcpClientReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+// Initialize performs the initialize handshake. It must be called before other methods.
+func (c *MCPClient) Initialize(ctx context.Context) (*MCPInitializeResult, error) {
+	var result MCPInitializeResult
+	capabilities := map[string]interface{}{}
+	c.mu.RLock()
+	if c.onRequest != nil {
+		capabilities["sampling"] = map[string]interface{}{}
+		capabilities["elicitation"] = map[string]interface{}{}
+	}
+	if c.rootsEnabled {
+		capabilities["roots"] = map[string]interface{}{"listChanged": true}
+	}
+	c.mu.RUnlock()
+	params := MCPInitializeParams{
+		ProtocolVersion: MCPLatestVersion,
+		Capabilities:    capabilities,
+		ClientInfo:      c.info,
+	}
+	if err := c.Call(ctx, "initialize", params, &result); err != nil {
+		return nil, err
+	}
+	c.transport.setProtocolVersion(result.ProtocolVersion)
+	if err := c.transport.notify(ctx, jsonrpcNotification{JSONRPC: JSONRPCVersion, Method: "notifications/initialized"}); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.initResult = &result
+	c.mu.Unlock()
+	return &result, nil
+}
+
+// ServerInfo returns the server's name and version, once initialized.
+func (c *MCPClient) ServerInfo() MCPServerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.initResult == nil {
+		return MCPServerInfo{}
+	}
+	return c.initResult.ServerInfo
+}
+
+// ListTools returns the server's tools.
+func (c *MCPClient) ListTools(ctx context.Context) ([]MCPToolInfo, error) {
+	var result struct {
+		Tools []MCPToolInfo `json:"tools"`
+	}
+	if err := c.Call(ctx, "tools/list", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Tools, nil
+}
+
+// CallTool calls a tool. A result flagged as an error by the server is returned as is;
+// use its IsError field to tell it apart from a successful call.
+func (c *MCPClient) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*MCPClientToolResult, error) {
+	var result MCPClientToolResult
+	params := MCPToolCallParams{Name: name, Arguments: arguments}
+	if err := c.Call(ctx, "tools/call", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MCPClientToolResult is the result of a tool call made by an MCPClient.
+type MCPClientToolResult struct {
+	Content           []map[string]interface{} `json:"content"`
+	StructuredContent interface{}              `json:"structuredContent,omitempty"`
+	IsError           bool                     `json:"isError,omitempty"`
+}
+
+// Text joins the text content of the result.
+func (r *MCPClientToolResult) Text() string {
+	var parts []string
+	for _, c := range r.Content {
+		if text, ok := c["text"].(string); ok {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ListResources returns the server's resources.
+func (c *MCPClient) ListResources(ctx context.Context) ([]MCPResourceInfo, error) {
+	var result struct {
+		Resources []MCPResourceInfo `json:"resources"`
+	}
+	if err := c.Call(ctx, "resources/list", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Resources, nil
+}
+
+// ReadResource reads a resource and returns its contents.
+func (c *MCPClient) ReadResource(ctx context.Context, uri string) ([]MCPResourceContent, error) {
+	var result struct {
+		Contents []MCPResourceContent `json:"contents"`
+	}
+	if err := c.Call(ctx, "resources/read", MCPResourceReadParams{URI: uri}, &result); err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
+
+// Ping checks that the server responds.
+func (c *MCPClient) Ping(ctx context.Context) error {
+	return c.Call(ctx, "ping", nil, nil)
+}
+
+// Call sends a request and decodes its result into result, which may be nil. Errors
+// returned by the server are *JSONRPCError values.
+func (c *MCPClient) Call(ctx context.Context, method string, params, result interface{}) error {
+	req := &JSONRPCRequest{
+		JSONRPC: JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+		ID:      c.nextID.Add(1),
+	}
+	resp, err := c.transport.call(ctx, req)
+	if err != nil {
+		return fmt.Errorf("MCP %s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	data, err := json.Marshal(resp.Result)
+	if err != nil {
+		return fmt.Errorf("MCP %s: %w", method, err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("MCP %s: invalid result: %w", method, err)
+	}
+	return nil
+}
+
+// Close ends the session and releases the transport.
+func (c *MCPClient) Close() error {
+	return c.transport.close()
+}
+
+// mcpClientMessage is a message received by a client: a response or a notification.
+type mcpClientMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+// response returns the message as a response, or nil for notifications and requests.
+func (m *mcpClientMessage) response() *JSONRPCResponse {
+	if m.Method != "" || len(m.ID) == 0 || string(m.ID) == "null" {
+		return nil
+	}
+	return &JSONRPCResponse{JSONRPC: m.JSONRPC, Result: m.Result, Error: m.Error, ID: mcpClientID(m.ID)}
+}
+
+// mcpClientID normalizes a request ID so that sent and received IDs compare equal.
+func mcpClientID(id interface{}) string {
+	switch v := id.(type) {
+	case json.RawMessage:
+		return strings.Trim(string(v), `"`)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// =============================================================================
+// STREAMABLE HTTP CLIENT TRANSPORT
+// =============================================================================
+
+type mcpHTTPClientTransport struct {
+	endpoint      string
+	client        *http.Client
+	header        http.Header
+	notifications func(method string, params interface{})
+	requests      func(msg *mcpClientMessage) interface{}
+
+	mu        sync.RWMutex
+	sessionID string
+	version   string
+}
+
+func (t *mcpHTTPClientTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	t.version = version
+	t.mu.Unlock()
+}
+
+func (t *mcpHTTPClientTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.mu.RLock()
+	if t.sessionID != "" {
+		req.Header.Set(mcpSessionHeader, t.sessionID)
+	}
+	if t.version != "" {
+		req.Header.Set(mcpProtocolVersionHeader, t.version)
+	}
+	t.mu.RUnlock()
+	return req, nil
+}
+
+func (t *mcpHTTPClientTransport) post(ctx context.Context, msg interface{}) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get(mcpSessionHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(text)))
+	}
+	return resp, nil
+}
+
+func (t *mcpHTTPClientTransport) call(ctx context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	want := mcpClientID(req.ID)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var msg mcpClientMessage
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("invalid response: %w", err)
+		}
+		if r := msg.response(); r != nil && r.ID == want {
+			return r, nil
+		}
+		return nil, fmt.Errorf("unexpected response")
+	}
+
+	// The response follows any notifications on the stream
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if rest, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(rest, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var msg mcpClientMessage
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err != nil {
+			continue
+		}
+		if r := msg.response(); r != nil && r.ID == want {
+			return r, nil
+		}
+		if msg.Method != "" && len(msg.ID) > 0 {
+			// A request from the server, answered with a separate POST
+			go func() {
+				if resp, err := t.post(context.Background(), t.requests(&msg)); err == nil {
+					resp.Body.Close()
+				}
+			}()
+			continue
+		}
+		if msg.Method != "" && t.notifications != nil {
+			t.notifications(msg.Method, msg.Params)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("stream ended without a response")
+}
+
+func (t *mcpHTTPClientTransport) notify(ctx context.Context, n jsonrpcNotification) error {
+	resp, err := t.post(ctx, n)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *mcpHTTPClientTransport) close() error {
+	t.mu.RLock()
+	sessionID := t.sessionID
+	t.mu.RUnlock()
+	if sessionID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := t.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// =============================================================================
+// STDIO CLIENT TRANSPORT
+// =============================================================================
+
+type mcpStdioClientTransport struct {
+	writeMu sync.Mutex
+	w       io.Writer
+	closers []io.Closer
+	cmd     *exec.Cmd
+
+	mu      sync.Mutex
+	pending map[string]chan *JSONRPCResponse
+	done    chan struct{}
+	err     error // Why the transport stopped
+}
+
+func (t *mcpStdioClientTransport) setProtocolVersion(string) {}
+
+// readLoop delivers responses to waiting calls and notifications to notify, and
+// answers requests from the server with answer, until r ends.
+func (t *mcpStdioClientTransport) readLoop(r io.Reader, notify func(method string, params interface{}), answer func(*mcpClientMessage) interface{}) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var msg mcpClientMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if resp := msg.response(); resp != nil {
+			t.mu.Lock()
+			ch := t.pending[resp.ID.(string)]
+			delete(t.pending, resp.ID.(string))
+			t.mu.Unlock()
+			if ch != nil {
+				ch <- resp
+			}
+			continue
+		}
+		if msg.Method != "" && len(msg.ID) == 0 {
+			notify(msg.Method, msg.Params)
+		}
+		if msg.Method != "" && len(msg.ID) > 0 {
+			go t.write(answer(&msg))
+		}
+	}
+	t.mu.Lock()
+	if t.err == nil {
+		t.err = scanner.Err()
+	}
+	if t.err == nil {
+		t.err = io.EOF
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *mcpStdioClientTransport) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.w.Write(append(data, '\n'))
+	return err
+}
+
+func (t *mcpStdioClientTransport) call(ctx context.Context, req *JSONRPCRequest) (*JSONRPCResponse, error) {
+	id := mcpClientID(req.ID)
+	ch := make(chan *JSONRPCResponse, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, ErrMCPClientClosed
+	}
+	t.pending[id] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		return nil, ErrMCPClientClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *mcpStdioClientTransport) notify(_ context.Context, n jsonrpcNotification) error {
+	return t.write(n)
+}
+
+func (t *mcpStdioClientTransport) close() error {
+	t.mu.Lock()
+	if t.err == nil {
+		t.err = ErrMCPClientClosed
+	}
+	t.mu.Unlock()
+	var err error
+	for _, c := range t.closers {
+		err = errors.Join(err, c.Close())
+	}
+	if t.cmd != nil {
+		// Closing stdin asks the server to exit; stop it if it does not
+		select {
+		case <-t.done:
+		case <-time.After(5 * time.Second):
+			t.cmd.Process.Kill()
+		}
+		t.cmd.Wait()
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+)
+
+// paramMCPDevOverride acknowledges the risk of MCP developer mode in a production-like
+// environment.
+const paramMCPDevOverride = "HS_MCP_DEV_I_UNDERSTAND"
+
+// developerToolNames are the tools registered by RegisterDeveloperMCPTools.
+var developerToolNames = []string{
+	"mcp__hyperserve__server_control",
+	"mcp__hyperserve__route_inspector",
+	"mcp__hyperserve__request_debugger",
+	"mcp__hyperserve__dev_guide",
+	"mcp__hyperserve__config_diff",
+	"mcp__hyperserve__mcp_trace",
+}
+
+// WithMCPDevAllowRemote lets non-loopback clients call the MCP developer tools. By default
+// they only answer requests from loopback addresses and over stdio.
+func WithMCPDevAllowRemote() ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.MCPDevAllowRemote = true
+		return nil
+	}
+}
+
+// productionSignals returns the reasons to believe the server runs in production:
+// hardened or FIPS mode, an Addr that is not a loopback address, the production profile,
+// or APP_ENV=production. Wildcard addresses such as ":8080" count, since they listen on
+// every interface; bind to localhost:8080 for local development.
+func (srv *Server) productionSignals() []string {
+	var signals []string
+	if srv.Options.HardenedMode {
+		signals = append(signals, "hardened mode")
+	}
+	if srv.Options.FIPSMode {
+		signals = append(signals, "FIPS mode")
+	}
+	if host, _, err := net.SplitHostPort(srv.Options.Addr); err == nil && host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			signals = append(signals, "non-loopback address "+srv.Options.Addr)
+		}
+	}
+	if srv.Options.Profile == ProfileProduction {
+		signals = append(signals, "production profile")
+	}
+	if env := strings.ToLower(os.Getenv("APP_ENV")); env == "production" || env == "prod" {
+		signals = append(signals, "APP_ENV="+env)
+	}
+	return signals
+}
+
+// checkDeveloperMode refuses MCP developer mode when the server looks like production,
+// unless HS_MCP_DEV_I_UNDERSTAND is set.
+func (srv *Server) checkDeveloperMode() error {
+	signals := srv.productionSignals()
+	if len(signals) == 0 {
+		return nil
+	}
+	if v := os.Getenv(paramMCPDevOverride); v == "true" || v == "1" {
+		logger.Warn("MCP developer mode enabled despite production signals",
+			"signals", signals, "variable", paramMCPDevOverride)
+		return nil
+	}
+	return fmt.Errorf("MCP developer mode refused in a production environment (%s); set %s=true to override",
+		strings.Join(signals, ", "), paramMCPDevOverride)
+}
+
+// loopbackOnlyTools rejects calls of the named tools that arrive over HTTP from
+// non-loopback clients or through a proxy, which makes every client appear local.
+func loopbackOnlyTools(names []string) ToolMiddleware {
+	return func(next ToolExecutor) ToolExecutor {
+		return func(ctx context.Context, call *ToolCall) (interface{}, error) {
+			if call.Request != nil && slices.Contains(names, call.Name) {
+				if ip := net.ParseIP(clientIP(call.Request)); ip == nil || !ip.IsLoopback() || isForwardedRequest(call.Request) {
+					return nil, &JSONRPCError{
+						Code:    ErrorCodeToolForbidden,
+						Message: "Developer tools are restricted to localhost",
+						Data:    map[string]interface{}{"tool": call.Name},
+					}
+				}
+			}
+			return next(ctx, call)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeveloperModeRefusedInProduction(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ServerOptionFunc
+		env  string
+	}{
+		{"hardened mode", []ServerOptionFunc{WithHardenedMode()}, ""},
+		{"non-loopback address", []ServerOptionFunc{WithAddr("10.1.2.3:8080")}, ""},
+		{"wildcard address", []ServerOptionFunc{WithAddr(":8080")}, ""},
+		{"unspecified address", []ServerOptionFunc{WithAddr("0.0.0.0:8080")}, ""},
+		{"APP_ENV", []ServerOptionFunc{WithAddr("localhost:8080")}, "production"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.env)
+			opts := append(tt.opts, WithMCPSupport("test", "1.0.0", MCPDev()))
+			if _, err := NewServer(opts...); err == nil || !strings.Contains(err.Error(), paramMCPDevOverride) {
+				t.Fatalf("expected developer mode to be refused, got %v", err)
+			}
+
+			t.Setenv(paramMCPDevOverride, "true")
+			if _, err := NewServer(opts...); err != nil {
+				t.Errorf("override was not honored: %v", err)
+			}
+		})
+	}
+
+	if _, err := NewServer(WithAddr("127.0.0.1:0"), WithMCPSupport("test", "1.0.0", MCPDev())); err != nil {
+		t.Errorf("loopback developer server was refused: %v", err)
+	}
+}
+
+func TestDeveloperToolsLoopbackOnly(t *testing.T) {
+	srv, err := NewServer(WithAddr("127.0.0.1:0"), WithMCPSupport("test", "1.0.0", MCPDev()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(remoteAddr string, forwardedFor ...string) error {
+		r := httptest.NewRequest("POST", "/mcp", nil)
+		r.RemoteAddr = remoteAddr
+		for _, ip := range forwardedFor {
+			r.Header.Add("X-Forwarded-For", ip)
+		}
+		ctx := withMCPRequest(context.Background(), r)
+		_, err := srv.mcpHandler.handleToolsCallContext(ctx, map[string]interface{}{
+			"name":      "mcp__hyperserve__dev_guide",
+			"arguments": map[string]interface{}{"topic": "overview"},
+		})
+		return err
+	}
+
+	if err := call("203.0.113.9:5000"); err == nil || !strings.Contains(err.Error(), "restricted to localhost") {
+		t.Errorf("remote call was not rejected: %v", err)
+	}
+	if err := call("127.0.0.1:5000", "203.0.113.9"); err == nil || !strings.Contains(err.Error(), "restricted to localhost") {
+		t.Errorf("call through a local proxy was not rejected: %v", err)
+	}
+	if err := call("127.0.0.1:5000"); err != nil {
+		t.Errorf("loopback call failed: %v", err)
+	}
+}
+
+func TestRegisterDeveloperMCPToolsRefusedInProduction(t *testing.T) {
+	srv, err := NewServer(WithAddr(":8080"), WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterDeveloperMCPTools(); err == nil || !strings.Contains(err.Error(), paramMCPDevOverride) {
+		t.Fatalf("expected developer tools to be refused, got %v", err)
+	}
+	if _, ok := srv.mcpHandler.tools["mcp__hyperserve__server_control"]; ok {
+		t.Error("developer tools were registered despite the refusal")
+	}
+}
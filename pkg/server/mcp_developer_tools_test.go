@@ -1,6 +1,11 @@
 package server
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -517,3 +522,120 @@ func TestDevGuideTool(t *testing.T) {
 		}
 	})
 }
+
+func TestRequestDebuggerReplay(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	tool := &RequestDebuggerTool{server: srv}
+	srv.AddMiddleware("*", RequestCaptureMiddleware(tool))
+	srv.HandleFunc("POST /echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Mode", r.Header.Get("X-Mode"))
+		w.Header().Set("X-Remote-Addr", r.RemoteAddr)
+		fmt.Fprintf(w, "%s?%s", body, r.URL.RawQuery)
+	})
+	srv.HandleFunc("POST /v2/echo", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/echo?n=1", strings.NewReader("hello"))
+	req.Header.Set("X-Mode", "original")
+	req.RemoteAddr = "203.0.113.7:41000"
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	var id string
+	tool.captures.Range(func(key, value interface{}) bool {
+		id = key.(string)
+		if body := value.(*CapturedRequest).Body; body != "hello" {
+			t.Errorf("captured body = %q", body)
+		}
+		return false
+	})
+
+	result, err := tool.Execute(map[string]interface{}{
+		"action":     "replay",
+		"request_id": id,
+		"modifications": map[string]interface{}{
+			"headers": map[string]interface{}{"X-Mode": "replayed"},
+			"body":    "bye",
+		},
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	resp := result.(map[string]interface{})["response"].(*CapturedResponse)
+	if resp.Body != "bye?n=1" || resp.Headers["X-Mode"][0] != "replayed" {
+		t.Errorf("unexpected replay response %+v", resp)
+	}
+	if addr := resp.Headers["X-Remote-Addr"][0]; addr != "203.0.113.7:41000" {
+		t.Errorf("expected the replay to keep the original client, got %q", addr)
+	}
+
+	result, err = tool.Execute(map[string]interface{}{
+		"action":        "replay",
+		"request_id":    id,
+		"modifications": map[string]interface{}{"path": "/v2/echo"},
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if r := result.(map[string]interface{}); r["response"].(*CapturedResponse).Status != http.StatusCreated || r["status_changed"] != true {
+		t.Errorf("route override was not applied: %v", r)
+	}
+
+	count := 0
+	tool.captures.Range(func(key, value interface{}) bool { count++; return true })
+	if count != 3 {
+		t.Errorf("expected replayed requests to be captured, got %d captures", count)
+	}
+}
+
+func TestRequestDebuggerReplayStripsCredentials(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	tool := &RequestDebuggerTool{server: srv}
+	srv.AddMiddleware("*", RequestCaptureMiddleware(tool))
+	for _, pattern := range []string{"POST /orders", "DELETE /orders", "POST /admin"} {
+		srv.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s|%s", r.Header.Get("Authorization"), r.URL.Query().Get("token"))
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/orders?token=s3cret", strings.NewReader("{}"))
+	req.Header.Set("Authorization", "Bearer user-token")
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	var id string
+	tool.captures.Range(func(key, value interface{}) bool { id = key.(string); return false })
+
+	tests := []struct {
+		name          string
+		modifications map[string]interface{}
+		want          string
+	}{
+		{"unmodified", nil, "Bearer user-token|s3cret"},
+		{"body only", map[string]interface{}{"body": `{"n":1}`}, "Bearer user-token|s3cret"},
+		{"path", map[string]interface{}{"path": "/admin"}, "|" + redactedValue},
+		{"method", map[string]interface{}{"method": "DELETE"}, "|" + redactedValue},
+		{"headers", map[string]interface{}{"headers": map[string]interface{}{"X-Mode": "test"}}, "|" + redactedValue},
+		{"path with opt-in", map[string]interface{}{"path": "/admin", "keep_credentials": true}, "Bearer user-token|s3cret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Execute(map[string]interface{}{
+				"action":        "replay",
+				"request_id":    id,
+				"modifications": tt.modifications,
+			})
+			if err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			if body := result.(map[string]interface{})["response"].(*CapturedResponse).Body; body != tt.want {
+				t.Errorf("expected the handler to see %q, got %q", tt.want, body)
+			}
+		})
+	}
+}
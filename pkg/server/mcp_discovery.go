@@ -0,0 +1,226 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MCPNamespaceDiscovery describes how a namespace appears in the discovery document.
+type MCPNamespaceDiscovery struct {
+	Description string           `json:"description,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`   // OpenAPI-style tags applied to every tool of the namespace
+	Policy      *DiscoveryPolicy `json:"policy,omitempty"` // Overrides MCPDiscoveryPolicy; nil inherits it
+}
+
+// MCPDiscoveryNamespace lists a namespace in the discovery document. Tools and
+// resources are only listed when the namespace's policy allows it; the counts are
+// always present.
+type MCPDiscoveryNamespace struct {
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description,omitempty"`
+	Tags          []string               `json:"tags,omitempty"`
+	ToolCount     int                    `json:"toolCount"`
+	ResourceCount int                    `json:"resourceCount"`
+	Tools         []MCPDiscoveryTool     `json:"tools,omitempty"`
+	Resources     []MCPDiscoveryResource `json:"resources,omitempty"`
+}
+
+// MCPDiscoveryTool describes a tool in a namespace listing.
+type MCPDiscoveryTool struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// MCPDiscoveryResource describes a resource in a namespace listing.
+type MCPDiscoveryResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// mcpNamespaceOf returns the namespace of a prefixed tool name or resource URI
+// (mcp__<namespace>__<name>), or "" for names registered without a namespace.
+func mcpNamespaceOf(name string) string {
+	rest, ok := strings.CutPrefix(name, "mcp__")
+	if !ok {
+		return ""
+	}
+	ns, _, ok := strings.Cut(rest, "__")
+	if !ok {
+		return ""
+	}
+	return ns
+}
+
+// namespaceDiscovery returns the discovery settings of a namespace. Settings passed to
+// RegisterMCPNamespace are overridden by those of WithMCPNamespaceDiscoveryPolicy and
+// WithMCPNamespaceInfo.
+func (srv *Server) namespaceDiscovery(namespace string) MCPNamespaceDiscovery {
+	var d MCPNamespaceDiscovery
+	if srv.mcpHandler != nil {
+		srv.mcpHandler.registryMu.RLock()
+		if ns := srv.mcpHandler.namespaces[namespace]; ns != nil {
+			d = MCPNamespaceDiscovery{Description: ns.Description, Tags: ns.Tags, Policy: ns.DiscoveryPolicy}
+		}
+		srv.mcpHandler.registryMu.RUnlock()
+	}
+	if cfg, ok := srv.Options.MCPNamespaceDiscovery[namespace]; ok {
+		if cfg.Description != "" {
+			d.Description = cfg.Description
+		}
+		if len(cfg.Tags) > 0 {
+			d.Tags = cfg.Tags
+		}
+		if cfg.Policy != nil {
+			d.Policy = cfg.Policy
+		}
+	}
+	return d
+}
+
+// discoveryPolicyFor returns the policy that applies to the tools and resources of a
+// namespace, and whether the namespace overrides the server-wide policy.
+func (srv *Server) discoveryPolicyFor(namespace string) (DiscoveryPolicy, bool) {
+	if namespace != "" {
+		if d := srv.namespaceDiscovery(namespace); d.Policy != nil {
+			return *d.Policy, true
+		}
+	}
+	return srv.Options.current().MCPDiscoveryPolicy, false
+}
+
+// discoveryHidden reports whether a namespace is hidden from discovery entirely, counts
+// included. A server-wide DiscoveryNone keeps the counts, as it always has.
+func (srv *Server) discoveryHidden(namespace string) bool {
+	policy, override := srv.discoveryPolicyFor(namespace)
+	return override && policy == DiscoveryNone
+}
+
+// discoveryListAllowed reports whether policy allows names to be listed for r.
+func discoveryListAllowed(policy DiscoveryPolicy, r *http.Request) bool {
+	switch policy {
+	case DiscoveryNone, DiscoveryCount:
+		return false
+	case DiscoveryAuthenticated:
+		return r.Header.Get("Authorization") != ""
+	default:
+		return true
+	}
+}
+
+// discoveryToolTags returns the tags of a tool: those of its namespace followed by the
+// ones the tool reports through a Tags() []string method.
+func discoveryToolTags(namespaceTags []string, tool MCPTool) []string {
+	tags := append([]string(nil), namespaceTags...)
+	if tagged, ok := tool.(interface{ Tags() []string }); ok {
+		for _, tag := range tagged.Tags() {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// buildDiscoveryNamespaces lists the namespaces visible to r, sorted by name. If only
+// is set, just that namespace is listed.
+func (srv *Server) buildDiscoveryNamespaces(r *http.Request, only string) []MCPDiscoveryNamespace {
+	h := srv.mcpHandler
+	byName := make(map[string]*MCPDiscoveryNamespace)
+	entry := func(name string) *MCPDiscoveryNamespace {
+		if e := byName[name]; e != nil {
+			return e
+		}
+		d := srv.namespaceDiscovery(name)
+		e := &MCPDiscoveryNamespace{Name: name, Description: d.Description, Tags: d.Tags}
+		byName[name] = e
+		return e
+	}
+	visible := func(name string) bool {
+		return name != "" && (only == "" || name == only) && !srv.discoveryHidden(name)
+	}
+
+	tools := h.GetRegisteredTools()
+	sort.Strings(tools)
+	for _, name := range tools {
+		ns := mcpNamespaceOf(name)
+		if !visible(ns) {
+			continue
+		}
+		e := entry(ns)
+		e.ToolCount++
+		policy, _ := srv.discoveryPolicyFor(ns)
+		if !discoveryListAllowed(policy, r) || !srv.shouldExposeToolInDiscovery(name, r) {
+			continue
+		}
+		tool, ok := h.GetToolByName(name)
+		if !ok {
+			continue
+		}
+		e.Tools = append(e.Tools, MCPDiscoveryTool{
+			Name:        name,
+			Description: tool.Description(),
+			Tags:        discoveryToolTags(e.Tags, tool),
+		})
+	}
+
+	resources := h.GetRegisteredResources()
+	sort.Strings(resources)
+	for _, uri := range resources {
+		ns := mcpNamespaceOf(uri)
+		if !visible(ns) {
+			continue
+		}
+		e := entry(ns)
+		e.ResourceCount++
+		policy, _ := srv.discoveryPolicyFor(ns)
+		if !discoveryListAllowed(policy, r) {
+			continue
+		}
+		h.registryMu.RLock()
+		resource, ok := h.resources[uri]
+		h.registryMu.RUnlock()
+		if !ok {
+			continue
+		}
+		e.Resources = append(e.Resources, MCPDiscoveryResource{
+			URI:         uri,
+			Name:        resource.Name(),
+			Description: resource.Description(),
+		})
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	namespaces := make([]MCPDiscoveryNamespace, 0, len(names))
+	for _, name := range names {
+		namespaces = append(namespaces, *byName[name])
+	}
+	return namespaces
+}
+
+// discoveryTags returns the tags used by the listed namespaces and tools, sorted.
+func discoveryTags(namespaces []MCPDiscoveryNamespace) []string {
+	var tags []string
+	add := func(list []string) {
+		for _, tag := range list {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	for _, ns := range namespaces {
+		add(ns.Tags)
+		for _, tool := range ns.Tools {
+			add(tool.Tags)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
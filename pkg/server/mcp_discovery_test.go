@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+type taggedTool struct {
+	TestTool
+	tags []string
+}
+
+func (t *taggedTool) Tags() []string { return t.tags }
+
+func newNamespacedDiscoveryServer(t *testing.T, opts ...ServerOptionFunc) *Server {
+	t.Helper()
+	srv, err := NewServer(append([]ServerOptionFunc{WithMCPSupport("test", "1.0.0")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = srv.RegisterMCPNamespace("blog",
+		WithNamespaceDescription("Publish and search blog posts"),
+		WithNamespaceTags("content"),
+		WithNamespaceTools(
+			&TestTool{name: "publish", description: "Publish a post"},
+			&taggedTool{TestTool: TestTool{name: "search", description: "Search posts"}, tags: []string{"search"}},
+		),
+		WithNamespaceResources(&TestResource{uri: "posts://recent", name: "Recent posts"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = srv.RegisterMCPNamespace("ops",
+		WithNamespaceDiscoveryPolicy(DiscoveryCount),
+		WithNamespaceTools(&TestTool{name: "restart", description: "Restart workers"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func discoveryNamespace(info MCPDiscoveryInfo, name string) *MCPDiscoveryNamespace {
+	for i := range info.Namespaces {
+		if info.Namespaces[i].Name == name {
+			return &info.Namespaces[i]
+		}
+	}
+	return nil
+}
+
+func TestDiscoveryNamespaces(t *testing.T) {
+	srv := newNamespacedDiscoveryServer(t, WithMCPNamespaceDiscoveryPolicy("hyperserve", DiscoveryNone))
+
+	info := srv.buildDiscoveryInfo(httptest.NewRequest(http.MethodGet, "/.well-known/mcp.json", nil))
+
+	blog := discoveryNamespace(info, "blog")
+	if blog == nil {
+		t.Fatalf("expected blog namespace, got %+v", info.Namespaces)
+	}
+	if blog.Description != "Publish and search blog posts" || blog.ToolCount != 2 || blog.ResourceCount != 1 {
+		t.Errorf("unexpected blog listing %+v", blog)
+	}
+	if len(blog.Tools) != 2 || blog.Tools[1].Name != "mcp__blog__search" ||
+		!slices.Equal(blog.Tools[1].Tags, []string{"content", "search"}) {
+		t.Errorf("unexpected blog tools %+v", blog.Tools)
+	}
+	if len(blog.Resources) != 1 || blog.Resources[0].URI != "mcp__blog__posts://recent" {
+		t.Errorf("unexpected blog resources %+v", blog.Resources)
+	}
+
+	ops := discoveryNamespace(info, "ops")
+	if ops == nil || ops.ToolCount != 1 || len(ops.Tools) != 0 {
+		t.Errorf("expected ops to show counts only, got %+v", ops)
+	}
+	if discoveryNamespace(info, "hyperserve") != nil {
+		t.Error("expected the hyperserve namespace to be hidden")
+	}
+
+	available, _ := info.Capabilities["tools"].(map[string]interface{})["available"].([]string)
+	if slices.Contains(available, "mcp__ops__restart") || !slices.Contains(available, "mcp__blog__publish") {
+		t.Errorf("namespace policies not applied to available tools: %v", available)
+	}
+	for _, name := range available {
+		if mcpNamespaceOf(name) == "hyperserve" {
+			t.Errorf("hidden namespace tool %q listed", name)
+		}
+	}
+	if !slices.Equal(info.Tags, []string{"content", "search"}) {
+		t.Errorf("unexpected tags %v", info.Tags)
+	}
+}
+
+func TestDiscoveryNamespaceOverride(t *testing.T) {
+	// Server options override the settings passed at registration.
+	srv := newNamespacedDiscoveryServer(t,
+		WithMCPDiscoveryPolicy(DiscoveryNone),
+		WithMCPNamespaceDiscoveryPolicy("blog", DiscoveryAuthenticated),
+		WithMCPNamespaceInfo("blog", "The blog", "public"),
+	)
+
+	anonymous := srv.buildDiscoveryInfo(httptest.NewRequest(http.MethodGet, "/mcp/discover", nil))
+	if blog := discoveryNamespace(anonymous, "blog"); blog == nil || len(blog.Tools) != 0 || blog.Description != "The blog" {
+		t.Errorf("expected blog counts without names, got %+v", blog)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/mcp/discover?namespace=blog", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	authed := srv.buildDiscoveryInfo(req)
+	if len(authed.Namespaces) != 1 || len(authed.Namespaces[0].Tools) != 2 {
+		t.Fatalf("expected only the blog namespace with tools, got %+v", authed.Namespaces)
+	}
+	if authed.Capabilities["tools"].(map[string]interface{})["count"] != 2 {
+		t.Errorf("expected counts limited to the namespace, got %v", authed.Capabilities["tools"])
+	}
+}
+
+func TestDiscoveryEndpointNamespaces(t *testing.T) {
+	srv := newNamespacedDiscoveryServer(t)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/mcp.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["namespaces"].([]interface{}); !ok {
+		t.Errorf("expected namespaces in the discovery document, got %v", doc)
+	}
+	tools := doc["capabilities"].(map[string]interface{})["tools"].(map[string]interface{})
+	if tools["description"] == "" || tools["description"] == nil {
+		t.Errorf("expected a tools capability description, got %v", tools)
+	}
+}
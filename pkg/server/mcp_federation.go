@@ -0,0 +1,346 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MCPUpstreamConfig describes an upstream MCP server whose tools and resources are
+// mounted under Namespace. Set either URL for the Streamable HTTP transport or Command
+// to start a stdio server.
+type MCPUpstreamConfig struct {
+	Namespace string            `json:"namespace"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"` // Sent with every HTTP request, e.g. Authorization
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+}
+
+func (c MCPUpstreamConfig) validate() error {
+	if c.Namespace == "" {
+		return fmt.Errorf("MCP upstream requires a namespace")
+	}
+	if (c.URL == "") == (c.Command == "") {
+		return fmt.Errorf("MCP upstream %q requires either a URL or a command", c.Namespace)
+	}
+	return nil
+}
+
+// client creates a client for the upstream. HTTP upstreams are reached through hc.
+func (c MCPUpstreamConfig) client(hc *http.Client) (*MCPClient, error) {
+	if c.Command != "" {
+		return NewMCPCommandClient(c.Command, c.Args)
+	}
+	opts := []MCPClientOption{WithMCPClientHTTPClient(hc)}
+	for k, v := range c.Headers {
+		opts = append(opts, WithMCPClientHeader(k, v))
+	}
+	return NewMCPHTTPClient(c.URL, opts...), nil
+}
+
+// WithMCPUpstream mounts the tools and resources of an upstream MCP server under
+// namespace, so that clients reach several services through a single MCP endpoint. The
+// upstream is connected when the server starts, through Server.HTTPClient for URL
+// upstreams, and retried until it answers; its tools are called as
+// mcp__<namespace>__<tool> and its resources read as mcp__<namespace>__<uri>.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("gateway", "1.0.0"),
+//		server.WithMCPUpstream(server.MCPUpstreamConfig{Namespace: "billing", URL: "http://billing:8080/mcp"}),
+//		server.WithMCPUpstream(server.MCPUpstreamConfig{Namespace: "git", Command: "mcp-git", Args: []string{"--repo", "."}}),
+//	)
+func WithMCPUpstream(cfg MCPUpstreamConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		srv.Options.MCPUpstreams = append(srv.Options.MCPUpstreams, cfg)
+		return nil
+	}
+}
+
+// mcpUpstream is a mounted upstream server and the names registered for it.
+type mcpUpstream struct {
+	client    *MCPClient
+	tools     []string // Prefixed tool names
+	resources []string // Prefixed resource URIs
+}
+
+// mcpFederation tracks the mounted upstream servers by namespace.
+type mcpFederation struct {
+	mu        sync.Mutex
+	upstreams map[string]*mcpUpstream
+	mounting  map[string]*sync.Mutex // Serializes the mounts of each namespace
+}
+
+// lock serializes mounts of namespace and returns the function releasing it.
+func (f *mcpFederation) lock(namespace string) func() {
+	f.mu.Lock()
+	if f.mounting == nil {
+		f.mounting = make(map[string]*sync.Mutex)
+	}
+	mu, ok := f.mounting[namespace]
+	if !ok {
+		mu = new(sync.Mutex)
+		f.mounting[namespace] = mu
+	}
+	f.mu.Unlock()
+	mu.Lock()
+	return mu.Unlock
+}
+
+// mounted returns the upstream mounted under namespace, or nil.
+func (f *mcpFederation) mounted(namespace string) *mcpUpstream {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.upstreams[namespace]
+}
+
+// MountMCPUpstream registers the tools and resources of the server behind client under
+// namespace. The client is initialized if needed and is closed when the upstream is
+// unmounted. Mounting a namespace again replaces its registrations, and the upstream is
+// remounted when it announces that its tools or resources changed. Mounting fails if a
+// tool or resource of the upstream would replace one registered by other means.
+func (srv *Server) MountMCPUpstream(ctx context.Context, namespace string, client *MCPClient) error {
+	if !srv.MCPEnabled() {
+		return fmt.Errorf("MCP is not enabled on this server")
+	}
+	if namespace == "" {
+		return fmt.Errorf("MCP upstream requires a namespace")
+	}
+	unlock := srv.federation.lock(namespace)
+	defer unlock()
+	return srv.mountMCPUpstream(ctx, namespace, client)
+}
+
+// mountMCPUpstream mounts client under namespace. The caller serializes mounts of the
+// namespace.
+func (srv *Server) mountMCPUpstream(ctx context.Context, namespace string, client *MCPClient) error {
+	if client.ServerInfo() == (MCPServerInfo{}) {
+		if _, err := client.Initialize(ctx); err != nil {
+			return fmt.Errorf("failed to initialize MCP upstream %q: %w", namespace, err)
+		}
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tools of MCP upstream %q: %w", namespace, err)
+	}
+	resources, err := client.ListResources(ctx)
+	var rpcErr *JSONRPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == ErrorCodeMethodNotFound {
+		resources, err = nil, nil // Upstreams need not offer resources
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list resources of MCP upstream %q: %w", namespace, err)
+	}
+
+	h := srv.mcpHandler
+	prev := srv.federation.mounted(namespace)
+	owned := make(map[string]bool)
+	if prev != nil {
+		for _, name := range prev.tools {
+			owned[name] = true
+		}
+		for _, uri := range prev.resources {
+			owned[uri] = true
+		}
+	}
+	up := &mcpUpstream{client: client}
+	for _, info := range tools {
+		name := h.formatToolName(namespace, info.Name)
+		if _, exists := h.GetToolByName(name); exists && !owned[name] {
+			return fmt.Errorf("MCP upstream %q: tool %q is already registered", namespace, name)
+		}
+		up.tools = append(up.tools, name)
+	}
+	for _, info := range resources {
+		uri := h.formatResourceName(namespace, info.URI)
+		if h.hasResource(uri) && !owned[uri] {
+			return fmt.Errorf("MCP upstream %q: resource %q is already registered", namespace, uri)
+		}
+		up.resources = append(up.resources, uri)
+	}
+	for i, info := range tools {
+		h.addTool(up.tools[i], &upstreamTool{client: client, info: info}, true)
+	}
+	for _, info := range resources {
+		h.RegisterResourceInNamespace(&upstreamResource{client: client, info: info}, namespace)
+	}
+
+	srv.federation.mu.Lock()
+	if srv.federation.upstreams == nil {
+		srv.federation.upstreams = make(map[string]*mcpUpstream)
+	}
+	srv.federation.upstreams[namespace] = up
+	srv.federation.mu.Unlock()
+	if prev != nil {
+		srv.removeUpstreamRegistrations(prev, up)
+		if prev.client != client {
+			prev.client.Close()
+		}
+	}
+
+	client.OnNotification(func(method string, _ interface{}) {
+		if method != "notifications/tools/list_changed" && method != "notifications/resources/list_changed" {
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			unlock := srv.federation.lock(namespace)
+			defer unlock()
+			if up := srv.federation.mounted(namespace); up == nil || up.client != client {
+				return // Unmounted or replaced meanwhile
+			}
+			if err := srv.mountMCPUpstream(ctx, namespace, client); err != nil {
+				logger.Warn("Failed to refresh MCP upstream", "namespace", namespace, "error", err)
+			}
+		}()
+	})
+	logger.Info("MCP upstream mounted", "namespace", namespace, "server", client.ServerInfo().Name,
+		"tools", len(up.tools), "resources", len(up.resources))
+	return nil
+}
+
+// UnmountMCPUpstream removes the tools and resources mounted under namespace and closes
+// the upstream client. It reports whether the namespace was mounted.
+func (srv *Server) UnmountMCPUpstream(namespace string) bool {
+	unlock := srv.federation.lock(namespace)
+	defer unlock()
+	srv.federation.mu.Lock()
+	up := srv.federation.upstreams[namespace]
+	delete(srv.federation.upstreams, namespace)
+	srv.federation.mu.Unlock()
+	if up == nil {
+		return false
+	}
+	srv.removeUpstreamRegistrations(up, nil)
+	up.client.OnNotification(nil)
+	up.client.Close()
+	return true
+}
+
+// removeUpstreamRegistrations unregisters the names of prev that next does not register.
+func (srv *Server) removeUpstreamRegistrations(prev, next *mcpUpstream) {
+	keep := make(map[string]bool)
+	if next != nil {
+		for _, name := range next.tools {
+			keep[name] = true
+		}
+		for _, uri := range next.resources {
+			keep[uri] = true
+		}
+	}
+	for _, name := range prev.tools {
+		if !keep[name] {
+			srv.mcpHandler.UnregisterTool(name)
+		}
+	}
+	for _, uri := range prev.resources {
+		if !keep[uri] {
+			srv.mcpHandler.UnregisterResource(uri)
+		}
+	}
+}
+
+// mountMCPUpstreams connects the configured upstreams, retrying each with backoff until
+// it is mounted or ctx ends.
+func (srv *Server) mountMCPUpstreams(ctx context.Context) {
+	for _, cfg := range srv.Options.MCPUpstreams {
+		if err := cfg.validate(); err != nil {
+			logger.Error("Invalid MCP upstream", "error", err)
+			continue
+		}
+		go func(cfg MCPUpstreamConfig) {
+			for delay := time.Second; ; delay = min(2*delay, time.Minute) {
+				client, err := cfg.client(srv.HTTPClient())
+				if err == nil {
+					attemptCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+					err = srv.MountMCPUpstream(attemptCtx, cfg.Namespace, client)
+					cancel()
+					if err == nil {
+						return
+					}
+					client.Close()
+				}
+				logger.Warn("MCP upstream unavailable", "namespace", cfg.Namespace, "error", err, "retry_in", delay)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+			}
+		}(cfg)
+	}
+}
+
+// closeMCPUpstreams closes the clients of all mounted upstreams.
+func (srv *Server) closeMCPUpstreams() {
+	srv.federation.mu.Lock()
+	namespaces := make([]string, 0, len(srv.federation.upstreams))
+	for ns := range srv.federation.upstreams {
+		namespaces = append(namespaces, ns)
+	}
+	srv.federation.mu.Unlock()
+	for _, ns := range namespaces {
+		srv.UnmountMCPUpstream(ns)
+	}
+}
+
+// upstreamTool forwards calls to a tool of an upstream server.
+type upstreamTool struct {
+	client *MCPClient
+	info   MCPToolInfo
+}
+
+func (t *upstreamTool) Name() string        { return t.info.Name }
+func (t *upstreamTool) Description() string { return t.info.Description }
+
+func (t *upstreamTool) Schema() map[string]interface{} {
+	if t.info.InputSchema == nil {
+		return map[string]interface{}{"type": "object"}
+	}
+	return t.info.InputSchema
+}
+
+func (t *upstreamTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.ExecuteWithContext(context.Background(), params)
+}
+
+func (t *upstreamTool) ExecuteWithContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	result, err := t.client.CallTool(ctx, t.info.Name, params)
+	if err != nil {
+		return nil, err
+	}
+	if result.IsError {
+		return nil, errors.New(result.Text())
+	}
+	return map[string]interface{}{"content": result.Content}, nil
+}
+
+// upstreamResource reads a resource of an upstream server. Its contents, including binary
+// ones, are passed through as returned. Reads are not cached, since the upstream caches
+// them itself.
+type upstreamResource struct {
+	client *MCPClient
+	info   MCPResourceInfo
+}
+
+func (r *upstreamResource) URI() string             { return r.info.URI }
+func (r *upstreamResource) Name() string            { return r.info.Name }
+func (r *upstreamResource) Description() string     { return r.info.Description }
+func (r *upstreamResource) MimeType() string        { return r.info.MimeType }
+func (r *upstreamResource) List() ([]string, error) { return []string{r.info.URI}, nil }
+func (r *upstreamResource) CacheTTL() time.Duration { return 0 }
+
+func (r *upstreamResource) Read() (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return r.client.ReadResource(ctx, r.info.URI)
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newUpstreamMCPServer starts an MCP server over HTTP with an echo tool and a resource.
+func newUpstreamMCPServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	upstream, err := NewServer(WithAddr(":0"), WithMCPSupport("billing", "2.0.0"), WithMCPBuiltinTools(false), WithMCPBuiltinResources(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream.RegisterMCPTool(NewTool("echo").
+		WithDescription("Echo a message").
+		WithParameter("message", "string", "Message to echo", true).
+		WithExecute(func(params map[string]interface{}) (interface{}, error) {
+			return "billing: " + params["message"].(string), nil
+		}).Build())
+	upstream.RegisterMCPResource(NewResource("invoices://open").
+		WithName("Open invoices").
+		WithRead(func() (interface{}, error) { return "3 open", nil }).Build())
+	ts := httptest.NewServer(upstream.Handler())
+	t.Cleanup(ts.Close)
+	return upstream, ts
+}
+
+func TestMountMCPUpstream(t *testing.T) {
+	upstream, ts := newUpstreamMCPServer(t)
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("gateway", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	client := NewMCPHTTPClient(ts.URL + "/mcp")
+	if err := srv.MountMCPUpstream(ctx, "billing", client); err != nil {
+		t.Fatal(err)
+	}
+	if client.ServerInfo().Name != "billing" {
+		t.Errorf("unexpected upstream info %+v", client.ServerInfo())
+	}
+
+	h := srv.mcpHandler
+	result, err := h.handleToolsCall(map[string]interface{}{
+		"name":      "mcp__billing__echo",
+		"arguments": map[string]interface{}{"message": "hi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := result.(map[string]interface{})["content"].([]map[string]interface{})
+	if content[0]["text"] != "billing: hi" {
+		t.Errorf("unexpected result %v", content)
+	}
+	read, err := h.handleResourcesRead(map[string]interface{}{"uri": "mcp__billing__invoices://open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(read); !strings.Contains(string(data), "3 open") {
+		t.Errorf("unexpected resource %v", read)
+	}
+
+	// Tools added upstream appear after a remount
+	upstream.RegisterMCPTool(NewTool("refund").WithExecute(func(map[string]interface{}) (interface{}, error) { return "ok", nil }).Build())
+	upstream.mcpHandler.UnregisterTool("echo")
+	if err := srv.MountMCPUpstream(ctx, "billing", client); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.GetToolByName("mcp__billing__refund"); !ok {
+		t.Error("new upstream tool was not mounted")
+	}
+	if _, ok := h.GetToolByName("mcp__billing__echo"); ok {
+		t.Error("removed upstream tool is still mounted")
+	}
+
+	if !srv.UnmountMCPUpstream("billing") || srv.UnmountMCPUpstream("billing") {
+		t.Error("expected a single unmount")
+	}
+	if _, ok := h.GetToolByName("mcp__billing__refund"); ok {
+		t.Error("tool is still mounted after unmount")
+	}
+}
+
+func TestMCPStdioClient(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "stdio-upstream", Version: "1.0.0"})
+	h.RegisterTool(NewTool("fail").WithExecute(func(map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	}).Build())
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	go func() {
+		transport := NewStdioTransportWithIO(serverIn, serverOut, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		for h.ProcessRequestWithTransport(transport) == nil {
+		}
+		serverOut.Close()
+	}()
+
+	client := NewMCPStdioClient(clientIn, clientOut)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil || len(tools) != 1 || tools[0].Name != "fail" {
+		t.Fatalf("unexpected tools %v: %v", tools, err)
+	}
+	var rpcErr *JSONRPCError
+	if _, err := client.CallTool(ctx, "missing", nil); !errors.As(err, &rpcErr) {
+		t.Errorf("expected a JSON-RPC error, got %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx); !errors.Is(err, ErrMCPClientClosed) {
+		t.Errorf("expected ErrMCPClientClosed, got %v", err)
+	}
+}
+
+func TestMCPUpstreamUsesSharedClient(t *testing.T) {
+	_, ts := newUpstreamMCPServer(t)
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("gateway", "1.0.0"),
+		WithMCPUpstream(MCPUpstreamConfig{Namespace: "billing", URL: ts.URL + "/mcp"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv.mountMCPUpstreams(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := srv.mcpHandler.GetToolByName("mcp__billing__echo"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("upstream was not mounted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if srv.HTTPClientStats().Requests == 0 {
+		t.Error("expected the upstream to be reached through the shared client")
+	}
+	srv.UnmountMCPUpstream("billing")
+}
+
+func TestMountMCPUpstreamPassesContentsThrough(t *testing.T) {
+	upstream, ts := newUpstreamMCPServer(t)
+	upstream.RegisterMCPResource(NewResource("files://logo").
+		WithRead(func() (interface{}, error) {
+			return []MCPResourceContent{
+				{URI: "files://logo", MimeType: "image/png", Blob: "iVBORw0KGgo="},
+				{URI: "files://logo.txt", MimeType: "text/plain", Text: "alt text"},
+			}, nil
+		}).Build())
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("gateway", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.MountMCPUpstream(context.Background(), "billing", NewMCPHTTPClient(ts.URL+"/mcp")); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.UnmountMCPUpstream("billing")
+
+	read, err := srv.mcpHandler.handleResourcesRead(map[string]interface{}{"uri": "mcp__billing__files://logo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := read.(map[string]interface{})["contents"].([]MCPResourceContent)
+	if len(contents) != 2 || contents[0].Blob != "iVBORw0KGgo=" || contents[0].Text != nil || contents[1].Text != "alt text" {
+		t.Errorf("unexpected contents %+v", contents)
+	}
+}
+
+func TestMountMCPUpstreamConflicts(t *testing.T) {
+	_, ts := newUpstreamMCPServer(t)
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("gateway", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := NewTool("echo").WithExecute(func(map[string]interface{}) (interface{}, error) { return "local", nil }).Build()
+	if err := srv.RegisterMCPToolInNamespace(local, "billing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterMCPToolInNamespace(local, "billing"); err == nil {
+		t.Error("expected registering a tool twice to fail")
+	}
+
+	client := NewMCPHTTPClient(ts.URL + "/mcp")
+	defer client.Close()
+	err = srv.MountMCPUpstream(context.Background(), "billing", client)
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+	if tool, _ := srv.mcpHandler.GetToolByName("mcp__billing__echo"); tool != local {
+		t.Error("expected the local tool to be kept")
+	}
+	if srv.UnmountMCPUpstream("billing") {
+		t.Error("expected the upstream not to be mounted")
+	}
+}
+
+func TestMountMCPUpstreamConcurrently(t *testing.T) {
+	_, ts := newUpstreamMCPServer(t)
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("gateway", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewMCPHTTPClient(ts.URL + "/mcp")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.MountMCPUpstream(context.Background(), "billing", client); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, ok := srv.mcpHandler.GetToolByName("mcp__billing__echo"); !ok {
+		t.Error("expected the upstream tool to be mounted")
+	}
+	if !srv.UnmountMCPUpstream("billing") {
+		t.Error("expected the upstream to be mounted")
+	}
+	if _, ok := srv.mcpHandler.GetToolByName("mcp__billing__echo"); ok {
+		t.Error("expected the upstream tool to be unmounted")
+	}
+}
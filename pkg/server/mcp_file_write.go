@@ -0,0 +1,361 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMCPFileWriteMaxSize limits files written by the file write tools unless
+// MCPFileWriteConfig.MaxFileSize is set.
+const defaultMCPFileWriteMaxSize = 1 << 20
+
+// MCPFileWriteConfig limits the write_file, append_file, and delete_file tools.
+type MCPFileWriteConfig struct {
+	MaxFileSize       int64    `json:"max_file_size,omitempty"`      // Bytes a file may have after a write (default 1 MiB)
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"` // Such as ".md" or "json"; empty allows all
+	DryRun            bool     `json:"dry_run,omitempty"`            // Validate and report changes without applying them
+}
+
+// WithMCPFileWriteEnabled registers the write_file, append_file, and delete_file tools in
+// the hyperserve namespace. They are confined to the directory set with
+// WithMCPFileToolRoot, which is required, and are subject to the limits of cfg. Each
+// call may also pass "dry_run": true to preview a change.
+//
+// The tools let AI clients modify files, so consider restricting them further with
+// WithMCPToolPolicy.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("app", "1.0.0"),
+//		server.WithMCPFileToolRoot("./content"),
+//		server.WithMCPFileWriteEnabled(server.MCPFileWriteConfig{
+//			AllowedExtensions: []string{".md"},
+//		}),
+//	)
+func WithMCPFileWriteEnabled(cfg ...MCPFileWriteConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		var c MCPFileWriteConfig
+		if len(cfg) > 0 {
+			c = cfg[0]
+		}
+		if c.MaxFileSize < 0 {
+			return fmt.Errorf("MCP file write max size must not be negative")
+		}
+		srv.Options.MCPFileWrite = &c
+		return nil
+	}
+}
+
+// registerFileWriteTools registers the file write tools configured in Options.MCPFileWrite.
+// They share one open root directory, which is closed on shutdown.
+func (srv *Server) registerFileWriteTools() error {
+	if srv.Options.MCPFileToolRoot == "" {
+		return fmt.Errorf("MCP file write tools require a file tool root")
+	}
+	sandbox, err := newFileWriteSandbox(srv.Options.MCPFileToolRoot, *srv.Options.MCPFileWrite)
+	if err != nil {
+		return err
+	}
+	srv.mcpHandler.RegisterToolInNamespace(&FileWriteTool{sandbox: sandbox}, "hyperserve")
+	srv.mcpHandler.RegisterToolInNamespace(&FileAppendTool{sandbox: sandbox}, "hyperserve")
+	srv.mcpHandler.RegisterToolInNamespace(&FileDeleteTool{sandbox: sandbox}, "hyperserve")
+	srv.Options.OnShutdownHooks = append(srv.Options.OnShutdownHooks, func(context.Context) error {
+		return sandbox.root.Close()
+	})
+	logger.Warn("MCP file write tools enabled", "root", srv.Options.MCPFileToolRoot, "dry_run", srv.Options.MCPFileWrite.DryRun)
+	return nil
+}
+
+// fileWriteSandbox confines file changes to a root directory and enforces the limits of
+// an MCPFileWriteConfig.
+type fileWriteSandbox struct {
+	dir        string
+	root       *os.Root
+	maxSize    int64
+	extensions map[string]bool
+	dryRun     bool
+}
+
+func newFileWriteSandbox(rootDir string, cfg MCPFileWriteConfig) (*fileWriteSandbox, error) {
+	if rootDir == "" {
+		return nil, fmt.Errorf("file write tools require a root directory")
+	}
+	root, err := os.OpenRoot(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open root directory: %w", err)
+	}
+	s := &fileWriteSandbox{dir: rootDir, root: root, maxSize: cfg.MaxFileSize, dryRun: cfg.DryRun}
+	if s.maxSize == 0 {
+		s.maxSize = defaultMCPFileWriteMaxSize
+	}
+	if len(cfg.AllowedExtensions) > 0 {
+		s.extensions = make(map[string]bool, len(cfg.AllowedExtensions))
+		for _, ext := range cfg.AllowedExtensions {
+			s.extensions["."+strings.TrimPrefix(strings.ToLower(ext), ".")] = true
+		}
+	}
+	return s, nil
+}
+
+// target validates the path parameter and returns the cleaned path.
+func (s *fileWriteSandbox) target(params map[string]interface{}) (string, error) {
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return "", fmt.Errorf("path parameter is required and must be a string")
+	}
+	path = filepath.Clean(path)
+	if s.extensions != nil && !s.extensions[strings.ToLower(filepath.Ext(path))] {
+		return "", fmt.Errorf("file extension %q is not allowed", filepath.Ext(path))
+	}
+	if info, err := s.root.Stat(path); err == nil && info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	return path, nil
+}
+
+// checkRoots rejects paths outside the client's roots, when the client declared the roots
+// capability.
+func (s *fileWriteSandbox) checkRoots(ctx context.Context, client MCPClientRequester, params map[string]interface{}) error {
+	path, ok := params["path"].(string)
+	if !ok {
+		return nil // Rejected by target
+	}
+	return checkClientRoots(ctx, client, s.dir, filepath.Clean(path))
+}
+
+// existingSize returns the size of the file at path, or 0 if it does not exist.
+func (s *fileWriteSandbox) existingSize(path string) (int64, bool, error) {
+	info, err := s.root.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return info.Size(), true, nil
+}
+
+// dryRunRequested reports whether the call must not change files.
+func (s *fileWriteSandbox) dryRunRequested(params map[string]interface{}) bool {
+	dryRun, _ := params["dry_run"].(bool)
+	return s.dryRun || dryRun
+}
+
+// writeContent writes content to path, appending if flag contains os.O_APPEND.
+func (s *fileWriteSandbox) writeContent(params map[string]interface{}, flag int, action string) (interface{}, error) {
+	path, err := s.target(params)
+	if err != nil {
+		return nil, err
+	}
+	content, ok := params["content"].(string)
+	if !ok {
+		return nil, fmt.Errorf("content parameter is required and must be a string")
+	}
+	size, exists, err := s.existingSize(path)
+	if err != nil {
+		return nil, err
+	}
+	newSize := int64(len(content))
+	if flag&os.O_APPEND != 0 {
+		newSize += size
+	}
+	if newSize > s.maxSize {
+		return nil, fmt.Errorf("file would be %d bytes, which exceeds the limit of %d bytes", newSize, s.maxSize)
+	}
+
+	result := map[string]interface{}{
+		"path":    path,
+		"action":  action,
+		"bytes":   len(content),
+		"size":    newSize,
+		"created": !exists,
+	}
+	if s.dryRunRequested(params) {
+		result["dry_run"] = true
+		return result, nil
+	}
+
+	file, err := s.root.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer closeWithLog(file, path)
+	if _, err := file.WriteString(content); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	return result, nil
+}
+
+func fileWriteSchema(pathDescription string, content bool) map[string]interface{} {
+	properties := map[string]interface{}{
+		"path": map[string]interface{}{
+			"type":        "string",
+			"description": pathDescription,
+		},
+		"dry_run": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Report the change without applying it",
+			"default":     false,
+		},
+	}
+	required := []string{"path"}
+	if content {
+		properties["content"] = map[string]interface{}{
+			"type":        "string",
+			"description": "Text to write",
+		}
+		required = append(required, "content")
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// FileWriteTool implements MCPTool for creating or replacing files inside a root directory
+type FileWriteTool struct {
+	sandbox *fileWriteSandbox
+}
+
+// NewFileWriteTool creates a write_file tool confined to rootDir
+func NewFileWriteTool(rootDir string, cfg MCPFileWriteConfig) (*FileWriteTool, error) {
+	sandbox, err := newFileWriteSandbox(rootDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWriteTool{sandbox: sandbox}, nil
+}
+
+func (t *FileWriteTool) Name() string {
+	return "write_file"
+}
+
+func (t *FileWriteTool) Description() string {
+	return "Create a file or replace its contents"
+}
+
+func (t *FileWriteTool) Schema() map[string]interface{} {
+	return fileWriteSchema("Path to the file to write", true)
+}
+
+func (t *FileWriteTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.sandbox.writeContent(params, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, "write")
+}
+
+// ExecuteWithClient writes the file if it lies within the client's roots as well as the
+// root directory, when the client declared the roots capability.
+func (t *FileWriteTool) ExecuteWithClient(ctx context.Context, params map[string]interface{}, client MCPClientRequester) (interface{}, error) {
+	if err := t.sandbox.checkRoots(ctx, client, params); err != nil {
+		return nil, err
+	}
+	return t.Execute(params)
+}
+
+// FileAppendTool implements MCPTool for appending to files inside a root directory
+type FileAppendTool struct {
+	sandbox *fileWriteSandbox
+}
+
+// NewFileAppendTool creates an append_file tool confined to rootDir
+func NewFileAppendTool(rootDir string, cfg MCPFileWriteConfig) (*FileAppendTool, error) {
+	sandbox, err := newFileWriteSandbox(rootDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAppendTool{sandbox: sandbox}, nil
+}
+
+func (t *FileAppendTool) Name() string {
+	return "append_file"
+}
+
+func (t *FileAppendTool) Description() string {
+	return "Append text to a file, creating it if needed"
+}
+
+func (t *FileAppendTool) Schema() map[string]interface{} {
+	return fileWriteSchema("Path to the file to append to", true)
+}
+
+func (t *FileAppendTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.sandbox.writeContent(params, os.O_WRONLY|os.O_CREATE|os.O_APPEND, "append")
+}
+
+// ExecuteWithClient appends to the file if it lies within the client's roots as well as
+// the root directory, when the client declared the roots capability.
+func (t *FileAppendTool) ExecuteWithClient(ctx context.Context, params map[string]interface{}, client MCPClientRequester) (interface{}, error) {
+	if err := t.sandbox.checkRoots(ctx, client, params); err != nil {
+		return nil, err
+	}
+	return t.Execute(params)
+}
+
+// FileDeleteTool implements MCPTool for deleting files inside a root directory
+type FileDeleteTool struct {
+	sandbox *fileWriteSandbox
+}
+
+// NewFileDeleteTool creates a delete_file tool confined to rootDir
+func NewFileDeleteTool(rootDir string, cfg MCPFileWriteConfig) (*FileDeleteTool, error) {
+	sandbox, err := newFileWriteSandbox(rootDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDeleteTool{sandbox: sandbox}, nil
+}
+
+func (t *FileDeleteTool) Name() string {
+	return "delete_file"
+}
+
+func (t *FileDeleteTool) Description() string {
+	return "Delete a file"
+}
+
+func (t *FileDeleteTool) Schema() map[string]interface{} {
+	return fileWriteSchema("Path to the file to delete", false)
+}
+
+func (t *FileDeleteTool) Execute(params map[string]interface{}) (interface{}, error) {
+	path, err := t.sandbox.target(params)
+	if err != nil {
+		return nil, err
+	}
+	size, exists, err := t.sandbox.existingSize(path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+
+	result := map[string]interface{}{
+		"path":   path,
+		"action": "delete",
+		"size":   size,
+	}
+	if t.sandbox.dryRunRequested(params) {
+		result["dry_run"] = true
+		return result, nil
+	}
+	if err := t.sandbox.root.Remove(path); err != nil {
+		return nil, fmt.Errorf("failed to delete file: %w", err)
+	}
+	return result, nil
+}
+
+// ExecuteWithClient deletes the file if it lies within the client's roots as well as the
+// root directory, when the client declared the roots capability.
+func (t *FileDeleteTool) ExecuteWithClient(ctx context.Context, params map[string]interface{}, client MCPClientRequester) (interface{}, error) {
+	if err := t.sandbox.checkRoots(ctx, client, params); err != nil {
+		return nil, err
+	}
+	return t.Execute(params)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileWriteTools(t *testing.T) {
+	dir := t.TempDir()
+	cfg := MCPFileWriteConfig{MaxFileSize: 16, AllowedExtensions: []string{"md"}}
+	write, err := NewFileWriteTool(dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTool, _ := NewFileAppendTool(dir, cfg)
+	deleteTool, _ := NewFileDeleteTool(dir, cfg)
+
+	if _, err := write.Execute(map[string]interface{}{"path": "notes.md", "content": "hello"}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := appendTool.Execute(map[string]interface{}{"path": "notes.md", "content": " world"}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "notes.md")); string(data) != "hello world" {
+		t.Errorf("file contains %q", data)
+	}
+
+	rejected := []map[string]interface{}{
+		{"path": "notes.md", "content": " and more text"}, // exceeds the size limit
+		{"path": "script.sh", "content": "rm -rf /"},      // extension not allowed
+		{"path": "../escape.md", "content": "x"},          // outside the root
+	}
+	for _, params := range rejected {
+		if _, err := appendTool.Execute(params); err == nil {
+			t.Errorf("expected %v to be rejected", params)
+		}
+	}
+
+	result, err := deleteTool.Execute(map[string]interface{}{"path": "notes.md", "dry_run": true})
+	if err != nil || result.(map[string]interface{})["dry_run"] != true {
+		t.Fatalf("dry run failed: %v %v", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.md")); err != nil {
+		t.Fatal("dry run deleted the file")
+	}
+	if _, err := deleteTool.Execute(map[string]interface{}{"path": "notes.md"}); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.md")); !os.IsNotExist(err) {
+		t.Error("file was not deleted")
+	}
+}
+
+func TestFileWriteToolDryRunConfig(t *testing.T) {
+	dir := t.TempDir()
+	write, err := NewFileWriteTool(dir, MCPFileWriteConfig{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := write.Execute(map[string]interface{}{"path": "a.txt", "content": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := result.(map[string]interface{}); r["dry_run"] != true || r["created"] != true {
+		t.Errorf("unexpected result %v", r)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Error("dry run created the file")
+	}
+}
+
+func TestWithMCPFileWriteEnabled(t *testing.T) {
+	if _, err := NewServer(WithMCPSupport("test", "1.0.0"), WithMCPFileWriteEnabled()); err == nil {
+		t.Error("expected an error without a file tool root")
+	}
+
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"), WithMCPFileToolRoot(t.TempDir()), WithMCPFileWriteEnabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"write_file", "append_file", "delete_file"} {
+		if _, ok := srv.mcpHandler.GetToolByName("mcp__hyperserve__" + name); !ok {
+			t.Errorf("%s is not registered", name)
+		}
+	}
+
+	// The tools share one root, which shutdown closes
+	write, _ := srv.mcpHandler.GetToolByName("mcp__hyperserve__write_file")
+	deleteTool, _ := srv.mcpHandler.GetToolByName("mcp__hyperserve__delete_file")
+	if write.(*FileWriteTool).sandbox != deleteTool.(*FileDeleteTool).sandbox {
+		t.Error("expected the file write tools to share a sandbox")
+	}
+	for _, hook := range srv.Options.OnShutdownHooks {
+		hook(context.Background())
+	}
+	if _, err := write.Execute(map[string]interface{}{"path": "a.txt", "content": "x"}); err == nil {
+		t.Error("expected writes to fail after shutdown closed the root")
+	}
+}
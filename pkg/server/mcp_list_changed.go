@@ -0,0 +1,41 @@
+package server
+
+// addListener registers a connected client to receive notifications that concern all
+// clients, such as list changes. Call the returned function when the client disconnects.
+func (h *MCPHandler) addListener(n mcpNotifier) (remove func()) {
+	h.listenersMu.Lock()
+	h.listeners[n] = struct{}{}
+	h.listenersMu.Unlock()
+	return func() {
+		h.listenersMu.Lock()
+		delete(h.listeners, n)
+		h.listenersMu.Unlock()
+	}
+}
+
+// notifyToolsChanged tells connected clients to fetch tools/list again.
+func (h *MCPHandler) notifyToolsChanged() {
+	h.broadcastNotification("notifications/tools/list_changed", nil)
+}
+
+// broadcastNotification sends a notification to the SSE, WebSocket, and stdio clients
+// and to the GET streams of Streamable HTTP sessions.
+func (h *MCPHandler) broadcastNotification(method string, params interface{}) {
+	h.listenersMu.Lock()
+	listeners := make([]mcpNotifier, 0, len(h.listeners))
+	for n := range h.listeners {
+		listeners = append(listeners, n)
+	}
+	h.listenersMu.Unlock()
+
+	for _, n := range listeners {
+		if err := n.Notify(method, params); err != nil {
+			h.logger.Debug("Failed to send MCP notification", "method", method, "error", err)
+		}
+	}
+	for _, session := range h.sessions.all() {
+		if err := session.standaloneStream().Notify(method, params); err != nil {
+			h.logger.Debug("Failed to send MCP notification", "method", method, "session", session.id, "error", err)
+		}
+	}
+}
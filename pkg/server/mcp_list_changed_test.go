@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+func TestToolsListChangedOverWebSocket(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.wsUpgrader = newMCPUpgrader()
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	_, fr, fw := dialGatewayWebSocket(t, ts.URL)
+
+	// The response proves the connection is registered before the tool changes
+	writeClientFrame(t, fw, ws.OpcodeText, []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	readMCPFrame(t, fr)
+
+	h.RegisterTool(&countingTool{})
+	if msg := readMCPFrame(t, fr).(map[string]interface{}); msg["method"] != "notifications/tools/list_changed" {
+		t.Fatalf("expected list_changed after registration, got %v", msg)
+	}
+	if !h.UnregisterTool("count") {
+		t.Fatal("UnregisterTool did not find the tool")
+	}
+	if msg := readMCPFrame(t, fr).(map[string]interface{}); msg["method"] != "notifications/tools/list_changed" {
+		t.Fatalf("expected list_changed after unregistration, got %v", msg)
+	}
+	if _, ok := h.GetToolByName("count"); ok {
+		t.Error("tool is still registered")
+	}
+	if h.UnregisterTool("count") {
+		t.Error("UnregisterTool reported an unknown tool as removed")
+	}
+}
+
+func TestUnregisterMCPTool(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterMCPTool(&countingTool{}); err != nil {
+		t.Fatal(err)
+	}
+	session := srv.mcpHandler.sessions.create(MCPVersion)
+
+	if err := srv.UnregisterMCPTool("count"); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.UnregisterMCPTool("count"); err == nil {
+		t.Error("expected an error for an unknown tool")
+	}
+	if caps := srv.mcpHandler.getCapabilities(); !caps.Tools.ListChanged {
+		t.Error("tools capability does not advertise listChanged")
+	}
+
+	// Sessions receive the notification on their GET stream
+	stream := session.standaloneStream()
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if len(stream.events) != 1 {
+		t.Errorf("expected one queued notification, got %d", len(stream.events))
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// mcpNotifierKey carries the transport of the MCP request being processed, when it can
+// push notifications to the client.
+const mcpNotifierKey contextKey = "mcpNotifier"
+
+// MCPProgressReporter reports the progress of a tool call to the client.
+type MCPProgressReporter interface {
+	// Report sends a notifications/progress message. Progress must increase with every
+	// call; total is 0 when unknown. Reports are dropped when the client did not ask for
+	// progress, the transport cannot push messages (plain HTTP), or the call has returned.
+	Report(progress, total float64, message string)
+}
+
+// MCPToolWithProgress is implemented by long-running tools that report progress, such as
+// file processing or HTTP fetches, so that clients do not see them as hung. Like
+// MCPToolWithContext, the tool must return when ctx is cancelled.
+//
+// Example:
+//
+//	func (t *ImportTool) ExecuteWithProgress(ctx context.Context, params map[string]interface{}, progress server.MCPProgressReporter) (interface{}, error) {
+//		for i, file := range files {
+//			if err := ctx.Err(); err != nil {
+//				return nil, err
+//			}
+//			importFile(file)
+//			progress.Report(float64(i+1), float64(len(files)), "imported "+file)
+//		}
+//		return "done", nil
+//	}
+type MCPToolWithProgress interface {
+	MCPTool
+	ExecuteWithProgress(ctx context.Context, params map[string]interface{}, progress MCPProgressReporter) (interface{}, error)
+}
+
+// MCPRequestMeta is the _meta object of an MCP request.
+type MCPRequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"` // String or number chosen by the client
+}
+
+// MCPProgressParams are the parameters of a notifications/progress message.
+type MCPProgressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// jsonrpcNotification is a JSON-RPC request without an ID, which expects no response.
+type jsonrpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// mcpNotifier is implemented by transports that can send messages to the client outside
+// of a response.
+type mcpNotifier interface {
+	Notify(method string, params interface{}) error
+}
+
+// withMCPNotifier puts the transport into ctx if it can send notifications.
+func withMCPNotifier(ctx context.Context, transport MCPTransport) context.Context {
+	if n, ok := transport.(mcpNotifier); ok {
+		return context.WithValue(ctx, mcpNotifierKey, n)
+	}
+	return ctx
+}
+
+// progressReporter sends progress notifications for one tool call.
+type progressReporter struct {
+	notifier mcpNotifier // nil when progress is not reported
+	token    interface{}
+	logger   *slog.Logger
+
+	mu   sync.Mutex
+	last float64
+	sent bool
+	done bool
+}
+
+func newProgressReporter(ctx context.Context, h *MCPHandler, meta *MCPRequestMeta) *progressReporter {
+	p := &progressReporter{logger: h.logger}
+	if meta == nil || meta.ProgressToken == nil {
+		return p
+	}
+	if n, ok := ctx.Value(mcpNotifierKey).(mcpNotifier); ok {
+		p.notifier, p.token = n, meta.ProgressToken
+	}
+	return p
+}
+
+func (p *progressReporter) Report(progress, total float64, message string) {
+	if p.notifier == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done || (p.sent && progress <= p.last) {
+		return
+	}
+	p.last, p.sent = progress, true
+	err := p.notifier.Notify("notifications/progress", MCPProgressParams{
+		ProgressToken: p.token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+	if err != nil {
+		p.logger.Debug("Failed to send progress notification", "error", err)
+	}
+}
+
+// finish drops reports made after the response, which clients would not expect.
+func (p *progressReporter) finish() {
+	p.mu.Lock()
+	p.done = true
+	p.mu.Unlock()
+}
+
+// Notify writes a notification to stdout.
+func (t *stdioTransport) Notify(method string, params interface{}) error {
+	if err := t.write(jsonrpcNotification{JSONRPC: JSONRPCVersion, Method: method, Params: params}); err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return nil
+}
+
+// Notify queues a notification for the SSE stream.
+func (t *sseTransport) Notify(method string, params interface{}) error {
+	t.sseManager.mu.RLock()
+	client, exists := t.sseManager.clients[t.clientID]
+	t.sseManager.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("client not found: %s", t.clientID)
+	}
+	data, err := json.Marshal(jsonrpcNotification{JSONRPC: JSONRPCVersion, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	select {
+	case client.notifyChan <- data:
+		return nil
+	case <-client.closeChan:
+		return fmt.Errorf("client closed")
+	default:
+		return fmt.Errorf("notification channel full")
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type countingTool struct{}
+
+func (t *countingTool) Name() string        { return "count" }
+func (t *countingTool) Description() string { return "Counts to three" }
+func (t *countingTool) Schema() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *countingTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.ExecuteWithProgress(context.Background(), params, &progressReporter{})
+}
+
+func (t *countingTool) ExecuteWithProgress(ctx context.Context, params map[string]interface{}, progress MCPProgressReporter) (interface{}, error) {
+	progress.Report(1, 3, "one")
+	progress.Report(1, 3, "one again") // not increasing, dropped
+	progress.Report(2, 3, "two")
+	progress.Report(3, 3, "")
+	return "done", nil
+}
+
+func runStdioRequest(t *testing.T, h *MCPHandler, request string) []map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	transport := NewStdioTransportWithIO(strings.NewReader(request+"\n"), &out, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := h.ProcessRequestWithTransport(transport); err != nil {
+		t.Fatalf("ProcessRequestWithTransport: %v", err)
+	}
+	var messages []map[string]interface{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var msg map[string]interface{}
+		if err := dec.Decode(&msg); err != nil {
+			t.Fatalf("decode output: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestMCPToolProgress_Stdio(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&countingTool{})
+
+	messages := runStdioRequest(t, h, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"count","arguments":{},"_meta":{"progressToken":"tok-1"}}}`)
+	if len(messages) != 4 {
+		t.Fatalf("expected 3 notifications and the response, got %d: %v", len(messages), messages)
+	}
+	for i, msg := range messages[:3] {
+		if msg["method"] != "notifications/progress" || msg["id"] != nil {
+			t.Fatalf("message %d is not a progress notification: %v", i, msg)
+		}
+		params := msg["params"].(map[string]interface{})
+		if params["progressToken"] != "tok-1" || params["progress"] != float64(i+1) || params["total"] != float64(3) {
+			t.Errorf("unexpected progress params: %v", params)
+		}
+	}
+	if msg := messages[0]["params"].(map[string]interface{}); msg["message"] != "one" {
+		t.Errorf("expected message %q, got %v", "one", msg["message"])
+	}
+	if messages[3]["id"] != float64(7) || messages[3]["result"] == nil {
+		t.Errorf("expected the tool response last, got %v", messages[3])
+	}
+}
+
+type recordingNotifier struct {
+	params []MCPProgressParams
+}
+
+func (n *recordingNotifier) Notify(method string, params interface{}) error {
+	n.params = append(n.params, params.(MCPProgressParams))
+	return nil
+}
+
+func TestProgressReporter_AfterFinish(t *testing.T) {
+	n := &recordingNotifier{}
+	ctx := context.WithValue(context.Background(), mcpNotifierKey, mcpNotifier(n))
+	p := newProgressReporter(ctx, NewMCPHandler(MCPServerInfo{}), &MCPRequestMeta{ProgressToken: "t"})
+
+	p.Report(0.5, 0, "half")
+	p.finish()
+	p.Report(1, 0, "late")
+
+	if len(n.params) != 1 || n.params[0].Progress != 0.5 {
+		t.Fatalf("expected only the report before finish, got %+v", n.params)
+	}
+}
+
+func TestMCPToolProgress_WithoutToken(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&countingTool{})
+
+	messages := runStdioRequest(t, h, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"count","arguments":{}}}`)
+	if len(messages) != 1 || messages[0]["result"] == nil {
+		t.Fatalf("expected only the response without a progress token, got %v", messages)
+	}
+
+	// Plain HTTP cannot push notifications; the tool still runs
+	req := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"count","arguments":{},"_meta":{"progressToken":5}}}`
+	var resp JSONRPCResponse
+	if err := json.Unmarshal(h.ProcessRequest([]byte(req)), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %+v", resp.Error)
+	}
+}
@@ -0,0 +1,327 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// MCPReplayTarget sends a recorded request to the system under test and returns its response.
+type MCPReplayTarget func(ctx context.Context, request *JSONRPCRequest) (*JSONRPCResponse, error)
+
+// MCPHandlerTarget replays requests against an in-process MCP handler.
+func MCPHandlerTarget(h *MCPHandler) MCPReplayTarget {
+	return func(ctx context.Context, request *JSONRPCRequest) (*JSONRPCResponse, error) {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		var response JSONRPCResponse
+		if err := json.Unmarshal(h.ProcessRequest(data), &response); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &response, nil
+	}
+}
+
+// MCPEndpointTarget replays requests against the MCP endpoint of a running server, e.g.
+// "http://localhost:8080/mcp". A nil client uses http.DefaultClient.
+func MCPEndpointTarget(endpoint string, client *http.Client) MCPReplayTarget {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context, request *JSONRPCRequest) (*JSONRPCResponse, error) {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("MCP endpoint returned status %d", resp.StatusCode)
+		}
+		var response JSONRPCResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &response, nil
+	}
+}
+
+// MCPReplayOptions configures a trace replay.
+type MCPReplayOptions struct {
+	// IgnoreFields lists result keys, at any depth, that are expected to differ between
+	// runs (timestamps, uptimes, generated IDs) and are excluded from the comparison.
+	IgnoreFields []string
+	// StopOnMismatch ends the replay at the first differing response.
+	StopOnMismatch bool
+}
+
+// MCPReplayResult is the outcome of replaying one trace record.
+type MCPReplayResult struct {
+	Line     int              `json:"line"`
+	Method   string           `json:"method"`
+	ID       interface{}      `json:"id,omitempty"`
+	Status   string           `json:"status"` // "match", "mismatch", "error", or "skipped"
+	Detail   string           `json:"detail,omitempty"`
+	Expected *JSONRPCResponse `json:"expected,omitempty"`
+	Actual   *JSONRPCResponse `json:"actual,omitempty"`
+}
+
+// MCPReplayReport summarises a trace replay.
+type MCPReplayReport struct {
+	Total      int               `json:"total"`
+	Matched    int               `json:"matched"`
+	Mismatched int               `json:"mismatched"`
+	Errors     int               `json:"errors"`
+	Skipped    int               `json:"skipped"`
+	Results    []MCPReplayResult `json:"results"`
+}
+
+// OK reports whether every replayed response matched the recording.
+func (r *MCPReplayReport) OK() bool {
+	return r.Mismatched == 0 && r.Errors == 0
+}
+
+// ReplayMCPTraceFile replays a trace file written by WithMCPTrace or the mcp_trace tool.
+func ReplayMCPTraceFile(ctx context.Context, path string, target MCPReplayTarget, opts MCPReplayOptions) (*MCPReplayReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MCP trace: %w", err)
+	}
+	defer file.Close()
+	return ReplayMCPTrace(ctx, file, target, opts)
+}
+
+// ReplayMCPTrace sends each recorded request to target in order and compares the responses
+// with the recording, for regression-testing tool and resource changes. Since traces are
+// redacted, responses are redacted the same way before comparison, and requests whose
+// parameters were redacted are skipped.
+//
+// Example:
+//
+//	report, err := server.ReplayMCPTraceFile(ctx, "mcp-trace.ndjson",
+//		server.MCPEndpointTarget("http://localhost:8080/mcp", nil),
+//		server.MCPReplayOptions{IgnoreFields: []string{"uptime", "timestamp"}})
+func ReplayMCPTrace(ctx context.Context, r io.Reader, target MCPReplayTarget, opts MCPReplayOptions) (*MCPReplayReport, error) {
+	ignore := make(map[string]bool, len(opts.IgnoreFields))
+	for _, field := range opts.IgnoreFields {
+		ignore[field] = true
+	}
+
+	report := &MCPReplayReport{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		var record MCPTraceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return report, fmt.Errorf("line %d: invalid trace record: %w", line, err)
+		}
+		if record.Request == nil {
+			continue
+		}
+
+		result := replayRecord(ctx, target, &record, ignore)
+		result.Line = line
+		report.Total++
+		switch result.Status {
+		case "match":
+			report.Matched++
+		case "mismatch":
+			report.Mismatched++
+		case "error":
+			report.Errors++
+		default:
+			report.Skipped++
+		}
+		report.Results = append(report.Results, result)
+		if opts.StopOnMismatch && result.Status != "match" && result.Status != "skipped" {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read MCP trace: %w", err)
+	}
+	return report, nil
+}
+
+func replayRecord(ctx context.Context, target MCPReplayTarget, record *MCPTraceRecord, ignore map[string]bool) MCPReplayResult {
+	result := MCPReplayResult{Method: record.Request.Method, ID: record.Request.ID, Expected: record.Response}
+	if containsRedacted(record.Request.Params) {
+		result.Status = "skipped"
+		result.Detail = "request parameters were redacted"
+		return result
+	}
+
+	actual, err := target(ctx, record.Request)
+	if err != nil {
+		result.Status = "error"
+		result.Detail = err.Error()
+		return result
+	}
+	result.Actual = actual
+	if record.Response == nil {
+		result.Status = "match"
+		return result
+	}
+
+	if diff := diffReplayResponse(record.Response, actual, ignore); diff != "" {
+		result.Status = "mismatch"
+		result.Detail = diff
+		return result
+	}
+	result.Status = "match"
+	return result
+}
+
+// diffReplayResponse describes the first difference between two responses, or returns ""
+// if they match.
+func diffReplayResponse(expected, actual *JSONRPCResponse, ignore map[string]bool) string {
+	switch {
+	case expected.Error != nil && actual.Error == nil:
+		return fmt.Sprintf("expected error %d %q, got a result", expected.Error.Code, expected.Error.Message)
+	case expected.Error == nil && actual.Error != nil:
+		return fmt.Sprintf("unexpected error %d %q", actual.Error.Code, actual.Error.Message)
+	case expected.Error != nil:
+		if expected.Error.Code != actual.Error.Code || expected.Error.Message != actual.Error.Message {
+			return fmt.Sprintf("expected error %d %q, got %d %q",
+				expected.Error.Code, expected.Error.Message, actual.Error.Code, actual.Error.Message)
+		}
+		return ""
+	}
+
+	// Normalise both sides through the built-in redaction so they are comparable; values
+	// redacted by custom patterns when the trace was written match any value
+	want := stripReplayFields(defaultRedactor.value(expected.Result), ignore)
+	got := stripReplayFields(defaultRedactor.value(actual.Result), ignore)
+	return diffReplayValue("result", want, got)
+}
+
+func diffReplayValue(path string, want, got interface{}) string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected object, got %s", path, replayJSON(got))
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if !ok {
+				return fmt.Sprintf("%s.%s: missing", path, k)
+			}
+			if diff := diffReplayValue(path+"."+k, wv, gv); diff != "" {
+				return diff
+			}
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				return fmt.Sprintf("%s.%s: unexpected field", path, k)
+			}
+		}
+		return ""
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected array, got %s", path, replayJSON(got))
+		}
+		if len(w) != len(g) {
+			return fmt.Sprintf("%s: expected %d elements, got %d", path, len(w), len(g))
+		}
+		for i := range w {
+			if diff := diffReplayValue(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	default:
+		if want != redactedValue && !reflect.DeepEqual(want, got) {
+			return fmt.Sprintf("%s: expected %s, got %s", path, replayJSON(want), replayJSON(got))
+		}
+		return ""
+	}
+}
+
+// stripReplayFields removes ignored keys, including inside JSON documents embedded in
+// text content.
+func stripReplayFields(v interface{}, ignore map[string]bool) interface{} {
+	if len(ignore) == 0 {
+		return v
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range val {
+			if ignore[k] {
+				delete(val, k)
+			} else {
+				val[k] = stripReplayFields(inner, ignore)
+			}
+		}
+	case []interface{}:
+		for i, inner := range val {
+			val[i] = stripReplayFields(inner, ignore)
+		}
+	case string:
+		if trimmed := strings.TrimSpace(val); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var embedded interface{}
+			if json.Unmarshal([]byte(trimmed), &embedded) == nil {
+				return stripReplayFields(embedded, ignore)
+			}
+		}
+	}
+	return v
+}
+
+func containsRedacted(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, inner := range val {
+			if containsRedacted(inner) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, inner := range val {
+			if containsRedacted(inner) {
+				return true
+			}
+		}
+	case string:
+		return val == redactedValue
+	}
+	return false
+}
+
+func replayJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(data) > 200 {
+		return string(data[:200]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordReplayTrace records a short MCP session against a greeting tool.
+func recordReplayTrace(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trace.ndjson")
+	h := newReplayHandler("Hello")
+	if err := h.StartTrace(MCPTraceConfig{Path: path}); err != nil {
+		t.Fatalf("StartTrace: %v", err)
+	}
+	h.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"initialize","id":1,"params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`))
+	h.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"tools/call","id":2,"params":{"name":"greet","arguments":{"name":"Ada"}}}`))
+	h.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"tools/call","id":3,"params":{"name":"greet","arguments":{"name":"Ada","api_key":"k-1"}}}`))
+	h.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"tools/call","id":4,"params":{"name":"missing","arguments":{}}}`))
+	if err := h.StopTrace(); err != nil {
+		t.Fatalf("StopTrace: %v", err)
+	}
+	return path
+}
+
+func newReplayHandler(greeting string) *MCPHandler {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&mockTool{
+		name: "greet",
+		executeFunc: func(params map[string]interface{}) (interface{}, error) {
+			return map[string]interface{}{
+				"message":   greeting + ", " + params["name"].(string),
+				"timestamp": time.Now().UnixNano(),
+			}, nil
+		},
+	})
+	return h
+}
+
+func TestReplayMCPTraceMatches(t *testing.T) {
+	path := recordReplayTrace(t)
+
+	report, err := ReplayMCPTraceFile(context.Background(), path, MCPHandlerTarget(newReplayHandler("Hello")),
+		MCPReplayOptions{IgnoreFields: []string{"timestamp"}})
+	if err != nil {
+		t.Fatalf("ReplayMCPTraceFile: %v", err)
+	}
+	if !report.OK() {
+		t.Fatalf("expected replay to match, got %+v", report.Results)
+	}
+	if report.Total != 4 || report.Matched != 3 || report.Skipped != 1 {
+		t.Errorf("unexpected report counts: %+v", report)
+	}
+	if report.Results[2].Status != "skipped" {
+		t.Errorf("expected request with redacted parameters to be skipped, got %s", report.Results[2].Status)
+	}
+}
+
+func TestReplayMCPTraceDetectsRegression(t *testing.T) {
+	path := recordReplayTrace(t)
+
+	// Without ignoring the timestamp the responses differ on every run
+	report, err := ReplayMCPTraceFile(context.Background(), path, MCPHandlerTarget(newReplayHandler("Hello")), MCPReplayOptions{})
+	if err != nil {
+		t.Fatalf("ReplayMCPTraceFile: %v", err)
+	}
+	if report.OK() {
+		t.Fatal("expected timestamp differences to be reported")
+	}
+
+	// Replay over HTTP against a server whose tool output changed
+	ts := httptest.NewServer(newReplayHandler("Hi"))
+	defer ts.Close()
+	report, err = ReplayMCPTraceFile(context.Background(), path, MCPEndpointTarget(ts.URL, ts.Client()),
+		MCPReplayOptions{IgnoreFields: []string{"timestamp"}, StopOnMismatch: true})
+	if err != nil {
+		t.Fatalf("ReplayMCPTraceFile: %v", err)
+	}
+	if report.Mismatched != 1 || len(report.Results) != 2 {
+		t.Fatalf("expected to stop at the first mismatch, got %+v", report)
+	}
+	if detail := report.Results[1].Detail; !strings.Contains(detail, "Hello, Ada") || !strings.Contains(detail, "Hi, Ada") {
+		t.Errorf("expected mismatch detail to show both messages, got %q", detail)
+	}
+}
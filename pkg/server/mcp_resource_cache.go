@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultResourceCacheTTL is how long resource reads are cached unless the resource
+// implements CacheableResource.
+const DefaultResourceCacheTTL = 5 * time.Minute
+
+// CacheableResource is implemented by resources that choose how long their reads are
+// cached. A TTL of zero or less disables caching, which suits volatile resources such
+// as logs and health status.
+type CacheableResource interface {
+	MCPResource
+	CacheTTL() time.Duration
+}
+
+// resourceCacheTTL returns the cache TTL for resource.
+func resourceCacheTTL(resource MCPResource) time.Duration {
+	if c, ok := resource.(CacheableResource); ok {
+		return c.CacheTTL()
+	}
+	return DefaultResourceCacheTTL
+}
+
+// InvalidateResource drops the cached content of the resource at uri, so the next read
+// calls Read again. It returns whether anything was cached.
+func (h *MCPHandler) InvalidateResource(uri string) bool {
+	if h.cache.delete(uri) {
+		h.logger.Debug("MCP resource cache invalidated", "uri", uri)
+		return true
+	}
+	return false
+}
+
+// InvalidateMCPResource drops the cached content of the MCP resource at uri. Call it when
+// the data behind a resource changes before its cache TTL expires.
+func (srv *Server) InvalidateMCPResource(uri string) error {
+	if !srv.MCPEnabled() {
+		return fmt.Errorf("MCP is not enabled on this server")
+	}
+	srv.mcpHandler.InvalidateResource(uri)
+	return nil
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestResourceCacheTTL(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	reads := map[string]int{}
+	register := func(uri string, b *ResourceBuilder) {
+		h.RegisterResource(b.WithRead(func() (interface{}, error) {
+			reads[uri]++
+			return reads[uri], nil
+		}).Build())
+	}
+	register("cached://default", NewResource("cached://default"))
+	register("volatile://now", NewResource("volatile://now").WithCacheTTL(0))
+
+	for i := 0; i < 3; i++ {
+		for _, uri := range []string{"cached://default", "volatile://now"} {
+			if _, err := h.handleResourcesRead(map[string]interface{}{"uri": uri}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if reads["cached://default"] != 1 || reads["volatile://now"] != 3 {
+		t.Errorf("unexpected reads %v", reads)
+	}
+
+	if !h.InvalidateResource("cached://default") || h.InvalidateResource("volatile://now") {
+		t.Error("only the cached resource should have been invalidated")
+	}
+	h.handleResourcesRead(map[string]interface{}{"uri": "cached://default"})
+	if reads["cached://default"] != 2 {
+		t.Errorf("invalidated resource was served from cache: %v", reads)
+	}
+}
+
+func TestBuiltinResourcesNotCached(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []MCPResource{NewServerHealthResource(srv), NewServerLogResource(10), NewMetricsResource(srv)} {
+		if ttl := resourceCacheTTL(r); ttl != 0 {
+			t.Errorf("%s is cached for %v", r.URI(), ttl)
+		}
+	}
+	if ttl := resourceCacheTTL(NewServerConfigResource(srv)); ttl != DefaultResourceCacheTTL {
+		t.Errorf("config TTL = %v, want %v", ttl, DefaultResourceCacheTTL)
+	}
+	if err := srv.InvalidateMCPResource("config://server/current"); err != nil {
+		t.Error(err)
+	}
+
+	plain, _ := NewServer(WithAddr(":0"))
+	if err := plain.InvalidateMCPResource("config://server/current"); err == nil {
+		t.Error("expected an error without MCP")
+	}
+}
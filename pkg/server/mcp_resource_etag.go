@@ -0,0 +1,59 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// resourceContent is a resource read as cached by the MCP handler.
+type resourceContent struct {
+	text     string
+	etag     string
+	contents []MCPResourceContent // Returned as is instead of text if set
+}
+
+// contentETag returns a strong ETag for resource contents.
+func contentETag(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return `"` + hex.EncodeToString(sum[:])[:16] + `"`
+}
+
+// etagMatches reports whether an If-None-Match value, which may list several ETags or be
+// "*", matches etag. Weak validators compare equal to strong ones, as for GET.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceReadResult builds the resources/read result. The ETag is returned in _meta; if
+// it matches ifNoneMatch, the contents are left out and _meta.notModified is set.
+func resourceReadResult(resource MCPResource, content resourceContent, ifNoneMatch string) map[string]interface{} {
+	if ifNoneMatch != "" && etagMatches(ifNoneMatch, content.etag) {
+		return map[string]interface{}{
+			"contents": []map[string]interface{}{},
+			"_meta":    map[string]interface{}{"etag": content.etag, "notModified": true},
+		}
+	}
+	if content.contents != nil {
+		return map[string]interface{}{
+			"contents": content.contents,
+			"_meta":    map[string]interface{}{"etag": content.etag},
+		}
+	}
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"uri":      resource.URI(),
+				"mimeType": resource.MimeType(),
+				"text":     content.text,
+			},
+		},
+		"_meta": map[string]interface{}{"etag": content.etag},
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newETagHandler() *MCPHandler {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterResource(&SimpleResource{
+		URIFunc:  func() string { return "stats://requests" },
+		ReadFunc: func() (interface{}, error) { return map[string]int{"total": 42}, nil },
+	})
+	return h
+}
+
+func TestResourcesReadConditional(t *testing.T) {
+	h := newETagHandler()
+
+	first, err := h.handleResourcesRead(map[string]interface{}{"uri": "stats://requests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := first.(map[string]interface{})["_meta"].(map[string]interface{})["etag"].(string)
+	if etag != contentETag(`{"total":42}`) {
+		t.Fatalf("etag = %q", etag)
+	}
+
+	second, err := h.handleResourcesRead(map[string]interface{}{"uri": "stats://requests", "ifNoneMatch": etag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := second.(map[string]interface{})
+	if meta := result["_meta"].(map[string]interface{}); meta["notModified"] != true || len(result["contents"].([]map[string]interface{})) != 0 {
+		t.Errorf("expected an unmodified result, got %v", result)
+	}
+
+	third, _ := h.handleResourcesRead(map[string]interface{}{"uri": "stats://requests", "ifNoneMatch": `"stale"`})
+	if contents := third.(map[string]interface{})["contents"].([]map[string]interface{}); len(contents) != 1 {
+		t.Errorf("expected contents for a stale etag, got %v", third)
+	}
+}
+
+func TestResourcesReadIfNoneMatchHeader(t *testing.T) {
+	h := newETagHandler()
+	read := func(ifNoneMatch string) map[string]interface{} {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"stats://requests"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp struct {
+			Result map[string]interface{} `json:"result"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Result
+	}
+
+	etag := read("")["_meta"].(map[string]interface{})["etag"].(string)
+	if meta := read(`"other", W/` + etag)["_meta"].(map[string]interface{}); meta["notModified"] != true {
+		t.Errorf("If-None-Match header was not honored: %v", meta)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"x", "abc"`, true},
+		{`*`, true},
+		{`"abcd"`, false},
+		{`"ab"`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%s) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MCPResourceTemplate is a family of resources addressed by an RFC 6570 URI template such
+// as "blog://posts/{id}". Clients discover templates with resources/templates/list and
+// read them with resources/read on a concrete URI; the values of the template variables
+// are passed to Read.
+//
+// Supported expressions are simple strings ({id}), reserved strings that may contain
+// slashes ({+path}), and form-style queries ({?limit,offset}), whose variables are optional.
+type MCPResourceTemplate interface {
+	URITemplate() string
+	Name() string
+	Description() string
+	MimeType() string
+	Read(params map[string]string) (interface{}, error)
+}
+
+// compiledResourceTemplate matches URIs against a registered template.
+type compiledResourceTemplate struct {
+	template MCPResourceTemplate
+	pattern  *regexp.Regexp
+	vars     []string // Path variables, in the order of the pattern's groups
+	query    []string // Variables of a trailing {?...} expression
+}
+
+var uriTemplateExpr = regexp.MustCompile(`\{([+?]?)([A-Za-z0-9_.,]+)\}`)
+
+func compileResourceTemplate(t MCPResourceTemplate) (*compiledResourceTemplate, error) {
+	tmpl := t.URITemplate()
+	c := &compiledResourceTemplate{template: t}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, m := range uriTemplateExpr.FindAllStringSubmatchIndex(tmpl, -1) {
+		if c.query != nil {
+			return nil, fmt.Errorf("resource template %q: {?...} must be the last expression", tmpl)
+		}
+		pattern.WriteString(regexp.QuoteMeta(tmpl[last:m[0]]))
+		last = m[1]
+		operator, names := tmpl[m[2]:m[3]], strings.Split(tmpl[m[4]:m[5]], ",")
+		switch operator {
+		case "?":
+			c.query = names
+			pattern.WriteString(`(?:\?(.*))?`)
+		case "+":
+			c.vars = append(c.vars, names[0])
+			pattern.WriteString(`(.+)`)
+		default:
+			c.vars = append(c.vars, names[0])
+			pattern.WriteString(`([^/?#]+)`)
+		}
+		if operator != "?" && len(names) > 1 {
+			return nil, fmt.Errorf("resource template %q: only {?...} may list several variables", tmpl)
+		}
+	}
+	if strings.ContainsAny(tmpl[last:], "{}") {
+		return nil, fmt.Errorf("resource template %q: unsupported expression", tmpl)
+	}
+	pattern.WriteString(regexp.QuoteMeta(tmpl[last:]))
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("resource template %q: %w", tmpl, err)
+	}
+	c.pattern = re
+	return c, nil
+}
+
+// match returns the variables of uri, or false if the template does not match it.
+func (c *compiledResourceTemplate) match(uri string) (map[string]string, bool) {
+	m := c.pattern.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+	params := make(map[string]string, len(c.vars)+len(c.query))
+	for i, name := range c.vars {
+		value, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false
+		}
+		params[name] = value
+	}
+	if c.query != nil && m[len(m)-1] != "" {
+		values, err := url.ParseQuery(m[len(m)-1])
+		if err != nil {
+			return nil, false
+		}
+		for _, name := range c.query {
+			if values.Has(name) {
+				params[name] = values.Get(name)
+			}
+		}
+	}
+	return params, true
+}
+
+// templateResource is a resource read through a template.
+type templateResource struct {
+	template MCPResourceTemplate
+	uri      string
+	params   map[string]string
+}
+
+func (r *templateResource) URI() string                { return r.uri }
+func (r *templateResource) Name() string               { return r.template.Name() }
+func (r *templateResource) Description() string        { return r.template.Description() }
+func (r *templateResource) MimeType() string           { return r.template.MimeType() }
+func (r *templateResource) Read() (interface{}, error) { return r.template.Read(r.params) }
+func (r *templateResource) List() ([]string, error)    { return []string{r.uri}, nil }
+
+// CacheTTL uses the template's TTL if it implements CacheTTL.
+func (r *templateResource) CacheTTL() time.Duration {
+	if c, ok := r.template.(interface{ CacheTTL() time.Duration }); ok {
+		return c.CacheTTL()
+	}
+	return DefaultResourceCacheTTL
+}
+
+// RegisterResourceTemplate registers a resource template. Templates are matched in
+// registration order against URIs that are not registered as resources.
+func (h *MCPHandler) RegisterResourceTemplate(t MCPResourceTemplate) error {
+	c, err := compileResourceTemplate(t)
+	if err != nil {
+		return err
+	}
+	h.registryMu.Lock()
+	h.templates = append(h.templates, c)
+	h.registryMu.Unlock()
+	h.logger.Debug("MCP resource template registered", "template", t.URITemplate())
+	return nil
+}
+
+// findResource returns the resource registered for uri or a resource read through the
+// first matching template.
+func (h *MCPHandler) findResource(uri string) (MCPResource, bool) {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
+	if resource, ok := h.resources[uri]; ok {
+		return resource, true
+	}
+	for _, c := range h.templates {
+		if params, ok := c.match(uri); ok {
+			return &templateResource{template: c.template, uri: uri, params: params}, true
+		}
+	}
+	return nil, false
+}
+
+func (h *MCPHandler) handleResourceTemplatesList(params interface{}) (interface{}, error) {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
+	templates := make([]map[string]interface{}, 0, len(h.templates))
+	for _, c := range h.templates {
+		templates = append(templates, map[string]interface{}{
+			"uriTemplate": c.template.URITemplate(),
+			"name":        c.template.Name(),
+			"description": c.template.Description(),
+			"mimeType":    c.template.MimeType(),
+		})
+	}
+	return map[string]interface{}{"resourceTemplates": templates}, nil
+}
+
+// RegisterMCPResourceTemplate registers a custom MCP resource template
+func (srv *Server) RegisterMCPResourceTemplate(t MCPResourceTemplate) error {
+	if !srv.MCPEnabled() {
+		return fmt.Errorf("MCP is not enabled on this server")
+	}
+	return srv.mcpHandler.RegisterResourceTemplate(t)
+}
+
+// ResourceTemplateBuilder provides a fluent API for building resource templates
+type ResourceTemplateBuilder struct {
+	uriTemplate string
+	name        string
+	description string
+	mimeType    string
+	readFunc    func(params map[string]string) (interface{}, error)
+}
+
+// NewResourceTemplate creates a new resource template builder
+//
+// Example:
+//
+//	srv.RegisterMCPResourceTemplate(server.NewResourceTemplate("blog://posts/{id}").
+//		WithName("Blog post").
+//		WithRead(func(params map[string]string) (interface{}, error) {
+//			return store.Post(params["id"])
+//		}).
+//		Build())
+func NewResourceTemplate(uriTemplate string) *ResourceTemplateBuilder {
+	return &ResourceTemplateBuilder{
+		uriTemplate: uriTemplate,
+		mimeType:    "application/json",
+	}
+}
+
+func (b *ResourceTemplateBuilder) WithName(name string) *ResourceTemplateBuilder {
+	b.name = name
+	return b
+}
+
+func (b *ResourceTemplateBuilder) WithDescription(desc string) *ResourceTemplateBuilder {
+	b.description = desc
+	return b
+}
+
+func (b *ResourceTemplateBuilder) WithMimeType(mimeType string) *ResourceTemplateBuilder {
+	b.mimeType = mimeType
+	return b
+}
+
+func (b *ResourceTemplateBuilder) WithRead(fn func(params map[string]string) (interface{}, error)) *ResourceTemplateBuilder {
+	b.readFunc = fn
+	return b
+}
+
+func (b *ResourceTemplateBuilder) Build() MCPResourceTemplate {
+	return &builtResourceTemplate{
+		uriTemplate: b.uriTemplate,
+		name:        b.name,
+		description: b.description,
+		mimeType:    b.mimeType,
+		readFunc:    b.readFunc,
+	}
+}
+
+type builtResourceTemplate struct {
+	uriTemplate string
+	name        string
+	description string
+	mimeType    string
+	readFunc    func(params map[string]string) (interface{}, error)
+}
+
+func (t *builtResourceTemplate) URITemplate() string { return t.uriTemplate }
+func (t *builtResourceTemplate) Name() string        { return t.name }
+func (t *builtResourceTemplate) Description() string { return t.description }
+func (t *builtResourceTemplate) MimeType() string    { return t.mimeType }
+func (t *builtResourceTemplate) Read(params map[string]string) (interface{}, error) {
+	if t.readFunc == nil {
+		return nil, fmt.Errorf("read function not implemented")
+	}
+	return t.readFunc(params)
+}
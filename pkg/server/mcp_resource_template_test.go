@@ -0,0 +1,81 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResourceTemplateMatch(t *testing.T) {
+	tests := []struct {
+		template string
+		uri      string
+		want     map[string]string
+	}{
+		{"blog://posts/{id}", "blog://posts/42", map[string]string{"id": "42"}},
+		{"blog://posts/{id}", "blog://posts/hello%20world", map[string]string{"id": "hello world"}},
+		{"blog://posts/{id}", "blog://posts/42/comments", nil},
+		{"blog://posts/{id}/comments/{comment}", "blog://posts/1/comments/7", map[string]string{"id": "1", "comment": "7"}},
+		{"file:///{+path}", "file:///docs/guide.md", map[string]string{"path": "docs/guide.md"}},
+		{"logs://app{?level,limit}", "logs://app?level=error&other=1", map[string]string{"level": "error"}},
+		{"logs://app{?level,limit}", "logs://app", map[string]string{}},
+		{"logs://app{?level,limit}", "logs://api", nil},
+	}
+	for _, tt := range tests {
+		c, err := compileResourceTemplate(NewResourceTemplate(tt.template).Build())
+		if err != nil {
+			t.Fatalf("compile %s: %v", tt.template, err)
+		}
+		got, ok := c.match(tt.uri)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("%s matched %s: %v", tt.template, tt.uri, got)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s on %s = %v, %v; want %v", tt.template, tt.uri, got, ok, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"blog://{?a}/{id}", "blog://{a,b}", "blog://{#id}"} {
+		if _, err := compileResourceTemplate(NewResourceTemplate(invalid).Build()); err == nil {
+			t.Errorf("expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestResourceTemplatesThroughHandler(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	err := h.RegisterResourceTemplate(NewResourceTemplate("blog://posts/{id}").
+		WithName("Blog post").
+		WithMimeType("text/plain").
+		WithRead(func(params map[string]string) (interface{}, error) {
+			return "post " + params["id"], nil
+		}).
+		Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := h.handleResourceTemplatesList(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := list.(map[string]interface{})["resourceTemplates"].([]map[string]interface{})
+	if len(templates) != 1 || templates[0]["uriTemplate"] != "blog://posts/{id}" || templates[0]["mimeType"] != "text/plain" {
+		t.Errorf("unexpected templates %v", templates)
+	}
+
+	result, err := h.handleResourcesRead(map[string]interface{}{"uri": "blog://posts/7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := result.(map[string]interface{})["contents"].([]map[string]interface{})[0]
+	if content["uri"] != "blog://posts/7" || content["text"] != "post 7" {
+		t.Errorf("unexpected content %v", content)
+	}
+
+	if _, err := h.handleResourcesRead(map[string]interface{}{"uri": "blog://drafts/7"}); err == nil {
+		t.Error("expected an error for a URI no template matches")
+	}
+}
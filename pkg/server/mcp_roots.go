@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// ErrMCPRootsUnsupported is returned by ListRoots when the client did not declare the
+// roots capability on initialize, so the server is not restricted to any roots.
+var ErrMCPRootsUnsupported = errors.New("MCP client does not support roots")
+
+// MCPRoot is a directory the client lets the server work in, such as the project open
+// in the user's editor.
+type MCPRoot struct {
+	URI  string `json:"uri"` // A file:// URI
+	Name string `json:"name,omitempty"`
+}
+
+// NewMCPRoot returns the root for a local directory, named after its base name.
+func NewMCPRoot(dir string) MCPRoot {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(dir)}
+	return MCPRoot{URI: u.String(), Name: filepath.Base(dir)}
+}
+
+// Path returns the local path of the root, or "" if the URI is not a file:// URI.
+func (r MCPRoot) Path() string {
+	u, err := url.Parse(r.URI)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return ""
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path))
+}
+
+// MCPRootsContain reports whether the absolute path lies within one of roots. Symlinks
+// are resolved where the paths exist, so a link inside a root cannot point out of it.
+func MCPRootsContain(roots []MCPRoot, path string) bool {
+	target := resolvePath(path)
+	for _, root := range roots {
+		dir := root.Path()
+		if dir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(resolvePath(dir), target)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns path with symlinks resolved, or cleaned if it does not exist.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// mcpSessionRoots tracks the roots of a session's client. The roots are fetched with
+// roots/list when a tool first needs them and cached until the client sends
+// notifications/roots/list_changed.
+type mcpSessionRoots struct {
+	supported  bool // The client declared the roots capability
+	fetched    bool
+	roots      []MCPRoot
+	generation uint64 // Incremented when the roots change, so stale fetches are discarded
+}
+
+// setRootsSupported records whether the client declared the roots capability on
+// initialize, dropping roots cached for an earlier connection.
+func (s *MCPSession) setRootsSupported(supported bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roots = mcpSessionRoots{supported: supported, generation: s.roots.generation + 1}
+}
+
+// cachedRoots returns the cached roots, whether they are cached, and the generation
+// under which fetched roots are stored.
+func (s *MCPSession) cachedRoots() (roots []MCPRoot, supported, fetched bool, generation uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.roots.roots, s.roots.supported, s.roots.fetched, s.roots.generation
+}
+
+// storeRoots caches fetched roots unless they changed while they were fetched.
+func (s *MCPSession) storeRoots(generation uint64, roots []MCPRoot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.roots.generation == generation {
+		s.roots.roots, s.roots.fetched = roots, true
+	}
+}
+
+// invalidateRoots drops the cached roots after the client reported a change.
+func (s *MCPSession) invalidateRoots() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roots.roots, s.roots.fetched = nil, false
+	s.roots.generation++
+}
+
+// initializeDeclaresRoots reports whether initialize params declare the roots capability.
+func initializeDeclaresRoots(params interface{}) bool {
+	p, _ := params.(map[string]interface{})
+	capabilities, _ := p["capabilities"].(map[string]interface{})
+	_, ok := capabilities["roots"]
+	return ok
+}
+
+// handleRootsListChanged drops the cached roots of the client's session.
+func (h *MCPHandler) handleRootsListChanged(ctx context.Context, params interface{}) (interface{}, error) {
+	if session := MCPSessionFromContext(ctx); session != nil {
+		session.invalidateRoots()
+		h.logger.Debug("MCP client roots changed", "session", session.ID())
+	}
+	return nil, nil
+}
+
+func (c *clientRequester) ListRoots(ctx context.Context) ([]MCPRoot, error) {
+	session := MCPSessionFromContext(c.ctx)
+	if session == nil {
+		return nil, ErrMCPRootsUnsupported
+	}
+	roots, supported, fetched, generation := session.cachedRoots()
+	switch {
+	case !supported:
+		return nil, ErrMCPRootsUnsupported
+	case fetched:
+		return roots, nil
+	}
+	m, err := c.messenger()
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Roots []MCPRoot `json:"roots"`
+	}
+	if err := c.h.callClient(ctx, m, "roots/list", nil, &result); err != nil {
+		return nil, err
+	}
+	session.storeRoots(generation, result.Roots)
+	return result.Roots, nil
+}
+
+// checkClientRoots restricts a file tool to the client's roots: path, relative to the
+// tool's sandbox dir or to the working directory if dir is "", must lie within one of
+// them. Clients without the roots capability leave the tool restricted to its sandbox.
+func checkClientRoots(ctx context.Context, client MCPClientRequester, dir, path string) error {
+	roots, err := client.ListRoots(ctx)
+	switch {
+	case errors.Is(err, ErrMCPRootsUnsupported):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to list client roots: %w", err)
+	}
+	target := filepath.Join(dir, path)
+	if !filepath.IsAbs(target) {
+		if target, err = filepath.Abs(target); err != nil {
+			return err
+		}
+	}
+	if !MCPRootsContain(roots, target) {
+		return fmt.Errorf("path %s is outside the client's roots", path)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRootsClient serves the file tools confined to dir over stdio and connects a client
+// with opts.
+func newRootsClient(t *testing.T, dir string, opts ...MCPClientOption) *MCPClient {
+	t.Helper()
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	tool, err := NewFileReadTool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.RegisterTool(tool)
+	write, err := NewFileWriteTool(dir, MCPFileWriteConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.RegisterTool(write)
+	deleteTool, err := NewFileDeleteTool(dir, MCPFileWriteConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.RegisterTool(deleteTool)
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- h.ServeStdio(serverIn, serverOut)
+		serverOut.Close()
+	}()
+	client := NewMCPStdioClient(clientIn, clientOut, opts...)
+	t.Cleanup(func() {
+		client.Close()
+		<-done
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func writeRootsFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"a/doc.txt", "b/doc.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readFileError(t *testing.T, client *MCPClient, path string) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.CallTool(ctx, "read_file", map[string]interface{}{"path": path})
+	return err
+}
+
+func TestMCPRootsRestrictFileTools(t *testing.T) {
+	dir := writeRootsFixture(t)
+	client := newRootsClient(t, dir, WithMCPClientRoots(NewMCPRoot(filepath.Join(dir, "a"))))
+
+	if err := readFileError(t, client, "a/doc.txt"); err != nil {
+		t.Errorf("expected a file within the roots to be readable, got %v", err)
+	}
+	if err := readFileError(t, client, "b/doc.txt"); err == nil {
+		t.Error("expected a file outside the roots to be rejected")
+	}
+
+	// The server fetches the roots again after the client reports a change
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.SetRoots(ctx, NewMCPRoot(filepath.Join(dir, "b"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := readFileError(t, client, "b/doc.txt"); err != nil {
+		t.Errorf("expected the new root to be readable, got %v", err)
+	}
+	if err := readFileError(t, client, "a/doc.txt"); err == nil {
+		t.Error("expected the old root to be rejected")
+	}
+}
+
+func TestMCPRootsRestrictFileWriteTools(t *testing.T) {
+	dir := writeRootsFixture(t)
+	client := newRootsClient(t, dir, WithMCPClientRoots(NewMCPRoot(filepath.Join(dir, "a"))))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.CallTool(ctx, "write_file", map[string]interface{}{"path": "a/new.txt", "content": "ok"}); err != nil {
+		t.Errorf("expected a write within the roots to succeed, got %v", err)
+	}
+	if _, err := client.CallTool(ctx, "write_file", map[string]interface{}{"path": "b/doc.txt", "content": "replaced"}); err == nil {
+		t.Error("expected a write outside the roots to be rejected")
+	}
+	if _, err := client.CallTool(ctx, "delete_file", map[string]interface{}{"path": "b/doc.txt"}); err == nil {
+		t.Error("expected a delete outside the roots to be rejected")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "b/doc.txt")); err != nil || string(data) != "b/doc.txt" {
+		t.Errorf("expected the file outside the roots to be unchanged, got %q: %v", data, err)
+	}
+}
+
+func TestMCPRootsWithoutCapability(t *testing.T) {
+	dir := writeRootsFixture(t)
+	client := newRootsClient(t, dir)
+
+	for _, path := range []string{"a/doc.txt", "b/doc.txt"} {
+		if err := readFileError(t, client, path); err != nil {
+			t.Errorf("expected %s to be readable without roots, got %v", path, err)
+		}
+	}
+	if err := readFileError(t, client, "../outside.txt"); err == nil {
+		t.Error("expected the sandbox to apply without roots")
+	}
+}
+
+func TestMCPRootsContain(t *testing.T) {
+	dir := t.TempDir()
+	roots := []MCPRoot{NewMCPRoot(filepath.Join(dir, "project")), {URI: "https://example.com/project"}}
+
+	tests := map[string]bool{
+		filepath.Join(dir, "project"):                  true,
+		filepath.Join(dir, "project", "src", "x.go"):   true,
+		filepath.Join(dir, "project-other", "x.go"):    false,
+		filepath.Join(dir, "project", "..", "secrets"): false,
+		dir: false,
+	}
+	for path, want := range tests {
+		if got := MCPRootsContain(roots, path); got != want {
+			t.Errorf("MCPRootsContain(%s) = %v, want %v", path, got, want)
+		}
+	}
+	if !strings.HasPrefix(roots[0].URI, "file:///") || roots[0].Name != "project" {
+		t.Errorf("unexpected root %+v", roots[0])
+	}
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrMCPClientUnreachable is returned when a tool asks the client for input over a
+// transport that cannot carry requests to the client: plain HTTP POST, the legacy SSE
+// transport, or a Streamable HTTP call answered with JSON while no GET stream is open.
+var ErrMCPClientUnreachable = errors.New("MCP client cannot receive requests on this transport")
+
+// MCPClientRequester sends requests to the connected client while a tool runs. The
+// client must have declared the matching capability; clients that have not answer with
+// a JSON-RPC error, returned as *JSONRPCError. It also reports the progress of the call,
+// as MCPToolWithProgress tools do.
+type MCPClientRequester interface {
+	MCPProgressReporter
+	// CreateMessage asks the client's LLM for a completion (sampling/createMessage).
+	CreateMessage(ctx context.Context, req MCPSamplingRequest) (*MCPSamplingResult, error)
+	// Elicit asks the user for structured input (elicitation/create).
+	Elicit(ctx context.Context, req MCPElicitationRequest) (*MCPElicitationResult, error)
+	// ListRoots returns the directories the client lets the server work in (roots/list),
+	// cached until the client reports a change. It returns ErrMCPRootsUnsupported if the
+	// client did not declare the roots capability.
+	ListRoots(ctx context.Context) ([]MCPRoot, error)
+}
+
+// MCPToolWithClient is implemented by tools that ask the LLM or the user for more input
+// while they run, such as a tool that drafts text with the model or confirms a
+// destructive action with the user. Like MCPToolWithContext, the tool must return when
+// ctx is cancelled.
+//
+// Example:
+//
+//	func (t *DeployTool) ExecuteWithClient(ctx context.Context, params map[string]interface{}, client server.MCPClientRequester) (interface{}, error) {
+//		answer, err := client.Elicit(ctx, server.MCPElicitationRequest{
+//			Message: "Deploy to production?",
+//			RequestedSchema: map[string]interface{}{
+//				"type":       "object",
+//				"properties": map[string]interface{}{"confirm": map[string]interface{}{"type": "boolean"}},
+//			},
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		if !answer.Accepted() || answer.Content["confirm"] != true {
+//			return "Deployment cancelled", nil
+//		}
+//		return deploy(ctx)
+//	}
+type MCPToolWithClient interface {
+	MCPTool
+	ExecuteWithClient(ctx context.Context, params map[string]interface{}, client MCPClientRequester) (interface{}, error)
+}
+
+// MCPContent is a content block of a sampling message.
+type MCPContent struct {
+	Type     string `json:"type"` // "text", "image", or "audio"
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // Base64 for images and audio
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// MCPSamplingMessage is a message of the conversation sent to the client's LLM.
+type MCPSamplingMessage struct {
+	Role    string     `json:"role"` // "user" or "assistant"
+	Content MCPContent `json:"content"`
+}
+
+// MCPModelPreferences guide the client's choice of model. Priorities range from 0 to 1.
+type MCPModelPreferences struct {
+	Hints                []MCPModelHint `json:"hints,omitempty"`
+	CostPriority         float64        `json:"costPriority,omitempty"`
+	SpeedPriority        float64        `json:"speedPriority,omitempty"`
+	IntelligencePriority float64        `json:"intelligencePriority,omitempty"`
+}
+
+// MCPModelHint names a model or model family, such as "claude-3-sonnet".
+type MCPModelHint struct {
+	Name string `json:"name"`
+}
+
+// MCPSamplingRequest is the parameters of sampling/createMessage.
+type MCPSamplingRequest struct {
+	Messages         []MCPSamplingMessage   `json:"messages"`
+	ModelPreferences *MCPModelPreferences   `json:"modelPreferences,omitempty"`
+	SystemPrompt     string                 `json:"systemPrompt,omitempty"`
+	IncludeContext   string                 `json:"includeContext,omitempty"` // "none", "thisServer", or "allServers"
+	Temperature      float64                `json:"temperature,omitempty"`
+	MaxTokens        int                    `json:"maxTokens"`
+	StopSequences    []string               `json:"stopSequences,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// MCPSamplingResult is the completion returned by the client.
+type MCPSamplingResult struct {
+	Role       string     `json:"role"`
+	Content    MCPContent `json:"content"`
+	Model      string     `json:"model"`
+	StopReason string     `json:"stopReason,omitempty"`
+}
+
+// MCPElicitationRequest is the parameters of elicitation/create. RequestedSchema is a
+// flat JSON Schema object whose properties are strings, numbers, booleans, or enums.
+type MCPElicitationRequest struct {
+	Message         string                 `json:"message"`
+	RequestedSchema map[string]interface{} `json:"requestedSchema"`
+}
+
+// MCPElicitationResult is the user's answer to an elicitation.
+type MCPElicitationResult struct {
+	Action  string                 `json:"action"` // "accept", "decline", or "cancel"
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// Accepted reports whether the user submitted the requested input.
+func (r *MCPElicitationResult) Accepted() bool {
+	return r.Action == "accept"
+}
+
+// jsonrpcMessage is a message from the client: a request, a notification, or a response
+// to a request the server sent.
+type jsonrpcMessage struct {
+	JSONRPCRequest
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *JSONRPCError   `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a request of the server.
+func (m *jsonrpcMessage) isResponse() bool {
+	return m.Method == "" && m.ID != nil && (m.Result != nil || m.Error != nil)
+}
+
+// jsonrpcOutgoingRequest is a request the server sends to the client.
+type jsonrpcOutgoingRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      string      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// mcpMessenger is implemented by transports that can send requests to the client.
+type mcpMessenger interface {
+	sendMessage(msg interface{}) error
+}
+
+// mcpClientCalls tracks the requests sent to clients until their responses arrive.
+type mcpClientCalls struct {
+	mu      sync.Mutex
+	pending map[string]chan *jsonrpcMessage
+}
+
+// deliverClientResponse hands a response from the client to the request waiting for it.
+func (h *MCPHandler) deliverClientResponse(msg *jsonrpcMessage) {
+	id, _ := msg.ID.(string)
+	h.clientCalls.mu.Lock()
+	ch := h.clientCalls.pending[id]
+	delete(h.clientCalls.pending, id)
+	h.clientCalls.mu.Unlock()
+	if ch == nil {
+		h.logger.Debug("Ignoring JSON-RPC response from client", "id", msg.ID)
+		return
+	}
+	ch <- msg
+}
+
+// callClient sends a request to the client through m and waits for the response.
+func (h *MCPHandler) callClient(ctx context.Context, m mcpMessenger, method string, params, result interface{}) error {
+	var b [12]byte
+	rand.Read(b[:])
+	id := "hs-" + hex.EncodeToString(b[:])
+	ch := make(chan *jsonrpcMessage, 1)
+
+	h.clientCalls.mu.Lock()
+	if h.clientCalls.pending == nil {
+		h.clientCalls.pending = make(map[string]chan *jsonrpcMessage)
+	}
+	h.clientCalls.pending[id] = ch
+	h.clientCalls.mu.Unlock()
+	defer func() {
+		h.clientCalls.mu.Lock()
+		delete(h.clientCalls.pending, id)
+		h.clientCalls.mu.Unlock()
+	}()
+
+	if err := m.sendMessage(jsonrpcOutgoingRequest{JSONRPC: JSONRPCVersion, ID: id, Method: method, Params: params}); err != nil {
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// clientRequester sends requests to the client whose request is being served in ctx.
+type clientRequester struct {
+	*progressReporter
+	h   *MCPHandler
+	ctx context.Context // Context of the MCP request, which identifies the transport
+}
+
+// messenger returns the way to reach the client: the transport or stream of the current
+// request, or else the open GET stream of the Streamable HTTP session.
+func (c *clientRequester) messenger() (mcpMessenger, error) {
+	if m, ok := c.ctx.Value(mcpNotifierKey).(mcpMessenger); ok {
+		return m, nil
+	}
+	if ref, ok := c.ctx.Value(mcpSessionKey).(mcpSessionRef); ok {
+		if session := c.h.sessions.get(ref.id); session != nil && session.streaming() {
+			return session.standaloneStream(), nil
+		}
+	}
+	return nil, ErrMCPClientUnreachable
+}
+
+func (c *clientRequester) CreateMessage(ctx context.Context, req MCPSamplingRequest) (*MCPSamplingResult, error) {
+	m, err := c.messenger()
+	if err != nil {
+		return nil, err
+	}
+	var result MCPSamplingResult
+	if err := c.h.callClient(ctx, m, "sampling/createMessage", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *clientRequester) Elicit(ctx context.Context, req MCPElicitationRequest) (*MCPElicitationResult, error) {
+	m, err := c.messenger()
+	if err != nil {
+		return nil, err
+	}
+	var result MCPElicitationResult
+	if err := c.h.callClient(ctx, m, "elicitation/create", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// sendMessage writes a request to stdout, making the transport an mcpMessenger.
+func (t *stdioTransport) sendMessage(msg interface{}) error {
+	return t.write(msg)
+}
+
+// sendMessage sends a request as a text message, making the transport an mcpMessenger.
+func (t *websocketTransport) sendMessage(msg interface{}) error {
+	return t.write(msg)
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// draftTool asks the client's LLM for a draft and the user to approve it.
+type draftTool struct{}
+
+func (draftTool) Name() string                   { return "draft" }
+func (draftTool) Description() string            { return "Drafts a post" }
+func (draftTool) Schema() map[string]interface{} { return map[string]interface{}{"type": "object"} }
+func (draftTool) Execute(map[string]interface{}) (interface{}, error) {
+	return nil, errors.New("requires a client")
+}
+
+func (draftTool) ExecuteWithClient(ctx context.Context, _ map[string]interface{}, client MCPClientRequester) (interface{}, error) {
+	draft, err := client.CreateMessage(ctx, MCPSamplingRequest{
+		Messages:  []MCPSamplingMessage{{Role: "user", Content: MCPContent{Type: "text", Text: "Write a title"}}},
+		MaxTokens: 20,
+	})
+	if err != nil {
+		return nil, err
+	}
+	answer, err := client.Elicit(ctx, MCPElicitationRequest{
+		Message:         "Publish " + draft.Content.Text + "?",
+		RequestedSchema: map[string]interface{}{"type": "object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !answer.Accepted() {
+		return "not published", nil
+	}
+	return "published " + draft.Content.Text, nil
+}
+
+// answerClientRequest checks a request from the server and returns the client's response.
+func answerClientRequest(t *testing.T, msg map[string]interface{}) string {
+	t.Helper()
+	id, _ := json.Marshal(msg["id"])
+	switch msg["method"] {
+	case "sampling/createMessage":
+		return `{"jsonrpc":"2.0","id":` + string(id) + `,"result":{"role":"assistant","content":{"type":"text","text":"Hello"},"model":"test"}}`
+	case "elicitation/create":
+		params, _ := msg["params"].(map[string]interface{})
+		if params["message"] != "Publish Hello?" {
+			t.Errorf("unexpected elicitation %v", params)
+		}
+		return `{"jsonrpc":"2.0","id":` + string(id) + `,"result":{"action":"accept","content":{}}}`
+	}
+	t.Fatalf("unexpected message from server: %v", msg)
+	return ""
+}
+
+func toolResultText(t *testing.T, msg map[string]interface{}) string {
+	t.Helper()
+	result, _ := msg["result"].(map[string]interface{})
+	content, _ := result["content"].([]interface{})
+	if len(content) != 1 {
+		t.Fatalf("unexpected tool response %v", msg)
+	}
+	return content[0].(map[string]interface{})["text"].(string)
+}
+
+func TestMCPSamplingOverStdio(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	h.RegisterTool(draftTool{})
+
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- h.serveStdio(NewStdioTransportWithIO(stdinR, stdoutW, h.logger)) }()
+	out := bufio.NewScanner(stdoutR)
+	read := func() map[string]interface{} {
+		if !out.Scan() {
+			t.Fatal("stdout closed")
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	io.WriteString(stdinW, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"draft","arguments":{}}}`+"\n")
+	for range 2 {
+		io.WriteString(stdinW, answerClientRequest(t, read())+"\n")
+	}
+	if text := toolResultText(t, read()); text != "published Hello" {
+		t.Errorf("unexpected result %q", text)
+	}
+
+	stdinW.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// titleTool reports its progress while it waits for the client's LLM.
+type titleTool struct{ draftTool }
+
+func (titleTool) Name() string { return "title" }
+
+func (titleTool) ExecuteWithClient(ctx context.Context, _ map[string]interface{}, client MCPClientRequester) (interface{}, error) {
+	client.Report(1, 2, "asking")
+	result, err := client.CreateMessage(ctx, MCPSamplingRequest{
+		Messages:  []MCPSamplingMessage{{Role: "user", Content: MCPContent{Type: "text", Text: "Write a title"}}},
+		MaxTokens: 20,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client.Report(2, 2, "")
+	return result.Content.Text, nil
+}
+
+func TestMCPSamplingOverStdioWithQueuedRequest(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	h.RegisterTool(titleTool{})
+
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- h.serveStdio(NewStdioTransportWithIO(stdinR, stdoutW, h.logger)) }()
+	out := bufio.NewScanner(stdoutR)
+	read := func() map[string]interface{} {
+		if !out.Scan() {
+			t.Fatal("stdout closed")
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	io.WriteString(stdinW, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"title","arguments":{},"_meta":{"progressToken":"p"}}}`+"\n")
+	if msg := read(); msg["method"] != "notifications/progress" {
+		t.Fatalf("expected a progress notification, got %v", msg)
+	}
+	request := read()
+	// The client sends a request of its own before it answers the server's
+	io.WriteString(stdinW, `{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+	io.WriteString(stdinW, answerClientRequest(t, request)+"\n")
+
+	if msg := read(); msg["method"] != "notifications/progress" {
+		t.Fatalf("expected a progress notification, got %v", msg)
+	}
+	if text := toolResultText(t, read()); text != "Hello" {
+		t.Errorf("unexpected result %q", text)
+	}
+	if msg := read(); msg["id"] != float64(2) || msg["result"] == nil {
+		t.Errorf("expected the ping response, got %v", msg)
+	}
+
+	stdinW.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMCPSamplingOverStreamableHTTP(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(draftTool{})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	session, _ := initializeStreamable(t, ts.URL, "2025-06-18")
+
+	resp := postStreamable(t, ts.URL, session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"draft","arguments":{},"_meta":{"progressToken":"p"}}}`)
+	events := bufio.NewReader(resp.Body)
+	next := func() map[string]interface{} {
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("stream ended: %v", err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				var msg map[string]interface{}
+				if err := json.Unmarshal([]byte(data), &msg); err != nil {
+					t.Fatal(err)
+				}
+				return msg
+			}
+		}
+	}
+
+	for range 2 {
+		answer := postStreamable(t, ts.URL, session, answerClientRequest(t, next()))
+		if answer.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected 202 for a client response, got %d", answer.StatusCode)
+		}
+	}
+	if text := toolResultText(t, next()); text != "published Hello" {
+		t.Errorf("unexpected result %q", text)
+	}
+}
+
+func TestMCPSamplingUnreachableClient(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(draftTool{})
+
+	_, err := h.handleToolsCallContext(context.Background(), map[string]interface{}{"name": "draft"})
+	if !errors.Is(err, ErrMCPClientUnreachable) {
+		t.Errorf("expected ErrMCPClientUnreachable, got %v", err)
+	}
+}
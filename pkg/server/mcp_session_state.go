@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const mcpSessionKey contextKey = "mcpSession"
+
+// defaultMCPMaxSessions is the default number of sessions that keep state.
+const defaultMCPMaxSessions = 1000
+
+// MCPSession holds state that tools keep between calls from one client, so that
+// multi-step workflows need no global maps. A session belongs to a Streamable HTTP
+// session (Mcp-Session-Id), an SSE or WebSocket connection, or the stdio client, and
+// ends with it. Sessions unused for the idle timeout are dropped, as are the least
+// recently used ones beyond the session cap; see WithMCPSessionLimits.
+//
+// Example:
+//
+//	func (t *CheckoutTool) ExecuteWithContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+//		session := server.MCPSessionFromContext(ctx)
+//		if session == nil {
+//			return nil, fmt.Errorf("checkout requires a session")
+//		}
+//		cart, _ := session.Get("cart")
+//		...
+//	}
+type MCPSession struct {
+	id       string
+	created  time.Time
+	lastUsed atomic.Int64
+
+	mu     sync.RWMutex
+	values map[string]interface{}
+	roots  mcpSessionRoots
+}
+
+// ID returns the session ID, which is the Mcp-Session-Id or connection ID of the client.
+func (s *MCPSession) ID() string {
+	return s.id
+}
+
+// CreatedAt returns when the session state was created.
+func (s *MCPSession) CreatedAt() time.Time {
+	return s.created
+}
+
+// Get returns the value stored under key.
+func (s *MCPSession) Get(key string) (interface{}, bool) {
+	s.touch()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set stores value under key.
+func (s *MCPSession) Set(key string, value interface{}) {
+	s.touch()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Delete removes the value stored under key.
+func (s *MCPSession) Delete(key string) {
+	s.touch()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Keys returns the stored keys in sorted order.
+func (s *MCPSession) Keys() []string {
+	s.touch()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *MCPSession) touch() {
+	s.lastUsed.Store(time.Now().UnixNano())
+}
+
+// MCPSessionFromContext returns the session of the client whose request is being
+// served, or nil for transports without sessions such as plain HTTP POST requests.
+func MCPSessionFromContext(ctx context.Context) *MCPSession {
+	ref, ok := ctx.Value(mcpSessionKey).(mcpSessionRef)
+	if !ok {
+		return nil
+	}
+	return ref.store.session(ref.id)
+}
+
+// mcpSessionRef identifies a client's session in a context. The state is created when a
+// tool first asks for it.
+type mcpSessionRef struct {
+	store *mcpSessionStateStore
+	id    string
+}
+
+// withMCPSession makes the session state of the client with id available to tools.
+func (h *MCPHandler) withMCPSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, mcpSessionKey, mcpSessionRef{store: h.state, id: id})
+}
+
+// mcpSessionStateStore holds the session state of all clients.
+type mcpSessionStateStore struct {
+	mu          sync.Mutex
+	sessions    map[string]*MCPSession
+	maxSessions int
+	idle        time.Duration
+}
+
+func newMCPSessionStateStore() *mcpSessionStateStore {
+	return &mcpSessionStateStore{
+		sessions:    make(map[string]*MCPSession),
+		maxSessions: defaultMCPMaxSessions,
+		idle:        mcpSessionIdleTimeout,
+	}
+}
+
+// session returns the state of the session id, creating it if needed.
+func (s *mcpSessionStateStore) session(id string) *MCPSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	session := s.sessions[id]
+	if session == nil {
+		if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+			s.evictLocked()
+		}
+		session = &MCPSession{id: id, created: time.Now(), values: make(map[string]interface{})}
+		s.sessions[id] = session
+	}
+	session.touch()
+	return session
+}
+
+// remove drops the state of a session that ended.
+func (s *mcpSessionStateStore) remove(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
+
+func (s *mcpSessionStateStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	return len(s.sessions)
+}
+
+func (s *mcpSessionStateStore) expireLocked() {
+	if s.idle <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.idle).UnixNano()
+	for id, session := range s.sessions {
+		if session.lastUsed.Load() < cutoff {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// evictLocked drops the least recently used session.
+func (s *mcpSessionStateStore) evictLocked() {
+	var oldestID string
+	var oldest int64
+	for id, session := range s.sessions {
+		if used := session.lastUsed.Load(); oldestID == "" || used < oldest {
+			oldestID, oldest = id, used
+		}
+	}
+	delete(s.sessions, oldestID)
+	logger.Debug("MCP session state evicted", "session", oldestID, "max_sessions", s.maxSessions)
+}
+
+// WithMCPSessionLimits sets how many MCP sessions keep state and how long an unused
+// session is kept. Defaults are 1000 sessions and 30 minutes. Both also apply to
+// Streamable HTTP sessions: initialize is refused with 503 while maxSessions are open.
+func WithMCPSessionLimits(maxSessions int, idleTimeout time.Duration) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.MCPMaxSessions = maxSessions
+		srv.Options.MCPSessionIdleTimeout = idleTimeout
+		return nil
+	}
+}
+
+// SetSessionLimits applies the session cap and idle timeout; zero values keep the defaults.
+func (h *MCPHandler) SetSessionLimits(maxSessions int, idleTimeout time.Duration) {
+	h.state.mu.Lock()
+	if maxSessions > 0 {
+		h.state.maxSessions = maxSessions
+	}
+	if idleTimeout > 0 {
+		h.state.idle = idleTimeout
+	}
+	h.state.mu.Unlock()
+	h.sessions.mu.Lock()
+	if maxSessions > 0 {
+		h.sessions.max = maxSessions
+	}
+	if idleTimeout > 0 {
+		h.sessions.idle = idleTimeout
+	}
+	h.sessions.mu.Unlock()
+}
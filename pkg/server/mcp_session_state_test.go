@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// counterTool counts its calls in the session state.
+type counterTool struct{}
+
+func (counterTool) Name() string                   { return "counter" }
+func (counterTool) Description() string            { return "Counts calls per session" }
+func (counterTool) Schema() map[string]interface{} { return map[string]interface{}{"type": "object"} }
+func (counterTool) Execute(map[string]interface{}) (interface{}, error) {
+	return "no session", nil
+}
+func (counterTool) ExecuteWithContext(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+	session := MCPSessionFromContext(ctx)
+	if session == nil {
+		return "no session", nil
+	}
+	n, _ := session.Get("calls")
+	count, _ := n.(int)
+	session.Set("calls", count+1)
+	return map[string]interface{}{"calls": count + 1}, nil
+}
+
+func TestMCPSessionState(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithMCPSupport("test", "1.0.0"), WithMCPBuiltinTools(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.RegisterMCPTool(counterTool{})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	ctx := context.Background()
+	call := func(c *MCPClient) string {
+		t.Helper()
+		result, err := c.CallTool(ctx, "counter", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Text()
+	}
+	first, second := NewMCPHTTPClient(ts.URL+"/mcp"), NewMCPHTTPClient(ts.URL+"/mcp")
+	for _, c := range []*MCPClient{first, second} {
+		if _, err := c.Initialize(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	call(first)
+	if got := call(first); got != `{"calls":2}` {
+		t.Errorf("second call in a session = %s", got)
+	}
+	if got := call(second); got != `{"calls":1}` {
+		t.Errorf("first call in another session = %s", got)
+	}
+	if n := srv.mcpHandler.state.len(); n != 2 {
+		t.Errorf("expected two sessions with state, got %d", n)
+	}
+
+	// Ending the session drops its state
+	first.Close()
+	if n := srv.mcpHandler.state.len(); n != 1 {
+		t.Errorf("state of the closed session was kept: %d sessions", n)
+	}
+
+	// Plain HTTP requests have no session
+	result, err := srv.mcpHandler.handleToolsCall(map[string]interface{}{"name": "counter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.(map[string]interface{})["content"].([]map[string]interface{})[0]["text"]; text != "no session" {
+		t.Errorf("unexpected result without a session: %v", text)
+	}
+}
+
+func TestMCPSessionStateLimits(t *testing.T) {
+	store := newMCPSessionStateStore()
+	store.maxSessions = 2
+	store.idle = time.Hour
+
+	a := store.session("a")
+	a.Set("k", 1)
+	store.session("b")
+	a.lastUsed.Store(time.Now().Add(-time.Minute).UnixNano())
+	store.session("c") // evicts a, the least recently used
+	if _, ok := store.sessions["a"]; ok || store.len() != 2 {
+		t.Errorf("expected a to be evicted, have %d sessions", store.len())
+	}
+	if _, ok := store.session("a").Get("k"); ok {
+		t.Error("evicted session kept its values")
+	}
+
+	store.sessions["c"].lastUsed.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	if _, ok := store.sessions["c"]; !ok || store.len() != 1 {
+		t.Errorf("idle session was not expired, have %d sessions", store.len())
+	}
+}
@@ -0,0 +1,421 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Defaults for SQLQueryToolOptions.
+const (
+	defaultSQLMaxRows      = 100
+	defaultSQLMaxCellBytes = 1024
+	defaultSQLTimeout      = 10 * time.Second
+)
+
+// readOnlySQLStatements are the statements allowed in read-only mode unless
+// SQLQueryToolOptions.AllowedStatements is set.
+var readOnlySQLStatements = []string{"SELECT", "WITH", "EXPLAIN", "SHOW", "DESCRIBE", "VALUES"}
+
+// rowSQLStatements return rows; other statements report the number of affected rows.
+var rowSQLStatements = []string{"SELECT", "WITH", "EXPLAIN", "SHOW", "DESCRIBE", "DESC", "VALUES", "PRAGMA", "TABLE"}
+
+// modifyingSQLStatements change data when they appear in a common table expression or
+// are explained.
+var modifyingSQLStatements = []string{"INSERT", "UPDATE", "DELETE", "MERGE"}
+
+// SQLQueryToolOptions restricts what an SQLQueryTool may run and return.
+type SQLQueryToolOptions struct {
+	Name        string // Tool name (default "sql_query")
+	Description string // Shown to clients, e.g. to describe the schema
+	// ReadOnly rejects statements that modify data, including data-modifying common
+	// table expressions and EXPLAIN ANALYZE. Queries that only read run in a read-only
+	// transaction that is always rolled back, in either mode.
+	ReadOnly bool
+	// AllowedStatements lists the statement keywords that may run, such as "SELECT".
+	// Defaults to SELECT, WITH, EXPLAIN, SHOW, DESCRIBE, and VALUES in read-only mode
+	// and to any statement otherwise.
+	AllowedStatements []string
+	MaxRows           int           // Rows returned before the result is truncated (default 100)
+	MaxCellBytes      int           // Bytes of a text value before it is truncated (default 1024)
+	Timeout           time.Duration // Limit for each query (default 10s)
+}
+
+// SQLQueryTool implements MCPTool for running SQL against an application database, so
+// AI assistants can inspect application data. It is not registered by default; register
+// it with Server.RegisterMCPTool and consider restricting it with WithMCPToolPolicy.
+//
+// A query must be a single statement under the lexical rules of standard SQL, PostgreSQL,
+// and MySQL alike; semicolons in string literals, quoted identifiers, and comments are
+// ignored. Parameters are passed as "args" and bound by the driver,
+// never interpolated.
+type SQLQueryTool struct {
+	db      *sql.DB
+	opts    SQLQueryToolOptions
+	allowed []string
+}
+
+// NewSQLQueryTool creates an SQL query tool on db.
+//
+// Example:
+//
+//	srv.RegisterMCPTool(server.NewSQLQueryTool(db, server.SQLQueryToolOptions{
+//		ReadOnly:    true,
+//		Description: "Query the orders database (tables: customers, orders)",
+//	}))
+func NewSQLQueryTool(db *sql.DB, opts SQLQueryToolOptions) *SQLQueryTool {
+	if opts.Name == "" {
+		opts.Name = "sql_query"
+	}
+	if opts.Description == "" {
+		opts.Description = "Run an SQL query against the application database"
+		if opts.ReadOnly {
+			opts.Description = "Run a read-only SQL query against the application database"
+		}
+	}
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = defaultSQLMaxRows
+	}
+	if opts.MaxCellBytes <= 0 {
+		opts.MaxCellBytes = defaultSQLMaxCellBytes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultSQLTimeout
+	}
+	t := &SQLQueryTool{db: db, opts: opts}
+	for _, stmt := range opts.AllowedStatements {
+		t.allowed = append(t.allowed, strings.ToUpper(stmt))
+	}
+	if t.allowed == nil && opts.ReadOnly {
+		t.allowed = readOnlySQLStatements
+	}
+	return t
+}
+
+func (t *SQLQueryTool) Name() string {
+	return t.opts.Name
+}
+
+func (t *SQLQueryTool) Description() string {
+	return t.opts.Description
+}
+
+func (t *SQLQueryTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "A single SQL statement",
+			},
+			"args": map[string]interface{}{
+				"type":        "array",
+				"description": "Values for the statement's placeholders",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *SQLQueryTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.ExecuteWithContext(context.Background(), params)
+}
+
+func (t *SQLQueryTool) ExecuteWithContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if t.db == nil {
+		return nil, fmt.Errorf("no database configured")
+	}
+	query, ok := params["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query parameter is required and must be a string")
+	}
+	stmt, err := t.checkStatement(query)
+	if err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	if a, ok := params["args"].([]interface{}); ok {
+		args = a
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.opts.Timeout)
+	defer cancel()
+
+	var q interface {
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	} = t.db
+	if !stmt.modifies {
+		tx, err := t.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+
+	if !slices.Contains(rowSQLStatements, stmt.keyword) {
+		result, err := q.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("statement failed: %w", err)
+		}
+		affected, _ := result.RowsAffected()
+		return map[string]interface{}{"rows_affected": affected}, nil
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+	return t.readRows(rows)
+}
+
+// sqlStatement is a statement as classified by checkStatement.
+type sqlStatement struct {
+	keyword  string // First keyword, e.g. "SELECT"
+	modifies bool   // Whether the statement may change data
+}
+
+// checkStatement rejects multiple statements and statements that are not allowed, and
+// classifies the statement. The query is checked as read by each of sqlDialects.
+func (t *SQLQueryTool) checkStatement(query string) (sqlStatement, error) {
+	var stmt sqlStatement
+	for i, dialect := range sqlDialects {
+		words, err := dialect.keywords(query)
+		if err != nil {
+			return sqlStatement{}, err
+		}
+		s, err := t.classifyStatement(words)
+		if err != nil {
+			return sqlStatement{}, err
+		}
+		if i == 0 {
+			stmt = s
+		}
+		stmt.modifies = stmt.modifies || s.modifies
+	}
+	return stmt, nil
+}
+
+// classifyStatement checks the words of a statement. Statements other than
+// rowSQLStatements modify data, as do common table expressions containing INSERT, UPDATE,
+// DELETE, or MERGE, whose keyword must be allowed too, and EXPLAIN ANALYZE, which runs
+// the statement it explains.
+func (t *SQLQueryTool) classifyStatement(words []string) (sqlStatement, error) {
+	if len(words) == 0 {
+		return sqlStatement{}, fmt.Errorf("query contains no statement")
+	}
+	stmt := sqlStatement{keyword: words[0], modifies: !slices.Contains(rowSQLStatements, words[0])}
+	keywords := []string{stmt.keyword}
+	switch stmt.keyword {
+	case "WITH":
+		for _, word := range words[1:] {
+			if slices.Contains(modifyingSQLStatements, word) {
+				stmt.modifies = true
+				keywords = append(keywords, word)
+			}
+		}
+	case "EXPLAIN":
+		for _, word := range words[1:] {
+			if word == "ANALYZE" || word == "ANALYSE" {
+				stmt.modifies = true
+			}
+			if slices.Contains(rowSQLStatements, word) || slices.Contains(modifyingSQLStatements, word) {
+				break
+			}
+		}
+	}
+	if t.opts.ReadOnly && stmt.modifies {
+		return sqlStatement{}, fmt.Errorf("statements that modify data are not allowed in read-only mode")
+	}
+	for _, keyword := range keywords {
+		if t.allowed != nil && !slices.Contains(t.allowed, keyword) {
+			return sqlStatement{}, fmt.Errorf("%s statements are not allowed", keyword)
+		}
+	}
+	return stmt, nil
+}
+
+// sqlDialect selects the lexical rules of a database that affect where statements end.
+type sqlDialect struct {
+	nestedComments   bool // PostgreSQL: /* /* */ */ is one comment
+	dollarQuotes     bool // PostgreSQL: $$...$$ and $tag$...$tag$ strings
+	backslashEscapes bool // MySQL: \' does not end a string
+	hashComments     bool // MySQL: # starts a comment
+	dashCommentSpace bool // MySQL: -- starts a comment only when followed by whitespace
+}
+
+// sqlDialects are the dialects a query is checked against. The tool does not know which
+// database it talks to, so a query must be a single statement under all of them.
+var sqlDialects = []sqlDialect{
+	{}, // Standard SQL, SQLite
+	{nestedComments: true, dollarQuotes: true},
+	{backslashEscapes: true, hashComments: true, dashCommentSpace: true},
+}
+
+// keywords returns the upper-cased words of a query outside comments, string literals,
+// and quoted identifiers, and rejects multiple statements. A single trailing semicolon is
+// allowed.
+func (d sqlDialect) keywords(query string) ([]string, error) {
+	var words []string
+	ended := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--") &&
+			(!d.dashCommentSpace || len(query) == i+2 || query[i+2] <= ' '),
+			c == '#' && d.hashComments:
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return words, nil
+			}
+			i += end + 1
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end, err := d.commentEnd(query[i:])
+			if err != nil {
+				return nil, err
+			}
+			i += end
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+		}
+		if ended {
+			return nil, fmt.Errorf("only a single statement is allowed")
+		}
+		switch {
+		case c == ';':
+			ended = true
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			end, err := d.quoteEnd(query[i:])
+			if err != nil {
+				return nil, err
+			}
+			i += end
+		case c == '$' && d.dollarQuotes && sqlDollarTag(query[i:]) != "":
+			tag := sqlDollarTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string")
+			}
+			i += end + 2*len(tag)
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			start := i
+			for i < len(query) && (query[i] == '_' || query[i] == '$' || query[i] >= '0' && query[i] <= '9' ||
+				query[i] >= 'A' && query[i] <= 'Z' || query[i] >= 'a' && query[i] <= 'z') {
+				i++
+			}
+			words = append(words, strings.ToUpper(query[start:i]))
+		default:
+			i++
+		}
+	}
+	return words, nil
+}
+
+// commentEnd returns the length of the comment at the start of s.
+func (d sqlDialect) commentEnd(s string) (int, error) {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*':
+			if depth == 0 || d.nestedComments {
+				depth++
+			}
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated comment")
+}
+
+// quoteEnd returns the length of the quoted string or identifier at the start of s. A
+// doubled quote reads as two adjacent strings, which ends in the same place.
+func (d sqlDialect) quoteEnd(s string) (int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && d.backslashEscapes && quote != '`':
+			i++
+		case s[i] == quote:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string")
+}
+
+// sqlDollarTag returns the opening tag of a dollar-quoted string at the start of s, such
+// as "$$" or "$body$", or "" if s does not start with one.
+func sqlDollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// readRows reads up to MaxRows rows, truncating long text values.
+func (t *SQLQueryTool) readRows(rows *sql.Rows) (interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	result := [][]interface{}{}
+	truncated := false
+	for rows.Next() {
+		if len(result) == t.opts.MaxRows {
+			truncated = true
+			break
+		}
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			if s, ok := v.(string); ok && len(s) > t.opts.MaxCellBytes {
+				cut := t.opts.MaxCellBytes
+				for cut > 0 && !utf8.RuneStart(s[cut]) {
+					cut--
+				}
+				v = s[:cut] + "…"
+				truncated = true
+			}
+			values[i] = v
+		}
+		result = append(result, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return map[string]interface{}{
+		"columns":   columns,
+		"rows":      result,
+		"row_count": len(result),
+		"truncated": truncated,
+	}, nil
+}
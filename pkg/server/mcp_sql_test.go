@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSQLDriver returns three rows for every query and records the statements it runs.
+type fakeSQLDriver struct {
+	mu       sync.Mutex
+	queries  []string
+	readOnly []bool
+}
+
+func (d *fakeSQLDriver) Open(string) (driver.Conn, error) { return &fakeSQLConn{d: d}, nil }
+
+type fakeSQLConn struct{ d *fakeSQLDriver }
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.mu.Lock()
+	c.d.queries = append(c.d.queries, query)
+	c.d.mu.Unlock()
+	return fakeSQLStmt{}, nil
+}
+func (c *fakeSQLConn) Close() error              { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeSQLConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.d.mu.Lock()
+	c.d.readOnly = append(c.d.readOnly, opts.ReadOnly)
+	c.d.mu.Unlock()
+	return c, nil
+}
+func (c *fakeSQLConn) Commit() error   { return nil }
+func (c *fakeSQLConn) Rollback() error { return nil }
+
+type fakeSQLStmt struct{}
+
+func (fakeSQLStmt) Close() error                               { return nil }
+func (fakeSQLStmt) NumInput() int                              { return -1 }
+func (fakeSQLStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(2), nil }
+func (fakeSQLStmt) Query([]driver.Value) (driver.Rows, error)  { return &fakeSQLRows{}, nil }
+
+type fakeSQLRows struct{ n int }
+
+func (r *fakeSQLRows) Columns() []string { return []string{"id", "note"} }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if r.n == 3 {
+		return io.EOF
+	}
+	r.n++
+	dest[0] = int64(r.n)
+	dest[1] = []byte(strings.Repeat("x", 10*r.n))
+	return nil
+}
+
+var registerFakeSQL sync.Once
+
+func openFakeSQL(t *testing.T) (*sql.DB, *fakeSQLDriver) {
+	t.Helper()
+	d := &fakeSQLDriver{}
+	registerFakeSQL.Do(func() { sql.Register("hyperserve-fake", &fakeSQLDispatcher{}) })
+	fakeSQLDrivers.Store(t.Name(), d)
+	db, err := sql.Open("hyperserve-fake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+// fakeSQLDispatcher routes connections to the driver of the test named in the DSN.
+type fakeSQLDispatcher struct{}
+
+var fakeSQLDrivers sync.Map
+
+func (fakeSQLDispatcher) Open(name string) (driver.Conn, error) {
+	d, _ := fakeSQLDrivers.Load(name)
+	return d.(*fakeSQLDriver).Open(name)
+}
+
+func TestSQLQueryToolReadOnly(t *testing.T) {
+	db, d := openFakeSQL(t)
+	tool := NewSQLQueryTool(db, SQLQueryToolOptions{ReadOnly: true, MaxRows: 2, MaxCellBytes: 15})
+
+	result, err := tool.Execute(map[string]interface{}{"query": "-- recent\nSELECT id, note FROM notes WHERE id > ?", "args": []interface{}{0.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := result.(map[string]interface{})
+	rows := r["rows"].([][]interface{})
+	if len(rows) != 2 || r["truncated"] != true {
+		t.Fatalf("expected two rows and truncation, got %v", r)
+	}
+	if rows[1][1] != strings.Repeat("x", 15)+"…" {
+		t.Errorf("long value was not truncated: %q", rows[1][1])
+	}
+	if len(d.readOnly) != 1 || !d.readOnly[0] {
+		t.Errorf("query did not run in a read-only transaction: %v", d.readOnly)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM notes",
+		"/* harmless */ DROP TABLE notes",
+		"SELECT 1; DELETE FROM notes",
+	} {
+		if _, err := tool.Execute(map[string]interface{}{"query": query}); err == nil {
+			t.Errorf("expected %q to be rejected", query)
+		}
+	}
+	if len(d.queries) != 1 {
+		t.Errorf("rejected statements reached the database: %v", d.queries)
+	}
+}
+
+func TestSQLQueryToolAllowlist(t *testing.T) {
+	db, d := openFakeSQL(t)
+	tool := NewSQLQueryTool(db, SQLQueryToolOptions{Name: "orders_db", AllowedStatements: []string{"select", "update"}})
+
+	result, err := tool.Execute(map[string]interface{}{"query": "UPDATE orders SET status = 'shipped';"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.(map[string]interface{})["rows_affected"] != int64(2) {
+		t.Errorf("unexpected result %v", result)
+	}
+	if _, err := tool.Execute(map[string]interface{}{"query": "DELETE FROM orders"}); err == nil {
+		t.Error("expected DELETE to be rejected")
+	}
+	if tool.Name() != "orders_db" || len(d.readOnly) != 0 {
+		t.Errorf("unexpected name %q or transaction %v", tool.Name(), d.readOnly)
+	}
+}
+
+func TestSQLQueryToolStatementChecks(t *testing.T) {
+	db, d := openFakeSQL(t)
+	tool := NewSQLQueryTool(db, SQLQueryToolOptions{ReadOnly: true})
+
+	for _, query := range []string{
+		"SELECT 'a;b', \"odd;column\" FROM notes;",
+		"SELECT $1, ':' FROM notes -- trailing; comment",
+		"SELECT 1 /* ; */ FROM notes",
+		"EXPLAIN SELECT * FROM notes",
+		"WITH recent AS (SELECT * FROM notes) SELECT * FROM recent",
+	} {
+		if _, err := tool.Execute(map[string]interface{}{"query": query}); err != nil {
+			t.Errorf("expected %q to be allowed, got %v", query, err)
+		}
+	}
+
+	for _, query := range []string{
+		"WITH gone AS (DELETE FROM notes RETURNING *) SELECT * FROM gone",
+		"EXPLAIN ANALYZE DELETE FROM notes",
+		"EXPLAIN (ANALYZE, BUFFERS) SELECT * FROM notes",
+		"SELECT 'unterminated; DROP TABLE notes",
+		"SELECT 'a\\'; DROP TABLE notes; -- '",
+		"SELECT 1 --x\n; DROP TABLE notes",
+		"SELECT 1 # '\n; DROP TABLE notes; -- '",
+		"SELECT /* /* */ ' */ ; DROP TABLE notes; '",
+		"/* only a comment */",
+	} {
+		if _, err := tool.Execute(map[string]interface{}{"query": query}); err == nil {
+			t.Errorf("expected %q to be rejected", query)
+		}
+	}
+	if len(d.queries) != 5 {
+		t.Errorf("expected only the allowed queries to reach the database, got %v", d.queries)
+	}
+
+	writer := NewSQLQueryTool(db, SQLQueryToolOptions{AllowedStatements: []string{"SELECT", "WITH"}})
+	if _, err := writer.Execute(map[string]interface{}{"query": "WITH gone AS (DELETE FROM notes RETURNING *) SELECT * FROM gone"}); err == nil {
+		t.Error("expected the DELETE in a CTE to require DELETE to be allowed")
+	}
+	d.readOnly = nil
+	if _, err := writer.Execute(map[string]interface{}{"query": "SELECT * FROM notes"}); err != nil || len(d.readOnly) != 1 || !d.readOnly[0] {
+		t.Errorf("expected reads to run in a read-only transaction, got %v and %v", err, d.readOnly)
+	}
+}
@@ -334,3 +334,82 @@ type failingWriter struct{}
 func (w *failingWriter) Write(p []byte) (n int, err error) {
 	return 0, errors.New("write failed")
 }
+
+// TestStdioTransport_ContentLengthFraming tests clients that frame messages with headers
+func TestStdioTransport_ContentLengthFraming(t *testing.T) {
+	body := `{"jsonrpc":"2.0","method":"ping","id":7}`
+	input := "Content-Length: " + strconv.Itoa(len(body)) + "\r\nContent-Type: application/json\r\n\r\n" + body +
+		"content-length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	var outputBuf bytes.Buffer
+	transport := NewStdioTransportWithIO(strings.NewReader(input), &outputBuf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := 0; i < 2; i++ {
+		request, err := transport.Receive()
+		if err != nil {
+			t.Fatalf("Failed to receive framed request %d: %v", i, err)
+		}
+		if request.Method != "ping" {
+			t.Errorf("Expected method 'ping', got %s", request.Method)
+		}
+	}
+	if _, err := transport.Receive(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+
+	if err := transport.Send(&JSONRPCResponse{JSONRPC: JSONRPCVersion, Result: "pong", ID: float64(7)}); err != nil {
+		t.Fatal(err)
+	}
+	header, payload, ok := strings.Cut(outputBuf.String(), "\r\n\r\n")
+	if !ok || header != "Content-Length: "+strconv.Itoa(len(payload)) {
+		t.Errorf("Response is not framed: %q", outputBuf.String())
+	}
+}
+
+// TestMCPHandler_StdioLoopRecovers tests that invalid messages do not stop the loop
+func TestMCPHandler_StdioLoopRecovers(t *testing.T) {
+	input := "not json\n" +
+		`{"jsonrpc":"2.0","method":"test","params":"` + strings.Repeat("x", stdioMaxMessageSize) + `","id":1}` + "\n" +
+		"\n" +
+		`{"jsonrpc":"2.0","method":"ping","id":2}` + "\n"
+	var outputBuf bytes.Buffer
+	handler := NewMCPHandler(MCPServerInfo{Name: "test-server", Version: "1.0.0"})
+	handler.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	transport := NewStdioTransportWithIO(strings.NewReader(input), &outputBuf, handler.logger)
+
+	if err := handler.serveStdio(transport); err != nil {
+		t.Fatalf("serveStdio: %v", err)
+	}
+
+	var codes []int
+	var pong bool
+	dec := json.NewDecoder(&outputBuf)
+	for dec.More() {
+		var resp JSONRPCResponse
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("decode output: %v", err)
+		}
+		if resp.Error != nil {
+			codes = append(codes, resp.Error.Code)
+		} else if resp.ID == float64(2) {
+			pong = true
+		}
+	}
+	if len(codes) != 2 || codes[0] != ErrorCodeParseError || codes[1] != ErrorCodeInvalidRequest {
+		t.Errorf("Unexpected error codes %v", codes)
+	}
+	if !pong {
+		t.Error("Valid request after invalid messages was not answered")
+	}
+}
+
+// TestMCPHandler_StdioLoopWriteFailure tests that the loop stops when stdout fails
+func TestMCPHandler_StdioLoopWriteFailure(t *testing.T) {
+	handler := NewMCPHandler(MCPServerInfo{Name: "test-server", Version: "1.0.0"})
+	handler.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	input := strings.Repeat(`{"jsonrpc":"2.0","method":"ping","id":1}`+"\n", 3)
+	transport := NewStdioTransportWithIO(strings.NewReader(input), &failingWriter{}, handler.logger)
+
+	if err := handler.serveStdio(transport); err == nil {
+		t.Error("Expected an error when stdout fails")
+	}
+}
@@ -0,0 +1,627 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// =============================================================================
+// STREAMABLE HTTP TRANSPORT (2025-03-26 and later)
+// =============================================================================
+//
+// Clients of the Streamable HTTP transport POST JSON-RPC messages to the MCP endpoint
+// accepting both application/json and text/event-stream. The response to initialize
+// carries an Mcp-Session-Id header that the client sends with every later request.
+// Requests asking for progress are answered with an SSE stream carrying the progress
+// notifications followed by the response; all others with plain JSON. A GET with the
+// session ID opens a stream for server-initiated messages, a GET with Last-Event-ID
+// resumes an interrupted stream, and a DELETE ends the session.
+//
+// Requests without these headers are served as before, so 2024-11-05 clients keep
+// working with the HTTP+SSE transport.
+
+const (
+	mcpSessionHeader         = "Mcp-Session-Id"
+	mcpProtocolVersionHeader = "Mcp-Protocol-Version"
+
+	mcpSessionIdleTimeout = 30 * time.Minute
+	mcpStreamReplayEvents = 100 // Events kept per stream for resumption
+	mcpSessionStreams     = 32  // Streams kept per session for resumption
+)
+
+// isStreamableRequest reports whether r uses the Streamable HTTP transport.
+func isStreamableRequest(r *http.Request) bool {
+	if r.Header.Get(mcpSessionHeader) != "" || r.Header.Get(mcpProtocolVersionHeader) != "" {
+		return true
+	}
+	switch r.Method {
+	case http.MethodDelete:
+		return true
+	case http.MethodPost:
+		accept := strings.ToLower(r.Header.Get("Accept"))
+		return strings.Contains(accept, "text/event-stream") && isJSONAccepted(accept)
+	}
+	return false
+}
+
+// serveStreamable handles a request of the Streamable HTTP transport.
+func (h *MCPHandler) serveStreamable(w http.ResponseWriter, r *http.Request) {
+	if v := r.Header.Get(mcpProtocolVersionHeader); v != "" && !slices.Contains(mcpSupportedVersions, v) {
+		http.Error(w, fmt.Sprintf("Unsupported protocol version %q", v), http.StatusBadRequest)
+		return
+	}
+
+	var session *mcpSession
+	if id := r.Header.Get(mcpSessionHeader); id != "" {
+		if session = h.sessions.get(id); session == nil {
+			// The client must start a new session with initialize
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		h.serveStreamablePost(w, r, session)
+	case http.MethodGet:
+		if session == nil {
+			http.Error(w, "Mcp-Session-Id header required", http.StatusBadRequest)
+			return
+		}
+		h.serveStreamableGet(w, r, session)
+	case http.MethodDelete:
+		if session == nil {
+			http.Error(w, "Mcp-Session-Id header required", http.StatusBadRequest)
+			return
+		}
+		h.sessions.remove(session.id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *MCPHandler) serveStreamablePost(w http.ResponseWriter, r *http.Request, session *mcpSession) {
+	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
+		return
+	}
+	messages, batch, err := decodeJSONRPCMessages(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(createErrorResponse(ErrorCodeParseError, "Parse error", err.Error()))
+		return
+	}
+
+	ctx, cancel := h.streamableContext(r, session, false)
+	defer cancel()
+	var requests []*JSONRPCRequest
+	initialize, stream := false, false
+	for _, msg := range messages {
+		switch {
+		case msg.isResponse():
+			// A response to a sampling or elicitation request
+			h.deliverClientResponse(msg)
+		case msg.Method == "":
+			h.logger.Debug("Ignoring JSON-RPC message without method", "id", msg.ID)
+		case msg.ID == nil:
+			h.processNotification(ctx, &msg.JSONRPCRequest)
+		default:
+			requests = append(requests, &msg.JSONRPCRequest)
+			initialize = initialize || msg.Method == "initialize"
+			stream = stream || hasProgressToken(&msg.JSONRPCRequest)
+		}
+	}
+	if initialize && batch {
+		http.Error(w, "initialize must not be part of a batch", http.StatusBadRequest)
+		return
+	}
+	if len(requests) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if initialize && h.sessions.full() {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Too many MCP sessions", http.StatusServiceUnavailable)
+		return
+	}
+
+	if stream && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.streamResponses(w, r, session, requests)
+		return
+	}
+
+	responses := make([]*JSONRPCResponse, 0, len(requests))
+	for _, req := range requests {
+		responses = append(responses, h.processRequest(ctx, "streamable-http", req))
+	}
+	if initialize && responses[0].Error == nil {
+		version := MCPVersion
+		if result, ok := responses[0].Result.(map[string]interface{}); ok {
+			version, _ = result["protocolVersion"].(string)
+		}
+		if session = h.sessions.create(version); session == nil {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Too many MCP sessions", http.StatusServiceUnavailable)
+			return
+		}
+		h.state.session(session.id).setRootsSupported(initializeDeclaresRoots(requests[0].Params))
+		h.logger.Debug("MCP session started", "session", session.id, "protocol_version", version)
+	}
+
+	if session != nil {
+		w.Header().Set(mcpSessionHeader, session.id)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	var body interface{} = responses[0]
+	if batch {
+		body = responses
+	}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("Failed to write MCP response", "error", err)
+	}
+}
+
+// streamResponses answers requests with an SSE stream. The requests run to completion
+// even if the client disconnects, so that it can resume the stream with Last-Event-ID.
+func (h *MCPHandler) streamResponses(w http.ResponseWriter, r *http.Request, session *mcpSession, requests []*JSONRPCRequest) {
+	stream := newMCPStream("0")
+	if session != nil {
+		stream = session.newStream()
+		w.Header().Set(mcpSessionHeader, session.id)
+	}
+	ctx, cancel := h.streamableContext(r, session, true)
+	ctx = context.WithValue(ctx, mcpNotifierKey, mcpNotifier(stream))
+
+	go func() {
+		defer cancel()
+		defer stream.finish()
+		for _, req := range requests {
+			stream.sendMessage(h.processRequest(ctx, "streamable-http", req))
+		}
+	}()
+
+	startEventStream(w)
+	if err := stream.serve(r.Context(), w, 0); err != nil {
+		h.logger.Debug("MCP stream interrupted", "stream", stream.id, "error", err)
+	}
+}
+
+// streamableContext returns the context the messages of r are processed in. It carries the
+// values of the request context and ends with the request, or with the session. A
+// detached context outlives the request, for streams that the client can resume.
+func (h *MCPHandler) streamableContext(r *http.Request, session *mcpSession, detached bool) (context.Context, context.CancelFunc) {
+	ctx := withMCPRequest(r.Context(), r)
+	if detached {
+		ctx = context.WithoutCancel(ctx)
+	}
+	if session == nil {
+		return context.WithCancel(ctx)
+	}
+	ctx, cancel := context.WithCancel(h.withMCPSession(ctx, session.id))
+	stop := context.AfterFunc(session.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// serveStreamableGet resumes a stream after Last-Event-ID, or opens the stream for
+// messages the server sends outside of a request.
+func (h *MCPHandler) serveStreamableGet(w http.ResponseWriter, r *http.Request, session *mcpSession) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "Accept must include text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		streamID, seq, ok := parseMCPEventID(lastID)
+		stream := session.stream(streamID)
+		if !ok || stream == nil {
+			http.Error(w, "Unknown Last-Event-ID", http.StatusNotFound)
+			return
+		}
+		startEventStream(w)
+		stream.serve(r.Context(), w, seq)
+		return
+	}
+
+	stream, ok := session.attachStandalone()
+	if !ok {
+		http.Error(w, "A stream is already open for this session", http.StatusConflict)
+		return
+	}
+	defer session.detachStandalone()
+	startEventStream(w)
+	stream.serve(r.Context(), w, stream.deliveredSeq())
+}
+
+// SendSessionNotification sends a notification to a Streamable HTTP session on its GET
+// stream. Notifications sent while no stream is open are delivered when the client
+// connects, up to a limit of recent messages.
+func (h *MCPHandler) SendSessionNotification(sessionID, method string, params interface{}) error {
+	session := h.sessions.get(sessionID)
+	if session == nil {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	return session.standaloneStream().Notify(method, params)
+}
+
+// processNotification runs a notification from the client, which gets no response.
+func (h *MCPHandler) processNotification(ctx context.Context, msg *JSONRPCRequest) {
+	if !slices.Contains(h.rpcEngine.GetRegisteredMethods(), msg.Method) {
+		h.logger.Debug("Ignoring unsupported MCP notification", "method", msg.Method)
+		return
+	}
+	h.processRequest(ctx, "streamable-http", msg)
+}
+
+func hasProgressToken(req *JSONRPCRequest) bool {
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	meta, ok := params["_meta"].(map[string]interface{})
+	return ok && meta["progressToken"] != nil
+}
+
+// decodeJSONRPCMessages reads a single message or a batch.
+func decodeJSONRPCMessages(body io.Reader) ([]*jsonrpcMessage, bool, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var messages []*jsonrpcMessage
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, true, err
+		}
+		if len(messages) == 0 {
+			return nil, true, fmt.Errorf("empty batch")
+		}
+		return messages, true, nil
+	}
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, false, err
+	}
+	return []*jsonrpcMessage{&msg}, false, nil
+}
+
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's write timeout
+	rc.SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+}
+
+// mcpSessionStore holds the Streamable HTTP sessions. Idle sessions expire.
+type mcpSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*mcpSession
+	max      int // Sessions that can be open at once
+	idle     time.Duration
+	onClose  func(id string) // Called when a session ends, nil if unset
+}
+
+func newMCPSessionStore() *mcpSessionStore {
+	return &mcpSessionStore{sessions: make(map[string]*mcpSession), max: defaultMCPMaxSessions, idle: mcpSessionIdleTimeout}
+}
+
+// full reports whether no further session can be created.
+func (s *mcpSessionStore) full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	return s.max > 0 && len(s.sessions) >= s.max
+}
+
+// create starts a session, or returns nil if the store is full.
+func (s *mcpSessionStore) create(version string) *mcpSession {
+	var b [16]byte
+	rand.Read(b[:])
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &mcpSession{
+		id:      hex.EncodeToString(b[:]),
+		version: version,
+		ctx:     ctx,
+		cancel:  cancel,
+		streams: make(map[string]*mcpStream),
+	}
+	session.touch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	if s.max > 0 && len(s.sessions) >= s.max {
+		session.close()
+		return nil
+	}
+	s.sessions[session.id] = session
+	return session
+}
+
+func (s *mcpSessionStore) get(id string) *mcpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	session := s.sessions[id]
+	if session != nil {
+		session.touch()
+	}
+	return session
+}
+
+// all returns the live sessions.
+func (s *mcpSessionStore) all() []*mcpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	sessions := make([]*mcpSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func (s *mcpSessionStore) remove(id string) {
+	s.mu.Lock()
+	session := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if session != nil {
+		session.close()
+		if s.onClose != nil {
+			s.onClose(id)
+		}
+	}
+}
+
+func (s *mcpSessionStore) expireLocked() {
+	cutoff := time.Now().Add(-s.idle).UnixNano()
+	for id, session := range s.sessions {
+		if session.lastSeen.Load() < cutoff && !session.streaming() {
+			delete(s.sessions, id)
+			session.close()
+			if s.onClose != nil {
+				s.onClose(id)
+			}
+		}
+	}
+}
+
+// mcpSession is a Streamable HTTP session.
+type mcpSession struct {
+	id       string
+	version  string          // Negotiated protocol revision
+	ctx      context.Context // Cancelled when the session ends
+	cancel   context.CancelFunc
+	lastSeen atomic.Int64
+
+	mu         sync.Mutex
+	streams    map[string]*mcpStream
+	order      []string // Stream IDs, oldest first
+	nextStream int
+	standalone *mcpStream
+	attached   bool // A GET stream is connected to standalone
+}
+
+func (s *mcpSession) touch() {
+	s.lastSeen.Store(time.Now().UnixNano())
+}
+
+func (s *mcpSession) newStream() *mcpStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextStream++
+	stream := newMCPStream(strconv.Itoa(s.nextStream))
+	s.streams[stream.id] = stream
+	s.order = append(s.order, stream.id)
+	if len(s.order) > mcpSessionStreams {
+		for i, id := range s.order {
+			if s.streams[id].isDone() {
+				delete(s.streams, id)
+				s.order = slices.Delete(s.order, i, i+1)
+				break
+			}
+		}
+	}
+	return stream
+}
+
+func (s *mcpSession) stream(id string) *mcpStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == "0" {
+		return s.standalone
+	}
+	return s.streams[id]
+}
+
+func (s *mcpSession) standaloneStream() *mcpStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.standalone == nil {
+		s.standalone = newMCPStream("0")
+	}
+	return s.standalone
+}
+
+func (s *mcpSession) attachStandalone() (*mcpStream, bool) {
+	stream := s.standaloneStream()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attached {
+		return nil, false
+	}
+	s.attached = true
+	return stream, true
+}
+
+func (s *mcpSession) detachStandalone() {
+	s.mu.Lock()
+	s.attached = false
+	s.mu.Unlock()
+	s.touch()
+}
+
+// streaming reports whether a GET stream is connected, which keeps the session alive.
+func (s *mcpSession) streaming() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attached
+}
+
+func (s *mcpSession) close() {
+	s.cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stream := range s.streams {
+		stream.finish()
+	}
+	if s.standalone != nil {
+		s.standalone.finish()
+	}
+}
+
+// mcpStream is an SSE stream of a session. It keeps its recent events so that a client
+// can reconnect and resume after the last event it received.
+type mcpStream struct {
+	id string
+
+	mu        sync.Mutex
+	events    []mcpStreamEvent
+	seq       int
+	delivered int // Last event written to a client
+	done      bool
+	wake      chan struct{} // Closed and replaced when an event is added or the stream ends
+}
+
+type mcpStreamEvent struct {
+	seq  int
+	data []byte
+}
+
+func newMCPStream(id string) *mcpStream {
+	return &mcpStream{id: id, wake: make(chan struct{})}
+}
+
+// Notify queues a notification, making the stream an mcpNotifier.
+func (s *mcpStream) Notify(method string, params interface{}) error {
+	return s.sendMessage(jsonrpcNotification{JSONRPC: JSONRPCVersion, Method: method, Params: params})
+}
+
+func (s *mcpStream) sendMessage(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return fmt.Errorf("stream closed")
+	}
+	s.seq++
+	s.events = append(s.events, mcpStreamEvent{seq: s.seq, data: data})
+	if len(s.events) > mcpStreamReplayEvents {
+		s.events = slices.Delete(s.events, 0, len(s.events)-mcpStreamReplayEvents)
+	}
+	close(s.wake)
+	s.wake = make(chan struct{})
+	return nil
+}
+
+// finish ends the stream once its events are delivered.
+func (s *mcpStream) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.done = true
+		close(s.wake)
+	}
+}
+
+func (s *mcpStream) isDone() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+func (s *mcpStream) deliveredSeq() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delivered
+}
+
+// serve writes the events after sequence number after, then follows the stream until it
+// ends or ctx is cancelled.
+func (s *mcpStream) serve(ctx context.Context, w http.ResponseWriter, after int) error {
+	rc := http.NewResponseController(w)
+	for {
+		s.mu.Lock()
+		var pending []mcpStreamEvent
+		for _, e := range s.events {
+			if e.seq > after {
+				pending = append(pending, e)
+			}
+		}
+		done, wake := s.done, s.wake
+		s.mu.Unlock()
+
+		for _, e := range pending {
+			frame, err := encodeSSEFrame(fmt.Sprintf("%s-%d", s.id, e.seq), "", e.data)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(frame); err != nil {
+				return err
+			}
+			after = e.seq
+		}
+		if len(pending) > 0 {
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+			s.mu.Lock()
+			s.delivered = max(s.delivered, after)
+			s.mu.Unlock()
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// parseMCPEventID splits an event ID of the form "<stream>-<seq>".
+func parseMCPEventID(id string) (string, int, bool) {
+	streamID, seqStr, ok := strings.Cut(id, "-")
+	if !ok {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(seqStr)
+	return streamID, seq, err == nil
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postStreamable(t *testing.T, url, session, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set(mcpSessionHeader, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+type sseEvent struct {
+	id   string
+	data map[string]interface{}
+}
+
+// readSSEEvents reads events until the stream ends or n events were read.
+func readSSEEvents(t *testing.T, resp *http.Response, n int) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.data); err != nil {
+				t.Fatalf("invalid event data %q: %v", line, err)
+			}
+		case line == "":
+			events = append(events, current)
+			current = sseEvent{}
+		}
+	}
+	return events
+}
+
+func initializeStreamable(t *testing.T, url, version string) (string, map[string]interface{}) {
+	t.Helper()
+	resp := postStreamable(t, url, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+version+`","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize: status %d", resp.StatusCode)
+	}
+	var body JSONRPCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Header.Get(mcpSessionHeader), body.Result.(map[string]interface{})
+}
+
+func TestMCPStreamableSessionLifecycle(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	session, result := initializeStreamable(t, ts.URL, "2025-03-26")
+	if session == "" {
+		t.Fatal("expected an Mcp-Session-Id header")
+	}
+	if result["protocolVersion"] != "2025-03-26" {
+		t.Errorf("expected the requested version, got %v", result["protocolVersion"])
+	}
+
+	if resp := postStreamable(t, ts.URL, session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification: expected 202, got %d", resp.StatusCode)
+	}
+
+	resp := postStreamable(t, ts.URL, session, `[{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":3,"method":"tools/list"}]`)
+	var batch []JSONRPCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 2 || batch[0].ID != float64(2) || batch[1].ID != float64(3) {
+		t.Errorf("unexpected batch response: %+v", batch)
+	}
+
+	if resp := postStreamable(t, ts.URL, "unknown", `{"jsonrpc":"2.0","id":4,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown session: expected 404, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set(mcpSessionHeader, session)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del.Body.Close()
+	if del.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: expected 204, got %d", del.StatusCode)
+	}
+	if resp := postStreamable(t, ts.URL, session, `{"jsonrpc":"2.0","id":5,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deleted session: expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestMCPStreamableVersionNegotiation(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	if _, result := initializeStreamable(t, ts.URL, "2099-01-01"); result["protocolVersion"] != MCPLatestVersion {
+		t.Errorf("unsupported version: expected %s, got %v", MCPLatestVersion, result["protocolVersion"])
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(mcpProtocolVersionHeader, "1999-01-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsupported protocol version header: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestMCPStreamableProgressStreamAndResume(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&countingTool{})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	session, _ := initializeStreamable(t, ts.URL, MCPLatestVersion)
+	call := `{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{"name":"count","arguments":{},"_meta":{"progressToken":"p"}}}`
+	resp := postStreamable(t, ts.URL, session, call)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+	events := readSSEEvents(t, resp, 10)
+	if len(events) != 4 {
+		t.Fatalf("expected 3 progress notifications and the response, got %d", len(events))
+	}
+	if events[0].data["method"] != "notifications/progress" || events[3].data["id"] != float64(9) {
+		t.Errorf("unexpected events: %+v", events)
+	}
+
+	// Resume after the first event, as a client would after losing the connection
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(mcpSessionHeader, session)
+	req.Header.Set("Last-Event-ID", events[0].id)
+	resumed, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Body.Close()
+	replayed := readSSEEvents(t, resumed, 10)
+	if len(replayed) != 3 || replayed[0].id != events[1].id || replayed[2].data["id"] != float64(9) {
+		t.Errorf("expected the events after %s, got %+v", events[0].id, replayed)
+	}
+
+	// Without a progress token the response is plain JSON
+	plain := postStreamable(t, ts.URL, session, `{"jsonrpc":"2.0","id":10,"method":"tools/call","params":{"name":"count","arguments":{}}}`)
+	if ct := plain.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON without a progress token, got %q", ct)
+	}
+}
+
+func TestMCPStreamableServerNotifications(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	session, _ := initializeStreamable(t, ts.URL, MCPLatestVersion)
+	if err := h.SendSessionNotification(session, "notifications/tools/list_changed", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(mcpSessionHeader, session)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		h.SendSessionNotification(session, "notifications/resources/list_changed", nil)
+	}()
+	events := readSSEEvents(t, resp, 2)
+	if len(events) != 2 || events[0].data["method"] != "notifications/tools/list_changed" || events[1].data["method"] != "notifications/resources/list_changed" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+
+	second, err := http.DefaultClient.Do(req.Clone(req.Context()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Body.Close()
+	if second.StatusCode != http.StatusConflict {
+		t.Errorf("second GET stream: expected 409, got %d", second.StatusCode)
+	}
+}
+
+func TestMCPStreamableLegacyClientsUnchanged(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get(mcpSessionHeader) != "" {
+		t.Error("legacy clients must not get a session")
+	}
+	var body JSONRPCResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	if result, _ := body.Result.(map[string]interface{}); result["protocolVersion"] != MCPVersion {
+		t.Errorf("expected %s, got %v", MCPVersion, body.Result)
+	}
+}
+
+func TestMCPStreamableRequestContext(t *testing.T) {
+	type traceKey struct{}
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&testContextTool{name: "trace", executeFunc: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		trace, _ := ctx.Value(traceKey{}).(string)
+		return trace, nil
+	}})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), traceKey{}, "abc")))
+	}))
+	defer ts.Close()
+
+	session, _ := initializeStreamable(t, ts.URL, "2025-03-26")
+	resp := postStreamable(t, ts.URL, session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"trace","arguments":{}}}`)
+	var body JSONRPCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(body.Result); !strings.Contains(string(data), "abc") {
+		t.Errorf("expected the tool to see the request context, got %s", data)
+	}
+}
+
+func TestMCPStreamableMaxSessions(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.SetSessionLimits(1, 0)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	session, _ := initializeStreamable(t, ts.URL, "2025-03-26")
+	init := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+	if resp := postStreamable(t, ts.URL, "", init); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 beyond the session limit, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set(mcpSessionHeader, session)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del.Body.Close()
+	if next, _ := initializeStreamable(t, ts.URL, "2025-03-26"); next == "" {
+		t.Error("expected a session once the first one ended")
+	}
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorCodeMCPDisabled is the JSON-RPC error code for requests received while MCP is
+// disabled at runtime.
+const ErrorCodeMCPDisabled = -32004
+
+// mcpShutdownNotification tells connected clients that the server is closing their
+// connection because MCP was disabled.
+const mcpShutdownNotification = "notifications/shutdown"
+
+// mcpRegistrySnapshot holds the registrations of a disabled handler until it is enabled.
+type mcpRegistrySnapshot struct {
+	tools     map[string]MCPTool
+	resources map[string]MCPResource
+	templates []*compiledResourceTemplate
+}
+
+// Enabled reports whether the handler accepts requests. It is false after Disable.
+func (h *MCPHandler) Enabled() bool {
+	return !h.disabled.Load()
+}
+
+// Disable stops serving MCP without restarting the server. New requests are rejected with
+// 503 Service Unavailable over HTTP and ErrorCodeMCPDisabled over stdio; SSE, WebSocket,
+// and Streamable HTTP clients receive a notifications/shutdown notification and are
+// disconnected. Registered tools, resources, and templates are removed from the registry
+// and the resource cache is dropped. Disable returns false if the handler was already
+// disabled.
+func (h *MCPHandler) Disable(reason string) bool {
+	if !h.disabled.CompareAndSwap(false, true) {
+		return false
+	}
+	h.broadcastNotification(mcpShutdownNotification, map[string]interface{}{"reason": reason})
+
+	h.listenersMu.Lock()
+	listeners := make([]mcpNotifier, 0, len(h.listeners))
+	for n := range h.listeners {
+		listeners = append(listeners, n)
+	}
+	h.listenersMu.Unlock()
+	for _, n := range listeners {
+		// stdio stays open so that the client is told why its requests fail
+		if c, ok := n.(io.Closer); ok {
+			if _, stdio := n.(*stdioTransport); !stdio {
+				c.Close()
+			}
+		}
+	}
+	h.sseManager.closeAll()
+	for _, session := range h.sessions.all() {
+		h.sessions.remove(session.id)
+	}
+
+	h.registryMu.Lock()
+	h.suspended = &mcpRegistrySnapshot{tools: h.tools, resources: h.resources, templates: h.templates}
+	h.tools = make(map[string]MCPTool)
+	h.resources = make(map[string]MCPResource)
+	h.templates = nil
+	h.registryMu.Unlock()
+	h.cache.clear()
+
+	h.logger.Warn("MCP disabled", "reason", reason)
+	return true
+}
+
+// Enable resumes serving MCP after Disable. The registrations removed by Disable are
+// restored; tools and resources registered while disabled take precedence over them.
+// Enable returns false if the handler was not disabled.
+func (h *MCPHandler) Enable() bool {
+	h.registryMu.Lock()
+	if !h.disabled.Load() {
+		h.registryMu.Unlock()
+		return false
+	}
+	if s := h.suspended; s != nil {
+		for name, tool := range s.tools {
+			if _, exists := h.tools[name]; !exists {
+				h.tools[name] = tool
+			}
+		}
+		for uri, resource := range s.resources {
+			if _, exists := h.resources[uri]; !exists {
+				h.resources[uri] = resource
+			}
+		}
+		h.templates = append(s.templates, h.templates...)
+		h.suspended = nil
+	}
+	h.disabled.Store(false)
+	h.registryMu.Unlock()
+
+	h.logger.Info("MCP enabled")
+	return true
+}
+
+// writeDisabled rejects an HTTP request received while MCP is disabled.
+func (h *MCPHandler) writeDisabled(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(createErrorResponse(ErrorCodeMCPDisabled, "MCP is disabled", nil))
+}
+
+// SetMCPEnabled turns MCP on or off at runtime, so operators can shut off the AI surface
+// during an incident without restarting. See MCPHandler.Disable for what disabling does.
+// It returns an error if the server was not configured with MCP support.
+//
+// The same switch is available over HTTP with WithMCPAdminEndpoint, and the
+// HS_MCP_SUSPENDED environment variable starts the server with MCP disabled.
+func (srv *Server) SetMCPEnabled(enabled bool) error {
+	if !srv.MCPEnabled() {
+		return fmt.Errorf("MCP is not enabled on this server")
+	}
+	if enabled {
+		srv.mcpHandler.Enable()
+	} else {
+		srv.mcpHandler.Disable("disabled by operator")
+	}
+	return nil
+}
+
+// MCPActive reports whether MCP is configured and currently serving requests.
+func (srv *Server) MCPActive() bool {
+	return srv.MCPEnabled() && srv.mcpHandler.Enabled()
+}
+
+// WithMCPAdminEndpoint serves the MCP kill switch at path. GET returns {"enabled": bool};
+// POST with ?enabled=false or a {"enabled": false} body disables MCP, and enabled=true
+// enables it again. Tokens accepted by AuthMiddleware are not enough: the endpoint
+// requires the operator token set with WithMCPAdminToken, or a request from a loopback
+// address that was not forwarded by a proxy. The path can also be set via the
+// "mcp_admin_path" key in options.json or the HS_MCP_ADMIN_PATH environment variable.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("app", "1.0.0"),
+//		server.WithMCPAdminEndpoint("/admin/mcp"),
+//		server.WithMCPAdminToken(os.Getenv("MCP_ADMIN_TOKEN")),
+//	)
+//	// curl -X POST -H "Authorization: Bearer $MCP_ADMIN_TOKEN" 'https://example.com/admin/mcp?enabled=false'
+func WithMCPAdminEndpoint(path string) ServerOptionFunc {
+	return func(srv *Server) error {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("MCP admin path must start with '/': %q", path)
+		}
+		srv.Options.MCPAdminPath = path
+		return nil
+	}
+}
+
+// WithMCPAdminToken sets the bearer token that authorizes requests to the MCP admin
+// endpoint from other hosts, see WithMCPAdminEndpoint. It can also be set via the
+// HS_MCP_ADMIN_TOKEN environment variable, including as a secret reference.
+func WithMCPAdminToken(token string) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.MCPAdminToken = token
+		return nil
+	}
+}
+
+// registerMCPAdmin wires the kill switch under Options.MCPAdminPath.
+func (srv *Server) registerMCPAdmin() {
+	srv.Handle(srv.Options.MCPAdminPath, mcpAdminGuard(srv.Options.MCPAdminToken, srv.mcpAdminHandler))
+	logger.Info("MCP admin endpoint enabled", "path", srv.Options.MCPAdminPath, "token", srv.Options.MCPAdminToken != "")
+}
+
+// mcpAdminGuard restricts the kill switch to the operator token and local clients.
+func mcpAdminGuard(token string, next http.HandlerFunc) http.HandlerFunc {
+	return operatorGuard(token, "The MCP admin endpoint", next)
+}
+
+// operatorGuard admits requests carrying the operator token, and requests without
+// credentials from loopback addresses. Loopback requests with forwarding headers are
+// rejected, as a local reverse proxy makes every client appear to be local. Endpoint
+// names the guarded endpoint in error messages.
+func operatorGuard(token, endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get(authorizationHeader); auth != "" {
+			bearer, ok := strings.CutPrefix(auth, "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				writeErrorResponse(w, http.StatusForbidden, endpoint+" requires the operator token")
+				return
+			}
+			next(w, r)
+			return
+		}
+		ip := net.ParseIP(clientIP(r))
+		if ip == nil || !ip.IsLoopback() || isForwardedRequest(r) {
+			writeErrorResponse(w, http.StatusForbidden, endpoint+" is restricted to localhost or the operator token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isForwardedRequest reports whether r was relayed by a proxy.
+func isForwardedRequest(r *http.Request) bool {
+	for _, name := range []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Host", "X-Real-Ip"} {
+		if r.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (srv *Server) mcpAdminHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		enabled, err := parseMCPAdminRequest(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := srv.SetMCPEnabled(enabled); err != nil {
+			writeErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": srv.MCPActive()})
+}
+
+// parseMCPAdminRequest reads the desired state from the query or a JSON body.
+func parseMCPAdminRequest(r *http.Request) (bool, error) {
+	if v := r.URL.Query().Get("enabled"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid enabled value: %q", v)
+		}
+		return enabled, nil
+	}
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(&body); err != nil || body.Enabled == nil {
+		return false, fmt.Errorf(`expected ?enabled=true|false or a {"enabled": bool} body`)
+	}
+	return *body.Enabled, nil
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+func TestMCPDisableClosesWebSocketClients(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.wsUpgrader = newMCPUpgrader()
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	_, fr, fw := dialGatewayWebSocket(t, ts.URL)
+
+	writeClientFrame(t, fw, ws.OpcodeText, []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	readMCPFrame(t, fr)
+
+	if !h.Disable("incident") {
+		t.Fatal("Disable reported the handler as already disabled")
+	}
+	msg := readMCPFrame(t, fr).(map[string]interface{})
+	if msg["method"] != mcpShutdownNotification || msg["params"].(map[string]interface{})["reason"] != "incident" {
+		t.Fatalf("expected a shutdown notification, got %v", msg)
+	}
+	if frame, err := fr.ReadFrame(); err == nil && frame.Opcode != ws.OpcodeClose {
+		t.Errorf("expected the connection to close, got opcode %v", frame.Opcode)
+	}
+	if h.Disable("again") {
+		t.Error("Disable of a disabled handler reported a change")
+	}
+}
+
+func TestMCPDisableRejectsRequests(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&countingTool{})
+	h.Disable("incident")
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+	var resp JSONRPCResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error == nil || resp.Error.Code != ErrorCodeMCPDisabled {
+		t.Errorf("unexpected body %s", rec.Body.String())
+	}
+
+	msgs := runStdioRequest(t, h, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if errObj, _ := msgs[len(msgs)-1]["error"].(map[string]interface{}); errObj == nil || errObj["code"] != float64(ErrorCodeMCPDisabled) {
+		t.Errorf("stdio request was not rejected: %v", msgs)
+	}
+	if _, ok := h.GetToolByName("count"); ok {
+		t.Error("registry was not cleared")
+	}
+
+	if !h.Enable() {
+		t.Fatal("Enable reported the handler as already enabled")
+	}
+	if _, ok := h.GetToolByName("count"); !ok {
+		t.Error("registry was not restored")
+	}
+}
+
+func TestMCPAdminEndpoint(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"), WithMCPAdminEndpoint("/admin/mcp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := func(method, target string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:4321"
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := admin(http.MethodPost, "/admin/mcp?enabled=false", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"enabled":false`) {
+		t.Fatalf("disable failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if srv.MCPActive() {
+		t.Error("MCP is still active")
+	}
+	if rec := admin(http.MethodGet, "/mcp/discover", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("discovery status = %d, want 503", rec.Code)
+	}
+	if rec := admin(http.MethodPost, "/admin/mcp", `{"enabled": true}`); !strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Errorf("enable failed: %s", rec.Body.String())
+	}
+	if rec := admin(http.MethodPost, "/admin/mcp", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for a request without a state, want 400", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/mcp?enabled=false", nil)
+	rec := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || !srv.MCPActive() {
+		t.Errorf("remote client was not rejected: %d", rec.Code)
+	}
+
+	// A local reverse proxy makes remote clients appear to be local
+	req = httptest.NewRequest(http.MethodPost, "/admin/mcp?enabled=false", nil)
+	req.RemoteAddr = "127.0.0.1:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	rec = httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || !srv.MCPActive() {
+		t.Errorf("forwarded client was not rejected: %d", rec.Code)
+	}
+}
+
+func TestMCPAdminEndpointRequiresOperatorToken(t *testing.T) {
+	srv, err := NewServer(
+		WithMCPSupport("test", "1.0.0"),
+		WithMCPAdminEndpoint("/admin/mcp"),
+		WithMCPAdminToken("operator-token"),
+		WithAuthTokenValidator(func(token string) (bool, error) { return true, nil }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/mcp?enabled=false", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := admin("any-user-token"); code != http.StatusForbidden || !srv.MCPActive() {
+		t.Errorf("a token accepted by the validator flipped the switch: %d", code)
+	}
+	if code := admin("operator-token"); code != http.StatusOK || srv.MCPActive() {
+		t.Errorf("operator token was rejected: %d", code)
+	}
+}
+
+func TestSetMCPEnabledWithoutMCP(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.SetMCPEnabled(false); err == nil {
+		t.Error("expected an error when MCP is not configured")
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrorCodeToolBusy is returned for tool calls rejected because the tool or the server
+// is running as many calls as its concurrency limit allows.
+const ErrorCodeToolBusy = -32030
+
+// defaultToolQueueTimeout is how long a queued tool call waits for a slot by default.
+const defaultToolQueueTimeout = 10 * time.Second
+
+// MCPToolRejection selects how tool calls over the concurrency limit are answered.
+type MCPToolRejection string
+
+const (
+	// MCPRejectWithError answers with a JSON-RPC error (ErrorCodeToolBusy) that carries
+	// retry_after. This is the default.
+	MCPRejectWithError MCPToolRejection = "error"
+	// MCPRejectWithToolResult answers with a tool result flagged isError, so that the
+	// model reads the message and can back off instead of the client failing the call.
+	MCPRejectWithToolResult MCPToolRejection = "tool_result"
+)
+
+// MCPToolConcurrencyConfig caps how many tools/call requests run at once. Calls over a
+// cap wait in a bounded queue and are rejected when the queue is full or their wait
+// times out.
+type MCPToolConcurrencyConfig struct {
+	MaxConcurrent int              `json:"max_concurrent,omitempty"` // Running calls across all tools, 0 for no limit
+	PerTool       map[string]int   `json:"per_tool,omitempty"`       // Running calls per tool name
+	QueueSize     int              `json:"queue_size,omitempty"`     // Calls that may wait for a slot, 0 rejects at once
+	QueueTimeout  time.Duration    `json:"queue_timeout,omitempty"`  // How long a queued call waits, default 10s
+	Rejection     MCPToolRejection `json:"rejection,omitempty"`      // How rejected calls are answered, default "error"
+}
+
+// WithMCPToolConcurrency limits how many MCP tool calls run at once, overall and per
+// tool, so that a client calling expensive tools in a loop cannot exhaust the server.
+// Limits can also be configured via the "mcp_tool_concurrency" key in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("MyApp", "1.0.0"),
+//		server.WithMCPToolConcurrency(server.MCPToolConcurrencyConfig{
+//			MaxConcurrent: 16,
+//			PerTool:       map[string]int{"mcp__reports__render": 2},
+//			QueueSize:     32,
+//			QueueTimeout:  5 * time.Second,
+//		}),
+//	)
+func WithMCPToolConcurrency(cfg MCPToolConcurrencyConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.MCPToolConcurrency = &cfg
+		return nil
+	}
+}
+
+// mcpToolLimiter enforces MCPToolConcurrencyConfig with semaphores.
+type mcpToolLimiter struct {
+	global    chan struct{}            // nil when there is no global limit
+	perTool   map[string]chan struct{} // Tools without an entry are not limited
+	queue     chan struct{}            // Slots for waiting calls, nil when calls never wait
+	timeout   time.Duration
+	rejection MCPToolRejection
+}
+
+func newMCPToolLimiter(cfg MCPToolConcurrencyConfig) (*mcpToolLimiter, error) {
+	if cfg.MaxConcurrent < 0 || cfg.QueueSize < 0 || cfg.QueueTimeout < 0 {
+		return nil, fmt.Errorf("MCP tool concurrency: limits must not be negative")
+	}
+	switch cfg.Rejection {
+	case "", MCPRejectWithError, MCPRejectWithToolResult:
+	default:
+		return nil, fmt.Errorf("MCP tool concurrency: unknown rejection %q", cfg.Rejection)
+	}
+	l := &mcpToolLimiter{
+		perTool:   make(map[string]chan struct{}),
+		timeout:   cfg.QueueTimeout,
+		rejection: cfg.Rejection,
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.global = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	for name, n := range cfg.PerTool {
+		if n < 0 {
+			return nil, fmt.Errorf("MCP tool concurrency %s: limit must not be negative", name)
+		}
+		if n > 0 {
+			l.perTool[name] = make(chan struct{}, n)
+		}
+	}
+	if cfg.QueueSize > 0 {
+		l.queue = make(chan struct{}, cfg.QueueSize)
+	}
+	if l.timeout == 0 {
+		l.timeout = defaultToolQueueTimeout
+	}
+	return l, nil
+}
+
+// acquire takes a slot for a call of tool, waiting in the queue if needed, and returns
+// the function that gives it back.
+func (l *mcpToolLimiter) acquire(ctx context.Context, tool string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	toolSem := l.perTool[tool]
+	release := func() {
+		if l.global != nil {
+			<-l.global
+		}
+		if toolSem != nil {
+			<-toolSem
+		}
+	}
+
+	if tryAcquire(toolSem) {
+		if tryAcquire(l.global) {
+			return release, nil
+		}
+		if toolSem != nil {
+			<-toolSem
+		}
+	}
+
+	if l.queue == nil {
+		return nil, l.busy(tool, "concurrency limit reached")
+	}
+	if !tryAcquire(l.queue) {
+		return nil, l.busy(tool, "queue full")
+	}
+	defer func() { <-l.queue }()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	wait := func(sem chan struct{}) error {
+		if sem == nil {
+			return nil
+		}
+		select {
+		case sem <- struct{}{}:
+			return nil
+		case <-timer.C:
+			return l.busy(tool, "queue timeout")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := wait(toolSem); err != nil {
+		return nil, err
+	}
+	if err := wait(l.global); err != nil {
+		if toolSem != nil {
+			<-toolSem
+		}
+		return nil, err
+	}
+	return release, nil
+}
+
+// tryAcquire takes a slot of sem without waiting; a nil sem always has room.
+func tryAcquire(sem chan struct{}) bool {
+	if sem == nil {
+		return true
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *mcpToolLimiter) busy(tool, reason string) error {
+	logger.Debug("MCP tool call rejected", "tool", tool, "reason", reason)
+	return &JSONRPCError{
+		Code:    ErrorCodeToolBusy,
+		Message: "Tool busy",
+		Data: map[string]interface{}{
+			"tool":        tool,
+			"reason":      reason,
+			"retry_after": 1,
+		},
+	}
+}
+
+// busyResult turns a rejection into a tool result when the limiter is configured to
+// answer rejected calls that way.
+func (l *mcpToolLimiter) busyResult(err error) (map[string]interface{}, bool) {
+	var rpcErr *JSONRPCError
+	if l == nil || l.rejection != MCPRejectWithToolResult || !errors.As(err, &rpcErr) || rpcErr.Code != ErrorCodeToolBusy {
+		return nil, false
+	}
+	data, _ := rpcErr.Data.(map[string]interface{})
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": fmt.Sprintf("Tool %v is busy (%v); retry in a few seconds.", data["tool"], data["reason"]),
+			},
+		},
+		"isError": true,
+	}, true
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingTool runs until release is closed and reports each start on started.
+type blockingTool struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *blockingTool) Name() string        { return t.name }
+func (t *blockingTool) Description() string { return "Blocks until released" }
+func (t *blockingTool) Schema() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *blockingTool) Execute(map[string]interface{}) (interface{}, error) {
+	t.started <- struct{}{}
+	<-t.release
+	return "done", nil
+}
+
+func newConcurrencyHandler(t *testing.T, cfg MCPToolConcurrencyConfig) (*MCPHandler, *blockingTool) {
+	t.Helper()
+	limiter, err := newMCPToolLimiter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.toolLimiter = limiter
+	tool := &blockingTool{name: "render", started: make(chan struct{}, 10), release: make(chan struct{})}
+	h.RegisterTool(tool)
+	return h, tool
+}
+
+func callRender(h *MCPHandler) chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.handleToolsCallContext(context.Background(), map[string]interface{}{"name": "render"})
+		done <- err
+	}()
+	return done
+}
+
+func TestMCPToolConcurrencyRejects(t *testing.T) {
+	h, tool := newConcurrencyHandler(t, MCPToolConcurrencyConfig{PerTool: map[string]int{"render": 1}})
+
+	first := callRender(h)
+	<-tool.started
+
+	_, err := h.handleToolsCallContext(context.Background(), map[string]interface{}{"name": "render"})
+	var rpcErr *JSONRPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != ErrorCodeToolBusy {
+		t.Fatalf("expected a busy error, got %v", err)
+	}
+
+	close(tool.release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.handleToolsCallContext(context.Background(), map[string]interface{}{"name": "render"}); err != nil {
+		t.Errorf("expected the call to run once the slot is free, got %v", err)
+	}
+}
+
+func TestMCPToolConcurrencyQueue(t *testing.T) {
+	h, tool := newConcurrencyHandler(t, MCPToolConcurrencyConfig{
+		MaxConcurrent: 1,
+		QueueSize:     1,
+		QueueTimeout:  time.Second,
+	})
+
+	first := callRender(h)
+	<-tool.started
+	queued := callRender(h)
+
+	// The queue has one slot, which the second call is waiting in.
+	deadline := time.Now().Add(time.Second)
+	for len(h.toolLimiter.queue) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := h.handleToolsCallContext(context.Background(), map[string]interface{}{"name": "render"}); err == nil {
+		t.Fatal("expected a call to be rejected when the queue is full")
+	}
+
+	close(tool.release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-queued; err != nil {
+		t.Errorf("expected the queued call to run, got %v", err)
+	}
+}
+
+func TestMCPToolConcurrencyQueueTimeout(t *testing.T) {
+	h, tool := newConcurrencyHandler(t, MCPToolConcurrencyConfig{
+		MaxConcurrent: 1,
+		QueueSize:     1,
+		QueueTimeout:  20 * time.Millisecond,
+		Rejection:     MCPRejectWithToolResult,
+	})
+	defer close(tool.release)
+
+	callRender(h)
+	<-tool.started
+
+	result, err := h.handleToolsCallContext(context.Background(), map[string]interface{}{"name": "render"})
+	if err != nil {
+		t.Fatalf("expected a tool result, got %v", err)
+	}
+	if result.(map[string]interface{})["isError"] != true {
+		t.Errorf("expected an isError result, got %v", result)
+	}
+}
+
+func TestMCPToolConcurrencyConfigValidation(t *testing.T) {
+	if _, err := NewServer(WithMCPSupport("test", "1.0.0"), WithMCPToolConcurrency(MCPToolConcurrencyConfig{MaxConcurrent: -1})); err == nil {
+		t.Error("expected a negative limit to be rejected")
+	}
+	if _, err := newMCPToolLimiter(MCPToolConcurrencyConfig{Rejection: "drop"}); err == nil {
+		t.Error("expected an unknown rejection to be rejected")
+	}
+}
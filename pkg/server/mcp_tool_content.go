@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// MCPToolContent is a content block of a tool result: MCPTextContent, MCPImageContent,
+// MCPAudioContent, MCPResourceLink, or MCPEmbeddedResource.
+type MCPToolContent interface {
+	toolContent() map[string]interface{}
+}
+
+// MCPTextContent is text for the model to read.
+type MCPTextContent struct {
+	Text string
+}
+
+// MCPImageContent is an image, such as a chart or a screenshot, that clients display
+// and pass to the model.
+type MCPImageContent struct {
+	Data     []byte
+	MimeType string // Such as "image/png"
+}
+
+// MCPAudioContent is an audio clip.
+type MCPAudioContent struct {
+	Data     []byte
+	MimeType string // Such as "audio/wav"
+}
+
+// MCPResourceLink points to a resource the client can read with resources/read instead
+// of including its contents in the result.
+type MCPResourceLink struct {
+	URI         string
+	Name        string
+	Description string
+	MimeType    string
+	Size        int64 // In bytes, 0 if unknown
+}
+
+// MCPEmbeddedResource includes the contents of a resource in the result. Set Text for
+// text resources and Blob for binary ones.
+type MCPEmbeddedResource struct {
+	URI      string
+	MimeType string
+	Text     string
+	Blob     []byte
+}
+
+// MCPCallToolResult is a tool result made of typed content blocks. Tools return it, or a
+// single MCPToolContent or []MCPToolContent, to send images, audio, and resources that
+// clients render natively instead of a text block of JSON.
+//
+// Example:
+//
+//	func (t *ChartTool) Execute(params map[string]interface{}) (interface{}, error) {
+//		png, err := t.render(params)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return &server.MCPCallToolResult{
+//			Content: []server.MCPToolContent{
+//				server.MCPTextContent{Text: "Requests per minute over the last hour"},
+//				server.MCPImageContent{Data: png, MimeType: "image/png"},
+//				server.MCPResourceLink{URI: "metrics://server/routes", Name: "Route metrics"},
+//			},
+//		}, nil
+//	}
+type MCPCallToolResult struct {
+	Content           []MCPToolContent
+	StructuredContent interface{} // JSON value matching the tool's output schema, sent as structuredContent
+	IsError           bool        // The tool failed; the content describes the error for the model
+}
+
+func (c MCPTextContent) toolContent() map[string]interface{} {
+	return map[string]interface{}{"type": "text", "text": c.Text}
+}
+
+func (c MCPImageContent) toolContent() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "image",
+		"data":     base64.StdEncoding.EncodeToString(c.Data),
+		"mimeType": c.MimeType,
+	}
+}
+
+func (c MCPAudioContent) toolContent() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "audio",
+		"data":     base64.StdEncoding.EncodeToString(c.Data),
+		"mimeType": c.MimeType,
+	}
+}
+
+func (c MCPResourceLink) toolContent() map[string]interface{} {
+	block := map[string]interface{}{"type": "resource_link", "uri": c.URI, "name": c.Name}
+	if c.Description != "" {
+		block["description"] = c.Description
+	}
+	if c.MimeType != "" {
+		block["mimeType"] = c.MimeType
+	}
+	if c.Size > 0 {
+		block["size"] = c.Size
+	}
+	return block
+}
+
+func (c MCPEmbeddedResource) toolContent() map[string]interface{} {
+	resource := map[string]interface{}{"uri": c.URI}
+	if c.MimeType != "" {
+		resource["mimeType"] = c.MimeType
+	}
+	if c.Blob != nil {
+		resource["blob"] = base64.StdEncoding.EncodeToString(c.Blob)
+	} else {
+		resource["text"] = c.Text
+	}
+	return map[string]interface{}{"type": "resource", "resource": resource}
+}
+
+// toolContentResponse builds the tools/call response for results made of typed content
+// blocks, reporting false for other results.
+func toolContentResponse(result interface{}) (map[string]interface{}, bool) {
+	var r MCPCallToolResult
+	switch v := result.(type) {
+	case *MCPCallToolResult:
+		if v == nil {
+			return nil, false
+		}
+		r = *v
+	case MCPCallToolResult:
+		r = v
+	case []MCPToolContent:
+		r.Content = v
+	case MCPToolContent:
+		r.Content = []MCPToolContent{v}
+	default:
+		return nil, false
+	}
+
+	content := make([]map[string]interface{}, 0, len(r.Content))
+	for _, block := range r.Content {
+		if block != nil {
+			content = append(content, block.toolContent())
+		}
+	}
+	if len(content) == 0 && r.StructuredContent != nil {
+		// Clients that ignore structuredContent still get the result as text
+		if data, err := json.Marshal(r.StructuredContent); err == nil {
+			content = append(content, MCPTextContent{Text: string(data)}.toolContent())
+		}
+	}
+
+	response := map[string]interface{}{"content": content}
+	if r.StructuredContent != nil {
+		response["structuredContent"] = r.StructuredContent
+	}
+	if r.IsError {
+		response["isError"] = true
+	}
+	return response, true
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func callContentTool(t *testing.T, result interface{}) map[string]interface{} {
+	t.Helper()
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(NewTool("chart").WithExecute(func(map[string]interface{}) (interface{}, error) {
+		return result, nil
+	}).Build())
+	response, err := h.handleToolsCallContext(context.Background(), map[string]interface{}{"name": "chart"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.(map[string]interface{})
+}
+
+func TestMCPToolContentBlocks(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	response := callContentTool(t, &MCPCallToolResult{
+		Content: []MCPToolContent{
+			MCPTextContent{Text: "Requests per minute"},
+			MCPImageContent{Data: png, MimeType: "image/png"},
+			MCPAudioContent{Data: []byte("RIFF"), MimeType: "audio/wav"},
+			MCPResourceLink{URI: "metrics://server/routes", Name: "Route metrics", Size: 512},
+			MCPEmbeddedResource{URI: "config://server/current", MimeType: "application/json", Text: "{}"},
+			MCPEmbeddedResource{URI: "file:///logo.png", Blob: png},
+		},
+	})
+
+	want := []map[string]interface{}{
+		{"type": "text", "text": "Requests per minute"},
+		{"type": "image", "data": base64.StdEncoding.EncodeToString(png), "mimeType": "image/png"},
+		{"type": "audio", "data": base64.StdEncoding.EncodeToString([]byte("RIFF")), "mimeType": "audio/wav"},
+		{"type": "resource_link", "uri": "metrics://server/routes", "name": "Route metrics", "size": int64(512)},
+		{"type": "resource", "resource": map[string]interface{}{"uri": "config://server/current", "mimeType": "application/json", "text": "{}"}},
+		{"type": "resource", "resource": map[string]interface{}{"uri": "file:///logo.png", "blob": base64.StdEncoding.EncodeToString(png)}},
+	}
+	if got := response["content"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected content\n got: %v\nwant: %v", got, want)
+	}
+	if _, ok := response["isError"]; ok {
+		t.Error("expected no isError flag")
+	}
+}
+
+func TestMCPToolContentSingleBlock(t *testing.T) {
+	response := callContentTool(t, MCPResourceLink{URI: "posts://42", Name: "Post 42"})
+	content := response["content"].([]map[string]interface{})
+	if len(content) != 1 || content[0]["type"] != "resource_link" {
+		t.Errorf("unexpected content %v", content)
+	}
+}
+
+func TestMCPToolStructuredContent(t *testing.T) {
+	structured := map[string]interface{}{"count": 3}
+	response := callContentTool(t, MCPCallToolResult{StructuredContent: structured, IsError: true})
+
+	if !reflect.DeepEqual(response["structuredContent"], structured) {
+		t.Errorf("unexpected structured content %v", response["structuredContent"])
+	}
+	content := response["content"].([]map[string]interface{})
+	if len(content) != 1 || content[0]["text"] != `{"count":3}` {
+		t.Errorf("expected the structured content as text, got %v", content)
+	}
+	if response["isError"] != true {
+		t.Error("expected the isError flag")
+	}
+}
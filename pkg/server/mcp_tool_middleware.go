@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+const mcpRequestKey contextKey = "mcpRequest"
+
+// ToolCall describes one tools/call invocation as it passes through the tool middleware.
+type ToolCall struct {
+	Name      string
+	Arguments map[string]interface{} // Validated against the tool's schema; middleware may replace them
+	Tool      MCPTool
+	Request   *http.Request // The HTTP request that carried the call, nil for stdio
+}
+
+// ToolExecutor executes a tool call and returns the tool's result.
+type ToolExecutor func(ctx context.Context, call *ToolCall) (interface{}, error)
+
+// ToolMiddleware wraps the execution of every MCP tool. Middleware can inspect or rewrite
+// the call, deny it by returning an error without calling next, or post-process the
+// result, which makes it the place for per-tool authorization, argument redaction, and
+// audit logging.
+type ToolMiddleware func(next ToolExecutor) ToolExecutor
+
+// UseToolMiddleware adds middleware to all tool executions. Middleware added first runs
+// outermost. It runs after the arguments have been validated and within the tool's timeout.
+func (h *MCPHandler) UseToolMiddleware(mw ...ToolMiddleware) {
+	h.toolMiddlewareMu.Lock()
+	defer h.toolMiddlewareMu.Unlock()
+	h.toolMiddleware = append(h.toolMiddleware, mw...)
+}
+
+// AddMCPToolMiddleware adds middleware to all MCP tool executions.
+//
+// Example:
+//
+//	srv.AddMCPToolMiddleware(func(next server.ToolExecutor) server.ToolExecutor {
+//		return func(ctx context.Context, call *server.ToolCall) (interface{}, error) {
+//			if call.Name == "deploy" && !isAdmin(call.Request) {
+//				return nil, errors.New("forbidden")
+//			}
+//			return next(ctx, call)
+//		}
+//	})
+func (srv *Server) AddMCPToolMiddleware(mw ToolMiddleware) {
+	if srv.mcpHandler == nil {
+		logger.Warn("MCP is not enabled, ignoring tool middleware")
+		return
+	}
+	srv.mcpHandler.UseToolMiddleware(mw)
+}
+
+// toolExecutor returns the executor for a call: the tool itself wrapped in the middleware.
+func (h *MCPHandler) toolExecutor(progressCtx context.Context, meta *MCPRequestMeta) ToolExecutor {
+	exec := func(ctx context.Context, call *ToolCall) (interface{}, error) {
+		if clientTool, ok := call.Tool.(MCPToolWithClient); ok {
+			progress := newProgressReporter(progressCtx, h, meta)
+			defer progress.finish()
+			return clientTool.ExecuteWithClient(ctx, call.Arguments, &clientRequester{progressReporter: progress, h: h, ctx: progressCtx})
+		}
+		if progressTool, ok := call.Tool.(MCPToolWithProgress); ok {
+			progress := newProgressReporter(progressCtx, h, meta)
+			defer progress.finish()
+			return progressTool.ExecuteWithProgress(ctx, call.Arguments, progress)
+		}
+		return wrapToolWithContext(call.Tool).ExecuteWithContext(ctx, call.Arguments)
+	}
+
+	h.toolMiddlewareMu.RLock()
+	defer h.toolMiddlewareMu.RUnlock()
+	for i := len(h.toolMiddleware) - 1; i >= 0; i-- {
+		exec = h.toolMiddleware[i](exec)
+	}
+	return exec
+}
+
+// withMCPRequest puts the HTTP request that carries MCP messages into ctx.
+func withMCPRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, mcpRequestKey, r)
+}
+
+func mcpRequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(mcpRequestKey).(*http.Request)
+	return r
+}
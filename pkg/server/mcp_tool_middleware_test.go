@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToolMiddlewareOrder(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(validationTool())
+
+	var calls []string
+	trace := func(name string) ToolMiddleware {
+		return func(next ToolExecutor) ToolExecutor {
+			return func(ctx context.Context, call *ToolCall) (interface{}, error) {
+				calls = append(calls, name+":"+call.Name)
+				return next(ctx, call)
+			}
+		}
+	}
+	h.UseToolMiddleware(trace("outer"), trace("inner"))
+
+	if _, err := h.handleToolsCall(map[string]interface{}{"name": "deploy", "arguments": map[string]interface{}{"service": "api"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"outer:deploy", "inner:deploy"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestToolMiddlewareDeniesAndRewrites(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(validationTool())
+	h.UseToolMiddleware(func(next ToolExecutor) ToolExecutor {
+		return func(ctx context.Context, call *ToolCall) (interface{}, error) {
+			switch call.Arguments["service"] {
+			case "billing":
+				return nil, errors.New("forbidden")
+			case "api":
+				call.Arguments["service"] = "api-v2"
+			}
+			return next(ctx, call)
+		}
+	})
+
+	if _, err := h.handleToolsCall(map[string]interface{}{"name": "deploy", "arguments": map[string]interface{}{"service": "billing"}}); err == nil {
+		t.Error("expected the middleware to deny the call")
+	}
+
+	result, err := h.handleToolsCall(map[string]interface{}{"name": "deploy", "arguments": map[string]interface{}{"service": "api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.(map[string]interface{})["content"].([]map[string]interface{})[0]["text"]; text != "api-v2" {
+		t.Errorf("text = %v, want rewritten argument", text)
+	}
+}
+
+func TestToolMiddlewareWrapsProgressTools(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&countingTool{})
+	seen := false
+	h.UseToolMiddleware(func(next ToolExecutor) ToolExecutor {
+		return func(ctx context.Context, call *ToolCall) (interface{}, error) {
+			seen = true
+			return next(ctx, call)
+		}
+	})
+
+	messages := runStdioRequest(t, h, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"count","arguments":{},"_meta":{"progressToken":"tok-1"}}}`)
+	if !seen {
+		t.Error("middleware did not run for a progress tool")
+	}
+	if len(messages) != 4 {
+		t.Errorf("expected 3 notifications and the response, got %d: %v", len(messages), messages)
+	}
+}
+
+func TestToolMiddlewareSeesHTTPRequest(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(validationTool())
+	var user string
+	h.UseToolMiddleware(func(next ToolExecutor) ToolExecutor {
+		return func(ctx context.Context, call *ToolCall) (interface{}, error) {
+			if call.Request != nil {
+				user = call.Request.Header.Get("X-User")
+			}
+			return next(ctx, call)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"deploy","arguments":{"service":"api"}}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", "alice")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if user != "alice" {
+		t.Errorf("middleware saw user %q, want alice", user)
+	}
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// JSON-RPC error codes for tool calls rejected by an MCPToolPolicy.
+const (
+	ErrorCodeToolForbidden   = -32003
+	ErrorCodeToolRateLimited = -32029
+)
+
+// toolLimiterMaxEntries bounds the per-caller limiters before idle ones are dropped.
+const toolLimiterMaxEntries = 10000
+
+// MCPPrincipal identifies the caller of an MCP tool.
+type MCPPrincipal struct {
+	Subject string   // Stable caller ID, also the rate limit key
+	Roles   []string // Application roles, e.g. "admin"
+	Scopes  []string // OAuth scopes granted to the access token
+}
+
+// MCPPrincipalFunc resolves the principal for the HTTP request that carried a tool call.
+// A nil principal means the caller is anonymous.
+type MCPPrincipalFunc func(r *http.Request) (*MCPPrincipal, error)
+
+// MCPToolPolicy restricts who may call a tool and how often.
+type MCPToolPolicy struct {
+	Roles     []string  `json:"roles,omitempty"`      // The caller needs one of these roles
+	Scopes    []string  `json:"scopes,omitempty"`     // The caller needs all of these scopes
+	RateLimit RateLimit `json:"rate_limit,omitempty"` // Calls per second per caller, 0 for no limit
+	Burst     int       `json:"burst,omitempty"`      // Defaults to 1
+}
+
+// WithMCPToolPolicy restricts an MCP tool to callers with the given roles or scopes and
+// limits how often each caller may call it. Policies are enforced before the arguments are
+// validated. Calls over stdio have no principal, so they are denied by role and scope
+// restrictions. Policies can also be configured via the "mcp_tool_policies" key in
+// options.json, keyed by tool name.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("MyApp", "1.0.0"),
+//		server.WithMCPToolPolicy("mcp__hyperserve__write_file", server.MCPToolPolicy{
+//			Roles:     []string{"admin"},
+//			RateLimit: 1,
+//			Burst:     5,
+//		}),
+//	)
+func WithMCPToolPolicy(tool string, policy MCPToolPolicy) ServerOptionFunc {
+	return func(srv *Server) error {
+		if srv.Options.MCPToolPolicies == nil {
+			srv.Options.MCPToolPolicies = make(map[string]MCPToolPolicy)
+		}
+		srv.Options.MCPToolPolicies[tool] = policy
+		return nil
+	}
+}
+
+// WithMCPPrincipal configures how the caller of an MCP tool is identified for tool
+// policies. By default the principal is derived from the token introspection result when
+// WithIntrospection is configured.
+func WithMCPPrincipal(fn MCPPrincipalFunc) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.MCPPrincipalFunc = fn
+		return nil
+	}
+}
+
+// introspectionPrincipal derives the principal from the introspection result of the
+// access token that authenticated the request.
+func (srv *Server) introspectionPrincipal(r *http.Request) (*MCPPrincipal, error) {
+	token, ok := r.Context().Value(sessionIDKey).(string)
+	if !ok || srv.introspection == nil {
+		return nil, nil
+	}
+	res, err := srv.introspection.Introspect(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Active {
+		return nil, nil
+	}
+	subject := res.Sub
+	if subject == "" {
+		subject = res.Username
+	}
+	if subject == "" {
+		subject = res.ClientID
+	}
+	return &MCPPrincipal{Subject: subject, Scopes: strings.Fields(res.Scope)}, nil
+}
+
+// mcpToolPolicies enforces MCPToolPolicy for tools/call.
+type mcpToolPolicies struct {
+	policies  map[string]MCPToolPolicy
+	principal MCPPrincipalFunc
+
+	mu       sync.Mutex
+	limiters map[string]*rateLimiterEntry // Keyed by tool and caller
+}
+
+func newMCPToolPolicies(policies map[string]MCPToolPolicy, principal MCPPrincipalFunc) (*mcpToolPolicies, error) {
+	for name, policy := range policies {
+		if policy.RateLimit < 0 || policy.Burst < 0 {
+			return nil, fmt.Errorf("MCP tool policy %s: rate limit and burst must not be negative", name)
+		}
+	}
+	return &mcpToolPolicies{
+		policies:  policies,
+		principal: principal,
+		limiters:  make(map[string]*rateLimiterEntry),
+	}, nil
+}
+
+// authorize checks the policy of the tool against the caller of the request in ctx.
+func (p *mcpToolPolicies) authorize(ctx context.Context, tool string) error {
+	if p == nil {
+		return nil
+	}
+	policy, ok := p.policies[tool]
+	if !ok {
+		return nil
+	}
+
+	r := mcpRequestFromContext(ctx)
+	var principal *MCPPrincipal
+	if r != nil && p.principal != nil {
+		var err error
+		if principal, err = p.principal(r); err != nil {
+			return fmt.Errorf("failed to identify caller: %w", err)
+		}
+	}
+
+	if !policy.permits(principal) {
+		return &JSONRPCError{
+			Code:    ErrorCodeToolForbidden,
+			Message: "Forbidden",
+			Data:    map[string]interface{}{"tool": tool},
+		}
+	}
+
+	if policy.RateLimit > 0 {
+		key := "local"
+		switch {
+		case principal != nil && principal.Subject != "":
+			key = principal.Subject
+		case r != nil:
+			key = clientIP(r)
+		}
+		if wait := p.reserve(tool+"\x00"+key, policy); wait > 0 {
+			return &JSONRPCError{
+				Code:    ErrorCodeToolRateLimited,
+				Message: "Rate limit exceeded",
+				Data: map[string]interface{}{
+					"tool":        tool,
+					"retry_after": int(math.Ceil(wait.Seconds())),
+				},
+			}
+		}
+	}
+	return nil
+}
+
+// permits reports whether the principal has one of the roles and all of the scopes.
+func (policy MCPToolPolicy) permits(principal *MCPPrincipal) bool {
+	if len(policy.Roles) == 0 && len(policy.Scopes) == 0 {
+		return true
+	}
+	if principal == nil {
+		return false
+	}
+	if len(policy.Roles) > 0 && !slices.ContainsFunc(policy.Roles, func(role string) bool {
+		return slices.Contains(principal.Roles, role)
+	}) {
+		return false
+	}
+	for _, scope := range policy.Scopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// reserve takes a token from the caller's limiter and returns how long to wait if none
+// is available.
+func (p *mcpToolPolicies) reserve(key string, policy MCPToolPolicy) time.Duration {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.limiters[key]
+	if !ok {
+		if len(p.limiters) >= toolLimiterMaxEntries {
+			for k, e := range p.limiters {
+				if now.Sub(e.lastAccess) > 10*time.Minute {
+					delete(p.limiters, k)
+				}
+			}
+		}
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(policy.RateLimit, max(policy.Burst, 1))}
+		p.limiters[key] = entry
+	}
+	entry.lastAccess = now
+
+	reservation := entry.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
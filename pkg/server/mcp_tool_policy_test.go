@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newToolPolicyServer(t *testing.T, policy MCPToolPolicy) *Server {
+	t.Helper()
+	srv, err := NewServer(
+		WithMCPSupport("test", "1.0.0"),
+		WithMCPToolPolicy("deploy", policy),
+		WithMCPPrincipal(func(r *http.Request) (*MCPPrincipal, error) {
+			user := r.Header.Get("X-User")
+			if user == "" {
+				return nil, nil
+			}
+			return &MCPPrincipal{Subject: user, Roles: strings.Split(r.Header.Get("X-Roles"), ",")}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterMCPTool(validationTool()); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func callDeploy(t *testing.T, srv *Server, user, roles string) *JSONRPCResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"deploy","arguments":{"service":"api"}}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", user)
+	req.Header.Set("X-Roles", roles)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	var resp JSONRPCResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+	}
+	return &resp
+}
+
+func TestMCPToolPolicyRoles(t *testing.T) {
+	srv := newToolPolicyServer(t, MCPToolPolicy{Roles: []string{"admin"}})
+
+	if resp := callDeploy(t, srv, "", ""); resp.Error == nil || resp.Error.Code != ErrorCodeToolForbidden {
+		t.Errorf("anonymous caller: expected forbidden, got %+v", resp)
+	}
+	if resp := callDeploy(t, srv, "bob", "viewer"); resp.Error == nil || resp.Error.Code != ErrorCodeToolForbidden {
+		t.Errorf("viewer: expected forbidden, got %+v", resp)
+	}
+	if resp := callDeploy(t, srv, "alice", "viewer,admin"); resp.Error != nil {
+		t.Errorf("admin: unexpected error %+v", resp.Error)
+	}
+
+	// stdio calls have no principal
+	resp := srv.mcpHandler.rpcEngine.ProcessRequestContext(context.Background(), &JSONRPCRequest{
+		JSONRPC: JSONRPCVersion,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "deploy", "arguments": map[string]interface{}{"service": "api"}},
+		ID:      1,
+	})
+	if resp.Error == nil || resp.Error.Code != ErrorCodeToolForbidden {
+		t.Errorf("stdio: expected forbidden, got %+v", resp)
+	}
+}
+
+func TestMCPToolPolicyRateLimit(t *testing.T) {
+	srv := newToolPolicyServer(t, MCPToolPolicy{RateLimit: 0.001, Burst: 2})
+
+	for i := 0; i < 2; i++ {
+		if resp := callDeploy(t, srv, "alice", ""); resp.Error != nil {
+			t.Fatalf("call %d: unexpected error %+v", i, resp.Error)
+		}
+	}
+	resp := callDeploy(t, srv, "alice", "")
+	if resp.Error == nil || resp.Error.Code != ErrorCodeToolRateLimited {
+		t.Fatalf("expected rate limit error, got %+v", resp)
+	}
+	if data := resp.Error.Data.(map[string]interface{}); data["retry_after"].(float64) < 1 {
+		t.Errorf("unexpected error data %v", data)
+	}
+
+	if resp := callDeploy(t, srv, "bob", ""); resp.Error != nil {
+		t.Errorf("another caller was limited: %+v", resp.Error)
+	}
+}
+
+func TestMCPToolPolicyScopes(t *testing.T) {
+	policy := MCPToolPolicy{Scopes: []string{"tools:write", "tools:read"}}
+	tests := []struct {
+		principal *MCPPrincipal
+		want      bool
+	}{
+		{nil, false},
+		{&MCPPrincipal{Scopes: []string{"tools:read"}}, false},
+		{&MCPPrincipal{Scopes: []string{"tools:read", "tools:write"}}, true},
+	}
+	for _, tt := range tests {
+		if got := policy.permits(tt.principal); got != tt.want {
+			t.Errorf("permits(%+v) = %v, want %v", tt.principal, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultMCPTraceMaxSize    = 10 << 20 // 10 MiB
+	defaultMCPTraceMaxBackups = 3
+	defaultMCPTracePath       = "mcp-trace.ndjson"
+)
+
+// MCPTraceConfig configures mirroring of MCP traffic to an NDJSON trace file.
+// When the file exceeds MaxSize it is rotated to Path+".1", keeping MaxBackups old files.
+type MCPTraceConfig struct {
+	Path       string `json:"path"`
+	MaxSize    int64  `json:"max_size,omitempty"`    // Bytes before rotation (default 10 MiB)
+	MaxBackups int    `json:"max_backups,omitempty"` // Rotated files to keep (default 3)
+	Compress   bool   `json:"compress,omitempty"`    // Gzip rotated files
+}
+
+// MCPTraceRecord is one line of an MCP trace file: a request, its response, and timing.
+// Parameters and results are redacted before they are written.
+type MCPTraceRecord struct {
+	Time      time.Time        `json:"time"`
+	Transport string           `json:"transport"`
+	Request   *JSONRPCRequest  `json:"request"`
+	Response  *JSONRPCResponse `json:"response,omitempty"`
+	Duration  time.Duration    `json:"duration"`
+}
+
+// mcpTracer appends trace records to a size-rotated file.
+type mcpTracer struct {
+	config MCPTraceConfig
+	file   *RotatingFile
+}
+
+func newMCPTracer(cfg MCPTraceConfig) (*mcpTracer, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("MCP trace requires a file path")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultMCPTraceMaxSize
+	}
+	if cfg.MaxBackups <= 0 {
+		cfg.MaxBackups = defaultMCPTraceMaxBackups
+	}
+	file, err := NewRotatingFile(RotatingFileConfig{
+		Path:       cfg.Path,
+		MaxSize:    cfg.MaxSize,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MCP trace file: %w", err)
+	}
+	return &mcpTracer{config: cfg, file: file}, nil
+}
+
+func (t *mcpTracer) write(record *MCPTraceRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.Debug("Failed to encode MCP trace record", "error", err)
+		return
+	}
+	line = append(line, '\n')
+	if _, err := t.file.Write(line); err != nil && !errors.Is(err, os.ErrClosed) {
+		logger.Error("Failed to write MCP trace record", "error", err)
+	}
+}
+
+func (t *mcpTracer) close() error {
+	return t.file.Close()
+}
+
+// trace records a processed request if tracing is enabled.
+func (h *MCPHandler) trace(transport string, request *JSONRPCRequest, response *JSONRPCResponse, duration time.Duration) {
+	t := h.tracer.Load()
+	if t == nil || request == nil {
+		return
+	}
+	traced := *request
+	traced.Params = h.redactor.value(request.Params)
+	record := &MCPTraceRecord{
+		Time:      time.Now().UTC(),
+		Transport: transport,
+		Request:   &traced,
+		Duration:  duration,
+	}
+	if response != nil {
+		tracedResponse := *response
+		tracedResponse.Result = h.redactor.value(response.Result)
+		record.Response = &tracedResponse
+	}
+	t.write(record)
+}
+
+// transportName identifies a transport in trace records.
+func transportName(transport MCPTransport) string {
+	switch transport.(type) {
+	case *httpTransport:
+		return "http"
+	case *sseTransport:
+		return "sse"
+	case *stdioTransport:
+		return "stdio"
+	case *websocketTransport:
+		return "websocket"
+	default:
+		return "custom"
+	}
+}
+
+// StartTrace begins mirroring all MCP requests and responses to an NDJSON trace file,
+// replacing any active trace.
+func (h *MCPHandler) StartTrace(cfg MCPTraceConfig) error {
+	t, err := newMCPTracer(cfg)
+	if err != nil {
+		return err
+	}
+	if old := h.tracer.Swap(t); old != nil {
+		old.close()
+	}
+	h.logger.Info("MCP tracing started", "path", t.config.Path)
+	return nil
+}
+
+// StopTrace stops mirroring MCP traffic and closes the trace file.
+func (h *MCPHandler) StopTrace() error {
+	old := h.tracer.Swap(nil)
+	if old == nil {
+		return nil
+	}
+	h.logger.Info("MCP tracing stopped", "path", old.config.Path)
+	return old.close()
+}
+
+// TraceConfig returns the active trace configuration and whether tracing is enabled.
+func (h *MCPHandler) TraceConfig() (MCPTraceConfig, bool) {
+	t := h.tracer.Load()
+	if t == nil {
+		return MCPTraceConfig{}, false
+	}
+	return t.config, true
+}
+
+// WithMCPTrace mirrors all MCP requests and responses, with sensitive values redacted, to
+// an NDJSON trace file for later analysis or replay. Requires MCP support. In developer
+// mode tracing can also be toggled at runtime with the mcp_trace tool.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("MyApp", "1.0.0"),
+//		server.WithMCPTrace(server.MCPTraceConfig{Path: "traces/mcp.ndjson"}),
+//	)
+func WithMCPTrace(cfg MCPTraceConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if cfg.Path == "" {
+			return fmt.Errorf("MCP trace requires a file path")
+		}
+		srv.Options.MCPTrace = &cfg
+		return nil
+	}
+}
+
+// MCPTraceTool starts, stops, and reports MCP traffic tracing at runtime.
+type MCPTraceTool struct {
+	server *Server
+}
+
+func (t *MCPTraceTool) Name() string {
+	return "mcp_trace"
+}
+
+func (t *MCPTraceTool) Description() string {
+	return "Start or stop mirroring MCP requests and responses (redacted) to an NDJSON trace file, or show the trace status"
+}
+
+func (t *MCPTraceTool) Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"status", "start", "stop"},
+				"description": "Action to perform",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Trace file path relative to the working directory (start only, default " + defaultMCPTracePath + ")",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *MCPTraceTool) Execute(params map[string]interface{}) (interface{}, error) {
+	if t.server == nil || t.server.mcpHandler == nil {
+		return nil, fmt.Errorf("MCP handler not initialized")
+	}
+	h := t.server.mcpHandler
+	action, _ := params["action"].(string)
+
+	switch action {
+	case "status":
+	case "start":
+		cfg := MCPTraceConfig{Path: defaultMCPTracePath}
+		if t.server.Options.MCPTrace != nil {
+			cfg = *t.server.Options.MCPTrace
+		}
+		if path, ok := params["path"].(string); ok && path != "" {
+			if !filepath.IsLocal(path) {
+				return nil, fmt.Errorf("trace path must be relative to the working directory: %s", path)
+			}
+			cfg.Path = path
+		}
+		if err := h.StartTrace(cfg); err != nil {
+			return nil, err
+		}
+	case "stop":
+		if err := h.StopTrace(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
+	}
+
+	cfg, enabled := h.TraceConfig()
+	status := map[string]interface{}{"enabled": enabled}
+	if enabled {
+		status["path"] = cfg.Path
+		status["max_size"] = cfg.MaxSize
+		status["max_backups"] = cfg.MaxBackups
+	}
+	return status, nil
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readTraceRecords(t *testing.T, path string) []MCPTraceRecord {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open trace file: %v", err)
+	}
+	defer file.Close()
+
+	var records []MCPTraceRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record MCPTraceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid trace line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestMCPTraceRecordsRedactedTraffic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.ndjson")
+	srv, err := NewServer(
+		WithMCPSupport("test", "1.0.0"),
+		WithMCPTrace(MCPTraceConfig{Path: path}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.mcpHandler.StopTrace()
+
+	srv.RegisterMCPTool(&mockTool{
+		name: "login",
+		executeFunc: func(params map[string]interface{}) (interface{}, error) {
+			return map[string]interface{}{"user": params["user"], "access_token": "tok-123"}, nil
+		},
+	})
+
+	srv.mcpHandler.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"tools/call","id":1,` +
+		`"params":{"name":"login","arguments":{"user":"alice","password":"hunter2"}}}`))
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "tok-123") {
+		t.Fatalf("trace contains secrets: %s", data)
+	}
+
+	records := readTraceRecords(t, path)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 trace record, got %d", len(records))
+	}
+	record := records[0]
+	if record.Transport != "direct" || record.Request.Method != "tools/call" || record.Response == nil {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	args := record.Request.Params.(map[string]interface{})["arguments"].(map[string]interface{})
+	if args["user"] != "alice" || args["password"] != redactedValue {
+		t.Errorf("unexpected traced arguments: %v", args)
+	}
+}
+
+func TestMCPTraceRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.ndjson")
+	handler := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	if err := handler.StartTrace(MCPTraceConfig{Path: path, MaxSize: 300, MaxBackups: 2}); err != nil {
+		t.Fatalf("StartTrace: %v", err)
+	}
+	defer handler.StopTrace()
+
+	for i := 0; i < 20; i++ {
+		handler.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"ping","id":1}`))
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", filepath.Base(name), err)
+		}
+		if info.Size() > 300 {
+			t.Errorf("%s exceeds the size limit: %d bytes", filepath.Base(name), info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups to be kept")
+	}
+}
+
+func TestMCPTraceTool(t *testing.T) {
+	t.Chdir(t.TempDir())
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	tool := &MCPTraceTool{server: srv}
+
+	if _, err := tool.Execute(map[string]interface{}{"action": "start", "path": "../escape.ndjson"}); err == nil {
+		t.Error("expected error for path outside the working directory")
+	}
+
+	result, err := tool.Execute(map[string]interface{}{"action": "start"})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if status := result.(map[string]interface{}); status["enabled"] != true || status["path"] != defaultMCPTracePath {
+		t.Errorf("unexpected status after start: %v", status)
+	}
+
+	srv.mcpHandler.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"ping","id":7}`))
+
+	result, err = tool.Execute(map[string]interface{}{"action": "stop"})
+	if err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+	if status := result.(map[string]interface{}); status["enabled"] != false {
+		t.Errorf("unexpected status after stop: %v", status)
+	}
+	srv.mcpHandler.ProcessRequest([]byte(`{"jsonrpc":"2.0","method":"ping","id":8}`))
+
+	if records := readTraceRecords(t, defaultMCPTracePath); len(records) != 1 {
+		t.Errorf("expected 1 traced request while enabled, got %d", len(records))
+	}
+}
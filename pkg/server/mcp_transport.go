@@ -2,16 +2,22 @@ package server
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -35,10 +41,12 @@ const (
 
 // MCPDiscoveryInfo represents the discovery information for MCP endpoints
 type MCPDiscoveryInfo struct {
-	Version      string                 `json:"version"`
-	Transports   []MCPTransportInfo     `json:"transports"`
-	Endpoints    map[string]string      `json:"endpoints"`
-	Capabilities map[string]interface{} `json:"capabilities,omitempty"`
+	Version      string                  `json:"version"`
+	Transports   []MCPTransportInfo      `json:"transports"`
+	Endpoints    map[string]string       `json:"endpoints"`
+	Capabilities map[string]interface{}  `json:"capabilities,omitempty"`
+	Namespaces   []MCPDiscoveryNamespace `json:"namespaces,omitempty"`
+	Tags         []string                `json:"tags,omitempty"`
 }
 
 // MCPTransportInfo describes available transport mechanisms
@@ -62,6 +70,10 @@ func (srv *Server) setupDiscoveryEndpoints() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if !srv.mcpHandler.Enabled() {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "MCP is disabled")
+			return
+		}
 
 		discoveryInfo := srv.buildDiscoveryInfo(r)
 
@@ -80,6 +92,10 @@ func (srv *Server) setupDiscoveryEndpoints() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if !srv.mcpHandler.Enabled() {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "MCP is disabled")
+			return
+		}
 
 		discoveryInfo := srv.buildDiscoveryInfo(r)
 
@@ -96,19 +112,7 @@ func (srv *Server) setupDiscoveryEndpoints() {
 
 // buildDiscoveryInfo constructs the discovery information based on server configuration
 func (srv *Server) buildDiscoveryInfo(r *http.Request) MCPDiscoveryInfo {
-	// Determine the base URL
-	scheme := "http"
-	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
-		scheme = "https"
-	}
-
-	host := r.Host
-	if host == "" {
-		host = "localhost" + srv.Options.Addr
-	}
-
-	baseURL := scheme + "://" + host
-	mcpEndpoint := baseURL + srv.Options.MCPEndpoint
+	mcpEndpoint := srv.baseURL(r) + srv.Options.MCPEndpoint
 
 	info := MCPDiscoveryInfo{
 		Version: MCPVersion,
@@ -121,6 +125,15 @@ func (srv *Server) buildDiscoveryInfo(r *http.Request) MCPDiscoveryInfo {
 					"Content-Type": "application/json",
 				},
 			},
+			{
+				Type:        "streamable-http",
+				Endpoint:    mcpEndpoint,
+				Description: "Streamable HTTP with Mcp-Session-Id sessions and resumable streams (protocol 2025-03-26 and later)",
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+					"Accept":       "application/json, text/event-stream",
+				},
+			},
 			{
 				Type:        "sse",
 				Endpoint:    mcpEndpoint,
@@ -138,40 +151,65 @@ func (srv *Server) buildDiscoveryInfo(r *http.Request) MCPDiscoveryInfo {
 		},
 	}
 
-	// Add capabilities with dynamic tool/resource information
+	if srv.mcpHandler != nil && srv.mcpHandler.wsUpgrader != nil {
+		info.Transports = append(info.Transports, MCPTransportInfo{
+			Type:        "websocket",
+			Endpoint:    "ws" + strings.TrimPrefix(mcpEndpoint, "http"),
+			Description: "Bidirectional WebSocket with one JSON-RPC message per text frame (subprotocol \"mcp\")",
+		})
+	}
+
+	// Add capabilities with dynamic tool/resource information. A namespace query
+	// parameter narrows the document to one namespace.
 	if srv.mcpHandler != nil {
+		only := r.URL.Query().Get("namespace")
+		inScope := func(name string) bool {
+			ns := mcpNamespaceOf(name)
+			return (only == "" || ns == only) && !srv.discoveryHidden(ns)
+		}
+
 		// Get registered tools and resources
-		tools := srv.mcpHandler.GetRegisteredTools()
-		resources := srv.mcpHandler.GetRegisteredResources()
+		tools := slices.DeleteFunc(srv.mcpHandler.GetRegisteredTools(), func(name string) bool { return !inScope(name) })
+		resources := slices.DeleteFunc(srv.mcpHandler.GetRegisteredResources(), func(uri string) bool { return !inScope(uri) })
+		sort.Strings(tools)
+		sort.Strings(resources)
 
 		// Build tool capability info based on policy
 		toolCapability := map[string]interface{}{
-			"supported": true,
-			"count":     len(tools),
+			"supported":   true,
+			"description": "Functions the client can call with JSON arguments (tools/list, tools/call)",
+			"count":       len(tools),
 		}
 
-		// Apply discovery policy for tools
-		if srv.shouldIncludeToolList(r) {
-			filteredTools := make([]string, 0, len(tools))
-			for _, toolName := range tools {
-				if srv.shouldExposeToolInDiscovery(toolName, r) {
-					filteredTools = append(filteredTools, toolName)
-				}
-			}
-			if len(filteredTools) > 0 {
-				toolCapability["available"] = filteredTools
+		// Apply the discovery policy of each tool's namespace
+		filteredTools := make([]string, 0, len(tools))
+		for _, toolName := range tools {
+			policy, _ := srv.discoveryPolicyFor(mcpNamespaceOf(toolName))
+			if discoveryListAllowed(policy, r) && srv.shouldExposeToolInDiscovery(toolName, r) {
+				filteredTools = append(filteredTools, toolName)
 			}
 		}
+		if len(filteredTools) > 0 {
+			toolCapability["available"] = filteredTools
+		}
 
 		// Build resource capability info
 		resourceCapability := map[string]interface{}{
-			"supported": true,
-			"count":     len(resources),
+			"supported":   true,
+			"description": "Read-only data the client can list and read (resources/list, resources/read)",
+			"count":       len(resources),
 		}
 
 		// Resources follow the same policy as tools
-		if srv.shouldIncludeToolList(r) {
-			resourceCapability["available"] = resources
+		filteredResources := make([]string, 0, len(resources))
+		for _, uri := range resources {
+			policy, _ := srv.discoveryPolicyFor(mcpNamespaceOf(uri))
+			if discoveryListAllowed(policy, r) {
+				filteredResources = append(filteredResources, uri)
+			}
+		}
+		if len(filteredResources) > 0 || srv.shouldIncludeToolList(r) {
+			resourceCapability["available"] = filteredResources
 		}
 
 		info.Capabilities = map[string]interface{}{
@@ -179,6 +217,7 @@ func (srv *Server) buildDiscoveryInfo(r *http.Request) MCPDiscoveryInfo {
 			"resources": resourceCapability,
 			"sse": map[string]interface{}{
 				"enabled":       true,
+				"description":   "Responses and notifications streamed as Server-Sent Events",
 				"endpoint":      "same",
 				"headerRouting": true,
 			},
@@ -187,9 +226,13 @@ func (srv *Server) buildDiscoveryInfo(r *http.Request) MCPDiscoveryInfo {
 		// Add transport-specific capabilities
 		if srv.Options.MCPTransport == StdioTransport {
 			info.Capabilities["stdio"] = map[string]interface{}{
-				"supported": true,
+				"supported":   true,
+				"description": "JSON-RPC over standard input and output",
 			}
 		}
+
+		info.Namespaces = srv.buildDiscoveryNamespaces(r, only)
+		info.Tags = discoveryTags(info.Namespaces)
 	}
 
 	return info
@@ -197,17 +240,7 @@ func (srv *Server) buildDiscoveryInfo(r *http.Request) MCPDiscoveryInfo {
 
 // shouldIncludeToolList determines if tool/resource lists should be included based on policy
 func (srv *Server) shouldIncludeToolList(r *http.Request) bool {
-	switch srv.Options.MCPDiscoveryPolicy {
-	case DiscoveryNone, DiscoveryCount:
-		return false
-	case DiscoveryAuthenticated:
-		// Check for Authorization header
-		return r.Header.Get("Authorization") != ""
-	case DiscoveryPublic:
-		return true
-	default:
-		return true // Default to public
-	}
+	return discoveryListAllowed(srv.Options.current().MCPDiscoveryPolicy, r)
 }
 
 // shouldExposeToolInDiscovery determines if a specific tool should be exposed
@@ -217,8 +250,9 @@ func (srv *Server) shouldExposeToolInDiscovery(toolName string, r *http.Request)
 		return srv.Options.MCPDiscoveryFilter(toolName, r)
 	}
 
-	// Default filtering logic
-	switch srv.Options.MCPDiscoveryPolicy {
+	// Default filtering logic, using the policy of the tool's namespace
+	policy, _ := srv.discoveryPolicyFor(mcpNamespaceOf(toolName))
+	switch policy {
 	case DiscoveryNone:
 		return false
 
@@ -313,6 +347,7 @@ type SSEClient struct {
 	w             http.ResponseWriter
 	flusher       http.Flusher
 	messageChan   chan *JSONRPCResponse
+	notifyChan    chan []byte // Encoded notifications such as tool progress
 	closeChan     chan struct{}
 	closeOnce     sync.Once
 	lastMessageID int
@@ -346,6 +381,7 @@ func newSSEClient(id string, w http.ResponseWriter, flusher http.Flusher) *SSECl
 		w:           w,
 		flusher:     flusher,
 		messageChan: make(chan *JSONRPCResponse, 100), // Buffer for messages
+		notifyChan:  make(chan []byte, 100),
 		closeChan:   make(chan struct{}),
 		logger:      logger,
 	}
@@ -464,9 +500,11 @@ func (m *SSEManager) HandleSSE(w http.ResponseWriter, r *http.Request, mcpHandle
 
 	// Create SSE transport for processing requests
 	transport := newSSETransport(clientID, m, requestChan)
+	defer mcpHandler.addListener(transport)()
 
-	// Use request context for this connection
-	ctx := r.Context()
+	// Use request context for this connection; tools keep state for the client ID
+	ctx := mcpHandler.withMCPSession(withMCPRequest(r.Context(), r), clientID)
+	defer mcpHandler.state.remove(clientID)
 
 	// Start ping timer
 	pingTicker := time.NewTicker(m.pingInterval)
@@ -482,8 +520,7 @@ func (m *SSEManager) HandleSSE(w http.ResponseWriter, r *http.Request, mcpHandle
 				return
 			case request := <-requestChan:
 				if request != nil {
-					// Process the request directly using the RPC engine
-					response := mcpHandler.rpcEngine.ProcessRequestDirect(request)
+					response := mcpHandler.processRequest(withMCPNotifier(ctx, transport), "sse", request)
 
 					// Send response back via SSE
 					if err := transport.Send(response); err != nil {
@@ -518,8 +555,23 @@ func (m *SSEManager) HandleSSE(w http.ResponseWriter, r *http.Request, mcpHandle
 			return
 
 		case <-client.closeChan:
-			// Client closed
-			return
+			// Client closed; deliver queued notifications such as notifications/shutdown
+			for {
+				select {
+				case data := <-client.notifyChan:
+					if err := client.writeSSEMessage("notification", data); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+
+		case data := <-client.notifyChan:
+			if err := client.writeSSEMessage("notification", data); err != nil {
+				m.logger.Error("Failed to write SSE notification", "error", err, "client", clientID)
+				return
+			}
 
 		case response := <-client.messageChan:
 			// Send JSON-RPC response
@@ -606,6 +658,16 @@ func (m *SSEManager) removeClient(id string) {
 	}
 }
 
+// closeAll disconnects all SSE clients. Queued notifications are sent before the
+// stream ends.
+func (m *SSEManager) closeAll() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, client := range m.clients {
+		client.Close()
+	}
+}
+
 // generateClientID generates a unique client ID
 func generateClientID() string {
 	return fmt.Sprintf("sse-%d-%d", time.Now().UnixNano(), rand.Int())
@@ -662,76 +724,259 @@ func MCPOverSSE(endpoint string) MCPTransportConfig {
 // STDIO TRANSPORT
 // =============================================================================
 
-// stdioTransport implements MCPTransport for stdin/stdout communication
-// Note: Both Send and Receive are thread-safe, protected by mutex
+// stdioMaxMessageSize limits the size of a message read from stdin.
+const stdioMaxMessageSize = 1024 * 1024 // 1MB, suitable for most JSON-RPC requests
+
+// errStdioMalformed and errStdioTooLarge report messages that are skipped; the transport
+// keeps reading after them.
+var (
+	errStdioMalformed = errors.New("malformed message")
+	errStdioTooLarge  = errors.New("message exceeds the 1MB limit")
+)
+
+// stdioTransport implements MCPTransport for stdin/stdout communication. Messages are
+// newline-delimited JSON; clients that frame messages with Content-Length headers, as
+// the Language Server Protocol does, are answered in the same framing.
+// Note: Both Send and Receive are thread-safe. Writes have their own mutex so that
+// notifications can be sent while Receive waits for input.
 type stdioTransport struct {
-	scanner *bufio.Scanner
-	encoder *json.Encoder
+	reader  *bufio.Reader
+	w       io.Writer
 	logger  *slog.Logger
-	mu      sync.Mutex // Protects both encoder and scanner for thread safety
+	mu      sync.Mutex  // Protects the reader
+	writeMu sync.Mutex  // Protects w
+	framed  atomic.Bool // Set once the client sends a Content-Length header
+
+	sessionID string // Keys the client's session state
+
+	incoming   chan stdioMessage     // Messages read ahead by readAhead, nil when reading on demand
+	onResponse func(*jsonrpcMessage) // Receives responses to requests the server sent
+}
+
+// stdioMessage is a request read ahead from stdin, or the error reading it.
+type stdioMessage struct {
+	request *JSONRPCRequest
+	err     error
 }
 
 // NewStdioTransport creates a new stdio transport
 func NewStdioTransport(logger *slog.Logger) *stdioTransport {
-	scanner := bufio.NewScanner(os.Stdin)
-	// Set reasonable buffer limits to prevent memory exhaustion
-	// Max line size: 1MB (suitable for most JSON-RPC requests)
-	const maxScanTokenSize = 1024 * 1024 // 1MB
-	buf := make([]byte, 0, 64*1024)      // 64KB initial buffer
-	scanner.Buffer(buf, maxScanTokenSize)
-
-	return &stdioTransport{
-		scanner: scanner,
-		encoder: json.NewEncoder(os.Stdout),
-		logger:  logger,
-	}
+	return NewStdioTransportWithIO(os.Stdin, stdioOutput(), logger)
 }
 
 // NewStdioTransportWithIO creates a new stdio transport with custom IO
 func NewStdioTransportWithIO(r io.Reader, w io.Writer, logger *slog.Logger) *stdioTransport {
-	scanner := bufio.NewScanner(r)
-	// Set reasonable buffer limits to prevent memory exhaustion
-	const maxScanTokenSize = 1024 * 1024 // 1MB
-	buf := make([]byte, 0, 64*1024)      // 64KB initial buffer
-	scanner.Buffer(buf, maxScanTokenSize)
-
 	return &stdioTransport{
-		scanner: scanner,
-		encoder: json.NewEncoder(w),
-		logger:  logger,
+		reader:    bufio.NewReaderSize(r, 64*1024),
+		w:         w,
+		logger:    logger,
+		sessionID: "stdio-" + generateClientID(),
 	}
 }
 
 // Send sends a JSON-RPC response to stdout
 func (t *stdioTransport) Send(response *JSONRPCResponse) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	if err := t.encoder.Encode(response); err != nil {
+	if err := t.write(response); err != nil {
 		return fmt.Errorf("failed to encode response: %w", err)
 	}
-
 	return nil
 }
 
-// Receive receives a JSON-RPC request from stdin
+// write writes one message in the framing used by the client.
+func (t *stdioTransport) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if t.framed.Load() {
+		if _, err := fmt.Fprintf(t.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+			return err
+		}
+		_, err = t.w.Write(data)
+		return err
+	}
+	_, err = t.w.Write(append(data, '\n'))
+	return err
+}
+
+// Receive receives a JSON-RPC request from stdin. Blank lines are skipped. Malformed and
+// oversized messages are reported as errors wrapping errStdioMalformed and
+// errStdioTooLarge, after which the next call reads the following message.
 func (t *stdioTransport) Receive() (*JSONRPCRequest, error) {
+	if t.incoming != nil {
+		msg, ok := <-t.incoming
+		if !ok {
+			return nil, io.EOF
+		}
+		return msg.request, msg.err
+	}
+	return t.read()
+}
+
+// read reads the next request from stdin. Responses to requests the server sent are
+// passed to onResponse on the way.
+func (t *stdioTransport) read() (*JSONRPCRequest, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if !t.scanner.Scan() {
-		if err := t.scanner.Err(); err != nil {
+	for {
+		var data []byte
+		for len(data) == 0 {
+			line, err := t.readLine()
+			if err != nil {
+				return nil, err
+			}
+			data = bytes.TrimSpace(line)
+			if n, ok := contentLength(data); ok {
+				if data, err = t.readFramed(n); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal request: %w: %w", errStdioMalformed, err)
+		}
+		if !msg.isResponse() {
+			return &msg.JSONRPCRequest, nil
+		}
+		if t.onResponse != nil {
+			t.onResponse(&msg)
+		}
+	}
+}
+
+// stdioReadAheadLimit bounds the requests queued while one is being processed. Reading
+// pauses while the queue is full.
+const stdioReadAheadLimit = 1024
+
+// readAhead reads messages on a separate goroutine until stdin fails or done is closed,
+// so that responses to the server's requests arrive while a request is being processed.
+// Requests read in the meantime are queued, so a client that sends a request before it
+// answers the server's does not block the response behind it.
+func (t *stdioTransport) readAhead(done <-chan struct{}) {
+	incoming := make(chan stdioMessage)
+	t.incoming = incoming
+	messages := make(chan stdioMessage)
+	go func() {
+		defer close(messages)
+		for {
+			request, err := t.read()
+			select {
+			case messages <- stdioMessage{request: request, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil && !errors.Is(err, errStdioMalformed) && !errors.Is(err, errStdioTooLarge) {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(incoming)
+		var queue []stdioMessage
+		for messages != nil || len(queue) > 0 {
+			var out chan<- stdioMessage
+			var next stdioMessage
+			if len(queue) > 0 {
+				out, next = incoming, queue[0]
+			}
+			in := messages
+			if len(queue) >= stdioReadAheadLimit {
+				in = nil
+			}
+			select {
+			case msg, ok := <-in:
+				if !ok {
+					messages = nil
+					continue
+				}
+				queue = append(queue, msg)
+			case out <- next:
+				queue = queue[1:]
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// readLine reads the next line. A line longer than stdioMaxMessageSize is discarded up
+// to its end and reported as errStdioTooLarge.
+func (t *stdioTransport) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := t.reader.ReadSlice('\n')
+		if len(line)+len(chunk) > stdioMaxMessageSize {
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = t.reader.ReadSlice('\n')
+			}
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("scanner error: %w", err)
+			}
+			return nil, fmt.Errorf("scanner error: %w", errStdioTooLarge)
+		}
+		line = append(line, chunk...)
+		switch {
+		case err == nil:
+			return line, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF) && len(line) > 0:
+			return line, nil // Last line without a newline
+		case errors.Is(err, io.EOF):
+			return nil, io.EOF
+		default:
 			return nil, fmt.Errorf("scanner error: %w", err)
 		}
-		return nil, io.EOF
 	}
+}
 
-	var request JSONRPCRequest
-	if err := json.Unmarshal(t.scanner.Bytes(), &request); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal request: %w", err)
+// contentLength parses a Content-Length header line.
+func contentLength(line []byte) (int, bool) {
+	name, value, ok := bytes.Cut(line, []byte(":"))
+	if !ok || !strings.EqualFold(string(name), "Content-Length") {
+		return 0, false
 	}
+	n, err := strconv.Atoi(string(bytes.TrimSpace(value)))
+	return n, err == nil
+}
 
-	return &request, nil
+// readFramed reads the remaining headers and the body of a message framed with a
+// Content-Length header of n bytes.
+func (t *stdioTransport) readFramed(n int) ([]byte, error) {
+	if !t.framed.Swap(true) {
+		t.logger.Debug("MCP stdio client uses Content-Length framing")
+	}
+	for {
+		header, err := t.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(header)) == 0 {
+			break
+		}
+	}
+	if n < 0 || n > stdioMaxMessageSize {
+		if _, err := t.reader.Discard(max(n, 0)); err != nil {
+			return nil, fmt.Errorf("scanner error: %w", err)
+		}
+		return nil, fmt.Errorf("scanner error: %w", errStdioTooLarge)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(t.reader, body); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("scanner error: %w", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, fmt.Errorf("failed to unmarshal request: %w: empty body", errStdioMalformed)
+	}
+	return body, nil
 }
 
 // Close closes the stdio transport (no-op)
@@ -739,6 +984,35 @@ func (t *stdioTransport) Close() error {
 	return nil
 }
 
+// stdioStdout is the process's stdout, reserved for the JSON-RPC stream once
+// reserveStdout has run.
+var (
+	stdioStdout     *os.File
+	stdioStdoutOnce sync.Once
+)
+
+// reserveStdout keeps stdout for MCP messages: os.Stdout and the standard log package,
+// which backs the default slog handler, are pointed at stderr so that stray prints and
+// log records cannot corrupt the JSON-RPC stream. Loggers that already hold os.Stdout
+// are not affected and must be configured to write elsewhere.
+func reserveStdout() {
+	stdioStdoutOnce.Do(func() {
+		stdioStdout = os.Stdout
+		if log.Writer() == os.Stdout {
+			log.SetOutput(os.Stderr)
+		}
+		os.Stdout = os.Stderr
+	})
+}
+
+// stdioOutput returns the writer for MCP messages on stdout.
+func stdioOutput() io.Writer {
+	if stdioStdout != nil {
+		return stdioStdout
+	}
+	return os.Stdout
+}
+
 // createErrorResponse creates a standard JSON-RPC error response
 func createErrorResponse(code int, message string, data interface{}) *JSONRPCResponse {
 	return &JSONRPCResponse{
@@ -755,43 +1029,61 @@ func createErrorResponse(code int, message string, data interface{}) *JSONRPCRes
 // The loop continues processing requests until EOF is received on stdin.
 // EOF is treated as a normal shutdown signal (e.g., when stdin is closed).
 // This behavior is appropriate for stdio servers which typically run
-// for the lifetime of the parent process.
+// for the lifetime of the parent process. Stdout is reserved for MCP messages
+// while the loop runs; see reserveStdout.
 func (h *MCPHandler) RunStdioLoop() error {
-	transport := NewStdioTransport(h.logger)
+	reserveStdout()
+	return h.serveStdio(NewStdioTransport(h.logger))
+}
+
+// ServeStdio runs the MCP handler on r and w as it runs on stdin and stdout in stdio
+// mode, until r ends. It serves in-process clients, such as those of the mcptest package.
+func (h *MCPHandler) ServeStdio(r io.Reader, w io.Writer) error {
+	return h.serveStdio(NewStdioTransportWithIO(r, w, h.logger))
+}
+
+// serveStdio processes requests from transport until EOF. Malformed and oversized
+// messages are answered with an error and skipped; the loop ends with an error if
+// stdin or stdout fails.
+func (h *MCPHandler) serveStdio(transport *stdioTransport) error {
 	// Note: Close() is currently a no-op but called for future compatibility
 	defer transport.Close()
+	defer h.addListener(transport)()
+	defer h.state.remove(transport.sessionID)
+
+	// Read ahead so that tools waiting for the client's answer to a sampling or
+	// elicitation request do not block the loop that receives it
+	done := make(chan struct{})
+	defer close(done)
+	transport.onResponse = h.deliverClientResponse
+	transport.readAhead(done)
 
 	h.logger.Debug("MCP stdio server started")
 
 	// Main message loop
 	for {
 		err := h.ProcessRequestWithTransport(transport)
-		if errors.Is(err, io.EOF) {
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, io.EOF):
 			h.logger.Debug("MCP stdio server shutting down", "reason", "EOF received")
-			break
-		}
-		if err != nil {
-			h.logger.Error("Error processing request", "error", err)
-			// Determine appropriate error code based on error type
-			errorCode := ErrorCodeInternalError
-			if strings.Contains(err.Error(), "unmarshal") || strings.Contains(err.Error(), "parse") {
-				errorCode = ErrorCodeParseError
-			} else if strings.Contains(err.Error(), "scanner error") {
+			return nil
+		case errors.Is(err, errStdioMalformed), errors.Is(err, errStdioTooLarge):
+			h.logger.Warn("Skipping invalid MCP stdio message", "error", err)
+			errorCode := ErrorCodeParseError
+			if errors.Is(err, errStdioTooLarge) {
 				errorCode = ErrorCodeInvalidRequest
 			}
-
-			// Send error response
 			errorResponse := createErrorResponse(errorCode, "Request processing error", err.Error())
 			if sendErr := transport.Send(errorResponse); sendErr != nil {
-				h.logger.Error("Failed to send error response", "error", sendErr)
-				// Critical failure: both request processing and error response failed
-				// Log the critical state but continue processing to maintain service
-				h.logger.Error("Critical: Unable to send error response to client",
-					"original_error", err.Error(),
-					"send_error", sendErr.Error())
+				h.logger.Error("MCP stdio server stopped: unable to write to stdout", "error", sendErr)
+				return sendErr
 			}
+		default:
+			// Reading stdin or writing stdout failed; the client cannot be reached
+			h.logger.Error("MCP stdio server stopped", "error", err)
+			return err
 		}
 	}
-
-	return nil
 }
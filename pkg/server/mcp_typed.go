@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// NewTypedTool creates a tool whose arguments are decoded into Req and whose result is
+// returned as JSON. The input schema is derived from Req, which must be a struct:
+// properties are named after the json tags, fields without omitempty or a pointer type
+// are required, and the `description` and `enum` (comma-separated) tags document them.
+// Arguments that are missing, unknown, or of the wrong type are rejected before fn runs.
+//
+// Example:
+//
+//	type SearchArgs struct {
+//		Query string `json:"query" description:"Full-text query"`
+//		Limit int    `json:"limit,omitempty" description:"Maximum results"`
+//		Sort  string `json:"sort,omitempty" enum:"relevance,date"`
+//	}
+//
+//	handler.RegisterTool(server.NewTypedTool("search", "Search documents",
+//		func(ctx context.Context, args SearchArgs) ([]Document, error) {
+//			return index.Search(ctx, args.Query, args.Limit)
+//		}))
+func NewTypedTool[Req, Res any](name, description string, fn func(ctx context.Context, req Req) (Res, error)) MCPToolWithContext {
+	reqType := reflect.TypeFor[Req]()
+	for reqType.Kind() == reflect.Pointer {
+		reqType = reqType.Elem()
+	}
+	if reqType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("NewTypedTool %s: request type must be a struct, got %s", name, reqType))
+	}
+	return &typedTool[Req, Res]{
+		name:        name,
+		description: description,
+		schema:      jsonSchemaFor(reqType, map[reflect.Type]bool{}),
+		fn:          fn,
+	}
+}
+
+// typedTool adapts a typed function to MCPToolWithContext.
+type typedTool[Req, Res any] struct {
+	name        string
+	description string
+	schema      map[string]interface{}
+	fn          func(ctx context.Context, req Req) (Res, error)
+}
+
+func (t *typedTool[Req, Res]) Name() string                   { return t.name }
+func (t *typedTool[Req, Res]) Description() string            { return t.description }
+func (t *typedTool[Req, Res]) Schema() map[string]interface{} { return t.schema }
+
+func (t *typedTool[Req, Res]) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.ExecuteWithContext(context.Background(), params)
+}
+
+func (t *typedTool[Req, Res]) ExecuteWithContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	var req Req
+	if err := decodeToolArguments(t.schema, params, &req); err != nil {
+		return nil, err
+	}
+	return t.fn(ctx, req)
+}
+
+// decodeToolArguments checks the required properties of schema and decodes params into v.
+func decodeToolArguments(schema map[string]interface{}, params map[string]interface{}, v interface{}) error {
+	required, _ := schema["required"].([]string)
+	for _, name := range required {
+		if _, ok := params[name]; !ok {
+			return fmt.Errorf("invalid arguments: missing required argument %q", name)
+		}
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fmt.Errorf("invalid arguments: %q must be of type %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+		}
+		return fmt.Errorf("invalid arguments: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// jsonSchemaFor derives a JSON schema from a Go type. Recursive types are described as
+// plain objects where they repeat.
+func jsonSchemaFor(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Implements(reflect.TypeFor[json.Marshaler]()) || reflect.PointerTo(t).Implements(reflect.TypeFor[json.Marshaler]()):
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchemaFor(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaFor(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := map[string]interface{}{}
+		required := []string{}
+		addStructFields(t, visiting, properties, &required)
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		// Interfaces accept any value
+		return map[string]interface{}{}
+	}
+}
+
+// addStructFields adds the fields of t, including those of embedded structs, to properties.
+func addStructFields(t reflect.Type, visiting map[reflect.Type]bool, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addStructFields(embedded, visiting, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := jsonSchemaFor(field.Type, visiting)
+		if desc := field.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			values := strings.Split(enum, ",")
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+			prop["enum"] = values
+		}
+		properties[name] = prop
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// jsonTypeName names the JSON type that a Go type is decoded from.
+func jsonTypeName(t reflect.Type) string {
+	if name, ok := jsonSchemaFor(t, map[reflect.Type]bool{})["type"].(string); ok {
+		return name
+	}
+	return "any"
+}
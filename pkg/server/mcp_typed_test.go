@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type typedPage struct {
+	Size int `json:"size" description:"Page size"`
+}
+
+type typedSearchArgs struct {
+	typedPage
+	Query   string            `json:"query" description:"Full-text query"`
+	Sort    string            `json:"sort,omitempty" enum:"relevance, date"`
+	Tags    []string          `json:"tags,omitempty"`
+	Since   *time.Time        `json:"since"`
+	Filters map[string]string `json:"filters,omitempty"`
+	Debug   bool              `json:"-"`
+}
+
+type typedSearchResult struct {
+	Query string `json:"query"`
+	Size  int    `json:"size"`
+}
+
+func newTypedSearchTool() MCPToolWithContext {
+	return NewTypedTool("search", "Search documents", func(ctx context.Context, args typedSearchArgs) (typedSearchResult, error) {
+		return typedSearchResult{Query: args.Query, Size: args.Size}, nil
+	})
+}
+
+func TestNewTypedToolSchema(t *testing.T) {
+	schema := newTypedSearchTool().Schema()
+	props := schema["properties"].(map[string]interface{})
+
+	if got := schema["required"]; !reflect.DeepEqual(got, []string{"size", "query"}) {
+		t.Errorf("required = %v", got)
+	}
+	want := map[string]interface{}{
+		"size":    map[string]interface{}{"type": "integer", "description": "Page size"},
+		"query":   map[string]interface{}{"type": "string", "description": "Full-text query"},
+		"sort":    map[string]interface{}{"type": "string", "enum": []string{"relevance", "date"}},
+		"tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"since":   map[string]interface{}{"type": "string", "format": "date-time"},
+		"filters": map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("properties = %v, want %v", props, want)
+	}
+}
+
+func TestNewTypedToolExecute(t *testing.T) {
+	tool := newTypedSearchTool()
+	result, err := tool.ExecuteWithContext(context.Background(), map[string]interface{}{"query": "go", "size": float64(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (typedSearchResult{Query: "go", Size: 10}) {
+		t.Errorf("result = %+v", result)
+	}
+
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"size": float64(10)}, `missing required argument "query"`},
+		{map[string]interface{}{"query": "go", "size": "ten"}, `"size" must be of type integer, got string`},
+		{map[string]interface{}{"query": "go", "size": float64(1), "limit": float64(5)}, `unknown field "limit"`},
+	}
+	for _, tt := range tests {
+		if _, err := tool.Execute(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Execute(%v) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestNewTypedToolThroughHandler(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(newTypedSearchTool())
+
+	result, err := h.handleToolsCall(map[string]interface{}{
+		"name":      "search",
+		"arguments": map[string]interface{}{"query": "mcp", "size": float64(3)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := result.(map[string]interface{})["content"].([]map[string]interface{})
+	if text := content[0]["text"]; text != `{"query":"mcp","size":3}` {
+		t.Errorf("text = %v", text)
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MCPValidationError describes an argument that does not match the tool's input schema.
+// tools/call reports them in the data of an invalid params error.
+type MCPValidationError struct {
+	Path    string `json:"path"` // e.g. "query" or "filters.tags[2]"
+	Message string `json:"message"`
+}
+
+// validateToolArguments checks the arguments of a tools/call request against the tool's
+// input schema, so that tools do not have to defend against missing or mistyped
+// arguments. It supports the schema keywords that tool schemas use in practice: type,
+// properties, required, enum, items, and additionalProperties.
+func validateToolArguments(tool MCPTool, args map[string]interface{}) error {
+	schema := tool.Schema()
+	if len(schema) == 0 {
+		return nil
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var errs []MCPValidationError
+	validateSchemaValue(schema, args, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &JSONRPCError{
+		Code:    ErrorCodeInvalidParams,
+		Message: "Invalid params",
+		Data: map[string]interface{}{
+			"tool":   tool.Name(),
+			"errors": errs,
+		},
+	}
+}
+
+func validateSchemaValue(schema map[string]interface{}, value interface{}, path string, errs *[]MCPValidationError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, MCPValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types := schemaStrings(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesSchemaType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must be of type %s, got %s", strings.Join(types, " or "), jsonValueType(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		allowed := reflect.ValueOf(enum)
+		if allowed.Kind() == reflect.Slice {
+			found := false
+			for i := 0; i < allowed.Len(); i++ {
+				if schemaValuesEqual(allowed.Index(i).Interface(), value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				data, _ := json.Marshal(enum)
+				fail("must be one of %s", data)
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateSchemaObject(schema, v, path, errs)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchemaValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+}
+
+func validateSchemaObject(schema map[string]interface{}, obj map[string]interface{}, path string, errs *[]MCPValidationError) {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, MCPValidationError{Path: join(name), Message: "is required"})
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			validateSchemaValue(prop, obj[name], join(name), errs)
+			continue
+		}
+		if _, declared := properties[name]; declared {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*errs = append(*errs, MCPValidationError{Path: join(name), Message: "is not allowed"})
+			}
+		case map[string]interface{}:
+			validateSchemaValue(additional, obj[name], join(name), errs)
+		}
+	}
+}
+
+// matchesSchemaType reports whether value has the JSON schema type t. Integers are
+// numbers without a fraction, as JSON does not distinguish them.
+func matchesSchemaType(t string, value interface{}) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "integer":
+		f, ok := schemaNumber(value)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	default:
+		return jsonValueType(value) == t
+	}
+}
+
+// jsonValueType returns the JSON schema type of a decoded value.
+func jsonValueType(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	if _, ok := schemaNumber(value); ok {
+		return "number"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return reflect.TypeOf(value).String()
+	}
+}
+
+// schemaNumber converts the numeric types that arguments built in Go may contain.
+func schemaNumber(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func schemaValuesEqual(a, b interface{}) bool {
+	if fa, ok := schemaNumber(a); ok {
+		fb, ok := schemaNumber(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// schemaStrings reads a keyword that is a string or a list of strings, such as "type"
+// and "required". Schemas built in Go use []string, decoded ones []interface{}.
+func schemaStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
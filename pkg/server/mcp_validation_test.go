@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func validationTool() MCPTool {
+	return NewTool("deploy").
+		WithDescription("Deploys a service").
+		WithParameter("service", "string", "Service name", true).
+		WithParameter("replicas", "integer", "Replica count", false).
+		WithParameter("tags", "array", "Tags", false).
+		WithExecute(func(params map[string]interface{}) (interface{}, error) {
+			// Safe without checks: the schema was validated
+			return params["service"].(string), nil
+		}).
+		Build()
+}
+
+func TestValidateToolArguments(t *testing.T) {
+	tool := &SimpleTool{
+		NameFunc: func() string { return "search" },
+		SchemaFunc: func() map[string]interface{} {
+			return map[string]interface{}{
+				"type":     "object",
+				"required": []string{"query"},
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string"},
+					"limit": map[string]interface{}{"type": "integer"},
+					"sort":  map[string]interface{}{"type": "string", "enum": []string{"relevance", "date"}},
+					"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				},
+				"additionalProperties": false,
+			}
+		},
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []MCPValidationError
+	}{
+		{"valid", map[string]interface{}{"query": "go", "limit": float64(5), "sort": "date", "tags": []interface{}{"a"}}, nil},
+		{"integer from Go", map[string]interface{}{"query": "go", "limit": 5}, nil},
+		{"missing required", map[string]interface{}{}, []MCPValidationError{{"query", "is required"}}},
+		{"wrong types", map[string]interface{}{"query": float64(1), "limit": 2.5}, []MCPValidationError{
+			{"limit", "must be of type integer, got number"},
+			{"query", "must be of type string, got number"},
+		}},
+		{"enum", map[string]interface{}{"query": "go", "sort": "stars"}, []MCPValidationError{{"sort", `must be one of ["relevance","date"]`}}},
+		{"array items", map[string]interface{}{"query": "go", "tags": []interface{}{"a", true}}, []MCPValidationError{{"tags[1]", "must be of type string, got boolean"}}},
+		{"unknown argument", map[string]interface{}{"query": "go", "page": float64(2)}, []MCPValidationError{{"page", "is not allowed"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateToolArguments(tool, tt.args)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			rpcErr, ok := err.(*JSONRPCError)
+			if !ok || rpcErr.Code != ErrorCodeInvalidParams {
+				t.Fatalf("expected an invalid params error, got %v", err)
+			}
+			if got := rpcErr.Data.(map[string]interface{})["errors"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolsCallRejectsInvalidArguments(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(validationTool())
+
+	call := func(args map[string]interface{}) *JSONRPCResponse {
+		return h.rpcEngine.ProcessRequestContext(context.Background(), &JSONRPCRequest{
+			JSONRPC: JSONRPCVersion,
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": "deploy", "arguments": args},
+			ID:      1,
+		})
+	}
+
+	resp := call(map[string]interface{}{"replicas": "three"})
+	if resp.Error == nil || resp.Error.Code != ErrorCodeInvalidParams {
+		t.Fatalf("expected an invalid params error instead of a panic, got %+v", resp)
+	}
+	data := resp.Error.Data.(map[string]interface{})
+	if data["tool"] != "deploy" || len(data["errors"].([]MCPValidationError)) != 2 {
+		t.Errorf("unexpected error data: %+v", data)
+	}
+
+	if resp := call(map[string]interface{}{"service": "api", "replicas": float64(3)}); resp.Error != nil {
+		t.Errorf("valid arguments were rejected: %+v", resp.Error)
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// =============================================================================
+// WEBSOCKET TRANSPORT
+// =============================================================================
+
+// MCPOverWebSocket accepts WebSocket upgrades on the MCP endpoint, so clients can use a
+// single bidirectional socket instead of the SSE stream plus POST requests. Every text
+// message carries one JSON-RPC message or a batch; responses and notifications such as
+// tool progress are sent back on the same socket. Requests on one socket run
+// concurrently and are cancelled when it closes. Plain HTTP and SSE keep working on the
+// endpoint.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMCPSupport("MyApp", "1.0.0", server.MCPOverWebSocket()),
+//	)
+func MCPOverWebSocket() MCPTransportConfig {
+	return func(o *mcpTransportOptions) {
+		o.websocket = true
+	}
+}
+
+// newMCPUpgrader returns the Upgrader for MCP sockets. Browser clients must be
+// same-origin; clients that send no Origin header, such as CLI tools, are accepted like
+// they are for plain POST requests.
+func newMCPUpgrader() *Upgrader {
+	return &Upgrader{
+		Subprotocols: []string{"mcp"},
+		CheckOrigin: func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "" || DefaultCheckOrigin(r)
+		},
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			http.Error(w, reason.Error(), status)
+		},
+	}
+}
+
+// ServeWebSocket upgrades the request and serves MCP over the socket until the client
+// disconnects. ServeHTTP calls it for upgrade requests when MCPOverWebSocket is enabled;
+// it can also be mounted on its own route.
+func (h *MCPHandler) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := h.wsUpgrader
+	if upgrader == nil {
+		upgrader = newMCPUpgrader()
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Debug("MCP WebSocket upgrade failed", "error", err)
+		return
+	}
+	transport := &websocketTransport{conn: conn}
+	defer transport.Close()
+	h.logger.Debug("MCP WebSocket client connected", "remote", r.RemoteAddr)
+
+	sessionID := "ws-" + generateClientID()
+	defer h.state.remove(sessionID)
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = h.withMCPSession(withMCPRequest(withMCPNotifier(ctx, transport), r), sessionID)
+	defer h.addListener(transport)()
+	var inFlight sync.WaitGroup
+	defer func() {
+		cancel()
+		inFlight.Wait()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if IsUnexpectedCloseError(err, CloseNormalClosure, CloseGoingAway) {
+				h.logger.Debug("MCP WebSocket read failed", "error", err)
+			}
+			return
+		}
+		messages, batch, err := decodeJSONRPCMessages(bytes.NewReader(data))
+		if err != nil {
+			transport.Send(createErrorResponse(ErrorCodeParseError, "Parse error", err.Error()))
+			continue
+		}
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			h.serveWebSocketMessages(ctx, transport, messages, batch)
+		}()
+	}
+}
+
+// serveWebSocketMessages processes one socket message and sends the responses.
+func (h *MCPHandler) serveWebSocketMessages(ctx context.Context, transport *websocketTransport, messages []*jsonrpcMessage, batch bool) {
+	var responses []*JSONRPCResponse
+	for _, msg := range messages {
+		switch {
+		case msg.isResponse():
+			h.deliverClientResponse(msg)
+		case msg.Method == "":
+			h.logger.Debug("Ignoring JSON-RPC message without method", "id", msg.ID)
+		case msg.ID == nil:
+			h.processNotification(ctx, &msg.JSONRPCRequest)
+		default:
+			responses = append(responses, h.processRequest(ctx, "websocket", &msg.JSONRPCRequest))
+		}
+	}
+	if len(responses) == 0 {
+		return
+	}
+	var err error
+	if batch {
+		err = transport.write(responses)
+	} else {
+		err = transport.Send(responses[0])
+	}
+	if err != nil {
+		h.logger.Debug("Failed to send MCP WebSocket response", "error", err)
+	}
+}
+
+// websocketTransport implements MCPTransport over a WebSocket. Writes are serialised, since
+// responses and notifications of concurrent requests share the socket.
+type websocketTransport struct {
+	conn *Conn
+	mu   sync.Mutex
+}
+
+// Send sends a JSON-RPC response as a text message
+func (t *websocketTransport) Send(response *JSONRPCResponse) error {
+	return t.write(response)
+}
+
+// Receive reads a single JSON-RPC request
+func (t *websocketTransport) Receive() (*JSONRPCRequest, error) {
+	var request JSONRPCRequest
+	if err := t.conn.ReadJSON(&request); err != nil {
+		return nil, fmt.Errorf("failed to receive request: %w", err)
+	}
+	return &request, nil
+}
+
+// Notify sends a notification as a text message
+func (t *websocketTransport) Notify(method string, params interface{}) error {
+	return t.write(jsonrpcNotification{JSONRPC: JSONRPCVersion, Method: method, Params: params})
+}
+
+// Close closes the socket with a normal closure
+func (t *websocketTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.conn.Close()
+}
+
+func (t *websocketTransport) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.conn.WriteMessage(TextMessage, data)
+}
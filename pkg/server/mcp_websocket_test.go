@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+func readMCPFrame(t *testing.T, fr *ws.FrameReader) interface{} {
+	t.Helper()
+	frame, err := fr.ReadFrame()
+	if err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	var msg interface{}
+	if err := json.Unmarshal(frame.Payload, &msg); err != nil {
+		t.Fatalf("invalid message %q: %v", frame.Payload, err)
+	}
+	return msg
+}
+
+func TestMCPOverWebSocket(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	h.RegisterTool(&countingTool{})
+	h.wsUpgrader = newMCPUpgrader()
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	_, fr, fw := dialGatewayWebSocket(t, ts.URL)
+
+	writeClientFrame(t, fw, ws.OpcodeText, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`))
+	resp := readMCPFrame(t, fr).(map[string]interface{})
+	if result, _ := resp["result"].(map[string]interface{}); resp["id"] != float64(1) || result["protocolVersion"] != "2025-06-18" {
+		t.Fatalf("unexpected initialize response: %v", resp)
+	}
+
+	// Notifications get no reply, so the next frame answers the batch
+	writeClientFrame(t, fw, ws.OpcodeText, []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	writeClientFrame(t, fw, ws.OpcodeText, []byte(`[{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":3,"method":"tools/list"}]`))
+	if batch, ok := readMCPFrame(t, fr).([]interface{}); !ok || len(batch) != 2 {
+		t.Fatalf("expected a batch of two responses, got %v", batch)
+	}
+
+	writeClientFrame(t, fw, ws.OpcodeText, []byte(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"count","arguments":{},"_meta":{"progressToken":"p"}}}`))
+	for i := 1; i <= 3; i++ {
+		msg := readMCPFrame(t, fr).(map[string]interface{})
+		params, _ := msg["params"].(map[string]interface{})
+		if msg["method"] != "notifications/progress" || params["progress"] != float64(i) {
+			t.Fatalf("expected progress %d, got %v", i, msg)
+		}
+	}
+	if msg := readMCPFrame(t, fr).(map[string]interface{}); msg["id"] != float64(4) || msg["error"] != nil {
+		t.Fatalf("unexpected tool response: %v", msg)
+	}
+
+	writeClientFrame(t, fw, ws.OpcodeText, []byte(`{not json`))
+	msg := readMCPFrame(t, fr).(map[string]interface{})
+	if errObj, _ := msg["error"].(map[string]interface{}); errObj["code"] != float64(ErrorCodeParseError) {
+		t.Fatalf("expected a parse error, got %v", msg)
+	}
+}
+
+func upgradeStatus(t *testing.T, url, origin string) int {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestMCPOverWebSocketRefusedUpgrades(t *testing.T) {
+	h := NewMCPHandler(MCPServerInfo{Name: "test", Version: "1.0.0"})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	if status := upgradeStatus(t, ts.URL, ""); status == http.StatusSwitchingProtocols {
+		t.Error("expected the upgrade to be refused without MCPOverWebSocket")
+	}
+
+	h.wsUpgrader = newMCPUpgrader()
+	if status := upgradeStatus(t, ts.URL, "https://evil.example"); status != http.StatusForbidden {
+		t.Errorf("cross-origin upgrade: expected 403, got %d", status)
+	}
+}
+
+func TestMCPOverWebSocketOption(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0", MCPOverWebSocket()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.mcpHandler == nil || srv.mcpHandler.wsUpgrader == nil {
+		t.Fatal("expected MCPOverWebSocket to enable the WebSocket transport")
+	}
+	info := srv.buildDiscoveryInfo(httptest.NewRequest(http.MethodGet, "http://example.com/.well-known/mcp.json", nil))
+	found := false
+	for _, transport := range info.Transports {
+		if transport.Type == "websocket" {
+			found = transport.Endpoint == "ws://example.com"+srv.Options.MCPEndpoint
+		}
+	}
+	if !found {
+		t.Errorf("expected a websocket transport in discovery, got %+v", info.Transports)
+	}
+}
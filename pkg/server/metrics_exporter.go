@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const defaultMetricsExportInterval = 10 * time.Second
+
+// MetricsExporter pushes metrics snapshots to a monitoring backend. Snapshots are
+// cumulative since server start; exporters for delta-based backends keep the previous
+// snapshot themselves.
+type MetricsExporter interface {
+	Export(ctx context.Context, snap MetricsSnapshot) error
+}
+
+// MetricsExporterFunc adapts a function to the MetricsExporter interface.
+type MetricsExporterFunc func(ctx context.Context, snap MetricsSnapshot) error
+
+// Export calls f(ctx, snap).
+func (f MetricsExporterFunc) Export(ctx context.Context, snap MetricsSnapshot) error {
+	return f(ctx, snap)
+}
+
+type metricsExport struct {
+	exporter MetricsExporter
+	interval time.Duration
+}
+
+// WithMetricsExporter pushes srv.MetricsSnapshot() to exporter every interval (default
+// 10s) while the server runs, and once more on shutdown. Failed exports are logged and
+// retried with the next snapshot.
+//
+// Example:
+//
+//	statsd, _ := server.NewStatsDExporter(server.StatsDConfig{Prefix: "shop.", DogStatsD: true})
+//	srv, _ := server.NewServer(server.WithMetricsExporter(statsd, 10*time.Second))
+func WithMetricsExporter(exporter MetricsExporter, interval time.Duration) ServerOptionFunc {
+	return func(srv *Server) error {
+		if exporter == nil {
+			return fmt.Errorf("metrics exporter must not be nil")
+		}
+		if interval < 0 {
+			return fmt.Errorf("metrics export interval must not be negative: %s", interval)
+		}
+		if interval == 0 {
+			interval = defaultMetricsExportInterval
+		}
+		srv.exporters = append(srv.exporters, metricsExport{exporter: exporter, interval: interval})
+		return nil
+	}
+}
+
+// runMetricsExport exports on every tick until ctx is cancelled, then flushes once more.
+func (srv *Server) runMetricsExport(ctx context.Context, e metricsExport) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), min(e.interval, 5*time.Second))
+			srv.exportMetrics(flushCtx, e.exporter)
+			cancel()
+			return
+		case <-ticker.C:
+			exportCtx, cancel := context.WithTimeout(ctx, e.interval)
+			srv.exportMetrics(exportCtx, e.exporter)
+			cancel()
+		}
+	}
+}
+
+func (srv *Server) exportMetrics(ctx context.Context, exporter MetricsExporter) {
+	if err := exporter.Export(ctx, srv.MetricsSnapshot()); err != nil {
+		logger.Warn("Metrics export failed", "exporter", fmt.Sprintf("%T", exporter), "error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import "time"
+
+// MetricsSnapshot holds all counters and histograms of a server at one point in time.
+// Each group is read separately, so counters in different groups may be a few requests
+// apart under load.
+type MetricsSnapshot struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Uptime    time.Duration `json:"uptime"`
+	Running   bool          `json:"running"`
+	Ready     bool          `json:"ready"`
+
+	Requests          uint64        `json:"requests"`           // Requests seen by MetricsMiddleware, plus WebSocket upgrades
+	HandlerTime       time.Duration `json:"handler_time"`       // Total time spent serving requests
+	WebSocketUpgrades uint64        `json:"websocket_upgrades"` // Connections upgraded via WebSocketUpgrader
+	RateLimiters      int           `json:"rate_limiters"`      // Clients with an active rate limiter
+	SSE               SSEStats      `json:"sse"`                // Open Server-Sent Events streams
+
+	// Routes holds the per-route breakdown including latency histogram buckets.
+	Routes []RouteMetrics `json:"routes"`
+	// MiddlewareLayers is nil unless WithMiddlewareMetrics is enabled.
+	MiddlewareLayers []LayerMetrics    `json:"middleware_layers,omitempty"`
+	GeoPolicyHits    map[string]uint64 `json:"geo_policy_hits,omitempty"`
+	HTTPClient       HTTPClientStats   `json:"http_client"`
+	// GatewayWebSockets is nil unless a gateway is configured.
+	GatewayWebSockets *GatewayWebSocketStats `json:"gateway_websockets,omitempty"`
+}
+
+// MetricsSnapshot returns the server's counters and histograms as a typed struct, for
+// applications that push metrics to their own backend (StatsD, CloudWatch, a dashboard)
+// instead of reading the metrics MCP resources. Taking a snapshot does not reset anything;
+// compute rates from the difference between two snapshots.
+//
+// Example:
+//
+//	ticker := time.NewTicker(10 * time.Second)
+//	for range ticker.C {
+//		snap := srv.MetricsSnapshot()
+//		for _, route := range snap.Routes {
+//			statsd.Gauge("http.p99."+route.Route, route.Latency.P99.Seconds())
+//		}
+//	}
+func (srv *Server) MetricsSnapshot() MetricsSnapshot {
+	now := time.Now()
+	snap := MetricsSnapshot{
+		Timestamp:         now,
+		Running:           srv.isRunning.Load(),
+		Ready:             srv.isReady.Load(),
+		Requests:          srv.totalRequests.Load(),
+		HandlerTime:       time.Duration(srv.totalResponseTime.Load()) * time.Microsecond,
+		WebSocketUpgrades: srv.websocketConnections.Load(),
+		Routes:            []RouteMetrics{},
+		MiddlewareLayers:  srv.MiddlewareMetrics(),
+		HTTPClient:        srv.HTTPClientStats(),
+		SSE:               srv.SSEStats(),
+	}
+	if !srv.serverStart.IsZero() {
+		snap.Uptime = now.Sub(srv.serverStart)
+	}
+	if srv.metrics != nil {
+		snap.Routes = srv.metrics.snapshot(true)
+	}
+	if srv.gateway != nil {
+		ws := srv.GatewayWebSocketStats()
+		snap.GatewayWebSockets = &ws
+	}
+	if hits := srv.GeoPolicyHits(); len(hits) > 0 {
+		snap.GeoPolicyHits = hits
+	}
+	srv.limitersMu.RLock()
+	snap.RateLimiters = len(srv.clientLimiters)
+	srv.limitersMu.RUnlock()
+	return snap
+}
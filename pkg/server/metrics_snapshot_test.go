@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsSnapshot(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := srv.Handler()
+	for i := 0; i < 5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/items/%d", i), nil))
+	}
+
+	snap := srv.MetricsSnapshot()
+	if snap.Requests != 5 {
+		t.Errorf("expected 5 requests, got %d", snap.Requests)
+	}
+	if snap.Timestamp.IsZero() {
+		t.Error("expected snapshot timestamp")
+	}
+	var items *RouteMetrics
+	for i := range snap.Routes {
+		if snap.Routes[i].Route == "GET /items/{id}" {
+			items = &snap.Routes[i]
+		}
+	}
+	if items == nil {
+		t.Fatalf("route missing from snapshot: %+v", snap.Routes)
+	}
+	var bucketed uint64
+	for _, b := range items.Buckets {
+		bucketed += b.Count
+	}
+	if bucketed != items.Requests {
+		t.Errorf("histogram buckets hold %d requests, want %d", bucketed, items.Requests)
+	}
+
+	// Metrics() keeps its compact form without buckets
+	for _, route := range srv.Metrics() {
+		if route.Buckets != nil {
+			t.Errorf("Metrics() returned buckets for %s", route.Route)
+		}
+	}
+
+	if _, err := json.Marshal(snap); err != nil {
+		t.Errorf("snapshot does not marshal: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStatsDAddr       = "127.0.0.1:8125"
+	defaultStatsDPacketSize = 1432 // fits an Ethernet MTU without fragmentation
+)
+
+// StatsDConfig configures a StatsDExporter.
+type StatsDConfig struct {
+	Addr          string   // UDP address of the agent (default 127.0.0.1:8125)
+	Prefix        string   // Prepended to every metric name, e.g. "shop."
+	DogStatsD     bool     // Send route, method, and status as DogStatsD tags instead of in the name
+	Tags          []string // Constant tags such as "env:prod", DogStatsD only
+	MaxPacketSize int      // Metrics are batched into datagrams of at most this size (default 1432)
+}
+
+// StatsDExporter is a MetricsExporter for StatsD and the Datadog agent (DogStatsD). Request
+// and outbound client counters are sent as counts of what happened since the previous
+// export, latency percentiles as gauges in milliseconds.
+//
+// Metrics, with DogStatsD tags in brackets:
+//
+//	http.requests          count  [route, method, status, annotation labels]
+//	http.latency.{p50,p90,p99,max}  gauge  [route, method]
+//	http.websocket_upgrades  count
+//	http_client.{requests,errors,retries}  count
+//	http_client.in_flight  gauge
+//	uptime                 gauge (seconds)
+//
+// Without DogStatsD, tags are appended to the metric name, e.g.
+// "http.requests.GET__users__id_.GET.2xx".
+type StatsDExporter struct {
+	cfg  StatsDConfig
+	conn net.Conn
+
+	mu   sync.Mutex
+	prev map[string]uint64 // counter values at the previous export
+}
+
+// NewStatsDExporter creates an exporter sending to cfg.Addr over UDP.
+func NewStatsDExporter(cfg StatsDConfig) (*StatsDExporter, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = defaultStatsDAddr
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = defaultStatsDPacketSize
+	}
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open statsd connection to %s: %w", cfg.Addr, err)
+	}
+	return &StatsDExporter{cfg: cfg, conn: conn, prev: make(map[string]uint64)}, nil
+}
+
+// Close closes the UDP socket.
+func (e *StatsDExporter) Close() error {
+	return e.conn.Close()
+}
+
+// Export sends the snapshot. Counters that did not change since the previous export are
+// skipped.
+func (e *StatsDExporter) Export(ctx context.Context, snap MetricsSnapshot) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var lines []string
+	for _, route := range snap.Routes {
+		tags := []statsdTag{{"route", route.Route}, {"method", route.Method}}
+		for _, k := range slices.Sorted(maps.Keys(route.Labels)) {
+			tags = append(tags, statsdTag{k, route.Labels[k]})
+		}
+		for _, class := range slices.Sorted(maps.Keys(route.StatusClasses)) {
+			lines = e.count(lines, "http.requests", route.StatusClasses[class], append(tags[:len(tags):len(tags)], statsdTag{"status", class})...)
+		}
+		latencyTags := tags[:2]
+		lines = e.gauge(lines, "http.latency.p50", durationMillis(route.Latency.P50), latencyTags...)
+		lines = e.gauge(lines, "http.latency.p90", durationMillis(route.Latency.P90), latencyTags...)
+		lines = e.gauge(lines, "http.latency.p99", durationMillis(route.Latency.P99), latencyTags...)
+		lines = e.gauge(lines, "http.latency.max", durationMillis(route.Latency.Max), latencyTags...)
+	}
+	lines = e.count(lines, "http.websocket_upgrades", snap.WebSocketUpgrades)
+	for _, route := range slices.Sorted(maps.Keys(snap.SSE.Routes)) {
+		lines = e.gauge(lines, "sse.connections", strconv.FormatInt(snap.SSE.Routes[route], 10), statsdTag{"route", route})
+	}
+	lines = e.gauge(lines, "sse.open", strconv.FormatInt(snap.SSE.Open, 10))
+	lines = e.count(lines, "sse.rejected", snap.SSE.Rejected)
+	lines = e.count(lines, "http_client.requests", snap.HTTPClient.Requests)
+	lines = e.count(lines, "http_client.errors", snap.HTTPClient.Errors)
+	lines = e.count(lines, "http_client.retries", snap.HTTPClient.Retries)
+	lines = e.gauge(lines, "http_client.in_flight", strconv.FormatInt(snap.HTTPClient.InFlight, 10))
+	lines = e.gauge(lines, "uptime", strconv.FormatFloat(snap.Uptime.Seconds(), 'f', 0, 64))
+
+	return e.send(ctx, lines)
+}
+
+type statsdTag struct {
+	key, value string
+}
+
+// count appends the change of a cumulative counter since the previous export.
+func (e *StatsDExporter) count(lines []string, name string, total uint64, tags ...statsdTag) []string {
+	metric := e.metric(name, tags)
+	delta := total
+	if prev, ok := e.prev[metric]; ok && prev <= total {
+		delta = total - prev // a smaller total means the server restarted
+	}
+	e.prev[metric] = total
+	if delta == 0 {
+		return lines
+	}
+	return append(lines, metric+":"+strconv.FormatUint(delta, 10)+"|c"+e.tagSuffix(tags))
+}
+
+func (e *StatsDExporter) gauge(lines []string, name, value string, tags ...statsdTag) []string {
+	return append(lines, e.metric(name, tags)+":"+value+"|g"+e.tagSuffix(tags))
+}
+
+// metric returns the metric name, with the tag values appended for plain StatsD.
+func (e *StatsDExporter) metric(name string, tags []statsdTag) string {
+	if e.cfg.DogStatsD || len(tags) == 0 {
+		return e.cfg.Prefix + name
+	}
+	var b strings.Builder
+	b.WriteString(e.cfg.Prefix)
+	b.WriteString(name)
+	for _, tag := range tags {
+		b.WriteByte('.')
+		b.WriteString(statsdSanitize(tag.value, false))
+	}
+	return b.String()
+}
+
+func (e *StatsDExporter) tagSuffix(tags []statsdTag) string {
+	if !e.cfg.DogStatsD || (len(tags) == 0 && len(e.cfg.Tags) == 0) {
+		return ""
+	}
+	parts := slices.Clone(e.cfg.Tags)
+	for _, tag := range tags {
+		parts = append(parts, statsdSanitize(tag.key, false)+":"+statsdSanitize(tag.value, true))
+	}
+	return "|#" + strings.Join(parts, ",")
+}
+
+// send batches lines into datagrams of at most MaxPacketSize bytes.
+func (e *StatsDExporter) send(ctx context.Context, lines []string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		e.conn.SetWriteDeadline(deadline)
+	} else {
+		e.conn.SetWriteDeadline(time.Time{})
+	}
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := e.conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if packet.Len() > 0 && packet.Len()+1+len(line) > e.cfg.MaxPacketSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	return flush()
+}
+
+// statsdSanitize replaces characters that have a meaning in the StatsD line protocol.
+// Tag values may contain '/', '.' and ':'; metric name segments may not.
+func statsdSanitize(s string, tag bool) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case tag && (r == '/' || r == '.' || r == ':'):
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func durationMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
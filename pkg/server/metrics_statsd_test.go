@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listenStatsD(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readStatsD returns the lines of all datagrams received within a short window.
+func readStatsD(t *testing.T, conn *net.UDPConn) []string {
+	t.Helper()
+	var lines []string
+	buf := make([]byte, 64<<10)
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return lines
+		}
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+}
+
+func hasLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatsDExporterDogStatsD(t *testing.T) {
+	agent := listenStatsD(t)
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.Handler()
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	}
+
+	exporter, err := NewStatsDExporter(StatsDConfig{
+		Addr:          agent.LocalAddr().String(),
+		Prefix:        "shop.",
+		DogStatsD:     true,
+		Tags:          []string{"env:test"},
+		MaxPacketSize: 256,
+	})
+	if err != nil {
+		t.Fatalf("NewStatsDExporter: %v", err)
+	}
+	defer exporter.Close()
+
+	if err := exporter.Export(context.Background(), srv.MetricsSnapshot()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	lines := readStatsD(t, agent)
+	if want := "shop.http.requests:3|c|#env:test,route:GET_/users/_id_,method:GET,status:2xx"; !hasLine(lines, want) {
+		t.Errorf("missing %q in %q", want, lines)
+	}
+	var p99 bool
+	for _, line := range lines {
+		if strings.HasPrefix(line, "shop.http.latency.p99:") && strings.HasSuffix(line, "|g|#env:test,route:GET_/users/_id_,method:GET") {
+			p99 = true
+		}
+		if len(line) > 256 {
+			t.Errorf("line exceeds packet size: %q", line)
+		}
+	}
+	if !p99 {
+		t.Errorf("missing p99 gauge in %q", lines)
+	}
+
+	// Counters are sent as deltas
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	if err := exporter.Export(context.Background(), srv.MetricsSnapshot()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	lines = readStatsD(t, agent)
+	if want := "shop.http.requests:1|c|#env:test,route:GET_/users/_id_,method:GET,status:2xx"; !hasLine(lines, want) {
+		t.Errorf("missing %q in %q", want, lines)
+	}
+}
+
+func TestStatsDExporterPlainNames(t *testing.T) {
+	agent := listenStatsD(t)
+	exporter, err := NewStatsDExporter(StatsDConfig{Addr: agent.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("NewStatsDExporter: %v", err)
+	}
+	defer exporter.Close()
+
+	snap := MetricsSnapshot{Routes: []RouteMetrics{{
+		Route:         "GET /users/{id}",
+		Method:        http.MethodGet,
+		Requests:      2,
+		StatusClasses: map[string]uint64{"4xx": 2},
+	}}}
+	if err := exporter.Export(context.Background(), snap); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if lines, want := readStatsD(t, agent), "http.requests.GET__users__id_.GET.4xx:2|c"; !hasLine(lines, want) {
+		t.Errorf("missing %q in %q", want, lines)
+	}
+}
+
+func TestMetricsExporterFlushesOnShutdown(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	exports := make(chan MetricsSnapshot, 10)
+	exporter := MetricsExporterFunc(func(ctx context.Context, snap MetricsSnapshot) error {
+		exports <- snap
+		return nil
+	})
+	if err := WithMetricsExporter(exporter, 20*time.Millisecond)(srv); err != nil {
+		t.Fatalf("WithMetricsExporter: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.runMetricsExport(ctx, srv.exporters[0])
+		close(done)
+	}()
+	<-exports
+	cancel()
+	<-done
+	if len(exports) == 0 {
+		t.Error("expected a final export after cancellation")
+	}
+}
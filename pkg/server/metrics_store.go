@@ -0,0 +1,340 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Label values used by the metrics store to keep the number of series bounded.
+const (
+	// MetricsOtherLabel replaces label values that are not on the allowlist.
+	MetricsOtherLabel = "_other"
+	// MetricsOverflowLabel marks the single series that absorbs requests once
+	// the configured series limit has been reached.
+	MetricsOverflowLabel = "_overflow"
+	// MetricsUnmatchedRoute labels requests that did not match any registered route.
+	MetricsUnmatchedRoute = "_unmatched"
+)
+
+const defaultMetricsMaxSeries = 1000
+
+// knownMetricMethods are recorded as-is; any other method collapses into MetricsOtherLabel.
+var knownMetricMethods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodPatch:   {},
+	http.MethodDelete:  {},
+	http.MethodConnect: {},
+	http.MethodOptions: {},
+	http.MethodTrace:   {},
+}
+
+// metricLabels identifies a metrics series. Route is always the registered
+// pattern, never the raw URL, so user-supplied paths cannot create new series.
+type metricLabels struct {
+	Route  string
+	Method string
+	Extra  map[string]string
+}
+
+func (l metricLabels) key() string {
+	var b strings.Builder
+	b.WriteString(l.Route)
+	b.WriteByte('|')
+	b.WriteString(l.Method)
+	keys := make([]string, 0, len(l.Extra))
+	for k := range l.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteByte('|')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(l.Extra[k])
+	}
+	return b.String()
+}
+
+// metricSeries aggregates the requests recorded for one label set.
+type metricSeries struct {
+	labels        metricLabels
+	requests      uint64
+	statusClasses [5]uint64 // 1xx..5xx
+	totalDuration time.Duration
+	latency       latencyHistogram
+}
+
+// metricsStore holds labelled request metrics with cardinality protection.
+type metricsStore struct {
+	mu         sync.Mutex
+	series     map[string]*metricSeries
+	overflowed uint64
+}
+
+func newMetricsStore() *metricsStore {
+	return &metricsStore{series: make(map[string]*metricSeries)}
+}
+
+// record adds a request to the series identified by labels. Once maxSeries
+// distinct series exist, requests for new label sets are counted in the
+// overflow series instead.
+func (m *metricsStore) record(labels metricLabels, status int, duration time.Duration, maxSeries int) {
+	key := labels.key()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[key]
+	if !ok {
+		if maxSeries > 0 && len(m.series) >= maxSeries {
+			if m.overflowed == 0 {
+				logger.Warn("Metrics series limit reached, new label sets are counted in the overflow series",
+					"max_series", maxSeries)
+			}
+			m.overflowed++
+			labels = metricLabels{Route: MetricsOverflowLabel, Method: MetricsOverflowLabel}
+			key = labels.key()
+			s, ok = m.series[key]
+		}
+		if !ok {
+			s = &metricSeries{labels: labels}
+			m.series[key] = s
+		}
+	}
+
+	s.requests++
+	s.totalDuration += duration
+	s.latency.observe(duration)
+	if class := status/100 - 1; class >= 0 && class < len(s.statusClasses) {
+		s.statusClasses[class]++
+	}
+}
+
+// seriesCount returns the number of distinct series, including the overflow series.
+func (m *metricsStore) seriesCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.series)
+}
+
+// RouteMetrics is the metrics breakdown for one route, method, and label set.
+type RouteMetrics struct {
+	Route         string            `json:"route"`
+	Method        string            `json:"method"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Requests      uint64            `json:"requests"`
+	StatusClasses map[string]uint64 `json:"status_classes"` // "2xx", "4xx", ...
+	Latency       LatencySummary    `json:"latency"`
+	Buckets       []LatencyBucket   `json:"buckets,omitempty"` // Set by MetricsSnapshot only
+}
+
+// LatencyBucket is one non-empty bucket of a latency histogram. Counts are per bucket,
+// not cumulative; the last bucket has an UpperBound of math.MaxInt64.
+type LatencyBucket struct {
+	UpperBound time.Duration `json:"upper_bound"`
+	Count      uint64        `json:"count"`
+}
+
+// LatencySummary reports request latency estimated from a histogram; percentiles are
+// accurate to within about 41%.
+type LatencySummary struct {
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+func (h *latencyHistogram) summary() LatencySummary {
+	return LatencySummary{
+		Mean: h.mean(),
+		P50:  h.quantile(0.50),
+		P90:  h.quantile(0.90),
+		P99:  h.quantile(0.99),
+		Max:  h.max,
+	}
+}
+
+// snapshot returns the recorded series sorted by route, method, and labels, including
+// the histogram buckets when withBuckets is set.
+func (m *metricsStore) snapshot(withBuckets bool) []RouteMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]RouteMetrics, 0, len(keys))
+	for _, key := range keys {
+		s := m.series[key]
+		classes := make(map[string]uint64, len(s.statusClasses))
+		for i, n := range s.statusClasses {
+			if n > 0 {
+				classes[fmt.Sprintf("%dxx", i+1)] = n
+			}
+		}
+		var labels map[string]string
+		if len(s.labels.Extra) > 0 {
+			labels = maps.Clone(s.labels.Extra)
+		}
+		rm := RouteMetrics{
+			Route:         s.labels.Route,
+			Method:        s.labels.Method,
+			Labels:        labels,
+			Requests:      s.requests,
+			StatusClasses: classes,
+			Latency:       s.latency.summary(),
+		}
+		if withBuckets {
+			rm.Buckets = s.latency.buckets()
+		}
+		out = append(out, rm)
+	}
+	return out
+}
+
+// Metrics returns per-route request counts, status class distribution, and latency
+// percentiles, as recorded by MetricsMiddleware. Routes are identified by their
+// registered pattern; requests that matched no route are reported as MetricsUnmatchedRoute.
+func (srv *Server) Metrics() []RouteMetrics {
+	if srv.metrics == nil {
+		return nil
+	}
+	return srv.metrics.snapshot(false)
+}
+
+// RouteMetricsResource implements MCPResource for the per-route metrics breakdown.
+type RouteMetricsResource struct {
+	server *Server
+}
+
+// NewRouteMetricsResource creates a new per-route metrics resource.
+func NewRouteMetricsResource(srv *Server) *RouteMetricsResource {
+	return &RouteMetricsResource{server: srv}
+}
+
+func (r *RouteMetricsResource) URI() string {
+	return "metrics://server/routes"
+}
+
+func (r *RouteMetricsResource) CacheTTL() time.Duration {
+	return 0
+}
+
+func (r *RouteMetricsResource) Name() string {
+	return "Route Metrics"
+}
+
+func (r *RouteMetricsResource) Description() string {
+	return "Per-route request counts, status class distribution, and latency percentiles"
+}
+
+func (r *RouteMetricsResource) MimeType() string {
+	return "application/json"
+}
+
+func (r *RouteMetricsResource) Read() (interface{}, error) {
+	routes := r.server.Metrics()
+	if routes == nil {
+		routes = []RouteMetrics{}
+	}
+	return map[string]interface{}{
+		"routes":    routes,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+func (r *RouteMetricsResource) List() ([]string, error) {
+	return []string{r.URI()}, nil
+}
+
+// metricLabelsFor derives the bounded label set for a request: the matched
+// route pattern, a normalised method, and allowlisted request annotations.
+func (srv *Server) metricLabelsFor(r *http.Request) metricLabels {
+	labels := metricLabels{
+		Route:  srv.routePatternFor(r),
+		Method: r.Method,
+	}
+	if _, ok := knownMetricMethods[labels.Method]; !ok {
+		labels.Method = MetricsOtherLabel
+	}
+
+	if metricsLabels := srv.Options.current().MetricsLabels; len(metricsLabels) > 0 {
+		annotations := Annotations(r)
+		labels.Extra = make(map[string]string, len(metricsLabels))
+		for key, allowed := range metricsLabels {
+			value, ok := annotations[key]
+			if !ok {
+				labels.Extra[key] = ""
+				continue
+			}
+			v := fmt.Sprint(value)
+			if len(allowed) > 0 && !slices.Contains(allowed, v) {
+				v = MetricsOtherLabel
+			}
+			labels.Extra[key] = v
+		}
+	}
+	return labels
+}
+
+// routePatternFor returns the registered pattern that serves the request.
+func (srv *Server) routePatternFor(r *http.Request) string {
+	if pattern := RoutePattern(r); pattern != "" {
+		return pattern
+	}
+	if _, ok := r.Context().Value(routePatternKey).(string); !ok {
+		// Not served through the registry; resolve against the mux directly
+		if _, pattern := srv.mux.Handler(r); pattern != "" {
+			return pattern
+		}
+	}
+	return MetricsUnmatchedRoute
+}
+
+// WithMetricsMaxSeries limits the number of distinct label sets the metrics store tracks.
+// Requests for new label sets beyond the limit are counted in a single overflow series.
+// Default: 1000
+func WithMetricsMaxSeries(n int) ServerOptionFunc {
+	return func(srv *Server) error {
+		if n <= 0 {
+			return fmt.Errorf("metrics max series must be positive: %d", n)
+		}
+		srv.Options.MetricsMaxSeries = n
+		return nil
+	}
+}
+
+// WithMetricsLabel promotes a request annotation (see Annotate) to a metrics label.
+// Only the given values are recorded; any other value is reported as MetricsOtherLabel.
+// Without allowed values every value is recorded, bounded only by the series limit.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMetricsLabel("cache", "hit", "miss", "bypass"),
+//	)
+func WithMetricsLabel(key string, allowed ...string) ServerOptionFunc {
+	return func(srv *Server) error {
+		if key == "" {
+			return fmt.Errorf("metrics label requires a non-empty key")
+		}
+		if srv.Options.MetricsLabels == nil {
+			srv.Options.MetricsLabels = make(map[string][]string)
+		}
+		srv.Options.MetricsLabels[key] = allowed
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsUseRoutePatternNotRawPath(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := srv.Handler()
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d", i), nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/random-%d", i), nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := srv.metrics.seriesCount(); got != 2 {
+		t.Fatalf("expected 2 series (route + unmatched), got %d", got)
+	}
+
+	srv.metrics.mu.Lock()
+	defer srv.metrics.mu.Unlock()
+	route := srv.metrics.series[metricLabels{Route: "GET /users/{id}", Method: http.MethodGet}.key()]
+	if route == nil || route.requests != 50 {
+		t.Fatalf("expected 50 requests on route series, got %+v", route)
+	}
+	unmatched := srv.metrics.series[metricLabels{Route: MetricsUnmatchedRoute, Method: http.MethodGet}.key()]
+	if unmatched == nil || unmatched.statusClasses[3] != 50 {
+		t.Fatalf("expected 50 4xx responses on unmatched series, got %+v", unmatched)
+	}
+}
+
+func TestMetricsUnknownMethodsCollapse(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := srv.Handler()
+	for _, method := range []string{"FOO", "BAR", "BAZ"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+	}
+
+	if got := srv.metrics.seriesCount(); got != 1 {
+		t.Errorf("expected custom methods to share one series, got %d", got)
+	}
+}
+
+func TestMetricsAnnotationLabelsAllowlisted(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithMetricsLabel("cache", "hit", "miss"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r, "cache", r.URL.Query().Get("cache"))
+	})
+
+	handler := srv.Handler()
+	for _, value := range []string{"hit", "miss", "weird-1", "weird-2", "hit"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items?cache="+value, nil))
+	}
+
+	srv.metrics.mu.Lock()
+	defer srv.metrics.mu.Unlock()
+	if len(srv.metrics.series) != 3 {
+		t.Fatalf("expected hit, miss and other series, got %d", len(srv.metrics.series))
+	}
+	other := srv.metrics.series[metricLabels{
+		Route:  "/items",
+		Method: http.MethodGet,
+		Extra:  map[string]string{"cache": MetricsOtherLabel},
+	}.key()]
+	if other == nil || other.requests != 2 {
+		t.Errorf("expected 2 requests in other bucket, got %+v", other)
+	}
+}
+
+func TestMetricsOverflowSeries(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithMetricsMaxSeries(2), WithMetricsLabel("tenant"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r, "tenant", r.URL.Query().Get("t"))
+	})
+
+	handler := srv.Handler()
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/?t=%d", i), nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	srv.metrics.mu.Lock()
+	defer srv.metrics.mu.Unlock()
+	if len(srv.metrics.series) != 3 {
+		t.Fatalf("expected 2 series plus overflow, got %d", len(srv.metrics.series))
+	}
+	overflow := srv.metrics.series[metricLabels{Route: MetricsOverflowLabel, Method: MetricsOverflowLabel}.key()]
+	if overflow == nil || overflow.requests != 8 {
+		t.Errorf("expected 8 requests in overflow series, got %+v", overflow)
+	}
+	if srv.metrics.overflowed != 8 {
+		t.Errorf("expected overflow counter of 8, got %d", srv.metrics.overflowed)
+	}
+}
+
+func TestWithMetricsMaxSeriesRejectsNonPositive(t *testing.T) {
+	if _, err := NewServer(WithMetricsMaxSeries(0)); err == nil {
+		t.Fatal("expected error for zero max series")
+	}
+}
+
+func TestServerMetricsBreakdown(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	handler := srv.Handler()
+	for i := 0; i < 10; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/orders/%d", i), nil))
+	}
+
+	var orders *RouteMetrics
+	metrics := srv.Metrics()
+	for i := range metrics {
+		if metrics[i].Route == "GET /orders/{id}" {
+			orders = &metrics[i]
+		}
+	}
+	if orders == nil {
+		t.Fatalf("route missing from metrics: %+v", metrics)
+	}
+	if orders.Requests != 10 || orders.StatusClasses["2xx"] != 9 || orders.StatusClasses["5xx"] != 1 {
+		t.Errorf("unexpected breakdown: %+v", orders)
+	}
+	if orders.Latency.P99 < orders.Latency.P50 || orders.Latency.Max == 0 {
+		t.Errorf("unexpected latency summary: %+v", orders.Latency)
+	}
+
+	content, err := NewRouteMetricsResource(srv).Read()
+	if err != nil {
+		t.Fatalf("resource read failed: %v", err)
+	}
+	if routes := content.(map[string]interface{})["routes"].([]RouteMetrics); len(routes) != len(metrics) {
+		t.Errorf("resource returned %d routes, want %d", len(routes), len(metrics))
+	}
+}
@@ -6,7 +6,7 @@ The middleware package includes:
   - Panic recovery to prevent server crashes
   - Request metrics collection
   - Authentication (Basic, Bearer token, custom)
-  - Rate limiting per IP address
+  - Rate limiting per IP address, route, token, or API key
   - Security headers (HSTS, CSP, etc.)
   - Request/Response timing
 
@@ -23,6 +23,9 @@ Middleware can be applied globally or to specific routes:
 		server.AuthMiddleware(srv.Options),
 		server.RateLimitMiddleware(srv),
 	)
+
+	// Order by phase rather than by registration
+	srv.AddMiddlewareAt("/api", server.PhaseAuth, server.AuthMiddleware(srv.Options))
 */
 package server
 
@@ -33,10 +36,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"math/rand"
 	"net"
 	"net/http"
 	"reflect"
+	"runtime/debug"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -59,8 +64,14 @@ const GlobalMiddlewareRoute = "*"
 // MiddlewareRegistry manages middleware stacks for different routes.
 // It allows route-specific middleware configuration and supports exclusion of specific middleware.
 type MiddlewareRegistry struct {
-	middleware map[string]MiddlewareStack
-	exclude    []MiddlewareFunc
+	middleware   map[string]MiddlewareStack
+	phases       map[string][]MiddlewarePhase // phase of each middleware, by index
+	exclude      []MiddlewareFunc
+	layerMetrics *layerMetricsStore // per-layer timings, nil unless enabled
+	accessLog    *accessLogger      // access log format, nil for the default log line
+	redactor     *redactor          // scrubs access log entries, nil for the built-in patterns
+	reporter     ErrorReporter      // receives panics and 5xx responses, nil unless configured
+	sse          *sseConnections    // open SSE streams and their limits, nil disables tracking
 }
 
 // NewMiddlewareRegistry creates a new MiddlewareRegistry with optional global middleware.
@@ -68,6 +79,7 @@ type MiddlewareRegistry struct {
 func NewMiddlewareRegistry(globalMiddleware MiddlewareStack) *MiddlewareRegistry {
 	ret := &MiddlewareRegistry{
 		middleware: make(map[string]MiddlewareStack),
+		phases:     make(map[string][]MiddlewarePhase),
 	}
 	// add default middleware to all routes if defined in globalMiddleware stack
 	if globalMiddleware != nil {
@@ -78,66 +90,91 @@ func NewMiddlewareRegistry(globalMiddleware MiddlewareStack) *MiddlewareRegistry
 
 // Filter the MiddlewareRegistry based on include and exclude stacks
 func (mwr *MiddlewareRegistry) filterMiddleware() {
-	// range through the exclude middleware and remove them from the middleware registry
-	for _, excl := range mwr.exclude {
-		// range through all routes in the registry
-		for key, mw := range mwr.middleware {
-			filtered := MiddlewareStack{}
-			for _, m := range mw {
-				// we need to use reflect as Go as of 1.23 does not support direct comparison of func variables
-				if reflect.ValueOf(m) != reflect.ValueOf(excl) {
-					filtered = append(filtered, m)
-				}
+	if len(mwr.exclude) == 0 {
+		return
+	}
+	// range through all routes in the registry and remove the excluded middleware
+	for key, mw := range mwr.middleware {
+		filtered := MiddlewareStack{}
+		var phases []MiddlewarePhase
+		for i, m := range mw {
+			if !mwr.excluded(m) {
+				filtered = append(filtered, m)
+				phases = append(phases, mwr.phases[key][i])
 			}
-			mwr.middleware[key] = filtered
 		}
+		mwr.middleware[key] = filtered
+		mwr.phases[key] = phases
 	}
 }
 
+// excluded reports whether m is one of the excluded middleware.
+func (mwr *MiddlewareRegistry) excluded(m MiddlewareFunc) bool {
+	for _, excl := range mwr.exclude {
+		// we need to use reflect as Go as of 1.23 does not support direct comparison of func variables
+		if reflect.ValueOf(m) == reflect.ValueOf(excl) {
+			return true
+		}
+	}
+	return false
+}
+
 // applyToMux creates a handler that applies route-specific middleware
 func (mwr *MiddlewareRegistry) applyToMux(mux *http.ServeMux) http.Handler {
 	mwr.filterMiddleware()
 
 	// Return a handler that checks routes and applies appropriate middleware
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Start with the original mux as the final handler
+		// Start with the original mux as the final handler, counting SSE streams only
+		// once they have passed the other middleware
 		finalHandler := http.Handler(mux)
+		if mwr.sse != nil {
+			finalHandler = mwr.sse.wrap(finalHandler)
+		}
 
-		// Collect all applicable middleware for this request path
-		var applicableMiddleware []MiddlewareFunc
+		// Collect all applicable middleware for this request path, ordered by phase
+		applicableMiddleware := mwr.chain(r.URL.Path)
 
-		// First, add global middleware (if any)
-		if globalStack, exists := mwr.middleware[GlobalMiddlewareRoute]; exists {
-			applicableMiddleware = append(applicableMiddleware, globalStack...)
+		// The annotation store and matched route pattern are installed here so
+		// every layer can use them
+		r = withRoutePattern(withAnnotations(r), mux)
+		if mwr.accessLog != nil {
+			r = withAccessLogger(r, mwr.accessLog)
 		}
-
-		// Then, add route-specific middleware
-		for route, stack := range mwr.middleware {
-			if route != GlobalMiddlewareRoute && strings.HasPrefix(r.URL.Path, route) {
-				applicableMiddleware = append(applicableMiddleware, stack...)
-			}
+		if mwr.redactor != nil {
+			r = withRedactor(r, mwr.redactor)
 		}
 
 		// Apply middleware in reverse order (so first registered runs first)
-		for i := len(applicableMiddleware) - 1; i >= 0; i-- {
-			finalHandler = applicableMiddleware[i](finalHandler)
+		if mwr.layerMetrics != nil {
+			route := RoutePattern(r)
+			if route == "" {
+				route = MetricsUnmatchedRoute
+			}
+			r = r.WithContext(context.WithValue(r.Context(), layerScopeKey, &layerScope{store: mwr.layerMetrics, route: route}))
+			for i := len(applicableMiddleware) - 1; i >= 0; i-- {
+				finalHandler = mwr.layerMetrics.wrap(applicableMiddleware[i], finalHandler, route)
+			}
+		} else {
+			for i := len(applicableMiddleware) - 1; i >= 0; i-- {
+				finalHandler = applicableMiddleware[i](finalHandler)
+			}
 		}
 
 		// Serve the request with the wrapped handler
+		if mwr.reporter != nil {
+			serveWithErrorReporting(mwr.reporter, finalHandler, w, r)
+			return
+		}
 		finalHandler.ServeHTTP(w, r)
 	})
 }
 
 // Add registers a MiddlewareStack for a specific route in the registry.
 // Use GlobalMiddlewareRoute ("*") to apply middleware to all routes.
+// Middleware added this way runs in PhaseDefault, see AddWithPhase.
 func (mwr *MiddlewareRegistry) Add(route string, middleware MiddlewareStack) {
-	if existing, exists := mwr.middleware[route]; exists {
-		// Append to existing middleware for this route
-		mwr.middleware[route] = append(existing, middleware...)
-	} else {
-		// Create new entry
-		mwr.middleware[route] = middleware
-	}
+	mwr.AddWithPhase(route, PhaseDefault, middleware)
 }
 
 // Get retrieves the MiddlewareStack for a specific route.
@@ -155,11 +192,13 @@ func (mwr *MiddlewareRegistry) Get(route string) MiddlewareStack {
 // Does nothing if no middleware is registered for the route.
 func (mwr *MiddlewareRegistry) RemoveStack(route string) {
 	delete(mwr.middleware, route)
+	delete(mwr.phases, route)
 }
 
 // DefaultMiddleware returns a predefined middleware stack with essential server functionality.
 // Includes metrics collection, request logging, and panic recovery.
-// This middleware is applied by default unless explicitly excluded.
+// This middleware is applied by default; use WithDefaultMiddleware and
+// WithDefaultMiddlewareFunc to disable, reorder, or replace individual parts.
 func DefaultMiddleware(server *Server) MiddlewareStack {
 	return MiddlewareStack{
 		MetricsMiddleware(server),
@@ -168,7 +207,9 @@ func DefaultMiddleware(server *Server) MiddlewareStack {
 }
 
 // SecureAPI returns a middleware stack configured for secure API endpoints.
-// Includes authentication and rate limiting middleware.
+// Includes authentication and rate limiting middleware. Added with AddMiddlewareStack it
+// runs in PhaseDefault; register its parts with AddMiddlewareAt in PhaseAuth and
+// PhaseRateLimit to run them before application middleware registered earlier.
 func SecureAPI(srv *Server) MiddlewareStack {
 	return MiddlewareStack{
 		AuthMiddleware(srv.Options),
@@ -206,14 +247,30 @@ type Header struct {
 }
 
 // MetricsMiddleware returns a middleware function that collects request metrics.
-// It tracks total request count and response times for performance monitoring.
+// It tracks total request count and response times for performance monitoring, and records
+// per-route series labelled by route pattern, method, and allowlisted annotations.
+// The number of series is bounded by ServerOptions.MetricsMaxSeries. With
+// WithAPIUsageAnalytics it also records per-key usage.
 func MetricsMiddleware(srv *Server) MiddlewareFunc {
 	return func(next http.Handler) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			srv.totalRequests.Add(1)
+			r = withAnnotations(r)
+			mrw := &loggingResponseWriter{w, http.StatusOK, 0}
 			start := time.Now()
-			next.ServeHTTP(w, r)
-			srv.totalResponseTime.Add(time.Since(start).Microseconds())
+			next.ServeHTTP(mrw, r)
+			duration := time.Since(start)
+			srv.totalResponseTime.Add(duration.Microseconds())
+			if srv.metrics != nil || srv.slos != nil {
+				labels := srv.metricLabelsFor(r)
+				if srv.metrics != nil {
+					srv.metrics.record(labels, mrw.statusCode, duration, srv.Options.MetricsMaxSeries)
+				}
+				srv.slos.record(srv.Options.SLOs, labels, mrw.statusCode, duration)
+			}
+			if srv.apiUsage != nil {
+				srv.recordAPIUsage(r, mrw.statusCode)
+			}
 		}
 	}
 }
@@ -228,12 +285,12 @@ func AuthMiddleware(options *ServerOptions) MiddlewareFunc {
 
 			// check if header has bearer token
 			if !strings.HasPrefix(authHeader, bearerTokenPrefix) {
-				http.Error(w, "Unauthorized: Bearer token required", http.StatusUnauthorized)
+				unauthorized(w, r, options, "Unauthorized: Bearer token required")
 				return
 			}
 			token := strings.TrimPrefix(authHeader, bearerTokenPrefix)
 			if token == "" {
-				http.Error(w, "Unauthorized: Bearer token invalid", http.StatusUnauthorized)
+				unauthorized(w, r, options, "Unauthorized: Bearer token invalid")
 				return
 			}
 
@@ -256,7 +313,7 @@ func AuthMiddleware(options *ServerOptions) MiddlewareFunc {
 				return
 			}
 			if !valid {
-				http.Error(w, "Unauthorized: Bearer token invalid", http.StatusUnauthorized)
+				unauthorized(w, r, options, "Unauthorized: Bearer token invalid")
 				return
 			}
 
@@ -267,21 +324,41 @@ func AuthMiddleware(options *ServerOptions) MiddlewareFunc {
 	}
 }
 
+// unauthorized rejects the request with a 401, or the configured error page for browsers.
+func unauthorized(w http.ResponseWriter, r *http.Request, options *ServerOptions, message string) {
+	if options.ErrorPages.render(w, r, ErrorPage{Status: http.StatusUnauthorized, Message: "Please sign in to continue."}) {
+		return
+	}
+	http.Error(w, message, http.StatusUnauthorized)
+}
+
 // RequestLoggerMiddleware returns a middleware function that logs structured request information.
 // It captures and logs:
 //   - Client IP address
 //   - HTTP method and URL path
+//   - Matched route pattern
 //   - Trace ID (if present in X-Trace-ID header)
 //   - Response status code
 //   - Request duration
 //   - Response size in bytes
+//   - Annotations attached by the handler via Annotate
 //
+// The format, fields, and destination can be changed with WithAccessLog. Credentials in
+// query parameters and annotations are redacted, see WithRedaction.
 // This middleware is included by default in NewServer().
 // For high-traffic applications, consider the performance impact of logging.
 func RequestLoggerMiddleware(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// create a new logging response writer to capture status code and bytes written
 		lrw := &loggingResponseWriter{w, http.StatusOK, 0}
+		r = withAnnotations(r)
+
+		if al, ok := r.Context().Value(accessLogKey).(*accessLogger); ok {
+			start := time.Now()
+			next.ServeHTTP(lrw, r)
+			al.write(al.entry(r, start, lrw.statusCode, lrw.bytesWritten))
+			return
+		}
 
 		ip, _, _ := net.SplitHostPort(r.RemoteAddr)
 		traceID := r.Context().Value(traceIDKey)
@@ -292,13 +369,24 @@ func RequestLoggerMiddleware(next http.Handler) http.HandlerFunc {
 		start := time.Now()
 		next.ServeHTTP(lrw, r)
 		duration := time.Since(start)
-		logger.Info("Request completed",
+		args := []any{
 			"from", ip,
 			"method", r.Method,
-			"url", r.URL.String(),
+			"url", requestRedactor(r).url(r.URL.String()),
 			"trace_id", traceID,
 			"status", lrw.statusCode,
-			"duration", duration)
+			"duration", duration,
+			logModuleKey, LogModuleHTTP,
+		}
+		if route := RoutePattern(r); route != "" {
+			args = append(args, "route", route)
+		}
+		if a := annotationsFromContext(r.Context()); a != nil {
+			if attrs := a.attrs(requestRedactor(r)); len(attrs) > 0 {
+				args = append(args, slog.Group("annotations", attrs...))
+			}
+		}
+		logger.Info("Request completed", args...)
 	}
 }
 
@@ -320,6 +408,9 @@ func RecoveryMiddleware(next http.Handler) http.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				logger.Error("Panic recovered", "error", err)
+				if scope, ok := r.Context().Value(errorScopeKey).(*errorScope); ok {
+					scope.report(r, &PanicError{Value: err, Stack: debug.Stack()})
+				}
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
@@ -327,31 +418,35 @@ func RecoveryMiddleware(next http.Handler) http.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware returns a middleware function that enforces rate limiting per client IP address.
+// RateLimitMiddleware returns a middleware function that enforces rate limiting per client.
+// Clients are identified by IP address unless a RateLimitKeyFunc is configured via
+// WithRateLimitKeyFunc, in which case tokens, API keys, or user IDs can get individual buckets.
+// Per-key limits configured with WithRateLimitOverride take precedence over the server default.
 // Uses token bucket algorithm with configurable rate limit and burst capacity.
 // Returns 429 Too Many Requests when rate limit is exceeded.
 // Optimized for Go 1.24's Swiss Tables map implementation.
 func RateLimitMiddleware(srv *Server) MiddlewareFunc {
 	return func(next http.Handler) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+			key := srv.rateLimitKey(r)
+			limit, burst := srv.rateLimitFor(key)
 
 			// Try to get existing limiter with read lock (fast path)
 			srv.limitersMu.RLock()
-			entry, exists := srv.clientLimiters[ip]
+			entry, exists := srv.clientLimiters[key]
 			srv.limitersMu.RUnlock()
 
 			if !exists {
 				// Create new limiter with write lock
 				srv.limitersMu.Lock()
 				// Double-check in case another goroutine created it
-				entry, exists = srv.clientLimiters[ip]
+				entry, exists = srv.clientLimiters[key]
 				if !exists {
 					entry = &rateLimiterEntry{
-						limiter:    rate.NewLimiter(srv.Options.RateLimit, srv.Options.Burst),
+						limiter:    rate.NewLimiter(limit, burst),
 						lastAccess: time.Now(),
 					}
-					srv.clientLimiters[ip] = entry
+					srv.clientLimiters[key] = entry
 				}
 				srv.limitersMu.Unlock()
 			} else {
@@ -363,14 +458,17 @@ func RateLimitMiddleware(srv *Server) MiddlewareFunc {
 
 			if entry.limiter.Allow() {
 				// Add rate limit headers to inform clients of their current status
-				w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%.0f", float64(srv.Options.RateLimit)))
+				w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%.0f", float64(limit)))
 				w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%.0f", entry.limiter.Tokens()))
 				w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(time.Second).Unix()))
 				next.ServeHTTP(w, r)
 			} else {
 				// Add retry-after header for better client behavior
 				w.Header().Set("Retry-After", "1")
-				writeErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				page := ErrorPage{Status: http.StatusTooManyRequests, Message: "You are sending requests too quickly.", RetryAfter: 1}
+				if !srv.Options.ErrorPages.render(w, r, page) {
+					writeErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				}
 			}
 			return
 		}
@@ -414,20 +512,38 @@ func generateCSP(options *ServerOptions) string {
 func HeadersMiddleware(options *ServerOptions) MiddlewareFunc {
 	return func(next http.Handler) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
+			options := options.current()
 			// In hardened mode, suppress server header and apply stricter security policies
 			if !options.HardenedMode {
 				w.Header().Set("Server", "hyperserve")
 			}
 
-			// Set static security headers
-			for _, h := range securityHeaders {
-				w.Header().Set(h.key, h.value)
+			// Set static security headers, or those of the configured preset
+			headers, frameAncestors, csp := securityHeaders, "", ""
+			if options.SecurityHeaders != nil {
+				headers, frameAncestors, csp = options.SecurityHeaders.headers()
+			}
+			for _, h := range headers {
+				if h.value != "" && h.value != "-" {
+					w.Header().Set(h.key, h.value)
+				}
 			}
 
 			// Set dynamic CSP based on configuration
-			w.Header().Set("Content-Security-Policy", generateCSP(options))
+			if csp == "" {
+				csp = generateCSP(options)
+			}
+			if csp != "-" {
+				if frameAncestors != "" {
+					csp = setCSPDirective(csp, "frame-ancestors", frameAncestors)
+				}
+				if options.CSPNonce {
+					r, csp = withCSPNonce(r, csp, newCSPNonce())
+				}
+				w.Header().Set("Content-Security-Policy", csp)
+			}
 
-			if options.EnableTLS {
+			if options.EnableTLS && options.SecurityHeaders.tlsHSTS() {
 				w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
 			}
 
@@ -525,6 +641,7 @@ func addVaryHeader(w http.ResponseWriter, value string) {
 func ChaosMiddleware(options *ServerOptions) MiddlewareFunc {
 	return func(next http.Handler) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
+			options := options.current()
 			if !options.ChaosMode {
 				// Pass through if Chaos Mode is not enabled
 				next.ServeHTTP(w, r)
@@ -617,8 +734,16 @@ func (lrw *loggingResponseWriter) Flush() {
 	}
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. for write deadlines.
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
 func (lrw *loggingResponseWriter) WriteHeader(code int) {
-	lrw.statusCode = code
+	// Informational responses such as 103 Early Hints precede the final status
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		lrw.statusCode = code
+	}
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Names of the default middleware, see WithDefaultMiddleware.
+const (
+	DefaultMetrics  = "metrics"  // MetricsMiddleware
+	DefaultLogging  = "logging"  // RequestLoggerMiddleware
+	DefaultRecovery = "recovery" // RecoveryMiddleware
+)
+
+var defaultMiddlewareNames = []string{DefaultMetrics, DefaultLogging, DefaultRecovery}
+
+// defaultMiddlewareConfig selects the default middleware installed by NewServer.
+type defaultMiddlewareConfig struct {
+	order   []string // nil for all defaults in the standard order
+	replace map[string]MiddlewareFunc
+}
+
+// WithDefaultMiddleware selects which default middleware is installed and in which
+// order, outermost first. Defaults that are not named are disabled; no names disables
+// all of them. The defaults are MetricsMiddleware, RequestLoggerMiddleware, and
+// RecoveryMiddleware, in that order. They run in PhaseOutermost, ahead of middleware in
+// any other phase, so that panics, requests, and metrics are recorded for the whole chain.
+//
+// Example:
+//
+//	// Recover panics before they reach the metrics, and log requests elsewhere
+//	srv, _ := server.NewServer(server.WithDefaultMiddleware(server.DefaultRecovery, server.DefaultMetrics))
+func WithDefaultMiddleware(names ...string) ServerOptionFunc {
+	return func(srv *Server) error {
+		order := make([]string, 0, len(names))
+		for _, name := range names {
+			if !slices.Contains(defaultMiddlewareNames, name) {
+				return fmt.Errorf("unknown default middleware %q, expected one of %v", name, defaultMiddlewareNames)
+			}
+			if slices.Contains(order, name) {
+				return fmt.Errorf("default middleware %q listed twice", name)
+			}
+			order = append(order, name)
+		}
+		srv.defaultMiddleware.order = order
+		return nil
+	}
+}
+
+// WithDefaultMiddlewareFunc replaces one default middleware with a custom
+// implementation, keeping its position and the other defaults.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithDefaultMiddlewareFunc(server.DefaultRecovery, sentryRecovery))
+func WithDefaultMiddlewareFunc(name string, mw MiddlewareFunc) ServerOptionFunc {
+	return func(srv *Server) error {
+		if !slices.Contains(defaultMiddlewareNames, name) {
+			return fmt.Errorf("unknown default middleware %q, expected one of %v", name, defaultMiddlewareNames)
+		}
+		if mw == nil {
+			return fmt.Errorf("default middleware %q: replacement must not be nil", name)
+		}
+		if srv.defaultMiddleware.replace == nil {
+			srv.defaultMiddleware.replace = make(map[string]MiddlewareFunc)
+		}
+		srv.defaultMiddleware.replace[name] = mw
+		return nil
+	}
+}
+
+// defaultMiddlewareStack resolves the configured default middleware.
+func (srv *Server) defaultMiddlewareStack() ([]string, MiddlewareStack) {
+	order := srv.defaultMiddleware.order
+	if order == nil {
+		order = defaultMiddlewareNames
+	}
+	builtin := DefaultMiddleware(srv)
+	stack := make(MiddlewareStack, 0, len(order))
+	for _, name := range order {
+		mw, ok := srv.defaultMiddleware.replace[name]
+		if !ok {
+			mw = builtin[slices.Index(defaultMiddlewareNames, name)]
+		}
+		stack = append(stack, mw)
+	}
+	return order, stack
+}
+
+// prependWithPhase registers middleware for a route ahead of middleware already
+// registered, so the defaults stay outermost within their phase even though options
+// registering global middleware run before them.
+func (mwr *MiddlewareRegistry) prependWithPhase(route string, phase MiddlewarePhase, middleware MiddlewareStack) {
+	phases := make([]MiddlewarePhase, len(middleware))
+	for i := range phases {
+		phases[i] = phase
+	}
+	mwr.middleware[route] = append(slices.Clone(middleware), mwr.middleware[route]...)
+	mwr.phases[route] = append(phases, mwr.phases[route]...)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func chainNames(srv *Server) []string {
+	var names []string
+	for _, info := range srv.MiddlewareChain("/") {
+		names = append(names, strings.TrimSuffix(info.Name, ".func1"))
+	}
+	return names
+}
+
+func TestDefaultMiddlewareStandardOrder(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"server.MetricsMiddleware", "server.RequestLoggerMiddleware", "server.RecoveryMiddleware"}
+	if got := chainNames(srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	// The defaults also wrap middleware in earlier phases
+	srv.AddMiddlewareAt(GlobalMiddlewareRoute, PhasePreRouting, TraceMiddleware)
+	srv.AddMiddlewareAt(GlobalMiddlewareRoute, PhaseAuth, AuthMiddleware(srv.Options))
+	want = append(want, "server.TraceMiddleware", "server.AuthMiddleware")
+	if got := chainNames(srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+}
+
+func TestWithDefaultMiddlewareReorderAndDisable(t *testing.T) {
+	srv, err := NewServer(
+		WithDefaultMiddleware(DefaultRecovery, DefaultMetrics),
+		// Registers global middleware while options are applied, before the defaults
+		WithGeoProvider(GeoProviderFunc(func(ip net.IP) (GeoInfo, error) { return GeoInfo{}, nil })),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"server.RecoveryMiddleware", "server.MetricsMiddleware", "server.GeoEnrichmentMiddleware"}
+	if got := chainNames(srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	srv, err = NewServer(WithDefaultMiddleware())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.MiddlewareChain("/"); len(got) != 0 {
+		t.Errorf("expected no default middleware, got %v", got)
+	}
+}
+
+func TestWithDefaultMiddlewareFuncReplaces(t *testing.T) {
+	var order []string
+	srv, err := NewServer(WithDefaultMiddlewareFunc(DefaultLogging, recordingMiddleware("logger", &order)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { order = append(order, "handler") })
+	srv.middleware.applyToMux(srv.mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if want := []string{"logger", "handler"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if srv.totalRequests.Load() != 1 {
+		t.Errorf("expected the metrics default to remain installed")
+	}
+}
+
+func TestWithDefaultMiddlewareRejectsUnknownNames(t *testing.T) {
+	if _, err := NewServer(WithDefaultMiddleware("tracing")); err == nil {
+		t.Error("expected an error for an unknown default")
+	}
+	if _, err := NewServer(WithDefaultMiddleware(DefaultMetrics, DefaultMetrics)); err == nil {
+		t.Error("expected an error for a duplicate default")
+	}
+	if _, err := NewServer(WithDefaultMiddlewareFunc(DefaultRecovery, nil)); err == nil {
+		t.Error("expected an error for a nil replacement")
+	}
+}
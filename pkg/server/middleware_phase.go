@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MiddlewarePhase orders middleware independently of registration order. Middleware of a
+// lower phase runs first (further from the handler); within a phase, global middleware
+// runs before route middleware, shorter route prefixes before longer ones, and otherwise
+// in registration order.
+type MiddlewarePhase int
+
+const (
+	// PhaseOutermost is used by the default metrics, logging, and recovery middleware,
+	// so that they cover every other phase.
+	PhaseOutermost MiddlewarePhase = 0
+	// PhasePreRouting is for middleware that must see every request first, such as
+	// tracing, CORS preflight handling, or request rewriting.
+	PhasePreRouting MiddlewarePhase = 100
+	// PhaseAuth is for authentication and authorization.
+	PhaseAuth MiddlewarePhase = 200
+	// PhaseRateLimit is for rate limits and quotas, which may key on the authenticated identity.
+	PhaseRateLimit MiddlewarePhase = 300
+	// PhaseDefault is used by AddMiddleware and AddMiddlewareStack.
+	PhaseDefault MiddlewarePhase = 400
+	// PhasePost is for middleware that must run closest to the handler.
+	PhasePost MiddlewarePhase = 500
+)
+
+// String returns the phase name, or its number for custom phases.
+func (p MiddlewarePhase) String() string {
+	switch p {
+	case PhaseOutermost:
+		return "outermost"
+	case PhasePreRouting:
+		return "pre-routing"
+	case PhaseAuth:
+		return "auth"
+	case PhaseRateLimit:
+		return "rate-limit"
+	case PhaseDefault:
+		return "default"
+	case PhasePost:
+		return "post"
+	}
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+// MiddlewareInfo describes one middleware in the chain for a path.
+type MiddlewareInfo struct {
+	Route string          `json:"route"` // Registration route, "*" for global
+	Phase MiddlewarePhase `json:"phase"`
+	Name  string          `json:"name"` // Function name, e.g. "server.AuthMiddleware"
+}
+
+// AddWithPhase registers a MiddlewareStack for a route in the given phase.
+func (mwr *MiddlewareRegistry) AddWithPhase(route string, phase MiddlewarePhase, middleware MiddlewareStack) {
+	mwr.middleware[route] = append(mwr.middleware[route], middleware...)
+	for range middleware {
+		mwr.phases[route] = append(mwr.phases[route], phase)
+	}
+}
+
+type phasedMiddleware struct {
+	route string
+	phase MiddlewarePhase
+	fn    MiddlewareFunc
+}
+
+// ordered returns the middleware applying to path, outermost first.
+func (mwr *MiddlewareRegistry) ordered(path string) []phasedMiddleware {
+	routes := make([]string, 0, len(mwr.middleware))
+	for route := range mwr.middleware {
+		if route != GlobalMiddlewareRoute && strings.HasPrefix(path, route) {
+			routes = append(routes, route)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if len(routes[i]) != len(routes[j]) {
+			return len(routes[i]) < len(routes[j])
+		}
+		return routes[i] < routes[j]
+	})
+	if _, ok := mwr.middleware[GlobalMiddlewareRoute]; ok {
+		routes = append([]string{GlobalMiddlewareRoute}, routes...)
+	}
+
+	var chain []phasedMiddleware
+	for _, route := range routes {
+		for i, fn := range mwr.middleware[route] {
+			chain = append(chain, phasedMiddleware{route: route, phase: mwr.phases[route][i], fn: fn})
+		}
+	}
+	slices.SortStableFunc(chain, func(a, b phasedMiddleware) int {
+		return int(a.phase) - int(b.phase)
+	})
+	return chain
+}
+
+// chain returns the middleware functions applying to path, outermost first.
+func (mwr *MiddlewareRegistry) chain(path string) []MiddlewareFunc {
+	ordered := mwr.ordered(path)
+	fns := make([]MiddlewareFunc, len(ordered))
+	for i, m := range ordered {
+		fns[i] = m.fn
+	}
+	return fns
+}
+
+// AddMiddlewareAt adds middleware to a route in an explicit phase, so that it runs in a
+// predictable position relative to middleware registered elsewhere, regardless of the
+// order of the calls.
+//
+// Example:
+//
+//	srv.AddMiddlewareAt("/api", server.PhaseRateLimit, server.RateLimitMiddleware(srv))
+//	srv.AddMiddlewareAt("/api", server.PhaseAuth, server.AuthMiddleware(srv.Options))
+//	// Auth runs before the rate limit, which can key on the session
+func (srv *Server) AddMiddlewareAt(route string, phase MiddlewarePhase, mw ...MiddlewareFunc) {
+	srv.middleware.AddWithPhase(route, phase, mw)
+	logger.Debug("Middleware registered", "route", route, "phase", phase, "count", len(mw))
+}
+
+// MiddlewareChain returns the middleware that will run for a request path, outermost
+// first, for debugging the effective order. Excluded middleware is left out, without
+// changing the registry.
+func (srv *Server) MiddlewareChain(path string) []MiddlewareInfo {
+	infos := []MiddlewareInfo{}
+	for _, m := range srv.middleware.ordered(path) {
+		if !srv.middleware.excluded(m.fn) {
+			infos = append(infos, MiddlewareInfo{Route: m.route, Phase: m.phase, Name: middlewareName(m.fn)})
+		}
+	}
+	return infos
+}
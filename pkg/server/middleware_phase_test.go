@@ -0,0 +1,131 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingMiddleware appends name to order when the request passes through it.
+func recordingMiddleware(name string, order *[]string) MiddlewareFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			*order = append(*order, name)
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+func servePhased(t *testing.T, mwr *MiddlewareRegistry, path string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	mwr.applyToMux(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+}
+
+func TestMiddlewarePhasesOrderIndependentOfRegistration(t *testing.T) {
+	t.Parallel()
+	var order []string
+	mwr := NewMiddlewareRegistry(nil)
+	mwr.Add("/api", MiddlewareStack{recordingMiddleware("app", &order)})
+	mwr.AddWithPhase("/api", PhasePost, MiddlewareStack{recordingMiddleware("post", &order)})
+	mwr.AddWithPhase("/api", PhaseRateLimit, MiddlewareStack{recordingMiddleware("ratelimit", &order)})
+	mwr.AddWithPhase("/api", PhaseAuth, MiddlewareStack{recordingMiddleware("auth", &order)})
+	mwr.AddWithPhase(GlobalMiddlewareRoute, PhasePreRouting, MiddlewareStack{recordingMiddleware("trace", &order)})
+
+	servePhased(t, mwr, "/api/users")
+	want := []string{"trace", "auth", "ratelimit", "app", "post"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestMiddlewareDefaultPhaseKeepsRegistrationOrder(t *testing.T) {
+	t.Parallel()
+	var order []string
+	mwr := NewMiddlewareRegistry(MiddlewareStack{recordingMiddleware("global1", &order), recordingMiddleware("global2", &order)})
+	mwr.Add("/api/v1", MiddlewareStack{recordingMiddleware("v1", &order)})
+	mwr.Add("/api", MiddlewareStack{recordingMiddleware("api1", &order), recordingMiddleware("api2", &order)})
+	mwr.Add(GlobalMiddlewareRoute, MiddlewareStack{recordingMiddleware("global3", &order)})
+
+	servePhased(t, mwr, "/api/v1/items")
+	want := []string{"global1", "global2", "global3", "api1", "api2", "v1"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+
+	order = nil
+	servePhased(t, mwr, "/other")
+	if want := []string{"global1", "global2", "global3"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestMiddlewareChainIntrospection(t *testing.T) {
+	t.Parallel()
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AddMiddleware("/admin", RequestLoggerMiddleware)
+	srv.AddMiddlewareAt("/admin", PhaseAuth, AuthMiddleware(srv.Options))
+
+	chain := srv.MiddlewareChain("/admin/users")
+	auth, logging := -1, -1
+	for i, info := range chain {
+		switch {
+		case strings.HasSuffix(info.Name, "AuthMiddleware"):
+			auth = i
+			if info.Route != "/admin" || info.Phase != PhaseAuth {
+				t.Errorf("auth middleware info = %+v", info)
+			}
+		case info.Route == "/admin" && strings.HasSuffix(info.Name, "RequestLoggerMiddleware"):
+			logging = i
+			if info.Phase != PhaseDefault {
+				t.Errorf("logger phase = %v, want %v", info.Phase, PhaseDefault)
+			}
+		}
+	}
+	if auth < 0 || logging < 0 {
+		t.Fatalf("missing middleware in chain: %+v", chain)
+	}
+	if auth > logging {
+		t.Errorf("auth at %d runs after default-phase logger at %d", auth, logging)
+	}
+	for _, info := range srv.MiddlewareChain("/public") {
+		if info.Route == "/admin" {
+			t.Errorf("/admin middleware in chain for /public: %+v", info)
+		}
+	}
+}
+
+func TestMiddlewareChainLeavesRegistryUnchanged(t *testing.T) {
+	t.Parallel()
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AddMiddlewareStack("/admin", MiddlewareStack{RequestLoggerMiddleware, RecoveryMiddleware})
+	srv.middleware.exclude = []MiddlewareFunc{RecoveryMiddleware}
+
+	for _, info := range srv.MiddlewareChain("/admin") {
+		if info.Route == "/admin" && strings.HasSuffix(info.Name, "RecoveryMiddleware") {
+			t.Errorf("excluded middleware in chain: %+v", info)
+		}
+	}
+	if n := len(srv.middleware.middleware["/admin"]); n != 2 || len(srv.middleware.phases["/admin"]) != 2 {
+		t.Errorf("expected the registry to keep both middleware, got %d", n)
+	}
+}
+
+func TestMiddlewarePhaseString(t *testing.T) {
+	t.Parallel()
+	if got := PhaseRateLimit.String(); got != "rate-limit" {
+		t.Errorf("PhaseRateLimit.String() = %q", got)
+	}
+	if got := MiddlewarePhase(250).String(); got != "phase(250)" {
+		t.Errorf("MiddlewarePhase(250).String() = %q", got)
+	}
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOperationsPath = "/operations"
+	defaultOperationTTL   = time.Hour
+)
+
+// OperationStatus is the state of a long-running operation.
+type OperationStatus string
+
+const (
+	OperationRunning   OperationStatus = "running"
+	OperationSucceeded OperationStatus = "succeeded"
+	OperationFailed    OperationStatus = "failed"
+	OperationCanceled  OperationStatus = "canceled"
+)
+
+// Done reports whether the operation has finished.
+func (s OperationStatus) Done() bool {
+	return s != OperationRunning
+}
+
+// Operation is the state of a long-running operation as returned to clients.
+type Operation struct {
+	ID        string          `json:"id"`
+	Status    OperationStatus `json:"status"`
+	Result    interface{}     `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// OperationFunc performs the work of a long-running operation. Its result is returned to
+// clients as JSON. The context is cancelled when a client cancels the operation or the
+// server shuts down.
+type OperationFunc func(ctx context.Context) (interface{}, error)
+
+// OperationsConfig configures the long-running operations store and its endpoints.
+type OperationsConfig struct {
+	Path string        `json:"path,omitempty"` // Prefix of the operation URLs (default "/operations")
+	TTL  time.Duration `json:"ttl,omitempty"`  // How long finished operations can be polled (default 1h)
+}
+
+// WithOperations enables long-running operations. Handlers start them with
+// StartOperation, and clients poll GET /operations/{id}, wait for completion with an SSE
+// stream on the same URL (Accept: text/event-stream), or cancel with DELETE. Operations
+// are kept in memory. They can also be enabled via the "operations" key in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithOperations(server.OperationsConfig{TTL: 10 * time.Minute}))
+//	srv.HandleFunc("POST /reports", func(w http.ResponseWriter, r *http.Request) {
+//		srv.StartOperation(w, r, func(ctx context.Context) (interface{}, error) {
+//			return buildReport(ctx)
+//		})
+//	})
+func WithOperations(cfg OperationsConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		srv.Options.Operations = &cfg
+		return nil
+	}
+}
+
+func (cfg *OperationsConfig) validate() error {
+	if cfg.Path != "" && (!strings.HasPrefix(cfg.Path, "/") || strings.HasSuffix(cfg.Path, "/")) {
+		return fmt.Errorf("operations path must start and must not end with '/': %q", cfg.Path)
+	}
+	if cfg.TTL < 0 {
+		return fmt.Errorf("operations TTL must not be negative")
+	}
+	return nil
+}
+
+func (cfg *OperationsConfig) path() string {
+	if cfg.Path == "" {
+		return defaultOperationsPath
+	}
+	return cfg.Path
+}
+
+// setupOperations creates the store and registers the operation endpoints.
+func (srv *Server) setupOperations(cfg *OperationsConfig) {
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = defaultOperationTTL
+	}
+	srv.operations = &operationStore{ops: make(map[string]*operationEntry), ttl: ttl}
+	srv.HandleFunc("GET "+cfg.path()+"/{id}", srv.operationHandler)
+	srv.HandleFunc("DELETE "+cfg.path()+"/{id}", srv.cancelOperationHandler)
+}
+
+// StartOperation runs fn in the background and answers the request with 202 Accepted, the
+// operation and its URL in the Location header. It requires WithOperations.
+func (srv *Server) StartOperation(w http.ResponseWriter, r *http.Request, fn OperationFunc) {
+	if srv.operations == nil {
+		logger.Error("StartOperation requires WithOperations", "path", r.URL.Path)
+		writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	parent := srv.lifecycleCtx
+	if parent == nil {
+		parent = context.Background()
+	}
+	op := srv.operations.start(parent, fn)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", srv.Options.Operations.path()+"/"+op.ID)
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		logger.Error("Failed to encode operation", "error", err)
+	}
+}
+
+// Operation returns the operation with the given ID, if it exists and has not expired.
+func (srv *Server) Operation(id string) (Operation, bool) {
+	if srv.operations == nil {
+		return Operation{}, false
+	}
+	e := srv.operations.get(id)
+	if e == nil {
+		return Operation{}, false
+	}
+	op, _ := e.snapshot()
+	return op, true
+}
+
+// operationHandler returns the operation, or streams it until it is done for SSE clients.
+func (srv *Server) operationHandler(w http.ResponseWriter, r *http.Request) {
+	e := srv.operations.get(r.PathValue("id"))
+	if e == nil {
+		writeErrorResponse(w, http.StatusNotFound, "Operation not found")
+		return
+	}
+	op, done := e.snapshot()
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		if !op.Status.Done() {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(op); err != nil {
+			logger.Error("Failed to encode operation", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+	send := func(op Operation) error {
+		payload, err := json.Marshal(op)
+		if err != nil {
+			return err
+		}
+		frame, err := encodeSSEFrame("", "operation", payload)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if err := send(op); err != nil || op.Status.Done() {
+		return
+	}
+	select {
+	case <-done:
+		op, _ = e.snapshot()
+		send(op)
+	case <-r.Context().Done():
+	}
+}
+
+// cancelOperationHandler cancels a running operation.
+func (srv *Server) cancelOperationHandler(w http.ResponseWriter, r *http.Request) {
+	e := srv.operations.get(r.PathValue("id"))
+	if e == nil {
+		writeErrorResponse(w, http.StatusNotFound, "Operation not found")
+		return
+	}
+	e.cancel()
+	op, _ := e.snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		logger.Error("Failed to encode operation", "error", err)
+	}
+}
+
+// operationStore keeps operations in memory until their TTL after completion expires.
+type operationStore struct {
+	mu  sync.Mutex
+	ops map[string]*operationEntry
+	ttl time.Duration
+}
+
+type operationEntry struct {
+	mu       sync.Mutex
+	op       Operation
+	done     chan struct{}
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+func (e *operationEntry) snapshot() (Operation, <-chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.op, e.done
+}
+
+func (s *operationStore) start(parent context.Context, fn OperationFunc) Operation {
+	var b [16]byte
+	rand.Read(b[:])
+	now := time.Now()
+	ctx, cancel := context.WithCancel(parent)
+	e := &operationEntry{
+		op:   Operation{ID: hex.EncodeToString(b[:]), Status: OperationRunning, CreatedAt: now, UpdatedAt: now},
+		done: make(chan struct{}),
+	}
+	e.cancel = func() {
+		e.mu.Lock()
+		e.canceled = !e.op.Status.Done()
+		e.mu.Unlock()
+		cancel()
+	}
+
+	s.mu.Lock()
+	s.sweepLocked(now)
+	s.ops[e.op.ID] = e
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		result, err := runOperation(ctx, fn)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		switch {
+		case e.canceled || (err != nil && errors.Is(err, context.Canceled)):
+			e.op.Status, e.op.Error = OperationCanceled, "operation canceled"
+		case err != nil:
+			e.op.Status, e.op.Error = OperationFailed, err.Error()
+		default:
+			e.op.Status, e.op.Result = OperationSucceeded, result
+		}
+		e.op.UpdatedAt = time.Now()
+		close(e.done)
+	}()
+	return e.op
+}
+
+func runOperation(ctx context.Context, fn OperationFunc) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logger.Error("Operation panicked", "panic", p)
+			err = fmt.Errorf("internal error")
+		}
+	}()
+	return fn(ctx)
+}
+
+func (s *operationStore) get(id string) *operationEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.ops[id]
+	if e == nil {
+		return nil
+	}
+	if op, _ := e.snapshot(); op.Status.Done() && time.Since(op.UpdatedAt) > s.ttl {
+		delete(s.ops, id)
+		return nil
+	}
+	return e
+}
+
+// sweepLocked removes expired operations. Callers hold s.mu.
+func (s *operationStore) sweepLocked(now time.Time) {
+	for id, e := range s.ops {
+		if op, _ := e.snapshot(); op.Status.Done() && now.Sub(op.UpdatedAt) > s.ttl {
+			delete(s.ops, id)
+		}
+	}
+}
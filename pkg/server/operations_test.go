@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newOperationsServer(t *testing.T, fn OperationFunc) (*Server, *httptest.Server) {
+	t.Helper()
+	srv, err := NewServer(WithOperations(OperationsConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		srv.StartOperation(w, r, fn)
+	})
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func startJob(t *testing.T, ts *httptest.Server) (string, Operation) {
+	t.Helper()
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	var op Operation
+	if err := json.NewDecoder(resp.Body).Decode(&op); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Header.Get("Location"), op
+}
+
+func pollOperation(t *testing.T, url string) (int, Operation) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var op Operation
+	json.NewDecoder(resp.Body).Decode(&op)
+	return resp.StatusCode, op
+}
+
+func TestOperationPolling(t *testing.T) {
+	release := make(chan struct{})
+	_, ts := newOperationsServer(t, func(ctx context.Context) (interface{}, error) {
+		<-release
+		return map[string]int{"rows": 42}, nil
+	})
+
+	location, op := startJob(t, ts)
+	if location != "/operations/"+op.ID || op.Status != OperationRunning {
+		t.Fatalf("unexpected operation %+v at %q", op, location)
+	}
+	if _, polled := pollOperation(t, ts.URL+location); polled.Status != OperationRunning {
+		t.Errorf("expected running, got %s", polled.Status)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, polled := pollOperation(t, ts.URL+location)
+		if polled.Status == OperationSucceeded {
+			if result, _ := polled.Result.(map[string]interface{}); result["rows"] != float64(42) {
+				t.Errorf("unexpected result %v", polled.Result)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("operation did not finish: %+v", polled)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if status, _ := pollOperation(t, ts.URL+"/operations/unknown"); status != http.StatusNotFound {
+		t.Errorf("unknown operation: expected 404, got %d", status)
+	}
+}
+
+func TestOperationCompletionStream(t *testing.T) {
+	release := make(chan struct{})
+	_, ts := newOperationsServer(t, func(ctx context.Context) (interface{}, error) {
+		<-release
+		return nil, errors.New("upstream timed out")
+	})
+	location, _ := startJob(t, ts)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+location, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+
+	events := readSSEEvents(t, resp, 2)
+	if len(events) != 2 || events[0].data["status"] != "running" || events[1].data["status"] != "failed" || events[1].data["error"] != "upstream timed out" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestOperationCancel(t *testing.T) {
+	srv, ts := newOperationsServer(t, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	location, op := startJob(t, ts)
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+location, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("cancel: expected 202, got %d", resp.StatusCode)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		current, ok := srv.Operation(op.ID)
+		if ok && current.Status == OperationCanceled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("operation was not canceled: %+v", current)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOperationExpiry(t *testing.T) {
+	store := &operationStore{ops: make(map[string]*operationEntry), ttl: time.Millisecond}
+	op := store.start(context.Background(), func(ctx context.Context) (interface{}, error) { return "ok", nil })
+	<-store.get(op.ID).done
+	time.Sleep(5 * time.Millisecond)
+	if store.get(op.ID) != nil {
+		t.Error("expected the finished operation to expire")
+	}
+}
+
+func TestStartOperationRequiresWithOperations(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	srv.StartOperation(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader("")), func(ctx context.Context) (interface{}, error) { return nil, nil })
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
@@ -14,12 +14,23 @@ Environment Variables:
   - HS_MCP_ENABLED: Enable Model Context Protocol (default "false")
   - HS_MCP_ENDPOINT: MCP endpoint path (default "/mcp")
   - HS_MCP_DEV: Enable MCP developer tools (default "false")
+  - HS_MCP_DEV_I_UNDERSTAND: Allow MCP developer tools despite production signals (default "false")
   - HS_MCP_OBSERVABILITY: Enable MCP observability resources (default "false")
   - HS_MCP_TRANSPORT: MCP transport type: "http" or "stdio" (default "http")
+  - HS_MCP_SUSPENDED: Start with MCP disabled until it is enabled at runtime (default "false")
+  - HS_MCP_ADMIN_PATH: Path of the endpoint that enables and disables MCP at runtime (default "")
+  - HS_MCP_ADMIN_TOKEN: Bearer token that authorizes the MCP admin endpoint (default "")
   - HS_CSP_WEB_WORKER_SUPPORT: Enable Web Worker CSP headers (default "false")
+  - HS_TLS_MIN_VERSION: Minimum TLS version, "1.2" or "1.3" (default "1.2")
+  - HS_TLS_CIPHER_SUITES: Comma-separated TLS 1.2 cipher suites by IANA name (default: Go's secure suites)
+  - HS_TLS_CURVES: Comma-separated key exchange curves in order of preference (default: Go's defaults)
+  - HS_CSP_NONCE: Allow inline scripts by per-request nonce instead of 'unsafe-inline' (default "false")
   - HS_LOG_LEVEL: Set log level (DEBUG, INFO, WARN, ERROR) (default "INFO")
   - HS_DEBUG: Enable debug mode and debug logging (default "false")
+  - HS_REDACT_HEADERS: Comma-separated header name patterns to redact from logs and captures, in addition to the built-in ones
+  - HS_REDACT_FIELDS: Comma-separated body, query, and MCP parameter name patterns to redact, in addition to the built-in ones
   - HS_SUPPRESS_BANNER: Suppress the HyperServe ASCII banner at startup (default "false")
+  - HS_PROFILE: Configuration profile: "dev", "staging", "production", or one defined in options.json (default "")
 
 Example configuration file (options.json):
 
@@ -33,11 +44,25 @@ Example configuration file (options.json):
 	  "debug_mode": false,
 	  "log_level": "INFO"
 	}
+
+Profiles select a named block of overrides, chosen by the "profile" key or HS_PROFILE.
+The built-in profiles "dev" (debug logging, Web Worker CSP), "staging", and "production"
+(hardened mode) preset a few options; blocks in the file add to or override them:
+
+	{
+	  "profile": "staging",
+	  "rate_limit": 100,
+	  "profiles": {
+	    "dev": {"mcp_enabled": true},
+	    "production": {"log_level": "WARN", "run_health_server": true}
+	  }
+	}
 */
 package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -45,6 +70,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -77,34 +103,116 @@ type ServerOptions struct {
 	ChaosThrottleRate      float64       `json:"chaos_throttle_rate,omitempty"`
 	ChaosPanicRate         float64       `json:"chaos_panic_rate,omitempty"`
 	AuthTokenValidatorFunc func(token string) (bool, error)
-	FIPSMode               bool     `json:"fips_mode,omitempty"`
-	EnableECH              bool     `json:"enable_ech,omitempty"`
-	ECHKeys                [][]byte `json:"-"` // ECH keys are sensitive, don't serialize
-	HardenedMode           bool     `json:"hardened_mode,omitempty"`
+	FIPSMode               bool `json:"fips_mode,omitempty"`
+	// TLS settings, see WithTLSConfig and WithCustomTLSConfig
+	TLSMinVersion       string      `json:"tls_min_version,omitempty"`       // "1.2" or "1.3"
+	TLSCipherSuites     []string    `json:"tls_cipher_suites,omitempty"`     // IANA names, e.g. "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"
+	TLSCurvePreferences []string    `json:"tls_curve_preferences,omitempty"` // e.g. "X25519MLKEM768", "P256"
+	TLSConfig           *tls.Config `json:"-"`                               // Replaces the generated configuration
+	EnableECH           bool        `json:"enable_ech,omitempty"`
+	ECHKeys             [][]byte    `json:"-"` // ECH keys are sensitive, don't serialize
+	HardenedMode        bool        `json:"hardened_mode,omitempty"`
 	// MCP (Model Context Protocol) configuration
-	MCPEnabled          bool                                        `json:"mcp_enabled,omitempty"`
-	MCPEndpoint         string                                      `json:"mcp_endpoint,omitempty"`
-	MCPServerName       string                                      `json:"mcp_server_name,omitempty"`
-	MCPServerVersion    string                                      `json:"mcp_server_version,omitempty"`
-	MCPToolsEnabled     bool                                        `json:"mcp_tools_enabled,omitempty"`
-	MCPResourcesEnabled bool                                        `json:"mcp_resources_enabled,omitempty"`
-	MCPFileToolRoot     string                                      `json:"mcp_file_tool_root,omitempty"`
-	MCPLogResourceSize  int                                         `json:"mcp_log_resource_size,omitempty"`
-	MCPTransport        MCPTransportType                            `json:"mcp_transport,omitempty"`
-	MCPDev              bool                                        `json:"mcp_dev,omitempty"`
-	MCPObservability    bool                                        `json:"mcp_observability,omitempty"`
-	MCPDiscoveryPolicy  DiscoveryPolicy                             `json:"mcp_discovery_policy,omitempty"`
-	MCPDiscoveryFilter  func(toolName string, r *http.Request) bool `json:"-"` // Custom filter function
-	mcpTransportOpts    mcpTransportOptions                         // Internal transport options
+	MCPEnabled            bool                                        `json:"mcp_enabled,omitempty"`
+	MCPEndpoint           string                                      `json:"mcp_endpoint,omitempty"`
+	MCPServerName         string                                      `json:"mcp_server_name,omitempty"`
+	MCPServerVersion      string                                      `json:"mcp_server_version,omitempty"`
+	MCPToolsEnabled       bool                                        `json:"mcp_tools_enabled,omitempty"`
+	MCPResourcesEnabled   bool                                        `json:"mcp_resources_enabled,omitempty"`
+	MCPFileToolRoot       string                                      `json:"mcp_file_tool_root,omitempty"`
+	MCPFileWrite          *MCPFileWriteConfig                         `json:"mcp_file_write,omitempty"`           // Enables the file write tools, see WithMCPFileWriteEnabled
+	MCPMaxSessions        int                                         `json:"mcp_max_sessions,omitempty"`         // Open MCP sessions and sessions that keep tool state, see WithMCPSessionLimits
+	MCPSessionIdleTimeout time.Duration                               `json:"mcp_session_idle_timeout,omitempty"` // Unused sessions are dropped after this
+	MCPUpstreams          []MCPUpstreamConfig                         `json:"mcp_upstreams,omitempty"`            // Upstream MCP servers mounted under namespaces, see WithMCPUpstream
+	MCPLogResourceSize    int                                         `json:"mcp_log_resource_size,omitempty"`
+	MCPTransport          MCPTransportType                            `json:"mcp_transport,omitempty"`
+	MCPDev                bool                                        `json:"mcp_dev,omitempty"`
+	MCPDevAllowRemote     bool                                        `json:"mcp_dev_allow_remote,omitempty"` // Developer tools answer non-loopback clients
+	MCPObservability      bool                                        `json:"mcp_observability,omitempty"`
+	MCPDiscoveryPolicy    DiscoveryPolicy                             `json:"mcp_discovery_policy,omitempty"`
+	MCPDiscoveryFilter    func(toolName string, r *http.Request) bool `json:"-"`                                 // Custom filter function
+	MCPNamespaceDiscovery map[string]MCPNamespaceDiscovery            `json:"mcp_namespace_discovery,omitempty"` // Per-namespace description, tags, and policy
+	mcpTransportOpts      mcpTransportOptions                         // Internal transport options
+	MCPTrace              *MCPTraceConfig                             `json:"mcp_trace,omitempty"`      // Mirrors MCP traffic to an NDJSON file
+	MCPAudit              *MCPAuditConfig                             `json:"mcp_audit,omitempty"`      // Records MCP tool calls and resource reads
+	MCPSuspended          bool                                        `json:"mcp_suspended,omitempty"`  // Starts with MCP disabled, see Server.SetMCPEnabled
+	MCPAdminPath          string                                      `json:"mcp_admin_path,omitempty"` // Serves the MCP kill switch, see WithMCPAdminEndpoint
+	MCPAdminToken         string                                      `json:"-"`                        // Operator credential for the MCP kill switch
 	// CSP (Content Security Policy) configuration
-	CSPWebWorkerSupport bool         `json:"csp_web_worker_support,omitempty"`
-	CORS                *CORSOptions `json:"cors,omitempty"`
+	CSPWebWorkerSupport bool             `json:"csp_web_worker_support,omitempty"`
+	CSPNonce            bool             `json:"csp_nonce,omitempty"`        // Allows inline scripts by per-request nonce, see WithCSPNonce
+	SecurityHeaders     *SecurityHeaders `json:"security_headers,omitempty"` // Preset and overrides for HeadersMiddleware
+	CORS                *CORSOptions     `json:"cors,omitempty"`
 	// Logging configuration
 	LogLevel  string `json:"log_level,omitempty"`
 	DebugMode bool   `json:"debug_mode,omitempty"`
+	// Log sampling and per-module levels
+	Logging *LogConfig `json:"logging,omitempty"`
+	// Extra header and field patterns scrubbed from logs and captured requests
+	Redaction *RedactionConfig `json:"redaction,omitempty"`
+	// Configuration profile, e.g. "dev" or "production", selected by "profile" or HS_PROFILE
+	Profile string `json:"profile,omitempty"`
 	// Banner configuration
 	SuppressBanner bool `json:"suppress_banner,omitempty"`
 	BannerColor    bool `json:"banner_color,omitempty"`
+	// Rate limiting configuration
+	RateLimitKeyFunc   RateLimitKeyFunc             `json:"-"` // Selects the bucket per request (defaults to client IP)
+	RateLimitOverrides map[string]RateLimitOverride `json:"rate_limit_overrides,omitempty"`
+	// Metrics configuration
+	MetricsMaxSeries  int                 `json:"metrics_max_series,omitempty"` // Upper bound on distinct label sets
+	MetricsLabels     map[string][]string `json:"metrics_labels,omitempty"`     // Annotation keys promoted to labels, with allowed values
+	MiddlewareMetrics bool                `json:"middleware_metrics,omitempty"` // Time each middleware layer per route
+	// Access logging
+	AccessLog *AccessLogConfig `json:"access_log,omitempty"` // Format, fields, and output of request logs
+	// Error reporting
+	ErrorReporter ErrorReporter `json:"-"` // Receives panics, 5xx responses, and MCP tool failures
+	// Diagnostics
+	PprofPath  string `json:"pprof_path,omitempty"` // Serves net/http/pprof and runtime stats under this path
+	PprofToken string `json:"-"`                    // Operator credential for the profiling endpoints
+	// Static assets
+	AssetManifestPath string                  `json:"asset_manifest_path,omitempty"` // Serves the fingerprinted asset manifest at this path
+	ServiceWorker     *ServiceWorkerConfig    `json:"service_worker,omitempty"`      // Serves a generated offline service worker
+	DirectoryListing  *DirectoryListingConfig `json:"directory_listing,omitempty"`   // Lists static directories without an index.html
+	// Batch requests
+	Batch *BatchConfig `json:"batch,omitempty"` // Serves several sub-requests per round trip, see WithBatchEndpoint
+	// Long-running operations
+	Operations *OperationsConfig `json:"operations,omitempty"` // Enables StartOperation and the /operations endpoints
+	// HTML error pages
+	ErrorPages *ErrorPagesConfig `json:"error_pages,omitempty"` // Login redirect and friendly 401/429 pages for browsers
+	// Token introspection (RFC 7662)
+	Introspection *IntrospectionConfig `json:"introspection,omitempty"` // Validates bearer tokens against an external IdP
+	// MCP authorization (OAuth 2.1 protected resource)
+	MCPAuthorization *MCPAuthorizationConfig `json:"mcp_authorization,omitempty"` // Requires access tokens on the MCP endpoint
+	// Per-tool MCP authorization and rate limits
+	MCPToolPolicies  map[string]MCPToolPolicy `json:"mcp_tool_policies,omitempty"` // Keyed by tool name, see WithMCPToolPolicy
+	MCPPrincipalFunc MCPPrincipalFunc         `json:"-"`                           // Identifies tool callers (defaults to the introspection result)
+	// MCP tool concurrency limits
+	MCPToolConcurrency *MCPToolConcurrencyConfig `json:"mcp_tool_concurrency,omitempty"` // Caps running tool calls, see WithMCPToolConcurrency
+	// Geolocation enrichment
+	GeoProvider GeoProvider `json:"-"` // Resolves country/ASN for client IPs (no database is bundled)
+	// Usage quotas
+	QuotaStore QuotaStore `json:"-"` // Persists quota counters (defaults to in-memory)
+	// Scheduled route policies
+	RouteWindows []RouteWindow `json:"route_windows,omitempty"` // Time-of-day route availability and throttling
+	// Route metadata, consulted by retries and caching
+	RouteMeta map[string]RouteMeta `json:"route_meta,omitempty"` // Idempotency and cacheability keyed by route pattern
+	// Outbound HTTP client
+	HTTPClient *HTTPClientConfig `json:"http_client,omitempty"` // Shared client for gateway, MCP tools, and handlers
+	// API gateway
+	GatewayConfigFile     string        `json:"gateway_config_file,omitempty"`     // Proxied routes and upstreams, see GatewayConfig
+	GatewayReloadInterval time.Duration `json:"gateway_reload_interval,omitempty"` // How often to check the file for changes, 0 disables
+	// Service level objectives
+	SLOs []SLO `json:"slos,omitempty"` // Reported by /healthz/?verbose=1 and the SLO MCP resource
+	// API usage analytics
+	APIUsage *APIUsageConfig `json:"api_usage,omitempty"` // Per-key request counts and top endpoints, see WithAPIUsageAnalytics
+	// Server-Sent Events
+	SSELimits *SSELimitsConfig `json:"sse_limits,omitempty"` // Caps open event streams, see WithSSELimits
+	// Secrets referenced from configuration values as ${scheme:ref}
+	SecretProviders map[string]SecretProvider `json:"-"` // Keyed by scheme, see WithSecretProvider
+	// Configuration hot reload
+	ConfigReloadInterval time.Duration             `json:"config_reload_interval,omitempty"` // How often to check options.json for changes, 0 disables
+	OnConfigChange       []func(ConfigChangeEvent) `json:"-"`                                // Called after a reload changed the configuration
+	MaintenanceMode      bool                      `json:"maintenance_mode,omitempty"`       // Answers all but health checks with 503
 
 	// OnShutdownHooks are functions called when the server receives a shutdown signal.
 	// Hooks are executed sequentially in the order they were added, before HTTP server shutdown.
@@ -119,6 +227,13 @@ type ServerOptions struct {
 	OnReadyHooks []func(context.Context, *Server) error `json:"-"`
 	// StopOnDeferredInitFailure indicates whether the server should shut down if deferred init fails.
 	StopOnDeferredInitFailure bool `json:"stop_on_deferred_init_failure,omitempty"`
+
+	sources      map[string]string // Source of each option that is not a default, see Validate
+	secretFields map[string]bool   // Options whose value came from a secret reference
+
+	profileDefined bool // Whether options.json has a block for Profile
+
+	reloaded *atomic.Pointer[ServerOptions] // Latest snapshot published by a reload, see current
 }
 
 var defaultServerOptions = &ServerOptions{
@@ -168,6 +283,8 @@ var defaultServerOptions = &ServerOptions{
 	// Banner defaults
 	SuppressBanner: false,
 	BannerColor:    false,
+	// Metrics defaults
+	MetricsMaxSeries: defaultMetricsMaxSeries,
 	// Deferred init defaults
 	StopOnDeferredInitFailure: true,
 }
@@ -193,9 +310,13 @@ const (
 func NewServerOptions() *ServerOptions {
 	// Create a copy of defaultServerOptions to avoid modifying the shared instance
 	config := *defaultServerOptions
-	configPtr := applyEnvVars(applyConfigFile(&config))
-	configPtr.CORS = normalizeCORSOptions(configPtr.CORS)
-	return configPtr
+	applyConfigFile(&config)
+	fromFile := config
+	applyEnvVars(&config)
+	config.CORS = normalizeCORSOptions(config.CORS)
+	config.markConfigSources(&fromFile, &config, ConfigSourceEnv)
+	config.reloaded = new(atomic.Pointer[ServerOptions])
+	return &config
 }
 
 // ServerOptionFunc is a function type used to configure Server instances.
@@ -229,6 +350,22 @@ func applyEnvVars(config *ServerOptions) *ServerOptions {
 			logger.Debug("MCP disabled from environment variable", "variable", paramMCPEnabled)
 		}
 	}
+	if mcpSuspended := os.Getenv(paramMCPSuspended); mcpSuspended != "" {
+		if mcpSuspended == "true" || mcpSuspended == "1" {
+			config.MCPSuspended = true
+			logger.Debug("MCP suspended from environment variable", "variable", paramMCPSuspended)
+		} else if mcpSuspended == "false" || mcpSuspended == "0" {
+			config.MCPSuspended = false
+		}
+	}
+	if mcpAdminPath := os.Getenv(paramMCPAdminPath); mcpAdminPath != "" {
+		config.MCPAdminPath = mcpAdminPath
+		logger.Debug("MCP admin path set from environment variable", "variable", paramMCPAdminPath, "path", mcpAdminPath)
+	}
+	if mcpAdminToken := os.Getenv(paramMCPAdminToken); mcpAdminToken != "" {
+		config.MCPAdminToken = mcpAdminToken
+		logger.Debug("MCP admin token set from environment variable", "variable", paramMCPAdminToken)
+	}
 	if mcpEndpoint := os.Getenv(paramMCPEndpoint); mcpEndpoint != "" {
 		config.MCPEndpoint = mcpEndpoint
 		logger.Debug("MCP endpoint set from environment variable", "variable", paramMCPEndpoint, "endpoint", mcpEndpoint)
@@ -300,6 +437,44 @@ func applyEnvVars(config *ServerOptions) *ServerOptions {
 			logger.Debug("CSP Web Worker support disabled from environment variable", "variable", paramCSPWebWorkerSupport)
 		}
 	}
+	if minVersion := os.Getenv(paramTLSMinVersion); minVersion != "" {
+		config.TLSMinVersion = minVersion
+		logger.Debug("TLS minimum version set from environment variable", "variable", paramTLSMinVersion, "version", minVersion)
+	}
+	if suites := os.Getenv(paramTLSCipherSuites); suites != "" {
+		config.TLSCipherSuites = sanitizeTokens(strings.Split(suites, ","), false)
+		logger.Debug("TLS cipher suites set from environment variable", "variable", paramTLSCipherSuites)
+	}
+	if curves := os.Getenv(paramTLSCurves); curves != "" {
+		config.TLSCurvePreferences = sanitizeTokens(strings.Split(curves, ","), false)
+		logger.Debug("TLS curves set from environment variable", "variable", paramTLSCurves)
+	}
+	if cspNonce := os.Getenv(paramCSPNonce); cspNonce != "" {
+		if cspNonce == "true" || cspNonce == "1" {
+			config.CSPNonce = true
+			logger.Debug("CSP nonces enabled from environment variable", "variable", paramCSPNonce)
+		} else if cspNonce == "false" || cspNonce == "0" {
+			config.CSPNonce = false
+			logger.Debug("CSP nonces disabled from environment variable", "variable", paramCSPNonce)
+		}
+	}
+	headers, fields := os.Getenv(paramRedactHeaders), os.Getenv(paramRedactFields)
+	if headers != "" || fields != "" {
+		// Copy the file's redaction settings so that the change is attributed to the environment
+		var redaction RedactionConfig
+		if config.Redaction != nil {
+			redaction = *config.Redaction
+		}
+		if headers != "" {
+			redaction.Headers = sanitizeTokens(strings.Split(headers, ","), false)
+			logger.Debug("Redacted headers set from environment variable", "variable", paramRedactHeaders)
+		}
+		if fields != "" {
+			redaction.Fields = sanitizeTokens(strings.Split(fields, ","), false)
+			logger.Debug("Redacted fields set from environment variable", "variable", paramRedactFields)
+		}
+		config.Redaction = &redaction
+	}
 
 	// Logging environment variables
 	if logLevel := os.Getenv(paramLogLevel); logLevel != "" {
@@ -381,9 +556,43 @@ func applyEnvVars(config *ServerOptions) *ServerOptions {
 		config.CORS = normalizeCORSOptions(config.CORS)
 	}
 
+	if pprofPath := os.Getenv(paramPprofPath); pprofPath != "" {
+		config.PprofPath = pprofPath
+		logger.Debug("Profiling path set from environment variable", "variable", paramPprofPath, "path", pprofPath)
+	}
+	if pprofToken := os.Getenv(paramPprofToken); pprofToken != "" {
+		config.PprofToken = pprofToken
+		logger.Debug("Profiling token set from environment variable", "variable", paramPprofToken)
+	}
+
+	// Token introspection environment variables
+	if endpoint := os.Getenv(paramIntrospectURL); endpoint != "" {
+		ensureIntrospectionConfig(config).Endpoint = endpoint
+		logger.Debug("Token introspection endpoint set from environment variable", "variable", paramIntrospectURL, "endpoint", endpoint)
+	}
+	if clientID := os.Getenv(paramIntrospectClientID); clientID != "" {
+		ensureIntrospectionConfig(config).ClientID = clientID
+		logger.Debug("Token introspection client ID set from environment variable", "variable", paramIntrospectClientID)
+	}
+	if secret := os.Getenv(paramIntrospectSecret); secret != "" {
+		ensureIntrospectionConfig(config).ClientSecret = secret
+		logger.Debug("Token introspection client secret set from environment variable", "variable", paramIntrospectSecret)
+	}
+
 	return config
 }
 
+func ensureIntrospectionConfig(config *ServerOptions) *IntrospectionConfig {
+	if config.Introspection == nil {
+		config.Introspection = &IntrospectionConfig{}
+	} else {
+		// copy so that environment overrides do not modify a shared configuration
+		c := *config.Introspection
+		config.Introspection = &c
+	}
+	return config.Introspection
+}
+
 func ensureCORSOptions(config *ServerOptions) *CORSOptions {
 	if config.CORS == nil {
 		config.CORS = &CORSOptions{}
@@ -391,30 +600,35 @@ func ensureCORSOptions(config *ServerOptions) *CORSOptions {
 	return config.CORS
 }
 
-// helper to read a options file and apply it to the options
+// helper to read a options file and the selected profile and apply them to the options.
+// Records the source of the values it sets.
 func applyConfigFile(config *ServerOptions) *ServerOptions {
-	file, err := os.Open(paramFileName)
-	if err != nil {
+	before := *config
+	var keys map[string]json.RawMessage
+	fileConfig := &ServerOptions{}
+	if data, err := os.ReadFile(paramFileName); err != nil {
 		logger.Debug("Failed to open options file.", "error", err)
-		return config
+	} else if err := json.Unmarshal(data, &keys); err != nil {
+		logger.Debug("No options file or loading failed; Using environment and defaults")
+	} else if err := json.Unmarshal(data, fileConfig); err != nil {
+		logger.Debug("No options file or loading failed; Using environment and defaults")
+		keys = nil
+	} else {
+		logger.Debug("Server configuration loaded from file", "file", paramFileName)
+		mergeConfig(config, fileConfig)
+		config.markConfigSources(&before, config, ConfigSourceFile)
 	}
 
-	// make sure file is closed after reading
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			logger.Error("Failed to close file", "error", err, "file-name", file.Name())
+	if profile := configProfile(fileConfig.Profile); profile != "" {
+		config.Profile = profile
+		if os.Getenv(paramProfile) != "" {
+			config.markConfigSource("profile", ConfigSourceEnv)
 		}
-	}(file)
-
-	decoder := json.NewDecoder(file)
-	fileConfig := &ServerOptions{}
-	if err := decoder.Decode(fileConfig); err != nil {
-		logger.Debug("No options file or loading failed; Using environment and defaults")
-		return config
+		if err := applyConfigProfile(config, profile, keys); err != nil {
+			logger.Error("Failed to apply configuration profile", "profile", profile, "error", err)
+		}
+		logger.Debug("Configuration profile applied", "profile", profile)
 	}
-	logger.Debug("Server configuration loaded from file", "file", paramFileName)
-	mergeConfig(config, fileConfig)
 	return config
 }
 
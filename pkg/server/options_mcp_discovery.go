@@ -59,3 +59,48 @@ func WithMCPDiscoveryFilter(filter func(toolName string, r *http.Request) bool)
 		return nil
 	}
 }
+
+// WithMCPNamespaceDiscoveryPolicy sets the discovery policy for the tools and resources
+// of one namespace, overriding WithMCPDiscoveryPolicy. DiscoveryNone hides the namespace
+// entirely, counts included.
+//
+// Example - Show the blog tools but hide the built-in hyperserve tools:
+//
+//	srv, _ := server.NewServer(
+//	    server.WithMCPSupport("blog", "1.0.0"),
+//	    server.WithMCPNamespaceDiscoveryPolicy("hyperserve", server.DiscoveryNone),
+//	    server.WithMCPNamespaceDiscoveryPolicy("blog", server.DiscoveryPublic),
+//	)
+func WithMCPNamespaceDiscoveryPolicy(namespace string, policy DiscoveryPolicy) ServerOptionFunc {
+	return func(srv *Server) error {
+		cfg := srv.Options.MCPNamespaceDiscovery[namespace]
+		cfg.Policy = &policy
+		setNamespaceDiscovery(srv, namespace, cfg)
+		return nil
+	}
+}
+
+// WithMCPNamespaceInfo describes a namespace in the discovery document and tags its
+// tools, so that clients can group them like OpenAPI operations.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//	    server.WithMCPNamespaceInfo("blog", "Publish and search blog posts", "content", "public"),
+//	)
+func WithMCPNamespaceInfo(namespace, description string, tags ...string) ServerOptionFunc {
+	return func(srv *Server) error {
+		cfg := srv.Options.MCPNamespaceDiscovery[namespace]
+		cfg.Description = description
+		cfg.Tags = tags
+		setNamespaceDiscovery(srv, namespace, cfg)
+		return nil
+	}
+}
+
+func setNamespaceDiscovery(srv *Server, namespace string, cfg MCPNamespaceDiscovery) {
+	if srv.Options.MCPNamespaceDiscovery == nil {
+		srv.Options.MCPNamespaceDiscovery = make(map[string]MCPNamespaceDiscovery)
+	}
+	srv.Options.MCPNamespaceDiscovery[namespace] = cfg
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultOutboundTimeout         = 30 * time.Second
+	defaultOutboundDialTimeout     = 10 * time.Second
+	defaultOutboundIdleConnTimeout = 90 * time.Second
+	defaultOutboundMaxIdleConns    = 100
+	defaultOutboundMaxIdlePerHost  = 10
+	defaultOutboundRetryBackoff    = 100 * time.Millisecond
+	maxOutboundRetryBackoff        = 5 * time.Second
+)
+
+// HTTPClientConfig configures the shared outbound HTTP client returned by
+// Server.HTTPClient. Zero values select the defaults noted below.
+type HTTPClientConfig struct {
+	Timeout               time.Duration `json:"timeout,omitempty"`                 // Whole request including body (default 30s)
+	DialTimeout           time.Duration `json:"dial_timeout,omitempty"`            // TCP connect (default 10s)
+	TLSHandshakeTimeout   time.Duration `json:"tls_handshake_timeout,omitempty"`   // Default 10s
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"` // 0 waits up to Timeout
+	IdleConnTimeout       time.Duration `json:"idle_conn_timeout,omitempty"`       // Default 90s
+	MaxIdleConns          int           `json:"max_idle_conns,omitempty"`          // Default 100
+	MaxIdleConnsPerHost   int           `json:"max_idle_conns_per_host,omitempty"` // Default 10
+	MaxConnsPerHost       int           `json:"max_conns_per_host,omitempty"`      // 0 is unlimited
+	Proxy                 string        `json:"proxy,omitempty"`                   // Proxy URL, empty uses HTTP_PROXY/HTTPS_PROXY
+	Retries               int           `json:"retries,omitempty"`                 // Retries of idempotent requests on network errors, 502, 503, and 504
+	RetryBackoff          time.Duration `json:"retry_backoff,omitempty"`           // First retry delay, doubled per attempt (default 100ms)
+	// TLSConfig customises certificate verification and client certificates.
+	TLSConfig *tls.Config `json:"-"`
+}
+
+// HTTPClientStats counts requests made through the shared outbound client.
+type HTTPClientStats struct {
+	Requests uint64 `json:"requests"`
+	Errors   uint64 `json:"errors"` // Requests that failed without a response
+	Retries  uint64 `json:"retries"`
+	InFlight int64  `json:"in_flight"`
+}
+
+// WithHTTPClient configures the shared outbound HTTP client used by the gateway, the
+// built-in MCP http_request tool, MCP upstreams, token introspection, edge purgers and
+// Vault secret providers without a client of their own, and application code via
+// Server.HTTPClient. It can also be set via "http_client" in options.json.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithHTTPClient(server.HTTPClientConfig{
+//		Timeout:         10 * time.Second,
+//		MaxConnsPerHost: 50,
+//		Retries:         2,
+//	}))
+func WithHTTPClient(cfg HTTPClientConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if cfg.Retries < 0 {
+			return fmt.Errorf("http client retries must not be negative: %d", cfg.Retries)
+		}
+		if cfg.Proxy != "" {
+			if _, err := url.Parse(cfg.Proxy); err != nil {
+				return fmt.Errorf("invalid http client proxy %q: %w", cfg.Proxy, err)
+			}
+		}
+		srv.Options.HTTPClient = &cfg
+		return nil
+	}
+}
+
+// HTTPClient returns the server's shared outbound client. Connections are pooled across
+// all users, idempotent requests are retried with exponential backoff, the trace ID of
+// the inbound request is forwarded as X-Trace-ID when the request context carries one,
+// and requests are counted in HTTPClientStats. Create requests with the inbound
+// request's context so they are cancelled with it.
+//
+// Example:
+//
+//	req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, "https://api.example.com/users", nil)
+//	resp, err := srv.HTTPClient().Do(req)
+func (srv *Server) HTTPClient() *http.Client {
+	return srv.outbound().client
+}
+
+// HTTPClientStats returns the counters of the shared outbound client.
+func (srv *Server) HTTPClientStats() HTTPClientStats {
+	s := &srv.outbound().stats
+	return HTTPClientStats{
+		Requests: s.requests.Load(),
+		Errors:   s.errors.Load(),
+		Retries:  s.retries.Load(),
+		InFlight: s.inFlight.Load(),
+	}
+}
+
+// outboundClient holds the pooled transport shared by the client and the gateway.
+type outboundClient struct {
+	client    *http.Client
+	transport http.RoundTripper // instrumented, without retries
+	stats     outboundStats
+}
+
+type outboundStats struct {
+	requests atomic.Uint64
+	errors   atomic.Uint64
+	retries  atomic.Uint64
+	inFlight atomic.Int64
+}
+
+func (srv *Server) outbound() *outboundClient {
+	srv.outboundOnce.Do(func() {
+		var cfg HTTPClientConfig
+		if srv.Options.HTTPClient != nil {
+			cfg = *srv.Options.HTTPClient
+		}
+		srv.outboundClient = newOutboundClient(cfg)
+	})
+	return srv.outboundClient
+}
+
+func newOutboundClient(cfg HTTPClientConfig) *outboundClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultOutboundTimeout
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = defaultOutboundDialTimeout
+	}
+	if cfg.TLSHandshakeTimeout <= 0 {
+		cfg.TLSHandshakeTimeout = defaultOutboundDialTimeout
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = defaultOutboundIdleConnTimeout
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = defaultOutboundMaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = defaultOutboundMaxIdlePerHost
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultOutboundRetryBackoff
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		// Validated by WithHTTPClient; options.json values are checked here
+		if proxyURL, err := url.Parse(cfg.Proxy); err == nil {
+			proxy = http.ProxyURL(proxyURL)
+		} else {
+			logger.Warn("Ignoring invalid http client proxy", "proxy", cfg.Proxy, "error", err)
+		}
+	}
+	base := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           (&net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSClientConfig:       cfg.TLSConfig,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		ForceAttemptHTTP2:     true,
+	}
+
+	oc := &outboundClient{}
+	oc.transport = &instrumentedTransport{next: base, stats: &oc.stats}
+	oc.client = &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &instrumentedTransport{
+			next:    base,
+			stats:   &oc.stats,
+			retries: cfg.Retries,
+			backoff: cfg.RetryBackoff,
+		},
+	}
+	return oc
+}
+
+// instrumentedTransport counts requests, forwards trace IDs, and retries idempotent requests.
+type instrumentedTransport struct {
+	next    http.RoundTripper
+	stats   *outboundStats
+	retries int
+	backoff time.Duration
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if traceID, ok := req.Context().Value(traceIDKey).(string); ok && traceID != "" && req.Header.Get("X-Trace-ID") == "" {
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Trace-ID", traceID)
+	}
+
+	for attempt := 0; ; attempt++ {
+		t.stats.requests.Add(1)
+		t.stats.inFlight.Add(1)
+		start := time.Now()
+		resp, err := t.next.RoundTrip(req)
+		t.stats.inFlight.Add(-1)
+		if err != nil {
+			t.stats.errors.Add(1)
+			logger.Debug("Outbound request failed", "method", req.Method, "host", req.URL.Host, "duration", time.Since(start), "error", err)
+		} else {
+			logger.Debug("Outbound request", "method", req.Method, "host", req.URL.Host, "status", resp.StatusCode, "duration", time.Since(start))
+		}
+
+		if attempt >= t.retries || !retryableOutbound(req, resp, err) {
+			return resp, err
+		}
+		retryReq, rewindErr := rewindRequest(req)
+		if rewindErr != nil {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if !sleepContext(req.Context(), outboundBackoff(t.backoff, attempt)) {
+			return nil, req.Context().Err()
+		}
+		t.stats.retries.Add(1)
+		req = retryReq
+	}
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the pool.
+func (t *instrumentedTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// retryableOutbound allows retries of requests that are safe to repeat: safe methods, or
+// any method carrying an Idempotency-Key header.
+func retryableOutbound(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// rewindRequest prepares req to be sent again, re-creating its body if it has one.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body cannot be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r2 := req.Clone(req.Context())
+	r2.Body = body
+	return r2, nil
+}
+
+// outboundBackoff doubles base per attempt, capped, with up to 50% jitter.
+func outboundBackoff(base time.Duration, attempt int) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > maxOutboundRetryBackoff {
+		d = maxOutboundRetryBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	var bodies []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, r.Header.Get("X-Trace-ID"))
+	}))
+	defer upstream.Close()
+
+	srv, err := NewServer(WithHTTPClient(HTTPClientConfig{Retries: 1, RetryBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	client := srv.HTTPClient()
+	if client != srv.HTTPClient() {
+		t.Fatal("expected the client to be shared")
+	}
+
+	ctx := context.WithValue(context.Background(), traceIDKey, "trace-1")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "trace-1" {
+		t.Errorf("expected retried GET with trace ID, got %d %q", resp.StatusCode, body)
+	}
+	if req.Header.Get("X-Trace-ID") != "" {
+		t.Error("caller's request must not be modified")
+	}
+
+	resp, err = client.Post(upstream.URL, "text/plain", strings.NewReader("charge"))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected POST without Idempotency-Key not to be retried, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, upstream.URL, strings.NewReader("charge"))
+	req.Header.Set("Idempotency-Key", "abc")
+	// Skip the odd call so the keyed request fails first
+	calls.Add(1)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("POST with key: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || bodies[len(bodies)-1] != "charge" || bodies[len(bodies)-2] != "charge" {
+		t.Errorf("expected keyed POST to be retried with its body, got %d %q", resp.StatusCode, bodies)
+	}
+
+	stats := srv.HTTPClientStats()
+	if stats.Requests != 5 || stats.Retries != 2 || stats.Errors != 0 || stats.InFlight != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestHTTPClientConfigValidation(t *testing.T) {
+	if _, err := NewServer(WithHTTPClient(HTTPClientConfig{Retries: -1})); err == nil {
+		t.Error("expected error for negative retries")
+	}
+	if _, err := NewServer(WithHTTPClient(HTTPClientConfig{Proxy: "http://[::1"})); err == nil {
+		t.Error("expected error for invalid proxy URL")
+	}
+}
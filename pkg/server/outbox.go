@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const outboxKey contextKey = "outbox"
+
+// ErrNoOutbox is returned by AfterCommit outside of OutboxMiddleware and
+// TransactionMiddleware, or after the request has ended.
+var ErrNoOutbox = errors.New("no outbox for request")
+
+// AfterCommit queues fn to run once the request has succeeded, so that webhooks and
+// event bus publishes are not sent for requests that fail. Inside TransactionMiddleware
+// fn runs after the commit and is dropped on rollback; inside OutboxMiddleware it runs
+// after the handler responded with a 2xx or 3xx status. The innermost of the two decides, and
+// hooks of a committed transaction still wait for an enclosing OutboxMiddleware.
+//
+// Hooks run in the order they were queued, in their own goroutine after the handler has
+// returned, with a context that is not cancelled with the request. Errors and panics are
+// logged and passed to the ErrorReporter. Hooks are not persisted; to survive crashes,
+// write the event to an outbox table in the transaction and use the hook to publish it.
+//
+// Example:
+//
+//	if err := server.AfterCommit(r.Context(), func(ctx context.Context) error {
+//		return bus.Publish(ctx, "order.created", order)
+//	}); err != nil {
+//		return err
+//	}
+func AfterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	box, ok := ctx.Value(outboxKey).(*outbox)
+	if !ok {
+		return ErrNoOutbox
+	}
+	return box.add(fn)
+}
+
+// OutboxMiddleware lets handlers queue AfterCommit hooks without a transaction. The hooks
+// run when the handler responds with a 2xx or 3xx status and are dropped otherwise,
+// including when it panics.
+//
+// Example:
+//
+//	srv.AddMiddleware("/api/orders", server.OutboxMiddleware())
+func OutboxMiddleware() MiddlewareFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			box := newOutbox(r)
+			lrw := &loggingResponseWriter{w, http.StatusOK, 0}
+			next.ServeHTTP(lrw, r.WithContext(context.WithValue(r.Context(), outboxKey, box)))
+			box.end(lrw.statusCode >= 200 && lrw.statusCode < 400)
+		}
+	}
+}
+
+// outbox collects the hooks of one request or transaction.
+type outbox struct {
+	mu     sync.Mutex
+	r      *http.Request
+	parent *outbox // Receives the hooks on success, nil for the outermost outbox
+	hooks  []func(ctx context.Context) error
+	ended  bool
+}
+
+func newOutbox(r *http.Request) *outbox {
+	parent, _ := r.Context().Value(outboxKey).(*outbox)
+	return &outbox{r: r, parent: parent}
+}
+
+func (b *outbox) add(fn ...func(ctx context.Context) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ended {
+		return ErrNoOutbox
+	}
+	b.hooks = append(b.hooks, fn...)
+	return nil
+}
+
+// end hands the hooks to the parent outbox or runs them if succeeded, and drops them
+// otherwise. Later AfterCommit calls fail with ErrNoOutbox.
+func (b *outbox) end(succeeded bool) {
+	b.mu.Lock()
+	hooks := b.hooks
+	b.hooks, b.ended = nil, true
+	b.mu.Unlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+	if !succeeded {
+		logger.Debug("Dropping after-commit hooks of failed request", "path", b.r.URL.Path, "hooks", len(hooks))
+		return
+	}
+	if b.parent != nil {
+		if err := b.parent.add(hooks...); err == nil {
+			return
+		}
+	}
+	go b.run(context.WithoutCancel(b.r.Context()), hooks)
+}
+
+func (b *outbox) run(ctx context.Context, hooks []func(ctx context.Context) error) {
+	for _, hook := range hooks {
+		if err := runHook(ctx, hook); err != nil {
+			logger.Error("After-commit hook failed", "path", b.r.URL.Path, "error", err)
+			ReportError(b.r, fmt.Errorf("after-commit hook: %w", err))
+		}
+	}
+}
+
+func runHook(ctx context.Context, hook func(ctx context.Context) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return hook(ctx)
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// queueEvent returns a handler that queues an event and responds with status.
+func queueEvent(t *testing.T, events chan<- string, name string, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := AfterCommit(r.Context(), func(ctx context.Context) error {
+			events <- name
+			return nil
+		}); err != nil {
+			t.Errorf("AfterCommit: %v", err)
+		}
+		w.WriteHeader(status)
+	}
+}
+
+func receiveEvents(events <-chan string) []string {
+	var got []string
+	for {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(100 * time.Millisecond):
+			return got
+		}
+	}
+}
+
+func TestOutboxMiddleware(t *testing.T) {
+	events := make(chan string, 10)
+	serve := func(h http.Handler) {
+		RecoveryMiddleware(OutboxMiddleware()(h)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	}
+
+	serve(queueEvent(t, events, "created", http.StatusCreated))
+	serve(queueEvent(t, events, "invalid", http.StatusBadRequest))
+	serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queueEvent(t, events, "panicked", http.StatusOK)(w, r)
+		panic("after queueing")
+	}))
+
+	if got := receiveEvents(events); !reflect.DeepEqual(got, []string{"created"}) {
+		t.Errorf("events = %v, want only the successful request's", got)
+	}
+}
+
+func TestAfterCommitWithTransactions(t *testing.T) {
+	events := make(chan string, 10)
+	serveTx(&fakeTx{}, nil, queueEvent(t, events, "committed", http.StatusOK))
+	serveTx(&fakeTx{}, nil, queueEvent(t, events, "rolled back", http.StatusConflict))
+	serveTx(&fakeTx{commitErr: errors.New("serialization failure")}, nil, queueEvent(t, events, "commit failed", http.StatusOK))
+
+	if got := receiveEvents(events); !reflect.DeepEqual(got, []string{"committed"}) {
+		t.Errorf("events = %v, want only the committed transaction's", got)
+	}
+
+	// Inside OutboxMiddleware the hooks of a committed transaction wait for the response
+	tx := BeginnerFunc(func(ctx context.Context) (Tx, error) { return &fakeTx{}, nil })
+	handler := OutboxMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		TransactionMiddleware(tx)(queueEvent(t, events, "first", http.StatusOK)).ServeHTTP(httptest.NewRecorder(), r)
+		if got := receiveEvents(events); len(got) != 0 {
+			t.Errorf("hooks ran before the response: %v", got)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	if got := receiveEvents(events); len(got) != 0 {
+		t.Errorf("events = %v, want none for the failed request", got)
+	}
+}
+
+func TestAfterCommitWithoutOutbox(t *testing.T) {
+	if err := AfterCommit(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrNoOutbox) {
+		t.Errorf("expected ErrNoOutbox, got %v", err)
+	}
+}
+
+func TestAfterCommitHookFailuresAreReported(t *testing.T) {
+	reported := make(chan error, 2)
+	reporter := func(ctx context.Context, err error, r *http.Request) { reported <- err }
+	handler := OutboxMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AfterCommit(r.Context(), func(ctx context.Context) error {
+			if ctx.Err() != nil {
+				t.Error("hook context was cancelled with the request")
+			}
+			panic("webhook exploded")
+		})
+		AfterCommit(r.Context(), func(ctx context.Context) error { return errors.New("bus unavailable") })
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+	serveWithErrorReporting(reporter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		cancel()
+	}), httptest.NewRecorder(), req)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-reported:
+			if err == nil {
+				t.Error("expected an error")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("hook failure was not reported")
+		}
+	}
+}
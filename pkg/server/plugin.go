@@ -0,0 +1,203 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+)
+
+// Plugin is a reusable pack of routes, middleware, and MCP tools and resources, such as
+// an auth provider or a metrics exporter. A plugin implements Name and Init and any of
+// RoutesPlugin, MiddlewarePlugin, MCPToolsPlugin, and MCPResourcesPlugin for the parts
+// it contributes; Server.Use registers them consistently.
+type Plugin interface {
+	Name() string
+	// Init is called once before the plugin's contributions are registered.
+	Init(srv *Server) error
+}
+
+// PluginRoute is a route contributed by a plugin.
+type PluginRoute struct {
+	Pattern string
+	Handler http.HandlerFunc
+}
+
+// RoutesPlugin is implemented by plugins that serve routes.
+type RoutesPlugin interface {
+	Plugin
+	Routes() []PluginRoute
+}
+
+// MiddlewarePlugin is implemented by plugins that add middleware. The map is keyed by
+// route; use GlobalMiddlewareRoute to apply middleware to all routes.
+type MiddlewarePlugin interface {
+	Plugin
+	Middleware() map[string]MiddlewareStack
+}
+
+// MCPToolsPlugin is implemented by plugins that provide MCP tools.
+type MCPToolsPlugin interface {
+	Plugin
+	MCPTools() []MCPTool
+}
+
+// MCPResourcesPlugin is implemented by plugins that provide MCP resources.
+type MCPResourcesPlugin interface {
+	Plugin
+	MCPResources() []MCPResource
+}
+
+// Use registers plugins in order. A plugin is rejected before any of its parts are
+// registered if its name is already in use, or if one of its routes, tool names, or
+// resource URIs is already registered, so that two packs cannot silently shadow each other.
+//
+// Example:
+//
+//	if err := srv.Use(oauth.Plugin(cfg), s3tools.Plugin(bucket)); err != nil {
+//		log.Fatal(err)
+//	}
+func (srv *Server) Use(plugins ...Plugin) error {
+	for _, p := range plugins {
+		if err := srv.usePlugin(p); err != nil {
+			return fmt.Errorf("plugin %s: %w", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Plugins returns the names of the registered plugins in registration order.
+func (srv *Server) Plugins() []string {
+	srv.pluginsMu.Lock()
+	defer srv.pluginsMu.Unlock()
+	return append([]string(nil), srv.plugins...)
+}
+
+func (srv *Server) usePlugin(p Plugin) error {
+	if srv.isRunning.Load() {
+		return fmt.Errorf("cannot add plugins after the server has started")
+	}
+	srv.pluginsMu.Lock()
+	defer srv.pluginsMu.Unlock()
+
+	name := p.Name()
+	if name == "" {
+		return fmt.Errorf("plugin name cannot be empty")
+	}
+	for _, existing := range srv.plugins {
+		if existing == name {
+			return fmt.Errorf("a plugin with this name is already registered")
+		}
+	}
+
+	var routes []PluginRoute
+	if rp, ok := p.(RoutesPlugin); ok {
+		routes = rp.Routes()
+	}
+	var tools []MCPTool
+	if tp, ok := p.(MCPToolsPlugin); ok {
+		tools = tp.MCPTools()
+	}
+	var resources []MCPResource
+	if rp, ok := p.(MCPResourcesPlugin); ok {
+		resources = rp.MCPResources()
+	}
+	if err := srv.checkPluginConflicts(routes, tools, resources); err != nil {
+		return err
+	}
+
+	if err := p.Init(srv); err != nil {
+		return fmt.Errorf("init failed: %w", err)
+	}
+
+	for _, route := range routes {
+		srv.HandleFunc(route.Pattern, route.Handler)
+	}
+	if mp, ok := p.(MiddlewarePlugin); ok {
+		stacks := mp.Middleware()
+		keys := make([]string, 0, len(stacks))
+		for route := range stacks {
+			keys = append(keys, route)
+		}
+		sort.Strings(keys)
+		for _, route := range keys {
+			srv.AddMiddlewareStack(route, stacks[route])
+		}
+	}
+	for _, tool := range tools {
+		srv.mcpHandler.RegisterTool(tool)
+	}
+	for _, resource := range resources {
+		srv.mcpHandler.RegisterResource(resource)
+	}
+
+	srv.plugins = append(srv.plugins, name)
+	logger.Info("Plugin registered",
+		"name", name,
+		"routes", len(routes),
+		"tools", len(tools),
+		"resources", len(resources),
+	)
+	return nil
+}
+
+// checkPluginConflicts reports the first route, tool, or resource that is already
+// registered or appears twice in the plugin.
+func (srv *Server) checkPluginConflicts(routes []PluginRoute, tools []MCPTool, resources []MCPResource) error {
+	if err := srv.checkPluginRoutes(routes); err != nil {
+		return err
+	}
+
+	if len(tools) == 0 && len(resources) == 0 {
+		return nil
+	}
+	if !srv.MCPEnabled() {
+		return fmt.Errorf("provides MCP tools or resources but MCP is not enabled on this server")
+	}
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		if _, exists := srv.mcpHandler.GetToolByName(tool.Name()); exists || seen[tool.Name()] {
+			return fmt.Errorf("MCP tool %q is already registered", tool.Name())
+		}
+		seen[tool.Name()] = true
+	}
+	clear(seen)
+	srv.mcpHandler.registryMu.RLock()
+	defer srv.mcpHandler.registryMu.RUnlock()
+	for _, resource := range resources {
+		if _, exists := srv.mcpHandler.resources[resource.URI()]; exists || seen[resource.URI()] {
+			return fmt.Errorf("MCP resource %q is already registered", resource.URI())
+		}
+		seen[resource.URI()] = true
+	}
+	return nil
+}
+
+// checkPluginRoutes registers the server's routes and then the plugin's into a scratch
+// mux. ServeMux panics on invalid patterns and on patterns that conflict, such as
+// "GET /items/{id}" and "GET /items/{name}"; the panic is returned as the error.
+func (srv *Server) checkPluginRoutes(routes []PluginRoute) (err error) {
+	var current string
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("route %q conflicts with a registered route: %v", current, r)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	srv.routesMu.RLock()
+	patterns := slices.Collect(maps.Keys(srv.registeredRoutes))
+	srv.routesMu.RUnlock()
+	for _, pattern := range patterns {
+		mux.Handle(pattern, http.NotFoundHandler())
+	}
+	for _, route := range routes {
+		if route.Pattern == "" || route.Handler == nil {
+			return fmt.Errorf("route %q needs a pattern and a handler", route.Pattern)
+		}
+		current = route.Pattern
+		mux.Handle(route.Pattern, http.NotFoundHandler())
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testPlugin struct {
+	name      string
+	routes    []PluginRoute
+	tools     []MCPTool
+	initCalls int
+}
+
+func (p *testPlugin) Name() string { return p.name }
+
+func (p *testPlugin) Init(srv *Server) error {
+	p.initCalls++
+	return nil
+}
+
+func (p *testPlugin) Routes() []PluginRoute { return p.routes }
+func (p *testPlugin) MCPTools() []MCPTool   { return p.tools }
+
+func (p *testPlugin) Middleware() map[string]MiddlewareStack {
+	return map[string]MiddlewareStack{
+		GlobalMiddlewareRoute: {func(next http.Handler) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Plugin", p.name)
+				next.ServeHTTP(w, r)
+			}
+		}},
+	}
+}
+
+func TestUsePlugin(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := &testPlugin{
+		name: "greeter",
+		routes: []PluginRoute{{Pattern: "GET /hello", Handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}}},
+		tools: []MCPTool{validationTool()},
+	}
+	if err := srv.Use(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if rec.Body.String() != "hello" || rec.Header().Get("X-Plugin") != "greeter" {
+		t.Errorf("unexpected response %q with headers %v", rec.Body.String(), rec.Header())
+	}
+	if _, ok := srv.mcpHandler.tools["deploy"]; !ok {
+		t.Error("plugin tool was not registered")
+	}
+	if plugin.initCalls != 1 {
+		t.Errorf("Init called %d times", plugin.initCalls)
+	}
+	if got := srv.Plugins(); !reflect.DeepEqual(got, []string{"greeter"}) {
+		t.Errorf("Plugins() = %v", got)
+	}
+}
+
+func TestUsePluginConflicts(t *testing.T) {
+	srv, err := NewServer(WithMCPSupport("test", "1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.HandleFunc("GET /taken", func(w http.ResponseWriter, r *http.Request) {})
+	srv.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	if err := srv.Use(&testPlugin{name: "first", tools: []MCPTool{validationTool()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		plugin *testPlugin
+		want   string
+	}{
+		{&testPlugin{name: "first"}, "already registered"},
+		{&testPlugin{name: "routes", routes: []PluginRoute{{Pattern: "GET /taken", Handler: noop}}}, `route "GET /taken"`},
+		{&testPlugin{name: "wildcards", routes: []PluginRoute{{Pattern: "GET /items/{name}", Handler: noop}}}, `route "GET /items/{name}" conflicts`},
+		{&testPlugin{name: "invalid", routes: []PluginRoute{{Pattern: "GET /{", Handler: noop}}}, `route "GET /{"`},
+		{&testPlugin{name: "dupes", routes: []PluginRoute{{Pattern: "/a", Handler: noop}, {Pattern: "/a", Handler: noop}}}, `route "/a"`},
+		{&testPlugin{name: "tools", tools: []MCPTool{validationTool()}}, `MCP tool "deploy"`},
+	}
+	for _, tt := range tests {
+		err := srv.Use(tt.plugin)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Use(%s) error = %v, want %q", tt.plugin.name, err, tt.want)
+		}
+		if tt.plugin.initCalls != 0 {
+			t.Errorf("Use(%s) initialized a conflicting plugin", tt.plugin.name)
+		}
+	}
+	if got := srv.Plugins(); !reflect.DeepEqual(got, []string{"first"}) {
+		t.Errorf("Plugins() = %v", got)
+	}
+}
+
+func TestUsePluginRequiresMCP(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Use(&testPlugin{name: "tools", tools: []MCPTool{validationTool()}}); err == nil {
+		t.Error("expected an error for MCP tools without MCP support")
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// pprofIndexPrefix is the path prefix net/http/pprof expects for named profiles.
+const pprofIndexPrefix = "/debug/pprof/"
+
+// WithPprof serves the standard net/http/pprof handlers under prefix, plus runtime
+// statistics at prefix+"/runtime" and expvar variables at prefix+"/vars", so latency
+// issues can be profiled in production without code changes. The path can also be set
+// via the "pprof_path" key in options.json or the HS_PPROF_PATH environment variable.
+//
+// Tokens accepted by AuthMiddleware are not enough, since profiles expose memory contents
+// and the command line: the endpoints require the operator token set with WithPprofToken,
+// or a request from a loopback address that was not forwarded by a proxy. CPU profiles
+// and traces are limited by the server's WriteTimeout.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithPprof("/debug/pprof"),
+//		server.WithPprofToken(os.Getenv("PPROF_TOKEN")),
+//	)
+//	// go tool pprof http://localhost:8080/debug/pprof/heap
+func WithPprof(prefix string) ServerOptionFunc {
+	return func(srv *Server) error {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("pprof path must start with '/': %q", prefix)
+		}
+		srv.Options.PprofPath = prefix
+		return nil
+	}
+}
+
+// WithPprofToken sets the bearer token that authorizes requests to the profiling
+// endpoints from other hosts, see WithPprof. It can also be set via the HS_PPROF_TOKEN
+// environment variable.
+func WithPprofToken(token string) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.PprofToken = token
+		return nil
+	}
+}
+
+// registerPprof wires the profiling handlers under Options.PprofPath.
+func (srv *Server) registerPprof() {
+	prefix := strings.TrimSuffix(srv.Options.PprofPath, "/")
+	guard := pprofGuard(srv.Options)
+
+	srv.Handle(prefix+"/", guard(func(w http.ResponseWriter, r *http.Request) {
+		// pprof.Index resolves named profiles relative to /debug/pprof/
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = pprofIndexPrefix + strings.TrimPrefix(r.URL.Path, prefix+"/")
+		pprof.Index(w, r2)
+	}))
+	srv.Handle(prefix+"/cmdline", guard(pprof.Cmdline))
+	srv.Handle(prefix+"/profile", guard(pprof.Profile))
+	srv.Handle(prefix+"/symbol", guard(pprof.Symbol))
+	srv.Handle(prefix+"/trace", guard(pprof.Trace))
+	srv.Handle(prefix+"/vars", guard(expvar.Handler().ServeHTTP))
+	srv.Handle(prefix+"/runtime", guard(srv.runtimeStatsHandler))
+	logger.Info("Profiling endpoints enabled", "path", prefix)
+}
+
+// pprofGuard restricts diagnostics to the operator token and local clients.
+func pprofGuard(options *ServerOptions) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return operatorGuard(options.PprofToken, "The profiling endpoint", next)
+	}
+}
+
+// runtimeStatsHandler reports memory, GC, and scheduler statistics as JSON.
+func (srv *Server) runtimeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	var lastPause time.Duration
+	if m.NumGC > 0 {
+		lastPause = time.Duration(m.PauseNs[(m.NumGC+255)%256])
+	}
+	stats := map[string]interface{}{
+		"go_version": runtime.Version(),
+		"goroutines": runtime.NumGoroutine(),
+		"num_cpu":    runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"memory": map[string]interface{}{
+			"heap_alloc":     m.HeapAlloc,
+			"heap_inuse":     m.HeapInuse,
+			"heap_objects":   m.HeapObjects,
+			"stack_inuse":    m.StackInuse,
+			"sys":            m.Sys,
+			"total_alloc":    m.TotalAlloc,
+			"mallocs":        m.Mallocs,
+			"frees":          m.Frees,
+			"next_gc":        m.NextGC,
+			"gc_cpu_percent": m.GCCPUFraction * 100,
+		},
+		"gc": map[string]interface{}{
+			"num_gc":      m.NumGC,
+			"pause_total": time.Duration(m.PauseTotalNs).String(),
+			"last_pause":  lastPause.String(),
+		},
+	}
+	if !srv.serverStart.IsZero() {
+		stats["uptime"] = time.Since(srv.serverStart).String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logger.Error("Failed to encode runtime stats", "error", err)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithPprofAccessControl(t *testing.T) {
+	srv, err := NewServer(
+		WithPprof("/internal/pprof/"),
+		WithPprofToken("ops"),
+		WithAuthTokenValidator(func(token string) (bool, error) { return token == "user", nil }),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	tests := []struct {
+		name      string
+		remote    string
+		token     string
+		forwarded bool
+		want      int
+	}{
+		{"loopback", "127.0.0.1:5000", "", false, http.StatusOK},
+		{"loopback ipv6", "[::1]:5000", "", false, http.StatusOK},
+		{"forwarded by a local proxy", "127.0.0.1:5000", "", true, http.StatusForbidden},
+		{"remote without auth", "203.0.113.7:5000", "", false, http.StatusForbidden},
+		{"remote with operator token", "203.0.113.7:5000", "ops", false, http.StatusOK},
+		{"remote with user token", "203.0.113.7:5000", "user", false, http.StatusForbidden},
+		{"remote with invalid token", "203.0.113.7:5000", "guess", false, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/internal/pprof/runtime", nil)
+			req.RemoteAddr = tt.remote
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.forwarded {
+				req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			}
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestPprofEndpoints(t *testing.T) {
+	srv, err := NewServer(WithPprof("/debug/pprof"))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "127.0.0.1:5000"
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/debug/pprof/"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("index: status %d", rec.Code)
+	}
+	if rec := get("/debug/pprof/goroutine?debug=1"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Errorf("goroutine profile: status %d", rec.Code)
+	}
+	if rec := get("/debug/pprof/vars"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "memstats") {
+		t.Errorf("vars: status %d", rec.Code)
+	}
+
+	rec := get("/debug/pprof/runtime")
+	var stats map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("runtime stats are not JSON: %v", err)
+	}
+	if stats["goroutines"] == nil || stats["memory"] == nil {
+		t.Errorf("runtime stats missing fields: %v", stats)
+	}
+}
+
+func TestPprofFromEnvironment(t *testing.T) {
+	t.Setenv(paramPprofPath, "/diag")
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/diag/runtime", nil)
+	req.RemoteAddr = "127.0.0.1:5000"
+	rec := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+}
+
+func TestWithPprofRejectsRelativePath(t *testing.T) {
+	if _, err := NewServer(WithPprof("debug")); err == nil {
+		t.Error("expected error for relative path")
+	}
+}
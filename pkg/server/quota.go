@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaPeriod is the window over which quota usage accumulates.
+type QuotaPeriod string
+
+const (
+	// QuotaDaily resets usage at midnight UTC.
+	QuotaDaily QuotaPeriod = "daily"
+	// QuotaMonthly resets usage on the first day of each month, UTC.
+	QuotaMonthly QuotaPeriod = "monthly"
+)
+
+// QuotaUnit is what a quota counts.
+type QuotaUnit string
+
+const (
+	// QuotaRequests counts requests.
+	QuotaRequests QuotaUnit = "requests"
+	// QuotaBytes counts response body bytes.
+	QuotaBytes QuotaUnit = "bytes"
+)
+
+// QuotaPolicy caps the usage of a key (see RateLimitKeyFunc) over a daily or monthly window.
+// Unlike rate limiting, which smooths traffic per second, quotas enforce usage caps such as
+// "10,000 requests per API key per month".
+type QuotaPolicy struct {
+	Name   string      `json:"name"`
+	Period QuotaPeriod `json:"period"`
+	Unit   QuotaUnit   `json:"unit"`
+	Limit  int64       `json:"limit"`
+	// ExhaustedStatus is the response status once the quota is used up:
+	// http.StatusTooManyRequests (default) or http.StatusPaymentRequired.
+	ExhaustedStatus int `json:"exhausted_status,omitempty"`
+	// KeyFunc selects the quota key; defaults to the server's rate limit key.
+	KeyFunc RateLimitKeyFunc `json:"-"`
+}
+
+func (p *QuotaPolicy) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("quota policy requires a name")
+	}
+	if strings.Contains(p.Name, "|") {
+		return fmt.Errorf("quota policy %q: name must not contain '|'", p.Name)
+	}
+	if p.Period != QuotaDaily && p.Period != QuotaMonthly {
+		return fmt.Errorf("quota policy %q: unknown period %q", p.Name, p.Period)
+	}
+	if p.Unit == "" {
+		p.Unit = QuotaRequests
+	}
+	if p.Unit != QuotaRequests && p.Unit != QuotaBytes {
+		return fmt.Errorf("quota policy %q: unknown unit %q", p.Name, p.Unit)
+	}
+	if p.Limit <= 0 {
+		return fmt.Errorf("quota policy %q: limit must be positive", p.Name)
+	}
+	switch p.ExhaustedStatus {
+	case 0:
+		p.ExhaustedStatus = http.StatusTooManyRequests
+	case http.StatusTooManyRequests, http.StatusPaymentRequired:
+	default:
+		return fmt.Errorf("quota policy %q: exhausted status must be 402 or 429, got %d", p.Name, p.ExhaustedStatus)
+	}
+	return nil
+}
+
+// window returns the start and end of the quota window containing now.
+func (p *QuotaPolicy) window(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	if p.Period == QuotaMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// storePrefix identifies the counters of the policy's current window in the store.
+func (p *QuotaPolicy) storePrefix(start time.Time) string {
+	return p.Name + "|" + start.Format("2006-01-02") + "|"
+}
+
+// QuotaStore persists quota counters. Implementations must be safe for concurrent use;
+// a shared store (e.g. Redis) lets several instances enforce one quota.
+type QuotaStore interface {
+	// Increment adds delta to the counter for key and returns the new total.
+	// The counter may be discarded after expiresAt.
+	Increment(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error)
+	// Usage returns all live counters whose key starts with prefix.
+	Usage(ctx context.Context, prefix string) (map[string]int64, error)
+}
+
+type memoryQuotaEntry struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// MemoryQuotaStore is an in-process QuotaStore. Counters are lost on restart.
+type MemoryQuotaStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryQuotaEntry
+	lastSweep time.Time
+}
+
+// NewMemoryQuotaStore creates an empty in-memory quota store.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{entries: make(map[string]*memoryQuotaEntry)}
+}
+
+// Increment implements QuotaStore.
+func (s *MemoryQuotaStore) Increment(_ context.Context, key string, delta int64, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+
+	entry, ok := s.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &memoryQuotaEntry{expiresAt: expiresAt}
+		s.entries[key] = entry
+	}
+	entry.value += delta
+	return entry.value, nil
+}
+
+// Usage implements QuotaStore.
+func (s *MemoryQuotaStore) Usage(_ context.Context, prefix string) (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	usage := make(map[string]int64)
+	for key, entry := range s.entries {
+		if strings.HasPrefix(key, prefix) && !now.After(entry.expiresAt) {
+			usage[key] = entry.value
+		}
+	}
+	return usage, nil
+}
+
+func (s *MemoryQuotaStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// quotaBinding records a policy and the route group it applies to.
+type quotaBinding struct {
+	route  string
+	policy QuotaPolicy
+}
+
+// WithQuotaStore sets the storage backend for quota counters.
+// Default: an in-memory store created by WithQuota.
+func WithQuotaStore(store QuotaStore) ServerOptionFunc {
+	return func(srv *Server) error {
+		if store == nil {
+			return fmt.Errorf("quota store must not be nil")
+		}
+		srv.Options.QuotaStore = store
+		return nil
+	}
+}
+
+// WithQuota enforces usage quotas on all routes starting with route. Each policy counts
+// independently; a request is rejected once any applicable quota is exhausted.
+// Current consumption is exposed through the quota://server/usage MCP resource. Counters
+// are stored by policy name, so names must be unique across all WithQuota calls.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithRateLimitKeyFunc(server.RateLimitByHeader("X-API-Key")),
+//		server.WithQuota("/api",
+//			server.QuotaPolicy{Name: "free-tier", Period: server.QuotaMonthly, Limit: 10000},
+//			server.QuotaPolicy{Name: "egress", Period: server.QuotaDaily, Unit: server.QuotaBytes,
+//				Limit: 1 << 30, ExhaustedStatus: http.StatusPaymentRequired},
+//		),
+//	)
+func WithQuota(route string, policies ...QuotaPolicy) ServerOptionFunc {
+	return func(srv *Server) error {
+		for _, p := range policies {
+			if i := slices.IndexFunc(srv.quotas, func(b quotaBinding) bool { return b.policy.Name == p.Name }); i >= 0 {
+				return fmt.Errorf("quota policy %q is already applied to %s", p.Name, srv.quotas[i].route)
+			}
+		}
+		mw, err := QuotaMiddleware(srv, policies...)
+		if err != nil {
+			return err
+		}
+		if srv.Options.QuotaStore == nil {
+			srv.Options.QuotaStore = NewMemoryQuotaStore()
+		}
+		for _, p := range policies {
+			_ = p.validate() // normalise defaults; already validated above
+			srv.quotas = append(srv.quotas, quotaBinding{route: route, policy: p})
+		}
+		srv.AddMiddleware(route, mw)
+		return nil
+	}
+}
+
+// QuotaMiddleware returns a middleware function that enforces the given quota policies using
+// the configured QuotaStore. Responses carry X-Quota-Limit, X-Quota-Remaining, and
+// X-Quota-Reset headers for the most constrained policy. Store errors fail open.
+// Returns an error if a policy is invalid.
+func QuotaMiddleware(srv *Server, policies ...QuotaPolicy) (MiddlewareFunc, error) {
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("at least one quota policy is required")
+	}
+	policies = append([]QuotaPolicy(nil), policies...)
+	for i := range policies {
+		if err := policies[i].validate(); err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(policies[:i], func(p QuotaPolicy) bool { return p.Name == policies[i].Name }) {
+			return nil, fmt.Errorf("quota policy %q: duplicate name", policies[i].Name)
+		}
+	}
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			store := srv.Options.QuotaStore
+			if store == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			now := time.Now()
+			remaining := int64(-1)
+			var byteKeys []quotaCounter
+			var charged []quotaCounter
+
+			for i := range policies {
+				p := &policies[i]
+				key := ""
+				if p.KeyFunc != nil {
+					key = p.KeyFunc(r)
+				}
+				if key == "" {
+					key = srv.rateLimitKey(r)
+				}
+				start, end := p.window(now)
+				counter := quotaCounter{key: p.storePrefix(start) + key, expiresAt: end}
+
+				delta := int64(0)
+				if p.Unit == QuotaRequests {
+					delta = 1
+				}
+				used, err := store.Increment(ctx, counter.key, delta, end)
+				if err != nil {
+					logger.Warn("Quota store unavailable, allowing request", "policy", p.Name, "error", err)
+					continue
+				}
+
+				exhausted := used >= p.Limit
+				if p.Unit == QuotaRequests {
+					exhausted = used > p.Limit
+				}
+				if exhausted {
+					if delta > 0 {
+						_, _ = store.Increment(ctx, counter.key, -delta, end)
+					}
+					for _, c := range charged {
+						_, _ = store.Increment(ctx, c.key, -1, c.expiresAt)
+					}
+					Annotate(r, "quota.exhausted", p.Name)
+					setQuotaHeaders(w, p.Limit, 0, end)
+					w.Header().Set("Retry-After", strconv.FormatInt(int64(time.Until(end).Seconds())+1, 10))
+					writeErrorResponse(w, p.ExhaustedStatus, fmt.Sprintf("Quota %q exhausted", p.Name))
+					return
+				}
+
+				if p.Unit == QuotaRequests {
+					charged = append(charged, counter)
+				} else {
+					byteKeys = append(byteKeys, counter)
+				}
+				if left := p.Limit - used; remaining < 0 || left < remaining {
+					remaining = left
+					setQuotaHeaders(w, p.Limit, left, end)
+				}
+			}
+
+			if len(byteKeys) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			lrw := &loggingResponseWriter{w, http.StatusOK, 0}
+			next.ServeHTTP(lrw, r)
+			for _, c := range byteKeys {
+				if _, err := store.Increment(ctx, c.key, int64(lrw.bytesWritten), c.expiresAt); err != nil {
+					logger.Warn("Failed to record quota usage", "error", err)
+				}
+			}
+		}
+	}, nil
+}
+
+// quotaCounter identifies a store counter charged for the current request.
+type quotaCounter struct {
+	key       string
+	expiresAt time.Time
+}
+
+func setQuotaHeaders(w http.ResponseWriter, limit, remaining int64, reset time.Time) {
+	w.Header().Set("X-Quota-Limit", strconv.FormatInt(limit, 10))
+	w.Header().Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+	w.Header().Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+// maskKey identifies a rate limit or quota key in reports without revealing it, as the
+// first 8 hex characters of its SHA-256.
+func maskKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// QuotaResource exposes current quota consumption as an MCP resource.
+type QuotaResource struct {
+	server *Server
+}
+
+// NewQuotaResource creates a new quota usage resource.
+func NewQuotaResource(srv *Server) *QuotaResource {
+	return &QuotaResource{server: srv}
+}
+
+// URI returns the resource URI.
+func (r *QuotaResource) URI() string {
+	return "quota://server/usage"
+}
+
+func (r *QuotaResource) CacheTTL() time.Duration {
+	return 0
+}
+
+// Name returns the resource name.
+func (r *QuotaResource) Name() string {
+	return "Quota Usage"
+}
+
+// Description returns the resource description.
+func (r *QuotaResource) Description() string {
+	return "Current consumption of daily and monthly usage quotas per key"
+}
+
+// MimeType returns the resource MIME type.
+func (r *QuotaResource) MimeType() string {
+	return "application/json"
+}
+
+// Read returns the usage of every quota policy in its current window. Keys may be API keys
+// or tokens, so usage is listed by their masked form, see maskKey.
+func (r *QuotaResource) Read() (interface{}, error) {
+	store := r.server.Options.QuotaStore
+	if store == nil {
+		return nil, fmt.Errorf("no quota store configured")
+	}
+
+	now := time.Now()
+	policies := make([]map[string]interface{}, 0, len(r.server.quotas))
+	for _, binding := range r.server.quotas {
+		p := binding.policy
+		start, end := p.window(now)
+		prefix := p.storePrefix(start)
+		counters, err := store.Usage(context.Background(), prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quota usage: %w", err)
+		}
+		usage := make(map[string]int64, len(counters))
+		for key, value := range counters {
+			usage[maskKey(strings.TrimPrefix(key, prefix))] += value
+		}
+		policies = append(policies, map[string]interface{}{
+			"name":         p.Name,
+			"route":        binding.route,
+			"period":       p.Period,
+			"unit":         p.Unit,
+			"limit":        p.Limit,
+			"window_start": start.Format(time.RFC3339),
+			"reset":        end.Format(time.RFC3339),
+			"usage":        usage,
+		})
+	}
+
+	return map[string]interface{}{
+		"policies":  policies,
+		"timestamp": now.Format(time.RFC3339),
+	}, nil
+}
+
+// List returns the available resource URIs.
+func (r *QuotaResource) List() ([]string, error) {
+	return []string{r.URI()}, nil
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func quotaRequest(handler http.Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+	req.Header.Set("X-API-Key", key)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestQuotaRequestsPerKey(t *testing.T) {
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithRateLimitKeyFunc(RateLimitByHeader("X-API-Key")),
+		WithQuota("/api", QuotaPolicy{Name: "daily", Period: QuotaDaily, Limit: 2}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.Handler()
+
+	for i := 0; i < 2; i++ {
+		if rec := quotaRequest(handler, "alice"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := quotaRequest(handler, "alice")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once quota is exhausted, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Quota-Remaining") != "0" || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected quota headers on rejection, got %v", rec.Header())
+	}
+	if rec := quotaRequest(handler, "bob"); rec.Code != http.StatusOK {
+		t.Errorf("expected separate quota for bob, got %d", rec.Code)
+	}
+
+	// Rejected requests must not consume quota
+	usage, _ := srv.Options.QuotaStore.Usage(context.Background(), "daily|")
+	for key, used := range usage {
+		if strings.HasSuffix(key, "|alice") && used != 2 {
+			t.Errorf("expected alice usage of 2, got %d", used)
+		}
+	}
+}
+
+func TestQuotaBytesWithPaymentRequired(t *testing.T) {
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithRateLimitKeyFunc(RateLimitByHeader("X-API-Key")),
+		WithQuota("/api", QuotaPolicy{
+			Name: "egress", Period: QuotaMonthly, Unit: QuotaBytes, Limit: 10,
+			ExhaustedStatus: http.StatusPaymentRequired,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("123456"))
+	})
+	handler := srv.Handler()
+
+	for i := 0; i < 2; i++ {
+		if rec := quotaRequest(handler, "alice"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	if rec := quotaRequest(handler, "alice"); rec.Code != http.StatusPaymentRequired {
+		t.Errorf("expected 402 after 12 of 10 bytes, got %d", rec.Code)
+	}
+}
+
+func TestQuotaPolicyWindows(t *testing.T) {
+	now := time.Date(2025, time.March, 15, 13, 45, 0, 0, time.UTC)
+
+	daily := QuotaPolicy{Period: QuotaDaily}
+	start, end := daily.window(now)
+	if !start.Equal(time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)) || end.Sub(start) != 24*time.Hour {
+		t.Errorf("unexpected daily window %v - %v", start, end)
+	}
+
+	monthly := QuotaPolicy{Period: QuotaMonthly}
+	start, end = monthly.window(now)
+	if !start.Equal(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)) ||
+		!end.Equal(time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected monthly window %v - %v", start, end)
+	}
+}
+
+func TestQuotaPolicyValidation(t *testing.T) {
+	invalid := []QuotaPolicy{
+		{Period: QuotaDaily, Limit: 1},
+		{Name: "x", Period: "weekly", Limit: 1},
+		{Name: "x", Period: QuotaDaily},
+		{Name: "x", Period: QuotaDaily, Limit: 1, Unit: "tokens"},
+		{Name: "x", Period: QuotaDaily, Limit: 1, ExhaustedStatus: http.StatusForbidden},
+	}
+	for _, p := range invalid {
+		if _, err := NewServer(WithQuota("/api", p)); err == nil {
+			t.Errorf("expected validation error for %+v", p)
+		}
+	}
+
+	// Policies of the same name would share counters
+	daily := QuotaPolicy{Name: "daily", Period: QuotaDaily, Limit: 1}
+	if _, err := NewServer(WithQuota("/api", daily, daily)); err == nil {
+		t.Error("expected an error for duplicate policy names")
+	}
+	if _, err := NewServer(WithQuota("/api", daily), WithQuota("/admin", daily)); err == nil {
+		t.Error("expected an error for a policy name used by an earlier WithQuota")
+	}
+}
+
+func TestQuotaResource(t *testing.T) {
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithRateLimitKeyFunc(RateLimitByHeader("X-API-Key")),
+		WithQuota("/api", QuotaPolicy{Name: "daily", Period: QuotaDaily, Limit: 100}),
+		WithMCPSupport("test", "1.0.0", MCPObservability()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {})
+	quotaRequest(srv.Handler(), "alice")
+
+	resource := NewQuotaResource(srv)
+	registered := false
+	for _, r := range srv.mcpHandler.resources {
+		if r.URI() == resource.URI() {
+			registered = true
+		}
+	}
+	if !registered {
+		t.Fatal("expected quota resource to be registered")
+	}
+
+	data, err := resource.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	policies := data.(map[string]interface{})["policies"].([]map[string]interface{})
+	if len(policies) != 1 {
+		t.Fatalf("expected 1 policy, got %d", len(policies))
+	}
+	usage := policies[0]["usage"].(map[string]int64)
+	if usage[maskKey("X-Api-Key:alice")] != 1 {
+		t.Errorf("expected alice usage of 1, got %v", usage)
+	}
+	if _, ok := usage["alice"]; ok || len(maskKey("X-Api-Key:alice")) != 8 {
+		t.Errorf("expected the key to be masked, got %v", usage)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// RateLimitKeyFunc extracts the bucket key used by RateLimitMiddleware for a request.
+// Returning an empty string falls back to the client IP address so that
+// unauthenticated traffic is still limited.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitOverride defines an individual limit for a specific rate-limit key,
+// allowing authenticated tenants to receive quotas that differ from the server default.
+type RateLimitOverride struct {
+	Limit RateLimit `json:"limit"`
+	Burst int       `json:"burst"`
+}
+
+// RateLimitByIP keys rate limiting on the client IP address (the default behaviour).
+func RateLimitByIP(r *http.Request) string {
+	return clientIP(r)
+}
+
+// RateLimitByToken keys rate limiting on the bearer token validated by AuthMiddleware, so
+// place RateLimitMiddleware after it, e.g. in PhaseRateLimit. Keys are prefixed as in
+// "Bearer:abc"; overrides use the same form. Tokens that were not validated, such as
+// every token when the rate limit runs before AuthMiddleware, fall back to the client IP
+// address, since clients could otherwise get a fresh bucket by sending a new token with
+// each request. So do tokens longer than 256 bytes, which includes most JWTs; key those
+// on their subject with RateLimitByContextValue instead.
+func RateLimitByToken(r *http.Request) string {
+	token, ok := r.Context().Value(sessionIDKey).(string)
+	if !ok || !validRateLimitKey(token) {
+		return ""
+	}
+	return "Bearer:" + token
+}
+
+// RateLimitBySession keys rate limiting on the session ID stored by AuthMiddleware.
+// Place RateLimitMiddleware after AuthMiddleware for the session to be available.
+func RateLimitBySession(r *http.Request) string {
+	if session, ok := r.Context().Value(sessionIDKey).(string); ok {
+		return session
+	}
+	return ""
+}
+
+// rateLimitHeaderMaxLen caps the header values and tokens used as rate limit keys, which
+// clients choose freely.
+const rateLimitHeaderMaxLen = 256
+
+// RateLimitByHeader returns a RateLimitKeyFunc that keys rate limiting on the given
+// request header, e.g. "X-API-Key". Keys are prefixed with the canonical header name, as
+// in "X-Api-Key:abc", so that a header value cannot share the bucket of a client IP
+// address; overrides use the same form. Requests without the header, or with a value
+// longer than 256 bytes or containing control characters, fall back to the client IP
+// address.
+//
+// The header is not validated, so a client that sends a new value with each request gets
+// a new bucket each time and is not limited at all. Use it only for headers that a
+// trusted proxy validates or sets, or prefer RateLimitByToken or RateLimitByContextValue
+// with an identity established by authentication.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	prefix := http.CanonicalHeaderKey(name) + ":"
+	return func(r *http.Request) string {
+		value := strings.TrimSpace(r.Header.Get(name))
+		if !validRateLimitKey(value) {
+			return clientIP(r)
+		}
+		return prefix + value
+	}
+}
+
+// validRateLimitKey reports whether a client-supplied value can serve as a rate limit key.
+func validRateLimitKey(value string) bool {
+	return value != "" && len(value) <= rateLimitHeaderMaxLen && !strings.ContainsFunc(value, unicode.IsControl)
+}
+
+// RateLimitByContextValue returns a RateLimitKeyFunc that keys rate limiting on a value
+// stored in the request context, such as a user ID placed there by custom auth middleware.
+// Non-string values are formatted with fmt.Sprint.
+func RateLimitByContextValue(key any) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		value := r.Context().Value(key)
+		if value == nil {
+			return ""
+		}
+		if s, ok := value.(string); ok {
+			return s
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+// WithRateLimitKeyFunc configures how RateLimitMiddleware derives the bucket key for a request.
+// By default requests are limited per client IP address.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithRateLimitKeyFunc(server.RateLimitByHeader("X-API-Key")),
+//	)
+func WithRateLimitKeyFunc(fn RateLimitKeyFunc) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.RateLimitKeyFunc = fn
+		return nil
+	}
+}
+
+// WithRateLimitOverride sets an individual limit and burst for a specific rate-limit key.
+// The key must match the value returned by the configured RateLimitKeyFunc.
+func WithRateLimitOverride(key string, limit RateLimit, burst int) ServerOptionFunc {
+	return func(srv *Server) error {
+		if key == "" {
+			return fmt.Errorf("rate limit override requires a non-empty key")
+		}
+		if burst < 0 {
+			return fmt.Errorf("rate limit override burst must not be negative: %d", burst)
+		}
+		if srv.Options.RateLimitOverrides == nil {
+			srv.Options.RateLimitOverrides = make(map[string]RateLimitOverride)
+		}
+		srv.Options.RateLimitOverrides[key] = RateLimitOverride{Limit: limit, Burst: burst}
+		return nil
+	}
+}
+
+// rateLimitKey resolves the bucket key for a request using the configured extractor.
+func (srv *Server) rateLimitKey(r *http.Request) string {
+	if fn := srv.Options.RateLimitKeyFunc; fn != nil {
+		if key := fn(r); key != "" {
+			return key
+		}
+	}
+	return clientIP(r)
+}
+
+// rateLimitFor returns the limit and burst that apply to the given key.
+func (srv *Server) rateLimitFor(key string) (RateLimit, int) {
+	options := srv.Options.current()
+	if override, ok := options.RateLimitOverrides[key]; ok {
+		return override.Limit, override.Burst
+	}
+	return options.RateLimit, options.Burst
+}
+
+// clientIP returns the host portion of the request's remote address.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
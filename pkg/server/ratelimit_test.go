@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitByTokenSeparatesBucketsBehindSharedIP(t *testing.T) {
+	t.Parallel()
+	srv, err := NewServer(
+		WithRateLimitKeyFunc(RateLimitByToken),
+		WithAuthTokenValidator(func(token string) (bool, error) { return strings.HasPrefix(token, "tenant-"), nil }),
+	)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	srv.Options.RateLimit = rate.Every(time.Minute)
+	srv.Options.Burst = 1
+
+	handler := RateLimitMiddleware(srv)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	authenticated := AuthMiddleware(srv.Options)(handler)
+
+	send := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		if token != "" {
+			authenticated.ServeHTTP(rec, req)
+		} else {
+			handler.ServeHTTP(rec, req)
+		}
+		return rec.Code
+	}
+
+	if code := send("tenant-a"); code != http.StatusOK {
+		t.Fatalf("expected first tenant-a request to pass, got %d", code)
+	}
+	if code := send("tenant-b"); code != http.StatusOK {
+		t.Fatalf("expected tenant-b to have its own bucket, got %d", code)
+	}
+	if code := send("tenant-a"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected tenant-a to be limited, got %d", code)
+	}
+	// Requests without a token fall back to the IP bucket.
+	if code := send(""); code != http.StatusOK {
+		t.Fatalf("expected anonymous request to use IP bucket, got %d", code)
+	}
+
+	// Tokens the rate limit sees before they are validated share the IP bucket, so
+	// rotating tokens does not escape the limit
+	for _, token := range []string{"random-1", "random-2"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected unvalidated token %s to use the exhausted IP bucket, got %d", token, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitOverrideAppliesPerKey(t *testing.T) {
+	t.Parallel()
+	srv, err := NewServer(
+		WithRateLimitKeyFunc(RateLimitByHeader("X-API-Key")),
+		WithRateLimitOverride("X-Api-Key:premium", rate.Every(time.Minute), 3),
+	)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	srv.Options.RateLimit = rate.Every(time.Minute)
+	srv.Options.Burst = 1
+
+	handler := RateLimitMiddleware(srv)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	allowed := func(key string) int {
+		passed := 0
+		for i := 0; i < 5; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", key)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code == http.StatusOK {
+				passed++
+			}
+		}
+		return passed
+	}
+
+	if got := allowed("premium"); got != 3 {
+		t.Errorf("expected premium key to get burst of 3, got %d", got)
+	}
+	if got := allowed("basic"); got != 1 {
+		t.Errorf("expected basic key to get default burst of 1, got %d", got)
+	}
+}
+
+func TestRateLimitByHeader(t *testing.T) {
+	t.Parallel()
+	keyFunc := RateLimitByHeader("x-api-key")
+	for value, want := range map[string]string{
+		"abc":                    "X-Api-Key:abc",
+		"":                       "192.0.2.1",
+		"192.0.2.7":              "X-Api-Key:192.0.2.7",
+		strings.Repeat("a", 257): "192.0.2.1",
+		"a\x00b":                 "192.0.2.1",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", value)
+		if got := keyFunc(req); got != want {
+			t.Errorf("key for %q = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestRateLimitByContextValue(t *testing.T) {
+	t.Parallel()
+	type userKey struct{}
+	keyFunc := RateLimitByContextValue(userKey{})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := keyFunc(req); got != "" {
+		t.Errorf("expected empty key without context value, got %q", got)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), userKey{}, 42))
+	if got := keyFunc(req); got != "42" {
+		t.Errorf("expected formatted user ID, got %q", got)
+	}
+}
+
+func TestWithRateLimitOverrideRejectsEmptyKey(t *testing.T) {
+	t.Parallel()
+	if _, err := NewServer(WithRateLimitOverride("", 1, 1)); err == nil {
+		t.Fatal("expected error for empty override key")
+	}
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// redactedValue replaces the values of sensitive headers and fields.
+	redactedValue = "[redacted]"
+
+	redactorKey contextKey = "redactor"
+)
+
+// Built-in patterns that are always redacted. Patterns match names case-insensitively
+// anywhere in the name, so "token" covers "X-Auth-Token" and "refresh_token".
+var (
+	defaultRedactedHeaders = []string{
+		"authorization", "cookie", "api-key", "apikey", "token", "secret", "password", "signature",
+	}
+	defaultRedactedFields = []string{
+		"password", "passwd", "secret", "token", "authorization", "api_key", "apikey",
+		"cookie", "credential", "private_key",
+	}
+)
+
+// RedactionConfig adds patterns to the built-in ones that are scrubbed from access logs,
+// the MCP audit log and trace, requests captured by the request debugger, and the
+// config://server/current resource. Patterns match names case-insensitively anywhere in
+// the name; the built-in patterns cannot be removed.
+type RedactionConfig struct {
+	Headers []string `json:"headers,omitempty"` // Request and response header names, e.g. "X-Tenant-Key"
+	Fields  []string `json:"fields,omitempty"`  // Keys of JSON and form bodies, query parameters, and MCP parameters
+}
+
+// WithRedaction scrubs additional headers and fields from logs and captured requests, on
+// top of credentials such as Authorization, cookies, tokens, and passwords, which are
+// always redacted. It can also be set via "redaction" in options.json, or
+// HS_REDACT_HEADERS and HS_REDACT_FIELDS.
+//
+// Example:
+//
+//	server.WithRedaction(server.RedactionConfig{
+//		Headers: []string{"X-Tenant-Key"},
+//		Fields:  []string{"ssn", "card_number"},
+//	})
+func WithRedaction(cfg RedactionConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.Redaction = &cfg
+		return nil
+	}
+}
+
+// redactor scrubs sensitive values. A nil redactor uses the built-in patterns only.
+type redactor struct {
+	headers []string
+	fields  []string
+}
+
+var defaultRedactor = newRedactor(nil)
+
+func newRedactor(cfg *RedactionConfig) *redactor {
+	rd := &redactor{headers: defaultRedactedHeaders, fields: defaultRedactedFields}
+	if cfg != nil {
+		for _, p := range sanitizeTokens(cfg.Headers, false) {
+			rd.headers = append(rd.headers[:len(rd.headers):len(rd.headers)], strings.ToLower(p))
+		}
+		for _, p := range sanitizeTokens(cfg.Fields, false) {
+			rd.fields = append(rd.fields[:len(rd.fields):len(rd.fields)], strings.ToLower(p))
+		}
+	}
+	return rd
+}
+
+// withRedactor attaches the server's redactor to the request context so that
+// RequestLoggerMiddleware can use it.
+func withRedactor(r *http.Request, rd *redactor) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), redactorKey, rd))
+}
+
+// requestRedactor returns the redactor attached to r, or nil for the built-in patterns.
+func requestRedactor(r *http.Request) *redactor {
+	rd, _ := r.Context().Value(redactorKey).(*redactor)
+	return rd
+}
+
+func matchesRedactionPattern(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if strings.Contains(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rd *redactor) sensitiveHeader(name string) bool {
+	if rd == nil {
+		rd = defaultRedactor
+	}
+	return matchesRedactionPattern(name, rd.headers)
+}
+
+func (rd *redactor) sensitiveField(name string) bool {
+	if rd == nil {
+		rd = defaultRedactor
+	}
+	return matchesRedactionPattern(name, rd.fields)
+}
+
+// header returns a copy of h with the values of sensitive headers replaced.
+func (rd *redactor) header(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if rd.sensitiveHeader(k) {
+			out[k] = []string{redactedValue}
+		} else {
+			out[k] = append([]string(nil), v...)
+		}
+	}
+	return out
+}
+
+// query replaces the values of sensitive parameters in a raw query string, keeping the
+// order and encoding of the others.
+func (rd *redactor) query(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		key, _, hasValue := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err == nil && hasValue && rd.sensitiveField(name) {
+			params[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// url replaces sensitive query parameters and the password of a URL or request URI.
+func (rd *redactor) url(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.User != nil {
+		raw = u.Redacted()
+	}
+	base, query, ok := strings.Cut(raw, "?")
+	if !ok {
+		return raw
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+	raw = base + "?" + rd.query(query)
+	if hasFragment {
+		raw += "#" + fragment
+	}
+	return raw
+}
+
+// body replaces sensitive fields of JSON and form bodies. Other bodies, and bodies without
+// sensitive fields, are returned unchanged.
+func (rd *redactor) body(contentType, body string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return rd.query(body)
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "":
+		var generic interface{}
+		if json.Unmarshal([]byte(body), &generic) != nil {
+			return body
+		}
+		if redacted := rd.generic(generic); containsRedacted(redacted) {
+			if data, err := json.Marshal(redacted); err == nil {
+				return string(data)
+			}
+		}
+	}
+	return body
+}
+
+// value returns a copy of v with the values of sensitive keys replaced. Values are
+// normalised through JSON so that structs are redacted as well.
+func (rd *redactor) value(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return redactedValue
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return redactedValue
+	}
+	return rd.generic(generic)
+}
+
+func (rd *redactor) generic(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range val {
+			if rd.sensitiveField(k) {
+				val[k] = redactedValue
+			} else {
+				val[k] = rd.generic(inner)
+			}
+		}
+	case []interface{}:
+		for i, inner := range val {
+			val[i] = rd.generic(inner)
+		}
+	case string:
+		// Tool results carry JSON documents as text content
+		if trimmed := strings.TrimSpace(val); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var embedded interface{}
+			if json.Unmarshal([]byte(trimmed), &embedded) == nil {
+				if data, err := json.Marshal(rd.generic(embedded)); err == nil {
+					return string(data)
+				}
+			}
+		}
+	}
+	return v
+}
+
+// fieldValues returns a copy of m with the values of sensitive keys replaced, leaving the
+// other values as they are.
+func (rd *redactor) fieldValues(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if rd.sensitiveField(k) {
+			v = redactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRotatingFileMaxSize    = 100 << 20 // 100 MiB
+	defaultRotatingFileMaxBackups = 5
+	rotatingFileRetryDelay        = time.Minute // Between attempts after a failed rotation
+)
+
+// RotatingFileConfig configures a RotatingFile.
+type RotatingFileConfig struct {
+	Path       string        `json:"path"`
+	MaxSize    int64         `json:"max_size,omitempty"`    // Bytes before rotation (default 100 MiB)
+	Interval   time.Duration `json:"interval,omitempty"`    // Also rotate when the file is older than this, 0 disables
+	MaxBackups int           `json:"max_backups,omitempty"` // Rotated files to keep (default 5)
+	Compress   bool          `json:"compress,omitempty"`    // Gzip rotated files in the background
+}
+
+// RotatingFile is an io.WriteCloser that rotates its file by size and age, so servers
+// without an external log shipper do not fill their disks. When the file is rotated it is
+// renamed to Path+".1" (Path+".1.gz" when compressed), older backups are shifted up, and
+// backups beyond MaxBackups are deleted. It is safe for concurrent use.
+//
+// Example:
+//
+//	logFile, _ := server.NewRotatingFile(server.RotatingFileConfig{
+//		Path: "logs/app.log", MaxSize: 50 << 20, Interval: 24 * time.Hour, Compress: true,
+//	})
+//	server.SetDefaultLogger(slog.New(slog.NewJSONHandler(logFile, nil)))
+type RotatingFile struct {
+	config RotatingFileConfig
+
+	mu          sync.Mutex
+	file        *os.File // nil if closed, or if a rotation could not reopen it
+	closed      bool
+	size        int64
+	openedAt    time.Time
+	rotateAfter time.Time // Rotation is not retried before this after a failure
+
+	compressing sync.WaitGroup
+}
+
+// NewRotatingFile opens or creates the file at cfg.Path for appending.
+func NewRotatingFile(cfg RotatingFileConfig) (*RotatingFile, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("rotating file requires a path")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultRotatingFileMaxSize
+	}
+	if cfg.MaxBackups <= 0 {
+		cfg.MaxBackups = defaultRotatingFileMaxBackups
+	}
+	f := &RotatingFile{config: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.config.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %w", f.config.Path, err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// Write appends p to the file, rotating first if p would exceed MaxSize or the file is
+// older than Interval. A single write is never split across files. If the rotation fails,
+// the error is logged and p is appended to the current file, and rotation is retried
+// after a minute.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.size > 0 && time.Now().After(f.rotateAfter) && (f.size+int64(len(p)) > f.config.MaxSize ||
+		(f.config.Interval > 0 && time.Since(f.openedAt) >= f.config.Interval)) {
+		if err := f.rotate(); err != nil {
+			logger.Error("Failed to rotate file", "file", f.config.Path, "error", err)
+			f.rotateAfter = time.Now().Add(rotatingFileRetryDelay)
+			if f.file == nil {
+				return 0, fmt.Errorf("failed to rotate %s: %w", f.config.Path, err)
+			}
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately, e.g. on SIGHUP.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file == nil {
+		return f.open()
+	}
+	return f.rotate()
+}
+
+// rotate shifts Path -> Path.1 -> Path.2 ... and reopens Path. If Path cannot be renamed,
+// it is reopened and kept. Callers hold f.mu.
+func (f *RotatingFile) rotate() error {
+	closeErr := f.file.Close()
+	// Backups must not move while they are being compressed
+	f.compressing.Wait()
+
+	path := f.config.Path
+	os.Remove(f.backupName(f.config.MaxBackups))
+	os.Remove(f.backupName(f.config.MaxBackups) + ".gz")
+	for i := f.config.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(f.backupName(i), f.backupName(i+1))
+		os.Rename(f.backupName(i)+".gz", f.backupName(i+1)+".gz")
+	}
+	renameErr := os.Rename(path, f.backupName(1))
+	if err := f.open(); err != nil {
+		f.file = nil
+		return err
+	}
+	if renameErr != nil {
+		// Keep appending to the current file rather than losing records
+		return errors.Join(closeErr, renameErr)
+	}
+
+	if f.config.Compress {
+		f.compressing.Add(1)
+		go func(name string) {
+			defer f.compressing.Done()
+			if err := compressFile(name); err != nil {
+				logger.Error("Failed to compress rotated file", "file", name, "error", err)
+			}
+		}(f.backupName(1))
+	}
+	return closeErr
+}
+
+func (f *RotatingFile) backupName(n int) string {
+	return fmt.Sprintf("%s.%d", f.config.Path, n)
+}
+
+// Close closes the file and waits for background compression to finish.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	f.compressing.Wait()
+	return err
+}
+
+// compressFile gzips name to name+".gz" and removes the original.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, name+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(name)
+}
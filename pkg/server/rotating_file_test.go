@@ -0,0 +1,141 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSizeRotationAndCompression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: 20, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+
+	for _, line := range []string{"first line 0001\n", "second line 002\n", "third line 0003\n", "fourth line 004\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	current, _ := os.ReadFile(path)
+	if string(current) != "fourth line 004\n" {
+		t.Errorf("unexpected current file %q", current)
+	}
+	for name, want := range map[string]string{path + ".1.gz": "third line 0003\n", path + ".2.gz": "second line 002\n"} {
+		if got := readGzipFile(t, name); got != want {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(name), want, got)
+		}
+	}
+	for _, name := range []string{path + ".1", path + ".3", path + ".3.gz"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to exist", filepath.Base(name))
+		}
+	}
+
+	if _, err := f.Write([]byte("late")); err != os.ErrClosed {
+		t.Errorf("expected os.ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestRotatingFileIntervalAndManualRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := NewRotatingFile(RotatingFileConfig{Path: path, Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("old\n"))
+	f.mu.Lock()
+	f.openedAt = time.Now().Add(-2 * time.Hour)
+	f.mu.Unlock()
+	f.Write([]byte("new\n"))
+
+	if backup, _ := os.ReadFile(path + ".1"); string(backup) != "old\n" {
+		t.Errorf("expected interval rotation, backup is %q", backup)
+	}
+
+	if err := f.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if backup, _ := os.ReadFile(path + ".1"); string(backup) != "new\n" {
+		t.Errorf("expected manual rotation, backup is %q", backup)
+	}
+	if current, _ := os.ReadFile(path); len(current) != 0 {
+		t.Errorf("expected empty current file, got %q", current)
+	}
+}
+
+func TestRotatingFileKeepsWritingWhenRotationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// A non-empty directory in place of the backup cannot be removed or replaced
+	if err := os.MkdirAll(filepath.Join(path+".1", "keep"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: 10, MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first 0001\n", "second 002\n", "third 0003\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if current, _ := os.ReadFile(path); string(current) != "first 0001\nsecond 002\nthird 0003\n" {
+		t.Errorf("expected writes to be kept in the current file, got %q", current)
+	}
+}
+
+func TestAccessLogRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	srv, err := NewServer(WithAccessLog(AccessLogConfig{
+		Format: AccessLogCommon,
+		File:   &RotatingFileConfig{Path: path},
+	}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hi"))
+	})
+	srv.middleware.applyToMux(srv.mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if err := srv.middleware.accessLog.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"GET /hello HTTP/1.1" 200 2`) {
+		t.Errorf("expected common log line in file, got %q", data)
+	}
+}
+
+func readGzipFile(t *testing.T, name string) string {
+	t.Helper()
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip %s: %v", name, err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
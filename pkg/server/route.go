@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+const routePatternKey contextKey = "routePattern"
+
+// withRoutePattern resolves the mux pattern that will serve the request and records it
+// in the request context, so middleware running before the mux can key on the route.
+func withRoutePattern(r *http.Request, mux *http.ServeMux) *http.Request {
+	if _, ok := r.Context().Value(routePatternKey).(string); ok {
+		return r
+	}
+	_, pattern := mux.Handler(r)
+	return r.WithContext(context.WithValue(r.Context(), routePatternKey, pattern))
+}
+
+// RoutePattern returns the registered pattern that matches the request, such as
+// "GET /users/{id}", rather than the raw URL path. Unlike r.Pattern, which the mux
+// only sets once routing happens, RoutePattern is available to every middleware.
+// Returns an empty string if no registered route matches.
+//
+// Example:
+//
+//	func auditMiddleware(next http.Handler) http.HandlerFunc {
+//		return func(w http.ResponseWriter, r *http.Request) {
+//			log.Println("route", server.RoutePattern(r))
+//			next.ServeHTTP(w, r)
+//		}
+//	}
+func RoutePattern(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	pattern, _ := r.Context().Value(routePatternKey).(string)
+	return pattern
+}
+
+// RateLimitByRoute keys rate limiting on the client IP address and the matched route
+// pattern, giving every client an independent bucket per route.
+func RateLimitByRoute(r *http.Request) string {
+	pattern := RoutePattern(r)
+	if pattern == "" {
+		return ""
+	}
+	return clientIP(r) + " " + pattern
+}
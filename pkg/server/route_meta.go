@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RouteMeta declares whether requests to a route may be repeated and whether its responses
+// may be reused. Features that retry, hedge, coalesce, or cache requests consult it instead
+// of keeping their own configuration.
+type RouteMeta struct {
+	Idempotent bool          `json:"idempotent,omitempty"` // Sending the request twice has the same effect as once
+	Cacheable  bool          `json:"cacheable,omitempty"`  // Responses may be stored and shared between clients
+	MaxAge     time.Duration `json:"max_age,omitempty"`    // Freshness lifetime of cacheable responses
+}
+
+// WithRouteMeta declares metadata for a route pattern, matched against the pattern the
+// route was registered with (see RoutePattern). Metadata can also be set via "route_meta"
+// in options.json, keyed by pattern.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithRouteMeta("GET /api/products/", server.RouteMeta{Idempotent: true, Cacheable: true, MaxAge: time.Minute}),
+//		server.WithRouteMeta("POST /api/charges", server.RouteMeta{Idempotent: false}),
+//	)
+func WithRouteMeta(pattern string, meta RouteMeta) ServerOptionFunc {
+	return func(srv *Server) error {
+		if pattern == "" {
+			return fmt.Errorf("route metadata requires a pattern")
+		}
+		if meta.MaxAge < 0 {
+			return fmt.Errorf("route metadata max age must not be negative: %s", meta.MaxAge)
+		}
+		if srv.Options.RouteMeta == nil {
+			srv.Options.RouteMeta = make(map[string]RouteMeta)
+		}
+		srv.Options.RouteMeta[pattern] = meta
+		return nil
+	}
+}
+
+// SetRouteMeta declares or replaces the metadata of a route pattern at runtime.
+func (srv *Server) SetRouteMeta(pattern string, meta RouteMeta) {
+	srv.routesMu.Lock()
+	defer srv.routesMu.Unlock()
+	if srv.Options.RouteMeta == nil {
+		srv.Options.RouteMeta = make(map[string]RouteMeta)
+	}
+	srv.Options.RouteMeta[pattern] = meta
+}
+
+// RouteMetaFor returns the metadata declared for the route serving r.
+func (srv *Server) RouteMetaFor(r *http.Request) (RouteMeta, bool) {
+	pattern := RoutePattern(r)
+	if pattern == "" {
+		return RouteMeta{}, false
+	}
+	srv.routesMu.RLock()
+	defer srv.routesMu.RUnlock()
+	meta, ok := srv.Options.RouteMeta[pattern]
+	return meta, ok
+}
+
+// IsIdempotent reports whether r may safely be sent more than once, e.g. retried against
+// another upstream or hedged. Declared route metadata wins; routes without metadata are
+// idempotent only for safe methods (GET, HEAD, OPTIONS, TRACE).
+func (srv *Server) IsIdempotent(r *http.Request) bool {
+	if meta, ok := srv.RouteMetaFor(r); ok {
+		return meta.Idempotent
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// IsCacheable reports whether responses to r may be stored and shared, and for how long.
+// Only GET and HEAD requests to routes declared Cacheable qualify.
+func (srv *Server) IsCacheable(r *http.Request) (bool, time.Duration) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false, 0
+	}
+	meta, ok := srv.RouteMetaFor(r)
+	if !ok || !meta.Cacheable {
+		return false, 0
+	}
+	return true, meta.MaxAge
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteMetaIdempotencyAndCaching(t *testing.T) {
+	srv, err := NewServer(
+		WithRouteMeta("/products/", RouteMeta{Idempotent: true, Cacheable: true, MaxAge: time.Minute}),
+		WithRouteMeta("GET /report", RouteMeta{Idempotent: false}),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	srv.HandleFunc("/products/", noop)
+	srv.HandleFunc("GET /report", noop)
+	srv.HandleFunc("/orders", noop)
+
+	route := func(method, path string) *http.Request {
+		return withRoutePattern(httptest.NewRequest(method, path, nil), srv.mux)
+	}
+
+	tests := []struct {
+		method, path string
+		idempotent   bool
+		cacheable    bool
+	}{
+		{http.MethodGet, "/products/1", true, true},
+		{http.MethodPut, "/products/1", true, false},
+		{http.MethodGet, "/report", false, false},
+		{http.MethodGet, "/orders", true, false},
+		{http.MethodPost, "/orders", false, false},
+	}
+	for _, tt := range tests {
+		r := route(tt.method, tt.path)
+		if got := srv.IsIdempotent(r); got != tt.idempotent {
+			t.Errorf("%s %s: expected idempotent %v, got %v", tt.method, tt.path, tt.idempotent, got)
+		}
+		if got, _ := srv.IsCacheable(r); got != tt.cacheable {
+			t.Errorf("%s %s: expected cacheable %v, got %v", tt.method, tt.path, tt.cacheable, got)
+		}
+	}
+	if _, maxAge := srv.IsCacheable(route(http.MethodGet, "/products/1")); maxAge != time.Minute {
+		t.Errorf("expected max age 1m, got %s", maxAge)
+	}
+
+	srv.SetRouteMeta("/orders", RouteMeta{Idempotent: true})
+	if !srv.IsIdempotent(route(http.MethodPost, "/orders")) {
+		t.Error("expected runtime metadata to apply")
+	}
+	if _, err := NewServer(WithRouteMeta("", RouteMeta{})); err == nil {
+		t.Error("expected error for empty pattern")
+	}
+}
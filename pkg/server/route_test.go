@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutePatternAvailableToMiddleware(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	var seen string
+	srv.AddMiddleware("*", func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			seen = RoutePattern(r)
+			next.ServeHTTP(w, r)
+		}
+	})
+	srv.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if seen != "GET /users/{id}" {
+		t.Errorf("expected route pattern in middleware, got %q", seen)
+	}
+
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if seen != "" {
+		t.Errorf("expected empty pattern for unmatched route, got %q", seen)
+	}
+}
+
+func TestRateLimitByRoute(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithRateLimitKeyFunc(RateLimitByRoute))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.Options.Burst = 1
+	srv.Options.RateLimit = 0.001
+	srv.AddMiddleware("*", RateLimitMiddleware(srv))
+	srv.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {})
+	srv.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := srv.Handler()
+	send := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if code := send("/a"); code != http.StatusOK {
+		t.Fatalf("expected first /a request to pass, got %d", code)
+	}
+	if code := send("/b"); code != http.StatusOK {
+		t.Fatalf("expected /b to have its own bucket, got %d", code)
+	}
+	if code := send("/a"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected second /a request to be limited, got %d", code)
+	}
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RouteWindowMode determines how a RouteWindow affects its routes.
+type RouteWindowMode string
+
+const (
+	// RouteWindowDisable makes routes unavailable while the window is active
+	// (e.g. a maintenance window).
+	RouteWindowDisable RouteWindowMode = "disable"
+	// RouteWindowOnly makes routes available only while the window is active
+	// (e.g. batch imports only at night).
+	RouteWindowOnly RouteWindowMode = "only"
+	// RouteWindowThrottle rate limits routes while the window is active.
+	RouteWindowThrottle RouteWindowMode = "throttle"
+)
+
+// RouteWindow is a time-of-day rule for all routes starting with Route.
+// Start and End use 24-hour "HH:MM" notation; a window whose End is before its Start wraps
+// past midnight, and equal Start and End cover the whole day. Days restricts the window to
+// specific weekdays ("mon", "tue", ...), referring to the day the window starts.
+//
+// Windows are read from ServerOptions on every request, so reloading the configuration
+// takes effect immediately.
+type RouteWindow struct {
+	Name     string          `json:"name"`
+	Route    string          `json:"route"`
+	Mode     RouteWindowMode `json:"mode"`
+	Start    string          `json:"start"`
+	End      string          `json:"end"`
+	Days     []string        `json:"days,omitempty"`
+	Timezone string          `json:"timezone,omitempty"` // IANA name, defaults to UTC
+	Limit    RateLimit       `json:"limit,omitempty"`    // Requests per second for RouteWindowThrottle
+	Burst    int             `json:"burst,omitempty"`    // Burst size for RouteWindowThrottle
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// locationCache avoids reloading time zone data on every request.
+var locationCache sync.Map // map[string]*time.Location
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "UTC" {
+		return time.UTC, nil
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	hour, err := strconv.Atoi(h)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("invalid hour in %q", s)
+	}
+	minute, err := strconv.Atoi(m)
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid minute in %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Validate reports whether the window is well-formed.
+func (rw *RouteWindow) Validate() error {
+	if rw.Name == "" {
+		return fmt.Errorf("route window requires a name")
+	}
+	if rw.Route == "" {
+		return fmt.Errorf("route window %q requires a route", rw.Name)
+	}
+	switch rw.Mode {
+	case RouteWindowDisable, RouteWindowOnly:
+	case RouteWindowThrottle:
+		if rw.Limit <= 0 || rw.Burst <= 0 {
+			return fmt.Errorf("route window %q: throttle requires a positive limit and burst", rw.Name)
+		}
+	default:
+		return fmt.Errorf("route window %q: unknown mode %q", rw.Name, rw.Mode)
+	}
+	if _, err := parseClock(rw.Start); err != nil {
+		return fmt.Errorf("route window %q: %w", rw.Name, err)
+	}
+	if _, err := parseClock(rw.End); err != nil {
+		return fmt.Errorf("route window %q: %w", rw.Name, err)
+	}
+	for _, day := range rw.Days {
+		if _, ok := weekdayNames[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("route window %q: unknown day %q", rw.Name, day)
+		}
+	}
+	if _, err := loadLocation(rw.Timezone); err != nil {
+		return fmt.Errorf("route window %q: %w", rw.Name, err)
+	}
+	return nil
+}
+
+// Active reports whether the window is in effect at the given time.
+// Invalid windows are never active.
+func (rw *RouteWindow) Active(now time.Time) bool {
+	active, _ := rw.state(now)
+	return active
+}
+
+// state reports whether the window is active and how long until that changes.
+func (rw *RouteWindow) state(now time.Time) (bool, time.Duration) {
+	start, err1 := parseClock(rw.Start)
+	end, err2 := parseClock(rw.End)
+	loc, err3 := loadLocation(rw.Timezone)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return false, 0
+	}
+
+	t := now.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	untilMinute := func(target int) time.Duration {
+		d := target - minute
+		if d <= 0 {
+			d += 24 * 60
+		}
+		return time.Duration(d)*time.Minute - time.Duration(t.Second())*time.Second
+	}
+
+	var active bool
+	switch {
+	case start == end:
+		active = rw.onDay(day)
+	case start < end:
+		active = minute >= start && minute < end && rw.onDay(day)
+	default: // wraps past midnight
+		active = (minute >= start && rw.onDay(day)) || (minute < end && rw.onDay((day+6)%7))
+	}
+
+	if active {
+		return true, untilMinute(end)
+	}
+	return false, untilMinute(start)
+}
+
+func (rw *RouteWindow) onDay(day time.Weekday) bool {
+	if len(rw.Days) == 0 {
+		return true
+	}
+	for _, d := range rw.Days {
+		if weekdayNames[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRouteWindows adds time-of-day route policies, such as maintenance windows or
+// night-only batch endpoints. Windows can also be configured via the "route_windows"
+// key in options.json, where they are reloadable; a running server started without
+// windows needs a restart to enforce the first ones.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithRouteWindows(server.RouteWindow{
+//			Name: "nightly-import", Route: "/api/import", Mode: server.RouteWindowOnly,
+//			Start: "22:00", End: "06:00", Timezone: "Europe/Berlin",
+//		}),
+//	)
+func WithRouteWindows(windows ...RouteWindow) ServerOptionFunc {
+	return func(srv *Server) error {
+		for i := range windows {
+			if err := windows[i].Validate(); err != nil {
+				return err
+			}
+		}
+		srv.Options.RouteWindows = append(srv.Options.RouteWindows, windows...)
+		srv.installRouteWindows()
+		return nil
+	}
+}
+
+// installRouteWindows registers RouteWindowMiddleware globally once.
+func (srv *Server) installRouteWindows() {
+	if srv.routeWindowsWired {
+		return
+	}
+	srv.routeWindowsWired = true
+	srv.AddMiddleware(GlobalMiddlewareRoute, RouteWindowMiddleware(srv))
+}
+
+// RouteWindowMiddleware returns a middleware function that enforces the route windows in
+// ServerOptions.RouteWindows. Disabled routes respond with 503 Service Unavailable and a
+// Retry-After header indicating when the route becomes available again.
+// It is installed automatically when route windows are configured.
+func RouteWindowMiddleware(srv *Server) MiddlewareFunc {
+	var limitersMu sync.Mutex
+	limiters := make(map[string]*rate.Limiter)
+	allow := func(rw *RouteWindow) bool {
+		key := fmt.Sprintf("%s|%v|%d", rw.Name, rw.Limit, rw.Burst)
+		limitersMu.Lock()
+		limiter, ok := limiters[key]
+		if !ok {
+			limiter = rate.NewLimiter(rw.Limit, rw.Burst)
+			limiters[key] = limiter
+		}
+		limitersMu.Unlock()
+		return limiter.Allow()
+	}
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			windows := srv.Options.current().RouteWindows
+			for i := range windows {
+				rw := &windows[i]
+				if !strings.HasPrefix(r.URL.Path, rw.Route) {
+					continue
+				}
+				active, change := rw.state(now)
+
+				blocked := (rw.Mode == RouteWindowDisable && active) || (rw.Mode == RouteWindowOnly && !active)
+				if blocked {
+					Annotate(r, "route_window", rw.Name)
+					w.Header().Set("Retry-After", strconv.Itoa(int(change.Seconds())))
+					writeErrorResponse(w, http.StatusServiceUnavailable,
+						fmt.Sprintf("Route unavailable due to schedule %q", rw.Name))
+					return
+				}
+				if rw.Mode == RouteWindowThrottle && active && !allow(rw) {
+					Annotate(r, "route_window", rw.Name)
+					w.Header().Set("Retry-After", "1")
+					writeErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// routeWindowStatus summarises the configured windows for configuration resources.
+func routeWindowStatus(windows []RouteWindow, now time.Time) []map[string]interface{} {
+	status := make([]map[string]interface{}, 0, len(windows))
+	for i := range windows {
+		rw := &windows[i]
+		status = append(status, map[string]interface{}{
+			"name":     rw.Name,
+			"route":    rw.Route,
+			"mode":     rw.Mode,
+			"start":    rw.Start,
+			"end":      rw.End,
+			"days":     rw.Days,
+			"timezone": rw.Timezone,
+			"active":   rw.Active(now),
+		})
+	}
+	return status
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteWindowActive(t *testing.T) {
+	// 2025-03-17 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		window RouteWindow
+		now    time.Time
+		want   bool
+	}{
+		{"inside same-day window", RouteWindow{Start: "09:00", End: "17:00"}, at(17, 12, 0), true},
+		{"end is exclusive", RouteWindow{Start: "09:00", End: "17:00"}, at(17, 17, 0), false},
+		{"wrapping window before midnight", RouteWindow{Start: "22:00", End: "06:00"}, at(17, 23, 30), true},
+		{"wrapping window after midnight", RouteWindow{Start: "22:00", End: "06:00"}, at(18, 5, 59), true},
+		{"wrapping window outside", RouteWindow{Start: "22:00", End: "06:00"}, at(18, 12, 0), false},
+		{"day filter matches", RouteWindow{Start: "09:00", End: "17:00", Days: []string{"mon"}}, at(17, 10, 0), true},
+		{"day filter excludes", RouteWindow{Start: "09:00", End: "17:00", Days: []string{"tue"}}, at(17, 10, 0), false},
+		{"wrap uses start day", RouteWindow{Start: "22:00", End: "06:00", Days: []string{"Mon"}}, at(18, 2, 0), true},
+		{"whole day", RouteWindow{Start: "00:00", End: "00:00", Days: []string{"sun"}}, at(23, 15, 0), true},
+		{"timezone", RouteWindow{Start: "09:00", End: "10:00", Timezone: "Asia/Tokyo"}, at(17, 0, 30), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Active(tt.now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteWindowMiddleware(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithRouteWindows(
+		RouteWindow{Name: "always-down", Route: "/maintenance", Mode: RouteWindowDisable, Start: "00:00", End: "00:00"},
+		RouteWindow{Name: "never", Route: "/import", Mode: RouteWindowOnly, Start: "00:00", End: "00:00", Days: []string{}},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/maintenance", func(w http.ResponseWriter, r *http.Request) {})
+	srv.HandleFunc("/import", func(w http.ResponseWriter, r *http.Request) {})
+	srv.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.Handler()
+
+	send := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := send("/maintenance")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 503 with Retry-After during maintenance, got %d", rec.Code)
+	}
+	if rec := send("/import"); rec.Code != http.StatusOK {
+		t.Errorf("expected route to be available inside its window, got %d", rec.Code)
+	}
+	if rec := send("/other"); rec.Code != http.StatusOK {
+		t.Errorf("expected unrelated route to pass, got %d", rec.Code)
+	}
+
+	// Windows are read per request, so replacing them takes effect immediately
+	srv.Options.RouteWindows = nil
+	if rec := send("/maintenance"); rec.Code != http.StatusOK {
+		t.Errorf("expected route to be available after reload, got %d", rec.Code)
+	}
+}
+
+func TestRouteWindowThrottle(t *testing.T) {
+	srv, err := NewServer(WithAddr(":0"), WithRouteWindows(RouteWindow{
+		Name: "peak", Route: "/search", Mode: RouteWindowThrottle, Start: "00:00", End: "00:00", Limit: 0.001, Burst: 1,
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.Handler()
+
+	codes := []int{}
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+		codes = append(codes, rec.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("expected [200 429], got %v", codes)
+	}
+}
+
+func TestRouteWindowValidation(t *testing.T) {
+	invalid := []RouteWindow{
+		{Route: "/x", Mode: RouteWindowDisable, Start: "01:00", End: "02:00"},
+		{Name: "x", Route: "/x", Mode: "pause", Start: "01:00", End: "02:00"},
+		{Name: "x", Route: "/x", Mode: RouteWindowDisable, Start: "25:00", End: "02:00"},
+		{Name: "x", Route: "/x", Mode: RouteWindowDisable, Start: "01:00", End: "02:00", Days: []string{"someday"}},
+		{Name: "x", Route: "/x", Mode: RouteWindowDisable, Start: "01:00", End: "02:00", Timezone: "Mars/Olympus"},
+		{Name: "x", Route: "/x", Mode: RouteWindowThrottle, Start: "01:00", End: "02:00"},
+	}
+	for _, rw := range invalid {
+		if _, err := NewServer(WithRouteWindows(rw)); err == nil {
+			t.Errorf("expected validation error for %+v", rw)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// secretResolveTimeout bounds the time spent resolving the secrets of a configuration.
+const secretResolveTimeout = 30 * time.Second
+
+// secretRefPattern matches secret references such as ${vault:secret/data/api#token}.
+var secretRefPattern = regexp.MustCompile(`\$\{([a-z][a-z0-9_-]*):([^}]+)\}`)
+
+// SecretProvider looks up secrets referenced from configuration values. The reference is
+// the part after the scheme, e.g. "secret/data/api#token" in ${vault:secret/data/api#token}.
+type SecretProvider interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretProviderFunc adapts an ordinary function to the SecretProvider interface.
+type SecretProviderFunc func(ctx context.Context, ref string) (string, error)
+
+// Secret calls f(ctx, ref).
+func (f SecretProviderFunc) Secret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// WithSecretProvider lets configuration values reference secrets as ${scheme:ref}, so
+// that credentials need not sit in options.json or plain environment variables. Every
+// string option, including nested ones such as the introspection client secret, is
+// resolved when the server is created and on configuration reloads. References can be
+// part of a longer value, as in "postgres://app:${file:/run/secrets/db}@db/app".
+//
+// The "file" scheme (the trimmed contents of a file, as mounted by Docker and Kubernetes
+// secrets) and the "env" scheme (an environment variable) are built in. HyperServe does
+// not bundle cloud SDKs; VaultSecretProvider talks to HashiCorp Vault over HTTP, and
+// providers for AWS Secrets Manager and similar services wrap their SDK clients.
+//
+// Example:
+//
+//	sm := secretsmanager.NewFromConfig(awsConfig)
+//	srv, _ := server.NewServer(
+//		server.WithSecretProvider("vault", &server.VaultSecretProvider{}),
+//		server.WithSecretProvider("aws", server.SecretProviderFunc(func(ctx context.Context, ref string) (string, error) {
+//			out, err := sm.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &ref})
+//			if err != nil {
+//				return "", err
+//			}
+//			return *out.SecretString, nil
+//		})),
+//	)
+//
+// With HS_INTROSPECTION_CLIENT_SECRET='${vault:secret/data/idp#client_secret}' the
+// client secret is read from Vault.
+func WithSecretProvider(scheme string, provider SecretProvider) ServerOptionFunc {
+	return func(srv *Server) error {
+		if !secretRefPattern.MatchString("${" + scheme + ":x}") {
+			return fmt.Errorf("invalid secret scheme %q", scheme)
+		}
+		if provider == nil {
+			return fmt.Errorf("secret provider for %q must not be nil", scheme)
+		}
+		if srv.Options.SecretProviders == nil {
+			srv.Options.SecretProviders = make(map[string]SecretProvider)
+		}
+		srv.Options.SecretProviders[scheme] = provider
+		return nil
+	}
+}
+
+// useSharedClientForSecrets lets Vault providers without a client of their own read
+// through the shared outbound client. The client is configured once, so secret
+// references in its own options are resolved first, with the providers' default clients.
+func (srv *Server) useSharedClientForSecrets(ctx context.Context) error {
+	if cfg := srv.Options.HTTPClient; cfg != nil {
+		proxy, err := srv.Options.resolveSecret(ctx, cfg.Proxy)
+		if err != nil {
+			return err
+		}
+		cfg.Proxy = proxy
+	}
+	for _, p := range srv.Options.SecretProviders {
+		if vault, ok := p.(*VaultSecretProvider); ok && vault.Client == nil {
+			vault.Client = srv.HTTPClient()
+		}
+	}
+	return nil
+}
+
+// ResolveSecret replaces the secret references in value, such as ${vault:secret/data/jwt#key},
+// with the secrets they name. Handlers use it for secrets that are not server options,
+// like JWT signing keys or ECH keys.
+func (srv *Server) ResolveSecret(ctx context.Context, value string) (string, error) {
+	return srv.Options.resolveSecret(ctx, value)
+}
+
+// secretProvider returns the provider for scheme, including the built-in ones.
+func (o *ServerOptions) secretProvider(scheme string) SecretProvider {
+	if p, ok := o.SecretProviders[scheme]; ok {
+		return p
+	}
+	switch scheme {
+	case "file":
+		return SecretProviderFunc(fileSecret)
+	case "env":
+		return SecretProviderFunc(envSecret)
+	}
+	return nil
+}
+
+func (o *ServerOptions) resolveSecret(ctx context.Context, value string) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	var resolveErr error
+	resolved := secretRefPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+		m := secretRefPattern.FindStringSubmatch(match)
+		scheme, ref := m[1], m[2]
+		provider := o.secretProvider(scheme)
+		if provider == nil {
+			resolveErr = fmt.Errorf("no secret provider for %q, see WithSecretProvider", scheme)
+			return match
+		}
+		secret, err := provider.Secret(ctx, ref)
+		if err != nil {
+			// The reference is reported, never the value
+			resolveErr = fmt.Errorf("failed to resolve secret %s: %w", match, err)
+			return match
+		}
+		return secret
+	})
+	return resolved, resolveErr
+}
+
+// resolveSecrets replaces secret references in all string options, including those of
+// nested configuration structs, slices, and maps.
+func (o *ServerOptions) resolveSecrets(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, secretResolveTimeout)
+	defer cancel()
+
+	secretFields := make(map[string]bool)
+	value := reflect.ValueOf(o).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || name == "" {
+			continue
+		}
+		resolved, changed, err := o.resolveSecretsIn(ctx, value.Field(i))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if changed {
+			value.Field(i).Set(resolved)
+			secretFields[name] = true
+		}
+	}
+	o.secretFields = secretFields
+	return nil
+}
+
+// isStringConfigField reports whether values of t are strings or string slices.
+func isStringConfigField(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+// resolveSecretsIn returns v with its secret references resolved and whether any were
+// found. Pointers, slices, and maps that contain references are copied rather than
+// modified, since they may be shared with the configuration the caller passed in.
+func (o *ServerOptions) resolveSecretsIn(ctx context.Context, v reflect.Value) (reflect.Value, bool, error) {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := o.resolveSecret(ctx, v.String())
+		if err != nil || s == v.String() {
+			return v, false, err
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(s)
+		return out, true, nil
+
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return v, false, nil
+		}
+		elem, changed, err := o.resolveSecretsIn(ctx, v.Elem())
+		if err != nil || !changed {
+			return v, false, err
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, true, nil
+
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		changed := false
+		for i := 0; i < v.NumField(); i++ {
+			// Fields left out of JSON are resolved too when they hold strings, such as
+			// IntrospectionConfig.ClientSecret, but values such as clients are not walked
+			field := v.Type().Field(i)
+			if !field.IsExported() || (field.Tag.Get("json") == "-" && !isStringConfigField(field.Type)) {
+				continue
+			}
+			f, fieldChanged, err := o.resolveSecretsIn(ctx, v.Field(i))
+			if err != nil {
+				return v, false, fmt.Errorf("%s: %w", v.Type().Field(i).Name, err)
+			}
+			if fieldChanged {
+				out.Field(i).Set(f)
+				changed = true
+			}
+		}
+		return out, changed, nil
+
+	case reflect.Slice:
+		if kind := v.Type().Elem().Kind(); kind != reflect.String && kind != reflect.Struct {
+			return v, false, nil
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		changed := false
+		for i := 0; i < v.Len(); i++ {
+			elem, elemChanged, err := o.resolveSecretsIn(ctx, v.Index(i))
+			if err != nil {
+				return v, false, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out.Index(i).Set(elem)
+			changed = changed || elemChanged
+		}
+		if !changed {
+			return v, false, nil
+		}
+		return out, true, nil
+
+	case reflect.Map:
+		if kind := v.Type().Elem().Kind(); kind != reflect.String && kind != reflect.Struct {
+			return v, false, nil
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		changed := false
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, elemChanged, err := o.resolveSecretsIn(ctx, iter.Value())
+			if err != nil {
+				return v, false, fmt.Errorf("%v: %w", iter.Key(), err)
+			}
+			out.SetMapIndex(iter.Key(), elem)
+			changed = changed || elemChanged
+		}
+		if !changed {
+			return v, false, nil
+		}
+		return out, true, nil
+	}
+	return v, false, nil
+}
+
+// fileSecret reads a secret from a file, without the trailing newline editors add.
+func fileSecret(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// envSecret reads a secret from an environment variable.
+func envSecret(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// VaultSecretProvider reads secrets from HashiCorp Vault's key/value engine over its
+// HTTP API. References name a secret path and a key, e.g. "secret/data/api#token" for
+// version 2 of the engine or "secret/api#token" for version 1.
+type VaultSecretProvider struct {
+	Addr      string       // Vault address, defaults to $VAULT_ADDR
+	Token     string       // Access token, defaults to $VAULT_TOKEN
+	Namespace string       // Enterprise namespace, defaults to $VAULT_NAMESPACE
+	Client    *http.Client // Defaults to Server.HTTPClient, or a 10 second timeout client outside a server
+}
+
+// Secret reads the key named by ref from Vault.
+func (p *VaultSecretProvider) Secret(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault secret reference must be path#key, got %q", ref)
+	}
+	addr := cmp.Or(p.Addr, os.Getenv("VAULT_ADDR"))
+	if addr == "" {
+		return "", fmt.Errorf("vault address not configured, set VAULT_ADDR")
+	}
+	endpoint, err := url.JoinPath(addr, "v1", path)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault address: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", cmp.Or(p.Token, os.Getenv("VAULT_TOKEN")))
+	if ns := cmp.Or(p.Namespace, os.Getenv("VAULT_NAMESPACE")); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested // key/value version 2 wraps the secret with its metadata
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
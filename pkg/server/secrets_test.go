@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	secretFile := filepath.Join(dir, "origin")
+	if err := os.WriteFile(secretFile, []byte("https://app.example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeOptionsFile(t, `{
+		"cors": {"allowed_origins": ["${file:`+secretFile+`}"]},
+		"mcp_server_name": "${custom:name}-${env:TEST_SECRET_SUFFIX}"
+	}`)
+	t.Setenv("TEST_SECRET_SUFFIX", "prod")
+
+	srv, err := NewServer(WithSecretProvider("custom", SecretProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		return "api", nil
+	})))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if got := srv.Options.CORS.AllowedOrigins; len(got) != 1 || got[0] != "https://app.example.com" {
+		t.Errorf("expected the origin from the secret file, got %v", got)
+	}
+	if srv.Options.MCPServerName != "api-prod" {
+		t.Errorf("expected references within a value to be resolved, got %q", srv.Options.MCPServerName)
+	}
+	if changes := srv.PendingConfigChanges(); len(changes) != 0 {
+		t.Errorf("expected resolved secrets not to show up as pending changes, got %+v", changes)
+	}
+
+	if _, err := srv.ResolveSecret(context.Background(), "${unknown:x}"); err == nil {
+		t.Error("expected an error for an unknown scheme")
+	}
+	if _, err := srv.ResolveSecret(context.Background(), "${env:TEST_SECRET_MISSING}"); err == nil ||
+		!strings.Contains(err.Error(), "${env:TEST_SECRET_MISSING}") {
+		t.Errorf("expected the error to name the reference, got %v", err)
+	}
+}
+
+func TestNewServerFailsOnUnresolvableSecret(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeOptionsFile(t, `{"mcp_server_name": "${file:/does/not/exist}"}`)
+	if _, err := NewServer(); err == nil || !strings.Contains(err.Error(), "mcp_server_name") {
+		t.Fatalf("expected an error naming the option, got %v", err)
+	}
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/api":
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{"data": map[string]any{"token": "t0ken"}, "metadata": map[string]any{"version": 3}},
+			})
+		case "/v1/kv/api":
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"token": "v1-token"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vault.Close()
+
+	provider := &VaultSecretProvider{Addr: vault.URL, Token: "root"}
+	tests := []struct {
+		ref, want string
+		fails     bool
+	}{
+		{ref: "secret/data/api#token", want: "t0ken"},
+		{ref: "kv/api#token", want: "v1-token"},
+		{ref: "secret/data/api#missing", fails: true},
+		{ref: "secret/data/other#token", fails: true},
+		{ref: "secret/data/api", fails: true},
+	}
+	for _, tt := range tests {
+		got, err := provider.Secret(context.Background(), tt.ref)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q", tt.ref, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: expected %q, got %q, %v", tt.ref, tt.want, got, err)
+		}
+	}
+
+	if _, err := (&VaultSecretProvider{Addr: vault.URL, Token: "wrong"}).Secret(context.Background(), "secret/data/api#token"); err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+
+	srv, err := NewServer(WithSecretProvider("vault", &VaultSecretProvider{Addr: vault.URL, Token: "root"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := srv.ResolveSecret(context.Background(), "${vault:secret/data/api#token}"); err != nil || got != "t0ken" {
+		t.Errorf("expected the secret, got %q, %v", got, err)
+	}
+	if srv.HTTPClientStats().Requests != 1 {
+		t.Error("expected vault to be read through the shared client")
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SecurityHeaderPreset names a set of security headers for HeadersMiddleware.
+type SecurityHeaderPreset string
+
+const (
+	// StrictWeb suits first-party web applications: no framing, no referrer, all
+	// powerful browser features disabled, and a cross-origin isolated browsing context.
+	StrictWeb SecurityHeaderPreset = "strict_web"
+	// APIOnly suits JSON APIs: a policy that loads nothing, since responses are never
+	// rendered as documents, and no cross-origin isolation headers that browser clients
+	// would have to satisfy.
+	APIOnly SecurityHeaderPreset = "api_only"
+	// EmbeddedWidget suits pages embedded in other sites: framing is allowed by the
+	// frame-ancestors of the CSP (any https origin unless FrameAncestors narrows it) and
+	// resources may be loaded cross-origin.
+	EmbeddedWidget SecurityHeaderPreset = "embedded_widget"
+)
+
+// SecurityHeaders selects a preset for HeadersMiddleware and overrides individual headers
+// of it. Empty fields keep the preset's value and "-" omits the header, or for
+// FrameAncestors the directive. Without a preset
+// the overrides apply to the default headers. The presets do not opt into HSTS preloading,
+// which is hard to undo; set StrictTransportSecurity to opt in.
+type SecurityHeaders struct {
+	Preset                    SecurityHeaderPreset `json:"preset,omitempty"`
+	StrictTransportSecurity   string               `json:"strict_transport_security,omitempty"`
+	FrameOptions              string               `json:"frame_options,omitempty"` // X-Frame-Options
+	ReferrerPolicy            string               `json:"referrer_policy,omitempty"`
+	PermissionsPolicy         string               `json:"permissions_policy,omitempty"`
+	CrossOriginOpenerPolicy   string               `json:"cross_origin_opener_policy,omitempty"`
+	CrossOriginEmbedderPolicy string               `json:"cross_origin_embedder_policy,omitempty"`
+	CrossOriginResourcePolicy string               `json:"cross_origin_resource_policy,omitempty"`
+	FrameAncestors            string               `json:"frame_ancestors,omitempty"`         // Sources of the CSP frame-ancestors directive
+	ContentSecurityPolicy     string               `json:"content_security_policy,omitempty"` // Replaces the generated policy
+}
+
+// securityHeaderPresets holds the headers of each preset. The CSP is generated unless
+// ContentSecurityPolicy is set.
+var securityHeaderPresets = map[SecurityHeaderPreset]SecurityHeaders{
+	StrictWeb: {
+		StrictTransportSecurity:   "max-age=63072000; includeSubDomains",
+		FrameOptions:              "DENY",
+		ReferrerPolicy:            "no-referrer",
+		PermissionsPolicy:         "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=(), fullscreen=(self)",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginEmbedderPolicy: "require-corp",
+		CrossOriginResourcePolicy: "same-origin",
+		FrameAncestors:            "'none'",
+	},
+	APIOnly: {
+		StrictTransportSecurity:   "max-age=63072000; includeSubDomains",
+		FrameOptions:              "DENY",
+		ReferrerPolicy:            "no-referrer",
+		PermissionsPolicy:         "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=()",
+		CrossOriginOpenerPolicy:   "-",
+		CrossOriginEmbedderPolicy: "-",
+		CrossOriginResourcePolicy: "same-site",
+		ContentSecurityPolicy:     "default-src 'none'; frame-ancestors 'none'",
+	},
+	EmbeddedWidget: {
+		StrictTransportSecurity:   "max-age=31536000; includeSubDomains",
+		FrameOptions:              "-",
+		ReferrerPolicy:            "strict-origin-when-cross-origin",
+		PermissionsPolicy:         "geolocation=(), microphone=(), camera=(), payment=(), usb=()",
+		CrossOriginOpenerPolicy:   "-",
+		CrossOriginEmbedderPolicy: "-",
+		CrossOriginResourcePolicy: "cross-origin",
+		FrameAncestors:            "https:",
+	},
+}
+
+// WithSecurityHeaders selects a preset for the headers of HeadersMiddleware (see
+// SecureWeb) and overrides individual headers, instead of the defaults meant for a
+// same-origin web application. It can also be set via "security_headers" in
+// options.json, and configuration reloads apply changes to it.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithSecurityHeaders(server.SecurityHeaders{
+//		Preset:         server.EmbeddedWidget,
+//		FrameAncestors: "https://partner.example.com",
+//	}))
+//	srv.AddMiddlewareStack("/widget", server.SecureWeb(srv.Options))
+func WithSecurityHeaders(cfg SecurityHeaders) ServerOptionFunc {
+	return func(srv *Server) error {
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		srv.Options.SecurityHeaders = &cfg
+		return nil
+	}
+}
+
+func (cfg *SecurityHeaders) validate() error {
+	if _, ok := securityHeaderPresets[cfg.Preset]; cfg.Preset != "" && !ok {
+		return fmt.Errorf("unknown security header preset %q: use %s, %s, or %s", cfg.Preset, StrictWeb, APIOnly, EmbeddedWidget)
+	}
+	return nil
+}
+
+// headers returns the headers of the preset with the overrides applied, and the sources
+// of the CSP frame-ancestors directive and the policy replacing the generated CSP, if any.
+// Without a preset the overrides apply to securityHeaders.
+func (cfg *SecurityHeaders) headers() (headers []Header, frameAncestors, csp string) {
+	overrides := []Header{
+		{"Strict-Transport-Security", cfg.StrictTransportSecurity},
+		{"X-Frame-Options", cfg.FrameOptions},
+		{"Referrer-Policy", cfg.ReferrerPolicy},
+		{"Permissions-Policy", cfg.PermissionsPolicy},
+		{"Cross-Origin-Opener-Policy", cfg.CrossOriginOpenerPolicy},
+		{"Cross-Origin-Embedder-Policy", cfg.CrossOriginEmbedderPolicy},
+		{"Cross-Origin-Resource-Policy", cfg.CrossOriginResourcePolicy},
+	}
+	if cfg.Preset == "" {
+		headers = slices.Clone(securityHeaders)
+		for _, o := range overrides {
+			if i := slices.IndexFunc(headers, func(h Header) bool { return h.key == o.key }); i >= 0 && o.value != "" {
+				headers[i].value = o.value
+			}
+		}
+		return headers, cfg.FrameAncestors, cfg.ContentSecurityPolicy
+	}
+
+	preset := securityHeaderPresets[cfg.Preset]
+	pick := func(override, value string) string {
+		if override != "" {
+			return override
+		}
+		return value
+	}
+	headers = []Header{
+		{"X-Content-Type-Options", "nosniff"},
+		{"X-Permitted-Cross-Domain-Policies", "none"},
+		{"Strict-Transport-Security", pick(cfg.StrictTransportSecurity, preset.StrictTransportSecurity)},
+		{"X-Frame-Options", pick(cfg.FrameOptions, preset.FrameOptions)},
+		{"Referrer-Policy", pick(cfg.ReferrerPolicy, preset.ReferrerPolicy)},
+		{"Permissions-Policy", pick(cfg.PermissionsPolicy, preset.PermissionsPolicy)},
+		{"Cross-Origin-Opener-Policy", pick(cfg.CrossOriginOpenerPolicy, preset.CrossOriginOpenerPolicy)},
+		{"Cross-Origin-Embedder-Policy", pick(cfg.CrossOriginEmbedderPolicy, preset.CrossOriginEmbedderPolicy)},
+		{"Cross-Origin-Resource-Policy", pick(cfg.CrossOriginResourcePolicy, preset.CrossOriginResourcePolicy)},
+	}
+	return headers, pick(cfg.FrameAncestors, preset.FrameAncestors), pick(cfg.ContentSecurityPolicy, preset.ContentSecurityPolicy)
+}
+
+// tlsHSTS reports whether HeadersMiddleware strengthens HSTS over TLS, as it does for the
+// default headers unless HSTS is overridden.
+func (cfg *SecurityHeaders) tlsHSTS() bool {
+	return cfg == nil || cfg.Preset == "" && cfg.StrictTransportSecurity == ""
+}
+
+// setCSPDirective replaces or adds the directive name of csp, or removes it if sources
+// is "-".
+func setCSPDirective(csp, name, sources string) string {
+	directives := strings.Split(csp, ";")
+	for i, directive := range directives {
+		if fields := strings.Fields(directive); len(fields) > 0 && fields[0] == name {
+			if sources == "-" {
+				directives = slices.Delete(directives, i, i+1)
+			} else {
+				directives[i] = " " + name + " " + sources
+			}
+			return strings.TrimSpace(strings.Join(directives, ";"))
+		}
+	}
+	if sources == "-" {
+		return csp
+	}
+	return csp + "; " + name + " " + sources
+}
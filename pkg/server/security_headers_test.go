@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeaderPresets(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      SecurityHeaders
+		tls      bool
+		want     map[string]string // "" means the header is absent
+		cspHas   string
+		cspLacks string
+	}{
+		{
+			name: "strict web",
+			cfg:  SecurityHeaders{Preset: StrictWeb},
+			want: map[string]string{
+				"Referrer-Policy":              "no-referrer",
+				"X-Frame-Options":              "DENY",
+				"Cross-Origin-Embedder-Policy": "require-corp",
+				"Access-Control-Allow-Methods": "",
+				"Strict-Transport-Security":    "max-age=63072000; includeSubDomains",
+			},
+			cspHas: "frame-ancestors 'none'",
+		},
+		{
+			name: "api only",
+			cfg:  SecurityHeaders{Preset: APIOnly},
+			want: map[string]string{
+				"Cross-Origin-Opener-Policy":   "",
+				"Cross-Origin-Resource-Policy": "same-site",
+			},
+			cspHas: "default-src 'none'",
+		},
+		{
+			name: "embedded widget with overrides",
+			cfg: SecurityHeaders{
+				Preset:                    EmbeddedWidget,
+				FrameAncestors:            "https://partner.example.com",
+				ReferrerPolicy:            "origin",
+				CrossOriginResourcePolicy: "-",
+			},
+			want: map[string]string{
+				"X-Frame-Options":              "",
+				"Referrer-Policy":              "origin",
+				"Cross-Origin-Resource-Policy": "",
+				"X-Content-Type-Options":       "nosniff",
+			},
+			cspHas: "frame-ancestors https://partner.example.com",
+		},
+		{
+			name: "overrides without preset",
+			cfg:  SecurityHeaders{FrameOptions: "SAMEORIGIN"},
+			want: map[string]string{
+				"X-Frame-Options":              "SAMEORIGIN",
+				"Referrer-Policy":              "strict-origin-when-cross-origin",
+				"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
+			},
+			cspHas: "script-src 'self'",
+		},
+		{
+			name:   "overrides without preset over TLS",
+			cfg:    SecurityHeaders{ReferrerPolicy: "origin"},
+			tls:    true,
+			want:   map[string]string{"Strict-Transport-Security": "max-age=63072000; includeSubDomains"},
+			cspHas: "script-src 'self'",
+		},
+		{
+			name:   "HSTS override over TLS",
+			cfg:    SecurityHeaders{StrictTransportSecurity: "max-age=63072000; includeSubDomains; preload"},
+			tls:    true,
+			want:   map[string]string{"Strict-Transport-Security": "max-age=63072000; includeSubDomains; preload"},
+			cspHas: "script-src 'self'",
+		},
+		{
+			name: "strict web without CSP",
+			cfg:  SecurityHeaders{Preset: StrictWeb, ContentSecurityPolicy: "-"},
+			want: map[string]string{
+				"Content-Security-Policy": "",
+				"X-Frame-Options":         "DENY",
+			},
+		},
+		{
+			name:     "strict web without frame-ancestors",
+			cfg:      SecurityHeaders{Preset: StrictWeb, FrameAncestors: "-"},
+			cspHas:   "default-src 'self'",
+			cspLacks: "frame-ancestors",
+		},
+		{
+			name:     "custom CSP without frame-ancestors",
+			cfg:      SecurityHeaders{ContentSecurityPolicy: "default-src 'self'", FrameAncestors: "-"},
+			want:     map[string]string{"Content-Security-Policy": "default-src 'self'"},
+			cspLacks: "frame-ancestors",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := NewServer(WithSecurityHeaders(tt.cfg))
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			srv.Options.EnableTLS = tt.tls
+			handler := HeadersMiddleware(srv.Options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			for header, want := range tt.want {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s: expected %q, got %q", header, want, got)
+				}
+			}
+			if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, tt.cspHas) {
+				t.Errorf("expected the CSP to contain %q, got %q", tt.cspHas, csp)
+			}
+			if csp := rec.Header().Get("Content-Security-Policy"); tt.cspLacks != "" && strings.Contains(csp, tt.cspLacks) {
+				t.Errorf("expected the CSP not to contain %q, got %q", tt.cspLacks, csp)
+			}
+		})
+	}
+
+	if _, err := NewServer(WithSecurityHeaders(SecurityHeaders{Preset: "lax"})); err == nil {
+		t.Error("expected an error for an unknown preset")
+	}
+}
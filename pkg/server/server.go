@@ -93,6 +93,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -168,7 +169,16 @@ const (
 	paramMCPDev               = "HS_MCP_DEV"
 	paramMCPObservability     = "HS_MCP_OBSERVABILITY"
 	paramMCPTransport         = "HS_MCP_TRANSPORT"
+	paramMCPSuspended         = "HS_MCP_SUSPENDED"
+	paramMCPAdminPath         = "HS_MCP_ADMIN_PATH"
+	paramMCPAdminToken        = "HS_MCP_ADMIN_TOKEN"
 	paramCSPWebWorkerSupport  = "HS_CSP_WEB_WORKER_SUPPORT"
+	paramCSPNonce             = "HS_CSP_NONCE"
+	paramTLSMinVersion        = "HS_TLS_MIN_VERSION"
+	paramTLSCipherSuites      = "HS_TLS_CIPHER_SUITES"
+	paramTLSCurves            = "HS_TLS_CURVES"
+	paramRedactHeaders        = "HS_REDACT_HEADERS"
+	paramRedactFields         = "HS_REDACT_FIELDS"
 	paramCORSAllowedOrigins   = "HS_CORS_ALLOWED_ORIGINS"
 	paramCORSAllowCredentials = "HS_CORS_ALLOW_CREDENTIALS"
 	paramCORSAllowedMethods   = "HS_CORS_ALLOWED_METHODS"
@@ -178,7 +188,13 @@ const (
 	paramLogLevel             = "HS_LOG_LEVEL"
 	paramDebugMode            = "HS_DEBUG"
 	paramSuppressBanner       = "HS_SUPPRESS_BANNER"
+	paramProfile              = "HS_PROFILE"
 	paramBannerColor          = "HS_BANNER_COLOR"
+	paramPprofPath            = "HS_PPROF_PATH"
+	paramPprofToken           = "HS_PPROF_TOKEN"
+	paramIntrospectURL        = "HS_INTROSPECTION_URL"
+	paramIntrospectClientID   = "HS_INTROSPECTION_CLIENT_ID"
+	paramIntrospectSecret     = "HS_INTROSPECTION_CLIENT_SECRET"
 )
 
 // RateLimit limits requests per second that can be requested from the httpServer. Requires to add [RateLimitMiddleware]
@@ -211,13 +227,24 @@ type Server struct {
 	httpServer           *http.Server
 	healthServer         *http.Server
 	middleware           *MiddlewareRegistry
+	defaultMiddleware    defaultMiddlewareConfig
 	templates            *template.Template
 	templatesMu          sync.Mutex
+	templatesStamp       string                        // Names, sizes, and mtimes of the parsed template files, see templateSet
+	pageTemplates        map[string]*template.Template // Template set of each page, see loadTemplatesLocked
+	templateFuncs        template.FuncMap
 	Options              *ServerOptions
 	isReady              atomic.Bool
 	isRunning            atomic.Bool
 	totalRequests        atomic.Uint64
 	totalResponseTime    atomic.Int64
+	metrics              *metricsStore
+	apiUsage             *apiUsageStore
+	slos                 *sloStore // Requests per SLO in the evaluation windows, see WithSLO
+	redactor             *redactor // Scrubs secrets from logs and captures, see WithRedaction
+	geoPolicyHits        geoPolicyHits
+	quotas               []quotaBinding
+	routeWindowsWired    bool
 	websocketConnections atomic.Uint64
 	serverStart          time.Time
 	clientLimiters       map[string]*rateLimiterEntry
@@ -238,6 +265,30 @@ type Server struct {
 	registeredRoutes     map[string]struct{}
 	onReadyMu            sync.Mutex
 	onReadyExecuted      atomic.Bool
+	warmers              []CacheWarmer
+	warmupMu             sync.Mutex
+	warmupDone           atomic.Bool
+	warmupResults        []WarmupResult
+	introspection        *IntrospectionValidator
+	operations           *operationStore
+	plugins              []string
+	pluginsMu            sync.Mutex
+	edgePurgers          []EdgePurger
+	purgeHooks           []func(context.Context, []string)
+	edgePurgeMu          sync.Mutex
+	sessions             *SessionManager
+	sessionsOnce         sync.Once
+	staticPrefix         string
+	assetsMu             sync.Mutex
+	assets               *AssetManifest
+	assetsStamp          string // Names, sizes, and mtimes of the static files, see AssetManifest
+	gateway              *gateway
+	outboundOnce         sync.Once
+	outboundClient       *outboundClient
+	exporters            []metricsExport
+	federation           mcpFederation
+	configMu             sync.Mutex // serialises configuration reloads
+	maintenance          atomic.Bool
 }
 
 // NewServer creates a new instance of the Server with the given options.
@@ -262,6 +313,7 @@ func NewServer(opts ...ServerOptionFunc) (*Server, error) {
 		templates:      nil,
 		templatesMu:    sync.Mutex{},
 		clientLimiters: make(map[string]*rateLimiterEntry),
+		metrics:        newMetricsStore(),
 		cleanupDone:    make(chan bool),
 		bootstrapAllowPaths: map[string]struct{}{
 			"/healthz": {},
@@ -294,18 +346,138 @@ func NewServer(opts ...ServerOptionFunc) (*Server, error) {
 		logger.Debug("Debug mode enabled from configuration")
 	}
 
-	srv.middleware = NewMiddlewareRegistry(DefaultMiddleware(srv))
-	logger.Debug("Default middleware registered", "middlewares", []string{"MetricsMiddleware", "RequestLoggerMiddleware", "RecoveryMiddleware"})
+	srv.middleware = NewMiddlewareRegistry(nil)
 
 	// apply httpServer options
+	loaded := *srv.Options
 	for _, opt := range opts {
 		if err := opt(srv); err != nil {
 			return nil, err
 		}
 	}
+	srv.Options.markConfigSources(&loaded, srv.Options, ConfigSourceProgrammatic)
+	if err := srv.useSharedClientForSecrets(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to resolve configuration secrets: %w", err)
+	}
+	if err := srv.Options.resolveSecrets(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to resolve configuration secrets: %w", err)
+	}
+	if err := srv.Options.Validate(); err != nil {
+		return nil, err
+	}
+	srv.redactor = newRedactor(srv.Options.Redaction)
+	srv.middleware.redactor = srv.redactor
+	srv.maintenance.Store(srv.Options.MaintenanceMode)
+	defaults, stack := srv.defaultMiddlewareStack()
+	srv.middleware.prependWithPhase(GlobalMiddlewareRoute, PhaseOutermost, stack)
+	logger.Debug("Default middleware registered", "middlewares", defaults)
+	installLogControl()
+	if err := applyLogConfig(srv.Options.Logging); err != nil {
+		return nil, err
+	}
+	if srv.Options.MiddlewareMetrics && srv.middleware.layerMetrics == nil {
+		srv.middleware.layerMetrics = newLayerMetricsStore()
+	}
+	if srv.Options.AccessLog != nil && srv.middleware.accessLog == nil {
+		al, err := newAccessLogger(*srv.Options.AccessLog)
+		if err != nil {
+			return nil, err
+		}
+		srv.middleware.accessLog = al
+	}
+	if len(srv.Options.RouteWindows) > 0 {
+		for i := range srv.Options.RouteWindows {
+			if err := srv.Options.RouteWindows[i].Validate(); err != nil {
+				return nil, err
+			}
+		}
+		srv.installRouteWindows()
+	}
 	if srv.deferredInit == nil && srv.Options.DeferredInit != nil {
 		srv.deferredInit = srv.Options.DeferredInit
 	}
+	if srv.Options.PprofPath != "" {
+		if !strings.HasPrefix(srv.Options.PprofPath, "/") {
+			return nil, fmt.Errorf("pprof path must start with '/': %q", srv.Options.PprofPath)
+		}
+		srv.registerPprof()
+	}
+	if srv.Options.AssetManifestPath != "" {
+		srv.HandleFunc(srv.Options.AssetManifestPath, srv.assetManifestHandler)
+	}
+	for i := range srv.Options.SLOs {
+		if err := srv.Options.SLOs[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(srv.Options.SLOs) > 0 {
+		srv.slos = newSLOStore(len(srv.Options.SLOs))
+	}
+	if srv.Options.APIUsage != nil {
+		if err := srv.Options.APIUsage.validate(); err != nil {
+			return nil, err
+		}
+		srv.apiUsage = newAPIUsageStore(*srv.Options.APIUsage)
+		if srv.Options.APIUsage.Path != "" {
+			srv.registerAPIUsage()
+		}
+	}
+	var sseLimits SSELimitsConfig
+	if srv.Options.SSELimits != nil {
+		if err := srv.Options.SSELimits.validate(); err != nil {
+			return nil, err
+		}
+		sseLimits = *srv.Options.SSELimits
+	}
+	srv.middleware.sse = newSSEConnections(sseLimits)
+	if srv.Options.GatewayConfigFile != "" {
+		gw, err := newGateway(srv, srv.Options.GatewayConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		srv.gateway = gw
+	}
+	if cfg := srv.Options.ServiceWorker; cfg != nil {
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		srv.HandleFunc(cfg.path(), srv.serviceWorkerHandler)
+	}
+	if cfg := srv.Options.Batch; cfg != nil {
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		srv.HandleFunc(cfg.path(), srv.batchHandler)
+	}
+	if cfg := srv.Options.Operations; cfg != nil {
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		srv.setupOperations(cfg)
+	}
+	if cfg := srv.Options.ErrorPages; cfg != nil {
+		if err := cfg.compile(); err != nil {
+			return nil, err
+		}
+	}
+	srv.useSharedClientForPurgers()
+	if cfg := srv.Options.Introspection; cfg != nil && cfg.Endpoint != "" && srv.introspection == nil {
+		vcfg := *cfg
+		if vcfg.HTTPClient == nil {
+			vcfg.HTTPClient = srv.HTTPClient()
+		}
+		v, err := NewIntrospectionValidator(vcfg)
+		if err != nil {
+			return nil, err
+		}
+		srv.introspection = v
+		srv.Options.AuthTokenValidatorFunc = v.Validate
+	}
+	if srv.deferredInit == nil && len(srv.warmers) > 0 {
+		// Cache warmers run as part of deferred initialization so that the server
+		// is not ready until they complete
+		srv.deferredInit = func(context.Context, *Server) error { return nil }
+	}
 
 	// Auto-configure MCP if enabled via environment/flags but not already configured programmatically
 	if srv.Options.MCPEnabled && srv.Options.MCPServerName != "" && srv.mcpHandler == nil {
@@ -357,13 +529,46 @@ func NewServer(opts ...ServerOptionFunc) (*Server, error) {
 		}
 	}
 
+	if srv.Options.MCPEnabled && srv.Options.mcpTransportOpts.developerMode {
+		if err := srv.checkDeveloperMode(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Initialize MCP handler if enabled
 	if srv.Options.MCPEnabled {
+		if srv.Options.MCPTransport == StdioTransport {
+			// Keep stdout for JSON-RPC before anything else can write to it
+			reserveStdout()
+		}
 		serverInfo := MCPServerInfo{
 			Name:    srv.Options.MCPServerName,
 			Version: srv.Options.MCPServerVersion,
 		}
 		srv.mcpHandler = NewMCPHandler(serverInfo)
+		srv.mcpHandler.reporter = srv.Options.ErrorReporter
+		srv.mcpHandler.SetSessionLimits(srv.Options.MCPMaxSessions, srv.Options.MCPSessionIdleTimeout)
+		if srv.Options.mcpTransportOpts.websocket {
+			srv.mcpHandler.wsUpgrader = newMCPUpgrader()
+		}
+		if len(srv.Options.MCPToolPolicies) > 0 {
+			principal := srv.Options.MCPPrincipalFunc
+			if principal == nil {
+				principal = srv.introspectionPrincipal
+			}
+			policies, err := newMCPToolPolicies(srv.Options.MCPToolPolicies, principal)
+			if err != nil {
+				return nil, err
+			}
+			srv.mcpHandler.toolPolicies = policies
+		}
+		if cfg := srv.Options.MCPToolConcurrency; cfg != nil {
+			limiter, err := newMCPToolLimiter(*cfg)
+			if err != nil {
+				return nil, err
+			}
+			srv.mcpHandler.toolLimiter = limiter
+		}
 
 		// Register built-in tools if enabled
 		if srv.Options.MCPToolsEnabled {
@@ -383,11 +588,18 @@ func NewServer(opts ...ServerOptionFunc) (*Server, error) {
 			}
 
 			// HTTP request tool
-			srv.mcpHandler.RegisterToolInNamespace(NewHTTPRequestTool(), "hyperserve")
+			httpTool := NewHTTPRequestTool()
+			httpTool.client = srv.HTTPClient()
+			srv.mcpHandler.RegisterToolInNamespace(httpTool, "hyperserve")
 
 			// Calculator tool
 			srv.mcpHandler.RegisterToolInNamespace(NewCalculatorTool(), "hyperserve")
 		}
+		if srv.Options.MCPFileWrite != nil {
+			if err := srv.registerFileWriteTools(); err != nil {
+				return nil, err
+			}
+		}
 
 		// Register built-in resources if enabled
 		if srv.Options.MCPResourcesEnabled {
@@ -397,23 +609,73 @@ func NewServer(opts ...ServerOptionFunc) (*Server, error) {
 				srv.RegisterObservabilityMCPResources()
 			} else if srv.Options.mcpTransportOpts.developerMode {
 				// Developer mode: development tools and resources
-				srv.RegisterDeveloperMCPTools()
+				if err := srv.RegisterDeveloperMCPTools(); err != nil {
+					return nil, err
+				}
 			} else {
 				// Standard mode: full set of built-in resources
 				srv.mcpHandler.RegisterResource(NewConfigResource(srv.Options))
 				srv.mcpHandler.RegisterResource(NewMetricsResource(srv))
+				srv.mcpHandler.RegisterResource(NewRouteMetricsResource(srv))
 				srv.mcpHandler.RegisterResource(NewSystemResource())
 				srv.mcpHandler.RegisterResource(NewLogResource(srv.Options.MCPLogResourceSize))
 			}
+			if len(srv.quotas) > 0 {
+				srv.mcpHandler.RegisterResource(NewQuotaResource(srv))
+			}
+			if srv.apiUsage != nil {
+				srv.mcpHandler.RegisterResource(NewAPIUsageResource(srv))
+			}
+			srv.mcpHandler.RegisterResource(NewSSEConnectionsResource(srv))
+			if len(srv.Options.SLOs) > 0 {
+				srv.mcpHandler.RegisterResource(NewSLOResource(srv))
+			}
+			if srv.gateway != nil {
+				srv.mcpHandler.RegisterResource(NewGatewayUpstreamsResource(srv))
+			}
+		}
+
+		srv.mcpHandler.redactor = srv.redactor
+		if srv.Options.MCPTrace != nil {
+			if err := srv.mcpHandler.StartTrace(*srv.Options.MCPTrace); err != nil {
+				return nil, err
+			}
+		}
+		if srv.Options.MCPAudit != nil {
+			principal := srv.Options.MCPPrincipalFunc
+			if principal == nil && srv.introspection != nil {
+				principal = srv.introspectionPrincipal
+			}
+			if err := srv.mcpHandler.enableAudit(*srv.Options.MCPAudit, principal); err != nil {
+				return nil, err
+			}
 		}
 
 		// Register unified MCP endpoint
+		var mcpEndpoint http.Handler = srv.mcpHandler
+		if cfg := srv.Options.MCPAuthorization; cfg != nil {
+			if err := cfg.validate(); err != nil {
+				return nil, err
+			}
+			mcpEndpoint = srv.mcpAuthorization(mcpEndpoint)
+			srv.setupProtectedResourceMetadata()
+		}
 		srv.registerRoute(srv.Options.MCPEndpoint)
-		srv.mux.Handle(srv.Options.MCPEndpoint, srv.mcpHandler)
+		srv.mux.Handle(srv.Options.MCPEndpoint, mcpEndpoint)
 		logger.Debug("MCP handler initialized", "endpoint", srv.Options.MCPEndpoint)
 
 		// Setup discovery endpoints for Claude Code
 		srv.setupDiscoveryEndpoints()
+
+		if srv.Options.MCPAdminPath != "" {
+			if !strings.HasPrefix(srv.Options.MCPAdminPath, "/") {
+				return nil, fmt.Errorf("MCP admin path must start with '/': %q", srv.Options.MCPAdminPath)
+			}
+			srv.registerMCPAdmin()
+		}
+		if srv.Options.MCPSuspended {
+			srv.mcpHandler.Disable("suspended at startup")
+		}
 	}
 
 	// Start cleanup ticker for rate limiters (run every 5 minutes)
@@ -463,6 +725,8 @@ func (srv *Server) Run() error {
 			return fmt.Errorf("MCP handler not initialized for stdio transport")
 		}
 		srv.isRunning.Store(true)
+		srv.mountMCPUpstreams(context.Background())
+		defer srv.closeMCPUpstreams()
 		return srv.mcpHandler.RunStdioLoop()
 	}
 
@@ -470,7 +734,24 @@ func (srv *Server) Run() error {
 	srv.lifecycleCtx = lifecycleCtx
 	srv.lifecycleCancel = lifecycleCancel
 
+	if srv.gateway != nil {
+		go srv.gateway.probe(lifecycleCtx)
+		if srv.Options.GatewayReloadInterval > 0 {
+			go srv.gateway.watch(lifecycleCtx, srv.Options.GatewayReloadInterval)
+		}
+	}
+	if srv.Options.ConfigReloadInterval > 0 {
+		go srv.watchConfig(lifecycleCtx, srv.Options.ConfigReloadInterval)
+	}
+	for _, e := range srv.exporters {
+		go srv.runMetricsExport(lifecycleCtx, e)
+	}
+	if srv.MCPEnabled() {
+		srv.mountMCPUpstreams(lifecycleCtx)
+	}
+
 	baseHandler := srv.middleware.applyToMux(srv.mux)
+	baseHandler = srv.maintenanceHandler(baseHandler)
 	if srv.deferredInit != nil {
 		baseHandler = srv.bootstrapReadinessHandler(baseHandler)
 	}
@@ -511,13 +792,22 @@ func (srv *Server) Run() error {
 	var listenErr error
 
 	if srv.Options.EnableTLS {
-		if srv.Options.CertFile == "" || srv.Options.KeyFile == "" {
+		hasCertificates := srv.Options.tlsConfigHasCertificates()
+		if !hasCertificates && (srv.Options.CertFile == "" || srv.Options.KeyFile == "") {
 			listenErr = fmt.Errorf("TLS enabled but no key or cert file provided")
 			logger.Error(listenErr.Error(), "key", srv.Options.KeyFile, "cert", srv.Options.CertFile)
 			return listenErr
 		}
 		// Configure TLS settings
-		srv.httpServer.TLSConfig = srv.tlsConfig()
+		tlsConfig := srv.tlsConfig()
+		if !hasCertificates {
+			cert, err := tls.LoadX509KeyPair(srv.Options.CertFile, srv.Options.KeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load TLS certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		srv.httpServer.TLSConfig = tlsConfig
 		srv.httpServer.Addr = srv.Options.TLSAddr
 		listener, listenErr = net.Listen("tcp", srv.Options.TLSAddr)
 		if listenErr != nil {
@@ -573,6 +863,11 @@ func (srv *Server) logServerMetrics() {
 }
 
 func (srv *Server) tlsConfig() *tls.Config {
+	if srv.Options.TLSConfig != nil {
+		logger.Info("TLS configured with a custom tls.Config")
+		return srv.Options.TLSConfig.Clone()
+	}
+
 	config := &tls.Config{
 		MinVersion: tls.VersionTLS12,
 		MaxVersion: tls.VersionTLS13,
@@ -580,18 +875,8 @@ func (srv *Server) tlsConfig() *tls.Config {
 
 	if srv.Options.FIPSMode {
 		// FIPS 140-3 compliant cipher suites and curves only
-		config.CipherSuites = []uint16{
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_AES_128_GCM_SHA256, // TLS 1.3 FIPS approved
-			tls.TLS_AES_256_GCM_SHA384, // TLS 1.3 FIPS approved
-		}
-		config.CurvePreferences = []tls.CurveID{
-			tls.CurveP256,
-			tls.CurveP384,
-		}
+		config.CipherSuites = slices.Clone(fipsCipherSuites)
+		config.CurvePreferences = slices.Clone(fipsCurves)
 		logger.Info("TLS configured in FIPS 140-3 mode")
 	} else {
 		// Standard cipher suites including post-quantum ready
@@ -608,6 +893,17 @@ func (srv *Server) tlsConfig() *tls.Config {
 		config.CurvePreferences = nil
 	}
 
+	// Settings pinned with WithTLSConfig, whose names Validate has checked
+	if version, err := parseTLSVersion(srv.Options.TLSMinVersion); err == nil {
+		config.MinVersion = version
+	}
+	if suites, err := parseTLSCipherSuites(srv.Options.TLSCipherSuites); err == nil && len(suites) > 0 {
+		config.CipherSuites = suites
+	}
+	if curves, err := parseTLSCurves(srv.Options.TLSCurvePreferences); err == nil && len(curves) > 0 {
+		config.CurvePreferences = curves
+	}
+
 	// Enable Encrypted Client Hello if configured
 	if srv.Options.EnableECH && len(srv.Options.ECHKeys) > 0 {
 		// ECH configuration will be automatically handled by Go 1.24's crypto/tls
@@ -849,6 +1145,11 @@ func (srv *Server) completeDeferredInit(ctx context.Context, initErr error, errC
 		ctx = srv.lifecycleCtx
 	}
 
+	if err := srv.runCacheWarmers(ctx); err != nil {
+		srv.reportDeferredInitError("Cache warming failed", err, errChan)
+		return err
+	}
+
 	if err := srv.runOnReadyOnce(ctx); err != nil {
 		srv.reportDeferredInitError("OnReady hook failed", err, errChan)
 		return err
@@ -965,6 +1266,7 @@ func (srv *Server) shutdown(ctx context.Context) error {
 	if srv.lifecycleCancel != nil {
 		srv.lifecycleCancel()
 	}
+	srv.closeMCPUpstreams()
 
 	// Execute shutdown hooks first (before HTTP server shutdown)
 	// Give hooks 5 seconds of the 10-second budget
@@ -1067,6 +1369,21 @@ func (srv *Server) shutdown(ctx context.Context) error {
 			logger.Error("Failed to close template root", "error", err)
 		}
 	}
+	if srv.mcpHandler != nil {
+		if err := srv.mcpHandler.StopTrace(); err != nil {
+			logger.Error("Failed to close MCP trace file", "error", err)
+		}
+		if srv.mcpHandler.auditor != nil {
+			if err := srv.mcpHandler.auditor.close(); err != nil {
+				logger.Error("Failed to close MCP audit log", "error", err)
+			}
+		}
+	}
+	if srv.middleware != nil && srv.middleware.accessLog != nil {
+		if err := srv.middleware.accessLog.close(); err != nil {
+			logger.Error("Failed to close access log file", "error", err)
+		}
+	}
 
 	return shutdownErr
 }
@@ -1176,7 +1493,7 @@ func (srv *Server) hasRoute(pattern string) bool {
 //	})
 
 func (srv *Server) Handler() http.Handler {
-	return srv.middleware.applyToMux(srv.mux)
+	return srv.maintenanceHandler(srv.middleware.applyToMux(srv.mux))
 }
 
 func (srv *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
@@ -1205,7 +1522,7 @@ func (srv *Server) HandleFuncDynamic(pattern, tmplName string, dataFunc DataFunc
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 			data := dataFunc(r)
-			if err := srv.templates.ExecuteTemplate(w, tmplName, data); err != nil {
+			if err := srv.executeTemplate(w, r, tmplName, tmplName, data); err != nil {
 				logger.Error("Failed to execute template", "template", tmplName, "error", err)
 				http.Error(w, "Error rendering template", http.StatusInternalServerError)
 				return
@@ -1226,6 +1543,9 @@ func EnsureTrailingSlash(dir string) string {
 // HandleStatic registers a handler for serving static files from the configured static directory.
 // The pattern should typically end with a wildcard (e.g., "/static/").
 // Uses os.Root for secure file access when available (Go 1.24+).
+// Pre-compressed siblings such as app.js.br and app.js.gz are served in place of app.js
+// to clients that accept their encoding. Directories without an index.html are listed
+// where WithDirectoryListing enables it.
 func (srv *Server) HandleStatic(pattern string) {
 	// Lazy initialization of static root on first use
 	if srv.staticRoot == nil && srv.Options.StaticDir != "" {
@@ -1239,15 +1559,19 @@ func (srv *Server) HandleStatic(pattern string) {
 	}
 
 	srv.registerRoute(pattern)
+	srv.assetsMu.Lock()
+	srv.staticPrefix = pattern
+	srv.assets = nil // fingerprinted URLs include the prefix
+	srv.assetsMu.Unlock()
 
 	if srv.staticRoot != nil {
 		// Use secure os.Root with custom handler
-		srv.mux.Handle(pattern, http.StripPrefix(pattern, srv.rootFileServer()))
+		srv.mux.Handle(pattern, http.StripPrefix(pattern, srv.fingerprintedAssets(srv.precompressedAssets(srv.directoryListing(srv.rootFileServer())))))
 		logger.Info("Static file serving using secure os.Root", "pattern", pattern)
 	} else {
 		// Fallback to traditional file server
 		staticDir := EnsureTrailingSlash(srv.Options.StaticDir)
-		srv.mux.Handle(pattern, http.StripPrefix(pattern, http.FileServer(http.Dir(staticDir))))
+		srv.mux.Handle(pattern, http.StripPrefix(pattern, srv.fingerprintedAssets(srv.precompressedAssets(srv.directoryListing(http.FileServer(http.Dir(staticDir)))))))
 		logger.Info("Static file serving using http.Dir", "pattern", pattern, "dir", staticDir)
 	}
 }
@@ -1319,97 +1643,13 @@ func (srv *Server) parseTemplates() error {
 		// Templates already parsed
 		return nil
 	}
-
-	if srv.templateRoot != nil {
-		// Use secure os.Root for template parsing (Go 1.24+)
-		tmpl := template.New("root")
-
-		// List directory contents using a helper function
-		templateFiles, err := srv.listTemplateFiles()
-		if err != nil {
-			return fmt.Errorf("failed to list template files: %w", err)
-		}
-
-		for _, filename := range templateFiles {
-			if strings.HasSuffix(filename, ".html") {
-				// Open and read the template file
-				file, err := srv.templateRoot.Open(filename)
-				if err != nil {
-					logger.Error("Failed to open template file", "file", filename, "error", err)
-					continue
-				}
-
-				content, err := io.ReadAll(file)
-				file.Close()
-				if err != nil {
-					logger.Error("Failed to read template file", "file", filename, "error", err)
-					continue
-				}
-
-				_, err = tmpl.New(filename).Parse(string(content))
-				if err != nil {
-					logger.Error("Failed to parse template", "file", filename, "error", err)
-					return fmt.Errorf("failed to parse template %s: %w", filename, err)
-				}
-			}
-		}
-
-		srv.templates = tmpl
-		logger.Info("Templates parsed using secure os.Root", "count", len(tmpl.Templates())-1) // -1 for root template
-		return nil
-	}
-
-	// Fallback to traditional template parsing
-	templateDir := srv.Options.TemplateDir
-	// Check if the template directory exists
-	if _, err := os.Stat(templateDir); os.IsNotExist(err) {
-		wd, _ := os.Getwd()
-		ad, _ := filepath.Abs(templateDir)
-		return fmt.Errorf("template directory not found. working-dir %s abs-path: %s, error %w", wd, ad, err)
-	}
-
-	// Parse the templates
-	tmpl, err := template.ParseGlob(filepath.Join(templateDir, "*.html"))
-	if err != nil {
-		logger.Error("Failed to parse templates", "error", err)
-		return fmt.Errorf("failed to parse templates: %w", err)
-	}
-
-	srv.templates = tmpl
-	logger.Info("Templates parsed.", "pattern", filepath.Join(templateDir, "*.html"))
-	return nil
+	return srv.loadTemplatesLocked()
 }
 
 // DataFunc is a function type that generates data for template rendering.
 // It receives the current HTTP request and returns data to be passed to the template.
 type DataFunc func(r *http.Request) interface{}
 
-// listTemplateFiles lists all files in the template root directory
-func (srv *Server) listTemplateFiles() ([]string, error) {
-	// Since os.Root doesn't have ReadDir, we need to use the regular os package
-	// to list files, then validate them through os.Root
-	var files []string
-
-	// Read the actual directory
-	entries, err := os.ReadDir(srv.Options.TemplateDir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			// Verify we can open it through os.Root (validates it's within root)
-			file, err := srv.templateRoot.Open(entry.Name())
-			if err == nil {
-				file.Close()
-				files = append(files, entry.Name())
-			}
-		}
-	}
-
-	return files, nil
-}
-
 func checkfile(file, wd string) error {
 	if _, err := os.Stat(file); err != nil {
 		return fmt.Errorf("File %s not found in working directory %s. %w ", file, wd, err)
@@ -1459,6 +1699,8 @@ func WithLoglevel(level slog.Level) ServerOptionFunc {
 
 // WithDebugMode enables debug logging and additional debug features.
 // This is equivalent to WithLoglevel(LevelDebug) plus additional debug information.
+// Templates in TemplateDir are re-parsed when they change, so markup edits show up on
+// the next request without a restart.
 func WithDebugMode() ServerOptionFunc {
 	return func(srv *Server) error {
 		srv.Options.DebugMode = true
@@ -1872,7 +2114,7 @@ func WithCSPWebWorkerSupport() ServerOptionFunc {
 }
 
 // cleanupRateLimiters runs periodically to clean up old rate limiters
-// This prevents memory leaks from accumulating client IP rate limiters
+// This prevents memory leaks from accumulating per-client rate limiters
 func (srv *Server) cleanupRateLimiters() {
 	ticker := srv.cleanupTicker
 	if ticker == nil {
@@ -1916,8 +2158,14 @@ func (srv *Server) MCPEnabled() bool {
 	return srv.Options.MCPEnabled && srv.mcpHandler != nil
 }
 
+// MCPHandler returns the server's MCP handler, or nil if MCP is not enabled.
+func (srv *Server) MCPHandler() *MCPHandler {
+	return srv.mcpHandler
+}
+
 // RegisterMCPTool registers a custom MCP tool
-// This must be called after server creation but before Run()
+// Tools can be registered while the server is running; connected clients are sent
+// notifications/tools/list_changed.
 func (srv *Server) RegisterMCPTool(tool MCPTool) error {
 	if !srv.MCPEnabled() {
 		return fmt.Errorf("MCP is not enabled on this server")
@@ -1926,6 +2174,19 @@ func (srv *Server) RegisterMCPTool(tool MCPTool) error {
 	return nil
 }
 
+// UnregisterMCPTool removes an MCP tool by the name clients call it by, e.g.
+// "mcp__hyperserve__calculator" for namespaced tools. Connected clients are sent
+// notifications/tools/list_changed.
+func (srv *Server) UnregisterMCPTool(name string) error {
+	if !srv.MCPEnabled() {
+		return fmt.Errorf("MCP is not enabled on this server")
+	}
+	if !srv.mcpHandler.UnregisterTool(name) {
+		return fmt.Errorf("MCP tool not found: %s", name)
+	}
+	return nil
+}
+
 // RegisterMCPResource registers a custom MCP resource
 // This must be called after server creation but before Run()
 func (srv *Server) RegisterMCPResource(resource MCPResource) error {
@@ -1937,13 +2198,13 @@ func (srv *Server) RegisterMCPResource(resource MCPResource) error {
 }
 
 // RegisterMCPToolInNamespace registers a custom MCP tool in the specified namespace
-// This must be called after server creation but before Run()
+// This must be called after server creation but before Run(). It fails if the namespace
+// already has a tool of the same name.
 func (srv *Server) RegisterMCPToolInNamespace(tool MCPTool, namespace string) error {
 	if !srv.MCPEnabled() {
 		return fmt.Errorf("MCP is not enabled on this server")
 	}
-	srv.mcpHandler.RegisterToolInNamespace(tool, namespace)
-	return nil
+	return srv.mcpHandler.RegisterToolInNamespace(tool, namespace)
 }
 
 // RegisterMCPResourceInNamespace registers a custom MCP resource in the specified namespace
@@ -20,6 +20,7 @@ func TestMCPProgrammaticConfigurationNoDoubleWarning(t *testing.T) {
 
 	// Simulate HF_DAW's configuration approach
 	serverOpts := []ServerOptionFunc{
+		WithAddr("localhost:8080"),
 		WithRateLimit(100, 200),
 		WithCSPWebWorkerSupport(),
 	}
@@ -80,7 +81,7 @@ func TestMCPEnvironmentConfiguration(t *testing.T) {
 	defer func() { logger = oldLogger }()
 
 	// Create server without programmatic MCP configuration
-	srv, err := NewServer()
+	srv, err := NewServer(WithAddr("localhost:8080"))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
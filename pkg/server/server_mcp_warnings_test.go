@@ -37,6 +37,7 @@ func TestMCPDevModeWarnings(t *testing.T) {
 				log.Println("⚠️  MCP Developer Mode enabled - use for development only!") // HF_DAW's warning
 
 				srv, _ := NewServer(
+					WithAddr("localhost:8080"),
 					WithMCPSupport("Test App", "1.0.0", mcpConfigs...),
 				)
 
@@ -58,6 +59,7 @@ func TestMCPDevModeWarnings(t *testing.T) {
 
 				// No application warning here
 				srv, _ := NewServer(
+					WithAddr("localhost:8080"),
 					WithMCPSupport("Test App", "1.0.0", MCPDev()),
 				)
 
@@ -83,7 +85,7 @@ func TestMCPDevModeWarnings(t *testing.T) {
 				logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
 				defer func() { logger = oldLogger }()
 
-				srv, _ := NewServer() // Auto-configured from environment
+				srv, _ := NewServer(WithAddr("localhost:8080")) // Auto-configured from environment
 
 				return srv, &buf
 			},
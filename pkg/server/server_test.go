@@ -77,6 +77,74 @@ func TestHandleTemplateMissingTemplate(t *testing.T) {
 	}
 }
 
+func TestTemplateHotReload(t *testing.T) {
+	render := func(t *testing.T, srv *Server) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/page", nil))
+		return rec.Body.String()
+	}
+	setup := func(t *testing.T, opts ...ServerOptionFunc) (*Server, string) {
+		t.Helper()
+		templateDir := t.TempDir()
+		if err := os.WriteFile(templateDir+"/page.html", []byte("<p>v1 {{.}}</p>"), 0644); err != nil {
+			t.Fatalf("error writing template file: %v", err)
+		}
+		srv, err := NewServer(append(opts, WithTemplateDir(templateDir))...)
+		if err != nil {
+			t.Fatalf("error creating server: %v", err)
+		}
+		if err := srv.HandleTemplate("/page", "page.html", "data"); err != nil {
+			t.Fatalf("failed to add template handler: %v", err)
+		}
+		return srv, templateDir
+	}
+	edit := func(t *testing.T, templateDir, content string) {
+		t.Helper()
+		path := templateDir + "/page.html"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing template file: %v", err)
+		}
+		// Coarse file system timestamps must not hide the change
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatalf("error touching template file: %v", err)
+		}
+	}
+
+	t.Run("debug mode reloads changed templates", func(t *testing.T) {
+		srv, templateDir := setup(t, WithDebugMode())
+		if got := render(t, srv); got != "<p>v1 data</p>" {
+			t.Fatalf("unexpected body %q", got)
+		}
+		edit(t, templateDir, "<p>v2 {{.}}</p>")
+		if got := render(t, srv); got != "<p>v2 data</p>" {
+			t.Fatalf("expected the edited template, got %q", got)
+		}
+
+		// A broken edit fails the render until it is fixed
+		edit(t, templateDir, "<p>{{.</p>")
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/page", nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected 500 for a broken template, got %d", rec.Code)
+		}
+		edit(t, templateDir, "<p>v3 {{.}}</p>")
+		if got := render(t, srv); got != "<p>v3 data</p>" {
+			t.Fatalf("expected the fixed template, got %q", got)
+		}
+	})
+
+	t.Run("production keeps parsed templates", func(t *testing.T) {
+		srv, templateDir := setup(t)
+		render(t, srv)
+		edit(t, templateDir, "<p>v2 {{.}}</p>")
+		if got := render(t, srv); got != "<p>v1 data</p>" {
+			t.Fatalf("expected cached template, got %q", got)
+		}
+	})
+}
+
 func TestHandleFuncDynamicValidTemplate(t *testing.T) {
 	t.Parallel()
 	// Use unique directory name to avoid conflicts in parallel tests
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	defaultServiceWorkerPath  = "/service-worker.js"
+	defaultServiceWorkerCache = "hyperserve"
+)
+
+// CacheStrategy selects how a service worker answers requests matching a runtime rule.
+type CacheStrategy string
+
+const (
+	// CacheNetworkFirst tries the network and falls back to the cache when offline.
+	CacheNetworkFirst CacheStrategy = "network-first"
+	// CacheCacheFirst answers from the cache and only fetches on a miss.
+	CacheCacheFirst CacheStrategy = "cache-first"
+	// CacheStaleWhileRevalidate answers from the cache and refreshes it in the background.
+	CacheStaleWhileRevalidate CacheStrategy = "stale-while-revalidate"
+	// CacheNetworkOnly never caches.
+	CacheNetworkOnly CacheStrategy = "network-only"
+)
+
+// RuntimeCacheRule caches same-origin GET requests whose path matches Pattern, a regular
+// expression evaluated by the browser (keep to syntax shared by Go and JavaScript).
+type RuntimeCacheRule struct {
+	Pattern    string        `json:"pattern"`
+	Strategy   CacheStrategy `json:"strategy"`
+	MaxEntries int           `json:"max_entries,omitempty"` // Oldest entries are evicted beyond this, 0 is unlimited
+}
+
+// ServiceWorkerConfig configures the generated service worker.
+type ServiceWorkerConfig struct {
+	Path           string             `json:"path,omitempty"`       // Script URL (default /service-worker.js)
+	Scope          string             `json:"scope,omitempty"`      // Sent as Service-Worker-Allowed when set
+	CacheName      string             `json:"cache_name,omitempty"` // Prefix of all cache names (default "hyperserve")
+	Precache       []string           `json:"precache,omitempty"`   // URLs cached on install in addition to static assets
+	OfflinePage    string             `json:"offline_page,omitempty"`
+	RuntimeCaching []RuntimeCacheRule `json:"runtime_caching,omitempty"`
+	// SkipAssets disables precaching the fingerprinted files from the asset manifest.
+	SkipAssets bool `json:"skip_assets,omitempty"`
+}
+
+// serviceWorkerRule is a runtime rule as embedded in the generated script.
+type serviceWorkerRule struct {
+	Pattern    string        `json:"pattern"`
+	Strategy   CacheStrategy `json:"strategy"`
+	CacheName  string        `json:"cacheName"`
+	MaxEntries int           `json:"maxEntries"`
+}
+
+// WithServiceWorker serves a generated service worker that precaches the fingerprinted
+// static assets (see WithAssetManifest) and applies runtime caching rules, for offline
+// support in template apps. The script is served with no-cache so browsers pick up new
+// asset versions immediately; caches of previous versions are removed on activation.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithServiceWorker(server.ServiceWorkerConfig{
+//		Precache:    []string{"/", "/offline"},
+//		OfflinePage: "/offline",
+//		RuntimeCaching: []server.RuntimeCacheRule{
+//			{Pattern: "^/api/", Strategy: server.CacheNetworkFirst, MaxEntries: 50},
+//		},
+//	}))
+func WithServiceWorker(cfg ServiceWorkerConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		srv.Options.ServiceWorker = &cfg
+		return nil
+	}
+}
+
+func (cfg *ServiceWorkerConfig) validate() error {
+	if cfg.Path != "" && !strings.HasPrefix(cfg.Path, "/") {
+		return fmt.Errorf("service worker path must start with '/': %q", cfg.Path)
+	}
+	for _, rule := range cfg.RuntimeCaching {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid service worker cache pattern %q: %w", rule.Pattern, err)
+		}
+		switch rule.Strategy {
+		case CacheNetworkFirst, CacheCacheFirst, CacheStaleWhileRevalidate, CacheNetworkOnly:
+		default:
+			return fmt.Errorf("unknown service worker cache strategy %q", rule.Strategy)
+		}
+	}
+	return nil
+}
+
+func (cfg *ServiceWorkerConfig) path() string {
+	if cfg.Path == "" {
+		return defaultServiceWorkerPath
+	}
+	return cfg.Path
+}
+
+// ServiceWorkerRegistration returns a script tag that registers the service worker, for
+// inclusion in page templates. It is empty if no service worker is configured.
+func (srv *Server) ServiceWorkerRegistration() template.HTML {
+	cfg := srv.Options.ServiceWorker
+	if cfg == nil {
+		return ""
+	}
+	path, _ := json.Marshal(cfg.path())
+	options := "{}"
+	if cfg.Scope != "" {
+		scope, _ := json.Marshal(cfg.Scope)
+		options = fmt.Sprintf("{scope: %s}", scope)
+	}
+	return template.HTML(fmt.Sprintf(`<script>if ("serviceWorker" in navigator) { navigator.serviceWorker.register(%s, %s); }</script>`, path, options))
+}
+
+// serviceWorkerScript renders the service worker for the current asset manifest.
+func (srv *Server) serviceWorkerScript() ([]byte, error) {
+	cfg := srv.Options.ServiceWorker
+	prefix := cfg.CacheName
+	if prefix == "" {
+		prefix = defaultServiceWorkerCache
+	}
+
+	urls := append([]string(nil), cfg.Precache...)
+	if cfg.OfflinePage != "" {
+		urls = append(urls, cfg.OfflinePage)
+	}
+	version := "static"
+	if !cfg.SkipAssets {
+		if m, err := srv.AssetManifest(); err == nil {
+			version = m.Version
+			for _, entry := range m.Assets {
+				urls = append(urls, entry.Path)
+			}
+		} else {
+			logger.Debug("Service worker generated without static assets", "error", err)
+		}
+	}
+	urls = uniqueSorted(urls)
+
+	rules := make([]serviceWorkerRule, len(cfg.RuntimeCaching))
+	for i, rule := range cfg.RuntimeCaching {
+		rules[i] = serviceWorkerRule{
+			Pattern:    rule.Pattern,
+			Strategy:   rule.Strategy,
+			CacheName:  fmt.Sprintf("%s-runtime-%d", prefix, i),
+			MaxEntries: rule.MaxEntries,
+		}
+	}
+
+	data := map[string]interface{}{
+		"Prefix":   prefix,
+		"Precache": fmt.Sprintf("%s-precache-%s", prefix, version),
+		"URLs":     urls,
+		"Offline":  cfg.OfflinePage,
+		"Rules":    rules,
+	}
+	var buf bytes.Buffer
+	if err := serviceWorkerTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// serviceWorkerHandler serves the generated script with revalidation.
+func (srv *Server) serviceWorkerHandler(w http.ResponseWriter, r *http.Request) {
+	script, err := srv.serviceWorkerScript()
+	if err != nil {
+		logger.Error("Failed to generate service worker", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, "Service worker unavailable")
+		return
+	}
+	sum := sha256.Sum256(script)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", etag)
+	if scope := srv.Options.ServiceWorker.Scope; scope != "" {
+		w.Header().Set("Service-Worker-Allowed", scope)
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(script)
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	// Stable output keeps the ETag unchanged between requests
+	sort.Strings(out)
+	return out
+}
+
+var serviceWorkerTemplate = texttemplate.Must(texttemplate.New("service-worker").Funcs(texttemplate.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}).Parse(`// Generated by HyperServe. Do not edit.
+const CACHE_PREFIX = {{json .Prefix}} + "-";
+const PRECACHE = {{json .Precache}};
+const PRECACHE_URLS = {{json .URLs}};
+const OFFLINE_URL = {{json .Offline}};
+const RULES = {{json .Rules}}.map((rule) => ({ ...rule, regex: new RegExp(rule.pattern) }));
+
+self.addEventListener("install", (event) => {
+  event.waitUntil(
+    caches.open(PRECACHE).then((cache) => cache.addAll(PRECACHE_URLS)).then(() => self.skipWaiting())
+  );
+});
+
+self.addEventListener("activate", (event) => {
+  const keep = new Set([PRECACHE, ...RULES.map((rule) => rule.cacheName)]);
+  event.waitUntil(
+    caches.keys()
+      .then((keys) => Promise.all(keys
+        .filter((key) => key.startsWith(CACHE_PREFIX) && !keep.has(key))
+        .map((key) => caches.delete(key))))
+      .then(() => self.clients.claim())
+  );
+});
+
+async function put(rule, request, response) {
+  if (!response || !response.ok) {
+    return;
+  }
+  const cache = await caches.open(rule.cacheName);
+  await cache.put(request, response);
+  if (rule.maxEntries > 0) {
+    const keys = await cache.keys();
+    for (let i = 0; i < keys.length - rule.maxEntries; i++) {
+      await cache.delete(keys[i]);
+    }
+  }
+}
+
+const strategies = {
+  "network-first": async (request, rule) => {
+    try {
+      const response = await fetch(request);
+      await put(rule, request, response.clone());
+      return response;
+    } catch (err) {
+      const cached = await caches.match(request, { cacheName: rule.cacheName });
+      if (cached) {
+        return cached;
+      }
+      throw err;
+    }
+  },
+  "cache-first": async (request, rule) => {
+    const cached = await caches.match(request, { cacheName: rule.cacheName });
+    if (cached) {
+      return cached;
+    }
+    const response = await fetch(request);
+    await put(rule, request, response.clone());
+    return response;
+  },
+  "stale-while-revalidate": async (request, rule) => {
+    const cached = await caches.match(request, { cacheName: rule.cacheName });
+    const network = fetch(request).then(async (response) => {
+      await put(rule, request, response.clone());
+      return response;
+    });
+    return cached || network;
+  },
+  "network-only": (request) => fetch(request),
+};
+
+async function handle(request) {
+  const url = new URL(request.url);
+  const rule = RULES.find((r) => r.regex.test(url.pathname));
+  try {
+    if (rule) {
+      return await strategies[rule.strategy](request, rule);
+    }
+    const cached = await caches.match(request, { cacheName: PRECACHE });
+    return cached || await fetch(request);
+  } catch (err) {
+    if (OFFLINE_URL && request.mode === "navigate") {
+      const offline = await caches.match(OFFLINE_URL, { cacheName: PRECACHE });
+      if (offline) {
+        return offline;
+      }
+    }
+    throw err;
+  }
+}
+
+self.addEventListener("fetch", (event) => {
+  const request = event.request;
+  if (request.method !== "GET" || new URL(request.url).origin !== self.location.origin) {
+    return;
+  }
+  event.respondWith(handle(request));
+});
+`))
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceWorkerScript(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o644)
+
+	srv, err := NewServer(WithServiceWorker(ServiceWorkerConfig{
+		Precache:    []string{"/"},
+		OfflinePage: "/offline",
+		RuntimeCaching: []RuntimeCacheRule{
+			{Pattern: "^/api/", Strategy: CacheNetworkFirst, MaxEntries: 20},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.Options.StaticDir = dir
+	srv.HandleStatic("/static/")
+
+	rec := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/service-worker.js", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/javascript") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected no-cache, got %q", cc)
+	}
+
+	manifest, err := srv.AssetManifest()
+	if err != nil {
+		t.Fatalf("AssetManifest: %v", err)
+	}
+	script := rec.Body.String()
+	for _, want := range []string{
+		manifest.Assets["app.css"].Path,
+		`"/offline"`,
+		`"hyperserve-precache-` + manifest.Version + `"`,
+		`"pattern":"^/api/","strategy":"network-first","cacheName":"hyperserve-runtime-0","maxEntries":20`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected script to contain %s", want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/service-worker.js", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for unchanged script, got %d", rec.Code)
+	}
+
+	if reg := string(srv.ServiceWorkerRegistration()); !strings.Contains(reg, `register("/service-worker.js", {})`) {
+		t.Errorf("unexpected registration snippet %s", reg)
+	}
+}
+
+func TestServiceWorkerConfigValidation(t *testing.T) {
+	if _, err := NewServer(WithServiceWorker(ServiceWorkerConfig{
+		RuntimeCaching: []RuntimeCacheRule{{Pattern: "^/api/", Strategy: "cache-everything"}},
+	})); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+	if _, err := NewServer(WithServiceWorker(ServiceWorkerConfig{Path: "sw.js"})); err == nil {
+		t.Error("expected error for relative path")
+	}
+}
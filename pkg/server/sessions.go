@@ -0,0 +1,392 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSessionRetention is how long invalidations are remembered. It should exceed the
+// lifetime of the tokens accepted by the server.
+const defaultSessionRetention = 24 * time.Hour
+
+// SessionIdentity identifies the user and session behind a request.
+type SessionIdentity struct {
+	Subject   string    // User ID ("sub" claim)
+	SessionID string    // Identity provider session ("sid" claim), optional
+	IssuedAt  time.Time // When the credential was issued, zero if unknown
+}
+
+// SessionIdentityFunc resolves the session identity of a request. It runs before
+// route-specific middleware such as AuthMiddleware, so it must derive the identity from
+// the request itself (e.g. the bearer token). An empty Subject and SessionID mean the
+// request is not associated with a session.
+type SessionIdentityFunc func(r *http.Request) SessionIdentity
+
+// LogoutClaims are the claims of a verified OIDC logout token.
+type LogoutClaims struct {
+	Subject   string
+	SessionID string
+}
+
+// LogoutTokenVerifier verifies an OIDC back-channel logout token and returns its claims.
+// Implementations must validate the signature against the identity provider's keys and
+// check the iss, aud, iat, and events claims as required by OpenID Connect Back-Channel
+// Logout 1.0.
+type LogoutTokenVerifier func(ctx context.Context, logoutToken string) (LogoutClaims, error)
+
+// SessionManager tracks terminated sessions and the long-lived connections (SSE and
+// WebSocket) of each subject, so that all of a user's sessions can be ended at once.
+// Obtain it with srv.Sessions().
+type SessionManager struct {
+	identity  SessionIdentityFunc
+	retention time.Duration
+
+	mu          sync.Mutex
+	subjects    map[string]time.Time // subject -> invalidated at
+	sessionIDs  map[string]time.Time // sid -> invalidated at
+	sessionless map[string]time.Time // subject -> session logout, for identities without a sid
+	connections map[string]map[*trackedConnection]struct{}
+}
+
+// trackedConnection is a long-lived request that is closed when its session ends.
+type trackedConnection struct {
+	identity SessionIdentity
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	conn     net.Conn // set once the connection is hijacked (WebSocket)
+}
+
+func (c *trackedConnection) close() {
+	c.cancel()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+func newSessionManager(identity SessionIdentityFunc) *SessionManager {
+	return &SessionManager{
+		identity:    identity,
+		retention:   defaultSessionRetention,
+		subjects:    make(map[string]time.Time),
+		sessionIDs:  make(map[string]time.Time),
+		sessionless: make(map[string]time.Time),
+		connections: make(map[string]map[*trackedConnection]struct{}),
+	}
+}
+
+// WithSessions enables session enforcement, see Sessions. WithSessionIdentity and
+// WithBackChannelLogout enable it as well.
+func WithSessions() ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Sessions()
+		return nil
+	}
+}
+
+// Sessions returns the server's session manager, enabling session enforcement on first use.
+// Requests from terminated sessions are rejected with 401 Unauthorized.
+//
+// Enforcement installs a middleware, so it must be enabled before the server starts,
+// preferably with WithSessions. Once the server runs, Sessions only returns the manager:
+// invalidations are recorded but not enforced, and an error is logged.
+func (srv *Server) Sessions() *SessionManager {
+	srv.sessionsOnce.Do(func() {
+		srv.sessions = newSessionManager(srv.introspectionIdentity)
+		if srv.isRunning.Load() {
+			logger.Error("Session enforcement cannot be enabled while the server runs, use WithSessions")
+			return
+		}
+		srv.AddMiddleware(GlobalMiddlewareRoute, SessionMiddleware(srv))
+	})
+	return srv.sessions
+}
+
+// introspectionIdentity is the default SessionIdentityFunc. It resolves the subject and, if
+// the provider returns one, the session ID of the bearer token through the configured
+// introspection validator, whose results are cached.
+func (srv *Server) introspectionIdentity(r *http.Request) SessionIdentity {
+	token, _ := strings.CutPrefix(r.Header.Get(authorizationHeader), bearerTokenPrefix)
+	if token == "" || srv.introspection == nil {
+		return SessionIdentity{}
+	}
+	res, err := srv.introspection.Introspect(r.Context(), token)
+	if err != nil || !res.Active {
+		return SessionIdentity{}
+	}
+	identity := SessionIdentity{Subject: res.Sub, SessionID: res.Sid}
+	if res.Iat > 0 {
+		identity.IssuedAt = time.Unix(res.Iat, 0)
+	}
+	return identity
+}
+
+// WithSessionIdentity sets how requests are mapped to users and sessions for session
+// invalidation. By default the subject of the bearer token is resolved through token
+// introspection (see WithIntrospection).
+func WithSessionIdentity(fn SessionIdentityFunc) ServerOptionFunc {
+	return func(srv *Server) error {
+		if fn == nil {
+			return fmt.Errorf("session identity function must not be nil")
+		}
+		srv.Sessions().identity = fn
+		return nil
+	}
+}
+
+// InvalidateSubject terminates all sessions of a user: credentials issued before now are
+// rejected and the user's open SSE and WebSocket connections are closed.
+// Returns the number of connections closed.
+func (m *SessionManager) InvalidateSubject(subject string) int {
+	if subject == "" {
+		return 0
+	}
+	m.mu.Lock()
+	m.prune(time.Now())
+	m.subjects[subject] = time.Now()
+	m.mu.Unlock()
+
+	closed := m.closeConnections(func(id SessionIdentity) bool { return id.Subject == subject })
+	logger.Info("Sessions invalidated", "subject", subject, "connections_closed", closed)
+	return closed
+}
+
+// InvalidateSession terminates a single identity provider session by its session ID.
+// Returns the number of connections closed.
+func (m *SessionManager) InvalidateSession(sessionID string) int {
+	if sessionID == "" {
+		return 0
+	}
+	m.mu.Lock()
+	m.prune(time.Now())
+	m.sessionIDs[sessionID] = time.Now()
+	m.mu.Unlock()
+
+	closed := m.closeConnections(func(id SessionIdentity) bool { return id.SessionID == sessionID })
+	logger.Info("Session invalidated", "sid", sessionID, "connections_closed", closed)
+	return closed
+}
+
+// logout terminates the session named by a back-channel logout token. A token naming
+// both a session and a subject also terminates the subject's credentials that carry no
+// session ID, since those cannot be told apart from the session that logged out.
+func (m *SessionManager) logout(claims LogoutClaims) {
+	if claims.SessionID == "" {
+		m.InvalidateSubject(claims.Subject)
+		return
+	}
+	m.InvalidateSession(claims.SessionID)
+	if claims.Subject == "" {
+		return
+	}
+	m.mu.Lock()
+	m.sessionless[claims.Subject] = time.Now()
+	m.mu.Unlock()
+	m.closeConnections(func(id SessionIdentity) bool { return id.Subject == claims.Subject && id.SessionID == "" })
+}
+
+// Valid reports whether the identity belongs to a session that has not been terminated.
+// Credentials without an issue time are rejected for the whole retention period after
+// their subject was invalidated.
+func (m *SessionManager) Valid(id SessionIdentity) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id.SessionID != "" {
+		if _, ok := m.sessionIDs[id.SessionID]; ok {
+			return false
+		}
+	} else if at, ok := m.sessionless[id.Subject]; ok && id.Subject != "" && m.issuedBefore(id, at) {
+		return false
+	}
+	if at, ok := m.subjects[id.Subject]; ok && id.Subject != "" {
+		return !m.issuedBefore(id, at)
+	}
+	return true
+}
+
+// issuedBefore reports whether the credential of id predates an invalidation at at that
+// is still within the retention period. Callers hold m.mu.
+func (m *SessionManager) issuedBefore(id SessionIdentity, at time.Time) bool {
+	if time.Since(at) > m.retention {
+		return false
+	}
+	return id.IssuedAt.IsZero() || !id.IssuedAt.After(at)
+}
+
+// prune forgets invalidations older than the retention period. Callers hold m.mu.
+func (m *SessionManager) prune(now time.Time) {
+	for subject, at := range m.subjects {
+		if now.Sub(at) > m.retention {
+			delete(m.subjects, subject)
+		}
+	}
+	for sid, at := range m.sessionIDs {
+		if now.Sub(at) > m.retention {
+			delete(m.sessionIDs, sid)
+		}
+	}
+	for subject, at := range m.sessionless {
+		if now.Sub(at) > m.retention {
+			delete(m.sessionless, subject)
+		}
+	}
+}
+
+func (m *SessionManager) track(c *trackedConnection) func() {
+	key := c.identity.Subject + "\x00" + c.identity.SessionID
+	m.mu.Lock()
+	if m.connections[key] == nil {
+		m.connections[key] = make(map[*trackedConnection]struct{})
+	}
+	m.connections[key][c] = struct{}{}
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		delete(m.connections[key], c)
+		if len(m.connections[key]) == 0 {
+			delete(m.connections, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *SessionManager) closeConnections(match func(SessionIdentity) bool) int {
+	var matched []*trackedConnection
+	m.mu.Lock()
+	for _, conns := range m.connections {
+		for c := range conns {
+			if match(c.identity) {
+				matched = append(matched, c)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, c := range matched {
+		c.close()
+	}
+	return len(matched)
+}
+
+// isLongLived reports whether the request opens an SSE stream or a WebSocket.
+func isLongLived(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// SessionMiddleware returns a middleware function that rejects requests from terminated
+// sessions and tracks SSE and WebSocket connections so they can be closed on invalidation.
+// It is installed automatically by srv.Sessions().
+func SessionMiddleware(srv *Server) MiddlewareFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			m := srv.sessions
+			id := m.identity(r)
+			if id.Subject == "" && id.SessionID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !m.Valid(id) {
+				Annotate(r, "session", "terminated")
+				writeErrorResponse(w, http.StatusUnauthorized, "Session has been terminated")
+				return
+			}
+			if !isLongLived(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			c := &trackedConnection{identity: id, cancel: cancel}
+			defer m.track(c)()
+			next.ServeHTTP(&sessionResponseWriter{ResponseWriter: w, conn: c}, r.WithContext(ctx))
+		}
+	}
+}
+
+// sessionResponseWriter records hijacked connections so they can be closed.
+type sessionResponseWriter struct {
+	http.ResponseWriter
+	conn *trackedConnection
+}
+
+func (w *sessionResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *sessionResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.conn.mu.Lock()
+		w.conn.conn = conn
+		w.conn.mu.Unlock()
+	}
+	return conn, rw, err
+}
+
+func (w *sessionResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WithBackChannelLogout serves an OpenID Connect Back-Channel Logout endpoint at path.
+// The identity provider POSTs a logout token, which is checked by verify; the subject or
+// session it names is then invalidated via srv.Sessions(). A token naming a session also
+// ends the subject's credentials whose identity has no session ID, such as tokens whose
+// introspection response lacks "sid".
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithIntrospection(introspectionConfig),
+//		server.WithBackChannelLogout("/oidc/backchannel-logout", verifyLogoutToken),
+//	)
+func WithBackChannelLogout(path string, verify LogoutTokenVerifier) ServerOptionFunc {
+	return func(srv *Server) error {
+		if verify == nil {
+			return fmt.Errorf("back-channel logout requires a logout token verifier")
+		}
+		sessions := srv.Sessions()
+		srv.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+			token := r.PostFormValue("logout_token")
+			if token == "" {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid_request: missing logout_token")
+				return
+			}
+			claims, err := verify(r.Context(), token)
+			if err != nil {
+				logger.Warn("Rejected back-channel logout token", "error", err)
+				writeErrorResponse(w, http.StatusBadRequest, "invalid_request: logout token rejected")
+				return
+			}
+			if claims.Subject == "" && claims.SessionID == "" {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid_request: logout token has neither sub nor sid")
+				return
+			}
+			sessions.logout(claims)
+			w.WriteHeader(http.StatusOK)
+		})
+		return nil
+	}
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// headerIdentity maps the X-User and X-Sid headers to a session identity issued at the
+// time given in X-Issued-At (Unix seconds).
+func headerIdentity(r *http.Request) SessionIdentity {
+	id := SessionIdentity{Subject: r.Header.Get("X-User"), SessionID: r.Header.Get("X-Sid")}
+	if iat := r.Header.Get("X-Issued-At"); iat != "" {
+		t, _ := time.Parse(time.RFC3339Nano, iat)
+		id.IssuedAt = t
+	}
+	return id
+}
+
+func sessionRequest(user, sid string, issuedAt time.Time) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-User", user)
+	if sid != "" {
+		req.Header.Set("X-Sid", sid)
+	}
+	if !issuedAt.IsZero() {
+		req.Header.Set("X-Issued-At", issuedAt.Format(time.RFC3339Nano))
+	}
+	return req
+}
+
+func TestSessionsInvalidateSubject(t *testing.T) {
+	srv, err := NewServer(WithSessionIdentity(headerIdentity))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+	srv.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.middleware.applyToMux(srv.mux)
+
+	serve := func(req *http.Request) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	issued := time.Now().Add(-time.Minute)
+	if code := serve(sessionRequest("alice", "", issued)); code != http.StatusOK {
+		t.Fatalf("expected 200 before invalidation, got %d", code)
+	}
+
+	srv.Sessions().InvalidateSubject("alice")
+
+	if code := serve(sessionRequest("alice", "", issued)); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for credential issued before invalidation, got %d", code)
+	}
+	if code := serve(sessionRequest("alice", "", time.Time{})); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for credential without issue time, got %d", code)
+	}
+	if code := serve(sessionRequest("alice", "", time.Now().Add(time.Second))); code != http.StatusOK {
+		t.Errorf("expected 200 for credential issued after invalidation, got %d", code)
+	}
+	if code := serve(sessionRequest("bob", "", issued)); code != http.StatusOK {
+		t.Errorf("expected 200 for other subject, got %d", code)
+	}
+
+	srv.Sessions().InvalidateSession("sid-1")
+	if code := serve(sessionRequest("bob", "sid-1", issued)); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for invalidated session ID, got %d", code)
+	}
+}
+
+func TestSessionsCloseStreams(t *testing.T) {
+	srv, err := NewServer(WithSessionIdentity(headerIdentity))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	connected := make(chan struct{})
+	srv.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		close(connected)
+		<-r.Context().Done()
+	})
+	ts := httptest.NewServer(srv.middleware.applyToMux(srv.mux))
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-User", "alice")
+	done := make(chan error, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			_, err = resp.Body.Read(make([]byte, 1))
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	select {
+	case <-connected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not connect")
+	}
+	if n := srv.Sessions().InvalidateSubject("alice"); n != 1 {
+		t.Errorf("expected 1 connection closed, got %d", n)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream was not closed after invalidation")
+	}
+}
+
+func TestBackChannelLogout(t *testing.T) {
+	verify := func(ctx context.Context, token string) (LogoutClaims, error) {
+		if sub, ok := strings.CutPrefix(token, "valid:"); ok {
+			return LogoutClaims{Subject: sub}, nil
+		}
+		return LogoutClaims{}, errors.New("bad signature")
+	}
+	srv, err := NewServer(
+		WithSessionIdentity(headerIdentity),
+		WithBackChannelLogout("/logout/backchannel", verify),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	post := func(token string) *httptest.ResponseRecorder {
+		form := url.Values{"logout_token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/logout/backchannel", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("forged"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for rejected token, got %d", rec.Code)
+	}
+	rec := post("valid:alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("expected Cache-Control: no-store")
+	}
+	if srv.Sessions().Valid(SessionIdentity{Subject: "alice", IssuedAt: time.Now().Add(-time.Minute)}) {
+		t.Error("expected alice's sessions to be invalidated")
+	}
+}
+
+func TestBackChannelLogoutWithIntrospection(t *testing.T) {
+	var calls atomic.Int32
+	issued := time.Now().Add(-time.Minute).Unix()
+	ts := newIntrospectionEndpoint(t, &calls, func(w http.ResponseWriter, token string) {
+		sub, sid, _ := strings.Cut(token, ":")
+		json.NewEncoder(w).Encode(map[string]any{"active": true, "sub": sub, "sid": sid, "iat": issued})
+	})
+	verify := func(ctx context.Context, token string) (LogoutClaims, error) {
+		sub, sid, _ := strings.Cut(token, ":")
+		return LogoutClaims{Subject: sub, SessionID: sid}, nil
+	}
+	srv, err := NewServer(
+		WithIntrospection(IntrospectionConfig{Endpoint: ts.URL, ClientID: "gateway", ClientSecret: "s3cret"}),
+		WithBackChannelLogout("/logout/backchannel", verify),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+	srv.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {})
+	handler := srv.middleware.applyToMux(srv.mux)
+	serve := func(method, path, token string, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	logout := func(token string) {
+		t.Helper()
+		if code := serve(http.MethodPost, "/logout/backchannel", "", url.Values{"logout_token": {token}}.Encode()); code != http.StatusOK {
+			t.Fatalf("logout failed with %d", code)
+		}
+	}
+
+	// The introspection response carries the session ID
+	logout("alice:sid-1")
+	if code := serve(http.MethodGet, "/api", "alice:sid-1", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for the logged out session, got %d", code)
+	}
+	if code := serve(http.MethodGet, "/api", "alice:sid-2", ""); code != http.StatusOK {
+		t.Errorf("expected 200 for another session of the subject, got %d", code)
+	}
+
+	// Without a session ID, the subject's credentials end
+	logout("bob:sid-9")
+	if code := serve(http.MethodGet, "/api", "bob", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a credential without sid, got %d", code)
+	}
+}
+
+func TestSessionsEnforcementIsEnabledBeforeStart(t *testing.T) {
+	srv, err := NewServer(WithSessions())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.stopCleanup()
+	installed := len(srv.middleware.middleware[GlobalMiddlewareRoute])
+
+	running, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer running.stopCleanup()
+	before := len(running.middleware.middleware[GlobalMiddlewareRoute])
+	if installed != before+1 {
+		t.Errorf("expected WithSessions to install the session middleware, got %d and %d middleware", installed, before)
+	}
+	running.isRunning.Store(true)
+	if running.Sessions() == nil {
+		t.Fatal("expected a session manager")
+	}
+	if after := len(running.middleware.middleware[GlobalMiddlewareRoute]); after != before {
+		t.Errorf("expected no middleware to be registered while running, got %d, want %d", after, before)
+	}
+}
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Windows over which SLOs are evaluated. Compliance and the burn rates cover the long
+// window; the short window tells a current burn from one that has already stopped.
+const (
+	sloShortWindow = 5 * time.Minute
+	sloLongWindow  = time.Hour
+)
+
+// SLO is a service level objective over a group of routes, evaluated against the requests
+// of the last hour. Per-tenant objectives filter on metrics labels, e.g. a "tenant"
+// annotation promoted with WithMetricsLabel.
+type SLO struct {
+	Name string `json:"name"`
+	// Routes are route patterns as registered, or path prefixes ending in "*" such as
+	// "/api/*". Empty matches every route except unmatched requests.
+	Routes []string          `json:"routes,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"` // Metrics labels the requests must carry
+	// LatencyP99 is the target 99th percentile latency, 0 for no latency objective.
+	LatencyP99 time.Duration `json:"latency_p99,omitempty"`
+	// ErrorRate is the highest acceptable fraction of 5xx responses, e.g. 0.001 for 99.9%
+	// availability, 0 for no availability objective.
+	ErrorRate float64 `json:"error_rate,omitempty"`
+}
+
+func (s *SLO) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("SLO requires a name")
+	}
+	if s.LatencyP99 < 0 {
+		return fmt.Errorf("SLO %q: latency target must not be negative", s.Name)
+	}
+	if s.ErrorRate < 0 || s.ErrorRate >= 1 {
+		return fmt.Errorf("SLO %q: error rate must be in [0, 1), got %g", s.Name, s.ErrorRate)
+	}
+	if s.LatencyP99 == 0 && s.ErrorRate == 0 {
+		return fmt.Errorf("SLO %q: set a latency or error rate target", s.Name)
+	}
+	return nil
+}
+
+// matches reports whether a metrics series belongs to the SLO.
+func (s *SLO) matches(labels metricLabels) bool {
+	for k, v := range s.Labels {
+		if labels.Extra[k] != v {
+			return false
+		}
+	}
+	if len(s.Routes) == 0 {
+		return labels.Route != MetricsUnmatchedRoute
+	}
+	path := labels.Route
+	if i := strings.IndexByte(path, ' '); i >= 0 {
+		path = path[i+1:] // "GET /users/{id}" -> "/users/{id}"
+	}
+	for _, route := range s.Routes {
+		if prefix, ok := strings.CutSuffix(route, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if route == labels.Route || route == path {
+			return true
+		}
+	}
+	return false
+}
+
+// SLOStatus is the compliance of an SLO over the last hour. A burn rate is the observed
+// share of failing requests divided by the share the objective allows: 1 consumes the
+// error budget exactly, above 1 violates the objective. The short burn rates cover the
+// last five minutes; alerting when both burn rates are high catches fast burns quickly
+// and stops once they end.
+type SLOStatus struct {
+	Name      string        `json:"name"`
+	Compliant bool          `json:"compliant"`
+	Window    time.Duration `json:"window"`
+	Requests  uint64        `json:"requests"`
+
+	LatencyTarget    time.Duration `json:"latency_target,omitempty"`
+	P99              time.Duration `json:"p99"`
+	SlowRatio        float64       `json:"slow_ratio"` // Share of requests slower than LatencyTarget
+	LatencyBurn      float64       `json:"latency_burn,omitempty"`
+	LatencyBurnShort float64       `json:"latency_burn_short,omitempty"`
+
+	ErrorRateTarget float64 `json:"error_rate_target,omitempty"`
+	ErrorRate       float64 `json:"error_rate"`
+	ErrorBurn       float64 `json:"error_burn,omitempty"`
+	ErrorBurnShort  float64 `json:"error_burn_short,omitempty"`
+}
+
+// WithSLO registers service level objectives. Their compliance is reported by
+// srv.SLOStatus(), the /healthz/?verbose=1 payload of the health server, and the
+// slo://server/status MCP resource. SLOs can also be set via "slos" in options.json.
+// Reporting never fails a health probe; alert on the compliant flag or the burn rates.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithMetricsLabel("tenant", "acme", "globex"),
+//		server.WithSLO(
+//			server.SLO{Name: "api", Routes: []string{"/api/*"}, LatencyP99: 300 * time.Millisecond, ErrorRate: 0.001},
+//			server.SLO{Name: "acme-checkout", Routes: []string{"POST /checkout"}, Labels: map[string]string{"tenant": "acme"}, ErrorRate: 0.0001},
+//		),
+//	)
+func WithSLO(slos ...SLO) ServerOptionFunc {
+	return func(srv *Server) error {
+		for i := range slos {
+			if err := slos[i].validate(); err != nil {
+				return err
+			}
+		}
+		srv.Options.SLOs = append(srv.Options.SLOs, slos...)
+		return nil
+	}
+}
+
+// SLOStatus evaluates every registered SLO against the requests of the last hour.
+func (srv *Server) SLOStatus() []SLOStatus {
+	if srv.Options == nil || len(srv.Options.SLOs) == 0 {
+		return nil
+	}
+	out := make([]SLOStatus, 0, len(srv.Options.SLOs))
+	for i := range srv.Options.SLOs {
+		out = append(out, srv.slos.evaluate(i, &srv.Options.SLOs[i]))
+	}
+	return out
+}
+
+// sloBucket holds the requests matching an SLO in one minute.
+type sloBucket struct {
+	minute       int64
+	latency      latencyHistogram
+	serverErrors uint64
+}
+
+// sloStore records the requests matching each SLO in one-minute buckets spanning the long
+// window, so that requests age out of the evaluation.
+type sloStore struct {
+	mu      sync.Mutex
+	buckets [][sloLongWindow / time.Minute]sloBucket // Per SLO, indexed by minute
+	now     func() time.Time
+}
+
+func newSLOStore(slos int) *sloStore {
+	return &sloStore{buckets: make([][sloLongWindow / time.Minute]sloBucket, slos), now: time.Now}
+}
+
+// record adds a request to the buckets of the SLOs it matches.
+func (s *sloStore) record(slos []SLO, labels metricLabels, status int, duration time.Duration) {
+	if s == nil {
+		return
+	}
+	minute := s.now().Unix() / 60
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range min(len(slos), len(s.buckets)) {
+		if !slos[i].matches(labels) {
+			continue
+		}
+		b := &s.buckets[i][minute%int64(len(s.buckets[i]))]
+		if b.minute != minute {
+			*b = sloBucket{minute: minute}
+		}
+		b.latency.observe(duration)
+		if status >= 500 {
+			b.serverErrors++
+		}
+	}
+}
+
+// window sums the buckets of SLO i for the window ending with the current minute.
+func (s *sloStore) window(i int, window time.Duration) (hist latencyHistogram, serverErrors uint64) {
+	if s == nil || i >= len(s.buckets) {
+		return hist, 0
+	}
+	minute := s.now().Unix() / 60
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for j := range s.buckets[i] {
+		b := &s.buckets[i][j]
+		if age := minute - b.minute; age >= 0 && age < int64(window/time.Minute) {
+			hist.merge(&b.latency)
+			serverErrors += b.serverErrors
+		}
+	}
+	return hist, serverErrors
+}
+
+// evaluate reports the compliance of SLO i over the long window and its burn rates over
+// both windows.
+func (s *sloStore) evaluate(i int, slo *SLO) SLOStatus {
+	hist, serverErrors := s.window(i, sloLongWindow)
+	status := SLOStatus{
+		Name:            slo.Name,
+		Compliant:       true,
+		Window:          sloLongWindow,
+		Requests:        hist.count,
+		LatencyTarget:   slo.LatencyP99,
+		P99:             hist.quantile(0.99),
+		ErrorRateTarget: slo.ErrorRate,
+	}
+	if hist.count == 0 {
+		return status
+	}
+	short, shortErrors := s.window(i, sloShortWindow)
+	if slo.LatencyP99 > 0 {
+		status.SlowRatio = float64(hist.countAbove(slo.LatencyP99)) / float64(hist.count)
+		status.LatencyBurn = roundBurn(status.SlowRatio / 0.01)
+		if short.count > 0 {
+			status.LatencyBurnShort = roundBurn(float64(short.countAbove(slo.LatencyP99)) / float64(short.count) / 0.01)
+		}
+		status.Compliant = status.P99 <= slo.LatencyP99
+	}
+	status.ErrorRate = float64(serverErrors) / float64(hist.count)
+	if slo.ErrorRate > 0 {
+		status.ErrorBurn = roundBurn(status.ErrorRate / slo.ErrorRate)
+		if short.count > 0 {
+			status.ErrorBurnShort = roundBurn(float64(shortErrors) / float64(short.count) / slo.ErrorRate)
+		}
+		status.Compliant = status.Compliant && status.ErrorRate <= slo.ErrorRate
+	}
+	return status
+}
+
+func roundBurn(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// writeVerboseHealth writes the health payload including SLO compliance.
+func (srv *Server) writeVerboseHealth(w http.ResponseWriter, healthy bool) {
+	status, code := "ok", http.StatusOK
+	if !healthy {
+		status, code = "unhealthy", http.StatusServiceUnavailable
+	}
+	slos := srv.SLOStatus()
+	if slos == nil {
+		slos = []SLOStatus{}
+	}
+	compliant := true
+	for _, s := range slos {
+		compliant = compliant && s.Compliant
+	}
+	payload := map[string]interface{}{
+		"status":        status,
+		"slo_compliant": compliant,
+		"slos":          slos,
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	if srv.gateway != nil {
+		payload["upstreams"] = srv.GatewayUpstreamStatus()
+	}
+	if !srv.serverStart.IsZero() {
+		payload["uptime_seconds"] = int(time.Since(srv.serverStart).Seconds())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		logger.Error("error writing verbose health status", "error", err)
+	}
+}
+
+// SLOResource implements MCPResource for SLO compliance.
+type SLOResource struct {
+	server *Server
+}
+
+// NewSLOResource creates a new SLO compliance resource.
+func NewSLOResource(srv *Server) *SLOResource {
+	return &SLOResource{server: srv}
+}
+
+func (r *SLOResource) URI() string {
+	return "slo://server/status"
+}
+
+func (r *SLOResource) CacheTTL() time.Duration {
+	return 0
+}
+
+func (r *SLOResource) Name() string {
+	return "SLO Status"
+}
+
+func (r *SLOResource) Description() string {
+	return "Compliance and error budget burn rates of the configured service level objectives"
+}
+
+func (r *SLOResource) MimeType() string {
+	return "application/json"
+}
+
+func (r *SLOResource) Read() (interface{}, error) {
+	slos := r.server.SLOStatus()
+	if slos == nil {
+		slos = []SLOStatus{}
+	}
+	return map[string]interface{}{
+		"slos":      slos,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+func (r *SLOResource) List() ([]string, error) {
+	return []string{r.URI()}, nil
+}
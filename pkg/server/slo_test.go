@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSLOStatus(t *testing.T) {
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithMetricsLabel("tenant", "acme", "globex"),
+		WithSLO(
+			SLO{Name: "api", Routes: []string{"/api/*"}, LatencyP99: time.Minute, ErrorRate: 0.2},
+			SLO{Name: "acme", Labels: map[string]string{"tenant": "acme"}, ErrorRate: 0.01},
+			SLO{Name: "status", Routes: []string{"GET /status"}, LatencyP99: time.Nanosecond},
+		),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.HandleFunc("GET /api/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.URL.Query().Get("tenant")
+		Annotate(r, "tenant", tenant)
+		if r.PathValue("id") == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	srv.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := srv.Handler()
+	for _, target := range []string{
+		"/api/orders/0?tenant=acme", "/api/orders/1?tenant=acme", "/api/orders/2?tenant=globex",
+		"/api/orders/3?tenant=globex", "/api/orders/4?tenant=globex", "/status", "/missing",
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	statuses := make(map[string]SLOStatus)
+	for _, s := range srv.SLOStatus() {
+		statuses[s.Name] = s
+	}
+
+	api := statuses["api"]
+	if api.Requests != 5 || api.ErrorRate != 0.2 || !api.Compliant || api.ErrorBurn != 1 {
+		t.Errorf("api status = %+v", api)
+	}
+	acme := statuses["acme"]
+	if acme.Requests != 2 || acme.ErrorRate != 0.5 || acme.Compliant || acme.ErrorBurn != 50 {
+		t.Errorf("acme status = %+v", acme)
+	}
+	status := statuses["status"]
+	if status.Requests != 1 || status.Compliant || status.SlowRatio != 1 || status.LatencyBurn != 100 {
+		t.Errorf("status status = %+v", status)
+	}
+}
+
+func TestSLOStatusWindows(t *testing.T) {
+	slos := []SLO{{Name: "api", ErrorRate: 0.1}}
+	store := newSLOStore(len(slos))
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+
+	// An outage half an hour ago, healthy traffic since
+	for range 10 {
+		store.record(slos, metricLabels{Route: "/api"}, http.StatusInternalServerError, time.Millisecond)
+	}
+	now = now.Add(30 * time.Minute)
+	for range 10 {
+		store.record(slos, metricLabels{Route: "/api"}, http.StatusOK, time.Millisecond)
+	}
+	status := store.evaluate(0, &slos[0])
+	if status.Requests != 20 || status.ErrorRate != 0.5 || status.ErrorBurn != 5 || status.ErrorBurnShort != 0 || status.Compliant {
+		t.Errorf("expected the outage to burn the long window only, got %+v", status)
+	}
+
+	now = now.Add(45 * time.Minute)
+	if status := store.evaluate(0, &slos[0]); status.Requests != 10 || status.ErrorBurn != 0 || !status.Compliant {
+		t.Errorf("expected the outage to age out of the long window, got %+v", status)
+	}
+	now = now.Add(time.Hour)
+	if status := store.evaluate(0, &slos[0]); status.Requests != 0 || !status.Compliant {
+		t.Errorf("expected no requests in the window, got %+v", status)
+	}
+}
+
+func TestHealthzVerboseReportsSLOs(t *testing.T) {
+	srv, err := NewServer(WithSLO(SLO{Name: "all", ErrorRate: 0.01}))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.isRunning.Store(true)
+
+	rec := httptest.NewRecorder()
+	srv.healthzHandler(rec, httptest.NewRequest(http.MethodGet, "/healthz/?verbose=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var payload struct {
+		Status       string      `json:"status"`
+		SLOCompliant bool        `json:"slo_compliant"`
+		SLOs         []SLOStatus `json:"slos"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid payload %q: %v", rec.Body.String(), err)
+	}
+	if payload.Status != "ok" || !payload.SLOCompliant || len(payload.SLOs) != 1 || payload.SLOs[0].Name != "all" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+
+	content, err := NewSLOResource(srv).Read()
+	if err != nil {
+		t.Fatalf("resource read failed: %v", err)
+	}
+	if slos := content.(map[string]interface{})["slos"].([]SLOStatus); len(slos) != 1 {
+		t.Errorf("resource returned %d SLOs, want 1", len(slos))
+	}
+}
+
+func TestWithSLORejectsInvalidObjectives(t *testing.T) {
+	for _, slo := range []SLO{
+		{ErrorRate: 0.1},
+		{Name: "none"},
+		{Name: "rate", ErrorRate: 1.5},
+	} {
+		if _, err := NewServer(WithSLO(slo)); err == nil {
+			t.Errorf("expected error for %+v", slo)
+		}
+	}
+}
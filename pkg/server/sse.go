@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSSEClientDisconnected is returned by WriteSSE when the event cannot be written
+// because the client went away.
+var ErrSSEClientDisconnected = errors.New("SSE client disconnected")
+
+// SSEEvent is a Server-Sent Event with all fields of the event stream format. Unlike
+// SSEMessage it carries an ID, which browsers send back as Last-Event-ID when they
+// reconnect, and a retry interval that sets their reconnection delay.
+type SSEEvent struct {
+	ID    string        // Event ID, omitted when empty
+	Event string        // Event type, browsers dispatch events without one as "message"
+	Retry time.Duration // Reconnection delay sent to the client, omitted when zero
+	Data  any           // Payload, JSON-encoded unless it is a string or []byte
+}
+
+// Validate reports whether the event can be encoded. Line breaks in ID or Event would
+// start new fields, and browsers ignore IDs containing NUL.
+func (e *SSEEvent) Validate() error {
+	if strings.ContainsAny(e.ID, "\r\n\x00") {
+		return fmt.Errorf("SSE event ID must not contain line breaks or NUL: %q", e.ID)
+	}
+	if strings.ContainsAny(e.Event, "\r\n") {
+		return fmt.Errorf("SSE event type must not contain line breaks: %q", e.Event)
+	}
+	if e.Retry < 0 {
+		return fmt.Errorf("SSE retry must not be negative: %s", e.Retry)
+	}
+	return nil
+}
+
+// Encode validates the event and formats it for the event stream. Multi-line payloads are
+// split into one data line per line, so they reach the client unchanged.
+func (e *SSEEvent) Encode() ([]byte, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	payload, err := fanoutPayload(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("encode SSE data: %w", err)
+	}
+	frame, err := encodeSSEFrame("", e.Event, payload)
+	if err != nil {
+		return nil, err
+	}
+	var head []byte
+	if e.ID != "" {
+		head = append(head, "id: "+e.ID+"\n"...)
+	}
+	if e.Retry > 0 {
+		head = append(head, "retry: "+strconv.FormatInt(e.Retry.Milliseconds(), 10)+"\n"...)
+	}
+	return append(head, frame...), nil
+}
+
+// WriteSSE writes an event to an event stream and flushes it to the client. Encoding
+// errors are returned before anything is written; failed writes return an error wrapping
+// ErrSSEClientDisconnected, after which the handler should return. The caller sets the
+// event stream headers before the first event.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "text/event-stream")
+//	w.Header().Set("Cache-Control", "no-cache")
+//	for update := range updates {
+//		if err := server.WriteSSE(w, &server.SSEEvent{ID: update.ID, Event: "update", Data: update}); err != nil {
+//			return
+//		}
+//	}
+func WriteSSE(w http.ResponseWriter, event *SSEEvent) error {
+	if event == nil {
+		return errors.New("SSE event must not be nil")
+	}
+	frame, err := event.Encode()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(frame); err != nil {
+		return fmt.Errorf("%w: %w", ErrSSEClientDisconnected, err)
+	}
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrSSEClientDisconnected, err)
+	}
+	return nil
+}
@@ -0,0 +1,467 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultSSEBrokerQueueSize is the number of events buffered per client by default.
+const defaultSSEBrokerQueueSize = 64
+
+// defaultSSEHeartbeat is how often idle SSE streams get a keepalive comment by default,
+// well below the 60s idle timeout of common proxies such as nginx and AWS ALB.
+const defaultSSEHeartbeat = 15 * time.Second
+
+// defaultSSEReplayTTL is how long events stay in the replay buffer by default.
+const defaultSSEReplayTTL = 5 * time.Minute
+
+// sseHeartbeatFrame is a comment line, which EventSource ignores.
+var sseHeartbeatFrame = []byte(": keepalive\n\n")
+
+// SSEBackpressure selects what an SSEBroker does when a client's queue is full because
+// the client reads slower than events are published.
+type SSEBackpressure string
+
+const (
+	// SSEDropOldest discards the oldest queued event to make room. This is the default,
+	// suited to streams where the latest state matters most.
+	SSEDropOldest SSEBackpressure = "drop_oldest"
+	// SSEDropNewest discards the event being published.
+	SSEDropNewest SSEBackpressure = "drop_newest"
+	// SSEDisconnect closes the client's stream, so that it reconnects and resynchronises
+	// instead of silently missing events.
+	SSEDisconnect SSEBackpressure = "disconnect"
+)
+
+// SSEBrokerConfig configures an SSEBroker.
+type SSEBrokerConfig struct {
+	QueueSize    int             `json:"queue_size,omitempty"`    // Events buffered per client (default 64)
+	Backpressure SSEBackpressure `json:"backpressure,omitempty"`  // What to do when a queue is full (default drop_oldest)
+	WriteTimeout time.Duration   `json:"write_timeout,omitempty"` // Per-write deadline before a client is dropped (default 5s)
+	ReplayBuffer int             `json:"replay_buffer,omitempty"` // Events kept per topic for clients resuming with Last-Event-ID, 0 disables
+	ReplayTTL    time.Duration   `json:"replay_ttl,omitempty"`    // How long events are kept for replay (default 5m)
+	Heartbeat    time.Duration   `json:"heartbeat,omitempty"`     // Interval of keepalive comments (default 15s, negative disables)
+}
+
+// SSETopicSelector returns the topics the client of r subscribes to. Returning an error
+// rejects the client with 403 Forbidden, so selectors also decide who may read a topic.
+type SSETopicSelector func(r *http.Request) ([]string, error)
+
+// SSETopics subscribes every client to the given topics.
+func SSETopics(topics ...string) SSETopicSelector {
+	return func(*http.Request) ([]string, error) {
+		return topics, nil
+	}
+}
+
+// SSETopicsFromQuery subscribes clients to the topics named by a query parameter, such
+// as /events?topic=orders&topic=prices. If allowed is not empty, clients may only
+// subscribe to those topics.
+func SSETopicsFromQuery(param string, allowed ...string) SSETopicSelector {
+	return func(r *http.Request) ([]string, error) {
+		topics := r.URL.Query()[param]
+		if len(allowed) > 0 {
+			for _, topic := range topics {
+				if !slices.Contains(allowed, topic) {
+					return nil, fmt.Errorf("topic %q is not allowed", topic)
+				}
+			}
+		}
+		return topics, nil
+	}
+}
+
+// SSEBroker streams events published to named topics to SSE clients. Each client has a
+// bounded send queue drained by its own handler goroutine, so Publish never waits for a
+// slow client; a full queue is handled according to the configured SSEBackpressure.
+//
+// Every event gets an ID that increases across all topics. With a replay buffer, a client
+// that reconnects with Last-Event-ID, as EventSource does automatically, first receives
+// the buffered events it missed. Events that already left the buffer are lost, and so
+// are events older than the replay TTL; buffers of topics without recent events are freed.
+//
+// Streams get a keepalive comment every heartbeat interval, so that proxies do not close
+// them while no events are published. The comment is written with the same deadline as
+// events, so clients that went away without closing the connection are dropped.
+//
+// Example:
+//
+//	broker := server.NewSSEBroker()
+//	srv.HandleSSE("/events", broker, server.SSETopicsFromQuery("topic", "orders", "prices"))
+//
+//	go func() {
+//		for order := range orders {
+//			broker.Publish("orders", &server.SSEMessage{Event: "order", Data: order})
+//		}
+//	}()
+type SSEBroker struct {
+	queueSize    int
+	backpressure SSEBackpressure
+	writeTimeout time.Duration
+	replaySize   int
+	replayTTL    time.Duration
+	heartbeat    time.Duration
+
+	mu     sync.RWMutex
+	topics map[string]map[*sseBrokerClient]struct{}
+	replay map[string][]sseBrokerEvent // Recent events per topic, oldest first
+	lastID uint64
+	pruned time.Time // Last sweep of expired replay events
+	closed chan struct{}
+	once   sync.Once
+	now    func() time.Time
+}
+
+// sseBrokerEvent is an encoded event kept for replay.
+type sseBrokerEvent struct {
+	id    uint64
+	at    time.Time
+	frame []byte
+}
+
+// sseBrokerClient is one connected client and its send queue.
+type sseBrokerClient struct {
+	queue   chan []byte
+	done    chan struct{} // Closed when the broker disconnects the client
+	once    sync.Once
+	dropped atomic.Int64 // Events discarded because the queue was full
+}
+
+func (c *sseBrokerClient) disconnect() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// NewSSEBroker creates a broker. Without a config, queues hold 64 events, the oldest
+// event is dropped when a queue is full, writes time out after 5s, no events are kept
+// for replay, replayed events expire after 5m, and idle streams get a keepalive comment every 15s.
+func NewSSEBroker(cfg ...SSEBrokerConfig) *SSEBroker {
+	var c SSEBrokerConfig
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultSSEBrokerQueueSize
+	}
+	if c.Backpressure == "" {
+		c.Backpressure = SSEDropOldest
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = defaultFanoutWriteTimeout
+	}
+	if c.ReplayTTL <= 0 {
+		c.ReplayTTL = defaultSSEReplayTTL
+	}
+	if c.Heartbeat == 0 {
+		c.Heartbeat = defaultSSEHeartbeat
+	}
+	return &SSEBroker{
+		queueSize:    c.QueueSize,
+		backpressure: c.Backpressure,
+		writeTimeout: c.WriteTimeout,
+		replaySize:   max(c.ReplayBuffer, 0),
+		replayTTL:    c.ReplayTTL,
+		heartbeat:    c.Heartbeat,
+		topics:       make(map[string]map[*sseBrokerClient]struct{}),
+		replay:       make(map[string][]sseBrokerEvent),
+		closed:       make(chan struct{}),
+		now:          time.Now,
+	}
+}
+
+// Publish queues an event for every client subscribed to topic and returns how many
+// clients it was queued for. Data is JSON-encoded unless it is a string or []byte; an
+// empty Event sends the event without an event field, which browsers dispatch as
+// "message".
+func (b *SSEBroker) Publish(topic string, msg *SSEMessage) (int, error) {
+	if msg == nil {
+		return 0, errors.New("SSE message must not be nil")
+	}
+	if strings.ContainsAny(msg.Event, "\r\n") {
+		return 0, fmt.Errorf("SSE event type must not contain line breaks: %q", msg.Event)
+	}
+	payload, err := fanoutPayload(msg.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	// The ID is assigned, the event buffered, and the subscribers taken under one lock,
+	// so a client subscribing concurrently gets the event either replayed or queued
+	b.mu.Lock()
+	b.lastID++
+	frame, _ := encodeSSEFrame(strconv.FormatUint(b.lastID, 10), msg.Event, payload) // the event type is checked above
+	if b.replaySize > 0 {
+		now := b.now()
+		b.pruneReplay(now)
+		events := append(b.replay[topic], sseBrokerEvent{id: b.lastID, at: now, frame: frame})
+		if len(events) > b.replaySize {
+			events = events[len(events)-b.replaySize:]
+		}
+		b.replay[topic] = events
+	}
+	clients := make([]*sseBrokerClient, 0, len(b.topics[topic]))
+	for c := range b.topics[topic] {
+		clients = append(clients, c)
+	}
+	b.mu.Unlock()
+
+	queued := 0
+	for _, c := range clients {
+		if b.enqueue(c, frame) {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// pruneReplay drops expired events from the replay buffers and the buffers of topics
+// left without events, at most once per TTL. The caller holds b.mu.
+func (b *SSEBroker) pruneReplay(now time.Time) {
+	if now.Sub(b.pruned) < b.replayTTL {
+		return
+	}
+	b.pruned = now
+	cutoff := now.Add(-b.replayTTL)
+	for topic, events := range b.replay {
+		// Events are buffered in the order they were published
+		i, _ := slices.BinarySearchFunc(events, cutoff, func(e sseBrokerEvent, t time.Time) int { return e.at.Compare(t) })
+		if i == len(events) {
+			delete(b.replay, topic)
+		} else if i > 0 {
+			b.replay[topic] = slices.Clone(events[i:])
+		}
+	}
+}
+
+// enqueue adds a frame to a client's queue, applying the backpressure policy when the
+// queue is full.
+func (b *SSEBroker) enqueue(c *sseBrokerClient, frame []byte) bool {
+	select {
+	case c.queue <- frame:
+		return true
+	default:
+	}
+	switch b.backpressure {
+	case SSEDisconnect:
+		logger.Debug("Disconnecting slow SSE client", "queue_size", b.queueSize)
+		c.disconnect()
+		return false
+	case SSEDropOldest:
+		select {
+		case <-c.queue:
+			c.dropped.Add(1)
+		default:
+		}
+		select {
+		case c.queue <- frame:
+			return true
+		default:
+		}
+	}
+	c.dropped.Add(1)
+	return false
+}
+
+// Subscribers returns the number of clients subscribed to topic.
+func (b *SSEBroker) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.topics[topic])
+}
+
+// Topics returns the topics that have subscribers.
+func (b *SSEBroker) Topics() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	topics := make([]string, 0, len(b.topics))
+	for topic := range b.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Close ends all streams and rejects new clients. It is safe to call more than once.
+func (b *SSEBroker) Close() {
+	b.once.Do(func() { close(b.closed) })
+}
+
+// subscribe registers a client for topics and returns the buffered events published
+// after lastID, in order.
+func (b *SSEBroker) subscribe(topics []string, lastID uint64) (*sseBrokerClient, [][]byte) {
+	c := &sseBrokerClient{queue: make(chan []byte, b.queueSize), done: make(chan struct{})}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var missed []sseBrokerEvent
+	cutoff := b.now().Add(-b.replayTTL)
+	for _, topic := range topics {
+		if b.topics[topic] == nil {
+			b.topics[topic] = make(map[*sseBrokerClient]struct{})
+		}
+		if _, dup := b.topics[topic][c]; dup {
+			continue
+		}
+		b.topics[topic][c] = struct{}{}
+		if lastID > 0 {
+			for _, e := range b.replay[topic] {
+				if e.id > lastID && !e.at.Before(cutoff) {
+					missed = append(missed, e)
+				}
+			}
+		}
+	}
+	slices.SortFunc(missed, func(a, b sseBrokerEvent) int { return cmp.Compare(a.id, b.id) })
+	frames := make([][]byte, len(missed))
+	for i, e := range missed {
+		frames[i] = e.frame
+	}
+	return c, frames
+}
+
+func (b *SSEBroker) unsubscribe(c *sseBrokerClient, topics []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		delete(b.topics[topic], c)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+	}
+}
+
+// Handler returns a handler that streams the topics chosen by selector to each client. A
+// nil selector rejects every client.
+func (b *SSEBroker) Handler(selector SSETopicSelector) http.HandlerFunc {
+	if selector == nil {
+		selector = func(*http.Request) ([]string, error) {
+			return nil, errors.New("no SSE topic selector configured")
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		topics, err := selector(r)
+		if err != nil {
+			logger.Debug("SSE subscription rejected", "error", err, "remote_addr", r.RemoteAddr)
+			writeErrorResponse(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+		if len(topics) == 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "No topics selected")
+			return
+		}
+		select {
+		case <-b.closed:
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Event stream closed")
+			return
+		default:
+		}
+		b.ServeTopics(w, r, topics...)
+	}
+}
+
+// ServeTopics streams events published to topics to the client until it disconnects, the
+// broker closes, or the client falls behind under the SSEDisconnect policy.
+func (b *SSEBroker) ServeTopics(w http.ResponseWriter, r *http.Request, topics ...string) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		logger.Error("Streaming not supported", "error", err)
+		return
+	}
+
+	// Only IDs issued by this broker are honoured; others resume without replay
+	lastID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	c, missed := b.subscribe(topics, lastID)
+	defer b.unsubscribe(c, topics)
+	for _, frame := range missed {
+		if err := b.write(rc, w, nil, frame); err != nil {
+			logger.Debug("Dropping SSE client during replay", "error", err)
+			return
+		}
+	}
+	var heartbeat <-chan time.Time
+	if b.heartbeat > 0 {
+		ticker := time.NewTicker(b.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-b.closed:
+			return
+		case <-c.done:
+			return
+		case <-heartbeat:
+			if err := b.write(rc, w, nil, sseHeartbeatFrame); err != nil {
+				logger.Debug("Dropping unresponsive SSE client", "error", err)
+				return
+			}
+		case frame := <-c.queue:
+			if err := b.write(rc, w, c, frame); err != nil {
+				logger.Debug("Dropping SSE client", "error", err, "dropped_events", c.dropped.Load())
+				return
+			}
+		}
+	}
+}
+
+// write sends a frame and any further frames queued for c, then flushes once.
+func (b *SSEBroker) write(rc *http.ResponseController, w http.ResponseWriter, c *sseBrokerClient, frame []byte) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(b.writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	for more := true; more; {
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+		if c == nil {
+			break
+		}
+		select {
+		case frame = <-c.queue:
+		default:
+			more = false
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return err
+	}
+	// Idle streams must not be cut off by the server's WriteTimeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// HandleSSE streams the events of broker on pattern, subscribing each client to the
+// topics selector returns. Selectors decide which topics a client may read, so a nil
+// selector rejects every client with 403; pass SSETopicsFromQuery with the allowed topics
+// to let clients choose. The broker is closed when the server shuts down, so that open
+// streams do not hold up graceful shutdown.
+//
+// Example:
+//
+//	broker := server.NewSSEBroker()
+//	srv.HandleSSE("/events", broker, server.SSETopics("news"))
+//	broker.Publish("news", server.NewSSEMessage(headline))
+func (srv *Server) HandleSSE(pattern string, broker *SSEBroker, selector SSETopicSelector) {
+	if selector == nil {
+		logger.Warn("HandleSSE without a topic selector rejects every client", "pattern", pattern)
+	}
+	srv.Handle(pattern, broker.Handler(selector))
+	srv.Options.OnShutdownHooks = append(srv.Options.OnShutdownHooks, func(context.Context) error {
+		broker.Close()
+		return nil
+	})
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForSubscribers waits until topic has n subscribers.
+func waitForSubscribers(t *testing.T, b *SSEBroker, topic string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Subscribers(topic) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers of %s, got %d", n, topic, b.Subscribers(topic))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// readSSEFrame reads lines up to the blank line that ends an event.
+func readSSEFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var frame strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		if line == "\n" {
+			return frame.String()
+		}
+		frame.WriteString(line)
+	}
+}
+
+func TestSSEBrokerPublish(t *testing.T) {
+	b := NewSSEBroker()
+	ts := httptest.NewServer(b.Handler(SSETopicsFromQuery("topic", "orders", "prices")))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?topic=orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	waitForSubscribers(t, b, "orders", 1)
+
+	if n, err := b.Publish("prices", NewSSEMessage(1)); err != nil || n != 0 {
+		t.Fatalf("expected no subscribers of prices, got %d: %v", n, err)
+	}
+	if n, err := b.Publish("orders", &SSEMessage{Event: "order", Data: map[string]int{"id": 7}}); err != nil || n != 1 {
+		t.Fatalf("expected one subscriber of orders, got %d: %v", n, err)
+	}
+	if frame := readSSEFrame(t, bufio.NewReader(resp.Body)); frame != "id: 2\nevent: order\ndata: {\"id\":7}\n" {
+		t.Errorf("unexpected frame %q", frame)
+	}
+}
+
+func TestSSEBrokerRejectsTopics(t *testing.T) {
+	b := NewSSEBroker()
+	handler := b.Handler(SSETopicsFromQuery("topic", "orders"))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/events?topic=admin", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a topic that is not allowed, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without topics, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	b.Handler(nil)(rec, httptest.NewRequest(http.MethodGet, "/events?topic=orders", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without a selector, got %d", rec.Code)
+	}
+}
+
+func TestSSEBrokerBackpressure(t *testing.T) {
+	frames := [][]byte{[]byte("1"), []byte("2"), []byte("3")}
+	tests := []struct {
+		policy       SSEBackpressure
+		queued       []string
+		disconnected bool
+	}{
+		{SSEDropOldest, []string{"2", "3"}, false},
+		{SSEDropNewest, []string{"1", "2"}, false},
+		{SSEDisconnect, []string{"1", "2"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			b := NewSSEBroker(SSEBrokerConfig{QueueSize: 2, Backpressure: tt.policy})
+			c, _ := b.subscribe([]string{"t"}, 0)
+			for _, frame := range frames {
+				b.enqueue(c, frame)
+			}
+			var queued []string
+			for len(c.queue) > 0 {
+				queued = append(queued, string(<-c.queue))
+			}
+			if strings.Join(queued, ",") != strings.Join(tt.queued, ",") {
+				t.Errorf("expected queue %v, got %v", tt.queued, queued)
+			}
+			select {
+			case <-c.done:
+				if !tt.disconnected {
+					t.Error("unexpected disconnect")
+				}
+			default:
+				if tt.disconnected {
+					t.Error("expected the client to be disconnected")
+				}
+			}
+		})
+	}
+}
+
+func TestHandleSSEClosesOnShutdown(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewSSEBroker()
+	srv.HandleSSE("/events", b, SSETopics("news"))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	waitForSubscribers(t, b, "news", 1)
+
+	for _, hook := range srv.Options.OnShutdownHooks {
+		hook(t.Context())
+	}
+	waitForSubscribers(t, b, "news", 0)
+
+	rec := httptest.NewRecorder()
+	b.Handler(SSETopics("news"))(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after close, got %d", rec.Code)
+	}
+}
+
+func TestSSEBrokerReplay(t *testing.T) {
+	b := NewSSEBroker(SSEBrokerConfig{ReplayBuffer: 2})
+	ts := httptest.NewServer(b.Handler(SSETopicsFromQuery("topic")))
+	defer ts.Close()
+
+	for i := 1; i <= 4; i++ {
+		topic := "a"
+		if i == 3 {
+			topic = "other"
+		}
+		b.Publish(topic, NewSSEMessage(i))
+	}
+
+	// The buffer of topic a holds events 2 and 4; event 3 belongs to another topic
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"?topic=a", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	for _, want := range []string{"id: 2\nevent: message\ndata: 2\n", "id: 4\nevent: message\ndata: 4\n"} {
+		if frame := readSSEFrame(t, events); frame != want {
+			t.Errorf("expected replayed frame %q, got %q", want, frame)
+		}
+	}
+
+	// Live events follow the replay
+	waitForSubscribers(t, b, "a", 1)
+	b.Publish("a", NewSSEMessage(5))
+	if frame := readSSEFrame(t, events); frame != "id: 5\nevent: message\ndata: 5\n" {
+		t.Errorf("unexpected live frame %q", frame)
+	}
+}
+
+func TestSSEBrokerReplayExpires(t *testing.T) {
+	now := time.Now()
+	b := NewSSEBroker(SSEBrokerConfig{ReplayBuffer: 10, ReplayTTL: time.Minute})
+	b.now = func() time.Time { return now }
+
+	b.Publish("idle", NewSSEMessage(1))
+	b.Publish("busy", NewSSEMessage(2))
+	now = now.Add(45 * time.Second)
+	b.Publish("busy", NewSSEMessage(3))
+	now = now.Add(30 * time.Second)
+
+	// Event 2 has expired but is not swept until the next publish
+	if _, missed := b.subscribe([]string{"busy"}, 1); len(missed) != 1 || !strings.Contains(string(missed[0]), "data: 3") {
+		t.Errorf("expected only the unexpired event to be replayed, got %q", missed)
+	}
+	b.Publish("busy", NewSSEMessage(4))
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if _, ok := b.replay["idle"]; ok {
+		t.Error("expected the buffer of the idle topic to be freed")
+	}
+	if n := len(b.replay["busy"]); n != 2 {
+		t.Errorf("expected 2 buffered events of the busy topic, got %d", n)
+	}
+}
+
+func TestSSEBrokerHeartbeat(t *testing.T) {
+	b := NewSSEBroker(SSEBrokerConfig{Heartbeat: 10 * time.Millisecond})
+	ts := httptest.NewServer(b.Handler(SSETopics("idle")))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if frame := readSSEFrame(t, bufio.NewReader(resp.Body)); frame != ": keepalive\n" {
+		t.Errorf("expected keepalive comment, got %q", frame)
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SSELimitsConfig caps the number of open Server-Sent Events streams. Browsers keep
+// EventSource connections open and reconnect them automatically, so without a cap a
+// burst of clients can exhaust file descriptors and goroutines. Zero means unlimited.
+type SSELimitsConfig struct {
+	MaxConnections int            `json:"max_connections,omitempty"` // Open streams across all routes
+	MaxPerRoute    int            `json:"max_per_route,omitempty"`   // Open streams per route pattern
+	Routes         map[string]int `json:"routes,omitempty"`          // Per-route caps keyed by route pattern, overriding MaxPerRoute
+}
+
+func (c *SSELimitsConfig) validate() error {
+	if c.MaxConnections < 0 || c.MaxPerRoute < 0 {
+		return fmt.Errorf("SSE connection limits must not be negative")
+	}
+	for route, n := range c.Routes {
+		if n < 0 {
+			return fmt.Errorf("SSE connection limit for route %q must not be negative", route)
+		}
+	}
+	return nil
+}
+
+// WithSSELimits caps the number of open SSE streams globally and per route. Clients
+// over a cap are rejected with 503 Service Unavailable and a Retry-After header, which
+// EventSource treats as a failed connection. Streams are counted whether or not limits
+// are configured; see Server.SSEStats.
+//
+// Example:
+//
+//	srv, _ := server.NewServer(server.WithSSELimits(server.SSELimitsConfig{
+//		MaxConnections: 10000,
+//		Routes:         map[string]int{"/events/admin": 50},
+//	}))
+func WithSSELimits(cfg SSELimitsConfig) ServerOptionFunc {
+	return func(srv *Server) error {
+		srv.Options.SSELimits = &cfg
+		return nil
+	}
+}
+
+// SSEStats reports the open SSE streams of a server.
+type SSEStats struct {
+	Open     int64            `json:"open"`     // Open streams across all routes
+	Routes   map[string]int64 `json:"routes"`   // Open streams by route pattern
+	Accepted uint64           `json:"accepted"` // Streams opened since start
+	Rejected uint64           `json:"rejected"` // Streams rejected by SSELimitsConfig
+	Limits   SSELimitsConfig  `json:"limits"`
+}
+
+// sseConnections counts open SSE streams and enforces SSELimitsConfig. Streams are
+// recognised by the Accept: text/event-stream header that EventSource sends.
+type sseConnections struct {
+	limits SSELimitsConfig
+
+	mu       sync.Mutex
+	open     int64
+	routes   map[string]int64
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+}
+
+func newSSEConnections(limits SSELimitsConfig) *sseConnections {
+	return &sseConnections{limits: limits, routes: make(map[string]int64)}
+}
+
+// isSSERequest reports whether r asks for an event stream.
+func isSSERequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// acquire counts a stream on route unless a limit is reached.
+func (s *sseConnections) acquire(route string) bool {
+	routeLimit := s.limits.MaxPerRoute
+	if n, ok := s.limits.Routes[route]; ok {
+		routeLimit = n
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if (s.limits.MaxConnections > 0 && s.open >= int64(s.limits.MaxConnections)) ||
+		(routeLimit > 0 && s.routes[route] >= int64(routeLimit)) {
+		s.rejected.Add(1)
+		return false
+	}
+	s.open++
+	s.routes[route]++
+	s.accepted.Add(1)
+	return true
+}
+
+func (s *sseConnections) release(route string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open--
+	if s.routes[route]--; s.routes[route] <= 0 {
+		delete(s.routes, route)
+	}
+}
+
+// wrap counts the SSE streams served by next and rejects those over the limits.
+func (s *sseConnections) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isSSERequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := RoutePattern(r)
+		if route == "" {
+			route = MetricsUnmatchedRoute
+		}
+		if !s.acquire(route) {
+			Annotate(r, "sse", "limit")
+			logger.Warn("SSE connection limit reached", "route", route, "remote_addr", r.RemoteAddr)
+			w.Header().Set("Retry-After", "5")
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Too many event streams, try again later")
+			return
+		}
+		defer s.release(route)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *sseConnections) stats() SSEStats {
+	s.mu.Lock()
+	stats := SSEStats{Open: s.open, Routes: maps.Clone(s.routes)}
+	s.mu.Unlock()
+	stats.Accepted = s.accepted.Load()
+	stats.Rejected = s.rejected.Load()
+	stats.Limits = s.limits
+	return stats
+}
+
+// SSEStats returns the number of open SSE streams, in total and per route, along with
+// the configured limits.
+func (srv *Server) SSEStats() SSEStats {
+	if srv.middleware == nil || srv.middleware.sse == nil {
+		return SSEStats{Routes: map[string]int64{}}
+	}
+	return srv.middleware.sse.stats()
+}
+
+// SSEConnectionsResource exposes open SSE streams per route as an MCP resource.
+type SSEConnectionsResource struct {
+	server *Server
+}
+
+// NewSSEConnectionsResource creates a new SSE connections resource.
+func NewSSEConnectionsResource(srv *Server) *SSEConnectionsResource {
+	return &SSEConnectionsResource{server: srv}
+}
+
+func (r *SSEConnectionsResource) URI() string {
+	return "metrics://server/sse"
+}
+
+func (r *SSEConnectionsResource) CacheTTL() time.Duration {
+	return 0
+}
+
+func (r *SSEConnectionsResource) Name() string {
+	return "SSE Connections"
+}
+
+func (r *SSEConnectionsResource) Description() string {
+	return "Open Server-Sent Events streams per route, rejected streams, and connection limits"
+}
+
+func (r *SSEConnectionsResource) MimeType() string {
+	return "application/json"
+}
+
+func (r *SSEConnectionsResource) Read() (interface{}, error) {
+	return map[string]interface{}{
+		"sse":       r.server.SSEStats(),
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+func (r *SSEConnectionsResource) List() ([]string, error) {
+	return []string{r.URI()}, nil
+}
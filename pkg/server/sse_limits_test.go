@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSELimits(t *testing.T) {
+	srv, err := NewServer(
+		WithAddr(":0"),
+		WithSSELimits(SSELimitsConfig{MaxConnections: 2, MaxPerRoute: 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+	}
+	srv.HandleFunc("GET /events", stream)
+	srv.HandleFunc("GET /prices", stream)
+	srv.HandleFunc("GET /news", stream)
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close) // runs after the streams opened below are closed
+
+	open := func(path string) int {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp.StatusCode
+	}
+
+	if code := open("/events"); code != http.StatusOK {
+		t.Fatalf("first stream: status %d", code)
+	}
+	if code := open("/events"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected the per-route limit to reject the second stream, got %d", code)
+	}
+	if code := open("/prices"); code != http.StatusOK {
+		t.Fatalf("stream on another route: status %d", code)
+	}
+	if code := open("/news"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected the global limit to reject the third stream, got %d", code)
+	}
+
+	// Requests that do not ask for an event stream are not counted
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	stats := srv.SSEStats()
+	if stats.Open != 2 || stats.Routes["GET /events"] != 1 || stats.Routes["GET /prices"] != 1 || stats.Rejected != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if snap := srv.MetricsSnapshot(); snap.SSE.Open != 2 {
+		t.Errorf("expected open streams in the metrics snapshot, got %+v", snap.SSE)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSSEEventEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		event SSEEvent
+		want  string
+	}{
+		{"data only", SSEEvent{Data: "hi"}, "data: hi\n\n"},
+		{"all fields", SSEEvent{ID: "42", Event: "update", Retry: 3 * time.Second, Data: map[string]int{"n": 1}},
+			"id: 42\nretry: 3000\nevent: update\ndata: {\"n\":1}\n\n"},
+		{"multi-line data", SSEEvent{Data: "a\r\nb\nc"}, "data: a\ndata: b\ndata: c\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := tt.event.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(frame) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, frame)
+			}
+		})
+	}
+}
+
+func TestSSEEventValidate(t *testing.T) {
+	for _, event := range []SSEEvent{
+		{ID: "1\n2"},
+		{ID: "1\x00"},
+		{Event: "a\rb"},
+		{Retry: -time.Second},
+	} {
+		if err := event.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", event)
+		}
+	}
+}
+
+// disconnectedWriter is a ResponseWriter whose client has gone away.
+type disconnectedWriter struct{ *httptest.ResponseRecorder }
+
+func (disconnectedWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestWriteSSE(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := WriteSSE(rec, &SSEEvent{ID: "1", Data: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Body.String(); got != "id: 1\ndata: hello\n\n" || !rec.Flushed {
+		t.Errorf("expected flushed event, got %q (flushed %v)", got, rec.Flushed)
+	}
+
+	if err := WriteSSE(rec, &SSEEvent{Event: "bad\n"}); err == nil || errors.Is(err, ErrSSEClientDisconnected) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+
+	var w http.ResponseWriter = disconnectedWriter{httptest.NewRecorder()}
+	if err := WriteSSE(w, &SSEEvent{Data: "lost"}); !errors.Is(err, ErrSSEClientDisconnected) {
+		t.Errorf("expected ErrSSEClientDisconnected, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// precompressedEncodings are the encodings of pre-compressed siblings of static files,
+// in order of preference.
+var precompressedEncodings = []struct {
+	name string // Content-Encoding
+	ext  string // File extension of the sibling
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// precompressedAssets serves the pre-compressed sibling of a static file, such as
+// app.js.br or app.js.gz next to app.js, to clients that accept its encoding, so bundles
+// compressed at build time are not compressed again on every request. Siblings older
+// than the file are ignored as stale.
+func (srv *Server) precompressedAssets(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || strings.HasSuffix(r.URL.Path, "/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		fsys, err := srv.staticFS()
+		if err != nil || !fs.ValidPath(name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		original, err := fs.Stat(fsys, name)
+		if err != nil || !original.Mode().IsRegular() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		varied := false
+		for _, enc := range precompressedEncodings {
+			info, err := fs.Stat(fsys, name+enc.ext)
+			if err != nil || !info.Mode().IsRegular() || info.ModTime().Before(original.ModTime()) {
+				continue
+			}
+			if !varied {
+				// The response depends on Accept-Encoding whichever variant is served
+				w.Header().Add("Vary", "Accept-Encoding")
+				varied = true
+			}
+			if !acceptsEncoding(r.Header.Get("Accept-Encoding"), enc.name) {
+				continue
+			}
+			if serveEncoded(w, r, fsys, name, enc.name, enc.ext, info) {
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveEncoded serves the sibling of name with the given encoding and reports whether it
+// could be opened.
+func serveEncoded(w http.ResponseWriter, r *http.Request, fsys fs.FS, name, encoding, ext string, info fs.FileInfo) bool {
+	file, err := fsys.Open(name + ext)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = sniffContentType(fsys, name)
+	}
+	h := w.Header()
+	h.Set("Content-Type", ctype)
+	h.Set("Content-Encoding", encoding)
+	// Each encoding is a different representation and needs its own validator
+	h.Set("ETag", fmt.Sprintf(`"%x-%x-%s"`, info.ModTime().UnixNano(), info.Size(), encoding))
+	http.ServeContent(w, r, name, info.ModTime(), content)
+	return true
+}
+
+// sniffContentType detects the content type of a file from its first bytes.
+func sniffContentType(fsys fs.FS, name string) string {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer file.Close()
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(file, buf)
+	return http.DetectContentType(buf[:n])
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header accepts encoding. Codings
+// with q=0 are refused.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), encoding) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
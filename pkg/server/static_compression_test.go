@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrecompressedStaticFiles(t *testing.T) {
+	dir := t.TempDir()
+	built := time.Now().Truncate(time.Second)
+	files := map[string]string{
+		"app.js":          "console.log(1)",
+		"app.js.br":       "BR",
+		"app.js.gz":       "GZ",
+		"style.css":       "body{}",
+		"style.css.gz":    "STALE",
+		"data.unknown":    "plain text",
+		"data.unknown.gz": "GZ",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(filepath.Join(dir, name), built, built)
+	}
+	// A sibling older than its file is a leftover from a previous build
+	old := built.Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "style.css.gz"), old, old)
+
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv.Options.StaticDir = dir
+	srv.HandleStatic("/assets/")
+
+	get := func(path, acceptEncoding string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name, path, accept string
+		body, encoding     string
+	}{
+		{"prefers brotli", "/assets/app.js", "gzip, deflate, br", "BR", "br"},
+		{"falls back to gzip", "/assets/app.js", "gzip", "GZ", "gzip"},
+		{"respects q=0", "/assets/app.js", "br;q=0, gzip", "GZ", "gzip"},
+		{"identity without Accept-Encoding", "/assets/app.js", "", "console.log(1)", ""},
+		{"ignores stale siblings", "/assets/style.css", "gzip", "body{}", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.path, tt.accept)
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.body {
+				t.Fatalf("expected %q, got %d %q", tt.body, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+			if tt.path == "/assets/app.js" {
+				if rec.Header().Get("Vary") != "Accept-Encoding" {
+					t.Errorf("expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+				}
+				if ctype := rec.Header().Get("Content-Type"); !strings.Contains(ctype, "javascript") {
+					t.Errorf("expected the content type of app.js, got %q", ctype)
+				}
+			}
+		})
+	}
+
+	t.Run("content type of unknown extensions is sniffed from the original", func(t *testing.T) {
+		rec := get("/assets/data.unknown", "gzip")
+		if ctype := rec.Header().Get("Content-Type"); !strings.HasPrefix(ctype, "text/plain") {
+			t.Errorf("expected text/plain, got %q", ctype)
+		}
+	})
+
+	t.Run("ETag per encoding", func(t *testing.T) {
+		br := get("/assets/app.js", "br").Header().Get("ETag")
+		gz := get("/assets/app.js", "gzip").Header().Get("ETag")
+		if br == "" || br == gz {
+			t.Fatalf("expected distinct ETags per encoding, got %q and %q", br, gz)
+		}
+		if rec := get("/assets/app.js", "br", "If-None-Match", br); rec.Code != http.StatusNotModified {
+			t.Errorf("expected 304 for matching ETag, got %d", rec.Code)
+		}
+	})
+
+	t.Run("fingerprinted URLs", func(t *testing.T) {
+		rec := get(srv.AssetURL("app.js"), "br")
+		if rec.Body.String() != "BR" || rec.Header().Get("Cache-Control") != immutableCacheHeader {
+			t.Errorf("expected immutable brotli response, got %q %q", rec.Body.String(), rec.Header().Get("Cache-Control"))
+		}
+	})
+}
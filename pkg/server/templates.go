@@ -0,0 +1,260 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Directories of TemplateDir whose templates are shared by all pages. They are named by
+// their path, e.g. "layouts/base.html" or "partials/user-row.html".
+const (
+	templateLayoutsDir  = "layouts"
+	templatePartialsDir = "partials"
+)
+
+// AddTemplateFuncs makes functions available to the templates in TemplateDir, next to
+// the built-in asset (see AssetURL) and cspNonce (see WithCSPNonce) functions, which they
+// may replace. Add them before registering template handlers, since templates that call
+// unknown functions fail to parse; adding functions later re-parses the templates on the
+// next render.
+//
+// Example:
+//
+//	srv.AddTemplateFuncs(template.FuncMap{
+//		"upper": strings.ToUpper,
+//		"date":  func(t time.Time) string { return t.Format("2 Jan 2006") },
+//	})
+func (srv *Server) AddTemplateFuncs(funcs template.FuncMap) {
+	srv.templatesMu.Lock()
+	defer srv.templatesMu.Unlock()
+	if srv.templateFuncs == nil {
+		srv.templateFuncs = make(template.FuncMap, len(funcs))
+	}
+	maps.Copy(srv.templateFuncs, funcs)
+	srv.templates = nil
+	srv.pageTemplates = nil
+}
+
+// RenderTemplate renders a page of TemplateDir as the response, for handlers that
+// load their own data. Pages are the .html files at the top of TemplateDir; each is
+// rendered with the layouts and partials, so a page can wrap itself in a layout and
+// fill its blocks:
+//
+//	<!-- layouts/base.html -->
+//	<html><body>{{block "content" .}}{{end}}</body></html>
+//
+//	<!-- users.html -->
+//	{{template "layouts/base.html" .}}
+//	{{define "content"}}<table>{{block "rows" .}}{{range .}}{{template "partials/user-row.html" .}}{{end}}{{end}}</table>{{end}}
+//
+// Blocks defined by one page do not affect other pages.
+func (srv *Server) RenderTemplate(w http.ResponseWriter, name string, data any) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return srv.executeTemplate(w, nil, name, name, data)
+}
+
+// RenderPartial renders one named block of a page without the rest of the page or its
+// layout, such as the rows an HTMX request swaps into a table. With an empty page, block
+// names a shared template such as "partials/user-row.html". RenderHTMX chooses between
+// the two by the request's htmx headers.
+//
+// Example:
+//
+//	srv.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+//		if server.IsHTMX(r) {
+//			srv.RenderPartial(w, "users.html", "rows", users)
+//			return
+//		}
+//		srv.RenderTemplate(w, "users.html", users)
+//	})
+func (srv *Server) RenderPartial(w http.ResponseWriter, page, block string, data any) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return srv.executeTemplate(w, nil, page, block, data)
+}
+
+// ExecuteTemplate renders block of page into w, which need not be the response, e.g. to
+// render into a buffer. An empty block renders the whole page. The cspNonce function
+// renders the nonce of r, see WithCSPNonce.
+func (srv *Server) ExecuteTemplate(w io.Writer, r *http.Request, page, block string, data any) error {
+	if block == "" {
+		block = page
+	}
+	return srv.executeTemplate(w, r, page, block, data)
+}
+
+// executeTemplate renders the template named block with the template set of page. The
+// nonce comes from r, or else from the Content-Security-Policy of w if w is the response;
+// without either, cspNonce renders empty.
+func (srv *Server) executeTemplate(w io.Writer, r *http.Request, page, block string, data any) error {
+	tmpl, err := srv.templateSet()
+	if err != nil {
+		return err
+	}
+	srv.templatesMu.Lock()
+	if pageTmpl := srv.pageTemplates[page]; pageTmpl != nil {
+		tmpl = pageTmpl
+	}
+	srv.templatesMu.Unlock()
+	var nonce string
+	if r != nil {
+		nonce = CSPNonce(r)
+	}
+	if rw, ok := w.(http.ResponseWriter); ok && nonce == "" {
+		nonce = responseCSPNonce(rw.Header())
+	}
+	return tmpl.ExecuteTemplate(&cspNonceWriter{w: w, nonce: []byte(nonce)}, block, data)
+}
+
+// templateSet returns the templates to render with. In DebugMode the template
+// directory is checked on every call and re-parsed when a template was added, removed,
+// or modified, so markup changes show up without a restart. Otherwise templates are
+// parsed once and never change.
+func (srv *Server) templateSet() (*template.Template, error) {
+	if srv.Options == nil || !srv.Options.current().DebugMode {
+		if err := srv.parseTemplates(); err != nil {
+			return nil, err
+		}
+		return srv.templates, nil
+	}
+
+	srv.templatesMu.Lock()
+	defer srv.templatesMu.Unlock()
+	if srv.templates != nil {
+		if fsys, err := srv.templateFS(); err == nil {
+			if stamp, err := templateStamp(fsys); err == nil && stamp == srv.templatesStamp {
+				return srv.templates, nil
+			}
+		}
+	}
+	if err := srv.loadTemplatesLocked(); err != nil {
+		srv.templatesStamp = "" // Report the error until the template is fixed
+		return nil, err
+	}
+	return srv.templates, nil
+}
+
+// templateFS returns the template directory as an fs.FS, preferring the secure os.Root.
+func (srv *Server) templateFS() (fs.FS, error) {
+	if srv.templateRoot != nil {
+		return srv.templateRoot.FS(), nil
+	}
+	templateDir := srv.Options.TemplateDir
+	if _, err := os.Stat(templateDir); os.IsNotExist(err) {
+		wd, _ := os.Getwd()
+		ad, _ := filepath.Abs(templateDir)
+		return nil, fmt.Errorf("template directory not found. working-dir %s abs-path: %s, error %w", wd, ad, err)
+	}
+	return os.DirFS(templateDir), nil
+}
+
+// templateFiles lists the pages at the top of the template directory and the shared
+// templates in its layouts and partials directories.
+func templateFiles(fsys fs.FS) (pages, shared []string, err error) {
+	for _, dir := range []string{".", templateLayoutsDir, templatePartialsDir} {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			if dir != "." && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".html") {
+				continue
+			}
+			if dir == "." {
+				pages = append(pages, entry.Name())
+			} else {
+				shared = append(shared, path.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return pages, shared, nil
+}
+
+// templateStamp summarises the template files so that changes can be detected.
+func templateStamp(fsys fs.FS) (string, error) {
+	pages, shared, err := templateFiles(fsys)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, name := range append(shared, pages...) {
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// loadTemplatesLocked parses the templates in TemplateDir. The caller holds templatesMu.
+//
+// All templates are parsed into one set, which keeps pages able to include each other.
+// Each page is then parsed again into its own clone of that set, so that the blocks it
+// defines for a layout take precedence over those of other pages.
+func (srv *Server) loadTemplatesLocked() error {
+	fsys, err := srv.templateFS()
+	if err != nil {
+		return err
+	}
+	// Stamp before parsing so that a change made while parsing triggers another reload
+	srv.templatesStamp, _ = templateStamp(fsys)
+
+	pages, shared, err := templateFiles(fsys)
+	if err != nil {
+		return fmt.Errorf("failed to list template files: %w", err)
+	}
+	contents := make(map[string]string, len(pages)+len(shared))
+	tmpl := template.New("root").Funcs(template.FuncMap{
+		"asset":    srv.AssetURL,
+		"cspNonce": func() string { return cspNoncePlaceholder },
+	}).Funcs(srv.templateFuncs)
+	for _, name := range append(shared, pages...) {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			logger.Error("Failed to read template file", "file", name, "error", err)
+			continue
+		}
+		contents[name] = string(content)
+		if _, err := tmpl.New(name).Parse(string(content)); err != nil {
+			logger.Error("Failed to parse template", "file", name, "error", err)
+			return fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+	}
+
+	pageTemplates := make(map[string]*template.Template, len(pages))
+	for _, name := range pages {
+		content, ok := contents[name]
+		if !ok {
+			continue
+		}
+		pageTmpl, err := tmpl.Clone()
+		if err != nil {
+			return fmt.Errorf("failed to clone templates for %s: %w", name, err)
+		}
+		if _, err := pageTmpl.New(name).Parse(content); err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		pageTemplates[name] = pageTmpl
+	}
+
+	srv.templates = tmpl
+	srv.pageTemplates = pageTemplates
+	if srv.templateRoot != nil {
+		logger.Info("Templates parsed using secure os.Root", "count", len(pages), "shared", len(shared))
+	} else {
+		logger.Info("Templates parsed.", "dir", srv.Options.TemplateDir, "count", len(pages), "shared", len(shared))
+	}
+	return nil
+}
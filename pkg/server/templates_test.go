@@ -0,0 +1,106 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating template directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing template file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestTemplateLayoutsAndPartials(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/base.html":     `<main>{{block "content" .}}{{end}}</main>`,
+		"partials/item.html":    `<li>{{shout .}}</li>`,
+		"list.html":             `{{template "layouts/base.html" .}}{{define "content"}}<ul>{{block "items" .}}{{range .}}{{template "partials/item.html" .}}{{end}}{{end}}</ul>{{end}}`,
+		"about.html":            `{{template "layouts/base.html" .}}{{define "content"}}<p>about {{.}}</p>{{end}}`,
+		"legacy.html":           `<h1>{{template "footer.html"}}</h1>`,
+		"footer.html":           `footer`,
+		"partials/ignored.txt":  `not a template`,
+		"layouts/nested/x.html": `nested directories are not parsed`,
+	})
+	srv, err := NewServer(WithTemplateDir(dir))
+	if err != nil {
+		t.Fatalf("error creating server: %v", err)
+	}
+	srv.AddTemplateFuncs(template.FuncMap{"shout": strings.ToUpper})
+
+	srv.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		items := []string{"a", "b"}
+		if r.Header.Get("HX-Request") == "true" {
+			srv.RenderPartial(w, "list.html", "items", items)
+			return
+		}
+		srv.RenderTemplate(w, "list.html", items)
+	})
+	if err := srv.HandleTemplate("/about", "about.html", "us"); err != nil {
+		t.Fatalf("failed to add template handler: %v", err)
+	}
+	if err := srv.HandleTemplate("/legacy", "legacy.html", nil); err != nil {
+		t.Fatalf("failed to add template handler: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		htmx bool
+		want string
+	}{
+		{"/list", false, "<main><ul><li>A</li><li>B</li></ul></main>"},
+		{"/list", true, "<li>A</li><li>B</li>"},
+		{"/about", false, "<main><p>about us</p></main>"},
+		{"/legacy", false, "<h1>footer</h1>"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		rec := httptest.NewRecorder()
+		srv.mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+			t.Errorf("%s (htmx=%v): expected %q, got %d %q", tt.path, tt.htmx, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	if err := srv.RenderPartial(rec, "", "partials/item.html", "c"); err != nil || rec.Body.String() != "<li>C</li>" {
+		t.Errorf("expected shared partial to render, got %q, %v", rec.Body.String(), err)
+	}
+	if err := srv.RenderTemplate(httptest.NewRecorder(), "missing.html", nil); err == nil {
+		t.Error("expected an error for a missing page")
+	}
+}
+
+func TestAddTemplateFuncsReparses(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{"page.html": `{{greet .}}`})
+	srv, err := NewServer(WithTemplateDir(dir))
+	if err != nil {
+		t.Fatalf("error creating server: %v", err)
+	}
+	if err := srv.RenderTemplate(httptest.NewRecorder(), "page.html", "x"); err == nil {
+		t.Fatal("expected parsing to fail without the function")
+	}
+
+	srv.AddTemplateFuncs(template.FuncMap{"greet": func(s string) string { return "hello " + s }})
+	rec := httptest.NewRecorder()
+	if err := srv.RenderTemplate(rec, "page.html", "x"); err != nil || rec.Body.String() != "hello x" {
+		t.Fatalf("expected rendering with the added function, got %q, %v", rec.Body.String(), err)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// tlsCurves lists the key exchange mechanisms that TLSCurvePreferences can name.
+var tlsCurves = []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// fipsCipherSuites and fipsCurves are the algorithms FIPSMode allows.
+var (
+	fipsCipherSuites = []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_AES_128_GCM_SHA256,
+		tls.TLS_AES_256_GCM_SHA384,
+	}
+	fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+)
+
+// WithTLSConfig pins the minimum TLS version, the cipher suites, and the key exchange
+// curves in order of preference, instead of the defaults of HyperServe and Go. A zero
+// minVersion keeps TLS 1.2, and nil slices keep the defaults. Go does not make the TLS 1.3
+// cipher suites configurable, so cipherSuites only restricts TLS 1.2 connections.
+//
+// In FIPSMode only FIPS-approved suites and curves are accepted. The settings can also be
+// given by name via "tls_min_version", "tls_cipher_suites", and "tls_curve_preferences"
+// in options.json, or HS_TLS_MIN_VERSION, HS_TLS_CIPHER_SUITES, and HS_TLS_CURVES.
+//
+// Example:
+//
+//	server.WithTLSConfig(tls.VersionTLS12,
+//		[]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+//		[]tls.CurveID{tls.CurveP384, tls.CurveP256},
+//	)
+func WithTLSConfig(minVersion uint16, cipherSuites []uint16, curvePreferences []tls.CurveID) ServerOptionFunc {
+	return func(srv *Server) error {
+		if minVersion != 0 {
+			srv.Options.TLSMinVersion = strings.TrimPrefix(tls.VersionName(minVersion), "TLS ")
+		}
+		srv.Options.TLSCipherSuites = nil
+		for _, id := range cipherSuites {
+			srv.Options.TLSCipherSuites = append(srv.Options.TLSCipherSuites, tls.CipherSuiteName(id))
+		}
+		srv.Options.TLSCurvePreferences = nil
+		for _, curve := range curvePreferences {
+			srv.Options.TLSCurvePreferences = append(srv.Options.TLSCurvePreferences, curve.String())
+		}
+		return nil
+	}
+}
+
+// WithCustomTLSConfig serves TLS with a copy of config, for settings WithTLSConfig does
+// not cover such as client certificate authentication or certificate selection. It takes
+// precedence over WithTLSConfig and FIPSMode. When config provides certificates itself,
+// through Certificates, GetCertificate, or GetConfigForClient, TLS is enabled without
+// certificate files; otherwise the files of WithTLS are loaded.
+func WithCustomTLSConfig(config *tls.Config) ServerOptionFunc {
+	return func(srv *Server) error {
+		if config == nil {
+			return fmt.Errorf("custom TLS config must not be nil")
+		}
+		srv.Options.TLSConfig = config.Clone()
+		if srv.Options.tlsConfigHasCertificates() {
+			srv.Options.EnableTLS = true
+		}
+		return nil
+	}
+}
+
+// tlsConfigHasCertificates reports whether the custom TLS config provides the server
+// certificate, so that no certificate files are needed.
+func (o *ServerOptions) tlsConfigHasCertificates() bool {
+	c := o.TLSConfig
+	return c != nil && (len(c.Certificates) > 0 || c.GetCertificate != nil || c.GetConfigForClient != nil)
+}
+
+// parseTLSVersion parses versions such as "1.2" or "TLS 1.3".
+func parseTLSVersion(name string) (uint16, error) {
+	switch strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "TLS")) {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q, use 1.2 or 1.3", name)
+}
+
+// parseTLSCipherSuites looks up cipher suites by their IANA names.
+func parseTLSCipherSuites(names []string) ([]uint16, error) {
+	suites := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(suites, func(s *tls.CipherSuite) bool { return strings.EqualFold(s.Name, strings.TrimSpace(name)) })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		if suites[i].Insecure {
+			return nil, fmt.Errorf("cipher suite %s is insecure", suites[i].Name)
+		}
+		ids = append(ids, suites[i].ID)
+	}
+	return ids, nil
+}
+
+// parseTLSCurves looks up curves by name, e.g. "X25519MLKEM768", "CurveP256", or "P256".
+func parseTLSCurves(names []string) ([]tls.CurveID, error) {
+	curves := make([]tls.CurveID, 0, len(names))
+	for _, name := range names {
+		name = strings.ReplaceAll(strings.TrimSpace(name), "-", "")
+		i := slices.IndexFunc(tlsCurves, func(c tls.CurveID) bool {
+			return strings.EqualFold(c.String(), name) || strings.EqualFold(strings.TrimPrefix(c.String(), "Curve"), name)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("unknown curve %q", name)
+		}
+		curves = append(curves, tlsCurves[i])
+	}
+	return curves, nil
+}
+
+// validateTLS reports problems with the TLS settings, including algorithms that FIPSMode
+// does not allow.
+func (o *ServerOptions) validateTLS(v *configValidator) {
+	if o.TLSMinVersion != "" {
+		if version, err := parseTLSVersion(o.TLSMinVersion); err != nil {
+			v.add("tls_min_version", "%v", err)
+		} else if version < tls.VersionTLS12 && (o.FIPSMode || o.HardenedMode) {
+			v.add("tls_min_version", "must be at least 1.2 in FIPS or hardened mode, got %s", o.TLSMinVersion)
+		}
+	}
+	if suites, err := parseTLSCipherSuites(o.TLSCipherSuites); err != nil {
+		v.add("tls_cipher_suites", "%v", err)
+	} else if o.FIPSMode {
+		for _, id := range suites {
+			if !slices.Contains(fipsCipherSuites, id) {
+				v.add("tls_cipher_suites", "%s is not FIPS-approved", tls.CipherSuiteName(id))
+			}
+		}
+	}
+	if curves, err := parseTLSCurves(o.TLSCurvePreferences); err != nil {
+		v.add("tls_curve_preferences", "%v", err)
+	} else if o.FIPSMode {
+		for _, curve := range curves {
+			if !slices.Contains(fipsCurves, curve) {
+				v.add("tls_curve_preferences", "%s is not FIPS-approved", curve)
+			}
+		}
+	}
+}
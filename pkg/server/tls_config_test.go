@@ -0,0 +1,161 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithTLSConfig(t *testing.T) {
+	t.Chdir(t.TempDir())
+	srv, err := NewServer(WithTLSConfig(tls.VersionTLS13,
+		[]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+		[]tls.CurveID{tls.CurveP384, tls.X25519MLKEM768},
+	))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if srv.Options.TLSMinVersion != "1.3" {
+		t.Errorf("expected the version by name, got %q", srv.Options.TLSMinVersion)
+	}
+	config := srv.tlsConfig()
+	if config.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected TLS 1.3 as the minimum, got %x", config.MinVersion)
+	}
+	if !slices.Equal(config.CipherSuites, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}) {
+		t.Errorf("expected the pinned cipher suite, got %v", config.CipherSuites)
+	}
+	if !slices.Equal(config.CurvePreferences, []tls.CurveID{tls.CurveP384, tls.X25519MLKEM768}) {
+		t.Errorf("expected the pinned curves, got %v", config.CurvePreferences)
+	}
+}
+
+func TestTLSConfigFromEnvironment(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(paramTLSMinVersion, "TLS 1.2")
+	t.Setenv(paramTLSCurves, "P-256, x25519")
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	config := srv.tlsConfig()
+	if config.MinVersion != tls.VersionTLS12 || !slices.Equal(config.CurvePreferences, []tls.CurveID{tls.CurveP256, tls.X25519}) {
+		t.Errorf("expected the settings from the environment, got version %x and curves %v", config.MinVersion, config.CurvePreferences)
+	}
+
+	t.Setenv(paramTLSCipherSuites, "TLS_RSA_WITH_RC4_128_SHA")
+	if _, err := NewServer(); err == nil {
+		t.Error("expected an insecure cipher suite to fail validation")
+	}
+}
+
+func TestTLSConfigFIPSValidation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	_, err := NewServer(WithFIPSMode(), WithTLSConfig(tls.VersionTLS12,
+		[]uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256},
+		[]tls.CurveID{tls.X25519},
+	))
+	var verr *ConfigValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 2 {
+		t.Fatalf("expected the suite and curve to be rejected in FIPS mode, got %v", err)
+	}
+}
+
+func TestWithCustomTLSConfig(t *testing.T) {
+	custom := &tls.Config{MinVersion: tls.VersionTLS13, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv, err := NewServer(WithFIPSMode(), WithCustomTLSConfig(custom))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	config := srv.tlsConfig()
+	if config == custom || config.ClientAuth != tls.RequireAndVerifyClientCert || config.CipherSuites != nil {
+		t.Errorf("expected a copy of the custom config to take precedence, got %+v", config)
+	}
+}
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 and a pool that trusts it.
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "hyperserve test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestCustomTLSConfigServesWithoutCertificateFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cert, pool := selfSignedCertificate(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	srv, err := NewServer(
+		WithCustomTLSConfig(&tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &cert, nil }}),
+		WithSuppressBanner(true),
+	)
+	if err != nil {
+		t.Fatalf("expected a custom config with certificates to pass validation, got %v", err)
+	}
+	srv.Options.TLSAddr = addr
+	srv.Options.RunHealthServer = false
+	srv.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
+
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- srv.Run() }()
+	for !srv.isRunning.Load() {
+		select {
+		case err := <-serverErr:
+			t.Fatalf("server stopped: %v", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	defer func() {
+		srv.Stop()
+		<-serverErr
+	}()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get("https://" + addr + "/hello")
+	if err != nil {
+		t.Fatalf("TLS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" || resp.TLS == nil {
+		t.Errorf("expected the handler over TLS, got %d %q", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	txKey contextKey = "tx"
+
+	// maxTxResponseBytes caps the response TransactionMiddleware holds back until the
+	// transaction ends.
+	maxTxResponseBytes = 8 << 20
+)
+
+// errTxResponseTooLarge is returned by writes beyond maxTxResponseBytes.
+var errTxResponseTooLarge = errors.New("transaction response exceeds the buffer limit")
+
+// Tx is a transaction begun by a Beginner. *sql.Tx implements it.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// Beginner begins the transaction of a request for TransactionMiddleware.
+type Beginner interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// BeginnerFunc adapts an ordinary function to the Beginner interface.
+type BeginnerFunc func(ctx context.Context) (Tx, error)
+
+func (f BeginnerFunc) Begin(ctx context.Context) (Tx, error) {
+	return f(ctx)
+}
+
+// SQLBeginner begins database/sql transactions with the given options, nil for the
+// driver defaults.
+func SQLBeginner(db *sql.DB, opts *sql.TxOptions) Beginner {
+	return BeginnerFunc(func(ctx context.Context) (Tx, error) {
+		return db.BeginTx(ctx, opts)
+	})
+}
+
+// TxFromContext returns the transaction of the request, if TransactionMiddleware began one.
+//
+// Example:
+//
+//	tx, _ := server.TxFromContext(r.Context())
+//	_, err := tx.(*sql.Tx).ExecContext(r.Context(), "UPDATE accounts SET balance = balance - $1 WHERE id = $2", amount, id)
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txKey).(Tx)
+	return tx, ok
+}
+
+// TransactionMiddleware runs each request in a transaction begun by b. The transaction is
+// committed when the handler responds with a 2xx or 3xx status and rolled back otherwise,
+// including when it panics; the panic is then passed on to RecoveryMiddleware. Hooks
+// queued with AfterCommit run only after a successful commit.
+//
+// The response is buffered until the transaction ends, so that a failed commit can
+// still be answered with a 500 instead of a success the database does not reflect.
+// Flushing has no effect inside the middleware; do not use it for streaming handlers.
+// Writes beyond 8 MiB fail, and the transaction is then rolled back and answered with a
+// 500. Failures to begin (503) or commit (500) are passed to the ErrorReporter.
+//
+// Example:
+//
+//	srv.AddMiddleware("/api/orders", server.TransactionMiddleware(server.SQLBeginner(db, nil)))
+func TransactionMiddleware(b Beginner) MiddlewareFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tx, err := b.Begin(r.Context())
+			if err != nil {
+				logger.Error("Failed to begin transaction", "path", r.URL.Path, "error", err)
+				ReportError(r, fmt.Errorf("begin transaction: %w", err))
+				writeErrorResponse(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+				return
+			}
+
+			buf := &txResponseWriter{ResponseWriter: w, header: w.Header().Clone(), status: http.StatusOK}
+			box := newOutbox(r)
+			committed := false
+			defer func() {
+				if !committed {
+					// Panics and handlers that returned an error status
+					box.end(false)
+					if err := tx.Rollback(); err != nil {
+						logger.Error("Failed to roll back transaction", "path", r.URL.Path, "error", err)
+					}
+				}
+			}()
+
+			ctx := context.WithValue(r.Context(), txKey, tx)
+			next.ServeHTTP(buf, r.WithContext(context.WithValue(ctx, outboxKey, box)))
+
+			if buf.overflow {
+				logger.Error("Transaction response too large", "path", r.URL.Path, "limit", maxTxResponseBytes)
+				ReportError(r, errTxResponseTooLarge)
+				writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+			if buf.status < 200 || buf.status >= 400 {
+				buf.flush()
+				return
+			}
+			committed = true
+			if err := tx.Commit(); err != nil {
+				logger.Error("Failed to commit transaction", "path", r.URL.Path, "error", err)
+				ReportError(r, fmt.Errorf("commit transaction: %w", err))
+				writeErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+				box.end(false)
+				return
+			}
+			buf.flush()
+			box.end(true)
+		}
+	}
+}
+
+// txResponseWriter holds back the response until the transaction has ended.
+type txResponseWriter struct {
+	http.ResponseWriter
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	wrote    bool
+	overflow bool // A write exceeded maxTxResponseBytes
+}
+
+func (w *txResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *txResponseWriter) WriteHeader(code int) {
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		// Informational responses such as 103 Early Hints are sent right away
+		for k, v := range w.header {
+			w.ResponseWriter.Header()[k] = v
+		}
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.wrote {
+		return
+	}
+	w.status, w.wrote = code, true
+}
+
+func (w *txResponseWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	if w.overflow || w.body.Len()+len(b) > maxTxResponseBytes {
+		w.overflow = true
+		return 0, errTxResponseTooLarge
+	}
+	return w.body.Write(b)
+}
+
+// Flush is a no-op so that handlers flushing through http.ResponseController do not
+// send the response before the commit.
+func (w *txResponseWriter) Flush() {}
+
+func (w *txResponseWriter) flush() {
+	dst := w.ResponseWriter.Header()
+	clear(dst)
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+		logger.Debug("Failed to write buffered response", "error", err)
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeTx struct {
+	committed, rolledBack bool
+	commitErr             error
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.committed = true
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.rolledBack = true
+	return nil
+}
+
+func serveTx(tx *fakeTx, beginErr error, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	b := BeginnerFunc(func(ctx context.Context) (Tx, error) {
+		if beginErr != nil {
+			return nil, beginErr
+		}
+		return tx, nil
+	})
+	h := RecoveryMiddleware(TransactionMiddleware(b)(handler))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	return rec
+}
+
+func TestTransactionMiddlewareCommitsOnSuccess(t *testing.T) {
+	tx := &fakeTx{}
+	rec := serveTx(tx, nil, func(w http.ResponseWriter, r *http.Request) {
+		if got, ok := TxFromContext(r.Context()); !ok || got != tx {
+			t.Error("expected the transaction in the request context")
+		}
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+
+	if !tx.committed || tx.rolledBack {
+		t.Errorf("expected commit only, got committed=%v rolledBack=%v", tx.committed, tx.rolledBack)
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != "created" || rec.Header().Get("Location") != "/orders/1" {
+		t.Errorf("unexpected response: %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	// Post/Redirect/Get answers a successful write with a redirect
+	tx = &fakeTx{}
+	rec = serveTx(tx, nil, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/orders/1", http.StatusSeeOther)
+	})
+	if !tx.committed || tx.rolledBack {
+		t.Errorf("expected commit on 303, got committed=%v rolledBack=%v", tx.committed, tx.rolledBack)
+	}
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("expected the redirect, got %d", rec.Code)
+	}
+}
+
+func TestTransactionMiddlewareRollsBack(t *testing.T) {
+	tx := &fakeTx{}
+	rec := serveTx(tx, nil, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid order", http.StatusUnprocessableEntity)
+	})
+	if tx.committed || !tx.rolledBack {
+		t.Errorf("expected rollback on 422, got committed=%v rolledBack=%v", tx.committed, tx.rolledBack)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected the handler's status, got %d", rec.Code)
+	}
+
+	tx = &fakeTx{}
+	rec = serveTx(tx, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	})
+	if tx.committed || !tx.rolledBack {
+		t.Errorf("expected rollback on panic, got committed=%v rolledBack=%v", tx.committed, tx.rolledBack)
+	}
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() == "partial" {
+		t.Errorf("expected RecoveryMiddleware's 500 without the partial body, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTransactionMiddlewareFailures(t *testing.T) {
+	tx := &fakeTx{commitErr: errors.New("serialization failure")}
+	rec := serveTx(tx, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/orders/1")
+		w.Write([]byte("ok"))
+	})
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Location") != "" {
+		t.Errorf("expected 500 without the handler's response after a failed commit, got %d %v", rec.Code, rec.Header())
+	}
+	if tx.rolledBack {
+		t.Error("a failed commit must not be rolled back again")
+	}
+
+	tx = &fakeTx{}
+	var writeErr error
+	rec = serveTx(tx, nil, func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, 1<<20)
+		for range maxTxResponseBytes/len(chunk) + 1 {
+			if _, writeErr = w.Write(chunk); writeErr != nil {
+				return
+			}
+		}
+	})
+	if !errors.Is(writeErr, errTxResponseTooLarge) {
+		t.Errorf("expected the write beyond the limit to fail, got %v", writeErr)
+	}
+	if tx.committed || !tx.rolledBack || rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected rollback and 500 for an oversized response, got %d committed=%v rolledBack=%v", rec.Code, tx.committed, tx.rolledBack)
+	}
+
+	called := false
+	rec = serveTx(nil, errors.New("pool exhausted"), func(w http.ResponseWriter, r *http.Request) { called = true })
+	if rec.Code != http.StatusServiceUnavailable || called {
+		t.Errorf("expected 503 without calling the handler, got %d called=%v", rec.Code, called)
+	}
+}
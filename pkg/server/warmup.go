@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CacheWarmer preloads caches before the server is marked ready, so the first requests do
+// not pay for cold caches. Warmers run sequentially after the listener is up, while
+// non-health endpoints respond with 503.
+type CacheWarmer struct {
+	Name string
+	Warm func(ctx context.Context, srv *Server) error
+	// Optional warmers log failures instead of keeping the server from becoming ready.
+	Optional bool
+}
+
+// WarmupResult reports the outcome of a single cache warmer.
+type WarmupResult struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// WithCacheWarmer registers cache warmers that run before readiness. Timings are logged in
+// the startup summary and available from srv.WarmupResults().
+//
+// Example:
+//
+//	srv, _ := server.NewServer(
+//		server.WithCacheWarmer(
+//			server.WarmTemplates(),
+//			server.WarmRoutes("/", "/api/catalog"),
+//			server.CacheWarmer{Name: "pricing", Warm: loadPricing},
+//		),
+//	)
+func WithCacheWarmer(warmers ...CacheWarmer) ServerOptionFunc {
+	return func(srv *Server) error {
+		for _, w := range warmers {
+			if w.Name == "" || w.Warm == nil {
+				return fmt.Errorf("cache warmer requires a name and a warm function")
+			}
+		}
+		srv.warmers = append(srv.warmers, warmers...)
+		return nil
+	}
+}
+
+// WarmTemplates parses the templates in TemplateDir ahead of the first render.
+func WarmTemplates() CacheWarmer {
+	return CacheWarmer{
+		Name: "templates",
+		Warm: func(ctx context.Context, srv *Server) error {
+			if srv.templateRoot == nil && srv.Options.TemplateDir == "" {
+				return nil
+			}
+			return srv.parseTemplates()
+		},
+	}
+}
+
+// WarmRoutes issues in-process GET requests for the given paths through the full
+// middleware chain, priming response caches for key routes. Responses with a status of
+// 400 or above fail the warmer.
+func WarmRoutes(paths ...string) CacheWarmer {
+	return CacheWarmer{
+		Name: "routes",
+		Warm: func(ctx context.Context, srv *Server) error {
+			handler := srv.middleware.applyToMux(srv.mux)
+			for _, path := range paths {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+				if err != nil {
+					return fmt.Errorf("invalid warmup path %q: %w", path, err)
+				}
+				req.RemoteAddr = "127.0.0.1:0"
+				req.Header.Set("X-Cache-Warmup", "1")
+				w := &warmupResponseWriter{header: make(http.Header), status: http.StatusOK}
+				handler.ServeHTTP(w, req)
+				if w.status >= http.StatusBadRequest {
+					return fmt.Errorf("warming %s returned status %d", path, w.status)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// WarmMCPResources reads the given MCP resources so that their content is cached before
+// the first client request. With no URIs, all registered resources are read.
+func WarmMCPResources(uris ...string) CacheWarmer {
+	return CacheWarmer{
+		Name: "mcp_resources",
+		Warm: func(ctx context.Context, srv *Server) error {
+			if srv.mcpHandler == nil {
+				return nil
+			}
+			if len(uris) == 0 {
+				uris = srv.mcpHandler.GetRegisteredResources()
+			}
+			for _, uri := range uris {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if _, err := srv.mcpHandler.handleResourcesRead(map[string]interface{}{"uri": uri}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// runCacheWarmers runs the registered warmers once and logs their timings.
+func (srv *Server) runCacheWarmers(ctx context.Context) error {
+	if len(srv.warmers) == 0 || srv.warmupDone.Load() {
+		return nil
+	}
+
+	srv.warmupMu.Lock()
+	defer srv.warmupMu.Unlock()
+	if srv.warmupDone.Load() {
+		return nil
+	}
+
+	results := make([]WarmupResult, 0, len(srv.warmers))
+	summary := make([]string, 0, len(srv.warmers))
+	begin := time.Now()
+	for _, w := range srv.warmers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now()
+		err := w.Warm(ctx, srv)
+		result := WarmupResult{Name: w.Name, Duration: time.Since(start)}
+		if err != nil {
+			result.Error = err.Error()
+			if !w.Optional {
+				srv.warmupResults = append(results, result)
+				return fmt.Errorf("cache warmer %q failed: %w", w.Name, err)
+			}
+			logger.Warn("Optional cache warmer failed", "warmer", w.Name, "error", err)
+		}
+		logger.Debug("Cache warmer completed", "warmer", w.Name, "duration", result.Duration)
+		results = append(results, result)
+		summary = append(summary, fmt.Sprintf("%s=%s", w.Name, result.Duration.Round(time.Microsecond)))
+	}
+	srv.warmupResults = results
+	srv.warmupDone.Store(true)
+
+	logger.Info("Cache warming completed", "duration", time.Since(begin), "warmers", strings.Join(summary, " "))
+	return nil
+}
+
+// WarmupResults returns the timings of the cache warmers from the last warmup run.
+func (srv *Server) WarmupResults() []WarmupResult {
+	srv.warmupMu.Lock()
+	defer srv.warmupMu.Unlock()
+	return append([]WarmupResult(nil), srv.warmupResults...)
+}
+
+// warmupResponseWriter discards the response body of warmup requests.
+type warmupResponseWriter struct {
+	header http.Header
+	status int
+	wrote  bool
+}
+
+func (w *warmupResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *warmupResponseWriter) WriteHeader(status int) {
+	// Informational responses such as 103 Early Hints precede the final status
+	if !w.wrote && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		w.status = status
+		w.wrote = true
+	}
+}
+
+func (w *warmupResponseWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return len(b), nil
+}
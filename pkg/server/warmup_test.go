@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCacheWarmersRunBeforeReady(t *testing.T) {
+	var order []string
+	var warmedPath string
+
+	srv, err := NewServer(
+		WithCacheWarmer(
+			CacheWarmer{Name: "first", Warm: func(ctx context.Context, app *Server) error {
+				order = append(order, "first")
+				return nil
+			}},
+			WarmRoutes("/catalog"),
+		),
+		WithOnReady(func(ctx context.Context, app *Server) error {
+			order = append(order, "on_ready")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	srv.HandleFunc("/catalog", func(w http.ResponseWriter, r *http.Request) {
+		warmedPath = r.URL.Path + " " + r.Header.Get("X-Cache-Warmup")
+	})
+
+	if srv.isReady.Load() {
+		t.Fatal("server with cache warmers should not be ready before warming")
+	}
+	if err := srv.CompleteDeferredInit(context.Background(), nil); err != nil {
+		t.Fatalf("deferred init failed: %v", err)
+	}
+	if !srv.isReady.Load() {
+		t.Fatal("server should be ready after warming")
+	}
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "on_ready" {
+		t.Errorf("unexpected execution order %v", order)
+	}
+	if warmedPath != "/catalog 1" {
+		t.Errorf("route was not warmed, got %q", warmedPath)
+	}
+
+	results := srv.WarmupResults()
+	if len(results) != 2 || results[0].Name != "first" || results[1].Name != "routes" {
+		t.Fatalf("unexpected warmup results %+v", results)
+	}
+
+	// Warmers run once, even if initialization is completed again
+	if err := srv.CompleteDeferredInit(context.Background(), nil); err != nil {
+		t.Fatalf("second completion failed: %v", err)
+	}
+	if len(order) != 2 {
+		t.Errorf("warmers ran again: %v", order)
+	}
+}
+
+func TestCacheWarmerFailure(t *testing.T) {
+	srv, err := NewServer(
+		WithCacheWarmer(
+			CacheWarmer{Name: "optional", Optional: true, Warm: func(ctx context.Context, app *Server) error {
+				return errors.New("cold")
+			}},
+			CacheWarmer{Name: "required", Warm: func(ctx context.Context, app *Server) error {
+				return errors.New("unavailable")
+			}},
+		),
+		WithDeferredInitStopOnFailure(false),
+	)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	if err := srv.CompleteDeferredInit(context.Background(), nil); err == nil {
+		t.Fatal("expected required warmer failure")
+	}
+	if srv.isReady.Load() {
+		t.Fatal("server should not be ready after a required warmer fails")
+	}
+
+	results := srv.WarmupResults()
+	if len(results) != 2 || results[0].Error != "cold" || results[1].Error != "unavailable" {
+		t.Errorf("unexpected warmup results %+v", results)
+	}
+}
+
+func TestWarmRoutesReportsErrors(t *testing.T) {
+	srv, err := NewServer()
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer srv.stopCleanup()
+
+	if err := WarmRoutes("/missing").Warm(context.Background(), srv); err == nil {
+		t.Error("expected error for a route returning 404")
+	}
+
+	// The final status counts, not the early hints before it
+	srv.HandleFunc("/hinted", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	if err := WarmRoutes("/hinted").Warm(context.Background(), srv); err == nil {
+		t.Error("expected error for a route returning 500 after early hints")
+	}
+}
+
+func TestWithCacheWarmerValidates(t *testing.T) {
+	if _, err := NewServer(WithCacheWarmer(CacheWarmer{Name: "nameless"})); err == nil {
+		t.Error("expected error for warmer without function")
+	}
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultWASMFuel         = 100_000_000
+	defaultWASMMemoryBytes  = 16 << 20
+	defaultWASMTimeout      = 5 * time.Second
+	defaultWASMMaxBodyBytes = 1 << 20
+)
+
+// Errors that a WASMRuntime returns when a module exceeds its limits.
+var (
+	ErrWASMFuelExhausted = errors.New("wasm: fuel exhausted")
+	ErrWASMMemoryLimit   = errors.New("wasm: memory limit exceeded")
+)
+
+// WASMLimits bounds the resources of a single call into a WebAssembly module.
+type WASMLimits struct {
+	Fuel           uint64        `json:"fuel,omitempty"`             // Instructions per call (default 100M)
+	MaxMemoryBytes uint64        `json:"max_memory_bytes,omitempty"` // Linear memory per instance (default 16 MiB)
+	Timeout        time.Duration `json:"timeout,omitempty"`          // Wall-clock limit per call (default 5s)
+}
+
+func (l WASMLimits) withDefaults() WASMLimits {
+	if l.Fuel == 0 {
+		l.Fuel = defaultWASMFuel
+	}
+	if l.MaxMemoryBytes == 0 {
+		l.MaxMemoryBytes = defaultWASMMemoryBytes
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = defaultWASMTimeout
+	}
+	return l
+}
+
+// WASMRuntime compiles WebAssembly modules for sandboxed execution.
+// HyperServe does not bundle a runtime; implementations typically wrap wazero or
+// wasmtime. The runtime must not give modules access to the host filesystem, network,
+// or environment. Compile receives the limits of the module so that they can be applied
+// to the runtime configuration, such as wazero's memory limit or wasmtime's fuel
+// consumption, which cannot be enabled per call.
+type WASMRuntime interface {
+	Compile(ctx context.Context, wasm []byte, limits WASMLimits) (WASMModule, error)
+}
+
+// WASMModule is a compiled module. Call instantiates it with fresh memory, passes input to
+// the exported function, and returns the function's output. Call must enforce limits,
+// returning ErrWASMFuelExhausted or ErrWASMMemoryLimit when they are exceeded, and stop
+// when ctx is done. Modules exchange JSON with the host.
+type WASMModule interface {
+	Call(ctx context.Context, function string, input []byte, limits WASMLimits) ([]byte, error)
+	Close(ctx context.Context) error
+}
+
+// WASMToolConfig describes an MCP tool implemented by a WebAssembly module.
+type WASMToolConfig struct {
+	Name        string
+	Description string
+	Schema      map[string]interface{} // Input schema, validated before the module runs
+	Module      []byte                 // The compiled .wasm binary
+	Function    string                 // Exported function (default "call")
+	Limits      WASMLimits
+}
+
+// NewWASMTool compiles an MCP tool from a WebAssembly module, so that untrusted or
+// tenant-supplied tools can run without access to the server process. The module receives
+// the tool arguments as a JSON object and returns the result as JSON.
+//
+// Example:
+//
+//	wasm, _ := os.ReadFile("tools/summarize.wasm")
+//	tool, err := server.NewWASMTool(ctx, runtime, server.WASMToolConfig{
+//		Name:        "summarize",
+//		Description: "Summarizes a document",
+//		Schema:      schema,
+//		Module:      wasm,
+//		Limits:      server.WASMLimits{Fuel: 10_000_000},
+//	})
+//	if err == nil {
+//		srv.RegisterMCPTool(tool)
+//	}
+func NewWASMTool(ctx context.Context, runtime WASMRuntime, cfg WASMToolConfig) (MCPToolWithContext, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("wasm tool name cannot be empty")
+	}
+	if cfg.Function == "" {
+		cfg.Function = "call"
+	}
+	if cfg.Schema == nil {
+		cfg.Schema = map[string]interface{}{"type": "object"}
+	}
+	cfg.Limits = cfg.Limits.withDefaults()
+	module, err := runtime.Compile(ctx, cfg.Module, cfg.Limits)
+	if err != nil {
+		return nil, fmt.Errorf("wasm tool %s: %w", cfg.Name, err)
+	}
+	return &wasmTool{cfg: cfg, module: module}, nil
+}
+
+type wasmTool struct {
+	cfg    WASMToolConfig
+	module WASMModule
+}
+
+func (t *wasmTool) Name() string                   { return t.cfg.Name }
+func (t *wasmTool) Description() string            { return t.cfg.Description }
+func (t *wasmTool) Schema() map[string]interface{} { return t.cfg.Schema }
+
+func (t *wasmTool) Execute(params map[string]interface{}) (interface{}, error) {
+	return t.ExecuteWithContext(context.Background(), params)
+}
+
+func (t *wasmTool) ExecuteWithContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	input, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+	output, err := callWASM(ctx, t.module, t.cfg.Function, input, t.cfg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("wasm tool %s returned invalid JSON: %w", t.cfg.Name, err)
+	}
+	return result, nil
+}
+
+// callWASM calls the module within the wall-clock limit.
+func callWASM(ctx context.Context, module WASMModule, function string, input []byte, limits WASMLimits) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	defer cancel()
+	output, err := module.Call(ctx, function, input, limits)
+	if err != nil {
+		if ctx.Err() != nil && !errors.Is(err, ErrWASMFuelExhausted) && !errors.Is(err, ErrWASMMemoryLimit) {
+			return nil, fmt.Errorf("wasm call %s: %w", function, ctx.Err())
+		}
+		return nil, fmt.Errorf("wasm call %s: %w", function, err)
+	}
+	return output, nil
+}
+
+// WASMTransformConfig describes a request transformer implemented by a WebAssembly module.
+type WASMTransformConfig struct {
+	Module             []byte
+	Function           string   // Exported function (default "transform")
+	MaxBodyBytes       int64    // Largest request body passed to the module (default 1 MiB)
+	AllowedHeaders     []string // Headers the module may set, see WASMTransformResult
+	ForwardCredentials bool     // Pass the Authorization and Cookie headers to the module
+	Limits             WASMLimits
+}
+
+// wasmCredentialHeaders are withheld from transformer modules unless ForwardCredentials
+// is set.
+var wasmCredentialHeaders = []string{"Authorization", "Cookie"}
+
+// WASMRequest is the JSON document a transformer module receives. The Authorization and
+// Cookie headers are left out unless WASMTransformConfig.ForwardCredentials is set.
+type WASMRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   string              `json:"query,omitempty"`
+	Headers map[string][]string `json:"headers"`
+	Body    []byte              `json:"body,omitempty"` // base64 in JSON
+}
+
+// WASMTransformResult is the JSON document a transformer module returns. A non-zero
+// Status of at least 200 rejects the request with that status and Body, and Headers are
+// set on the response; otherwise Headers are set on the request and Body, when set,
+// replaces the request body before it reaches the handler. Only headers listed in
+// WASMTransformConfig.AllowedHeaders are set, and an allowed header with no values is
+// removed; other headers are ignored.
+type WASMTransformResult struct {
+	Status  int                 `json:"status,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    []byte              `json:"body,omitempty"`
+}
+
+// WASMTransformMiddleware returns middleware that lets a WebAssembly module rewrite or
+// reject requests, for tenant-supplied request policies. If the module fails, the request
+// is rejected with 502 so that a broken module cannot be bypassed.
+//
+// Example:
+//
+//	transform, err := server.WASMTransformMiddleware(ctx, runtime, server.WASMTransformConfig{
+//		Module:         wasm,
+//		AllowedHeaders: []string{"X-Tenant-Plan"},
+//	})
+//	if err == nil {
+//		srv.AddMiddleware("/tenants/acme/", transform)
+//	}
+func WASMTransformMiddleware(ctx context.Context, runtime WASMRuntime, cfg WASMTransformConfig) (MiddlewareFunc, error) {
+	if cfg.Function == "" {
+		cfg.Function = "transform"
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultWASMMaxBodyBytes
+	}
+	cfg.Limits = cfg.Limits.withDefaults()
+	module, err := runtime.Compile(ctx, cfg.Module, cfg.Limits)
+	if err != nil {
+		return nil, fmt.Errorf("wasm transform: %w", err)
+	}
+	allowed := make(map[string]bool, len(cfg.AllowedHeaders))
+	for _, name := range cfg.AllowedHeaders {
+		allowed[http.CanonicalHeaderKey(name)] = true
+	}
+	// setAllowed copies the allowed headers of the result to h
+	setAllowed := func(h http.Header, result map[string][]string) {
+		for name, values := range result {
+			name = http.CanonicalHeaderKey(name)
+			if !allowed[name] {
+				continue
+			}
+			if len(values) == 0 {
+				h.Del(name)
+			} else {
+				h[name] = values
+			}
+		}
+	}
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, cfg.MaxBodyBytes+1))
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			if int64(len(body)) > cfg.MaxBodyBytes {
+				writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+
+			headers := r.Header
+			if !cfg.ForwardCredentials {
+				headers = r.Header.Clone()
+				for _, name := range wasmCredentialHeaders {
+					headers.Del(name)
+				}
+			}
+			input, _ := json.Marshal(WASMRequest{
+				Method:  r.Method,
+				Path:    r.URL.Path,
+				Query:   r.URL.RawQuery,
+				Headers: headers,
+				Body:    body,
+			})
+			output, err := callWASM(r.Context(), module, cfg.Function, input, cfg.Limits)
+			var result WASMTransformResult
+			if err == nil {
+				err = json.Unmarshal(output, &result)
+			}
+			if err == nil && result.Status != 0 && (result.Status < 200 || result.Status > 599) {
+				err = fmt.Errorf("invalid status %d", result.Status)
+			}
+			if err != nil {
+				logger.Error("WASM request transform failed", "path", r.URL.Path, "error", err)
+				writeErrorResponse(w, http.StatusBadGateway, "Request transform failed")
+				return
+			}
+
+			if result.Status != 0 {
+				setAllowed(w.Header(), result.Headers)
+				w.WriteHeader(result.Status)
+				w.Write(result.Body)
+				return
+			}
+			r = r.Clone(r.Context())
+			setAllowed(r.Header, result.Headers)
+			if result.Body != nil {
+				body = result.Body
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			next.ServeHTTP(w, r)
+		}
+	}, nil
+}
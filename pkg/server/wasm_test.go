@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeWASMRuntime runs Go functions in place of compiled modules, keyed by module bytes.
+type fakeWASMRuntime map[string]func(ctx context.Context, input []byte, limits WASMLimits) ([]byte, error)
+
+func (rt fakeWASMRuntime) Compile(ctx context.Context, wasm []byte, limits WASMLimits) (WASMModule, error) {
+	fn, ok := rt[string(wasm)]
+	if !ok {
+		return nil, errors.New("invalid module")
+	}
+	if limits.Fuel == 0 || limits.MaxMemoryBytes == 0 {
+		return nil, errors.New("runtime configured without limits")
+	}
+	return fakeWASMModule(fn), nil
+}
+
+type fakeWASMModule func(ctx context.Context, input []byte, limits WASMLimits) ([]byte, error)
+
+func (m fakeWASMModule) Call(ctx context.Context, function string, input []byte, limits WASMLimits) ([]byte, error) {
+	return m(ctx, input, limits)
+}
+
+func (m fakeWASMModule) Close(ctx context.Context) error { return nil }
+
+func TestWASMTool(t *testing.T) {
+	rt := fakeWASMRuntime{
+		"echo": func(ctx context.Context, input []byte, limits WASMLimits) ([]byte, error) {
+			if limits.Fuel != 1000 || limits.MaxMemoryBytes != defaultWASMMemoryBytes {
+				t.Errorf("unexpected limits %+v", limits)
+			}
+			return input, nil
+		},
+		"loop": func(ctx context.Context, input []byte, limits WASMLimits) ([]byte, error) {
+			return nil, ErrWASMFuelExhausted
+		},
+	}
+
+	tool, err := NewWASMTool(context.Background(), rt, WASMToolConfig{Name: "echo", Module: []byte("echo"), Limits: WASMLimits{Fuel: 1000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := tool.ExecuteWithContext(context.Background(), map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.(map[string]interface{})["text"] != "hi" {
+		t.Errorf("result = %v", result)
+	}
+
+	tool, err = NewWASMTool(context.Background(), rt, WASMToolConfig{Name: "loop", Module: []byte("loop")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Execute(nil); !errors.Is(err, ErrWASMFuelExhausted) {
+		t.Errorf("expected fuel exhaustion, got %v", err)
+	}
+
+	if _, err := NewWASMTool(context.Background(), rt, WASMToolConfig{Name: "bad", Module: []byte("garbage")}); err == nil {
+		t.Error("expected a compile error")
+	}
+}
+
+func TestWASMTransformMiddleware(t *testing.T) {
+	rt := fakeWASMRuntime{
+		"policy": func(ctx context.Context, input []byte, limits WASMLimits) ([]byte, error) {
+			var req WASMRequest
+			if err := json.Unmarshal(input, &req); err != nil {
+				return nil, err
+			}
+			if req.Headers["Authorization"] != nil || req.Headers["Cookie"] != nil {
+				return nil, errors.New("module received credentials")
+			}
+			if req.Headers["X-Tenant"] == nil {
+				return json.Marshal(WASMTransformResult{
+					Status:  http.StatusForbidden,
+					Headers: map[string][]string{"X-Reason": {"tenant"}, "Set-Cookie": {"session=x"}},
+					Body:    []byte("tenant required"),
+				})
+			}
+			if req.Headers["X-Upgrade"] != nil {
+				return json.Marshal(WASMTransformResult{Status: http.StatusSwitchingProtocols})
+			}
+			req.Headers["X-Transformed"] = []string{"1"}
+			req.Headers["Authorization"] = []string{"Bearer forged"}
+			req.Headers["X-Tenant"] = []string{}
+			return json.Marshal(WASMTransformResult{Headers: req.Headers, Body: []byte(strings.ToUpper(string(req.Body)))})
+		},
+		"broken": func(ctx context.Context, input []byte, limits WASMLimits) ([]byte, error) {
+			return nil, ErrWASMMemoryLimit
+		},
+	}
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Header.Get("X-Transformed") + ":" + r.Header.Get("X-Tenant") + ":" + r.Header.Get("Authorization") + ":" + string(body)))
+	})
+
+	mw, err := WASMTransformMiddleware(context.Background(), rt, WASMTransformConfig{
+		Module:         []byte("policy"),
+		AllowedHeaders: []string{"x-transformed", "X-Tenant", "X-Reason"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := mw(echo)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello"))
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("Authorization", "Bearer user")
+	req.Header.Set("Cookie", "session=s")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Body.String() != "1::Bearer user:HELLO" {
+		t.Errorf("transformed request = %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello")))
+	if rec.Code != http.StatusForbidden || rec.Body.String() != "tenant required" {
+		t.Errorf("rejected request: got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Reason") != "tenant" || rec.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expected only allowed response headers, got %v", rec.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Upgrade", "1")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("informational status: expected 502, got %d", rec.Code)
+	}
+
+	mw, err = WASMTransformMiddleware(context.Background(), rt, WASMTransformConfig{Module: []byte("broken")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	mw(echo).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("failing module: expected 502, got %d", rec.Code)
+	}
+}
@@ -1,14 +1,21 @@
 package server
 
 import (
+	"context"
 	"net/http"
 
 	pkgwebsocket "github.com/osauer/hyperserve/pkg/websocket"
 )
 
 type (
-	Upgrader = pkgwebsocket.Upgrader
-	Conn     = pkgwebsocket.Conn
+	Upgrader        = pkgwebsocket.Upgrader
+	Conn            = pkgwebsocket.Conn
+	ConnLimits      = pkgwebsocket.ConnLimits
+	OversizePolicy  = pkgwebsocket.OversizePolicy
+	RateLimitPolicy = pkgwebsocket.RateLimitPolicy
+
+	// WebSocketDialOptions configures DialWebSocket
+	WebSocketDialOptions = pkgwebsocket.DialOptions
 )
 
 const (
@@ -31,11 +38,18 @@ const (
 	CloseServiceRestart          = pkgwebsocket.CloseServiceRestart
 	CloseTryAgainLater           = pkgwebsocket.CloseTryAgainLater
 	CloseTLSHandshake            = pkgwebsocket.CloseTLSHandshake
+	OversizeClose                = pkgwebsocket.OversizeClose
+	OversizeDrop                 = pkgwebsocket.OversizeDrop
+	RateLimitClose               = pkgwebsocket.RateLimitClose
+	RateLimitDrop                = pkgwebsocket.RateLimitDrop
+	RateLimitThrottle            = pkgwebsocket.RateLimitThrottle
 )
 
 var (
-	ErrNotWebSocket = pkgwebsocket.ErrNotWebSocket
-	ErrBadHandshake = pkgwebsocket.ErrBadHandshake
+	ErrNotWebSocket  = pkgwebsocket.ErrNotWebSocket
+	ErrBadHandshake  = pkgwebsocket.ErrBadHandshake
+	ErrMessageTooBig = pkgwebsocket.ErrMessageTooBig
+	ErrRateLimited   = pkgwebsocket.ErrRateLimited
 )
 
 // DefaultCheckOrigin wraps pkg/websocket.DefaultCheckOrigin for internal use.
@@ -48,6 +62,12 @@ func CheckOriginWithAllowedList(allowedOrigins []string) func(r *http.Request) b
 	return pkgwebsocket.CheckOriginWithAllowedList(allowedOrigins)
 }
 
+// DialWebSocket wraps pkg/websocket.DialWebSocket: it connects to a ws:// or wss:// URL
+// and returns a Conn that pings the server and, optionally, reconnects with backoff.
+func DialWebSocket(ctx context.Context, url string, opts ...WebSocketDialOptions) (*Conn, error) {
+	return pkgwebsocket.DialWebSocket(ctx, url, opts...)
+}
+
 func IsCloseError(err error, codes ...int) bool {
 	return pkgwebsocket.IsCloseError(err, codes...)
 }
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// WebSocketMessage is a JSON-RPC 2.0 message received by a WebSocketRouter.
+type WebSocketMessage struct {
+	Conn   *Conn           // Connection the message arrived on
+	Method string          // Name the handler was registered under
+	Params json.RawMessage // Raw params, decode them with Bind
+	ID     any             // Request ID, nil for notifications, which get no response
+}
+
+// Bind decodes the message params into v. Missing params leave v unchanged.
+func (m *WebSocketMessage) Bind(v any) error {
+	if len(m.Params) == 0 || string(m.Params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(m.Params, v); err != nil {
+		return &JSONRPCError{Code: ErrorCodeInvalidParams, Message: "Invalid params", Data: err.Error()}
+	}
+	return nil
+}
+
+// WebSocketHandler handles one message routed by a WebSocketRouter. The result is sent
+// back as the JSON-RPC result; an error is sent as a JSON-RPC error, with the code of a
+// *JSONRPCError or ErrorCodeInternalError otherwise.
+type WebSocketHandler func(ctx context.Context, msg *WebSocketMessage) (any, error)
+
+// WebSocketMiddleware wraps the handlers of a WebSocketRouter, e.g. to log, authorise,
+// or time messages.
+type WebSocketMiddleware func(next WebSocketHandler) WebSocketHandler
+
+// WebSocketHandle adapts a typed function to a WebSocketHandler: params are decoded into
+// P, and params that do not decode are answered with ErrorCodeInvalidParams.
+//
+// Example:
+//
+//	type JoinParams struct {
+//		Room string `json:"room"`
+//	}
+//
+//	router.On("join", server.WebSocketHandle(func(ctx context.Context, conn *server.Conn, p JoinParams) (string, error) {
+//		return "joined " + p.Room, hub.Join(p.Room, conn)
+//	}))
+func WebSocketHandle[P, R any](fn func(ctx context.Context, conn *Conn, params P) (R, error)) WebSocketHandler {
+	return func(ctx context.Context, msg *WebSocketMessage) (any, error) {
+		var params P
+		if err := msg.Bind(&params); err != nil {
+			return nil, err
+		}
+		return fn(ctx, msg.Conn, params)
+	}
+}
+
+type websocketMessageKey struct{}
+
+// WebSocketRouter dispatches JSON-RPC 2.0 messages received on WebSocket connections to
+// the handler registered for their method, instead of a switch over a message type in
+// every read loop. Requests are answered with a response carrying their ID, malformed
+// messages and unknown methods with the matching JSON-RPC error, and notifications, which
+// have no ID, get no response. Messages of one connection are handled in order.
+//
+// Example:
+//
+//	router := server.NewWebSocketRouter()
+//	router.Use(func(next server.WebSocketHandler) server.WebSocketHandler {
+//		return func(ctx context.Context, msg *server.WebSocketMessage) (any, error) {
+//			log.Printf("%s from %s", msg.Method, msg.Conn.RemoteAddr())
+//			return next(ctx, msg)
+//		}
+//	})
+//	router.On("ping", func(ctx context.Context, msg *server.WebSocketMessage) (any, error) {
+//		return "pong", nil
+//	})
+//	srv.HandleFunc("/ws", router.Handler(srv.WebSocketUpgrader()))
+//
+//	// Client: {"jsonrpc":"2.0","method":"ping","id":1} -> {"jsonrpc":"2.0","result":"pong","id":1}
+type WebSocketRouter struct {
+	engine *JSONRPCEngine
+
+	mu         sync.RWMutex
+	middleware []WebSocketMiddleware
+}
+
+// NewWebSocketRouter creates a router without handlers.
+func NewWebSocketRouter() *WebSocketRouter {
+	return &WebSocketRouter{engine: NewJSONRPCEngine()}
+}
+
+// On registers the handler for method, replacing any previous handler. Register handlers
+// before serving connections.
+func (rt *WebSocketRouter) On(method string, handler WebSocketHandler) {
+	rt.engine.RegisterMethodWithContext(method, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		msg, _ := ctx.Value(websocketMessageKey{}).(*WebSocketMessage)
+		if msg == nil {
+			return nil, fmt.Errorf("WebSocket method %q called outside a router", method)
+		}
+		rt.mu.RLock()
+		h := handler
+		for i := len(rt.middleware) - 1; i >= 0; i-- {
+			h = rt.middleware[i](h)
+		}
+		rt.mu.RUnlock()
+		return h(ctx, msg)
+	})
+}
+
+// Use adds middleware that wraps every handler, outermost first.
+func (rt *WebSocketRouter) Use(middleware ...WebSocketMiddleware) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.middleware = append(rt.middleware, middleware...)
+}
+
+// Methods returns the registered methods in sorted order.
+func (rt *WebSocketRouter) Methods() []string {
+	return rt.engine.GetRegisteredMethods()
+}
+
+// Serve reads messages from conn and dispatches them until the connection fails or is
+// closed. The error that ended the read loop is returned; closures with
+// CloseNormalClosure or CloseGoingAway return nil.
+func (rt *WebSocketRouter) Serve(ctx context.Context, conn *Conn) error {
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			if IsCloseError(err, CloseNormalClosure, CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+		if messageType == CloseMessage {
+			return nil
+		}
+		response := rt.dispatch(ctx, conn, messageType, data)
+		if response == nil {
+			continue
+		}
+		if err := conn.WriteJSON(response); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch handles one message and returns the response, nil for notifications.
+func (rt *WebSocketRouter) dispatch(ctx context.Context, conn *Conn, messageType int, data []byte) *JSONRPCResponse {
+	if messageType != TextMessage {
+		return &JSONRPCResponse{
+			JSONRPC: JSONRPCVersion,
+			Error:   &JSONRPCError{Code: ErrorCodeInvalidRequest, Message: "Invalid Request", Data: "expected a text message"},
+		}
+	}
+	var envelope struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params,omitempty"`
+		ID      any             `json:"id,omitempty"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return &JSONRPCResponse{
+			JSONRPC: JSONRPCVersion,
+			Error:   &JSONRPCError{Code: ErrorCodeParseError, Message: "Parse error", Data: err.Error()},
+		}
+	}
+	msg := &WebSocketMessage{Conn: conn, Method: envelope.Method, Params: envelope.Params, ID: envelope.ID}
+	response := rt.engine.ProcessRequestContext(context.WithValue(ctx, websocketMessageKey{}, msg), &JSONRPCRequest{
+		JSONRPC: envelope.JSONRPC,
+		Method:  envelope.Method,
+		ID:      envelope.ID,
+	})
+	if envelope.ID == nil {
+		if response.Error != nil {
+			logger.Debug("WebSocket notification failed", "method", envelope.Method, "error", response.Error.Message)
+		}
+		return nil
+	}
+	return response
+}
+
+// Handler returns a handler that upgrades requests with upgrader and serves the
+// connection with the router. A nil upgrader accepts same-origin requests only.
+func (rt *WebSocketRouter) Handler(upgrader *Upgrader) http.HandlerFunc {
+	if upgrader == nil {
+		upgrader = &Upgrader{}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Debug("WebSocket upgrade failed", "error", err, "remote_addr", r.RemoteAddr)
+			return
+		}
+		defer conn.Close()
+		if err := rt.Serve(r.Context(), conn); err != nil {
+			logger.Debug("WebSocket connection ended", "error", err, "remote_addr", r.RemoteAddr)
+		}
+	}
+}
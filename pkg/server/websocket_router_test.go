@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+func TestWebSocketRouter(t *testing.T) {
+	type addParams struct {
+		A, B int
+	}
+	router := NewWebSocketRouter()
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	router.Use(func(next WebSocketHandler) WebSocketHandler {
+		return func(ctx context.Context, msg *WebSocketMessage) (any, error) {
+			mu.Lock()
+			calls = append(calls, msg.Method)
+			mu.Unlock()
+			return next(ctx, msg)
+		}
+	})
+	router.On("ping", func(ctx context.Context, msg *WebSocketMessage) (any, error) {
+		return "pong", nil
+	})
+	router.On("add", WebSocketHandle(func(ctx context.Context, conn *Conn, p addParams) (int, error) {
+		return p.A + p.B, nil
+	}))
+	router.On("fail", func(ctx context.Context, msg *WebSocketMessage) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	ts := httptest.NewServer(router.Handler(&Upgrader{CheckOrigin: func(*http.Request) bool { return true }}))
+	defer ts.Close()
+	_, reader, writer := dialGatewayWebSocket(t, ts.URL)
+
+	tests := []struct {
+		request string
+		want    string
+	}{
+		{`{"jsonrpc":"2.0","method":"ping","id":1}`, `{"jsonrpc":"2.0","result":"pong","id":1}`},
+		{`{"jsonrpc":"2.0","method":"add","params":{"A":2,"B":3},"id":2}`, `{"jsonrpc":"2.0","result":5,"id":2}`},
+		{`{"jsonrpc":"2.0","method":"add","params":[1],"id":3}`, `"code":-32602`},
+		{`{"jsonrpc":"2.0","method":"missing","id":4}`, `"code":-32601`},
+		{`{"jsonrpc":"2.0","method":"fail","id":5}`, `"code":-32603`},
+		{`{not json`, `"code":-32700`},
+	}
+	for _, tt := range tests {
+		writeClientFrame(t, writer, ws.OpcodeText, []byte(tt.request))
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			t.Fatalf("read response to %s: %v", tt.request, err)
+		}
+		if !json.Valid(frame.Payload) || !strings.Contains(string(frame.Payload), tt.want) {
+			t.Errorf("request %s: expected response containing %s, got %s", tt.request, tt.want, frame.Payload)
+		}
+	}
+
+	// Notifications are handled without a response, so the next frame answers the ping
+	writeClientFrame(t, writer, ws.OpcodeText, []byte(`{"jsonrpc":"2.0","method":"fail"}`))
+	writeClientFrame(t, writer, ws.OpcodeText, []byte(`{"jsonrpc":"2.0","method":"ping","id":6}`))
+	if frame, err := reader.ReadFrame(); err != nil || string(frame.Payload) != `{"jsonrpc":"2.0","result":"pong","id":6}` {
+		t.Errorf("expected only the ping response, got %s: %v", frame.Payload, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"ping", "add", "add", "fail", "fail", "ping"}; !slices.Equal(calls, want) {
+		t.Errorf("middleware saw %v, want %v", calls, want)
+	}
+}
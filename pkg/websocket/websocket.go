@@ -1,14 +1,17 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/osauer/hyperserve/internal/ws"
+	"golang.org/x/time/rate"
 )
 
 // WebSocket message types
@@ -40,12 +43,59 @@ const (
 
 // WebSocket errors
 var (
-	ErrNotWebSocket = ws.ErrNotWebSocket
-	ErrBadHandshake = ws.ErrBadHandshake
+	ErrNotWebSocket  = ws.ErrNotWebSocket
+	ErrBadHandshake  = ws.ErrBadHandshake
+	ErrMessageTooBig = ws.ErrMessageTooBig
+	ErrRateLimited   = errors.New("message rate limit exceeded")
 )
 
+// OversizePolicy decides what happens to a message over ConnLimits.MaxMessageSize
+type OversizePolicy string
+
+const (
+	// OversizeClose closes the connection with CloseMessageTooBig (default)
+	OversizeClose OversizePolicy = "close"
+	// OversizeDrop skips the message and keeps reading
+	OversizeDrop OversizePolicy = "drop"
+)
+
+// RateLimitPolicy decides what happens to a message over ConnLimits.MessagesPerSecond
+type RateLimitPolicy string
+
+const (
+	// RateLimitClose closes the connection with ClosePolicyViolation (default)
+	RateLimitClose RateLimitPolicy = "close"
+	// RateLimitDrop skips the message and keeps reading
+	RateLimitDrop RateLimitPolicy = "drop"
+	// RateLimitThrottle delays reading until the rate allows the next message, which
+	// slows the peer down through TCP flow control
+	RateLimitThrottle RateLimitPolicy = "throttle"
+)
+
+// ConnLimits bounds what a single peer may send, so that one misbehaving client
+// cannot saturate the handlers and broadcasts fed by its read loop
+type ConnLimits struct {
+	// MaxMessageSize is the maximum size of a message read from the peer, overriding
+	// Upgrader.MaxMessageSize when positive
+	MaxMessageSize int64
+
+	// OversizePolicy applies to messages over MaxMessageSize (default OversizeClose)
+	OversizePolicy OversizePolicy
+
+	// MessagesPerSecond is the sustained rate of data messages read from the peer;
+	// zero means unlimited. Control messages are not counted
+	MessagesPerSecond float64
+
+	// Burst is the number of messages allowed in excess of the rate (default 1)
+	Burst int
+
+	// RateLimitPolicy applies to messages over the rate (default RateLimitClose)
+	RateLimitPolicy RateLimitPolicy
+}
+
 // Conn represents a WebSocket connection
 type Conn struct {
+	connMu       sync.RWMutex // guards conn, which a client replaces when it reconnects
 	conn         *ws.Conn
 	pingInterval time.Duration
 	pongTimeout  time.Duration
@@ -57,6 +107,14 @@ type Conn struct {
 
 	// Handler mutex for thread safety
 	handlerMu sync.Mutex
+
+	// Read limits, see SetLimits
+	limits  ConnLimits
+	limiter *rate.Limiter
+	dropped atomic.Int64
+
+	// Client state, nil for connections accepted by an Upgrader; see DialWebSocket
+	client *clientConn
 }
 
 // Upgrader upgrades HTTP connections to WebSocket connections
@@ -175,10 +233,83 @@ func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeade
 	return c, nil
 }
 
-// ReadMessage reads a message from the WebSocket connection
+// current returns the underlying connection
+func (c *Conn) current() *ws.Conn {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.conn
+}
+
+// ReadMessage reads a message from the WebSocket connection, enforcing the limits set
+// with SetLimits. A message over the size limit closes the connection with
+// CloseMessageTooBig and returns ErrMessageTooBig, and a message over the rate limit
+// closes it with ClosePolicyViolation and returns ErrRateLimited, unless the policies
+// drop such messages instead.
 func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	for {
+		if c.limiter != nil && c.limits.RateLimitPolicy == RateLimitThrottle {
+			// Not reading applies backpressure to the peer through TCP flow control
+			if err := c.limiter.Wait(context.Background()); err != nil {
+				return 0, nil, err
+			}
+		}
+		messageType, p, err = c.readMessage()
+		if err != nil && !errors.Is(err, ws.ErrMessageTooBig) && c.client != nil && c.client.redial(c, err) {
+			continue
+		}
+		if errors.Is(err, ws.ErrMessageTooBig) {
+			if c.limits.OversizePolicy == OversizeDrop {
+				c.dropped.Add(1)
+				continue
+			}
+			c.current().CloseWithCode(CloseMessageTooBig, "message too big")
+			return messageType, p, err
+		}
+		if err != nil || c.limiter == nil || c.limits.RateLimitPolicy == RateLimitThrottle {
+			return messageType, p, err
+		}
+		if (messageType == TextMessage || messageType == BinaryMessage) && !c.limiter.Allow() {
+			if c.limits.RateLimitPolicy == RateLimitDrop {
+				c.dropped.Add(1)
+				continue
+			}
+			c.current().CloseWithCode(ClosePolicyViolation, "message rate limit exceeded")
+			return 0, nil, ErrRateLimited
+		}
+		return messageType, p, nil
+	}
+}
+
+// SetLimits sets the read limits of the connection. Call it before the read loop
+// starts; it blocks while a read is in progress.
+func (c *Conn) SetLimits(limits ConnLimits) {
+	if limits.OversizePolicy == "" {
+		limits.OversizePolicy = OversizeClose
+	}
+	if limits.RateLimitPolicy == "" {
+		limits.RateLimitPolicy = RateLimitClose
+	}
+	if limits.Burst <= 0 {
+		limits.Burst = 1
+	}
+	c.limits = limits
+	c.limiter = nil
+	if limits.MessagesPerSecond > 0 {
+		c.limiter = rate.NewLimiter(rate.Limit(limits.MessagesPerSecond), limits.Burst)
+	}
+	c.current().SetMaxMessageSize(limits.MaxMessageSize, limits.OversizePolicy == OversizeDrop)
+}
+
+// DroppedMessages returns the number of messages skipped by the OversizeDrop and
+// RateLimitDrop policies
+func (c *Conn) DroppedMessages() int64 {
+	return c.dropped.Load()
+}
+
+// readMessage reads the next message, handling control messages
+func (c *Conn) readMessage() (messageType int, p []byte, err error) {
 	// Read the message
-	messageType, p, err = c.conn.ReadMessage()
+	messageType, p, err = c.current().ReadMessage()
 	if err != nil {
 		return messageType, p, err
 	}
@@ -195,7 +326,7 @@ func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
 			}
 		}
 		// Continue reading for the next message
-		return c.ReadMessage()
+		return c.readMessage()
 
 	case PongMessage:
 		c.handlerMu.Lock()
@@ -207,7 +338,7 @@ func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
 			}
 		}
 		// Continue reading for the next message
-		return c.ReadMessage()
+		return c.readMessage()
 
 	case CloseMessage:
 		var code int
@@ -237,19 +368,23 @@ func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
 
 // WriteMessage writes a message to the WebSocket connection
 func (c *Conn) WriteMessage(messageType int, data []byte) error {
-	return c.conn.WriteMessage(messageType, data)
+	return c.current().WriteMessage(messageType, data)
 }
 
 // WriteControl writes a control message with the given deadline
 func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
-	c.conn.SetWriteDeadline(deadline)
-	defer c.conn.SetWriteDeadline(time.Time{})
-	return c.conn.WriteControl(messageType, data)
+	conn := c.current()
+	conn.SetWriteDeadline(deadline)
+	defer conn.SetWriteDeadline(time.Time{})
+	return conn.WriteControl(messageType, data)
 }
 
-// Close closes the WebSocket connection
+// Close closes the WebSocket connection. A client connection stops reconnecting.
 func (c *Conn) Close() error {
-	return c.conn.Close()
+	if c.client != nil {
+		c.client.close()
+	}
+	return c.current().Close()
 }
 
 // CloseHandler returns the current close handler
@@ -344,22 +479,22 @@ func (c *Conn) SetPongHandler(h func(appData string) error) {
 
 // SetReadDeadline sets the read deadline on the connection
 func (c *Conn) SetReadDeadline(t time.Time) error {
-	return c.conn.SetReadDeadline(t)
+	return c.current().SetReadDeadline(t)
 }
 
 // SetWriteDeadline sets the write deadline on the connection
 func (c *Conn) SetWriteDeadline(t time.Time) error {
-	return c.conn.SetWriteDeadline(t)
+	return c.current().SetWriteDeadline(t)
 }
 
 // LocalAddr returns the local network address
 func (c *Conn) LocalAddr() net.Addr {
-	return c.conn.LocalAddr()
+	return c.current().LocalAddr()
 }
 
 // RemoteAddr returns the remote network address
 func (c *Conn) RemoteAddr() net.Addr {
-	return c.conn.RemoteAddr()
+	return c.current().RemoteAddr()
 }
 
 // WriteJSON writes a JSON-encoded message to the connection
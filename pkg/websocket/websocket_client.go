@@ -0,0 +1,312 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+const (
+	defaultDialHandshakeTimeout = 10 * time.Second
+	defaultDialMinBackoff       = 500 * time.Millisecond
+	defaultDialMaxBackoff       = 30 * time.Second
+	defaultDialPingInterval     = 30 * time.Second
+	defaultDialPongTimeout      = 10 * time.Second
+)
+
+// DialOptions configures a client connection opened with DialWebSocket
+type DialOptions struct {
+	// Header is sent with the handshake request, e.g. Authorization or Origin
+	Header http.Header
+
+	// Subprotocols are offered to the server in order of preference
+	Subprotocols []string
+
+	// TLSConfig is used for wss:// URLs; the server name defaults to the URL host
+	TLSConfig *tls.Config
+
+	// HandshakeTimeout bounds connecting, the TLS handshake, and the upgrade (default 10s)
+	HandshakeTimeout time.Duration
+
+	// MaxMessageSize is the maximum size of a message read from the server (default 1MB)
+	MaxMessageSize int64
+
+	// Reconnect redials the server when the connection fails or the server closes it,
+	// after which ReadMessage continues with the new connection
+	Reconnect bool
+
+	// MaxReconnectAttempts stops reconnecting after this many failed dials in a row;
+	// zero means no limit
+	MaxReconnectAttempts int
+
+	// MinBackoff is the delay before the first reconnect attempt, doubled per failed
+	// attempt up to MaxBackoff (defaults 500ms and 30s)
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// PingInterval is how often the client pings the server (default 30s); negative
+	// disables pings
+	PingInterval time.Duration
+
+	// PongTimeout is how long after a missed pong the connection is considered dead,
+	// which fails ReadMessage or triggers a reconnect (default 10s)
+	PongTimeout time.Duration
+
+	// OnConnect is called after each successful dial, including reconnects, e.g. to
+	// resubscribe to a feed
+	OnConnect func(conn *Conn)
+
+	// OnDisconnect is called with the cause when a connection is lost and about to be
+	// redialled
+	OnDisconnect func(conn *Conn, err error)
+}
+
+// clientConn holds the state of a connection opened with DialWebSocket
+type clientConn struct {
+	url  *url.URL
+	opts DialOptions
+
+	closed     chan struct{}
+	closeOnce  sync.Once
+	reconnects atomic.Int64
+}
+
+// DialWebSocket connects to a ws:// or wss:// URL and returns the same Conn type that
+// Upgrader.Upgrade returns, so services can consume WebSocket feeds with the API they
+// serve them with. The client pings the server to detect dead connections and, with
+// DialOptions.Reconnect, redials it with exponential backoff; ReadMessage then carries
+// on with the new connection, while writes during a reconnect fail.
+//
+// Example:
+//
+//	conn, err := websocket.DialWebSocket(ctx, "wss://feed.example.com/prices", websocket.DialOptions{
+//		Reconnect: true,
+//		OnConnect: func(conn *websocket.Conn) {
+//			conn.WriteJSON(map[string]string{"subscribe": "EURUSD"})
+//		},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer conn.Close()
+//	for {
+//		_, msg, err := conn.ReadMessage()
+//		if err != nil {
+//			return err
+//		}
+//		hub.Broadcast("prices", msg)
+//	}
+func DialWebSocket(ctx context.Context, rawURL string, opts ...DialOptions) (*Conn, error) {
+	var o DialOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.HandshakeTimeout <= 0 {
+		o.HandshakeTimeout = defaultDialHandshakeTimeout
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = defaultDialMinBackoff
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = max(defaultDialMaxBackoff, o.MinBackoff)
+	}
+	if o.PingInterval == 0 {
+		o.PingInterval = defaultDialPingInterval
+	}
+	if o.PongTimeout <= 0 {
+		o.PongTimeout = defaultDialPongTimeout
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebSocket URL: %w", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("unsupported WebSocket URL scheme %q, use ws or wss", u.Scheme)
+	}
+
+	cc := &clientConn{url: u, opts: o, closed: make(chan struct{})}
+	wsConn, err := cc.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{conn: wsConn, client: cc}
+	c.SetCloseHandler(nil)
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	cc.connected(c, wsConn)
+	return c, nil
+}
+
+// Reconnects returns how often a client connection was redialled; it is zero for
+// connections accepted by an Upgrader
+func (c *Conn) Reconnects() int64 {
+	if c.client == nil {
+		return 0
+	}
+	return c.client.reconnects.Load()
+}
+
+// dial connects to the server and performs the opening handshake.
+func (cc *clientConn) dial(ctx context.Context) (*ws.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, cc.opts.HandshakeTimeout)
+	defer cancel()
+
+	addr := cc.url.Host
+	if cc.url.Port() == "" {
+		port := "80"
+		if cc.url.Scheme == "wss" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(cc.url.Hostname(), port)
+	}
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if cc.url.Scheme == "wss" {
+		cfg := cc.opts.TLSConfig.Clone()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = cc.url.Hostname()
+		}
+		tlsConn := tls.Client(netConn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		netConn = tlsConn
+	}
+
+	deadline, _ := ctx.Deadline()
+	netConn.SetDeadline(deadline)
+	buf, _, err := ws.ClientHandshake(netConn, cc.url, cc.opts.Header, cc.opts.Subprotocols)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetDeadline(time.Time{})
+	return ws.NewConn(netConn, buf, false, cc.opts.MaxMessageSize), nil
+}
+
+// connected prepares a new underlying connection of c and starts pinging the server.
+func (cc *clientConn) connected(c *Conn, wsConn *ws.Conn) {
+	if cc.opts.PingInterval > 0 {
+		timeout := cc.opts.PingInterval + cc.opts.PongTimeout
+		wsConn.SetReadDeadline(time.Now().Add(timeout))
+		wsConn.SetPongHandler(func(data []byte) {
+			wsConn.SetReadDeadline(time.Now().Add(timeout))
+			c.PongHandler()(string(data))
+		})
+		go cc.keepalive(c, wsConn)
+	}
+	if cc.opts.OnConnect != nil {
+		cc.opts.OnConnect(c)
+	}
+}
+
+// keepalive pings the server until the connection is replaced or closed. A connection
+// whose pongs stop fails its next read once the read deadline passes.
+func (cc *clientConn) keepalive(c *Conn, wsConn *ws.Conn) {
+	ticker := time.NewTicker(cc.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cc.closed:
+			return
+		case <-ticker.C:
+		}
+		if c.current() != wsConn {
+			return
+		}
+		if err := wsConn.WriteControl(ws.OpcodePing, nil); err != nil {
+			wsConn.Close()
+			return
+		}
+	}
+}
+
+// redial replaces the failed connection of c, retrying with backoff, and reports whether
+// c is connected again. It gives up when reconnecting is disabled, c was closed, or the
+// attempts are exhausted.
+func (cc *clientConn) redial(c *Conn, cause error) bool {
+	if !cc.opts.Reconnect || cc.isClosed() {
+		return false
+	}
+	c.current().Close()
+	if cc.opts.OnDisconnect != nil {
+		cc.opts.OnDisconnect(c, cause)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-cc.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for attempt := 0; cc.opts.MaxReconnectAttempts <= 0 || attempt < cc.opts.MaxReconnectAttempts; attempt++ {
+		timer := time.NewTimer(cc.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		wsConn, err := cc.dial(ctx)
+		if err != nil {
+			continue
+		}
+		wsConn.SetMaxMessageSize(c.limits.MaxMessageSize, c.limits.OversizePolicy == OversizeDrop)
+		c.connMu.Lock()
+		c.conn = wsConn
+		c.connMu.Unlock()
+		if cc.isClosed() {
+			// Closed while dialling; Close may have missed the new connection
+			wsConn.Close()
+			return false
+		}
+		cc.reconnects.Add(1)
+		cc.connected(c, wsConn)
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before a reconnect attempt, with jitter so that clients
+// dropped together do not redial together.
+func (cc *clientConn) backoff(attempt int) time.Duration {
+	d := cc.opts.MinBackoff << min(attempt, 32)
+	if d <= 0 || d > cc.opts.MaxBackoff {
+		d = cc.opts.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func (cc *clientConn) close() {
+	cc.closeOnce.Do(func() { close(cc.closed) })
+}
+
+func (cc *clientConn) isClosed() bool {
+	select {
+	case <-cc.closed:
+		return true
+	default:
+		return false
+	}
+}
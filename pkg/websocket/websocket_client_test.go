@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func wsURL(httpURL string) string {
+	return "ws" + strings.TrimPrefix(httpURL, "http")
+}
+
+func TestDialWebSocketEcho(t *testing.T) {
+	upgrader := Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	ts := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, payload); err != nil {
+				return
+			}
+		}
+	}))
+
+	var pongs atomic.Int64
+	conn, err := DialWebSocket(context.Background(), wsURL(ts.URL), DialOptions{PingInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetPongHandler(func(string) error {
+		pongs.Add(1)
+		return nil
+	})
+
+	if err := conn.WriteJSON(map[string]string{"hello": "server"}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	var reply map[string]string
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if reply["hello"] != "server" {
+		t.Fatalf("unexpected echo: %v", reply)
+	}
+
+	// Pongs are handled while reading
+	go conn.ReadMessage()
+	waitUntil(t, func() bool { return pongs.Load() > 0 })
+}
+
+func TestDialWebSocketReconnect(t *testing.T) {
+	upgrader := Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	var accepted atomic.Int64
+	ts := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		n := accepted.Add(1)
+		conn.WriteMessage(TextMessage, []byte(fmt.Sprintf("hello %d", n)))
+		if n == 1 {
+			// Drop the first connection, as a restarting server would
+			conn.Close()
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+
+	var connects, disconnects atomic.Int64
+	conn, err := DialWebSocket(context.Background(), wsURL(ts.URL), DialOptions{
+		Reconnect:    true,
+		MinBackoff:   time.Millisecond,
+		OnConnect:    func(*Conn) { connects.Add(1) },
+		OnDisconnect: func(*Conn, error) { disconnects.Add(1) },
+	})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	for _, want := range []string{"hello 1", "hello 2"} {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if string(msg) != want {
+			t.Fatalf("expected %q, got %q", want, msg)
+		}
+	}
+	if conn.Reconnects() != 1 || connects.Load() != 2 || disconnects.Load() != 1 {
+		t.Fatalf("expected one reconnect, got reconnects=%d connects=%d disconnects=%d",
+			conn.Reconnects(), connects.Load(), disconnects.Load())
+	}
+
+	// Closing the client stops reconnecting
+	conn.Close()
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("expected read on a closed connection to fail")
+	}
+	if accepted.Load() != 2 {
+		t.Fatalf("expected no further dials after Close, got %d connections", accepted.Load())
+	}
+}
+
+func TestDialWebSocketErrors(t *testing.T) {
+	if _, err := DialWebSocket(context.Background(), "http://example.com/ws"); err == nil {
+		t.Fatal("expected an error for a non-WebSocket scheme")
+	}
+
+	ts := newTestServer(t, http.NotFoundHandler())
+	_, err := DialWebSocket(context.Background(), wsURL(ts.URL))
+	if err == nil || !strings.Contains(err.Error(), ErrBadHandshake.Error()) {
+		t.Fatalf("expected a bad handshake error, got %v", err)
+	}
+}
@@ -0,0 +1,372 @@
+package websocket
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultHubWriteTimeout bounds how long one slow member can hold up a broadcast.
+const defaultHubWriteTimeout = 5 * time.Second
+
+// ErrHubClosed is returned when joining a Hub that has been closed.
+var ErrHubClosed = errors.New("websocket hub closed")
+
+// HubConfig configures a Hub
+type HubConfig struct {
+	// WriteTimeout is the deadline of each write to a member; members that miss it or
+	// fail are removed from all rooms and closed (default 5s)
+	WriteTimeout time.Duration
+
+	// Pool, if set, closes removed members through the pool so its statistics stay
+	// accurate
+	Pool *WebSocketPool
+
+	// OnJoin is called after a connection joins a room
+	OnJoin func(room string, member Member)
+
+	// OnLeave is called after a connection leaves a room. reason is nil when it left
+	// through Leave or LeaveAll, and the write error when it was removed
+	OnLeave func(room string, member Member, reason error)
+
+	// OnPresence is called after a member's presence state changes
+	OnPresence func(room string, member Member)
+
+	// OnRoomEmpty is called after the last member left a room
+	OnRoomEmpty func(room string)
+}
+
+// Member is a connection in a room along with its presence state
+type Member struct {
+	Conn     *Conn
+	JoinedAt time.Time
+	State    any // Application-defined presence state, see Hub.SetPresence
+}
+
+// hubConn serialises the writes of the hub to one connection, which may be in several
+// rooms.
+type hubConn struct {
+	mu    sync.Mutex
+	rooms map[string]*Member
+}
+
+// Hub groups WebSocket connections into named rooms and broadcasts messages to the
+// members of a room. Connections may join several rooms. The hub tracks who is in each
+// room along with an application-defined presence state, and reports joins, leaves,
+// and presence changes through the callbacks of HubConfig.
+//
+// Connections keep reading in their own handler; while a connection is in a room, write
+// to it only through Send so that writes are not interleaved with broadcasts. Call
+// LeaveAll when the read loop ends.
+//
+// Example:
+//
+//	hub := websocket.NewHub(websocket.HubConfig{
+//		OnJoin: func(room string, m websocket.Member) { log.Printf("%s joined %s", m.Conn.RemoteAddr(), room) },
+//	})
+//
+//	func chat(w http.ResponseWriter, r *http.Request) {
+//		conn, err := upgrader.Upgrade(w, r, nil)
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close()
+//		room := r.URL.Query().Get("room")
+//		hub.Join(room, conn)
+//		defer hub.LeaveAll(conn)
+//		for {
+//			_, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			hub.Broadcast(room, msg)
+//		}
+//	}
+type Hub struct {
+	config HubConfig
+
+	mu     sync.RWMutex
+	rooms  map[string]map[*Conn]struct{}
+	conns  map[*Conn]*hubConn
+	closed bool
+}
+
+// NewHub creates a hub
+func NewHub(config ...HubConfig) *Hub {
+	var cfg HubConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = defaultHubWriteTimeout
+	}
+	return &Hub{
+		config: cfg,
+		rooms:  make(map[string]map[*Conn]struct{}),
+		conns:  make(map[*Conn]*hubConn),
+	}
+}
+
+// Join adds conn to room. Joining a room twice has no effect.
+func (h *Hub) Join(room string, conn *Conn) error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return ErrHubClosed
+	}
+	hc, ok := h.conns[conn]
+	if !ok {
+		hc = &hubConn{rooms: make(map[string]*Member)}
+		h.conns[conn] = hc
+	}
+	if _, ok := hc.rooms[room]; ok {
+		h.mu.Unlock()
+		return nil
+	}
+	m := &Member{Conn: conn, JoinedAt: time.Now()}
+	hc.rooms[room] = m
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Conn]struct{})
+	}
+	h.rooms[room][conn] = struct{}{}
+	joined := *m
+	h.mu.Unlock()
+
+	if h.config.OnJoin != nil {
+		h.config.OnJoin(room, joined)
+	}
+	return nil
+}
+
+// Leave removes conn from room
+func (h *Hub) Leave(room string, conn *Conn) {
+	h.mu.Lock()
+	m, empty, ok := h.removeLocked(room, conn)
+	h.mu.Unlock()
+	if ok {
+		h.notifyLeave(room, m, empty, nil)
+	}
+}
+
+// LeaveAll removes conn from every room it joined
+func (h *Hub) LeaveAll(conn *Conn) {
+	h.leaveAll(conn, nil)
+}
+
+func (h *Hub) leaveAll(conn *Conn, reason error) {
+	type left struct {
+		room  string
+		m     Member
+		empty bool
+	}
+	var rooms []left
+	h.mu.Lock()
+	if hc, ok := h.conns[conn]; ok {
+		for room := range hc.rooms {
+			m, empty, _ := h.removeLocked(room, conn)
+			rooms = append(rooms, left{room, m, empty})
+		}
+	}
+	h.mu.Unlock()
+	slices.SortFunc(rooms, func(a, b left) int { return cmp.Compare(a.room, b.room) })
+	for _, l := range rooms {
+		h.notifyLeave(l.room, l.m, l.empty, reason)
+	}
+}
+
+// removeLocked removes conn from room and reports whether the room is now empty.
+func (h *Hub) removeLocked(room string, conn *Conn) (Member, bool, bool) {
+	hc, ok := h.conns[conn]
+	if !ok {
+		return Member{}, false, false
+	}
+	m, ok := hc.rooms[room]
+	if !ok {
+		return Member{}, false, false
+	}
+	delete(hc.rooms, room)
+	if len(hc.rooms) == 0 {
+		delete(h.conns, conn)
+	}
+	delete(h.rooms[room], conn)
+	empty := len(h.rooms[room]) == 0
+	if empty {
+		delete(h.rooms, room)
+	}
+	return *m, empty, true
+}
+
+func (h *Hub) notifyLeave(room string, m Member, empty bool, reason error) {
+	if h.config.OnLeave != nil {
+		h.config.OnLeave(room, m, reason)
+	}
+	if empty && h.config.OnRoomEmpty != nil {
+		h.config.OnRoomEmpty(room)
+	}
+}
+
+// SetPresence sets the presence state of conn in room, such as a user name or
+// "typing", and reports whether conn is in the room
+func (h *Hub) SetPresence(room string, conn *Conn, state any) bool {
+	h.mu.Lock()
+	hc, ok := h.conns[conn]
+	if !ok || hc.rooms[room] == nil {
+		h.mu.Unlock()
+		return false
+	}
+	m := hc.rooms[room]
+	m.State = state
+	updated := *m
+	h.mu.Unlock()
+
+	if h.config.OnPresence != nil {
+		h.config.OnPresence(room, updated)
+	}
+	return true
+}
+
+// Presence returns the members of room in the order they joined
+func (h *Hub) Presence(room string) []Member {
+	h.mu.RLock()
+	members := make([]Member, 0, len(h.rooms[room]))
+	for conn := range h.rooms[room] {
+		members = append(members, *h.conns[conn].rooms[room])
+	}
+	h.mu.RUnlock()
+	slices.SortFunc(members, func(a, b Member) int { return a.JoinedAt.Compare(b.JoinedAt) })
+	return members
+}
+
+// Rooms returns the rooms that have members
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+	return rooms
+}
+
+// Len returns the number of members of room
+func (h *Hub) Len(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast sends a text message to every member of room and returns how many received
+// it. Members are written to concurrently, so a slow member delays the broadcast by at
+// most the write timeout.
+func (h *Hub) Broadcast(room string, msg []byte) int {
+	return h.broadcast(room, TextMessage, msg, nil)
+}
+
+// BroadcastBinary sends a binary message to every member of room
+func (h *Hub) BroadcastBinary(room string, msg []byte) int {
+	return h.broadcast(room, BinaryMessage, msg, nil)
+}
+
+// BroadcastJSON sends v, encoded as JSON, to every member of room
+func (h *Hub) BroadcastJSON(room string, v any) (int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return h.broadcast(room, TextMessage, data, nil), nil
+}
+
+// BroadcastExcept sends a text message to every member of room except sender, such as
+// the connection that sent a chat message
+func (h *Hub) BroadcastExcept(room string, sender *Conn, msg []byte) int {
+	return h.broadcast(room, TextMessage, msg, sender)
+}
+
+func (h *Hub) broadcast(room string, messageType int, data []byte, except *Conn) int {
+	h.mu.RLock()
+	targets := make(map[*Conn]*hubConn, len(h.rooms[room]))
+	for conn := range h.rooms[room] {
+		if conn != except {
+			targets[conn] = h.conns[conn]
+		}
+	}
+	h.mu.RUnlock()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	for conn, hc := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if h.write(conn, hc, messageType, data) == nil {
+				mu.Lock()
+				delivered++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return delivered
+}
+
+// Send writes a message to one member, serialised with the hub's broadcasts. A failed
+// write removes conn from all rooms and closes it.
+func (h *Hub) Send(conn *Conn, messageType int, data []byte) error {
+	h.mu.RLock()
+	hc, ok := h.conns[conn]
+	h.mu.RUnlock()
+	if !ok {
+		return conn.WriteMessage(messageType, data)
+	}
+	return h.write(conn, hc, messageType, data)
+}
+
+func (h *Hub) write(conn *Conn, hc *hubConn, messageType int, data []byte) error {
+	hc.mu.Lock()
+	err := conn.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
+	if err == nil {
+		err = conn.WriteMessage(messageType, data)
+	}
+	if err == nil {
+		err = conn.SetWriteDeadline(time.Time{})
+	}
+	hc.mu.Unlock()
+	if err != nil {
+		h.leaveAll(conn, err)
+		h.close(conn, hc, err)
+	}
+	return err
+}
+
+// close closes a removed member once no write to it is in flight.
+func (h *Hub) close(conn *Conn, hc *hubConn, reason error) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if h.config.Pool != nil {
+		h.config.Pool.Close(conn, reason)
+		return
+	}
+	conn.Close()
+}
+
+// Close removes all members, closes their connections, and rejects further joins
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	conns := make(map[*Conn]*hubConn, len(h.conns))
+	for conn, hc := range h.conns {
+		conns[conn] = hc
+	}
+	h.mu.Unlock()
+	for conn, hc := range conns {
+		h.leaveAll(conn, ErrHubClosed)
+		h.close(conn, hc, ErrHubClosed)
+	}
+}
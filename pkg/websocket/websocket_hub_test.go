@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+// dialTestConn performs a client handshake and returns the raw connection.
+func dialTestConn(t *testing.T, url string) (io.ReadWriteCloser, *ws.FrameReader) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("handshake request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		t.Fatalf("expected 101 Switching Protocols, got %d", resp.StatusCode)
+	}
+	conn := resp.Body.(io.ReadWriteCloser)
+	t.Cleanup(func() { conn.Close() })
+	return conn, ws.NewFrameReader(bufio.NewReader(conn), 1<<20)
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHubRoomsAndPresence(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	hub := NewHub(HubConfig{
+		OnJoin:      func(room string, m Member) { record("join " + room) },
+		OnLeave:     func(room string, m Member, reason error) { record("leave " + room) },
+		OnRoomEmpty: func(room string) { record("empty " + room) },
+	})
+	t.Cleanup(hub.Close)
+
+	upgrader := Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		room := r.URL.Query().Get("room")
+		hub.Join(room, conn)
+		hub.SetPresence(room, conn, r.URL.Query().Get("user"))
+		defer hub.LeaveAll(conn)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	server := newTestServer(t, mux)
+
+	_, alice := dialTestConn(t, server.URL+"/ws?room=lobby&user=alice")
+	waitUntil(t, func() bool { return hub.Len("lobby") == 1 })
+	bobConn, bob := dialTestConn(t, server.URL+"/ws?room=lobby&user=bob")
+	_, carol := dialTestConn(t, server.URL+"/ws?room=other&user=carol")
+	waitUntil(t, func() bool { return hub.Len("lobby") == 2 && hub.Len("other") == 1 })
+
+	presence := hub.Presence("lobby")
+	waitUntil(t, func() bool {
+		presence = hub.Presence("lobby")
+		return presence[1].State == "bob"
+	})
+	if presence[0].State != "alice" {
+		t.Errorf("expected members in join order, got %v and %v", presence[0].State, presence[1].State)
+	}
+
+	if n := hub.Broadcast("lobby", []byte("hello lobby")); n != 2 {
+		t.Fatalf("expected broadcast to 2 members, got %d", n)
+	}
+	for _, reader := range []*ws.FrameReader{alice, bob} {
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			t.Fatalf("read broadcast: %v", err)
+		}
+		if frame.Opcode != TextMessage || string(frame.Payload) != "hello lobby" {
+			t.Errorf("unexpected frame %d %q", frame.Opcode, frame.Payload)
+		}
+	}
+	if n, err := hub.BroadcastJSON("other", map[string]string{"to": "carol"}); err != nil || n != 1 {
+		t.Fatalf("expected JSON broadcast to 1 member, got %d: %v", n, err)
+	}
+	if frame, err := carol.ReadFrame(); err != nil || string(frame.Payload) != `{"to":"carol"}` {
+		t.Errorf("unexpected frame for carol: %v", err)
+	}
+
+	bobConn.Close()
+	waitUntil(t, func() bool { return hub.Len("lobby") == 1 })
+	if rooms := hub.Rooms(); len(rooms) != 2 || rooms[0] != "lobby" || rooms[1] != "other" {
+		t.Errorf("unexpected rooms %v", rooms)
+	}
+
+	hub.Close()
+	if err := hub.Join("lobby", nil); err != ErrHubClosed {
+		t.Errorf("expected ErrHubClosed, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var leaves, empties int
+	for _, e := range events {
+		switch e {
+		case "leave lobby", "leave other":
+			leaves++
+		case "empty lobby", "empty other":
+			empties++
+		}
+	}
+	if len(events) != 3+leaves+empties || leaves != 3 || empties != 2 {
+		t.Errorf("unexpected lifecycle events %v", events)
+	}
+}
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osauer/hyperserve/internal/ws"
+)
+
+// limitedServer upgrades connections, applies limits, and reports what ReadMessage returned.
+func limitedServer(t *testing.T, limits ConnLimits) (string, <-chan string, <-chan error) {
+	t.Helper()
+	messages := make(chan string, 16)
+	done := make(chan error, 1)
+	upgrader := Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	ts := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		conn.SetLimits(limits)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				done <- err
+				return
+			}
+			messages <- string(data)
+		}
+	}))
+	return ts.URL, messages, done
+}
+
+func sendClientText(t *testing.T, conn io.Writer, text string) {
+	t.Helper()
+	fw := ws.NewFrameWriter(bufio.NewWriter(conn), false)
+	frame := &ws.Frame{Fin: true, Opcode: ws.OpcodeText, Masked: true, MaskKey: [4]byte{1, 2, 3, 4}, Payload: []byte(text)}
+	if err := fw.WriteFrame(frame); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+func expectCloseCode(t *testing.T, fr *ws.FrameReader, code int) {
+	t.Helper()
+	frame, err := fr.ReadFrame()
+	if err != nil {
+		t.Fatalf("read close frame: %v", err)
+	}
+	if frame.Opcode != ws.OpcodeClose || len(frame.Payload) < 2 {
+		t.Fatalf("expected close frame, got opcode %d", frame.Opcode)
+	}
+	if got := int(binary.BigEndian.Uint16(frame.Payload)); got != code {
+		t.Fatalf("expected close code %d, got %d", code, got)
+	}
+}
+
+func TestConnLimitsOversize(t *testing.T) {
+	t.Run("close", func(t *testing.T) {
+		url, _, done := limitedServer(t, ConnLimits{MaxMessageSize: 8})
+		conn, fr := dialTestConn(t, url)
+		sendClientText(t, conn, strings.Repeat("x", 32))
+
+		expectCloseCode(t, fr, CloseMessageTooBig)
+		if err := <-done; !errors.Is(err, ErrMessageTooBig) {
+			t.Fatalf("expected ErrMessageTooBig, got %v", err)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		url, messages, _ := limitedServer(t, ConnLimits{MaxMessageSize: 8, OversizePolicy: OversizeDrop})
+		conn, _ := dialTestConn(t, url)
+		sendClientText(t, conn, strings.Repeat("x", 32))
+		sendClientText(t, conn, "small")
+
+		select {
+		case msg := <-messages:
+			if msg != "small" {
+				t.Fatalf("expected the oversized message to be dropped, got %q", msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("message after an oversized one was not read")
+		}
+	})
+}
+
+func TestConnLimitsRate(t *testing.T) {
+	t.Run("close", func(t *testing.T) {
+		url, messages, done := limitedServer(t, ConnLimits{MessagesPerSecond: 1, Burst: 2})
+		conn, fr := dialTestConn(t, url)
+		for i := 0; i < 3; i++ {
+			sendClientText(t, conn, "hi")
+		}
+
+		expectCloseCode(t, fr, ClosePolicyViolation)
+		if err := <-done; !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected ErrRateLimited, got %v", err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("expected the burst of 2 messages to be read, got %d", len(messages))
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		accepted := make(chan *Conn, 1)
+		upgrader := Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+		read := make(chan string, 16)
+		ts := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.SetLimits(ConnLimits{MessagesPerSecond: 0.001, RateLimitPolicy: RateLimitDrop})
+			accepted <- conn
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				read <- string(data)
+			}
+		}))
+		conn, _ := dialTestConn(t, ts.URL)
+		for _, text := range []string{"first", "second", "third"} {
+			sendClientText(t, conn, text)
+		}
+
+		if msg := <-read; msg != "first" {
+			t.Fatalf("expected first message, got %q", msg)
+		}
+		server := <-accepted
+		waitUntil(t, func() bool { return server.DroppedMessages() == 2 })
+	})
+}
@@ -48,6 +48,9 @@ type PoolConfig struct {
 	// EnableCompression enables WebSocket compression
 	EnableCompression bool
 
+	// Limits bounds the message size and rate of each connection, see ConnLimits
+	Limits ConnLimits
+
 	// OnConnectionCreated is called when a new connection is created
 	OnConnectionCreated func(endpoint string, conn *Conn)
 
@@ -175,6 +178,7 @@ func (p *WebSocketPool) Get(ctx context.Context, endpoint string, upgrader *Upgr
 		p.stats.FailedConnections.Add(1)
 		return nil, fmt.Errorf("failed to upgrade connection: %w", err)
 	}
+	newConn.SetLimits(p.config.Limits)
 
 	// Wrap in pooled connection
 	pc := &pooledConn{